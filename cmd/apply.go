@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/budget"
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/model"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/output/progress"
+	"github.com/blacksilver/termplate-go/internal/service/apply"
+)
+
+var (
+	applyFiles           []string
+	applyPrune           bool
+	applyDryRun          bool
+	applyContinueOnError bool
+	applyServerDiff      bool
+	applyForceConflicts  bool
+	applyPlanOut         string
+	applyPlanFile        string
+	applySelector        string
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Bring API resources to the state declared in files",
+	Long: `Read resources from YAML or JSON files, compare them with the API, show
+the changes as diffs, and make them.
+
+Each resource names its kind and name, and declares the fields it sets:
+
+  kind: users
+  metadata:
+    name: ana
+  spec:
+    email: ana@example.com
+    role: admin
+
+A resource is read at api.base_url + api.resource_path, with {kind} and
+{name} replaced, and created in its collection, the path before /{name}.
+Fields not in the spec are left as they are. An update finding the
+resource changed since it was read is resolved as api.on_conflict says.
+
+The spec each resource was applied with is kept in the state directory,
+so a field dropped from its file is removed the next time, and a field
+someone else changed since it was applied is reported as a conflict: the
+update fails unless --force-conflicts is given.
+
+--server-diff shows the changes as the API's dry run of them returns the
+resources, with the defaults and computed fields it fills in. The API
+must take a dry-run query, set in api.dry_run_query (e.g. dryRun=All).
+
+--plan-out saves the plan to a file instead of applying it, and --plan
+applies a saved plan exactly as it was reviewed, without reading the
+files again. A saved plan is refused once api.plan_expiry passes, when
+the file was edited, or when a resource it changes has changed since it
+was made (exit code 7).
+
+With --prune, live resources of the kinds in the files that no file
+declares are deleted. --selector limits apply to the resources whose
+labels (metadata.labels) match, both declared and pruned; see
+'termplate resources --help' for the syntax. Exits with code 3 when any
+change fails.
+
+Examples:
+  termplate apply -f users.yaml --dry-run
+  termplate apply -f resources/ --prune
+  termplate apply -f resources/ --prune -l env=staging
+  termplate apply -f users.yaml --server-diff --dry-run
+  termplate apply -f resources/ --plan-out plan.json
+  termplate apply --plan plan.json
+  cat users.json | termplate apply -f -`,
+
+	Args: cobra.NoArgs,
+
+	Annotations: map[string]string{
+		lock.Annotation:                "apply",
+		daemon.LocalAnnotation:         "true", // Prompts for conflicts
+		introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure, model.ExitOverBudget, model.ExitConflict),
+	},
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runApply(cmd.Context())
+	},
+}
+
+func init() {
+	applyCmd.Flags().StringSliceVarP(&applyFiles, "filename", "f", nil, "files or directories of resources; - reads stdin (repeatable)")
+	applyCmd.Flags().BoolVar(&applyPrune, "prune", false, "delete resources of the same kinds that no file declares")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "show the changes without making them")
+	applyCmd.Flags().BoolVar(&applyContinueOnError, "continue-on-error", true, "keep applying after a change fails")
+	applyCmd.Flags().BoolVar(&applyServerDiff, "server-diff", false, "show the changes as the API's dry run returns them")
+	applyCmd.Flags().BoolVar(&applyForceConflicts, "force-conflicts", false, "overwrite fields changed elsewhere since they were last applied")
+	applyCmd.Flags().StringVar(&applyPlanOut, "plan-out", "", "save the plan to this file instead of applying it")
+	applyCmd.Flags().StringVarP(&applySelector, "selector", "l", "", "only apply resources whose labels match, e.g. env=prod,team!=core")
+	applyCmd.Flags().StringVar(&applyPlanFile, "plan", "", "apply the plan saved to this file by --plan-out")
+	flags.OneRequired(applyCmd, "filename", "plan")
+	for _, name := range []string{"filename", "plan-out", "prune", "selector", "server-diff"} {
+		// A saved plan is applied as it was made
+		flags.MutuallyExclusive(applyCmd, "plan", name)
+	}
+}
+
+func runApply(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewApplyHandler()
+	var plan *apply.Plan
+	if applyPlanFile != "" {
+		spinner := progress.NewSpinner("Checking the plan", progress.Options{Quiet: cfg.Output.Quiet})
+		plan, err = h.LoadPlan(ctx, cfg.API, applyPlanFile)
+		spinner.Stop()
+		if err != nil {
+			return fmt.Errorf("loading plan: %w", err)
+		}
+	} else {
+		spinner := progress.NewSpinner("Planning changes", progress.Options{Quiet: cfg.Output.Quiet})
+		plan, err = h.Plan(ctx, cfg.API, handler.ApplyInput{Files: applyFiles, Prune: applyPrune, ServerDiff: applyServerDiff, Selector: applySelector})
+		spinner.Stop()
+		if err != nil {
+			return fmt.Errorf("planning changes: %w", err)
+		}
+	}
+
+	out := &handler.ApplyOutput{Plan: plan}
+	if applyPlanOut != "" {
+		if err := h.SavePlan(cfg.API, plan, applyPlanOut); err != nil {
+			return err
+		}
+		out.PlanFile = applyPlanOut
+	} else if !applyDryRun && !plan.Empty() {
+		out.Report, err = h.Apply(ctx, cfg.API, plan, apply.ApplyOptions{
+			Options: bulk.Options{
+				ContinueOnError: applyContinueOnError,
+				Budget:          budget.FromConfig(cfg.Budget),
+			},
+			ForceConflicts: applyForceConflicts,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := formatter.NewFormatter(cfg.Output).Print(out); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+	} else if err := printApply(cfg.Output, out); err != nil {
+		return err
+	}
+
+	if out.Report != nil {
+		return out.Report.Err()
+	}
+	return nil
+}
+
+// applyActionStyle colors actions as diffs do
+func applyActionStyle(action string) formatter.Style {
+	switch action {
+	case apply.ActionCreate, string(bulk.StatusSucceeded):
+		return formatter.StyleGreen
+	case apply.ActionUpdate:
+		return formatter.StyleYellow
+	case apply.ActionDelete, string(bulk.StatusFailed):
+		return formatter.StyleRed
+	default:
+		return formatter.StyleNone
+	}
+}
+
+func printApply(cfg config.OutputConfig, out *handler.ApplyOutput) error {
+	cfg.Format = "table"
+	for _, c := range out.Plan.Changes {
+		if c.Diff != "" {
+			fmt.Println(c.Diff)
+			fmt.Println()
+		}
+	}
+	for _, c := range out.Plan.Changes {
+		if len(c.Conflicts) > 0 {
+			fmt.Printf("Conflict: %s: %s changed elsewhere since the last apply", c.ID(), strings.Join(c.Conflicts, ", "))
+			if applyForceConflicts {
+				fmt.Println("; overwriting")
+			} else {
+				fmt.Println("; --force-conflicts overwrites")
+			}
+		}
+	}
+	fmt.Printf("Plan: %s\n", out.Plan.Summary())
+
+	switch {
+	case out.PlanFile != "":
+		fmt.Printf("Saved the plan to %s; apply it with --plan %s\n", out.PlanFile, out.PlanFile)
+		return nil
+	case out.Plan.Empty():
+		fmt.Println("Nothing to change")
+		return nil
+	case out.Report == nil:
+		fmt.Println("Dry run: nothing was changed")
+		return nil
+	}
+
+	fmt.Println()
+	actions := map[string]string{}
+	for _, c := range out.Plan.Changes {
+		actions[c.ID()] = c.Action
+	}
+	table := [][]string{{"Resource", "Action", "Status", "Reason"}}
+	for _, res := range out.Report.Results {
+		table = append(table, []string{res.ID, actions[res.ID], string(res.Status), res.Reason})
+	}
+	f := formatter.NewFormatter(cfg).WithColumnStyle("Status", applyActionStyle).WithColumnStyle("Action", applyActionStyle)
+	if err := f.Print(table); err != nil {
+		return fmt.Errorf("printing results: %w", err)
+	}
+	return nil
+}
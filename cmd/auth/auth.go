@@ -0,0 +1,24 @@
+package auth
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for logging in to the API
+var Cmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Log in to the API with OAuth 2.0",
+	Long: `Commands for logging in to the API as the OAuth 2.0 client configured
+in api.oauth, rather than with a fixed api.token.
+
+'termplate auth login' signs in through the browser (the authorization
+code flow with PKCE) or, with --device, by entering a code on any device.
+The tokens are kept in the OS keyring (the macOS keychain, or the Secret
+Service through secret-tool on Linux), or with api.oauth.token_store: file
+in $XDG_STATE_HOME/termplate/credentials.json. API requests then send the
+access token, refreshed as it expires.`,
+}
+
+func init() {
+	Cmd.AddCommand(loginCmd)
+	Cmd.AddCommand(logoutCmd)
+	Cmd.AddCommand(statusCmd)
+}
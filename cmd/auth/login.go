@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var loginDevice bool
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Log in to the API",
+	Long: `Log in as the OAuth 2.0 client configured in api.oauth and store the
+tokens. The authorization page opens in the browser, which returns to
+termplate on 127.0.0.1 once you've signed in. With --device, or when only
+api.oauth.device_url is set, a code is shown instead, to enter on any
+device, e.g. when logged in over SSH.
+
+Examples:
+  termplate auth login
+  termplate auth login --device`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runLogin(cmd.Context())
+	},
+}
+
+func init() {
+	loginCmd.Flags().BoolVar(&loginDevice, "device", false, "log in by entering a code on any device instead of through the browser")
+}
+
+func runLogin(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewAuthHandler()
+	status, err := h.Login(ctx, cfg.API, loginDevice, os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := output.NewFormatter(cfg.Output).Print(status); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+	} else if !cfg.Output.Quiet {
+		if status.Expires != nil {
+			fmt.Printf("Logged in; the access token expires at %s\n", status.Expires.Local().Format(time.RFC3339))
+		} else {
+			fmt.Println("Logged in")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored login",
+	Long: `Remove the tokens 'termplate auth login' stored for the OAuth client
+configured in api.oauth. API requests fail until you log in again.`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runLogout(cmd.Context())
+	},
+}
+
+func runLogout(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewAuthHandler()
+	if err := h.Logout(ctx, cfg.API); err != nil {
+		return err
+	}
+	if !cfg.Output.Quiet {
+		fmt.Println("Logged out")
+	}
+	return nil
+}
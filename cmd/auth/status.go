@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether you're logged in",
+	Long: `Show the OAuth client you're logged in as, where the tokens are
+stored, when the access token expires, and whether it can be refreshed.
+Exits with code 1 when you aren't logged in.`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runStatus(cmd.Context())
+	},
+}
+
+func runStatus(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewAuthHandler()
+	status, err := h.Status(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(status); err != nil {
+		return fmt.Errorf("printing status: %w", err)
+	}
+	return nil
+}
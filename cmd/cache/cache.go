@@ -0,0 +1,27 @@
+package cache
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for the API response and completion caches
+var Cmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the API response and completion caches",
+	Long: `Commands for the response cache: GET responses from the API kept on
+disk so repeated commands don't wait on a slow API.
+
+A response is kept as its Cache-Control, ETag, and Last-Modified headers
+allow. One still fresh (max-age) is used without asking the API; a stale
+one is sent again only when the API says it changed (If-None-Match).
+Caching is on with api.cache, and --no-cache skips it for one command.
+
+Shell completions that need the API, such as resource names, are cached
+too, for completion.cache_ttl; stale ones are still offered while they're
+fetched again in the background.
+
+Responses are stored in $XDG_CACHE_HOME/termplate/http, and completions
+in $XDG_CACHE_HOME/termplate/completion.`,
+}
+
+func init() {
+	Cmd.AddCommand(clearCmd)
+}
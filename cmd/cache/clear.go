@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached API response and completion",
+	Long: `Remove every cached API response and shell completion list, so the next
+request for each is sent to the API.
+
+Examples:
+  termplate cache clear`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		h := handler.NewCacheHandler()
+		out, err := h.Clear(cmd.Context())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cached response(s) and %d completion list(s) (%s)\n", out.Removed, out.Completions, out.Size)
+		return nil
+	},
+}
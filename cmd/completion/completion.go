@@ -0,0 +1,90 @@
+package completion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/service/completion"
+)
+
+// Cmd prints shell completion scripts, and installs them
+var Cmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion scripts",
+	Long: `Print the completion script for a shell. To install it where your shell
+loads it from, use 'termplate completion install'.
+
+To load completions by hand:
+
+Bash:
+  $ source <(termplate completion bash)
+  $ termplate completion bash > /etc/bash_completion.d/termplate
+
+Zsh:
+  $ termplate completion zsh > "${fpath[1]}/_termplate"
+  $ source ~/.zshrc
+
+Fish:
+  $ termplate completion fish | source
+  $ termplate completion fish > ~/.config/fish/completions/termplate.fish
+
+PowerShell:
+  PS> termplate completion powershell | Out-String | Invoke-Expression
+`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             completion.Shells,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeScript(cmd.Root(), args[0], os.Stdout)
+	},
+}
+
+func init() {
+	Cmd.AddCommand(installCmd)
+	Cmd.AddCommand(uninstallCmd)
+}
+
+// writeScript writes root's completion script for shell to w
+func writeScript(root *cobra.Command, shell string, w io.Writer) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletion(w)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletion(w)
+	}
+	return nil
+}
+
+// script returns root's completion script for shell
+func script(root *cobra.Command, shell string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeScript(root, shell, &buf); err != nil {
+		return nil, fmt.Errorf("generating completion script: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// shellArg returns the shell named in args, or else the login shell
+func shellArg(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	if login := filepath.Base(os.Getenv("SHELL")); slices.Contains(completion.Shells, login) {
+		return login, nil
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell", nil
+	}
+	return "", fmt.Errorf("can't tell your shell from $SHELL; name it, e.g. 'completion install bash'")
+}
@@ -0,0 +1,72 @@
+package completion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/service/completion"
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install [bash|zsh|fish|powershell]",
+	Short: "Install the completion script for your shell",
+	Long: `Write the completion script where the shell loads completions from, then
+start the shell to check that it loads. The shell defaults to your login
+shell ($SHELL).
+
+  bash        $XDG_DATA_HOME/bash-completion/completions/termplate
+              (loaded by the bash-completion package)
+  zsh         the first writable directory on your fpath under your home,
+              else ~/.zfunc/_termplate, which you add to fpath
+  fish        $XDG_CONFIG_HOME/fish/completions/termplate.fish
+  powershell  termplate-completion.ps1 next to $PROFILE, which loads it
+
+Installing again replaces the script, such as after upgrading termplate.
+
+Examples:
+  termplate completion install
+  termplate completion install zsh`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: completion.Shells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell, err := shellArg(args)
+		if err != nil {
+			return err
+		}
+		return runInstall(cmd.Context(), cmd.Root(), shell)
+	},
+}
+
+func runInstall(ctx context.Context, root *cobra.Command, shell string) error {
+	// Empty for an unsupported shell, which the handler rejects
+	data, err := script(root, shell)
+	if err != nil {
+		return err
+	}
+
+	h := handler.NewCompletionHandler()
+	res, err := h.Install(ctx, handler.CompletionInstallInput{
+		Shell:  shell,
+		Name:   root.Name(),
+		Script: data,
+	})
+	if err != nil {
+		return fmt.Errorf("installing completion: %w", err)
+	}
+
+	fmt.Printf("Installed %s completion in %s\n", res.Shell, res.Path)
+	if res.Profile != "" {
+		fmt.Printf("Loaded from %s\n", res.Profile)
+	}
+	if res.Verified {
+		fmt.Printf("Verified: a new %s loads it\n", res.Shell)
+	}
+	if res.Note != "" {
+		fmt.Printf("Note: %s\n", res.Note)
+	}
+	fmt.Println("Start a new shell to use it.")
+	return nil
+}
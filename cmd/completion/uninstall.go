@@ -0,0 +1,52 @@
+package completion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/service/completion"
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall [bash|zsh|fish|powershell]",
+	Short: "Remove the completion script installed for your shell",
+	Long: `Remove the completion script 'completion install' wrote, and for
+PowerShell the profile line loading it. The shell defaults to your login
+shell ($SHELL).
+
+Examples:
+  termplate completion uninstall
+  termplate completion uninstall fish`,
+	Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: completion.Shells,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell, err := shellArg(args)
+		if err != nil {
+			return err
+		}
+		return runUninstall(cmd.Context(), cmd.Root().Name(), shell)
+	},
+}
+
+func runUninstall(ctx context.Context, name, shell string) error {
+	h := handler.NewCompletionHandler()
+	res, err := h.Uninstall(ctx, shell, name)
+	if err != nil {
+		return fmt.Errorf("uninstalling completion: %w", err)
+	}
+
+	if res == nil {
+		fmt.Printf("No %s completion installed\n", shell)
+		return nil
+	}
+	if res.Path != "" {
+		fmt.Printf("Removed %s\n", res.Path)
+	}
+	if res.Profile != "" {
+		fmt.Printf("Removed the line loading it from %s\n", res.Profile)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for inspecting and editing the configuration
+var Cmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit the configuration",
+	Long: `Commands for showing, checking, and changing the configuration.
+
+Keys are dotted paths into the config file, such as output.format or
+api.timeout. Edits go to the config file in use (--config, or the file
+found in the home or current directory), keeping its comments.`,
+}
+
+func init() {
+	Cmd.AddCommand(decryptCmd)
+	Cmd.AddCommand(diffCmd)
+	Cmd.AddCommand(doctorCmd)
+	Cmd.AddCommand(encryptCmd)
+	Cmd.AddCommand(getCmd)
+	Cmd.AddCommand(setCmd)
+	Cmd.AddCommand(unsetCmd)
+	Cmd.AddCommand(validateCmd)
+	Cmd.AddCommand(viewCmd)
+	Cmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt [key...]",
+	Short: "Store encrypted settings in plain text again",
+	Long: `Decrypt the named settings in the config file, or every encrypted one,
+storing them in plain text again. The key is read as for config encrypt.
+
+Examples:
+  termplate config decrypt
+  termplate config decrypt api.token`,
+
+	Annotations:       map[string]string{lock.Annotation: "config"},
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDecrypt(cmd.Context(), args)
+	},
+}
+
+func runDecrypt(ctx context.Context, keys []string) error {
+	h := handler.NewConfigHandler()
+	result, err := h.Decrypt(ctx, config.FromContext(ctx), keys)
+	if err != nil {
+		return fmt.Errorf("decrypting config: %w", err)
+	}
+
+	if len(result.Keys) == 0 {
+		fmt.Printf("Nothing encrypted in %s\n", result.File)
+		return nil
+	}
+	fmt.Printf("Decrypted %s in %s\n", strings.Join(result.Keys, ", "), result.File)
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var diffShowSecrets bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [section]",
+	Short: "Show the settings changed from their defaults",
+	Long: `List only the settings that differ from the built-in defaults, each with
+its value, its default, and what sets it: the config file (and profile),
+remote config, environment variable, or flag. It answers "why is the tool
+behaving like this" without reading through every setting. A section,
+such as api, limits the list to its settings. Credentials are hidden
+unless --show-secrets is given.
+
+Examples:
+  termplate config diff
+  termplate config diff api
+  TERMPLATE_OUTPUT_FORMAT=json termplate config diff output`,
+
+	Args: cobra.MaximumNArgs(1),
+
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		section := ""
+		if len(args) > 0 {
+			section = args[0]
+		}
+		return runDiff(cmd.Context(), section)
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffShowSecrets, "show-secrets", false, "show credentials instead of hiding them")
+}
+
+func runDiff(ctx context.Context, section string) error {
+	loader := config.FromContext(ctx)
+	h := handler.NewConfigHandler()
+	overrides, err := h.Diff(ctx, loader, section, diffShowSecrets)
+	if err != nil {
+		return fmt.Errorf("comparing config with the defaults: %w", err)
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.Output.Format == "text" {
+		if len(overrides) == 0 {
+			fmt.Println("All settings are at their defaults")
+			return nil
+		}
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(overrides); err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	return nil
+}
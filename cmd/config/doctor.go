@@ -0,0 +1,73 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration for problems",
+	Long: `Check the configuration file for problems:
+
+- the file can't be read or parsed
+- deprecated keys, with their replacement and removal version
+- unknown keys, which are usually typos
+- ${VAR} references to unset environment variables and values that
+  don't parse (sizes, durations)
+- settings that fail validation
+
+Exits with a non-zero code when any check reports an error.
+
+Examples:
+  termplate config doctor
+  termplate config doctor -c ./staging.yaml -o json`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runDoctor(cmd.Context())
+	},
+}
+
+func runDoctor(ctx context.Context) error {
+	loader := config.FromContext(ctx)
+	h := handler.NewConfigHandler()
+	report, err := h.Doctor(ctx, loader)
+	if err != nil {
+		return fmt.Errorf("checking config: %w", err)
+	}
+
+	// The config itself may be what's broken, so fall back to the raw
+	// output settings when it doesn't load
+	outCfg := config.OutputConfig{
+		Format:      loader.GetString("output.format"),
+		ColorOutput: loader.GetBool("output.color"),
+		TableStyle:  loader.GetString("output.table_style"),
+		Quiet:       loader.GetBool("output.quiet"),
+	}
+	if cfg, err := loader.Load(); err == nil {
+		outCfg = cfg.Output
+	}
+
+	// Quiet output lists the checks that found something
+	if outCfg.Format == "text" || outCfg.Format == "table" || outCfg.Quiet {
+		outCfg.Format = "table"
+		if err := output.NewFormatter(outCfg).Print(report.Checks); err != nil {
+			return fmt.Errorf("printing report: %w", err)
+		}
+	} else if err := output.NewFormatter(outCfg).Print(report); err != nil {
+		return fmt.Errorf("printing report: %w", err)
+	}
+
+	if n := report.Errors(); n > 0 {
+		return fmt.Errorf("config has %d error(s)", n)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt [key...]",
+	Short: "Encrypt credentials in the config file",
+	Long: `Encrypt the named settings in the config file, or every credential it
+holds in plain text, such as api.token. Values are encrypted one by one
+with AES-256-GCM, so the file stays readable YAML, and are decrypted
+transparently when the config is loaded.
+
+The key is read from TERMPLATE_CONFIG_KEY, base64-encoded, or else from the
+OS keychain (macOS Keychain, or libsecret's secret-tool on Linux). Without
+either, a new key is created and stored in the keychain.
+
+Settings that reference environment variables, like ${API_TOKEN}, are left
+as they are.
+
+Examples:
+  termplate config encrypt
+  termplate config encrypt api.token
+  TERMPLATE_CONFIG_KEY=$(openssl rand -base64 32) termplate config encrypt`,
+
+	Annotations:       map[string]string{lock.Annotation: "config"},
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEncrypt(cmd.Context(), args)
+	},
+}
+
+func runEncrypt(ctx context.Context, keys []string) error {
+	h := handler.NewConfigHandler()
+	result, err := h.Encrypt(ctx, config.FromContext(ctx), keys)
+	if err != nil {
+		return fmt.Errorf("encrypting config: %w", err)
+	}
+
+	if result.KeyCreated {
+		fmt.Fprintln(os.Stderr, "Created a config key in the OS keychain")
+	}
+	for _, key := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s: it references the environment\n", key)
+	}
+	if len(result.Keys) == 0 {
+		fmt.Printf("Nothing to encrypt in %s\n", result.File)
+		return nil
+	}
+	fmt.Printf("Encrypted %s in %s\n", strings.Join(result.Keys, ", "), result.File)
+	return nil
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Long: `Print the value of a setting as in effect after the config file,
+environment, and flags are merged. A section, such as output, prints all
+of its settings, with credentials hidden; ask for one by name, such as
+api.token, to print it.
+
+Examples:
+  termplate config get api.timeout
+  termplate config get output -o json`,
+
+	Args: cobra.ExactArgs(1),
+
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGet(cmd.Context(), args[0])
+	},
+}
+
+func runGet(ctx context.Context, key string) error {
+	loader := config.FromContext(ctx)
+	h := handler.NewConfigHandler()
+	value, err := h.Get(ctx, loader, key)
+	if err != nil {
+		return fmt.Errorf("getting %s: %w", key, err)
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	// A single value is printed as it is unless structured output is asked for
+	if s, ok := value.(string); ok && (cfg.Output.Format == "text" || cfg.Output.Format == "table") {
+		fmt.Println(s)
+		return nil
+	}
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "yaml"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(value); err != nil {
+		return fmt.Errorf("printing %s: %w", key, err)
+	}
+	return nil
+}
+
+// completeKeys completes the first argument with config keys
+func completeKeys(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return config.Keys(), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a setting to the config file",
+	Long: `Write a setting to the config file, creating the file if there is none.
+
+The value must suit the setting: true or false, a number, a duration
+(30s), a size (100MiB), or comma-separated items for lists. The
+resulting configuration must pass validation. Values may reference
+environment variables, e.g. '${API_TOKEN}'.
+
+Examples:
+  termplate config set output.format json
+  termplate config set api.timeout 1m
+  termplate config set files.patterns '*.go,*.md'`,
+
+	Args: cobra.ExactArgs(2),
+
+	Annotations:       map[string]string{lock.Annotation: "config"},
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSet(cmd.Context(), args[0], args[1])
+	},
+}
+
+func runSet(ctx context.Context, key, value string) error {
+	h := handler.NewConfigHandler()
+	edit, err := h.Set(ctx, config.FromContext(ctx), key, value)
+	if err != nil {
+		return fmt.Errorf("setting %s: %w", key, err)
+	}
+
+	fmt.Printf("Set %s in %s\n", edit.Key, edit.File)
+	warnOverride(edit)
+	return nil
+}
+
+// warnOverride notes an environment variable that hides the edit
+func warnOverride(edit *handler.ConfigEdit) {
+	if edit.EnvVar != "" {
+		fmt.Fprintf(os.Stderr, "Note: %s is set and takes priority over the config file\n", edit.EnvVar)
+	}
+}
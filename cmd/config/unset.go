@@ -0,0 +1,48 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var unsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting from the config file",
+	Long: `Remove a setting from the config file, so it falls back to its default.
+Sections left empty are removed too. Unknown and deprecated keys reported
+by config doctor can be removed the same way.
+
+Examples:
+  termplate config unset output.format
+  termplate config unset api`,
+
+	Args: cobra.ExactArgs(1),
+
+	Annotations:       map[string]string{lock.Annotation: "config"},
+	ValidArgsFunction: completeKeys,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUnset(cmd.Context(), args[0])
+	},
+}
+
+func runUnset(ctx context.Context, key string) error {
+	h := handler.NewConfigHandler()
+	edit, err := h.Unset(ctx, config.FromContext(ctx), key)
+	if err != nil {
+		return fmt.Errorf("unsetting %s: %w", key, err)
+	}
+
+	if !edit.Changed {
+		fmt.Printf("%s is not set in %s\n", edit.Key, edit.File)
+		return nil
+	}
+	fmt.Printf("Removed %s from %s\n", edit.Key, edit.File)
+	warnOverride(edit)
+	return nil
+}
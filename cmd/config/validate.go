@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate a config file, reporting every problem",
+	Long: `Validate a config file on its own, without the environment or flags, and
+report every problem at once with its line and column:
+
+- YAML syntax errors
+- values that don't parse (numbers, durations, sizes, true/false) or that
+  reference unset environment variables
+- settings that fail validation, such as an unknown output format
+- unknown keys, with the key they are likely a typo of, and deprecated
+  keys (warnings)
+
+Checks the config file in use when no file is given. Exits with a
+non-zero code when any error is found; warnings alone don't fail.
+
+Examples:
+  termplate config validate
+  termplate config validate ./staging.yaml
+  termplate config validate ./staging.yaml -o json`,
+
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := ""
+		if len(args) > 0 {
+			file = args[0]
+		}
+		return runValidate(cmd.Context(), file)
+	},
+}
+
+func runValidate(ctx context.Context, file string) error {
+	loader := config.FromContext(ctx)
+	h := handler.NewConfigHandler()
+	report, err := h.Validate(ctx, loader, file)
+	if err != nil {
+		return fmt.Errorf("validating config: %w", err)
+	}
+
+	// As with doctor, the config in use may be broken itself
+	outCfg := config.OutputConfig{
+		Format:      loader.GetString("output.format"),
+		ColorOutput: loader.GetBool("output.color"),
+		TableStyle:  loader.GetString("output.table_style"),
+		Quiet:       loader.GetBool("output.quiet"),
+	}
+	if cfg, err := loader.Load(); err == nil {
+		outCfg = cfg.Output
+	}
+
+	switch {
+	case outCfg.Format == "text" && !outCfg.Quiet:
+		// One diagnostic per line, as editors and CI logs expect
+		for _, p := range report.Problems {
+			fmt.Println(p)
+		}
+		if len(report.Problems) == 0 {
+			fmt.Printf("%s: valid\n", report.File)
+		}
+	case outCfg.Format == "text" || outCfg.Format == "table" || outCfg.Quiet:
+		outCfg.Format = "table"
+		if err := output.NewFormatter(outCfg).Print(report.Problems); err != nil {
+			return fmt.Errorf("printing report: %w", err)
+		}
+	default:
+		if err := output.NewFormatter(outCfg).Print(report); err != nil {
+			return fmt.Errorf("printing report: %w", err)
+		}
+	}
+
+	if report.Errors > 0 {
+		return fmt.Errorf("%s has %d error(s)", report.File, report.Errors)
+	}
+	return nil
+}
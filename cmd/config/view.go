@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	showSecrets bool
+	showOrigin  bool
+)
+
+var viewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective configuration",
+	Long: `Show the configuration in effect: the defaults, overridden by the config
+files, then TERMPLATE_* environment variables, then flags. Environment
+references in values are expanded. Credentials (` + "api.key, api.secret, api.token,\ndatabase.password" + `) are hidden unless --show-secrets is given.
+
+With --show-origin, each setting is listed with where its value comes
+from: the config file (and profile), environment variable, or flag that
+sets it, or the default.
+
+Examples:
+  termplate config view
+  termplate config view -o json -q .output
+  termplate -c base.yaml -c local.yaml config view --show-origin`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runView(cmd.Context())
+	},
+}
+
+func init() {
+	viewCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "show credentials instead of hiding them")
+	viewCmd.Flags().BoolVar(&showOrigin, "show-origin", false, "list each setting with the file, variable, or flag it comes from")
+}
+
+func runView(ctx context.Context) error {
+	loader := config.FromContext(ctx)
+	h := handler.NewConfigHandler()
+	if showOrigin {
+		return runViewOrigins(ctx, loader, h)
+	}
+	settings, err := h.View(ctx, loader, showSecrets)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "yaml"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(settings); err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	return nil
+}
+
+func runViewOrigins(ctx context.Context, loader *config.Loader, h *handler.ConfigHandler) error {
+	settings, err := h.Origins(ctx, loader, showSecrets)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(settings); err != nil {
+		return fmt.Errorf("printing config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Report changes to the config file as it is edited",
+	Long: `Watch the config files in use and report each change as it is saved: the
+keys whose values changed, or why the new file wasn't loaded. This is how
+long-running commands see the file, so it shows what they would pick up.
+A remote config (remote.url) is checked every remote.poll_interval.
+Runs until interrupted.
+
+Examples:
+  termplate config watch
+  termplate config watch -o ndjson`,
+
+	Args: cobra.NoArgs,
+
+	// Watches for as long as it runs, which a daemon shouldn't be tied up by
+	Annotations: map[string]string{daemon.LocalAnnotation: "true"},
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runWatch(cmd.Context())
+	},
+}
+
+func runWatch(ctx context.Context) error {
+	loader := config.FromContext(ctx)
+	cfg, err := loader.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewConfigHandler()
+	changes, err := h.Watch(ctx, loader)
+	if err != nil {
+		return fmt.Errorf("watching config: %w", err)
+	}
+
+	// Text is a line per change, after the files watched; other formats
+	// stream a row per change
+	if cfg.Output.Format == "text" {
+		watched := loader.ConfigFiles()
+		if cfg.Remote.URL != "" && cfg.Remote.PollInterval > 0 {
+			watched = append(watched, fmt.Sprintf("%s (every %s)", config.RedactedURL(cfg.Remote.URL), cfg.Remote.PollInterval))
+		}
+		fmt.Printf("Watching %s\n", strings.Join(watched, ", "))
+		// Changes have no identifier to print instead
+		cfg.Output.Quiet = false
+	}
+
+	s := output.NewFormatter(cfg.Output).Stream()
+	if err := s.Begin(nil); err != nil {
+		return err
+	}
+	p := s.Pipe()
+	for c := range changes {
+		if err := p.Send(ctx, c); err != nil {
+			break
+		}
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if err := s.End(); err != nil {
+		return err
+	}
+	return stopped(ctx)
+}
+
+// stopped returns why watching ended; a plain interrupt is how it's meant
+// to stop
+func stopped(ctx context.Context) error {
+	err := context.Cause(ctx)
+	if errors.Is(err, model.ErrInterrupted) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package cron
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for the jobs the daemon runs on a schedule
+var Cmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Inspect the jobs the daemon runs on a schedule",
+	Long: `Commands for scheduled jobs: termplate commands the daemon runs every
+so often, configured in the schedule section, e.g.
+
+  schedule:
+    jobs:
+      prune-cache:
+        every: 24h
+        command: cache prune
+
+Each job's last run, next run, and failures are kept in
+$XDG_STATE_HOME/termplate/schedule.json across restarts. Runs missed while
+the daemon was down are skipped, made once, or each made, per
+schedule.catch_up.`,
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+}
@@ -0,0 +1,54 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scheduled jobs with their last and next runs",
+	Long: `List the scheduled jobs, by name, with when each last ran and how,
+when it runs next, and its runs, failures in a row, and runs missed. The
+jobs are the running daemon's, or the config's when no daemon runs.
+
+Examples:
+  termplate cron list
+  termplate cron list -o json --filter 'failures>0'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runList(cmd.Context())
+	},
+}
+
+func runList(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewCronHandler()
+	jobs, err := h.List(ctx, cfg.Schedule)
+	if err != nil {
+		return fmt.Errorf("listing jobs: %w", err)
+	}
+
+	if cfg.Output.Format == "text" {
+		if len(jobs) == 0 && !cfg.Output.Quiet {
+			fmt.Fprintln(os.Stderr, "No jobs are scheduled; add them to the schedule section of the config")
+			return nil
+		}
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(jobs); err != nil {
+		return fmt.Errorf("printing jobs: %w", err)
+	}
+	return nil
+}
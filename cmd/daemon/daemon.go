@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/usage"
+)
+
+// Execute runs a command line in this process for daemon clients; the
+// root command sets it
+var Execute daemon.RunFunc
+
+// Cmd is the parent command for managing the background daemon
+var Cmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run termplate as a background daemon",
+	Long: `Keep a termplate process running so later invocations skip startup.
+
+While the daemon runs, commands are sent to it over a unix socket in
+$XDG_STATE_HOME/termplate and run there, one at a time, with the caller's
+working directory, environment, and output. Commands that prompt, such as
+'new' and 'upgrade', always run in-process, and so does everything when
+the daemon runs a different version. Set TERMPLATE_NO_DAEMON=1 to bypass
+the daemon.`,
+	Annotations: map[string]string{
+		daemon.LocalAnnotation: "true",
+		usage.SkipAnnotation:   "true", // A foreground daemon runs until stopped
+	},
+}
+
+func init() {
+	Cmd.AddCommand(startCmd)
+	Cmd.AddCommand(stopCmd)
+	Cmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var foreground bool
+
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon",
+	Long: `Start the daemon in the background, logging to
+$XDG_STATE_HOME/termplate/daemon.log. With --foreground it runs in this
+process until interrupted or stopped, for service managers such as systemd.
+The daemon runs the jobs of the schedule section (see 'termplate cron').
+
+Examples:
+  termplate daemon start
+  termplate daemon start --foreground -v`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runStart(cmd.Context())
+	},
+}
+
+func init() {
+	startCmd.Flags().BoolVar(&foreground, "foreground", false, "run in this process instead of the background")
+}
+
+func runStart(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewDaemonHandler()
+	status, err := h.Start(ctx, handler.DaemonStartInput{
+		Foreground: foreground,
+		Run:        Execute,
+		Args:       []string{"daemon", "start", "--foreground"},
+		Schedule:   cfg.Schedule,
+	})
+	if err != nil {
+		return fmt.Errorf("starting daemon: %w", err)
+	}
+	if status != nil {
+		fmt.Printf("Daemon started (pid %d, socket %s)\n", status.PID, status.Socket)
+	}
+	return nil
+}
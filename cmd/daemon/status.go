@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the daemon is running",
+	Long: `Show the daemon's PID, version, socket, start time, and the number
+of commands it has run. Exits with code 1 when no daemon is running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runStatus(cmd.Context())
+	},
+}
+
+func runStatus(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewDaemonHandler()
+	status, err := h.Status(ctx)
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return daemon.ErrNotRunning
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(status); err != nil {
+		return fmt.Errorf("printing status: %w", err)
+	}
+	return nil
+}
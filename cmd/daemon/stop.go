@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon",
+	Long:  `Stop the daemon once the commands it is running finish.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runStop(cmd.Context())
+	},
+}
+
+func runStop(ctx context.Context) error {
+	h := handler.NewDaemonHandler()
+	err := h.Stop(ctx)
+	if errors.Is(err, daemon.ErrNotRunning) {
+		fmt.Println("Daemon is not running")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println("Daemon stopped")
+	return nil
+}
@@ -0,0 +1,14 @@
+package env
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for working with the shell environment
+var Cmd = &cobra.Command{
+	Use:   "env",
+	Short: "Share configuration with the shell environment",
+	Long:  `Commands for passing configuration values to other tools through environment variables.`,
+}
+
+func init() {
+	Cmd.AddCommand(exportCmd)
+}
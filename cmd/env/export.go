@@ -0,0 +1,72 @@
+package env
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/service/shellenv"
+)
+
+var (
+	shell  string
+	prefix string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [key...]",
+	Short: "Print config values as shell export statements",
+	Long: `Print statements that set config values as environment variables, for
+eval in your shell. Variables are named like termplate's own environment
+overrides: api.base_url becomes TERMPLATE_API_BASE_URL.
+
+Without keys, exports the API endpoint and credentials that are set
+(` + strings.Join(handler.DefaultExportKeys, ", ") + `).
+
+Examples:
+  eval "$(termplate env export)"
+  eval "$(termplate env export api.base_url server.port --prefix MYAPP)"
+  termplate env export --shell fish | source
+  termplate env export --shell powershell | Invoke-Expression`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExport(cmd.Context(), args)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&shell, "shell", "", "shell syntax: "+strings.Join(shellenv.Shells, ", ")+" (default: from $SHELL, else bash)")
+	exportCmd.Flags().StringVar(&prefix, "prefix", "TERMPLATE", "variable name prefix; empty for none")
+}
+
+func runExport(ctx context.Context, keys []string) error {
+	h := handler.NewEnvHandler()
+	out, err := h.Export(ctx, handler.EnvExportInput{
+		Shell:  shellOrDefault(),
+		Keys:   keys,
+		Prefix: prefix,
+		Loader: config.FromContext(ctx),
+	})
+	if err != nil {
+		return fmt.Errorf("exporting environment: %w", err)
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// shellOrDefault returns --shell, or the login shell when it is supported
+func shellOrDefault() string {
+	if shell != "" {
+		return shell
+	}
+	if login := filepath.Base(os.Getenv("SHELL")); slices.Contains(shellenv.Shells, login) {
+		return login
+	}
+	return "bash"
+}
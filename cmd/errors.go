@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/model"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+)
+
+// errorFormat is the output format errors are printed in; see PrintError
+var errorFormat string
+
+// PrintError writes an error returned by Execute to w. A usage error shows
+// the command line with the offending argument highlighted and help for
+// just the flags concerned. Under -o json or ndjson it is a JSON envelope
+// instead, so tools can read the exit code and suggestions.
+func PrintError(w io.Writer, err error) {
+	if err == nil {
+		return
+	}
+	var ue *model.UsageError
+	errors.As(err, &ue)
+
+	if errorFormat == "json" || errorFormat == "ndjson" {
+		env := errorEnvelope{Error: errorBody{ExitCode: model.ExitCode(err), Message: err.Error()}}
+		for _, info := range model.ExitCodes {
+			if info.Code == env.Error.ExitCode {
+				env.Error.Code = info.Name
+			}
+		}
+		if ue != nil {
+			env.Error.Message = ue.Message
+			env.Error.Command = ue.Command
+			env.Error.Argument = ue.Token
+			env.Error.Flag = ue.Flag
+			env.Error.Suggestions = ue.Suggestions
+		}
+		data, _ := json.Marshal(env)
+		fmt.Fprintln(w, string(data))
+		return
+	}
+
+	if ue == nil {
+		fmt.Fprintln(w, "Error:", err)
+		return
+	}
+
+	color := formatter.ColorEnabled(!noColor, w)
+	paint := func(style formatter.Style, s string) string {
+		if color {
+			return style.Paint(s)
+		}
+		return s
+	}
+	fmt.Fprintf(w, "%s %s\n", paint(formatter.StyleRed, "Error:"), ue.Message)
+	if line, marker := commandLine(ue, paint); line != "" {
+		fmt.Fprintf(w, "\n  %s\n", line)
+		if marker != "" {
+			fmt.Fprintf(w, "  %s\n", paint(formatter.StyleRed, marker))
+		}
+	}
+	if ue.Help != "" {
+		fmt.Fprintf(w, "\n%s", ue.Help)
+	}
+	fmt.Fprintf(w, "\nRun '%s --help' for usage.\n", ue.Command)
+}
+
+// errorEnvelope is how errors are printed under -o json
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code        string   `json:"code"` // Exit code name, e.g. "usage"
+	ExitCode    int      `json:"exit_code"`
+	Message     string   `json:"message"`
+	Command     string   `json:"command,omitempty"`
+	Argument    string   `json:"argument,omitempty"`
+	Flag        string   `json:"flag,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// commandLine returns the command line with the offending argument
+// highlighted, and a line of carets under it for terminals without color
+func commandLine(ue *model.UsageError, paint func(formatter.Style, string) string) (string, string) {
+	if len(ue.Args) == 0 {
+		return "", ""
+	}
+	root := strings.Fields(ue.Command)[0]
+	line, marker := root, strings.Repeat(" ", len(root))
+	found := false
+	for _, arg := range ue.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		line += " "
+		marker += " "
+		if !found && ue.Token != "" && (arg == ue.Token || strings.HasPrefix(arg, ue.Token+"=")) {
+			found = true
+			line += paint(formatter.StyleBold+";"+formatter.StyleRed, arg)
+			marker += strings.Repeat("^", len(arg))
+			continue
+		}
+		line += arg
+		marker += strings.Repeat(" ", len(arg))
+	}
+	if !found {
+		return line, ""
+	}
+	return line, strings.TrimRight(marker, " ")
+}
+
+// flagError is the root command's flag error func: it turns the flag
+// parser's errors into a *model.UsageError
+func flagError(c *cobra.Command, err error) error {
+	ue := &model.UsageError{Command: c.CommandPath(), Message: err.Error()}
+
+	var notExist *pflag.NotExistError
+	var noValue *pflag.ValueRequiredError
+	var badValue *pflag.InvalidValueError
+	var badSyntax *pflag.InvalidSyntaxError
+	switch {
+	case errors.As(err, &notExist):
+		if short := notExist.GetSpecifiedShortnames(); short != "" {
+			ue.Token = "-" + short
+			break
+		}
+		ue.Flag = notExist.GetSpecifiedName()
+		ue.Token = "--" + ue.Flag
+		similar := similarFlags(c, ue.Flag)
+		for _, f := range similar {
+			ue.Suggestions = append(ue.Suggestions, "--"+f.Name)
+		}
+		if len(similar) > 0 {
+			ue.Help = "Did you mean:\n" + flags.Usages(similar)
+		}
+	case errors.As(err, &noValue):
+		f := noValue.GetFlag()
+		ue.Flag = f.Name
+		ue.Token = "--" + noValue.GetSpecifiedName()
+		if short := noValue.GetSpecifiedShortnames(); short != "" {
+			ue.Token = "-" + short
+		}
+		ue.Help = "Flag:\n" + flags.Usages([]*pflag.Flag{f})
+	case errors.As(err, &badValue):
+		f := badValue.GetFlag()
+		ue.Flag = f.Name
+		ue.Token = badValue.GetValue()
+		ue.Help = "Flag:\n" + flags.Usages([]*pflag.Flag{f})
+	case errors.As(err, &badSyntax):
+		ue.Token = badSyntax.GetSpecifiedFlag()
+	}
+	return ue
+}
+
+// flagGroup finds the first flag group in cobra's flag group errors, for
+// groups not declared with the flags package
+var flagGroup = regexp.MustCompile(`\[([^\]]+)\]`)
+
+// usageError turns an error from a command that never ran, because its
+// command line was rejected, into a *model.UsageError for args
+func usageError(c *cobra.Command, args []string, err error) *model.UsageError {
+	var ue *model.UsageError
+	if !errors.As(err, &ue) {
+		ue = &model.UsageError{Command: c.CommandPath(), Message: err.Error()}
+		msg := err.Error()
+		switch {
+		case strings.HasPrefix(msg, "unknown command "):
+			// Cobra appends its own suggestions; ours go in Suggestions
+			name, _ := strconv.Unquote(strings.Fields(msg)[2])
+			ue.Message = fmt.Sprintf("unknown command %q for %q", name, ue.Command)
+			ue.Token = name
+			ue.Suggestions = c.SuggestionsFor(name)
+			if len(ue.Suggestions) > 0 {
+				ue.Help = "Did you mean:\n" + commandList(c, ue.Suggestions)
+			} else {
+				ue.Help = "Available commands:\n" + commandList(c, nil)
+			}
+		case strings.Contains(msg, "flags in the group ["):
+			var group []*pflag.Flag
+			if m := flagGroup.FindStringSubmatch(msg); m != nil {
+				for _, name := range strings.Fields(m[1]) {
+					if f := c.Flags().Lookup(name); f != nil {
+						group = append(group, f)
+					}
+				}
+			}
+			ue.Help = "Flags:\n" + flags.Usages(group)
+		default:
+			// The arguments were wrong
+			ue.Help = "Usage:\n  " + c.UseLine() + "\n"
+		}
+	}
+	ue.Args = args
+	return ue
+}
+
+// similarFlags returns c's visible flags whose names are close to name or
+// start with it, closest first
+func similarFlags(c *cobra.Command, name string) []*pflag.Flag {
+	maxDistance := c.SuggestionsMinimumDistance
+	if maxDistance <= 0 {
+		maxDistance = 2
+	}
+	distance := map[*pflag.Flag]int{}
+	var similar []*pflag.Flag
+	c.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" {
+			return
+		}
+		d := levenshtein(strings.ToLower(name), strings.ToLower(f.Name))
+		if d <= maxDistance || strings.HasPrefix(f.Name, strings.ToLower(name)) {
+			distance[f] = d
+			similar = append(similar, f)
+		}
+	})
+	slices.SortStableFunc(similar, func(a, b *pflag.Flag) int {
+		return distance[a] - distance[b]
+	})
+	return similar[:min(len(similar), 3)]
+}
+
+// commandList returns a line for each of c's available subcommands, or
+// just those named when names isn't nil
+func commandList(c *cobra.Command, names []string) string {
+	var b strings.Builder
+	for _, sub := range c.Commands() {
+		if sub.IsAvailableCommand() && (names == nil || slices.Contains(names, sub.Name())) {
+			fmt.Fprintf(&b, "  %-*s %s\n", sub.NamePadding(), sub.Name(), sub.Short)
+		}
+	}
+	return b.String()
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// errorSettings sets how errors are printed from args and the environment,
+// for errors raised before the config is loaded
+func errorSettings(args []string) {
+	errorFormat = formatFromArgs(args)
+	// A bad theme is reported once the config is loaded
+	_ = formatter.SetTheme(os.Getenv("TERMPLATE_OUTPUT_THEME"))
+}
+
+// formatFromArgs returns the output format given by -o or --output in
+// args, or else by TERMPLATE_OUTPUT_FORMAT, for errors raised before the
+// config is loaded
+func formatFromArgs(args []string) string {
+	format := os.Getenv("TERMPLATE_OUTPUT_FORMAT")
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return format
+		case arg == "-o" || arg == "--output":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--output="):
+			format = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "-o"):
+			format = strings.TrimPrefix(strings.TrimPrefix(arg, "-o"), "=")
+		}
+	}
+	return format
+}
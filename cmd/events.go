@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/model"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	eventsSeverity string
+	eventsSource   string
+	eventsSince    time.Duration
+	eventsFollow   bool
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List significant events, such as retries and config reloads",
+	Long: `List the events termplate recorded, oldest first: operations retried
+after transient failures, config reloads, and the results of bulk
+operations and outbox flushes. Where logs describe one run in detail,
+events are a short record of what happened across runs.
+
+Events are kept in $XDG_STATE_HOME/termplate/events.jsonl, the last
+events.size of them (1000 by default). With events.persist: false they're
+kept only in memory, for programs embedding termplate, and this command
+has none to show.
+
+Examples:
+  termplate events
+  termplate events --severity warning --since 1h
+  termplate events --source retry -o json
+  termplate events -f`,
+
+	Args: cobra.NoArgs,
+
+	// Follows for as long as it runs, which a daemon shouldn't be tied up by
+	Annotations: map[string]string{daemon.LocalAnnotation: "true"},
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runEvents(cmd.Context())
+	},
+}
+
+func init() {
+	eventsCmd.Flags().StringVar(&eventsSeverity, "severity", "", "only events this severe or more: info, warning, or error")
+	eventsCmd.Flags().StringVar(&eventsSource, "source", "", "only events from this source, e.g. retry, config, bulk, or outbox")
+	eventsCmd.Flags().DurationVar(&eventsSince, "since", 0, "only events from this long ago or later, e.g. 1h")
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "keep printing events as they're recorded, until interrupted")
+}
+
+func runEvents(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	filter := handler.EventFilter{Source: eventsSource}
+	if eventsSeverity != "" {
+		if filter.Severity, err = events.ParseSeverity(eventsSeverity); err != nil {
+			return model.NewValidationError("severity", err.Error())
+		}
+	}
+	if eventsSince < 0 {
+		return model.NewValidationError("since", "--since must not be negative")
+	}
+	if eventsSince > 0 {
+		filter.Since = time.Now().Add(-eventsSince)
+	}
+
+	h := handler.NewEventsHandler()
+	list, last, err := h.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if !eventsFollow {
+		if cfg.Output.Format == "text" && !cfg.Output.Quiet {
+			if len(list) == 0 {
+				fmt.Fprintln(os.Stderr, "No events")
+				return nil
+			}
+			for _, e := range list {
+				fmt.Println(e)
+			}
+			return nil
+		}
+		if err := formatter.NewFormatter(cfg.Output).Print(list); err != nil {
+			return fmt.Errorf("printing events: %w", err)
+		}
+		return nil
+	}
+
+	followed, err := h.Follow(ctx, filter, last)
+	if err != nil {
+		return fmt.Errorf("following events: %w", err)
+	}
+
+	// Text is a line per event; other formats stream a row per event
+	s := formatter.NewFormatter(cfg.Output).Stream()
+	if err := s.Begin(nil); err != nil {
+		return err
+	}
+	p := s.Pipe()
+	for _, e := range list {
+		if err := p.Send(ctx, e); err != nil {
+			break
+		}
+	}
+	for e := range followed {
+		if err := p.Send(ctx, e); err != nil {
+			break
+		}
+	}
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if err := s.End(); err != nil {
+		return err
+	}
+	return interrupted(ctx)
+}
+
+// interrupted returns why following ended; a plain interrupt is how it's
+// meant to stop
+func interrupted(ctx context.Context) error {
+	err := context.Cause(ctx)
+	if errors.Is(err, model.ErrInterrupted) {
+		return nil
+	}
+	return err
+}
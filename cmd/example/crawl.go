@@ -0,0 +1,115 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/output/progress"
+)
+
+var (
+	crawlWorkers int
+	crawlRate    float64
+	crawlDelay   time.Duration
+)
+
+var crawlCmd = &cobra.Command{
+	Use:   "crawl [dir]",
+	Short: "Hash every file under a directory on a worker pool",
+	Long: `Hash every file under a directory (default: the current one), skipping
+hidden directories, as a reference for long-running work: a worker pool,
+rate limiting, progress, cancellation, and partial results.
+
+Files are printed as they're hashed, in the order they're listed, so the
+output is the same from run to run; --unordered prints each as soon as
+it's done instead. Press Ctrl-C or set --timeout to stop early: files
+already hashed are still printed, the rest are marked skipped, and the
+command exits with code 130 or 124. Files that can't be read are
+reported and give exit code 3. --delay adds simulated work per file, to
+watch it happen.
+
+Examples:
+  termplate example crawl ./internal
+  termplate example crawl . --workers 8 --rate 20 -o json
+  termplate example crawl . --unordered -o csv
+  termplate example crawl . --delay 200ms --timeout 2s`,
+
+	Args: cobra.MaximumNArgs(1),
+
+	Annotations: map[string]string{
+		introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure),
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		return runCrawl(cmd.Context(), dir)
+	},
+}
+
+func init() {
+	crawlCmd.Flags().IntVarP(&crawlWorkers, "workers", "w", 4, "files hashed at once")
+	crawlCmd.Flags().Float64Var(&crawlRate, "rate", 0, "start at most this many files per second (0: no limit)")
+	crawlCmd.Flags().DurationVar(&crawlDelay, "delay", 0, "simulated work per file, e.g. 100ms")
+}
+
+func runCrawl(ctx context.Context, dir string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if crawlWorkers < 1 {
+		return model.NewValidationError("workers", "must be at least 1")
+	}
+	if crawlRate < 0 {
+		return model.NewValidationError("rate", "must not be negative")
+	}
+
+	// Files are printed as they're hashed, which a bar on the same
+	// terminal would draw over
+	quiet := cfg.Output.Quiet || output.CursorControl(os.Stdout)
+	bar := progress.NewBar("Hashing", 0, progress.Options{Quiet: quiet, Unit: "files"})
+	in := handler.CrawlInput{
+		Root:      dir,
+		Workers:   crawlWorkers,
+		Rate:      crawlRate,
+		Delay:     crawlDelay,
+		Unordered: cfg.Output.Unordered,
+		Progress:  bar,
+	}
+
+	// Print what was done even when canceled, then say why it stopped
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+	}
+	h := handler.NewCrawlHandler()
+	result := &handler.CrawlOutput{}
+	var crawlErr error
+	rows := func(yield func(row interface{}) bool) error {
+		crawlErr = h.Stream(ctx, in, func(entry handler.CrawlEntry) bool {
+			result.Files = append(result.Files, entry)
+			return yield(entry)
+		})
+		return crawlErr
+	}
+	err = output.NewFormatter(cfg.Output).PrintRows(nil, rows)
+	bar.Finish()
+	switch {
+	case crawlErr != nil:
+		return fmt.Errorf("crawling: %w", crawlErr)
+	case err != nil:
+		return fmt.Errorf("printing results: %w", err)
+	}
+	return result.Err(ctx)
+}
@@ -11,4 +11,6 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.AddCommand(greetCmd)
+	Cmd.AddCommand(greetBatchCmd)
+	Cmd.AddCommand(crawlCmd)
 }
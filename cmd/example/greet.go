@@ -7,13 +7,18 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/blacksilver/termplate-go/internal/config"
 	"github.com/blacksilver/termplate-go/internal/handler"
 )
 
-var (
-	name      string
-	uppercase bool
-)
+// greetOptions are greet's settings: its flags, or the
+// commands.example.greet config section
+type greetOptions struct {
+	Name      string `mapstructure:"name"`
+	Uppercase bool   `mapstructure:"uppercase"`
+}
+
+var greetOpts greetOptions
 
 var greetCmd = &cobra.Command{
 	Use:   "greet",
@@ -22,13 +27,21 @@ var greetCmd = &cobra.Command{
 
 Examples:
   ever-so-powerful-go example greet --name John
-  ever-so-powerful-go example greet --name Jane --uppercase`,
+  ever-so-powerful-go example greet --name Jane --uppercase
+
+Each flag can also be set in the commands.example.greet config section:
+
+  commands:
+    example:
+      greet:
+        name: John
+        uppercase: true`,
 
 	Args: cobra.NoArgs,
 
 	PreRunE: func(_ *cobra.Command, _ []string) error {
-		if name == "" {
-			return fmt.Errorf("--name is required")
+		if greetOpts.Name == "" {
+			return fmt.Errorf("--name or commands.example.greet.name is required")
 		}
 		return nil
 	},
@@ -39,22 +52,22 @@ Examples:
 }
 
 func init() {
-	greetCmd.Flags().StringVarP(&name, "name", "n", "", "name to greet (required)")
-	greetCmd.Flags().BoolVarP(&uppercase, "uppercase", "u", false, "convert message to uppercase")
+	greetCmd.Flags().StringP("name", "n", "", "name to greet (required)")
+	greetCmd.Flags().BoolP("uppercase", "u", false, "convert message to uppercase")
 
-	_ = greetCmd.MarkFlagRequired("name")
+	config.BindCommand(greetCmd, "commands.example.greet", &greetOpts)
 }
 
 func runGreet(ctx context.Context) error {
 	slog.Debug("greeting user",
-		"name", name,
-		"uppercase", uppercase,
+		"name", greetOpts.Name,
+		"uppercase", greetOpts.Uppercase,
 	)
 
 	h := handler.NewGreetHandler()
 	result, err := h.Greet(ctx, handler.GreetInput{
-		Name:      name,
-		Uppercase: uppercase,
+		Name:      greetOpts.Name,
+		Uppercase: greetOpts.Uppercase,
 	})
 	if err != nil {
 		return fmt.Errorf("greeting user: %w", err)
@@ -0,0 +1,100 @@
+package example
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/budget"
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	batchNames      []string
+	batchUppercase  bool
+	continueOnError bool
+	rollbackOnError bool
+)
+
+var greetBatchCmd = &cobra.Command{
+	Use:   "greet-batch",
+	Short: "Greet several users in one bulk operation",
+	Long: `Greet several users, reporting a per-item result for each name.
+
+Failed items do not stop the batch unless --continue-on-error=false is set.
+With --rollback-on-error, the first failure stops it and rolls back the
+greetings of the items before it.
+The command exits with code 3 when any item fails, and with code 5 when
+there are more names than budget.max_rows allows without
+--confirm-over-budget.
+
+Examples:
+  termplate example greet-batch --names Alice,Bob
+  termplate example greet-batch --names Alice,,Bob -o json
+  termplate example greet-batch --names Alice,,Bob --rollback-on-error`,
+
+	Args: cobra.NoArgs,
+
+	Annotations: map[string]string{
+		introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure, model.ExitOverBudget),
+	},
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runGreetBatch(cmd.Context())
+	},
+}
+
+func init() {
+	greetBatchCmd.Flags().StringSliceVar(&batchNames, "names", nil, "comma-separated names to greet (required)")
+	greetBatchCmd.Flags().BoolVarP(&batchUppercase, "uppercase", "u", false, "convert messages to uppercase")
+	greetBatchCmd.Flags().BoolVar(&continueOnError, "continue-on-error", true, "keep processing after an item fails")
+	greetBatchCmd.Flags().BoolVar(&rollbackOnError, "rollback-on-error", false, "roll back all items when any item fails, printing no greetings")
+
+	flags.Required(greetBatchCmd, "names")
+}
+
+func runGreetBatch(ctx context.Context) error {
+	slog.Debug("greeting users", "count", len(batchNames))
+
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewGreetHandler()
+	result, err := h.GreetBatch(ctx, handler.GreetBatchInput{
+		Names:     batchNames,
+		Uppercase: batchUppercase,
+		Options: bulk.Options{
+			ContinueOnError: continueOnError,
+			RollbackOnError: rollbackOnError,
+			Budget:          budget.FromConfig(cfg.Budget),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("greeting users: %w", err)
+	}
+
+	if cfg.Output.Format == "text" {
+		for _, msg := range result.Messages {
+			fmt.Println(msg)
+		}
+		fmt.Println()
+		cfg.Output.Format = "table"
+		if err := output.NewFormatter(cfg.Output).Print(result.Report.Table()); err != nil {
+			return fmt.Errorf("printing report: %w", err)
+		}
+	} else if err := output.NewFormatter(cfg.Output).Print(result.Report); err != nil {
+		return fmt.Errorf("printing report: %w", err)
+	}
+
+	return result.Report.Err()
+}
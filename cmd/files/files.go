@@ -0,0 +1,14 @@
+package files
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for file operations
+var Cmd = &cobra.Command{
+	Use:   "files",
+	Short: "File processing commands",
+	Long:  `Commands for processing, validating, and generating files.`,
+}
+
+func init() {
+	Cmd.AddCommand(lintTemplatesCmd)
+}
@@ -0,0 +1,101 @@
+package files
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/output/progress"
+)
+
+var (
+	valuesFile string
+	patterns   []string
+	leftDelim  string
+	rightDelim string
+)
+
+var lintTemplatesCmd = &cobra.Command{
+	Use:   "lint-templates [dir]",
+	Short: "Check template files for errors before rendering",
+	Long: `Parse every template file in a directory and report problems:
+
+- syntax errors such as a missing {{end}}
+- functions that are not defined
+- variables missing from the values file (when --values is set)
+- delimiters from other template languages or unbalanced delimiters
+
+Exits with a non-zero code when any error is found.
+
+Examples:
+  termplate files lint-templates ./templates
+  termplate files lint-templates ./templates --values values.yaml
+  termplate files lint-templates ./templates --left-delim '[[' --right-delim ']]'`,
+
+	Args: cobra.MaximumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		return runLintTemplates(cmd.Context(), dir)
+	},
+}
+
+func init() {
+	lintTemplatesCmd.Flags().StringVarP(&valuesFile, "values", "f", "", "YAML or JSON file with template values")
+	lintTemplatesCmd.Flags().StringSliceVar(&patterns, "pattern", []string{"*.tmpl", "*.tpl", "*.gotmpl"}, "template file name patterns")
+	lintTemplatesCmd.Flags().StringVar(&leftDelim, "left-delim", "", "left action delimiter (default {{)")
+	lintTemplatesCmd.Flags().StringVar(&rightDelim, "right-delim", "", "right action delimiter (default }})")
+	flags.RequiredTogether(lintTemplatesCmd, "left-delim", "right-delim")
+}
+
+func runLintTemplates(ctx context.Context, dir string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	spinner := progress.NewSpinner("Linting templates", progress.Options{Quiet: cfg.Output.Quiet})
+	h := handler.NewFilesHandler()
+	result, err := h.LintTemplates(ctx, handler.LintTemplatesInput{
+		Dir:        dir,
+		ValuesFile: valuesFile,
+		Patterns:   patterns,
+		LeftDelim:  leftDelim,
+		RightDelim: rightDelim,
+	})
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("linting templates: %w", err)
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := output.NewFormatter(cfg.Output).Print(result); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+	} else if len(result.Issues) > 0 {
+		table := [][]string{{"File", "Line", "Severity", "Message"}}
+		for _, issue := range result.Issues {
+			table = append(table, []string{issue.File, fmt.Sprint(issue.Line), issue.Severity, issue.Message})
+		}
+		cfg.Output.Format = "table"
+		if err := output.NewFormatter(cfg.Output).Print(table); err != nil {
+			return fmt.Errorf("printing issues: %w", err)
+		}
+	}
+
+	if n := result.Errors(); n > 0 {
+		return fmt.Errorf("%d error(s) found in %d template(s)", n, result.Files)
+	}
+	if cfg.Output.Format == "text" {
+		fmt.Printf("%d template(s) checked, %d warning(s)\n", result.Files, len(result.Issues))
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package generate
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for code generators
+var Cmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate code for a project",
+	Long: `Generate code from a project's sources.
+
+Generators are meant to be wired into go:generate directives, e.g.
+
+  //go:generate termplate generate mocks
+
+and run with 'go generate ./...' (or 'make generate').`,
+}
+
+func init() {
+	Cmd.AddCommand(mocksCmd)
+}
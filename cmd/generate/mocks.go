@@ -0,0 +1,91 @@
+package generate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	interfaces []string
+	mockPkg    string
+	dryRun     bool
+)
+
+var mocksCmd = &cobra.Command{
+	Use:   "mocks [dir...]",
+	Short: "Generate mocks for interfaces",
+	Long: `Generate a call-recording mock for every exported interface in the
+given package directories (default: the current directory). A trailing
+/... includes subdirectories.
+
+Mocks are written to a "mocks" package next to each interface, one file
+per interface. Each mock has a Func field per method that sets its
+behavior and a <Method>Calls accessor returning the recorded arguments:
+
+  repo := &mocks.RepositoryMock{
+      GetFunc: func(ctx context.Context, id string) (*model.User, error) {
+          return &model.User{ID: id}, nil
+      },
+  }
+  svc := service.New(repo)
+  ...
+  if len(repo.GetCalls()) != 1 { ... }
+
+Interfaces that embed interfaces from other packages or are generic are
+skipped with a reason.
+
+Examples:
+  termplate generate mocks
+  termplate generate mocks ./internal/...
+  termplate generate mocks ./internal/repository --interface UserRepository`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMocks(cmd.Context(), args)
+	},
+}
+
+func init() {
+	mocksCmd.Flags().StringSliceVar(&interfaces, "interface", nil, "interfaces to mock (default: all exported interfaces)")
+	mocksCmd.Flags().StringVar(&mockPkg, "package", "", "mock package name and directory (default \"mocks\")")
+	mocksCmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the mocks without writing them")
+}
+
+func runMocks(ctx context.Context, dirs []string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewGenerateHandler()
+	result, err := h.Mocks(ctx, handler.MocksInput{
+		Dirs:       dirs,
+		Interfaces: interfaces,
+		Package:    mockPkg,
+		DryRun:     dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := output.NewFormatter(cfg.Output).Print(result); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+		return nil
+	}
+
+	for _, m := range result.Mocks {
+		fmt.Printf("  %s -> %s\n", m.Interface, m.File)
+	}
+	for _, s := range result.Skipped {
+		fmt.Printf("  skipped %s: %s\n", s.Interface, s.Reason)
+	}
+	fmt.Printf("%d mock(s) generated\n", len(result.Mocks))
+	return nil
+}
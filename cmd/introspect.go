@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/pkg/version"
+)
+
+var introspectCmd = &cobra.Command{
+	Use:   "introspect",
+	Short: "Describe every command, flag, and exit code as JSON",
+	Long: `Print a machine-readable description of the CLI: the command tree with
+each command's usage, help text, and annotations, its flags with their
+types and defaults, and the exit codes each command can return.
+
+GUIs, docs sites, and assistants can use it to drive termplate without
+parsing help text. The output is JSON unless --output yaml is given;
+schema_version changes when fields are renamed or removed.
+
+Examples:
+  termplate introspect
+  termplate introspect -q '.command.commands[].path'
+  termplate introspect -o yaml`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runIntrospect(cmd.Context())
+	},
+}
+
+func runIntrospect(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	// Tables and text can't show the tree, and templates are left to
+	// callers that know the schema
+	if cfg.Output.Format != "yaml" && !formatter.IsTemplateFormat(cfg.Output.Format) {
+		cfg.Output.Format = "json"
+	}
+
+	cli := introspect.Describe(rootCmd, version.Version)
+	if err := formatter.NewFormatter(cfg.Output).Print(cli); err != nil {
+		return fmt.Errorf("printing description: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package ops
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for long-running API operations
+var Cmd = &cobra.Command{
+	Use:   "ops",
+	Short: "Track long-running API operations",
+	Long: `Commands for long-running operations: work the API accepts right away
+and finishes later, returning an ID to check on it with.
+
+An operation's status is read from api.base_url + api.operations_path,
+with {id} replaced by its ID. Responses such as {"status": "running"},
+{"status": "succeeded", "result": {...}}, and {"done": true, "response":
+{...}} are understood.`,
+}
+
+func init() {
+	Cmd.AddCommand(waitCmd)
+}
@@ -0,0 +1,60 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/output/progress"
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait <id>",
+	Short: "Wait for an operation to finish",
+	Long: `Wait for an operation to finish and print the resource it produced.
+
+The status is checked every api.poll_interval at first, waiting longer
+each time up to api.poll_max_interval, or as long as the API asks with
+Retry-After. Transient errors are tried again. The command fails if the
+operation does; use --timeout to stop waiting after a while.
+
+Examples:
+  termplate ops wait op-123
+  termplate --timeout 10m ops wait op-123 -o json`,
+
+	Args: cobra.ExactArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWait(cmd.Context(), args[0])
+	},
+}
+
+func runWait(ctx context.Context, id string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	spinner := progress.NewSpinner("Waiting for "+id, progress.Options{Quiet: cfg.Output.Quiet})
+	h := handler.NewOpsHandler()
+	resource, err := h.Wait(ctx, cfg.API, handler.OpsWaitInput{
+		ID:       id,
+		OnStatus: spinner.SetStatus,
+	})
+	spinner.Stop()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "yaml"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(resource); err != nil {
+		return fmt.Errorf("printing result: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var clearCmd = &cobra.Command{
+	Use:   "clear [id...]",
+	Short: "Drop queued requests without sending them",
+	Long: `Drop the queued requests with the given IDs, as shown by 'termplate
+outbox list', or every queued request when no IDs are given. Dropped
+requests are never sent.
+
+Examples:
+  termplate outbox clear 3
+  termplate outbox clear`,
+
+	Args: func(_ *cobra.Command, args []string) error {
+		for _, arg := range args {
+			if _, err := strconv.Atoi(arg); err != nil {
+				return fmt.Errorf("invalid request ID %q: expected a number from 'termplate outbox list'", arg)
+			}
+		}
+		return nil
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids := make([]int, len(args))
+		for i, arg := range args {
+			ids[i], _ = strconv.Atoi(arg)
+		}
+
+		h := handler.NewOutboxHandler()
+		n, err := h.Clear(cmd.Context(), ids)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dropped %d request(s)\n", n)
+		return nil
+	},
+}
@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var flushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Send the queued requests",
+	Long: `Send the queued requests to the API, in the order they were made.
+
+Each request is dropped from the outbox once the API accepts it. Sending
+stops at the first request that fails, so none is applied ahead of an
+earlier one; it stays queued with its error, shown by 'termplate outbox
+list', to be sent again or dropped with 'termplate outbox clear <id>'.
+
+Examples:
+  termplate outbox flush
+  termplate outbox flush -o json`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runFlush(cmd.Context())
+	},
+}
+
+func runFlush(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewOutboxHandler()
+	out, err := h.Flush(ctx, cfg.API)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := output.NewFormatter(cfg.Output).Print(out); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+	} else if !cfg.Output.Quiet {
+		fmt.Printf("Sent %d request(s)\n", out.Sent)
+	}
+
+	if out.Failed != nil {
+		return fmt.Errorf("request %d (%s %s) failed: %s; %d request(s) left in the outbox",
+			out.Failed.ID, out.Failed.Method, out.Failed.URL, out.Failed.LastError, out.Pending)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the queued requests, oldest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runList(cmd.Context())
+	},
+}
+
+func runList(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewOutboxHandler()
+	entries, err := h.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing outbox: %w", err)
+	}
+
+	if cfg.Output.Format == "text" {
+		if len(entries) == 0 && !cfg.Output.Quiet {
+			fmt.Fprintln(os.Stderr, "The outbox is empty")
+			return nil
+		}
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(entries); err != nil {
+		return fmt.Errorf("printing outbox: %w", err)
+	}
+	return nil
+}
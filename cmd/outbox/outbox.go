@@ -0,0 +1,26 @@
+package outbox
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for requests queued while offline
+var Cmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Manage API requests queued while offline",
+	Long: `Commands for the outbox: API requests that change things (POST, PUT,
+PATCH, DELETE) made while offline or while the API couldn't be reached,
+queued to be sent later in the order they were made.
+
+Queuing is on with api.outbox: true in the config. Queued requests are
+sent ahead of the next request that changes something once the API can be
+reached, or with 'termplate outbox flush'. Each is sent with an
+Idempotency-Key header, so one the API already applied isn't applied again.
+
+Queued requests are stored, without credentials, in
+$XDG_STATE_HOME/termplate/outbox.json.`,
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(flushCmd)
+	Cmd.AddCommand(clearCmd)
+}
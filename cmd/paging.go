@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/pages"
+)
+
+// pageFlags are left out of the command line a page token continues, so
+// the next page can be asked for with another --page-size
+var pageFlags = []string{"--next", "--page-size"}
+
+// startPaging prints cmd's list output a page of size rows at a time,
+// from the page --next continues when it's set. --page-size overrides the
+// size the token was issued for.
+func startPaging(cmd *cobra.Command, size int) error {
+	if size < 0 {
+		return model.NewValidationError("page-size", "must not be negative")
+	}
+	offset := 0
+	if nextPage != "" {
+		t, err := pages.Load(nextPage)
+		if errors.Is(err, pages.ErrUnknownToken) {
+			return model.NewValidationError("next", fmt.Sprintf("%s: %s (tokens last a day)", err, nextPage))
+		}
+		if err != nil {
+			return fmt.Errorf("loading page token: %w", err)
+		}
+		if args := pageArgs(); !slices.Equal(t.Args, args) {
+			return model.NewValidationError("next", fmt.Sprintf("token %s continues %q, not this command", nextPage, pageCommand(cmd.Root().Name(), t.Args)))
+		}
+		offset = t.Offset
+		if !cmd.Flags().Changed("page-size") {
+			size = t.Size
+		}
+	}
+	if size > 0 {
+		formatter.StartChunk(offset, size)
+	}
+	return nil
+}
+
+// finishPaging stops paging output and, when the command succeeded with
+// rows left for another page, saves a token for it and says how to print
+// it
+func finishPaging(ctx context.Context, cmdErr error) {
+	c, ok := formatter.EndChunk()
+	if !ok || !c.More || cmdErr != nil {
+		return
+	}
+	args := pageArgs()
+	t, err := pages.Save(context.WithoutCancel(ctx), args, c.Offset+c.Size, c.Size)
+	if err != nil {
+		slog.Warn("saving page token", "error", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Rows %d-%d shown; next page: %s --next %s\n",
+		c.Offset+1, c.Offset+c.Shown, pageCommand(rootCmd.Name(), args), t.ID)
+}
+
+// pageArgs returns the command line being run without the page flags
+func pageArgs() []string {
+	args := cmdArgs
+	if args == nil {
+		args = os.Args[1:]
+	}
+	out := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if slices.Contains(pageFlags, arg) {
+			i++ // And its value
+			continue
+		}
+		if name, _, ok := strings.Cut(arg, "="); ok && slices.Contains(pageFlags, name) {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// pageCommand returns args as a command line of program to run, quoting
+// those the shell would split or expand
+func pageCommand(program string, args []string) string {
+	words := []string{program}
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		words = append(words, arg)
+	}
+	return strings.Join(words, " ")
+}
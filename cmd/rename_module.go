@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	renameDir      string
+	renameOldPaths []string
+	renameBinary   string
+	renameDryRun   bool
+)
+
+var renameModuleCmd = &cobra.Command{
+	Use:   "rename-module <new-path>",
+	Short: "Change the module path and binary name of a project",
+	Long: `Rewrite a project's module path everywhere it appears: go.mod, import
+paths, docs, and build files. Imports that point into the project under
+another path (left over from a fork or copy) are detected and rewritten
+too; add more with --old-path.
+
+With --binary-name, the binary is renamed consistently as well: the root
+command's Use string, command examples in help text, the environment
+variable prefix (TERMPLATE_ becomes MYTOOL_), config file names, and
+BINARY_NAME in the Makefile.
+
+Run 'go build ./...' afterwards to check the result.
+
+Examples:
+  termplate rename-module github.com/me/mytool --dry-run
+  termplate rename-module github.com/me/mytool --binary-name mytool
+  termplate rename-module github.com/me/mytool --old-path github.com/someone/oldname`,
+
+	Args: cobra.ExactArgs(1),
+
+	Annotations: map[string]string{lock.Annotation: "rename-module"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRenameModule(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	renameModuleCmd.Flags().StringVar(&renameDir, "dir", ".", "project directory")
+	renameModuleCmd.Flags().StringArrayVar(&renameOldPaths, "old-path", nil, "additional import path to rewrite (repeatable)")
+	renameModuleCmd.Flags().StringVar(&renameBinary, "binary-name", "", "new binary name")
+	renameModuleCmd.Flags().BoolVar(&renameDryRun, "dry-run", false, "show what would change without writing files")
+}
+
+func runRenameModule(ctx context.Context, newPath string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewScaffoldHandler()
+	result, err := h.RenameModule(ctx, handler.RenameModuleInput{
+		Dir:      renameDir,
+		NewPath:  newPath,
+		OldPaths: renameOldPaths,
+		Binary:   renameBinary,
+		DryRun:   renameDryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := formatter.NewFormatter(cfg.Output).Print(result); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Module: %s -> %s\n", result.OldPath, result.NewPath)
+	for _, alias := range result.Aliases {
+		fmt.Printf("Also rewriting: %s\n", alias)
+	}
+	if result.NewBinary != "" {
+		fmt.Printf("Binary: %s -> %s\n", result.OldBinary, result.NewBinary)
+	}
+	if len(result.Files) == 0 {
+		fmt.Println("Nothing to change")
+		return nil
+	}
+
+	table := [][]string{{"File", "Changes", "Renamed To"}}
+	for _, f := range result.Files {
+		table = append(table, []string{f.Path, fmt.Sprint(f.Changes), f.NewPath})
+	}
+	cfg.Output.Format = "table"
+	if err := formatter.NewFormatter(cfg.Output).Print(table); err != nil {
+		return fmt.Errorf("printing changes: %w", err)
+	}
+	if renameDryRun {
+		fmt.Println("\nDry run: no files were changed")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package resources
+
+import (
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+// NewLoader builds the config loader from the command line, for shell
+// completion, which runs without one; the root command sets it
+var NewLoader func(*pflag.FlagSet) (*config.Loader, error)
+
+// completeNames completes the names of the live resources of the kind in
+// args[0], leaving out those already given
+func completeNames(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 || NewLoader == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	loader, err := NewLoader(cmd.Flags())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, err := handler.NewResourcesHandler().Names(cmd.Context(), cfg, args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		return slices.Contains(args[1:], name)
+	}), cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,107 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/budget"
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var (
+	deleteSelector        string
+	deleteDryRun          bool
+	deleteContinueOnError bool
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <kind> [name...]",
+	Short: "Delete resources by name or label",
+	Long: `Delete the named resources of a kind, or those --selector matches. One
+already gone counts as deleted. Exits with code 3 when any deletion fails.
+
+Examples:
+  termplate resources delete users ana bob
+  termplate resources delete users -l env=staging --dry-run
+  termplate resources delete users -l 'team in (old,legacy)'`,
+
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if (len(args) > 1) == (deleteSelector != "") {
+			return fmt.Errorf("give either names or --selector")
+		}
+		return nil
+	},
+	ValidArgsFunction: completeNames,
+
+	// Shares apply's lock, as both change the resources and the specs they
+	// were last applied with
+	Annotations: map[string]string{
+		lock.Annotation:                "apply",
+		introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure, model.ExitOverBudget),
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDelete(cmd.Context(), args[0], args[1:])
+	},
+}
+
+func init() {
+	deleteCmd.Flags().StringVarP(&deleteSelector, "selector", "l", "", "delete the resources whose labels match, e.g. env=staging")
+	deleteCmd.Flags().BoolVar(&deleteDryRun, "dry-run", false, "list the resources without deleting them")
+	deleteCmd.Flags().BoolVar(&deleteContinueOnError, "continue-on-error", true, "keep deleting after a deletion fails")
+}
+
+func runDelete(ctx context.Context, kind string, names []string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewResourcesHandler()
+	if deleteSelector != "" {
+		entries, err := h.Get(ctx, cfg.API, kind, nil, deleteSelector)
+		if err != nil {
+			return fmt.Errorf("selecting %s: %w", kind, err)
+		}
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Printf("No %s match %s\n", kind, deleteSelector)
+		return nil
+	}
+	if deleteDryRun {
+		for _, name := range names {
+			fmt.Printf("%s/%s would be deleted\n", kind, name)
+		}
+		return nil
+	}
+
+	report, err := h.Delete(ctx, cfg.API, kind, names, bulk.Options{
+		ContinueOnError: deleteContinueOnError,
+		Budget:          budget.FromConfig(cfg.Budget),
+	})
+	if err != nil {
+		return err
+	}
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+		if err := output.NewFormatter(cfg.Output).Print(report.Table()); err != nil {
+			return fmt.Errorf("printing results: %w", err)
+		}
+	} else if err := output.NewFormatter(cfg.Output).Print(report); err != nil {
+		return fmt.Errorf("printing results: %w", err)
+	}
+	return report.Err()
+}
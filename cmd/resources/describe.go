@@ -0,0 +1,72 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <kind> <name>",
+	Short: "Show a resource in full",
+	Long: `Show one live resource in detail: its metadata, spec, status, and most
+recent events. Events are read from api.events_path, when the API has
+them.
+
+Examples:
+  termplate resources describe users ana
+  termplate resources describe users ana -o yaml`,
+
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 1 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeNames(cmd, args, toComplete)
+	},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDescribe(cmd.Context(), args[0], args[1])
+	},
+}
+
+func runDescribe(ctx context.Context, kind, name string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewResourcesHandler()
+	detail, err := h.Describe(ctx, cfg.API, kind, name)
+	if err != nil {
+		return fmt.Errorf("describing %s/%s: %w", kind, name, err)
+	}
+
+	if err := output.NewFormatter(cfg.Output).Print(describe(detail)); err != nil {
+		return fmt.Errorf("printing %s/%s: %w", kind, name, err)
+	}
+	return nil
+}
+
+// describe lays out a resource's detail in sections
+func describe(d *handler.ResourceDetail) *output.Description {
+	return &output.Description{
+		Name: d.Kind + "/" + d.Name,
+		Sections: []output.Section{
+			{Title: "Metadata", Value: struct {
+				Kind        string            `json:"kind"`
+				Name        string            `json:"name"`
+				Labels      map[string]string `json:"labels"`
+				Annotations map[string]string `json:"annotations"`
+			}{d.Kind, d.Name, d.Labels, d.Annotations}},
+			{Title: "Spec", Value: d.Spec},
+			{Title: "Status", Value: d.Status},
+			{Title: "Events", Value: d.Events},
+		},
+	}
+}
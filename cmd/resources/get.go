@@ -0,0 +1,66 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var getSelector string
+
+var getCmd = &cobra.Command{
+	Use:     "get <kind> [name...]",
+	Aliases: []string{"list"},
+	Short:   "List resources in a compact table",
+	Long: `List the live resources of a kind, or the named ones, one row each with
+their labels; --selector keeps those whose labels match. 'resources
+describe' shows one in full.
+
+Examples:
+  termplate resources get users
+  termplate resources get users ana bob
+  termplate resources get users -l 'env=prod,team!=core'
+  termplate resources get users -l 'tier in (web,api)' -o json`,
+
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeNames,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGet(cmd.Context(), args[0], args[1:])
+	},
+}
+
+func init() {
+	getCmd.Flags().StringVarP(&getSelector, "selector", "l", "", "only resources whose labels match, e.g. env=prod,team!=core")
+}
+
+func runGet(ctx context.Context, kind string, names []string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewResourcesHandler()
+	entries, err := h.Get(ctx, cfg.API, kind, names, getSelector)
+	if err != nil {
+		return fmt.Errorf("getting %s: %w", kind, err)
+	}
+
+	if cfg.Output.Format == "text" {
+		if len(entries) == 0 && !cfg.Output.Quiet {
+			fmt.Fprintf(os.Stderr, "No %s found\n", kind)
+			return nil
+		}
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(entries); err != nil {
+		return fmt.Errorf("printing %s: %w", kind, err)
+	}
+	return nil
+}
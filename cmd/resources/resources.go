@@ -0,0 +1,28 @@
+package resources
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for the API resources apply manages
+var Cmd = &cobra.Command{
+	Use:   "resources",
+	Short: "Get, describe, and delete API resources by kind and label",
+	Long: `Commands for the API resources that 'termplate apply' manages, found at
+api.base_url + api.resource_path like it. 'get' lists them in a compact
+table and 'describe' shows one in full.
+
+A resource's labels are its labels field (or metadata.labels), set from
+metadata.labels in the files apply reads. --selector picks resources by
+them, with requirements separated by commas:
+
+  env=prod          env==prod         team!=core
+  tier in (web,api) tier notin (batch)
+  canary            !deprecated       (the label is set, or isn't)
+
+The same selectors work with 'termplate apply --selector'.`,
+}
+
+func init() {
+	Cmd.AddCommand(getCmd)
+	Cmd.AddCommand(describeCmd)
+	Cmd.AddCommand(deleteCmd)
+}
@@ -2,24 +2,76 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 
+	"github.com/blacksilver/termplate-go/cmd/auth"
+	"github.com/blacksilver/termplate-go/cmd/cache"
+	"github.com/blacksilver/termplate-go/cmd/completion"
+	configcmd "github.com/blacksilver/termplate-go/cmd/config"
+	"github.com/blacksilver/termplate-go/cmd/cron"
+	daemoncmd "github.com/blacksilver/termplate-go/cmd/daemon"
+	"github.com/blacksilver/termplate-go/cmd/env"
 	"github.com/blacksilver/termplate-go/cmd/example"
+	"github.com/blacksilver/termplate-go/cmd/files"
+	"github.com/blacksilver/termplate-go/cmd/generate"
+	"github.com/blacksilver/termplate-go/cmd/ops"
+	"github.com/blacksilver/termplate-go/cmd/outbox"
+	"github.com/blacksilver/termplate-go/cmd/resources"
+	"github.com/blacksilver/termplate-go/cmd/scaffold"
+	"github.com/blacksilver/termplate-go/cmd/stats"
+	"github.com/blacksilver/termplate-go/cmd/template"
+	"github.com/blacksilver/termplate-go/internal/apiclient"
 	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/grpcclient"
+	"github.com/blacksilver/termplate-go/internal/limits"
+	"github.com/blacksilver/termplate-go/internal/lock"
 	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/prompt"
+	"github.com/blacksilver/termplate-go/internal/retry"
+	"github.com/blacksilver/termplate-go/internal/throttle"
+	"github.com/blacksilver/termplate-go/internal/usage"
+	"github.com/blacksilver/termplate-go/pkg/version"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	output  string
+	cfgFiles []string
+	verbose  bool
+	output   string
+
+	noColor     bool
+	noPager     bool
+	noCache     bool
+	outputFile  string
+	appendFile  bool
+	waitLock    time.Duration
+	timeout     time.Duration
+	stopTimeout context.CancelFunc // Releases the --timeout context
+	offlineMode bool
+	showStats   bool
+	metrics     *apiclient.Metrics // Set with --stats
+	grpcMetrics *grpcclient.Metrics
+	heldLock    *lock.Lock
+	usageCmd    *cobra.Command // Set when the running command's usage is recorded
+	running     bool           // Set once the command line is accepted
+	outFile     *formatter.File
+	stdout      *os.File // Standard output while outFile replaces it
+	nextPage    string   // --next: token of the page of output to print
+	cmdArgs     []string // Command line being run, without the program name
 )
 
 var rootCmd = &cobra.Command{
@@ -40,8 +92,16 @@ Examples:
 
 	// Runs before any subcommand
 	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
-		// Skip for completion and help
-		if cmd.Name() == "completion" || cmd.Name() == "help" {
+		// Cobra has parsed the flags and checked the arguments, but checks
+		// required flags only after this hook; check them here so they're
+		// reported as usage errors too
+		if err := flags.Validate(cmd); err != nil {
+			return err
+		}
+		running = true
+
+		// Skip for completion scripts and help
+		if cmd == completion.Cmd || cmd.Name() == "help" {
 			return nil
 		}
 
@@ -52,44 +112,414 @@ Examples:
 		}
 		logger.Init(level, os.Getenv("ENV") == "production")
 
-		// Bind flags to viper
-		if err := viper.BindPFlags(cmd.Flags()); err != nil {
-			return fmt.Errorf("binding flags: %w", err)
+		loader, err := newLoader(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		cmd.SetContext(config.WithContext(cmd.Context(), loader))
+		errorFormat = loader.GetString("output.format")
+		if loader.GetBool("usage_stats") {
+			usageCmd = cmd
+		}
+
+		// Reject a bad --query before the command does any work
+		if q := loader.GetString("output.query"); q != "" {
+			if _, err := formatter.CompileQuery(q); err != nil {
+				return err
+			}
+		}
+
+		if expr := loader.GetString("output.filter"); expr != "" {
+			if _, err := formatter.CompileFilter(expr); err != nil {
+				return err
+			}
+		}
+
+		for _, def := range loader.GetStringSlice("output.add_columns") {
+			if _, err := formatter.CompileColumn(def); err != nil {
+				return err
+			}
+		}
+
+		if _, err := formatter.CompileGroupBy(loader.GetString("output.group_by"), loader.GetString("output.aggregates")); err != nil {
+			return err
+		}
+
+		if s := loader.GetString("output.sort_by"); s != "" {
+			if _, err := formatter.ParseSortBy(s); err != nil {
+				return err
+			}
+		}
+
+		if err := formatter.SetTheme(loader.GetString("output.theme")); err != nil {
+			return err
+		}
+
+		if format := loader.GetString("output.format"); formatter.IsTemplateFormat(format) {
+			if _, err := formatter.ParseTemplate(format); err != nil {
+				return err
+			}
+		}
+
+		if loader.GetBool("output.envelope") {
+			formatter.StartEnvelope(formatter.EnvelopeOptions{
+				Command:   cmd.CommandPath(),
+				Start:     time.Now(),
+				Timestamp: loader.GetBool("output.timestamp"),
+				Format:    loader.GetString("output.format"),
+				Pretty:    loader.GetBool("output.pretty"),
+			})
+		}
+
+		// --page-size prints lists a page at a time, continued with --next
+		if err := startPaging(cmd, loader.GetInt("output.page_size")); err != nil {
+			return err
+		}
+
+		// The flag is bound, so config and TERMPLATE_OFFLINE work too
+		offline.Set(loader.GetBool("offline"))
+
+		// Only the settings applied here are decoded (see startupKeys). A
+		// config that doesn't decode is reported by the command that needs
+		// it (or config doctor); until then the defaults apply here.
+		cfg, err := loader.LoadKeys(startupKeys...)
+		if err != nil {
+			slog.Debug("using default runtime settings", "error", err)
+			if cfg, err = config.NewLoader().LoadKeys(startupKeys...); err != nil {
+				return err
+			}
+		}
+		limits.Apply(cfg.Runtime)
+		throttle.Apply(cfg.Transfer)
+		events.Configure(events.Options{Size: cfg.Events.Size, Persist: cfg.Events.Persist})
+
+		// Transient failures are retried per api.retry_*, then the user is
+		// asked, unless --auto-retry answers for them
+		opts := retry.Options{
+			Attempts: cfg.API.RetryAttempts,
+			Delay:    cfg.API.RetryDelay,
+			MaxDelay: cfg.API.RetryMaxDelay,
+			Statuses: cfg.API.RetryStatuses,
+			Auto:     cfg.AutoRetry,
+		}
+		if prompt.IsInteractive() {
+			opts.Prompter = prompt.NewStdio()
+		}
+		cmd.SetContext(retry.WithContext(cmd.Context(), retry.New(opts)))
+
+		// Requests are timed for the summary --stats prints at the end
+		if showStats {
+			metrics, grpcMetrics = &apiclient.Metrics{}, &grpcclient.Metrics{}
+			cmd.SetContext(apiclient.WithMetrics(cmd.Context(), metrics))
+			cmd.SetContext(grpcclient.WithMetrics(cmd.Context(), grpcMetrics))
+		}
+
+		// --timeout covers everything the command does from here on
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout, model.ErrTimeout)
+			cmd.SetContext(ctx)
+			stopTimeout = cancel
+		}
+
+		// Commands that modify shared state opt in to a single-instance lock
+		if name := cmd.Annotations[lock.Annotation]; name != "" {
+			l, err := lock.Acquire(cmd.Context(), name, waitLock)
+			if err != nil {
+				return fmt.Errorf("acquiring lock: %w", err)
+			}
+			heldLock = l
+		}
+
+		// Everything the command prints goes to --output-file
+		if appendFile && outputFile == "" {
+			return model.NewValidationError("append", "--append requires --output-file")
+		}
+		if outputFile != "" {
+			f, err := formatter.CreateFile(outputFile, appendFile)
+			if err != nil {
+				return err
+			}
+			outFile, stdout = f, os.Stdout
+			os.Stdout = f.File
 		}
 
 		return nil
 	},
 
+	// Release the lock and commit the output file here too when mounted in
+	// another program, whose Execute doesn't go through ours
+	PersistentPostRunE: func(*cobra.Command, []string) error {
+		releaseLock()
+		stopRunTimeout()
+		return finishOutput(nil)
+	},
+
 	SilenceUsage:  true, // Don't show usage on error
 	SilenceErrors: true, // We handle errors ourselves
 }
 
 // Execute is the entry point called from main
 func Execute() error {
-	// Set up context with signal handling
-	ctx, cancel := signal.NotifyContext(
-		context.Background(),
-		syscall.SIGINT,
-		syscall.SIGTERM,
-	)
+	ctx, cancel := interruptContext()
 	defer cancel()
 
-	if err := rootCmd.ExecuteContext(ctx); err != nil {
+	args := os.Args[1:]
+	if err := dispatch(ctx, args); !errors.Is(err, errNotDispatched) {
+		errorSettings(args)
+		return model.CancelCause(ctx, err)
+	}
+	return ExecuteContext(ctx, args)
+}
+
+// interruptContext returns a context canceled with model.ErrInterrupted on
+// SIGINT or SIGTERM. The signal is then reset, so a second one kills a
+// command that doesn't stop.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigs:
+			cancel(model.ErrInterrupted)
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+var errNotDispatched = errors.New("not dispatched")
+
+// dispatch runs args on a running daemon. It returns errNotDispatched when
+// the command must run in this process: there is no daemon or it runs
+// another version, or the command is marked local.
+func dispatch(ctx context.Context, args []string) error {
+	if os.Getenv(daemon.EnvDisable) != "" {
+		return errNotDispatched
+	}
+	c, _, err := rootCmd.Find(args)
+	if err != nil || c == rootCmd {
+		return errNotDispatched
+	}
+	for ; c != nil; c = c.Parent() {
+		if c.Annotations[daemon.LocalAnnotation] != "" {
+			return errNotDispatched
+		}
+	}
+
+	socket, err := daemon.SocketPath()
+	if err != nil {
+		return errNotDispatched
+	}
+	err = daemon.Run(ctx, socket, version.Version, args, os.Stdout, os.Stderr)
+	if errors.Is(err, daemon.ErrNotRunning) || errors.Is(err, daemon.ErrVersionMismatch) {
+		return errNotDispatched
+	}
+	return err
+}
+
+// ExecuteContext runs the command line args (without the program name)
+// with ctx. Commands share flag variables, so calls must not overlap.
+func ExecuteContext(ctx context.Context, args []string) error {
+	// heldLock is set once the command starts running
+	defer releaseLock()
+
+	resetFlags(rootCmd)
+	resetContexts(rootCmd)
+	usageCmd, running, errorFormat, metrics, grpcMetrics = nil, false, "", nil, nil
+	cmdArgs = args
+	rootCmd.SetArgs(args)
+	start := time.Now()
+	c, err := rootCmd.ExecuteContextC(ctx)
+	if err != nil && !running {
+		errorSettings(args)
+		err = usageError(c, args, err)
+	}
+	// Say why a canceled command stopped; the command's context carries
+	// --timeout, ctx an interrupt
+	if c != nil && c.Context() != nil {
+		err = model.CancelCause(c.Context(), err)
+	}
+	err = model.CancelCause(ctx, err)
+	stopRunTimeout()
+	// Output a command left held, e.g. by a stream it didn't end, goes
+	// before the envelope and into --output-file
+	if ferr := formatter.FlushAll(); err == nil {
+		err = ferr
+	}
+	finishPaging(ctx, err)
+	// Before finishOutput, as the envelope goes to --output-file too
+	if eerr := formatter.WriteEnvelope(os.Stdout, model.ExitCode(err), err); err == nil {
+		err = eerr
+	}
+	if ferr := finishOutput(err); err == nil {
+		err = ferr
+	}
+	recordUsage(ctx, time.Since(start), err)
+	printStats()
+	if err != nil {
 		return fmt.Errorf("executing command: %w", err)
 	}
 	return nil
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
+// recordUsage adds the command that ran to the local usage stats. Commands
+// that failed before starting, such as on a bad flag, aren't recorded, and
+// neither are those marked with usage.SkipAnnotation.
+func recordUsage(ctx context.Context, d time.Duration, err error) {
+	if usageCmd == nil {
+		return
+	}
+	for c := usageCmd; c != nil; c = c.Parent() {
+		if c.Annotations[usage.SkipAnnotation] != "" {
+			return
+		}
+	}
+	// An interrupted command is still recorded
+	ctx = context.WithoutCancel(ctx)
+	if err := usage.Record(ctx, usageCmd.CommandPath(), d, err != nil); err != nil {
+		slog.Debug("recording usage", "error", err)
+	}
+}
 
+// printStats prints the --stats summary to stderr, when the command made
+// any requests or gRPC calls
+func printStats() {
+	if metrics == nil {
+		return
+	}
+	if s := metrics.Summary(); s.Requests > 0 {
+		fmt.Fprint(os.Stderr, s)
+	}
+	if s := grpcMetrics.Summary(); s.Calls > 0 {
+		fmt.Fprint(os.Stderr, s)
+	}
+}
+
+// Root returns the root command, so other programs can mount it under
+// their own or add commands to it (see pkg/termplate)
+func Root() *cobra.Command {
+	return rootCmd
+}
+
+// resetFlags puts every flag back to its default, so values from an
+// earlier ExecuteContext call in the same process don't leak into the next
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.PersistentFlags().VisitAll(reset)
+	c.Flags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// resetContexts drops the contexts commands kept from the last command
+// line, which cobra would otherwise give, canceled, to the next one
+func resetContexts(c *cobra.Command) {
+	c.SetContext(nil)
+	for _, sub := range c.Commands() {
+		resetContexts(sub)
+	}
+}
+
+// stopRunTimeout releases the --timeout context once the command is done
+func stopRunTimeout() {
+	if stopTimeout != nil {
+		stopTimeout()
+		stopTimeout = nil
+	}
+}
+
+func releaseLock() {
+	heldLock.Release()
+	heldLock = nil
+}
+
+// finishOutput restores standard output and, unless the command failed,
+// moves its output into --output-file. A failed command leaves the file as
+// it was (except for output already appended); a partial failure's report
+// is still written.
+func finishOutput(cmdErr error) error {
+	if outFile == nil {
+		return nil
+	}
+	f := outFile
+	os.Stdout, outFile, stdout = stdout, nil, nil
+	if cmdErr != nil && !errors.Is(cmdErr, model.ErrPartialFailure) {
+		f.Abort()
+		return nil
+	}
+	return f.Commit()
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	return model.ExitCode(err)
+}
+
+// startupKeys are the settings applied before every command. The rest of
+// the config, encrypted credentials and all, is decoded by the commands
+// that use it, so one like version starts as fast with an API and a
+// database configured as without.
+var startupKeys = []string{
+	"runtime",
+	"transfer",
+	"events",
+	"auto_retry",
+	"api.retry_attempts",
+	"api.retry_delay",
+	"api.retry_max_delay",
+	"api.retry_statuses",
+}
+
+// flagKeys maps flag names to config keys where they differ
+var flagKeys = map[string]string{
+	"output":              "output.format",
+	"confirm-over-budget": "budget.confirm",
+	"query":               "output.query",
+	"sort-by":             "output.sort_by",
+	"filter":              "output.filter",
+	"add-column":          "output.add_columns",
+	"group-by":            "output.group_by",
+	"agg":                 "output.aggregates",
+	"auto-retry":          "auto_retry",
+	"quiet":               "output.quiet",
+	"unordered":           "output.unordered",
+	"page-size":           "output.page_size",
+}
+
+func init() {
 	// Persistent flags (available to all subcommands)
-	rootCmd.PersistentFlags().StringVarP(
-		&cfgFile,
+	rootCmd.PersistentFlags().StringSliceVarP(
+		&cfgFiles,
 		"config", "c",
+		nil,
+		"config files or config.d directories, merged in order with later ones winning; repeat or separate with commas (default: $HOME/.termplate.yaml)",
+	)
+	rootCmd.PersistentFlags().String(
+		"profile",
 		"",
-		"config file (default: $HOME/.termplate.yaml)",
+		"config profile to merge over the config file, from its profiles section",
 	)
+	// Completed from the config file the other flags point to
+	_ = rootCmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		loader, err := newLoader(cmd.Flags())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return loader.Profiles(), cobra.ShellCompDirectiveNoFileComp
+	})
 	rootCmd.PersistentFlags().BoolVarP(
 		&verbose,
 		"verbose", "v",
@@ -100,41 +530,190 @@ func init() {
 		&output,
 		"output", "o",
 		"text",
-		"output format (text, json, yaml)",
+		"output format (text, json, ndjson, yaml, table, csv, tsv, html, xml, go-template=TEMPLATE)",
+	)
+	// Completed from the registry, so formats added with
+	// formatter.RegisterFormat are offered too
+	_ = rootCmd.RegisterFlagCompletionFunc("output", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return append(formatter.Formats(), "go-template="), cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCmd.PersistentFlags().StringP(
+		"query", "q",
+		"",
+		"filter and project structured output, jq style (e.g. '.items[].name')",
+	)
+	rootCmd.PersistentFlags().String(
+		"filter",
+		"",
+		"keep only rows matching an expression (e.g. 'status==running && age>3d')",
+	)
+	rootCmd.PersistentFlags().StringArray(
+		"add-column",
+		nil,
+		"add a column computed from the others, name=expression (e.g. 'mib=round(size/1048576, 1)'); repeatable",
+	)
+	rootCmd.PersistentFlags().String(
+		"group-by",
+		"",
+		"summarize rows into one per group of these columns (e.g. 'region,kind'); see --agg",
+	)
+	rootCmd.PersistentFlags().String(
+		"agg",
+		"",
+		"aggregates computed for each group: count, distinct, sum, avg, min, max (e.g. 'count,total=sum(size)')",
+	)
+	rootCmd.PersistentFlags().String(
+		"sort-by",
+		"",
+		"sort table, csv, tsv, and html rows by columns (e.g. 'size:desc,name')",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"quiet",
+		false,
+		"print only the IDs or names of the results, one per line, for piping into xargs",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"unordered",
+		false,
+		"print results of parallel work as each finishes instead of in input order",
+	)
+	rootCmd.PersistentFlags().Int(
+		"page-size",
+		0,
+		"print lists this many rows at a time, saying how to print the next page with --next",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&nextPage,
+		"next",
+		"",
+		"print the page of output after the one that issued this token (see --page-size)",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&noColor,
+		"no-color",
+		false,
+		"disable colored output (also NO_COLOR=1)",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&noPager,
+		"no-pager",
+		false,
+		"write long output directly instead of through $PAGER",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&noCache,
+		"no-cache",
+		false,
+		"send API requests without using or filling the response cache",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&outputFile,
+		"output-file",
+		"",
+		"write output to this file instead of stdout, replacing it only when the command succeeds",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&appendFile,
+		"append",
+		false,
+		"append to --output-file instead of replacing it",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&offlineMode,
+		"offline",
+		false,
+		"never access the network; use cached templates and fail fast otherwise",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"auto-retry",
+		false,
+		"retry operations that fail with transient errors without asking",
+	)
+	rootCmd.PersistentFlags().Bool(
+		"confirm-over-budget",
+		false,
+		"allow operations that exceed the configured budget limits",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&showStats,
+		"stats",
+		false,
+		"print a summary of network timings (DNS, connect, TLS, first byte, and gRPC calls) to stderr at the end",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&timeout,
+		"timeout",
+		0,
+		"stop the command if it runs longer than this (e.g. 5m); exits with code 124",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&waitLock,
+		"wait-lock",
+		0,
+		"wait up to this long for another running instance to finish (e.g. 30s)",
 	)
 
+	rootCmd.SetFlagErrorFunc(flagError)
+	daemoncmd.Execute = ExecuteContext
+	resources.NewLoader = newLoader
+
 	// Add subcommands
 	rootCmd.AddCommand(versionCmd)
-	rootCmd.AddCommand(completionCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(auth.Cmd)
+	rootCmd.AddCommand(cache.Cmd)
+	rootCmd.AddCommand(completion.Cmd)
+	rootCmd.AddCommand(configcmd.Cmd)
+	rootCmd.AddCommand(cron.Cmd)
+	rootCmd.AddCommand(daemoncmd.Cmd)
+	rootCmd.AddCommand(env.Cmd)
+	rootCmd.AddCommand(eventsCmd)
 	rootCmd.AddCommand(example.Cmd)
+	rootCmd.AddCommand(files.Cmd)
+	rootCmd.AddCommand(generate.Cmd)
+	rootCmd.AddCommand(introspectCmd)
+	rootCmd.AddCommand(outbox.Cmd)
+	rootCmd.AddCommand(ops.Cmd)
+	rootCmd.AddCommand(resources.Cmd)
+	rootCmd.AddCommand(scaffold.Cmd)
+	rootCmd.AddCommand(stats.Cmd)
+	rootCmd.AddCommand(template.Cmd)
+	rootCmd.AddCommand(renameModuleCmd)
+	rootCmd.AddCommand(upgradeCmd)
 }
 
-func initConfig() {
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
+// newLoader builds the config loader for this invocation from the config
+// file, TERMPLATE_* environment variables, and flags
+func newLoader(fs *pflag.FlagSet) (*config.Loader, error) {
+	loader := config.NewLoader().WithEnv("TERMPLATE")
+	if len(cfgFiles) > 0 {
+		loader.WithFiles(cfgFiles...)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		loader.WithSearchPaths(".ever-so-powerful-go", home, ".")
 	} else {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			slog.Error("failed to get home directory", "error", err)
-			return
-		}
-
-		// Search paths
-		viper.AddConfigPath(home)
-		viper.AddConfigPath(".")
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".ever-so-powerful-go")
+		slog.Error("failed to get home directory", "error", err)
 	}
 
-	// Environment variables
-	viper.SetEnvPrefix("TERMPLATE")
-	viper.AutomaticEnv()
+	if err := loader.BindFlags(fs, flagKeys); err != nil {
+		return nil, fmt.Errorf("binding flags: %w", err)
+	}
 
-	// Set defaults
-	config.SetDefaults()
+	// --no-color, --no-pager, and --no-cache are the inverses of their
+	// settings, so they can't be bound
+	if noColor {
+		loader.Set("output.color", false)
+	}
+	if noPager {
+		loader.Set("output.pager", false)
+	}
+	if noCache {
+		loader.Set("api.cache", false)
+	}
 
-	// Read config (ignore if not found)
-	if err := viper.ReadInConfig(); err == nil {
-		slog.Debug("using config file", "file", viper.ConfigFileUsed())
+	// Read errors are left to the commands that load the config, so
+	// config doctor can still report them
+	if err := loader.Read(); err != nil {
+		slog.Debug("config file not read", "error", err)
 	}
+	return loader, nil
 }
@@ -0,0 +1,60 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+var projectDir string
+
+var commandCmd = &cobra.Command{
+	Use:   "command <name>",
+	Short: "Generate a new command in an existing project",
+	Long: `Generate the files for a new command into an existing Go project.
+
+Built-in variables:
+  Module          module path read from go.mod
+  CommandName     the command name, e.g. fetch-data
+  CommandPackage  the name without hyphens, e.g. fetchdata
+
+Examples:
+  termplate new command fetch-data --from ./templates/command
+  termplate new command fetch-data --from ./templates/command --dir ../mycli`,
+
+	Args: cobra.ExactArgs(1),
+
+	// Failed post-generation hooks
+	Annotations: map[string]string{introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure)},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runNewCommand(cmd.Context(), args[0])
+	},
+}
+
+func init() {
+	commandCmd.Flags().StringVar(&projectDir, "dir", ".", "project root directory")
+}
+
+func runNewCommand(ctx context.Context, name string) error {
+	h := handler.NewScaffoldHandler()
+	result, err := h.NewCommand(ctx, handler.NewCommandInput{
+		Name:            name,
+		ProjectDir:      projectDir,
+		GenerateOptions: generateOptions(),
+	})
+	if err != nil {
+		return fmt.Errorf("creating command: %w", err)
+	}
+
+	if err := printGenerated(ctx, result); err != nil {
+		return err
+	}
+	fmt.Printf("Command %s created; register it in cmd/root.go\n", name)
+	return result.HooksErr()
+}
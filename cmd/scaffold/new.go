@@ -0,0 +1,150 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/prompt"
+)
+
+// Flags shared by the generators
+var (
+	from      string
+	vars      []string
+	noInput   bool
+	force     bool
+	skipHooks bool
+	yes       bool
+	with      []string
+)
+
+// Cmd is the parent command for generators
+var Cmd = &cobra.Command{
+	Use:   "new",
+	Short: "Generate projects and commands from templates",
+	Long: `Generate projects and commands from scaffold sources.
+
+--from accepts a built-in template name (see 'termplate template list'),
+a registered template name, a local directory, or a git URL with an
+optional subdirectory and pinned ref:
+
+  https://github.com/org/starters.git//cli#v1.2.0
+
+A scaffold source is a directory of files. Files ending in .tmpl are
+rendered with Go templates and paths may contain template actions.
+An optional template.yaml manifest declares the variables the templates
+use, with defaults, prompts, validation, and choices:
+
+  name: cli
+  variables:
+    - name: Module
+      prompt: Go module path
+      required: true
+      validate: '^[a-z0-9./_-]+$'
+    - name: License
+      default: MIT
+      choices: [MIT, Apache-2.0]
+
+Values are prompted for interactively, or set with --var key=value.
+
+The manifest may also declare post-generation hooks, run in order in the
+generated directory with their output streamed:
+
+  hooks:
+    - step: go-mod-tidy        # built-in: go-mod-tidy, gofmt, git-init
+    - step: git-init
+      if: "{{ .WithGit }}"
+    - name: Install tools
+      shell: make setup        # custom shell; asks for confirmation
+
+Shell hooks are skipped without confirmation unless --yes is set.
+
+Templates can declare optional subsystems. Their files are generated only
+when selected, templates test .Subsystems.<name> for the code and config
+that use them, and imports left unused are pruned from generated Go files:
+
+  subsystems:
+    - name: server
+      default: true
+      paths: [internal/server, cmd/serve.go.tmpl]
+    - name: telemetry
+      paths: [internal/telemetry]
+      requires: ["server|pushgateway"]   # one of the alternatives
+
+Pick them with --with (or --with none); otherwise they are asked for
+interactively, or the defaults are used. Missing requirements are added.`,
+	// Generators prompt for template variables
+	Annotations: map[string]string{daemon.LocalAnnotation: "true"},
+}
+
+func init() {
+	Cmd.PersistentFlags().StringVar(&from, "from", "", "template name, directory, or git URL (default: built-in template)")
+	Cmd.PersistentFlags().StringArrayVar(&vars, "var", nil, "set a template variable (key=value, repeatable)")
+	Cmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "never prompt; use --var values and defaults")
+	Cmd.PersistentFlags().BoolVar(&force, "force", false, "overwrite existing files")
+	Cmd.PersistentFlags().BoolVar(&skipHooks, "skip-hooks", false, "don't run post-generation hooks")
+	Cmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "run shell hooks without asking for confirmation")
+	Cmd.PersistentFlags().StringSliceVar(&with, "with", nil, "subsystems to include (comma-separated, or none)")
+
+	Cmd.AddCommand(projectCmd)
+	Cmd.AddCommand(commandCmd)
+}
+
+// generateOptions builds the handler options from the shared flags
+func generateOptions() handler.GenerateOptions {
+	var subsystems []string
+	if Cmd.PersistentFlags().Changed("with") {
+		subsystems = []string{}
+		for _, name := range with {
+			if name != "none" {
+				subsystems = append(subsystems, name)
+			}
+		}
+	}
+
+	return handler.GenerateOptions{
+		From:        from,
+		Vars:        vars,
+		Interactive: !noInput && prompt.IsInteractive(),
+		Force:       force,
+		SkipHooks:   skipHooks,
+		Yes:         yes,
+		HookOutput:  os.Stdout,
+		Subsystems:  subsystems,
+	}
+}
+
+func printGenerated(ctx context.Context, out *handler.GenerateOutput) error {
+	if len(out.Subsystems) > 0 {
+		fmt.Printf("Subsystems: %s\n", strings.Join(out.Subsystems, ", "))
+	}
+	for _, note := range out.Notes {
+		fmt.Printf("  note: %s\n", note)
+	}
+	for _, f := range out.Files {
+		fmt.Printf("  created %s\n", f)
+	}
+
+	if len(out.Hooks) == 0 {
+		return nil
+	}
+
+	table := [][]string{{"Hook", "Status", "Duration", "Reason"}}
+	for _, h := range out.Hooks {
+		table = append(table, []string{h.Name, h.Status, h.Duration.String(), h.Reason})
+	}
+	fmt.Println()
+	f := output.NewFormatter(config.OutputConfig{Format: "table", TableStyle: config.FromContext(ctx).GetString("output.table_style")})
+	if err := f.Print(table); err != nil {
+		return fmt.Errorf("printing hook summary: %w", err)
+	}
+	return nil
+}
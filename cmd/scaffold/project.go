@@ -0,0 +1,73 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/introspect"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project <dir>",
+	Short: "Generate a new project",
+	Long: `Generate a new project into a directory from a scaffold source.
+
+Built-in variables:
+  ProjectName   base name of the target directory
+
+The built-in cli template can also generate build scaffolding: a Makefile
+or Taskfile, a multi-arch distroless Dockerfile, and a goreleaser config,
+all passing version ldflags to pkg/version. Choose them with the flags
+below or answer the prompts.
+
+Examples:
+  termplate new project ./mycli
+  termplate new project ./mycli --build-tool task --docker --goreleaser
+  termplate new project ./mycli --from ./templates/cli
+  termplate new project ./mycli --from ./templates/cli --var Module=github.com/me/mycli --no-input`,
+
+	Args: cobra.ExactArgs(1),
+
+	// Failed post-generation hooks
+	Annotations: map[string]string{introspect.ExitCodesAnnotation: introspect.ExitCodes(model.ExitPartialFailure)},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runNewProject(cmd.Context(), args[0])
+	},
+}
+
+var (
+	buildTool  string
+	docker     bool
+	goreleaser bool
+)
+
+func init() {
+	projectCmd.Flags().StringVar(&buildTool, "build-tool", "", "generate a build file: make, task, or none")
+	projectCmd.Flags().BoolVar(&docker, "docker", false, "generate a multi-arch distroless Dockerfile")
+	projectCmd.Flags().BoolVar(&goreleaser, "goreleaser", false, "generate a goreleaser config")
+}
+
+func runNewProject(ctx context.Context, dir string) error {
+	h := handler.NewScaffoldHandler()
+	result, err := h.NewProject(ctx, handler.NewProjectInput{
+		Dir:             dir,
+		GenerateOptions: generateOptions(),
+		BuildTool:       buildTool,
+		Docker:          docker,
+		Goreleaser:      goreleaser,
+	})
+	if err != nil {
+		return fmt.Errorf("creating project: %w", err)
+	}
+
+	if err := printGenerated(ctx, result); err != nil {
+		return err
+	}
+	fmt.Printf("Project created in %s\n", result.Dir)
+	return result.HooksErr()
+}
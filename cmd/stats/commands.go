@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var limit int
+
+var commandsCmd = &cobra.Command{
+	Use:   "commands",
+	Short: "Show the most used and slowest commands",
+	Long: `Show the most used commands and the commands with the longest average
+duration, from the usage recorded on this machine.
+
+Examples:
+  termplate stats commands
+  termplate stats commands --limit 5
+  termplate stats commands -o json`,
+
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runCommands(cmd.Context())
+	},
+}
+
+func init() {
+	commandsCmd.Flags().IntVarP(&limit, "limit", "n", 10, "commands to show in each list; 0 for all")
+}
+
+func runCommands(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewStatsHandler()
+	stats, err := h.Commands(ctx, limit)
+	if err != nil {
+		return fmt.Errorf("getting stats: %w", err)
+	}
+
+	// Quiet output lists the most used commands
+	if cfg.Output.Quiet {
+		if err := output.NewFormatter(cfg.Output).Print(stats.MostUsed); err != nil {
+			return fmt.Errorf("printing stats: %w", err)
+		}
+		return nil
+	}
+	if cfg.Output.Format != "text" && cfg.Output.Format != "table" {
+		if err := output.NewFormatter(cfg.Output).Print(stats); err != nil {
+			return fmt.Errorf("printing stats: %w", err)
+		}
+		return nil
+	}
+
+	if len(stats.MostUsed) == 0 {
+		fmt.Fprintln(os.Stderr, "No usage recorded yet")
+		return nil
+	}
+	cfg.Output.Format = "table"
+	f := output.NewFormatter(cfg.Output)
+	fmt.Println("Most used")
+	if err := f.Print(stats.MostUsed); err != nil {
+		return fmt.Errorf("printing stats: %w", err)
+	}
+	fmt.Println()
+	fmt.Println("Slowest (by average duration)")
+	if err := f.Print(stats.Slowest); err != nil {
+		return fmt.Errorf("printing stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package stats
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the recorded usage",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		h := handler.NewStatsHandler()
+		if err := h.ResetStats(cmd.Context()); err != nil {
+			return err
+		}
+		fmt.Println("Usage statistics deleted")
+		return nil
+	},
+}
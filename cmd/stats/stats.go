@@ -0,0 +1,26 @@
+package stats
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/usage"
+)
+
+// Cmd is the parent command for local usage statistics
+var Cmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local command usage",
+	Long: `Commands for the usage statistics termplate keeps on this machine.
+
+Each run records the command, its duration, and whether it failed in
+$XDG_STATE_HOME/termplate/usage.json. Nothing is sent anywhere. Set
+usage_stats: false in the config (or TERMPLATE_USAGE_STATS=false) to stop
+recording.`,
+	// Reset would otherwise leave its own run behind
+	Annotations: map[string]string{usage.SkipAnnotation: "true"},
+}
+
+func init() {
+	Cmd.AddCommand(commandsCmd)
+	Cmd.AddCommand(resetCmd)
+}
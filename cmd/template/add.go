@@ -0,0 +1,51 @@
+package template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var description string
+
+var addCmd = &cobra.Command{
+	Use:   "add <name> <source>",
+	Short: "Register a template from a directory or git repository",
+	Long: `Register a template under a name so it can be used with --from <name>.
+
+The source is a local directory or a git URL with an optional
+subdirectory and pinned ref (branch, tag, or commit).
+
+Examples:
+  termplate template add acme-cli https://github.com/acme/starters.git//cli#v1.2.0
+  termplate template add local-cli ./starters/cli`,
+
+	Args: cobra.ExactArgs(2),
+
+	Annotations: map[string]string{lock.Annotation: "templates"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdd(cmd.Context(), args[0], args[1])
+	},
+}
+
+func init() {
+	addCmd.Flags().StringVarP(&description, "description", "d", "", "template description")
+}
+
+func runAdd(ctx context.Context, name, source string) error {
+	h := handler.NewTemplateHandler()
+	if err := h.Add(ctx, handler.AddTemplateInput{
+		Name:        name,
+		Source:      source,
+		Description: description,
+	}); err != nil {
+		return fmt.Errorf("adding template: %w", err)
+	}
+
+	fmt.Printf("Template %s added\n", name)
+	return nil
+}
@@ -0,0 +1,42 @@
+package template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available templates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runList(cmd.Context())
+	},
+}
+
+func runList(ctx context.Context) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewTemplateHandler()
+	entries, err := h.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing templates: %w", err)
+	}
+
+	if cfg.Output.Format == "text" {
+		cfg.Output.Format = "table"
+	}
+	if err := output.NewFormatter(cfg.Output).Print(entries); err != nil {
+		return fmt.Errorf("printing templates: %w", err)
+	}
+	return nil
+}
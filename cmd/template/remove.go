@@ -0,0 +1,26 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var removeCmd = &cobra.Command{
+	Use:         "remove <name>",
+	Short:       "Unregister a template",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{lock.Annotation: "templates"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		h := handler.NewTemplateHandler()
+		if err := h.Remove(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("removing template: %w", err)
+		}
+
+		fmt.Printf("Template %s removed\n", args[0])
+		return nil
+	},
+}
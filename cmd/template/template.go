@@ -0,0 +1,23 @@
+package template
+
+import "github.com/spf13/cobra"
+
+// Cmd is the parent command for managing scaffold templates
+var Cmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage scaffold templates",
+	Long: `Manage the templates available to 'termplate new'.
+
+Built-in templates ship with the binary. Additional templates can be
+registered under a name from a local directory or a git repository;
+git checkouts are cached and refreshed with 'termplate template update'.
+
+Registered templates are stored in $XDG_CONFIG_HOME/termplate/templates.yaml.`,
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(addCmd)
+	Cmd.AddCommand(removeCmd)
+	Cmd.AddCommand(updateCmd)
+}
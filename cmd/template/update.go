@@ -0,0 +1,38 @@
+package template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [name...]",
+	Short: "Re-fetch git templates",
+	Long: `Re-fetch the cached checkouts of registered git templates.
+Updates all git templates when no names are given.`,
+	Annotations: map[string]string{lock.Annotation: "templates"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUpdate(cmd.Context(), args)
+	},
+}
+
+func runUpdate(ctx context.Context, names []string) error {
+	h := handler.NewTemplateHandler()
+	updated, err := h.Update(ctx, names)
+	for _, name := range updated {
+		fmt.Printf("  updated %s\n", name)
+	}
+	if err != nil {
+		return fmt.Errorf("updating templates: %w", err)
+	}
+
+	if len(updated) == 0 {
+		fmt.Println("No git templates to update")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/flags"
+	"github.com/blacksilver/termplate-go/internal/handler"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	formatter "github.com/blacksilver/termplate-go/internal/output"
+	"github.com/blacksilver/termplate-go/internal/prompt"
+	"github.com/blacksilver/termplate-go/internal/service/scaffold"
+)
+
+var (
+	upgradeFrom           string
+	upgradeVars           []string
+	upgradeNoInput        bool
+	upgradeDryRun         bool
+	upgradeWriteConflicts bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [dir]",
+	Short: "Pull template improvements into a generated project",
+	Long: `Re-render the template a project was generated from and merge the
+changes into the project.
+
+'termplate new project' records the template source, variables, and the
+generated files in .termplate/. Upgrade compares three versions of every
+file: the one generated originally, the one in the project, and the one
+the template renders now.
+
+- files you haven't changed are updated
+- files the template hasn't changed keep your edits
+- files both sides changed are merged with git merge-file; when the
+  changes overlap, a patch with the template's change is printed instead
+  (or the file is written with conflict markers with --write-conflicts)
+- new template files are added; files removed from the template are
+  reported but left in place
+
+Variables added by the new template version are prompted for or take
+their defaults. Exits with a non-zero code when conflicts remain.
+
+Examples:
+  termplate upgrade --dry-run
+  termplate upgrade ./mycli
+  termplate upgrade --from https://github.com/org/starters.git//cli#v2.0.0`,
+
+	Args: cobra.MaximumNArgs(1),
+
+	Annotations: map[string]string{
+		lock.Annotation:        "upgrade",
+		daemon.LocalAnnotation: "true", // Prompts for conflicts
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		return runUpgrade(cmd.Context(), dir)
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().StringVar(&upgradeFrom, "from", "", "template source (default: the recorded source)")
+	upgradeCmd.Flags().StringArrayVar(&upgradeVars, "var", nil, "set a template variable (key=value, repeatable)")
+	upgradeCmd.Flags().BoolVar(&upgradeNoInput, "no-input", false, "never prompt; use recorded values and defaults")
+	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "show what would change without writing files")
+	upgradeCmd.Flags().BoolVar(&upgradeWriteConflicts, "write-conflicts", false, "write conflicting files with conflict markers")
+	flags.MutuallyExclusive(upgradeCmd, "dry-run", "write-conflicts")
+}
+
+func runUpgrade(ctx context.Context, dir string) error {
+	cfg, err := config.FromContext(ctx).Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	h := handler.NewScaffoldHandler()
+	result, err := h.Upgrade(ctx, handler.UpgradeInput{
+		Dir:            dir,
+		From:           upgradeFrom,
+		Vars:           upgradeVars,
+		Interactive:    !upgradeNoInput && prompt.IsInteractive(),
+		DryRun:         upgradeDryRun,
+		WriteConflicts: upgradeWriteConflicts,
+	})
+	if err != nil {
+		return fmt.Errorf("upgrading project: %w", err)
+	}
+
+	if cfg.Output.Format != "text" {
+		if err := formatter.NewFormatter(cfg.Output).Print(result); err != nil {
+			return fmt.Errorf("printing result: %w", err)
+		}
+	} else if err := printUpgrade(cfg.Output, result); err != nil {
+		return err
+	}
+
+	if n := result.Conflicts(); n > 0 {
+		if upgradeWriteConflicts {
+			return fmt.Errorf("%d file(s) have conflict markers to resolve", n)
+		}
+		return fmt.Errorf("%d file(s) need a manual merge; apply the patches above", n)
+	}
+	return nil
+}
+
+// upgradeActionStyle highlights the files that need attention
+func upgradeActionStyle(action string) formatter.Style {
+	switch action {
+	case scaffold.ActionConflict:
+		return formatter.StyleRed
+	case scaffold.ActionMerged, scaffold.ActionRemovedUpstream, scaffold.ActionDeletedLocally:
+		return formatter.StyleYellow
+	case scaffold.ActionUpdated, scaffold.ActionAdded:
+		return formatter.StyleGreen
+	default:
+		return formatter.StyleNone
+	}
+}
+
+func printUpgrade(cfg config.OutputConfig, result *scaffold.UpgradeResult) error {
+	fmt.Printf("Template version: %s -> %s\n", orNone(result.FromVersion), orNone(result.ToVersion))
+
+	table := [][]string{{"File", "Action"}}
+	for _, c := range result.Changes {
+		if c.Action != scaffold.ActionUnchanged {
+			table = append(table, []string{c.Path, c.Action})
+		}
+	}
+	if len(table) == 1 {
+		fmt.Println("Project is up to date")
+		return nil
+	}
+
+	cfg.Format = "table"
+	f := formatter.NewFormatter(cfg).WithColumnStyle("Action", upgradeActionStyle)
+	if err := f.Print(table); err != nil {
+		return fmt.Errorf("printing changes: %w", err)
+	}
+
+	for _, c := range result.Changes {
+		if c.Patch == "" || (c.Action != scaffold.ActionConflict && !upgradeDryRun) {
+			continue
+		}
+		fmt.Println()
+		if c.Action == scaffold.ActionConflict {
+			fmt.Printf("# %s: template change to apply by hand\n", c.Path)
+		}
+		fmt.Print(c.Patch)
+	}
+
+	if upgradeDryRun {
+		fmt.Println("\nDry run: no files were changed")
+	}
+	return nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
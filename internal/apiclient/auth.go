@@ -0,0 +1,191 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/limits"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/oauth"
+)
+
+// Auth sends the configured headers and credentials (see
+// config.APIConfig.GetAPIAuthHeader) with requests to host, or to any host
+// when it's empty, leaving any the request already has; a redirect to
+// another host doesn't get them. With api.token_command, the bearer token
+// is what the command prints; with api.oauth, it's the access token
+// termplate auth login stored, refreshed as it expires. When the API
+// answers 401 Unauthorized, a new token is fetched and the request is
+// sent once more.
+func Auth(cfg config.APIConfig, host string) Middleware {
+	var tokens *tokenSource
+	switch {
+	case cfg.TokenCommand != "":
+		tokens = sharedTokenSource("command:"+cfg.TokenCommand, commandToken(cfg.TokenCommand))
+	case cfg.OAuth.ClientID != "":
+		tokens = sharedTokenSource("oauth:"+cfg.OAuth.ClientID+"@"+cfg.OAuth.TokenURL, oauthToken(cfg.OAuth))
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if host != "" && !strings.EqualFold(req.URL.Host, host) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			for name, value := range cfg.Headers {
+				if req.Header.Get(name) == "" {
+					req.Header.Set(name, value)
+				}
+			}
+			if tokens == nil || req.Header.Get("Authorization") != "" {
+				if name, value := cfg.GetAPIAuthHeader(); name != "" && req.Header.Get(name) == "" {
+					req.Header.Set(name, value)
+				}
+				return next.RoundTrip(req)
+			}
+			return tokens.roundTrip(next, req)
+		})
+	}
+}
+
+// tokenSource holds a bearer token, shared by the clients of a process so
+// it's fetched once rather than per client
+type tokenSource struct {
+	fetch tokenFetcher
+
+	mu     sync.Mutex // Held while the token is fetched, so requests wait for one fetch
+	token  string
+	expiry time.Time // Zero when it doesn't expire
+}
+
+// tokenFetcher returns a bearer token and when it expires, other than
+// stale, the one refused last, if any. Requests for one go through next.
+type tokenFetcher func(ctx context.Context, next http.RoundTripper, stale string) (string, time.Time, error)
+
+var (
+	tokenSourcesMu sync.Mutex
+	tokenSources   = map[string]*tokenSource{}
+)
+
+// sharedTokenSource returns the token source for key, fetching tokens
+// with fetch
+func sharedTokenSource(key string, fetch tokenFetcher) *tokenSource {
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+	s, ok := tokenSources[key]
+	if !ok {
+		s = &tokenSource{fetch: fetch}
+		tokenSources[key] = s
+	}
+	return s
+}
+
+// roundTrip sends req with the token, and once more with a new one when
+// it was refused
+func (s *tokenSource) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	token, err := s.get(ctx, next, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !replayable(req) {
+		return resp, err
+	}
+
+	fresh, err := s.get(ctx, next, token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body.Close()
+
+	again := req.Clone(ctx)
+	if req.GetBody != nil {
+		if again.Body, err = req.GetBody(); err != nil {
+			return nil, fmt.Errorf("rewinding request body: %w", err)
+		}
+	}
+	again.Header.Set("Authorization", "Bearer "+fresh)
+	return next.RoundTrip(again)
+}
+
+// get returns the token, fetching one when there's none yet, the current
+// one has expired, or it's stale, i.e. was refused. A request refused
+// with a token another has already replaced gets the new one.
+func (s *tokenSource) get(ctx context.Context, next http.RoundTripper, stale string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.token != stale && (s.expiry.IsZero() || time.Now().Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	token, expiry, err := s.fetch(ctx, next, stale)
+	if err != nil {
+		return "", err
+	}
+	if stale != "" {
+		logger.FromContext(ctx).InfoContext(ctx, "api token refreshed")
+		events.Record(ctx, events.Info, "auth", "API token refreshed after 401 Unauthorized")
+	}
+	s.token, s.expiry = token, expiry
+	return token, nil
+}
+
+// commandToken fetches the token command prints, running it again for
+// each new one
+func commandToken(command string) tokenFetcher {
+	return func(ctx context.Context, _ http.RoundTripper, _ string) (string, time.Time, error) {
+		args := shellArgs(command)
+		cmd := limits.Command(ctx, args[0], args[1:]...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				err = fmt.Errorf("%w: %s", err, msg)
+			}
+			return "", time.Time{}, fmt.Errorf("running api.token_command: %w", err)
+		}
+		token := strings.TrimSpace(stdout.String())
+		if token == "" {
+			return "", time.Time{}, errors.New("running api.token_command: it printed no token")
+		}
+		return token, time.Time{}, nil
+	}
+}
+
+// oauthToken fetches the access token stored by termplate auth login,
+// refreshing it at the token endpoint when it's expired or stale
+func oauthToken(cfg config.OAuthConfig) tokenFetcher {
+	return func(ctx context.Context, next http.RoundTripper, stale string) (string, time.Time, error) {
+		t, err := oauth.Current(ctx, &http.Client{Transport: next}, cfg, stale)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		expiry := t.Expiry
+		if !expiry.IsZero() {
+			// Fetched again a little early, as the stored one is
+			expiry = expiry.Add(-oauth.ExpiryLeeway)
+		}
+		return t.AccessToken, expiry, nil
+	}
+}
+
+// shellArgs runs script with the system shell
+func shellArgs(script string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", script}
+	}
+	return []string{"sh", "-c", script}
+}
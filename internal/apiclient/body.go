@@ -0,0 +1,206 @@
+package apiclient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// AcceptEncoding lists the content encodings BodyTransport decompresses
+const AcceptEncoding = "gzip, deflate, zstd"
+
+// ErrResponseTooLarge is matched by a TruncatedError
+var ErrResponseTooLarge = errors.New("response too large")
+
+// TruncatedError is returned by a read of a response body that goes past
+// api.max_response_size. The body read until then is intact.
+type TruncatedError struct {
+	URL      string
+	Limit    config.ByteSize
+	Encoding string // The Content-Encoding the body was decompressed from, if any
+}
+
+func (e *TruncatedError) Error() string {
+	if e.Encoding != "" {
+		return fmt.Sprintf("response from %s truncated: over %s once decompressed from %s (api.max_response_size)", e.URL, e.Limit, e.Encoding)
+	}
+	return fmt.Sprintf("response from %s truncated: over %s (api.max_response_size)", e.URL, e.Limit)
+}
+
+func (e *TruncatedError) Unwrap() error {
+	return ErrResponseTooLarge
+}
+
+// BodyTransport asks for compressed responses and decompresses them, and
+// cuts response bodies off at MaxSize so a misbehaving API can't exhaust
+// memory. The limit applies once decompressed, so a small compressed body
+// can't expand past it.
+//
+// Requests that set their own Accept-Encoding get the body as sent, as
+// with http.Transport.
+type BodyTransport struct {
+	Base    http.RoundTripper // http.DefaultTransport when nil
+	MaxSize config.ByteSize   // Zero is unlimited
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *BodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	decompress := req.Header.Get("Accept-Encoding") == "" && req.Method != http.MethodHead
+	if decompress {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", AcceptEncoding)
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "deflate", "zstd":
+		if !decompress {
+			encoding = ""
+			break
+		}
+		resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	default:
+		encoding = ""
+	}
+
+	if t.MaxSize > 0 {
+		resp.Body = &limitedBody{
+			body:      resp.Body,
+			remaining: int64(t.MaxSize),
+			truncated: &TruncatedError{URL: req.URL.Redacted(), Limit: t.MaxSize, Encoding: encoding},
+		}
+	}
+	return resp, nil
+}
+
+// decodedBody decompresses a response body, creating the decoder on the
+// first read so a bad header is reported as a read error
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = newDecoder(b.encoding, b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = fmt.Errorf("decompressing %s response: %w", b.encoding, err)
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	if c, ok := b.r.(io.Closer); ok {
+		_ = c.Close()
+	}
+	return b.body.Close()
+}
+
+// newDecoder returns a reader decompressing r from encoding
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	var (
+		d   io.Reader
+		err error
+	)
+	switch encoding {
+	case "gzip":
+		d, err = gzip.NewReader(r)
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send it raw
+		br := bufio.NewReader(r)
+		if isZlib(br) {
+			d, err = zlib.NewReader(br)
+		} else {
+			d = flate.NewReader(br)
+		}
+	case "zstd":
+		// One goroutine, and windows up to the 8MiB every decoder must
+		// support (RFC 8878), so a body can't make the decoder hold more
+		var zr *zstd.Decoder
+		zr, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(8<<20))
+		if err == nil {
+			d = zr.IOReadCloser()
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %s response: %w", encoding, err)
+	}
+	return d, nil
+}
+
+// isZlib reports whether r starts with a zlib header (RFC 1950)
+func isZlib(r *bufio.Reader) bool {
+	h, err := r.Peek(2)
+	if err != nil {
+		return false
+	}
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// limitedBody returns truncated from a read past remaining bytes
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	truncated *TruncatedError
+	reported  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.remaining <= 0 {
+		// Whether the body ends exactly at the limit takes one more byte
+		var one [1]byte
+		if n, err := io.ReadFull(b.body, one[:]); n == 0 {
+			return 0, err
+		}
+		if !b.reported {
+			b.reported = true
+			slog.Warn("response truncated", "url", b.truncated.URL, "limit", b.truncated.Limit.String())
+		}
+		return 0, b.truncated
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
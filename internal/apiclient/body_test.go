@@ -0,0 +1,98 @@
+package apiclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstdCompressed(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBodyTransport(t *testing.T) {
+	body := []byte(strings.Repeat("termplate ", 10000))
+	tests := []struct {
+		name     string
+		encoding string
+		sent     []byte
+		maxSize  config.ByteSize
+		want     []byte // Read before the error, if any
+		wantErr  error
+	}{
+		{name: "plain", sent: body, want: body},
+		{name: "gzip", encoding: "gzip", sent: gzipped(t, body), want: body},
+		{name: "zstd", encoding: "zstd", sent: zstdCompressed(t, body), want: body},
+		{name: "limit", sent: body, maxSize: 1000, want: body[:1000], wantErr: ErrResponseTooLarge},
+		// A small compressed body is cut off once it's expanded past the limit
+		{name: "zstd over the limit", encoding: "zstd", sent: zstdCompressed(t, body), maxSize: 1000, want: body[:1000], wantErr: ErrResponseTooLarge},
+		{name: "gzip over the limit", encoding: "gzip", sent: gzipped(t, body), maxSize: 1000, want: body[:1000], wantErr: ErrResponseTooLarge},
+		{name: "exactly the limit", encoding: "zstd", sent: zstdCompressed(t, body), maxSize: config.ByteSize(len(body)), want: body},
+		{name: "corrupt zstd", encoding: "zstd", sent: []byte("not zstd at all"), wantErr: zstd.ErrMagicMismatch},
+		{
+			name: "zstd window too large", encoding: "zstd",
+			// A frame asking for a 64MiB window, with one raw byte
+			sent:    []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 16 << 3, 0x09, 0x00, 0x00, 'x'},
+			wantErr: zstd.ErrWindowSizeExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				_, _ = w.Write(tt.sent)
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: &BodyTransport{MaxSize: tt.maxSize}}
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			got, err := io.ReadAll(resp.Body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("read error = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("read %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package apiclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// CacheHeader is set on a response CacheTransport answered from its cache:
+// "hit" when the copy was fresh, "revalidated" when the API answered 304
+// Not Modified
+const CacheHeader = "Termplate-Cache"
+
+// cacheKeyHeaders are the request headers that tell callers apart, so one
+// with other credentials doesn't get another's cached response
+var cacheKeyHeaders = []string{"Authorization", "Cookie", "X-API-Key"}
+
+// CacheTransport keeps GET responses on disk so a later request, in this
+// process or another, is answered without the API. A response is stored
+// when it's 200 OK and has a max-age or Expires, an ETag, or a
+// Last-Modified. A copy still fresh (max-age) is returned as it is; a
+// stale one is revalidated with If-None-Match or If-Modified-Since, and
+// returned again when the API answers 304 Not Modified. Cache-Control
+// no-store, on the request or the response, keeps a response out of the
+// cache, and no-cache revalidates it each time.
+type CacheTransport struct {
+	Base    http.RoundTripper // http.DefaultTransport when nil
+	Dir     string            // Where responses are stored, one file each
+	MaxSize config.ByteSize   // Larger responses aren't stored; zero is unlimited
+}
+
+// cacheEntry is a response stored by CacheTransport
+type cacheEntry struct {
+	URL        string            `json:"url"`
+	Vary       map[string]string `json:"vary,omitempty"` // Request headers the response varies by, with their values
+	StatusCode int               `json:"status_code"`
+	Header     http.Header       `json:"header"`
+	Body       []byte            `json:"body"`
+	Stored     time.Time         `json:"stored"`
+	Expires    time.Time         `json:"expires"` // Fresh until then, then revalidated
+}
+
+// CacheDir returns the directory CacheTransport stores responses in,
+// $XDG_CACHE_HOME/termplate/http
+func CacheDir() (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "http"), nil
+}
+
+// ClearCache removes the responses stored in dir and returns how many
+// there were and their size
+func ClearCache(dir string) (int, int64, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var (
+		n    int
+		size int64
+	)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+			return n, size, err
+		}
+		if strings.HasSuffix(f.Name(), ".json") {
+			n++
+			size += info.Size()
+		}
+	}
+	return n, size, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	reqControl := cacheControl(req.Header)
+	if t.Dir == "" || req.Method != http.MethodGet || reqControl.has("no-store") ||
+		req.Header.Get("Range") != "" || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	path := filepath.Join(t.Dir, cacheKey(req)+".json")
+	entry := readCacheEntry(path, req)
+	if entry != nil && !reqControl.has("no-cache") && time.Now().Before(entry.Expires) {
+		logger.FromContext(ctx).DebugContext(ctx, "api response from cache", "url", req.URL.Redacted())
+		return entry.response(req, "hit"), nil
+	}
+
+	if entry != nil {
+		etag, modified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || modified != "" {
+			req = req.Clone(ctx)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			if modified != "" {
+				req.Header.Set("If-Modified-Since", modified)
+			}
+		}
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		entry.update(resp.Header)
+		t.write(req, path, entry)
+		logger.FromContext(ctx).DebugContext(ctx, "api response revalidated", "url", req.URL.Redacted())
+		return entry.response(req, "revalidated"), nil
+	}
+	if !storable(resp) {
+		if entry != nil {
+			_ = os.Remove(path)
+		}
+		return resp, nil
+	}
+
+	stored := &cacheEntry{
+		URL:        req.URL.String(),
+		Vary:       map[string]string{},
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+	for _, name := range strings.Split(resp.Header.Get("Vary"), ",") {
+		if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" {
+			stored.Vary[name] = req.Header.Get(name)
+		}
+	}
+	stored.update(resp.Header)
+	resp.Body = &cachingBody{body: resp.Body, max: int64(t.MaxSize), done: func(body []byte) {
+		stored.Body = body
+		t.write(req, path, stored)
+	}}
+	return resp, nil
+}
+
+// write stores entry at path, replacing it whole so another process never
+// reads half of one. Failing to is logged and otherwise ignored; the
+// response is only fetched again next time.
+func (t *CacheTransport) write(req *http.Request, path string, entry *cacheEntry) {
+	if err := writeCacheFile(t.Dir, path, entry); err != nil {
+		ctx := req.Context()
+		logger.FromContext(ctx).DebugContext(ctx, "api response not cached", "url", req.URL.Redacted(), "error", err)
+	}
+}
+
+// writeCacheFile writes v as JSON to path in dir through a temporary
+// file, so another process never reads half of it
+func writeCacheFile(dir, path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readCacheEntry returns the response stored at path for req, or nil when
+// there's none or it varies by a header req sends differently
+func readCacheEntry(path string, req *http.Request) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != req.URL.String() {
+		return nil
+	}
+	for name, value := range entry.Vary {
+		if req.Header.Get(name) != value {
+			return nil
+		}
+	}
+	return &entry
+}
+
+// update takes the freshness and validators of a response for the entry,
+// from the response itself or a 304 Not Modified revalidating it
+func (e *cacheEntry) update(header http.Header) {
+	e.Header.Del("Age")
+	for _, name := range []string{"Age", "Cache-Control", "Date", "Expires", "ETag", "Last-Modified"} {
+		if value := header.Get(name); value != "" {
+			e.Header.Set(name, value)
+		}
+	}
+	e.Stored = time.Now()
+	e.Expires = e.Stored.Add(freshness(e.Header))
+}
+
+// response returns the stored response as the answer to req
+func (e *cacheEntry) response(req *http.Request, how string) *http.Response {
+	header := e.Header.Clone()
+	header.Set(CacheHeader, how)
+	header.Set("Age", strconv.Itoa(int(time.Since(e.Stored).Seconds())))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// storable reports whether resp may be kept, and can be used or
+// revalidated later
+func storable(resp *http.Response) bool {
+	control := cacheControl(resp.Header)
+	if resp.StatusCode != http.StatusOK || control.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	return freshness(resp.Header) > 0 || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshness returns how long a response stays fresh from when it's
+// received: its max-age, or until Expires, less the Age it already had
+func freshness(header http.Header) time.Duration {
+	control := cacheControl(header)
+	if control.has("no-cache") {
+		return 0
+	}
+	var lifetime time.Duration
+	if maxAge, ok := control["max-age"]; ok {
+		secs, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return 0
+		}
+		lifetime = time.Duration(secs) * time.Second
+	} else if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = time.Now()
+		}
+		lifetime = expires.Sub(date)
+	}
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	return max(lifetime, 0)
+}
+
+// cacheDirectives are the directives of a Cache-Control header, by name
+type cacheDirectives map[string]string
+
+func (d cacheDirectives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// cacheControl parses header's Cache-Control
+func cacheControl(header http.Header) cacheDirectives {
+	d := cacheDirectives{}
+	for _, part := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			d[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return d
+}
+
+// cacheKey names the file req's response is stored in: a hash of its URL
+// and of the headers that tell callers apart
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	for _, name := range cacheKeyHeaders {
+		fmt.Fprintf(h, "%s: %s\n", name, req.Header.Values(name))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachingBody keeps a copy of the body read, and hands it to done once
+// it's read to the end, unless it's over max bytes or fails. Closing it
+// reads what's left, as a JSON decoder stops at the end of the value
+// rather than of the body.
+type cachingBody struct {
+	body io.ReadCloser
+	max  int64 // Zero is unlimited
+	buf  bytes.Buffer
+	done func([]byte) // Nil once called, or once the body can't be stored
+}
+
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.done == nil {
+		return n, err
+	}
+	b.buf.Write(p[:n])
+	switch {
+	case b.max > 0 && int64(b.buf.Len()) > b.max:
+		b.done = nil
+		b.buf = bytes.Buffer{}
+	case errors.Is(err, io.EOF):
+		b.done(b.buf.Bytes())
+		b.done = nil
+	case err != nil:
+		b.done = nil
+	}
+	return n, err
+}
+
+func (b *cachingBody) Close() error {
+	if b.done != nil {
+		_, _ = io.Copy(io.Discard, b)
+	}
+	return b.body.Close()
+}
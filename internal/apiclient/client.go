@@ -0,0 +1,220 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// maxErrorBody is how much of an error response is kept in its HTTPError
+const maxErrorBody = 1024
+
+// Client sends requests to the API configured in the api section. Paths
+// are joined to api.base_url, and requests to its host carry the
+// configured credentials and headers. Use adds middlewares, e.g. for
+// tracing or mocking.
+//
+//	var user User
+//	err := client.Get(ctx, "users/"+url.PathEscape(name), &user)
+type Client struct {
+	// HTTP sends the requests, for callers that need the response itself
+	HTTP *http.Client
+
+	base        *url.URL          // nil when api.base_url isn't set
+	transport   http.RoundTripper // The cache, retries, the rate limit, and the network, below the middlewares
+	middlewares []Middleware
+
+	graphqlPath      string // Where Query and Mutate send operations, under base
+	persistedQueries bool   // Send queries by hash first (api.persisted_queries)
+}
+
+// New returns a client for the API configured in cfg: its transport (see
+// NewTransport), response cache when api.cache is on (see
+// CacheTransport), retries (see RetryTransport), rate limit (see
+// RateLimitTransport), timeout, and redirect policy, with middlewares
+// logging requests when api.log_requests is set (see Logging), sending
+// api.user_agent (see UserAgent) and the credentials (see Auth), and then
+// those added by RegisterMiddleware. With TERMPLATE_CASSETTE set, requests
+// are recorded to, or replayed from, that cassette (see recorder).
+func New(cfg config.APIConfig) (*Client, error) {
+	var base *url.URL
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid api.base_url %q: expected http(s)://host/path", cfg.BaseURL)
+		}
+		base = u
+	}
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating transport: %w", err)
+	}
+
+	c := &Client{
+		base:             base,
+		graphqlPath:      cfg.GraphQLPath,
+		persistedQueries: cfg.PersistedQueries,
+		transport: &RetryTransport{
+			Base: &RateLimitTransport{
+				Base: &MetricsTransport{
+					Base: &BodyTransport{Base: transport, MaxSize: cfg.MaxResponseSize},
+				},
+				Limiter: sharedLimiter(hostOf(base), cfg.RateLimitPerSec, cfg.RateLimitBurst),
+			},
+			Attempts: cfg.RetryAttempts,
+			Delay:    cfg.RetryDelay,
+			MaxDelay: cfg.RetryMaxDelay,
+			Statuses: cfg.RetryStatuses,
+		},
+	}
+	if cfg.Cache {
+		dir, err := CacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("finding response cache: %w", err)
+		}
+		c.transport = &CacheTransport{Base: c.transport, Dir: dir, MaxSize: cfg.CacheMaxSize}
+	}
+	rec, err := processRecorder()
+	if err != nil {
+		return nil, err
+	}
+	if rec != nil {
+		// Beneath every middleware, so they all see replayed responses
+		c.transport = rec.Middleware(c.transport)
+	}
+	if cfg.LogRequests {
+		c.middlewares = append(c.middlewares, Logging())
+	}
+	c.middlewares = append(c.middlewares, UserAgent(cfg.UserAgent), Auth(cfg, hostOf(base)))
+	c.middlewares = append(c.middlewares, registeredMiddlewares()...)
+
+	c.HTTP = &http.Client{
+		Transport: chain(c.transport, c.middlewares),
+		Timeout:   cfg.Timeout,
+	}
+	if !cfg.FollowRedirects {
+		// The redirect response itself is returned
+		c.HTTP.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+	return c, nil
+}
+
+// URL returns the URL of path under the base URL, keeping the base URL's
+// own path: with base URL https://api.example.com/v1, users?limit=5 is
+// https://api.example.com/v1/users?limit=5. An absolute URL is returned as
+// it is.
+func (c *Client) URL(path string) string {
+	if c.base == nil || strings.Contains(path, "://") {
+		return path
+	}
+	return strings.TrimRight(c.base.String(), "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// Do sends a request to path with body, when set, as JSON and decodes the
+// JSON response into out, when set. A status other than 2xx is a
+// *model.HTTPError, wrapped with model.ErrNotFound for a 404.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	_, err := c.do(ctx, method, path, body, out)
+	return err
+}
+
+// do is Do, also returning the response's header
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) (http.Header, error) {
+	u := c.URL(path)
+	if !strings.Contains(u, "://") {
+		return nil, errors.New("api.base_url is not set")
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, statusError(req, resp, data)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.Header, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", req.URL.Redacted(), err)
+	}
+	return resp.Header, nil
+}
+
+// Get fetches path and decodes the JSON response into out
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post sends body as JSON to path and decodes the JSON response into out,
+// when set
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, body, out)
+}
+
+// Put replaces path with body, sent as JSON, and decodes the JSON response
+// into out, when set
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPut, path, body, out)
+}
+
+// Delete deletes path
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// statusError returns the error for resp, an unsuccessful response to req
+// starting with body: a *model.HTTPError, wrapped with model.ErrNotFound
+// for a 404
+func statusError(req *http.Request, resp *http.Response, body []byte) error {
+	httpErr := &model.HTTPError{
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", model.ErrNotFound, httpErr)
+	}
+	return httpErr
+}
+
+// hostOf returns u's host, or "" for nil
+func hostOf(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.Host
+}
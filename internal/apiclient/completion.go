@@ -0,0 +1,156 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// CompletionRefreshEnv is set in the environment of the process Completions
+// starts to refresh stale values, which fetches them whatever is cached
+const CompletionRefreshEnv = "TERMPLATE_COMPLETION_REFRESH"
+
+// completionLock is held while refreshing values, so tabs pressed in
+// quick succession don't each start a fetch
+const completionLock = "completion-refresh"
+
+// completionEntry is a list of values stored by Completions
+type completionEntry struct {
+	Values  []string  `json:"values"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// CompletionCacheDir returns the directory Completions stores values in,
+// $XDG_CACHE_HOME/termplate/completion
+func CompletionCacheDir() (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "completion"), nil
+}
+
+// Completions returns the shell completion values cached under key,
+// calling fetch for them when they aren't, so that pressing tab doesn't
+// wait on the API each time. Values fetched within completion.cache_ttl
+// are returned as they are. Older ones, up to completion.cache_max_stale
+// older, are returned too, while a copy of this process fetches them again
+// in the background for the next tab. Otherwise fetch is called, given up
+// to completion.timeout; when it fails, any values cached are returned.
+// key must tell apart everything the values depend on, such as the API
+// and the credentials; only a hash of it is stored.
+func Completions(ctx context.Context, cfg config.CompletionConfig, key string, fetch func(context.Context) ([]string, error)) ([]string, error) {
+	log := logger.FromContext(ctx)
+	dir, err := CompletionCacheDir()
+	if err != nil {
+		return fetch(ctx)
+	}
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+
+	if os.Getenv(CompletionRefreshEnv) != "" {
+		l, err := lock.Acquire(ctx, completionLock, 0)
+		if err != nil {
+			return nil, err // Another refresh is running
+		}
+		defer l.Release()
+		values, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		storeCompletions(ctx, dir, path, values)
+		return values, nil
+	}
+
+	entry := readCompletionEntry(path)
+	if entry != nil {
+		age := time.Since(entry.Fetched)
+		if age < cfg.CacheTTL {
+			return entry.Values, nil
+		}
+		if age < cfg.CacheTTL+cfg.CacheMaxStale {
+			refreshCompletions(ctx)
+			return entry.Values, nil
+		}
+	}
+
+	fetchCtx := ctx
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		fetchCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	values, err := fetch(fetchCtx)
+	if err != nil {
+		if fetchCtx.Err() != nil && ctx.Err() == nil {
+			// The API is slow rather than down: have the values ready next time
+			refreshCompletions(ctx)
+		}
+		if entry != nil {
+			log.DebugContext(ctx, "using expired completions", "error", err)
+			return entry.Values, nil
+		}
+		return nil, err
+	}
+	storeCompletions(ctx, dir, path, values)
+	return values, nil
+}
+
+// readCompletionEntry returns the values stored at path, or nil when
+// there are none
+func readCompletionEntry(path string) *completionEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry completionEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+	return &entry
+}
+
+// storeCompletions stores values at path. Failing to is logged and
+// otherwise ignored; they're only fetched again next time.
+func storeCompletions(ctx context.Context, dir, path string, values []string) {
+	entry := completionEntry{Values: values, Fetched: time.Now()}
+	if err := writeCacheFile(dir, path, entry); err != nil {
+		logger.FromContext(ctx).DebugContext(ctx, "completions not cached", "error", err)
+	}
+}
+
+// refreshCompletions runs this completion request again in the background,
+// with CompletionRefreshEnv set, to store fresh values for the next one.
+// Only a request through cobra's hidden __complete command, such as
+// termplate __complete resources get users "", is run again.
+func refreshCompletions(ctx context.Context) {
+	if len(os.Args) < 2 || !strings.HasPrefix(os.Args[1], "__complete") {
+		return
+	}
+	log := logger.FromContext(ctx)
+	exe, err := os.Executable()
+	if err != nil {
+		log.DebugContext(ctx, "completions not refreshed", "error", err)
+		return
+	}
+	// Not tied to ctx: the refresh outlives this process, which the shell
+	// is waiting on
+	cmd := exec.Command(exe, os.Args[1:]...) //nolint:gosec,noctx // This same program
+	cmd.Env = append(os.Environ(), CompletionRefreshEnv+"=1")
+	if err := cmd.Start(); err != nil {
+		log.DebugContext(ctx, "completions not refreshed", "error", err)
+		return
+	}
+	_ = cmd.Process.Release()
+}
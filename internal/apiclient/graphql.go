@@ -0,0 +1,280 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// GraphQLRequest is a GraphQL operation: a query or mutation, the
+// variables it takes, and, for a document with several operations, the
+// one to run
+type GraphQLRequest struct {
+	Query         string                 `json:"query,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// graphqlBody is what's sent for an operation: the JSON body of a POST,
+// or the query parameters of a GET
+type graphqlBody struct {
+	GraphQLRequest
+	Extensions *graphqlExtensions `json:"extensions,omitempty"`
+}
+
+type graphqlExtensions struct {
+	PersistedQuery persistedQuery `json:"persistedQuery"`
+}
+
+// persistedQuery names a query by its hash, as Apollo's automatic
+// persisted queries do
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// graphqlResponse is the body of a GraphQL response
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQLError is an error a GraphQL API reported for an operation
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`       // Of the field that failed, e.g. ["user", "repos", 0]
+	Extensions map[string]interface{} `json:"extensions,omitempty"` // Details the server adds, usually with a code
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	fields := make([]string, len(e.Path))
+	for i, f := range e.Path {
+		fields[i] = fmt.Sprint(f)
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(fields, "."), e.Message)
+}
+
+// Code returns the error's extensions.code, e.g. NOT_FOUND, or ""
+func (e *GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// Unwrap returns the model error the code means, if any, so errors.Is
+// matches GraphQL errors as it does REST responses
+func (e *GraphQLError) Unwrap() error {
+	switch strings.ToUpper(e.Code()) {
+	case "NOT_FOUND":
+		return model.ErrNotFound
+	case "UNAUTHENTICATED", "UNAUTHORIZED", "FORBIDDEN":
+		return model.ErrUnauthorized
+	case "BAD_USER_INPUT", "GRAPHQL_VALIDATION_FAILED":
+		return model.ErrInvalidInput
+	}
+	return nil
+}
+
+// GraphQLErrors are the errors of a GraphQL response. A response can have
+// data as well as errors, for the fields that didn't fail; that data is
+// still decoded, so callers can use a partial result:
+//
+//	err := client.Query(ctx, query, vars, &out)
+//	var gqlErrs apiclient.GraphQLErrors
+//	if errors.As(err, &gqlErrs) && out.User != nil { ... }
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	if len(e) == 1 {
+		return "graphql: " + e[0].Error()
+	}
+	return fmt.Sprintf("graphql: %s (and %d more errors)", e[0].Error(), len(e)-1)
+}
+
+func (e GraphQLErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Query runs a GraphQL query with vars at api.graphql_path and decodes its
+// data into out, when set. Queries change nothing, so they're retried as
+// GETs are. An error the API reports is a GraphQLErrors; an unsuccessful
+// status without one is a *model.HTTPError.
+//
+//	var out struct {
+//		User struct{ Name string } `json:"user"`
+//	}
+//	err := client.Query(ctx, `query($id: ID!) { user(id: $id) { name } }`,
+//		map[string]interface{}{"id": id}, &out)
+func (c *Client) Query(ctx context.Context, query string, vars map[string]interface{}, out interface{}) error {
+	return c.graphql(ctx, GraphQLRequest{Query: query, Variables: vars}, false, out)
+}
+
+// Mutate runs a GraphQL mutation as Query runs a query. Mutations aren't
+// retried, as they may have been applied.
+func (c *Client) Mutate(ctx context.Context, mutation string, vars map[string]interface{}, out interface{}) error {
+	return c.graphql(ctx, GraphQLRequest{Query: mutation, Variables: vars}, true, out)
+}
+
+// GraphQL runs req as Query or Mutate, by the document's first keyword
+func (c *Client) GraphQL(ctx context.Context, req GraphQLRequest, out interface{}) error {
+	return c.graphql(ctx, req, isMutation(req.Query), out)
+}
+
+// graphql sends an operation. With api.persisted_queries, it's sent first
+// by its hash alone, and again with the query once the API says it
+// doesn't know the hash, after which the API does.
+func (c *Client) graphql(ctx context.Context, req GraphQLRequest, mutation bool, out interface{}) error {
+	body := graphqlBody{GraphQLRequest: req}
+	if !c.persistedQueries {
+		return c.sendGraphQL(ctx, body, mutation, out)
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	body.Extensions = &graphqlExtensions{PersistedQuery: persistedQuery{Version: 1, SHA256Hash: hex.EncodeToString(sum[:])}}
+	byHash := body
+	byHash.Query = ""
+	err := c.sendGraphQL(ctx, byHash, mutation, out)
+	if !persistedQueryMissed(err) {
+		return err
+	}
+	return c.sendGraphQL(ctx, body, mutation, out)
+}
+
+// sendGraphQL sends body to the GraphQL endpoint and decodes the response.
+// A query sent by its hash alone is a GET, so responses can be cached;
+// anything else is a POST.
+func (c *Client) sendGraphQL(ctx context.Context, body graphqlBody, mutation bool, out interface{}) error {
+	u := c.URL(c.graphqlPath)
+	if !strings.Contains(u, "://") {
+		return errors.New("api.base_url is not set")
+	}
+	if !mutation {
+		ctx = withRepeatable(ctx)
+	}
+
+	var req *http.Request
+	var err error
+	if body.Query == "" && !mutation {
+		if u, err = graphqlURL(u, body); err != nil {
+			return err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	} else {
+		data, merr := json.Marshal(body)
+		if merr != nil {
+			return fmt.Errorf("encoding request: %w", merr)
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", req.URL.Redacted(), err)
+	}
+	failed := resp.StatusCode < 200 || resp.StatusCode >= 300
+
+	var gr graphqlResponse
+	jerr := json.Unmarshal(data, &gr)
+	if jerr != nil || (gr.Data == nil && gr.Errors == nil) {
+		if failed {
+			return statusError(req, resp, data[:min(len(data), maxErrorBody)])
+		}
+		if jerr == nil {
+			jerr = errors.New("neither data nor errors in the response")
+		}
+		return fmt.Errorf("parsing %s: %w", req.URL.Redacted(), jerr)
+	}
+
+	if out != nil && len(gr.Data) > 0 && !bytes.Equal(gr.Data, []byte("null")) {
+		if err := json.Unmarshal(gr.Data, out); err != nil {
+			return fmt.Errorf("parsing data from %s: %w", req.URL.Redacted(), err)
+		}
+	}
+	switch {
+	case len(gr.Errors) > 0 && failed:
+		return fmt.Errorf("%w: %w", gr.Errors, statusError(req, resp, nil))
+	case len(gr.Errors) > 0:
+		return gr.Errors
+	case failed:
+		return statusError(req, resp, data[:min(len(data), maxErrorBody)])
+	}
+	return nil
+}
+
+// graphqlURL returns u with body as query parameters, as GraphQL over
+// HTTP sends a GET
+func graphqlURL(u string, body graphqlBody) (string, error) {
+	params := map[string]string{"operationName": body.OperationName}
+	if body.Variables != nil {
+		data, err := json.Marshal(body.Variables)
+		if err != nil {
+			return "", fmt.Errorf("encoding variables: %w", err)
+		}
+		params["variables"] = string(data)
+	}
+	if body.Extensions != nil {
+		data, err := json.Marshal(body.Extensions)
+		if err != nil {
+			return "", err
+		}
+		params["extensions"] = string(data)
+	}
+	return withQuery(u, params)
+}
+
+// persistedQueryMissed reports whether err says the API doesn't know a
+// query's hash, or doesn't take queries by hash at all
+func persistedQueryMissed(err error) bool {
+	var errs GraphQLErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	for _, e := range errs {
+		switch {
+		case e.Code() == "PERSISTED_QUERY_NOT_FOUND", e.Code() == "PERSISTED_QUERY_NOT_SUPPORTED",
+			e.Message == "PersistedQueryNotFound", e.Message == "PersistedQueryNotSupported":
+			return true
+		}
+	}
+	return false
+}
+
+// isMutation reports whether a GraphQL document starts with a mutation,
+// after any comments
+func isMutation(doc string) bool {
+	for {
+		doc = strings.TrimLeft(doc, " \t\r\n,\ufeff")
+		if !strings.HasPrefix(doc, "#") {
+			break
+		}
+		_, doc, _ = strings.Cut(doc, "\n")
+	}
+	return strings.HasPrefix(doc, "mutation")
+}
@@ -0,0 +1,249 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing is how long the phases of one request took. Phases that didn't
+// happen, such as DNS and connect on a reused connection, are zero.
+type Timing struct {
+	Method  string
+	Host    string
+	Status  int // 0 when the request failed
+	Reused  bool
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // Until the first byte of the response
+	Total   time.Duration // Until the response headers were read
+}
+
+// Metrics aggregates the timings of requests. It's safe for concurrent
+// use.
+type Metrics struct {
+	// OnRequest, when set, is called with each request's timing, e.g. to
+	// emit a tracing span
+	OnRequest func(Timing)
+
+	mu       sync.Mutex
+	requests int
+	reused   int
+	failed   int
+	phases   [numPhases]Phase
+}
+
+// Phase summarizes one phase over the requests that went through it
+type Phase struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Avg returns the phase's average duration
+func (p Phase) Avg() time.Duration {
+	if p.Count == 0 {
+		return 0
+	}
+	return p.Total / time.Duration(p.Count)
+}
+
+func (p *Phase) add(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	p.Count++
+	p.Total += d
+	p.Max = max(p.Max, d)
+}
+
+const (
+	phaseDNS = iota
+	phaseConnect
+	phaseTLS
+	phaseTTFB
+	phaseTotal
+	numPhases
+)
+
+var phaseNames = [numPhases]string{"dns", "connect", "tls", "ttfb", "total"}
+
+// Record adds a request's timing
+func (m *Metrics) Record(t Timing) {
+	m.mu.Lock()
+	m.requests++
+	if t.Reused {
+		m.reused++
+	}
+	if t.Status == 0 {
+		m.failed++
+	}
+	for i, d := range [numPhases]time.Duration{t.DNS, t.Connect, t.TLS, t.TTFB, t.Total} {
+		m.phases[i].add(d)
+	}
+	onRequest := m.OnRequest
+	m.mu.Unlock()
+
+	if onRequest != nil {
+		onRequest(t)
+	}
+}
+
+// Summary is a snapshot of Metrics
+type Summary struct {
+	Requests int
+	Reused   int // On a kept-alive connection
+	Failed   int // Without a response
+	DNS      Phase
+	Connect  Phase
+	TLS      Phase
+	TTFB     Phase
+	Total    Phase
+}
+
+// Summary returns the metrics so far
+func (m *Metrics) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Summary{
+		Requests: m.requests,
+		Reused:   m.reused,
+		Failed:   m.failed,
+		DNS:      m.phases[phaseDNS],
+		Connect:  m.phases[phaseConnect],
+		TLS:      m.phases[phaseTLS],
+		TTFB:     m.phases[phaseTTFB],
+		Total:    m.phases[phaseTotal],
+	}
+}
+
+// String formats the summary for --stats:
+//
+//	HTTP: 12 requests, 10 on reused connections, 0 failed
+//	  dns       avg 12ms        max 30ms        (2 timed)
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP: %d requests, %d on reused connections, %d failed\n", s.Requests, s.Reused, s.Failed)
+	for i, p := range [numPhases]Phase{s.DNS, s.Connect, s.TLS, s.TTFB, s.Total} {
+		if p.Count == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-8s  avg %-10s  max %-10s  (%d timed)\n",
+			phaseNames[i], p.Avg().Round(time.Microsecond), p.Max.Round(time.Microsecond), p.Count)
+	}
+	return b.String()
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns a copy of ctx whose requests are recorded in m
+func WithMetrics(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// MetricsFromContext returns the metrics carried by ctx, or nil
+func MetricsFromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(metricsKey{}).(*Metrics)
+	return m
+}
+
+// MetricsTransport times the phases of each request with httptrace,
+// records them in the Metrics carried by the request's context, if any,
+// and logs them at debug level
+type MetricsTransport struct {
+	Base http.RoundTripper // http.DefaultTransport when nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *MetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	tr := &tracer{timing: Timing{Method: req.Method, Host: req.URL.Host}}
+	start := time.Now()
+	resp, err := base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), tr.trace(start))))
+
+	tr.mu.Lock()
+	timing := tr.timing
+	tr.mu.Unlock()
+	timing.Total = time.Since(start)
+	if err == nil {
+		timing.Status = resp.StatusCode
+	}
+
+	slog.Debug("http request", "method", timing.Method, "host", timing.Host, "status", timing.Status,
+		"reused", timing.Reused, "dns", timing.DNS, "connect", timing.Connect, "tls", timing.TLS,
+		"ttfb", timing.TTFB, "total", timing.Total)
+	if m := MetricsFromContext(req.Context()); m != nil {
+		m.Record(timing)
+	}
+	return resp, err
+}
+
+// tracer collects a Timing from httptrace callbacks, which may run on
+// other goroutines, e.g. while dialing several addresses
+type tracer struct {
+	mu           sync.Mutex
+	timing       Timing
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+}
+
+func (tr *tracer) trace(start time.Time) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.timing.DNS = time.Since(tr.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			if tr.connectStart.IsZero() {
+				tr.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			if err == nil {
+				tr.timing.Connect = time.Since(tr.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.timing.TLS = time.Since(tr.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.timing.Reused = info.Reused
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			defer tr.mu.Unlock()
+			tr.timing.TTFB = time.Since(start)
+		},
+	}
+}
@@ -0,0 +1,105 @@
+package apiclient
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/logger"
+)
+
+// Middleware wraps the transport a client sends requests through, e.g. to
+// trace, sign, cache, or mock them: it returns a RoundTripper that handles
+// a request by calling next, or answers it without doing so.
+//
+//	client.Use(func(next http.RoundTripper) http.RoundTripper {
+//		return apiclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//			req = req.Clone(req.Context())
+//			req.Header.Set("X-Signature", sign(req))
+//			return next.RoundTrip(req)
+//		})
+//	})
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a function used as an http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var (
+	registeredMu sync.RWMutex
+	registered   []Middleware
+)
+
+// RegisterMiddleware adds middlewares to every client New returns after
+// it's called, e.g. to trace all API requests a project makes. Call it
+// from an init function. Registered middlewares run before those added
+// with Use.
+func RegisterMiddleware(mw ...Middleware) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+	registered = append(registered, mw...)
+}
+
+// registeredMiddlewares returns the middlewares RegisterMiddleware added
+func registeredMiddlewares() []Middleware {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+	return slices.Clone(registered)
+}
+
+// Use adds middlewares to the requests c sends, the first added running
+// first. They run after the built-in ones (see New) have set the user
+// agent and credentials, and before the cache, retries, and the rate
+// limit, so each sees a request once, as it will be sent, however often
+// it's retried.
+// Call it before sending requests.
+func (c *Client) Use(mw ...Middleware) {
+	c.middlewares = append(c.middlewares, mw...)
+	c.HTTP.Transport = chain(c.transport, c.middlewares)
+}
+
+// chain wraps rt in middlewares, the first outermost
+func chain(rt http.RoundTripper, middlewares []Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// UserAgent sends ua as the User-Agent of requests that don't set one
+func UserAgent(ua string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if ua == "" || req.Header.Get("User-Agent") != "" {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("User-Agent", ua)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Logging logs each request with the context's logger once it's done: its
+// method, URL, status or error, and how long it took, retries included
+func Logging() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "duration", time.Since(start).Round(time.Millisecond)}
+			if err != nil {
+				logger.FromContext(ctx).InfoContext(ctx, "api request failed", append(attrs, "error", err)...)
+			} else {
+				logger.FromContext(ctx).InfoContext(ctx, "api request", append(attrs, "status", resp.StatusCode)...)
+			}
+			return resp, err
+		})
+	}
+}
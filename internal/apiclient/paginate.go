@@ -0,0 +1,340 @@
+package apiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// defaultPageSize is the limit NumberedPages asks for when
+// PageOptions.Limit isn't set
+const defaultPageSize = 100
+
+// nextFields are where CursorPages looks for the next cursor when
+// CursorOptions.NextField isn't set
+var nextFields = []string{"next_cursor", "next", "meta.next_cursor", "pagination.next_cursor"}
+
+// PageFunc fetches the page of a list at cursor, "" for the first, and
+// returns its items and the cursor of the page after it, "" after the last
+type PageFunc[T any] func(ctx context.Context, cursor string) (items []T, next string, err error)
+
+// Paginator iterates over a list an API returns a page at a time, fetching
+// each page once the items before it run out, so list commands don't each
+// loop over pages. CursorPages and NumberedPages paginate the two common
+// ways; NewPaginator any other.
+//
+//	p := apiclient.CursorPages[User](client, "users", apiclient.CursorOptions{})
+//	for p.Next(ctx) {
+//		user := p.Item()
+//		...
+//	}
+//	if err := p.Err(); err != nil { ... }
+//
+// A Paginator is for one goroutine at a time.
+type Paginator[T any] struct {
+	fetch   PageFunc[T]
+	cursor  string // Of the page to fetch next
+	started bool   // The first page was fetched
+	items   []T    // Left of the page fetched
+	item    T
+	err     error
+}
+
+// NewPaginator returns a paginator fetching pages with fetch
+func NewPaginator[T any](fetch PageFunc[T]) *Paginator[T] {
+	return &Paginator[T]{fetch: fetch}
+}
+
+// Next moves to the next item, fetching the next page when needed, and
+// reports whether there is one. Once it returns false, Err says whether
+// the list ended or fetching a page failed.
+func (p *Paginator[T]) Next(ctx context.Context) bool {
+	for len(p.items) == 0 {
+		if p.err != nil || (p.started && p.cursor == "") {
+			return false
+		}
+		items, next, err := p.fetch(ctx, p.cursor)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		if next != "" && next == p.cursor {
+			// The API would return this page forever
+			p.err = fmt.Errorf("the page after cursor %q has the same cursor", next)
+			return false
+		}
+		p.started, p.cursor, p.items = true, next, items
+	}
+	p.item, p.items = p.items[0], p.items[1:]
+	return true
+}
+
+// Item returns the item Next moved to
+func (p *Paginator[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error that stopped Next, or nil once the list ended
+func (p *Paginator[T]) Err() error {
+	return p.err
+}
+
+// All returns the items left, fetching every page after the current one
+func (p *Paginator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for p.Next(ctx) {
+		items = append(items, p.Item())
+	}
+	return items, p.Err()
+}
+
+// Rows returns the items left as output.Rows, to print with
+// Formatter.PrintRows as the pages arrive:
+//
+//	err := formatter.PrintRows(nil, p.Rows(ctx))
+func (p *Paginator[T]) Rows(ctx context.Context) func(yield func(row interface{}) bool) error {
+	return func(yield func(row interface{}) bool) error {
+		for p.Next(ctx) {
+			if !yield(p.Item()) {
+				return nil
+			}
+		}
+		return p.Err()
+	}
+}
+
+// CursorOptions configure how CursorPages pages
+type CursorOptions struct {
+	Param      string // Query parameter the cursor is sent in; "cursor" when empty
+	NextField  string // Field of a page holding the next cursor, e.g. meta.next; next_cursor, next, meta.next_cursor, or pagination.next_cursor when empty
+	ItemsField string // Field of a page holding its items, e.g. data.users; the page itself, or its items or data, when empty
+	LimitParam string // Query parameter the page size is sent in; "limit" when empty
+	Limit      int    // Items per page asked for; the API's default when 0
+}
+
+// CursorPages returns a paginator over the list at path for APIs that
+// return, with each page, a cursor to send for the next: in the field
+// opts.NextField, or as a Link header with rel="next". A next "cursor" that
+// is a URL, absolute or relative, is fetched as it is. The list ends with a
+// page without a cursor.
+func CursorPages[T any](c *Client, path string, opts CursorOptions) *Paginator[T] {
+	if opts.Param == "" {
+		opts.Param = "cursor"
+	}
+	if opts.LimitParam == "" {
+		opts.LimitParam = "limit"
+	}
+	return NewPaginator(func(ctx context.Context, cursor string) ([]T, string, error) {
+		u := c.URL(path)
+		if isPageURL(cursor) {
+			base, err := url.Parse(u)
+			if err != nil {
+				return nil, "", err
+			}
+			ref, err := url.Parse(cursor)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid next page %q: %w", cursor, err)
+			}
+			u = base.ResolveReference(ref).String()
+		} else {
+			params := map[string]string{opts.Param: cursor}
+			if opts.Limit > 0 {
+				params[opts.LimitParam] = strconv.Itoa(opts.Limit)
+			}
+			var err error
+			if u, err = withQuery(u, params); err != nil {
+				return nil, "", err
+			}
+		}
+
+		var raw json.RawMessage
+		header, err := c.do(ctx, http.MethodGet, u, nil, &raw)
+		if err != nil {
+			return nil, "", err
+		}
+		items, err := pageItems[T](raw, opts.ItemsField)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing page of %s: %w", path, err)
+		}
+		return items, nextCursor(raw, header, opts.NextField), nil
+	})
+}
+
+// PageOptions configure how NumberedPages pages
+type PageOptions struct {
+	PageParam  string // Query parameter the page number is sent in; "page" when empty
+	LimitParam string // Query parameter the page size is sent in; "limit" when empty
+	Limit      int    // Items per page; 100 when 0
+	First      int    // Number of the first page; 1 when 0
+	ItemsField string // Field of a page holding its items, as in CursorOptions
+}
+
+// NumberedPages returns a paginator over the list at path for APIs that
+// number their pages, sending the page number and size as query
+// parameters. The list ends with a page of fewer than opts.Limit items.
+func NumberedPages[T any](c *Client, path string, opts PageOptions) *Paginator[T] {
+	if opts.PageParam == "" {
+		opts.PageParam = "page"
+	}
+	if opts.LimitParam == "" {
+		opts.LimitParam = "limit"
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageSize
+	}
+	if opts.First == 0 {
+		opts.First = 1
+	}
+	return NewPaginator(func(ctx context.Context, cursor string) ([]T, string, error) {
+		page := opts.First
+		if cursor != "" {
+			page, _ = strconv.Atoi(cursor) // Only ever set below
+		}
+		u, err := withQuery(c.URL(path), map[string]string{
+			opts.PageParam:  strconv.Itoa(page),
+			opts.LimitParam: strconv.Itoa(opts.Limit),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var raw json.RawMessage
+		if _, err := c.do(ctx, http.MethodGet, u, nil, &raw); err != nil {
+			return nil, "", err
+		}
+		items, err := pageItems[T](raw, opts.ItemsField)
+		if err != nil {
+			return nil, "", fmt.Errorf("parsing page %d of %s: %w", page, path, err)
+		}
+		if len(items) < opts.Limit {
+			return items, "", nil
+		}
+		return items, strconv.Itoa(page + 1), nil
+	})
+}
+
+// withQuery returns u with params set in its query
+func withQuery(u string, params map[string]string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	q := parsed.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	parsed.RawQuery = q.Encode()
+	return parsed.String(), nil
+}
+
+// isPageURL reports whether a next cursor is the URL of the next page
+// rather than a token to send
+func isPageURL(cursor string) bool {
+	return strings.Contains(cursor, "://") || strings.HasPrefix(cursor, "/")
+}
+
+// pageItems decodes the items of a page: the array at field, or, when
+// field is empty, the page itself when it's an array, or else its items
+// or data
+func pageItems[T any](raw json.RawMessage, field string) ([]T, error) {
+	if field != "" {
+		raw = jsonField(raw, field)
+	} else if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] != '[' {
+		if raw = jsonField(trimmed, "items"); raw == nil {
+			raw = jsonField(trimmed, "data")
+		}
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("no list of items in %s", describeField(field))
+	}
+	var items []T
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// describeField names where pageItems looked for items
+func describeField(field string) string {
+	if field == "" {
+		return "the page, or its items or data"
+	}
+	return field
+}
+
+// nextCursor returns the cursor of the page after raw: the string or
+// number at field, or the first of nextFields set, or else the Link
+// header's next URL
+func nextCursor(raw json.RawMessage, header http.Header, field string) string {
+	fields := nextFields
+	if field != "" {
+		fields = []string{field}
+	}
+	for _, f := range fields {
+		v := jsonField(raw, f)
+		if v == nil {
+			continue
+		}
+		var s string
+		if json.Unmarshal(v, &s) == nil {
+			if s != "" {
+				return s
+			}
+			continue
+		}
+		var n json.Number
+		if json.Unmarshal(v, &n) == nil {
+			return n.String()
+		}
+	}
+	return linkNext(header)
+}
+
+// jsonField returns the value at a dotted path, such as meta.next, in a
+// JSON object, or nil when it isn't there or is null
+func jsonField(raw json.RawMessage, path string) json.RawMessage {
+	for _, key := range strings.Split(path, ".") {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) != nil {
+			return nil
+		}
+		if raw = obj[key]; raw == nil {
+			return nil
+		}
+	}
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+	return raw
+}
+
+// linkNext returns the URL of a Link header's rel="next" link, as GitHub's
+// API and RFC 8288 send them:
+//
+//	Link: <https://api.example.com/users?page=3>; rel="next", <...>; rel="last"
+func linkNext(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(strings.TrimSpace(link), ";")
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				isNext := func(r string) bool { return strings.EqualFold(r, "next") }
+				if strings.EqualFold(name, "rel") && slices.ContainsFunc(strings.Fields(strings.Trim(rel, `"`)), isNext) {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package apiclient
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitTransport holds requests back to a rate, waiting for a token
+// from Limiter before each one, including each retry, so batch commands
+// stay under the server's limits. Waiting ends with the request's context.
+type RateLimitTransport struct {
+	Base    http.RoundTripper
+	Limiter *rate.Limiter // nil sends requests as they come
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Limiter != nil {
+		if err := t.Limiter.Wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("waiting for rate limit: %w", err)
+		}
+	}
+	return t.Base.RoundTrip(req)
+}
+
+// limiterKey identifies the limiter shared by clients of one API
+type limiterKey struct {
+	host          string
+	perSec, burst int
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[limiterKey]*rate.Limiter{}
+)
+
+// sharedLimiter returns the limiter for perSec requests a second to host,
+// in bursts of up to burst (perSec when 0), shared by every client of the
+// process so that handlers and daemon commands running at once keep to
+// the rate together. It's nil when perSec is 0, i.e. unlimited.
+func sharedLimiter(host string, perSec, burst int) *rate.Limiter {
+	if perSec <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = perSec
+	}
+	key := limiterKey{host: host, perSec: perSec, burst: burst}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[key]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(perSec), burst)
+		limiters[key] = l
+	}
+	return l
+}
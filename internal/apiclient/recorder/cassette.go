@@ -0,0 +1,191 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the values of headers carrying credentials
+const redacted = "REDACTED"
+
+// redactedHeaders are the request headers, and redactedResponseHeaders the
+// response headers, whose values aren't recorded
+var (
+	redactedHeaders         = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-API-Key"}
+	redactedResponseHeaders = []string{"Set-Cookie"}
+)
+
+// cassette is the file interactions are recorded in
+type cassette struct {
+	Interactions []interaction `yaml:"interactions"`
+}
+
+// interaction is a request and the response the API gave it
+type interaction struct {
+	Request  recordedRequest  `yaml:"request"`
+	Response recordedResponse `yaml:"response"`
+}
+
+type recordedRequest struct {
+	Method string      `yaml:"method"`
+	URL    string      `yaml:"url"`
+	Header http.Header `yaml:"headers,omitempty"`
+	body   `yaml:",inline"`
+}
+
+type recordedResponse struct {
+	Status     string      `yaml:"status"`
+	StatusCode int         `yaml:"status_code"`
+	Header     http.Header `yaml:"headers,omitempty"`
+	body       `yaml:",inline"`
+}
+
+// body is a recorded body: as text when it's UTF-8, as base64 otherwise
+type body struct {
+	Body       string `yaml:"body,omitempty"`
+	BodyBase64 string `yaml:"body_base64,omitempty"`
+}
+
+func newBody(data []byte) body {
+	if utf8.Valid(data) {
+		return body{Body: string(data)}
+	}
+	return body{BodyBase64: base64.StdEncoding.EncodeToString(data)}
+}
+
+func (b body) bytes() []byte {
+	if b.BodyBase64 != "" {
+		data, _ := base64.StdEncoding.DecodeString(b.BodyBase64)
+		return data
+	}
+	return []byte(b.Body)
+}
+
+func newRecordedRequest(req *http.Request, data []byte) recordedRequest {
+	return recordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: redact(req.Header, redactedHeaders),
+		body:   newBody(data),
+	}
+}
+
+func newRecordedResponse(resp *http.Response, data []byte) recordedResponse {
+	return recordedResponse{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     redact(resp.Header, redactedResponseHeaders),
+		body:       newBody(data),
+	}
+}
+
+// matches reports whether a request with method, url, and data is the
+// one recorded
+func (r recordedRequest) matches(method, url string, data []byte) bool {
+	return r.Method == method && r.URL == url && sameBody(r.bytes(), data)
+}
+
+// sameBody reports whether two request bodies are the same, or the same
+// JSON with keys in another order or laid out differently
+func sameBody(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va) // Sorts object keys
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+// toHTTP returns the recorded response as the answer to req
+func (r recordedResponse) toHTTP(req *http.Request) *http.Response {
+	data := r.bytes()
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	status := r.Status
+	if status == "" {
+		status = strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode)
+	}
+	return &http.Response{
+		Status:        status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}
+}
+
+// redact returns a copy of header with the values of names replaced
+func redact(header http.Header, names []string) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	header = header.Clone()
+	for _, name := range names {
+		if values := header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = []string{redacted}
+		}
+	}
+	return header
+}
+
+func readCassette(path string) (*cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c cassette
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// writeCassette writes c to path through a temporary file, so a cassette
+// is never left half written
+func writeCassette(path string, c *cassette) error {
+	var buf strings.Builder
+	buf.WriteString("# Recorded by termplate's recorder; record again with " + EnvMode + "=record\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".cassette-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(buf.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,228 @@
+// Package recorder records the HTTP requests a client sends, and the API's
+// responses, to a YAML cassette, and replays them from it, so tests of
+// code built on the API client run against real API responses without the
+// API. Whether a test records or replays is chosen by the TERMPLATE_RECORD
+// environment variable, so the same test records against the live API
+// once and replays from then on, in CI too:
+//
+//	func TestListUsers(t *testing.T) {
+//		rec, err := recorder.Open("testdata/list_users.yaml", recorder.ModeFromEnv())
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		client, _ := apiclient.New(cfg)
+//		client.Use(rec.Middleware)
+//		...
+//	}
+//
+// Code creating its own clients, such as handlers, records with
+// apiclient.RegisterMiddleware(rec.Middleware) in TestMain. A whole
+// termplate process records and replays with TERMPLATE_CASSETTE set to the
+// cassette.
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvMode is the environment variable ModeFromEnv reads: replay, record,
+// once, or off
+const EnvMode = "TERMPLATE_RECORD"
+
+// EnvCassette is the environment variable naming the cassette every API
+// client of a termplate process records to or replays from, in the mode
+// EnvMode sets
+const EnvCassette = "TERMPLATE_CASSETTE"
+
+// Mode is what a Recorder does with requests
+type Mode int
+
+const (
+	// ModeOnce records when the cassette doesn't exist yet and replays
+	// from it when it does
+	ModeOnce Mode = iota
+	// ModeReplay answers requests from the cassette only; a request it
+	// has no interaction for fails
+	ModeReplay
+	// ModeRecord sends requests to the API and records them, replacing
+	// the cassette
+	ModeRecord
+	// ModeOff sends requests to the API without recording them
+	ModeOff
+)
+
+var modeNames = map[Mode]string{ModeOnce: "once", ModeReplay: "replay", ModeRecord: "record", ModeOff: "off"}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses a mode name; "" is ModeOnce
+func ParseMode(s string) (Mode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return ModeOnce, nil
+	}
+	for m, name := range modeNames {
+		if s == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid %s %q: must be once, replay, record, or off", EnvMode, s)
+}
+
+// ModeFromEnv returns the mode EnvMode sets. Unset, it's ModeOnce; set to
+// an unknown mode, it's ModeReplay, so a typo never sends requests to the
+// live API.
+func ModeFromEnv() Mode {
+	m, err := ParseMode(os.Getenv(EnvMode))
+	if err != nil {
+		return ModeReplay
+	}
+	return m
+}
+
+// Recorder records requests to a cassette, or replays them from it, as a
+// middleware of the API client. Credentials are never written: the values
+// of the Authorization, Cookie, Proxy-Authorization, and X-API-Key request
+// headers, and of Set-Cookie response headers, are recorded as REDACTED.
+// Requests are matched by method, URL, and body, JSON bodies regardless of
+// the order of their keys; a request sent again gets the next interaction
+// recorded for it, and the last one once they've all been replayed.
+type Recorder struct {
+	path string
+	mode Mode
+
+	mu       sync.Mutex
+	cassette *cassette
+	used     []bool // Interactions already replayed, by index
+}
+
+// Open returns a recorder for the cassette at path in mode. Replaying, the
+// cassette is read now; recording, it's written after each interaction,
+// its directory created as needed.
+func Open(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, cassette: &cassette{}}
+	if mode == ModeOnce || mode == ModeReplay {
+		c, err := readCassette(path)
+		switch {
+		case err == nil:
+			r.mode, r.cassette = ModeReplay, c
+		case mode == ModeOnce && errors.Is(err, fs.ErrNotExist):
+			r.mode = ModeRecord
+		default:
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Mode returns what r does: ModeReplay or ModeRecord when opened with
+// ModeOnce, depending on whether the cassette existed
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Middleware records or replays the requests sent through next; it's an
+// apiclient.Middleware
+func (r *Recorder) Middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		switch r.mode {
+		case ModeReplay:
+			return r.replay(req)
+		case ModeRecord:
+			return r.record(req, next)
+		default:
+			return next.RoundTrip(req)
+		}
+	})
+}
+
+// replay answers req with the interaction recorded for it
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, in := range r.cassette.Interactions {
+		if !in.Request.matches(req.Method, req.URL.String(), body) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return in.Response.toHTTP(req), nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("no interaction recorded in %s for %s %s", r.path, req.Method, req.URL.Redacted())
+	}
+	return r.cassette.Interactions[last].Response.toHTTP(req), nil
+}
+
+// record sends req through next and adds the interaction to the cassette
+func (r *Recorder) record(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	req, body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("recording response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction{
+		Request:  newRecordedRequest(req, body),
+		Response: newRecordedResponse(resp, respBody),
+	})
+	r.used = append(r.used, true)
+	if err := writeCassette(r.path, r.cassette); err != nil {
+		return nil, fmt.Errorf("writing cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// readRequestBody returns req's body, and a copy of req to send it with
+func readRequestBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading request body: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return req, body, nil
+}
+
+// roundTripperFunc is a function used as an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
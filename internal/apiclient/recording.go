@@ -0,0 +1,23 @@
+package apiclient
+
+import (
+	"os"
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/apiclient/recorder"
+)
+
+// processRecorder returns the recorder every client of this process
+// records to, or replays from, when recorder.EnvCassette names a cassette,
+// and nil otherwise
+var processRecorder = sync.OnceValues(func() (*recorder.Recorder, error) {
+	path := os.Getenv(recorder.EnvCassette)
+	if path == "" {
+		return nil, nil
+	}
+	mode, err := recorder.ParseMode(os.Getenv(recorder.EnvMode))
+	if err != nil {
+		return nil, err
+	}
+	return recorder.Open(path, mode)
+})
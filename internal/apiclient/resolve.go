@@ -0,0 +1,86 @@
+package apiclient
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// DialFunc dials a network address, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Overrides holds host overrides: exact hosts, and *.domain entries
+// matching its subdomains
+type Overrides struct {
+	hosts map[string]string
+}
+
+// NewOverrides returns the overrides in list; later entries for the same
+// host win
+func NewOverrides(list []config.HostOverride) *Overrides {
+	o := &Overrides{hosts: make(map[string]string, len(list))}
+	for _, h := range list {
+		o.hosts[h.Host] = h.Addr
+	}
+	return o
+}
+
+// Lookup returns the address overriding host: its own entry, or else the
+// entry of the closest *.domain above it
+func (o *Overrides) Lookup(host string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if addr, ok := o.hosts[host]; ok {
+		return addr, true
+	}
+	for domain := host; ; {
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return "", false
+		}
+		if addr, ok := o.hosts["*."+parent]; ok {
+			return addr, true
+		}
+		domain = parent
+	}
+}
+
+// Dial returns dial redirecting connections to overridden hosts. The port
+// of the address dialed is kept unless the override has its own.
+func (o *Overrides) Dial(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		target, ok := o.Lookup(host)
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			target = net.JoinHostPort(target, port)
+		}
+		slog.Debug("host override", "host", host, "addr", target)
+		return dial(ctx, network, target)
+	}
+}
+
+// newDialer returns the dialer for cfg: http.DefaultTransport's settings,
+// resolving with cfg.DNSServer when set
+func newDialer(cfg config.APIConfig) *net.Dialer {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if cfg.DNSServer != "" {
+		server := cfg.DNSServer
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var sd net.Dialer
+				return sd.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return d
+}
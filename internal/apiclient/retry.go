@@ -0,0 +1,129 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/outbox"
+	"github.com/blacksilver/termplate-go/internal/retry"
+)
+
+// idempotentMethods can be repeated without changing the result
+var idempotentMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete}
+
+// RetryTransport retries requests that fail with a transient error (see
+// retry.IsRetryable) or one of Statuses, waiting with exponential backoff
+// and jitter (see retry.Backoff), or as long as a Retry-After header asks.
+// Only requests that are safe to repeat are retried: those with an
+// idempotent method, others with an Idempotency-Key header, and those the
+// client knows change nothing, such as GraphQL queries. Each retry
+// is logged with the context's logger and recorded as an event.
+type RetryTransport struct {
+	Base     http.RoundTripper
+	Attempts int           // Retries after the first try
+	Delay    time.Duration // Wait before the first retry, doubled for each one after
+	MaxDelay time.Duration // Longest wait, including one a Retry-After header asks for; zero is unlimited
+	Statuses []int         // Statuses retried; retry.DefaultStatuses when nil
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Attempts <= 0 || !repeatable(req) {
+		return t.Base.RoundTrip(req)
+	}
+	ctx := req.Context()
+	for n := 1; ; n++ {
+		try := req
+		if n > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("rewinding request body: %w", err)
+			}
+			try = req.Clone(ctx)
+			try.Body = body
+		}
+		resp, err := t.Base.RoundTrip(try)
+		if n > t.Attempts || !t.retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := retry.Backoff(t.Delay, t.MaxDelay, n)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if d, ok := retry.RetryAfter(resp.Header, time.Now()); ok {
+				delay = d
+				if t.MaxDelay > 0 {
+					delay = min(delay, t.MaxDelay)
+				}
+			}
+			// The connection is reused once the body is read
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+		logger.FromContext(ctx).WarnContext(ctx, "retrying request",
+			"method", req.Method, "url", req.URL.Redacted(), "attempt", n+1, "wait", delay, "reason", reason)
+		events.Record(ctx, events.Warning, "retry", fmt.Sprintf("retrying %s %s: %s", req.Method, req.URL.Path, reason), "attempt", n+1)
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a try that ended with resp or err is worth
+// repeating
+func (t *RetryTransport) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return retry.IsRetryable(err)
+	}
+	statuses := t.Statuses
+	if statuses == nil {
+		statuses = retry.DefaultStatuses
+	}
+	return slices.Contains(statuses, resp.StatusCode)
+}
+
+// repeatableKey marks the context of a request that's safe to repeat
+// whatever its method
+type repeatableKey struct{}
+
+// withRepeatable returns ctx marking its requests as safe to repeat
+func withRepeatable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, repeatableKey{}, true)
+}
+
+// repeatable reports whether req is safe to send again: idempotent,
+// carrying an idempotency key, or marked with withRepeatable, with a body
+// that can be read again
+func repeatable(req *http.Request) bool {
+	marked, _ := req.Context().Value(repeatableKey{}).(bool)
+	if !marked && !slices.Contains(idempotentMethods, req.Method) && req.Header.Get(outbox.IdempotencyKeyHeader) == "" {
+		return false
+	}
+	return replayable(req)
+}
+
+// replayable reports whether req's body, if any, can be read again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// sleep waits for d, or returns ctx's cause once it's done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
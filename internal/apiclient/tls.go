@@ -0,0 +1,54 @@
+package apiclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// newTLSConfig returns the TLS settings of connections to the API, or nil
+// for Go's defaults: the CAs of api.ca_bundle trusted besides the
+// system's, the certificate of api.client_cert_file sent to servers that
+// ask for one, and, when api.verify_ssl is off, no verification
+func newTLSConfig(cfg config.APIConfig) (*tls.Config, error) {
+	if cfg.VerifySSL && cfg.CABundle == "" && cfg.ClientCertFile == "" {
+		return nil, nil
+	}
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	if !cfg.VerifySSL {
+		c.InsecureSkipVerify = true //nolint:gosec // Opted out with api.verify_ssl
+	}
+
+	if cfg.CABundle != "" {
+		data, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading api.ca_bundle: %w", err)
+		}
+		// Added to the system's, so hosts with public certificates, such
+		// as an OAuth provider, still verify
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("reading api.ca_bundle: no PEM certificates in %s", cfg.CABundle)
+		}
+		c.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" {
+		keyFile := cfg.ClientKeyFile
+		if keyFile == "" {
+			keyFile = cfg.ClientCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading api.client_cert_file: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
@@ -0,0 +1,48 @@
+// Package apiclient is the HTTP client for the API configured in the api
+// section: a Client sending JSON requests with the configured credentials,
+// built on a transport tuned by its connection pool, host override, and
+// TLS settings, a response cache on disk, retries and rate limiting,
+// decompression and size limits of response bodies, and the timing of
+// each request's phases.
+package apiclient
+
+import (
+	"net/http"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// NewTransport returns an http.Transport configured by cfg: its connection
+// pool settings, host overrides and DNS server, and TLS settings: a CA
+// bundle, a client certificate for mutual TLS, and, when verify_ssl is
+// off, skipping verification
+func NewTransport(cfg config.APIConfig) (*http.Transport, error) {
+	overrides, err := config.ParseHostOverrides(cfg.HostOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// Only where the connection goes changes: the Host header, TLS server
+	// name (SNI), and certificate check still use the request's host
+	dial := newDialer(cfg).DialContext
+	if len(overrides) > 0 {
+		t.DialContext = NewOverrides(overrides).Dial(dial)
+	} else {
+		t.DialContext = dial
+	}
+	t.MaxIdleConns = cfg.MaxIdleConns
+	t.MaxConnsPerHost = cfg.MaxConnsPerHost
+	t.IdleConnTimeout = cfg.IdleConnTimeout
+	t.DisableKeepAlives = cfg.DisableKeepAlives
+
+	// The client mostly talks to one host, so let it keep as many idle
+	// connections to it as it may open, rather than the default two
+	if cfg.MaxConnsPerHost > t.MaxIdleConnsPerHost {
+		t.MaxIdleConnsPerHost = cfg.MaxConnsPerHost
+	}
+	if t.TLSClientConfig, err = newTLSConfig(cfg); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,102 @@
+// Package budget enforces per-invocation limits on API calls, files
+// touched, and rows mutated, so a mistyped selector can't turn into a mass
+// change. Exceeding a limit requires --confirm-over-budget.
+package budget
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Resource is a kind of work a budget limits
+type Resource string
+
+const (
+	APICalls Resource = "api_calls"
+	Files    Resource = "files"
+	Rows     Resource = "rows"
+)
+
+// Limits are the maximum amounts of each resource; zero means unlimited
+type Limits map[Resource]int
+
+// Budget tracks the resources used by one invocation. A nil *Budget is
+// unlimited, so callers don't have to check for one.
+type Budget struct {
+	limits    Limits
+	confirmed bool
+
+	mu   sync.Mutex
+	used map[Resource]int
+}
+
+// New creates a budget. When confirmed is set, exceeding a limit is logged
+// instead of refused.
+func New(limits Limits, confirmed bool) *Budget {
+	return &Budget{limits: limits, confirmed: confirmed, used: map[Resource]int{}}
+}
+
+// FromConfig creates a budget from the budget config section
+func FromConfig(cfg config.BudgetConfig) *Budget {
+	return New(Limits{
+		APICalls: cfg.MaxAPICalls,
+		Files:    cfg.MaxFiles,
+		Rows:     cfg.MaxRows,
+	}, cfg.Confirm)
+}
+
+// Reserve claims n units of r, failing with an *OverBudgetError when that
+// would exceed the limit. Callers that know the size of an operation up
+// front should reserve it all before starting, so nothing is half done.
+func (b *Budget) Reserve(r Resource, n int) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limit := b.limits[r]
+	total := b.used[r] + n
+	if limit > 0 && total > limit {
+		if !b.confirmed {
+			return &OverBudgetError{Resource: r, Limit: limit, Requested: total}
+		}
+		slog.Warn("over budget, confirmed", "resource", r, "limit", limit, "requested", total)
+	}
+	b.used[r] = total
+	return nil
+}
+
+// Used returns the units of r reserved so far
+func (b *Budget) Used(r Resource) int {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used[r]
+}
+
+// OverBudgetError reports an operation that would exceed a limit
+type OverBudgetError struct {
+	Resource  Resource
+	Limit     int
+	Requested int
+}
+
+func (e *OverBudgetError) Error() string {
+	return fmt.Sprintf("operation needs %d %s, over the budget of %d; pass --confirm-over-budget to proceed",
+		e.Requested, e.Resource, e.Limit)
+}
+
+func (e *OverBudgetError) Unwrap() error {
+	return model.ErrOverBudget
+}
+
+func (e *OverBudgetError) ExitCode() int {
+	return model.ExitOverBudget
+}
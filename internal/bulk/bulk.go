@@ -0,0 +1,234 @@
+// Package bulk runs bulk create/update operations with per-item results
+// and a standard partial-failure model.
+package bulk
+
+//go:generate go run github.com/blacksilver/termplate-go generate mocks --interface Transactor,Tx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/blacksilver/termplate-go/internal/budget"
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/retry"
+)
+
+// Status is the outcome of a single item
+type Status string
+
+const (
+	StatusSucceeded  Status = "succeeded"
+	StatusFailed     Status = "failed"
+	StatusSkipped    Status = "skipped"
+	StatusRolledBack Status = "rolled_back"
+)
+
+// Item is a single unit of work in a bulk operation
+type Item struct {
+	ID    string
+	Apply func(ctx context.Context) error
+}
+
+// Result holds the outcome of a single item
+type Result struct {
+	ID     string `json:"id" yaml:"id"`
+	Status Status `json:"status" yaml:"status"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Options controls how failures are handled
+type Options struct {
+	ContinueOnError bool // Keep processing after an item fails
+	RollbackOnError bool // Roll back all items when any item fails (requires a Transactor)
+
+	// Budget is charged one Resource unit per item before the run starts;
+	// nil is unlimited. Resource defaults to budget.Rows.
+	Budget   *budget.Budget
+	Resource budget.Resource
+}
+
+// Transactor is implemented by backends that support transactions (e.g. a database)
+type Transactor interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// Tx is an open transaction
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+var ErrNoTransactor = errors.New("rollback on error requires a transactional backend")
+
+// Runner executes bulk operations
+type Runner struct {
+	opts Options
+	tx   Transactor
+}
+
+// NewRunner creates a new bulk runner
+func NewRunner(opts Options) *Runner {
+	return &Runner{opts: opts}
+}
+
+// WithTransactor sets the transactional backend used for RollbackOnError
+func (r *Runner) WithTransactor(t Transactor) *Runner {
+	r.tx = t
+	return r
+}
+
+// Run applies every item and returns a report of per-item results. Items
+// failing with transient errors are retried by ctx's retry.Retrier.
+// The returned error is only set when the run itself could not proceed;
+// item failures are reported through Report.Err.
+func (r *Runner) Run(ctx context.Context, items []Item) (*Report, error) {
+	report, err := r.run(ctx, items)
+	if report != nil {
+		report.record(ctx)
+	}
+	return report, err
+}
+
+func (r *Runner) run(ctx context.Context, items []Item) (*Report, error) {
+	if r.opts.RollbackOnError && r.tx == nil {
+		return nil, ErrNoTransactor
+	}
+
+	resource := r.opts.Resource
+	if resource == "" {
+		resource = budget.Rows
+	}
+	if err := r.opts.Budget.Reserve(resource, len(items)); err != nil {
+		return nil, err
+	}
+
+	var tx Tx
+	if r.opts.RollbackOnError {
+		var err error
+		tx, err = r.tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("beginning transaction: %w", err)
+		}
+	}
+
+	report := &Report{Results: make([]Result, 0, len(items))}
+	retrier := retry.FromContext(ctx)
+	stop := false
+	for _, item := range items {
+		if stop {
+			report.Results = append(report.Results, Result{ID: item.ID, Status: StatusSkipped, Reason: model.ErrDependencyFailed.Error()})
+			continue
+		}
+		if ctx.Err() != nil {
+			report.canceled = context.Cause(ctx)
+			report.Results = append(report.Results, Result{ID: item.ID, Status: StatusSkipped, Reason: report.canceled.Error()})
+			continue
+		}
+
+		if err := retrier.Do(ctx, "item "+item.ID, item.Apply); err != nil {
+			slog.DebugContext(ctx, "bulk item failed", "id", item.ID, "error", err)
+			report.Results = append(report.Results, Result{
+				ID:     item.ID,
+				Status: StatusFailed,
+				Reason: err.Error(),
+			})
+			stop = r.opts.RollbackOnError || !r.opts.ContinueOnError
+			continue
+		}
+		report.Results = append(report.Results, Result{ID: item.ID, Status: StatusSucceeded})
+	}
+
+	if tx == nil {
+		return report, nil
+	}
+
+	if report.Failed() > 0 || report.canceled != nil {
+		if err := tx.Rollback(); err != nil {
+			return report, fmt.Errorf("rolling back transaction: %w", err)
+		}
+		report.markRolledBack()
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("committing transaction: %w", err)
+	}
+	return report, nil
+}
+
+// Report summarizes a bulk run
+type Report struct {
+	Results    []Result `json:"results" yaml:"results"`
+	RolledBack bool     `json:"rolled_back" yaml:"rolled_back"`
+
+	canceled error // Why the run stopped early, when its context was canceled
+}
+
+// Succeeded returns the number of items that succeeded
+func (r *Report) Succeeded() int {
+	return r.count(StatusSucceeded)
+}
+
+// Failed returns the number of items that failed
+func (r *Report) Failed() int {
+	return r.count(StatusFailed)
+}
+
+// Err returns why the run was canceled, if it was, or else a partial
+// failure error when any item failed
+func (r *Report) Err() error {
+	if r.canceled != nil {
+		return r.canceled
+	}
+	if failed := r.Failed(); failed > 0 {
+		return model.NewPartialFailureError(failed, len(r.Results))
+	}
+	return nil
+}
+
+// Table returns the results as rows suitable for the output formatter
+func (r *Report) Table() [][]string {
+	table := [][]string{{"ID", "Status", "Reason"}}
+	for _, res := range r.Results {
+		table = append(table, []string{res.ID, string(res.Status), res.Reason})
+	}
+	return table
+}
+
+func (r *Report) count(s Status) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+// record records the run's outcome as an event
+func (r *Report) record(ctx context.Context) {
+	severity, outcome := events.Info, "bulk operation succeeded"
+	switch {
+	case r.canceled != nil:
+		severity, outcome = events.Warning, "bulk operation canceled"
+	case r.Failed() > 0:
+		severity, outcome = events.Error, "bulk operation failed"
+	}
+	if r.RolledBack {
+		outcome += " and was rolled back"
+	}
+	events.Record(ctx, severity, "bulk", fmt.Sprintf("%s: %d of %d items failed", outcome, r.Failed(), len(r.Results)))
+}
+
+// markRolledBack marks all succeeded items as rolled back
+func (r *Report) markRolledBack() {
+	r.RolledBack = true
+	for i := range r.Results {
+		if r.Results[i].Status == StatusSucceeded {
+			r.Results[i].Status = StatusRolledBack
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Code generated by termplate generate mocks; DO NOT EDIT.
+
+package mocks
+
+import (
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+)
+
+// TxMock is a mock of bulk.Tx. Set the Func fields to control
+// its behavior; calls are recorded for assertions.
+type TxMock struct {
+	CommitFunc   func() error
+	RollbackFunc func() error
+
+	mu    sync.Mutex
+	calls struct {
+		Commit   []TxMockCommitCall
+		Rollback []TxMockRollbackCall
+	}
+}
+
+var _ bulk.Tx = (*TxMock)(nil)
+
+// TxMockCommitCall records a call to Commit
+type TxMockCommitCall struct {
+}
+
+// Commit records the call and delegates to CommitFunc
+func (m *TxMock) Commit() error {
+	m.mu.Lock()
+	m.calls.Commit = append(m.calls.Commit, TxMockCommitCall{})
+	m.mu.Unlock()
+
+	if m.CommitFunc == nil {
+		panic("TxMock.CommitFunc is nil but Commit was called")
+	}
+	return m.CommitFunc()
+}
+
+// CommitCalls returns the recorded calls to Commit
+func (m *TxMock) CommitCalls() []TxMockCommitCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]TxMockCommitCall(nil), m.calls.Commit...)
+}
+
+// TxMockRollbackCall records a call to Rollback
+type TxMockRollbackCall struct {
+}
+
+// Rollback records the call and delegates to RollbackFunc
+func (m *TxMock) Rollback() error {
+	m.mu.Lock()
+	m.calls.Rollback = append(m.calls.Rollback, TxMockRollbackCall{})
+	m.mu.Unlock()
+
+	if m.RollbackFunc == nil {
+		panic("TxMock.RollbackFunc is nil but Rollback was called")
+	}
+	return m.RollbackFunc()
+}
+
+// RollbackCalls returns the recorded calls to Rollback
+func (m *TxMock) RollbackCalls() []TxMockRollbackCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]TxMockRollbackCall(nil), m.calls.Rollback...)
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	commandSectionsMu sync.RWMutex
+	commandSections   = map[string]commandSection{} // Registered by BindCommand, by key
+)
+
+// commandSection is a command's config section
+type commandSection struct {
+	typ      reflect.Type           // What it decodes into
+	defaults map[string]interface{} // Its flags' defaults, by setting
+}
+
+// BindCommand gives cmd a config section at key, such as
+// "commands.example.greet", and decodes it into target, a pointer to a
+// struct with mapstructure tags like Config's, before cmd runs. Each of
+// cmd's own flags is the setting of the same name in the section, with
+// dashes as underscores, so --dry-run is commands.example.greet.dry_run.
+// As for other settings, a flag the user sets overrides the environment
+// (TERMPLATE_COMMANDS_EXAMPLE_GREET_DRY_RUN), which overrides the config
+// files; the flag's default applies when none sets it. Fields without a
+// flag keep their value unless a file or environment variable sets them.
+//
+// The section is known to config get, set, and doctor like any other.
+// Call it from an init function once cmd's flags are declared; it panics
+// when key is a setting of Config or already bound, or target isn't a
+// pointer to a struct.
+func BindCommand(cmd *cobra.Command, key string, target interface{}) {
+	key = strings.ToLower(key)
+	t := reflect.TypeOf(target)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config: BindCommand needs a pointer to a struct for %s, got %T", key, target))
+	}
+	if _, ok := configKeyType(key); ok || key == "" {
+		panic(fmt.Sprintf("config: %q is not a free key for a command section", key))
+	}
+
+	defaults := map[string]interface{}{}
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		var value interface{} = f.DefValue
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			value = s.GetSlice()
+		}
+		defaults[flagSetting(f)] = value
+	})
+
+	commandSectionsMu.Lock()
+	if _, ok := commandSections[key]; ok {
+		commandSectionsMu.Unlock()
+		panic(fmt.Sprintf("config: command section %s bound twice", key))
+	}
+	commandSections[key] = commandSection{typ: t.Elem(), defaults: defaults}
+	commandSectionsMu.Unlock()
+
+	// Run first, so the command's own pre-run sees the settings
+	preRunE, preRun := cmd.PreRunE, cmd.PreRun
+	cmd.PreRunE = func(c *cobra.Command, args []string) error {
+		if err := FromContext(c.Context()).bindCommand(c.LocalFlags(), key, target); err != nil {
+			return fmt.Errorf("loading %s settings: %w", key, err)
+		}
+		if preRunE != nil {
+			return preRunE(c, args)
+		}
+		if preRun != nil {
+			preRun(c, args)
+		}
+		return nil
+	}
+	cmd.PreRun = nil
+}
+
+// bindCommand binds flags to the settings of the section key and decodes
+// it into target
+func (l *Loader) bindCommand(flags *pflag.FlagSet, key string, target interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		k := key + "." + flagSetting(f)
+		if l.flags == nil {
+			l.flags = map[string]*pflag.Flag{}
+		}
+		l.flags[k] = f
+		err = l.v.BindPFlag(k, f)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Read key by key, as a section read whole misses flags and
+	// environment variables
+	section := viper.New()
+	for _, k := range sectionKeys(reflect.TypeOf(target).Elem()) {
+		if value := l.v.Get(key + "." + k); value != nil {
+			section.Set(k, value)
+		}
+	}
+	if err := section.Unmarshal(target, decodeHook()); err != nil {
+		return valuesError(err, key)
+	}
+	return nil
+}
+
+// flagSetting returns the setting flag f is in its command's section
+func flagSetting(f *pflag.Flag) string {
+	return strings.ReplaceAll(f.Name, "-", "_")
+}
+
+// setCommandDefaults sets the defaults of the command sections' settings
+func setCommandDefaults(v *viper.Viper) {
+	commandSectionsMu.RLock()
+	defer commandSectionsMu.RUnlock()
+	for key, section := range commandSections {
+		for setting, value := range section.defaults {
+			v.SetDefault(key+"."+setting, value)
+		}
+	}
+}
+
+// sectionKeys returns the dotted keys of the fields of struct t that hold
+// a value, a list, or a map
+func sectionKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		switch {
+		case name == "-" || !f.IsExported():
+		case f.Type.Kind() == reflect.Struct:
+			for _, sub := range sectionKeys(f.Type) {
+				keys = append(keys, name+"."+sub)
+			}
+		default:
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// commandSectionType returns the type the command section key decodes
+// into, or an empty struct's when key holds command sections further
+// down, such as "commands" for "commands.example.greet"
+func commandSectionType(key string) (reflect.Type, bool) {
+	commandSectionsMu.RLock()
+	defer commandSectionsMu.RUnlock()
+	if section, ok := commandSections[key]; ok {
+		return section.typ, true
+	}
+	for section := range commandSections {
+		if strings.HasPrefix(section, key+".") {
+			return reflect.TypeOf(struct{}{}), true
+		}
+	}
+	return nil, false
+}
@@ -1,47 +1,203 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/go-viper/mapstructure/v2"
+	"google.golang.org/grpc/codes"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Verbose  bool         `mapstructure:"verbose"`
-	LogLevel string       `mapstructure:"log_level"`
-	Output   OutputConfig `mapstructure:"output"`
-	API      APIConfig    `mapstructure:"api"`
-	Server   ServerConfig `mapstructure:"server"`
-	Files    FilesConfig  `mapstructure:"files"`
-	Database DBConfig     `mapstructure:"database"`
+	Verbose    bool             `mapstructure:"verbose"`
+	Offline    bool             `mapstructure:"offline"`     // Never touch the network; use cached data
+	UsageStats bool             `mapstructure:"usage_stats"` // Record command counts and durations locally
+	AutoRetry  bool             `mapstructure:"auto_retry"`  // Retry transient failures without asking
+	LogLevel   string           `mapstructure:"log_level"`
+	Profile    string           `mapstructure:"profile"` // Profile merged over the file, from profiles (see Loader.Profiles)
+	Output     OutputConfig     `mapstructure:"output"`
+	API        APIConfig        `mapstructure:"api"`
+	GRPC       GRPCConfig       `mapstructure:"grpc"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Files      FilesConfig      `mapstructure:"files"`
+	Database   DBConfig         `mapstructure:"database"`
+	Budget     BudgetConfig     `mapstructure:"budget"`
+	Runtime    RuntimeConfig    `mapstructure:"runtime"`
+	Verify     VerifyConfig     `mapstructure:"verify"`
+	Transfer   TransferConfig   `mapstructure:"transfer"`
+	Remote     RemoteConfig     `mapstructure:"remote"`
+	Events     EventsConfig     `mapstructure:"events"`
+	Completion CompletionConfig `mapstructure:"completion"`
+	Schedule   ScheduleConfig   `mapstructure:"schedule"`
 }
 
 // OutputConfig controls output formatting
 type OutputConfig struct {
-	Format      string `mapstructure:"format"`      // text, json, yaml, table, csv
-	ColorOutput bool   `mapstructure:"color"`       // Enable colored output
-	Pretty      bool   `mapstructure:"pretty"`      // Pretty print JSON/YAML
-	Quiet       bool   `mapstructure:"quiet"`       // Minimal output
-	Timestamp   bool   `mapstructure:"timestamp"`   // Include timestamps
-	TableStyle  string `mapstructure:"table_style"` // ascii, unicode, markdown
+	Format      string   `mapstructure:"format"`      // text, json, ndjson, yaml, table, csv, tsv, html, xml, go-template=...
+	ColorOutput bool     `mapstructure:"color"`       // Enable colored output
+	Pretty      bool     `mapstructure:"pretty"`      // Pretty print JSON/YAML
+	Quiet       bool     `mapstructure:"quiet"`       // Only identifiers, one per line, and no progress
+	Timestamp   bool     `mapstructure:"timestamp"`   // Include the start time in the envelope
+	Envelope    bool     `mapstructure:"envelope"`    // Wrap json and yaml output with the command, duration, and exit status
+	TableStyle  string   `mapstructure:"table_style"` // ascii, unicode, markdown
+	Theme       string   `mapstructure:"theme"`       // Colors: default, deuteranopia, high-contrast
+	Query       string   `mapstructure:"query"`       // jq-style query applied before formatting
+	Filter      string   `mapstructure:"filter"`      // Keep rows or list items matching an expression, e.g. "status==running"
+	SortBy      string   `mapstructure:"sort_by"`     // Sort table, csv, tsv, and html rows, e.g. "size:desc,name"
+	AddColumns  []string `mapstructure:"add_columns"` // Columns computed from each row, as name=expr, e.g. "mib=size/1048576"
+	GroupBy     string   `mapstructure:"group_by"`    // Summarize rows with the same values in these columns, e.g. "region,kind"
+	Aggregates  string   `mapstructure:"aggregates"`  // Computed for each group, e.g. "count,sum(size)"; count when empty
+	Delimiter   string   `mapstructure:"delimiter"`   // Field separator for csv and tsv; "," and tab when empty
+	HTMLStyle   bool     `mapstructure:"html_style"`  // Add inline CSS to html tables
+
+	MaxColumnWidth int  `mapstructure:"max_column_width"` // Truncate wider table cells with "…"; 0 is unlimited
+	Wrap           bool `mapstructure:"wrap"`             // Wrap wide table cells onto more lines instead of truncating
+	MaxRows        int  `mapstructure:"max_rows"`         // Show at most this many table rows; 0 is unlimited
+	PageSize       int  `mapstructure:"page_size"`        // Print lists this many rows at a time, continued with --next; 0 prints them whole
+	ParallelRows   int  `mapstructure:"parallel_rows"`    // Lay out tables with at least this many rows on all CPUs; 0 never does
+	FastJSONRows   int  `mapstructure:"fast_json_rows"`   // Encode json and ndjson of at least this many rows through pooled buffers; 0 never does
+	Pager          bool `mapstructure:"pager"`            // Page output taller than the terminal through $PAGER
+	FlattenDepth   int  `mapstructure:"flatten_depth"`    // Expand nested objects into columns like spec.replicas this many levels deep; 0 shows them as JSON
+	Unordered      bool `mapstructure:"unordered"`        // Print results of parallel work as each finishes rather than in input order
+
+	// Streaming commands, such as config watch and events --follow, queue
+	// rows for output when it's slower than they're produced
+	StreamBuffer   int    `mapstructure:"stream_buffer"`   // Rows queued; 0 writes each as it's produced
+	StreamOverflow string `mapstructure:"stream_overflow"` // With the queue full: block, drop, or sample
+
+	// Output is written in blocks, which streams write out at least every
+	// flush_interval so rows produced slowly still show up promptly
+	WriteBuffer   ByteSize      `mapstructure:"write_buffer"`   // Output held before it's written; 0 writes it as it's produced
+	FlushInterval time.Duration `mapstructure:"flush_interval"` // Longest output is held; 0 holds it until the buffer fills or the output ends
+
+	// Columns formats table and html columns by name (matched like
+	// --filter columns), e.g. {"size": {align: right, unit: bytes}}
+	Columns map[string]ColumnFormat `mapstructure:"columns"`
+}
+
+// ColumnFormat controls how one column of table and html output is shown.
+// Cells that aren't numbers (or sizes and durations, for Unit) are left as
+// they are.
+type ColumnFormat struct {
+	Align     string `mapstructure:"align"`     // left (default), right, or center
+	Thousands bool   `mapstructure:"thousands"` // Group digits with commas: 1,234,567
+	Digits    int    `mapstructure:"digits"`    // Round numbers to this many significant digits; 0 keeps them all
+	Unit      string `mapstructure:"unit"`      // bytes or duration: show 1288490188 as 1.2GiB, 192 (seconds) as 3m12s
 }
 
 // APIConfig holds API client configuration
 type APIConfig struct {
 	BaseURL         string            `mapstructure:"base_url"`
-	Key             string            `mapstructure:"key"`
-	Secret          string            `mapstructure:"secret"`
-	Token           string            `mapstructure:"token"`
+	Key             string            `mapstructure:"key,secret"`
+	Secret          string            `mapstructure:"secret,secret"`
+	Token           string            `mapstructure:"token,secret"`
+	TokenCommand    string            `mapstructure:"token_command"` // Prints the bearer token, and a new one when the API answers 401; used instead of token
+	OAuth           OAuthConfig       `mapstructure:"oauth"`         // Log in with termplate auth login; used instead of token
 	Timeout         time.Duration     `mapstructure:"timeout"`
 	RetryAttempts   int               `mapstructure:"retry_attempts"`
-	RetryDelay      time.Duration     `mapstructure:"retry_delay"`
+	RetryDelay      time.Duration     `mapstructure:"retry_delay"`     // First wait before a retry, doubled for each one after, with jitter
+	RetryMaxDelay   time.Duration     `mapstructure:"retry_max_delay"` // Longest wait, including one a Retry-After header asks for; 0 is unlimited
+	RetryStatuses   []int             `mapstructure:"retry_statuses"`  // HTTP statuses retried, e.g. 429 and 503
 	FollowRedirects bool              `mapstructure:"follow_redirects"`
 	VerifySSL       bool              `mapstructure:"verify_ssl"`
+	CABundle        string            `mapstructure:"ca_bundle"`        // PEM file of CA certificates trusted besides the system's, e.g. a private CA
+	ClientCertFile  string            `mapstructure:"client_cert_file"` // PEM certificate sent for mutual TLS
+	ClientKeyFile   string            `mapstructure:"client_key_file"`  // Its private key; empty when it's in client_cert_file
 	UserAgent       string            `mapstructure:"user_agent"`
 	Headers         map[string]string `mapstructure:"headers"`
-	RateLimitPerSec int               `mapstructure:"rate_limit_per_sec"`
+	LogRequests     bool              `mapstructure:"log_requests"`       // Log each request's method, URL, status, and duration
+	RateLimitPerSec int               `mapstructure:"rate_limit_per_sec"` // Requests a second to the API; 0 is unlimited
+	RateLimitBurst  int               `mapstructure:"rate_limit_burst"`   // Requests sent at once before the rate applies; 0 is rate_limit_per_sec
+	SchemaDir       string            `mapstructure:"schema_dir"`         // Warn when responses drift from the JSON Schemas here; empty disables
+	RecordSchemas   bool              `mapstructure:"record_schemas"`     // Save a schema for endpoints in SchemaDir without one
+
+	// Connection pool; zero limits are unlimited
+	MaxIdleConns      int           `mapstructure:"max_idle_conns"`      // Idle connections kept for reuse
+	MaxConnsPerHost   int           `mapstructure:"max_conns_per_host"`  // Connections per host, including active ones
+	IdleConnTimeout   time.Duration `mapstructure:"idle_conn_timeout"`   // How long an idle connection is kept
+	DisableKeepAlives bool          `mapstructure:"disable_keep_alives"` // Use a new connection for every request
+
+	// Where connections go, for backends behind load balancers and test
+	// environments; TLS still verifies and sends (SNI) the request's host
+	HostOverrides []string `mapstructure:"host_overrides"` // host=address, e.g. api.example.com=10.0.0.5
+	DNSServer     string   `mapstructure:"dns_server"`     // Resolve with this server (host:port) instead of the system's
+
+	MaxResponseSize ByteSize `mapstructure:"max_response_size"` // Response bodies are cut off past this, once decompressed; zero is unlimited
+
+	// GET responses kept in $XDG_CACHE_HOME/termplate/http, as their
+	// Cache-Control, ETag, and Last-Modified headers allow
+	Cache        bool     `mapstructure:"cache"`          // Off with --no-cache
+	CacheMaxSize ByteSize `mapstructure:"cache_max_size"` // Larger responses aren't kept; zero is unlimited
+
+	Outbox bool `mapstructure:"outbox"` // Queue requests that change things while offline, to send later in order
+
+	// Long-running operations the API tracks by ID, waited on with ops wait
+	OperationsPath  string        `mapstructure:"operations_path"`   // Under base_url; {id} is replaced by the operation's ID
+	PollInterval    time.Duration `mapstructure:"poll_interval"`     // First wait between status checks
+	PollMaxInterval time.Duration `mapstructure:"poll_max_interval"` // Longest wait between checks as it grows
+
+	// What to do when a resource changed since it was fetched for an
+	// update: ask, retry, overwrite, or abort
+	OnConflict string `mapstructure:"on_conflict"`
+
+	ResourcePath string        `mapstructure:"resource_path"` // Where apply finds a resource under base_url; {kind} and {name} are replaced
+	DryRunQuery  string        `mapstructure:"dry_run_query"` // Query making a create or update a dry run, e.g. dryRun=All, for apply --server-diff
+	PlanExpiry   time.Duration `mapstructure:"plan_expiry"`   // How long a plan saved by apply --plan-out can be applied; 0 never expires
+	EventsPath   string        `mapstructure:"events_path"`   // Where resources describe finds a resource's events under base_url; {kind} and {name} are replaced; empty when the API has none
+
+	// A GraphQL endpoint, for APIs that have one (see apiclient.Client.Query)
+	GraphQLPath      string `mapstructure:"graphql_path"`      // Under base_url
+	PersistedQueries bool   `mapstructure:"persisted_queries"` // Send queries by their SHA-256 hash first, as Apollo's automatic persisted queries
+}
+
+// OAuthConfig is the OAuth 2.0 client termplate auth login signs in as.
+// With a client ID, requests send the access token it stored, refreshed
+// as it expires.
+type OAuthConfig struct {
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret,secret"` // For confidential clients only; PKCE needs none
+	AuthURL      string   `mapstructure:"auth_url"`             // Authorization endpoint, for logging in through the browser
+	TokenURL     string   `mapstructure:"token_url"`
+	DeviceURL    string   `mapstructure:"device_url"`    // Device authorization endpoint, for auth login --device
+	Scopes       []string `mapstructure:"scopes"`        // Asked for at login
+	RedirectPort int      `mapstructure:"redirect_port"` // Port on 127.0.0.1 the browser returns to; 0 picks a free one
+	TokenStore   string   `mapstructure:"token_store"`   // keyring (the OS credential store) or file
+}
+
+// GRPCConfig is how grpcclient dials a gRPC backend
+type GRPCConfig struct {
+	Address        string            `mapstructure:"address"`          // host:port, or a gRPC target such as dns:///host:443
+	Plaintext      bool              `mapstructure:"plaintext"`        // Connect without TLS, e.g. to a local server
+	VerifySSL      bool              `mapstructure:"verify_ssl"`       // Verify the server's certificate
+	CABundle       string            `mapstructure:"ca_bundle"`        // PEM file of CA certificates trusted besides the system's
+	ClientCertFile string            `mapstructure:"client_cert_file"` // PEM certificate sent for mutual TLS
+	ClientKeyFile  string            `mapstructure:"client_key_file"`  // Its private key; empty when it's in client_cert_file
+	ServerName     string            `mapstructure:"server_name"`      // Verified and sent (SNI) instead of the address's host
+	Token          string            `mapstructure:"token,secret"`     // Sent with each call as a bearer token
+	Metadata       map[string]string `mapstructure:"metadata"`         // Sent with each call, e.g. x-tenant-id
+	Timeout        time.Duration     `mapstructure:"timeout"`          // Deadline of unary calls without one; 0 is none
+	LogRequests    bool              `mapstructure:"log_requests"`     // Log each call's method, status code, and duration
+
+	// Idle connections with calls in flight are pinged, so a dead one is
+	// noticed before a call waits on it. Servers refuse pings more often
+	// than they allow (5m by default for grpc-go).
+	KeepaliveTime    time.Duration `mapstructure:"keepalive_time"`    // Ping after this long without activity; 0 never pings
+	KeepaliveTimeout time.Duration `mapstructure:"keepalive_timeout"` // Close the connection when a ping isn't answered in this long
+
+	// Unary calls failing with one of retry_codes are retried
+	RetryAttempts int           `mapstructure:"retry_attempts"`
+	RetryDelay    time.Duration `mapstructure:"retry_delay"`     // First wait before a retry, doubled for each one after, with jitter
+	RetryMaxDelay time.Duration `mapstructure:"retry_max_delay"` // Longest wait; 0 is unlimited
+	RetryCodes    []string      `mapstructure:"retry_codes"`     // Status codes retried, e.g. UNAVAILABLE
 }
 
 // ServerConfig holds server configuration
@@ -64,8 +220,8 @@ type FilesConfig struct {
 	TempDir           string   `mapstructure:"temp_dir"`
 	Patterns          []string `mapstructure:"patterns"`           // File patterns to match
 	ExcludePatterns   []string `mapstructure:"exclude_patterns"`   // Patterns to exclude
-	MaxFileSize       int64    `mapstructure:"max_file_size"`      // Max file size in bytes
-	BufferSize        int      `mapstructure:"buffer_size"`        // Buffer size for reading
+	MaxFileSize       ByteSize `mapstructure:"max_file_size"`      // Max file size, e.g. 100MiB
+	BufferSize        ByteSize `mapstructure:"buffer_size"`        // Buffer size for reading, e.g. 4KiB
 	CreateDirs        bool     `mapstructure:"create_dirs"`        // Auto-create directories
 	OverwriteExisting bool     `mapstructure:"overwrite_existing"` // Overwrite existing files
 	PreservePerms     bool     `mapstructure:"preserve_perms"`     // Preserve file permissions
@@ -79,7 +235,7 @@ type DBConfig struct {
 	Port            int           `mapstructure:"port"`
 	Database        string        `mapstructure:"database"`
 	Username        string        `mapstructure:"username"`
-	Password        string        `mapstructure:"password"`
+	Password        string        `mapstructure:"password,secret"`
 	SSLMode         string        `mapstructure:"ssl_mode"` // disable, require, verify-ca, verify-full
 	MaxOpenConns    int           `mapstructure:"max_open_conns"`
 	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
@@ -89,46 +245,503 @@ type DBConfig struct {
 	MigrationsPath  string        `mapstructure:"migrations_path"`
 }
 
-// Load reads configuration from viper
-func Load() (*Config, error) {
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("unmarshaling config: %w", err)
+// VerifyConfig holds the public keys downloads such as self-update
+// artifacts and template archives are checked against. Keys are given
+// inline or as paths of files holding them.
+type VerifyConfig struct {
+	MinisignKeys     []string `mapstructure:"minisign_keys"`     // minisign public keys, e.g. "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"
+	CosignKeys       []string `mapstructure:"cosign_keys"`       // PEM public keys from cosign generate-key-pair
+	GPGKeys          []string `mapstructure:"gpg_keys"`          // Armored GPG public keys
+	RequireSignature bool     `mapstructure:"require_signature"` // Refuse downloads without a valid signature; a checksum alone isn't enough
+}
+
+// BudgetConfig limits the work a single invocation may do; zero is unlimited
+type BudgetConfig struct {
+	MaxAPICalls int  `mapstructure:"max_api_calls"`
+	MaxFiles    int  `mapstructure:"max_files"` // Files created, modified, or deleted
+	MaxRows     int  `mapstructure:"max_rows"`  // Rows or items mutated by bulk operations
+	Confirm     bool `mapstructure:"confirm"`   // Allow exceeding the limits (--confirm-over-budget)
+}
+
+// TransferConfig limits the bandwidth of file transfers in bytes per
+// second, e.g. 2MiB; zero is unlimited
+type TransferConfig struct {
+	DownloadLimit      ByteSize `mapstructure:"download_limit"`       // Each download
+	UploadLimit        ByteSize `mapstructure:"upload_limit"`         // Each upload
+	TotalDownloadLimit ByteSize `mapstructure:"total_download_limit"` // All downloads at once together
+	TotalUploadLimit   ByteSize `mapstructure:"total_upload_limit"`   // All uploads at once together
+}
+
+// EventsConfig keeps the events listed by termplate events: retries,
+// config reloads, and results of bulk operations and outbox flushes
+type EventsConfig struct {
+	Persist bool `mapstructure:"persist"` // Keep events in the state directory, across runs
+	Size    int  `mapstructure:"size"`    // Events kept; the oldest are dropped
+}
+
+// CompletionConfig caches the values shell completion fetches from the
+// API, such as resource names, so pressing tab doesn't wait on it
+type CompletionConfig struct {
+	CacheTTL      time.Duration `mapstructure:"cache_ttl"`       // Cached values are used as they are for this long
+	CacheMaxStale time.Duration `mapstructure:"cache_max_stale"` // Then still used this much longer, while refreshed in the background
+	Timeout       time.Duration `mapstructure:"timeout"`         // Give up fetching values not cached after this long
+}
+
+// ScheduleConfig holds the commands the daemon runs on a schedule, whose
+// state (last run, next run, failures) is kept across restarts
+type ScheduleConfig struct {
+	CatchUp string               `mapstructure:"catch_up"` // Runs missed while the daemon was down: skip, once, or all
+	Jobs    map[string]JobConfig `mapstructure:"jobs"`     // By name, e.g. {"prune-cache": {every: 24h, command: "cache prune"}}
+}
+
+// JobConfig is one scheduled command
+type JobConfig struct {
+	Every   time.Duration `mapstructure:"every"`    // Run this often, counting from the daemon's first start
+	Command string        `mapstructure:"command"`  // termplate arguments, split at spaces, e.g. "cache prune"
+	CatchUp string        `mapstructure:"catch_up"` // Overrides schedule.catch_up for this job
+}
+
+// CatchUpPolicies are the values of schedule.catch_up
+var CatchUpPolicies = []string{"skip", "once", "all"}
+
+// RemoteConfig reads settings from a config served over HTTP, such as a
+// central config service or Consul's KV API (?raw), beneath the local
+// config files
+type RemoteConfig struct {
+	URL          string            `mapstructure:"url"`           // YAML or JSON config; empty disables
+	Format       string            `mapstructure:"format"`        // yaml or json; from the URL or Content-Type when empty
+	Headers      map[string]string `mapstructure:"headers"`       // Sent with each request, e.g. a token
+	Timeout      time.Duration     `mapstructure:"timeout"`       // Give up on a request after this long
+	PollInterval time.Duration     `mapstructure:"poll_interval"` // How often config watch checks for changes; zero doesn't
+	Fallback     bool              `mapstructure:"fallback"`      // When fetching fails, use the copy fetched last, or only the local files
+}
+
+// RuntimeConfig limits the resources of this process and of the processes
+// it starts, for constrained containers; zero is unlimited
+type RuntimeConfig struct {
+	MemoryLimit ByteSize          `mapstructure:"memory_limit"` // Soft Go memory limit (like GOMEMLIMIT)
+	MaxProcs    int               `mapstructure:"max_procs"`    // GOMAXPROCS; 0 follows the container's CPU quota
+	Child       ChildLimitsConfig `mapstructure:"child"`        // Limits for hooks and other child processes
+}
+
+// ChildLimitsConfig sets resource limits (rlimits) on child processes
+type ChildLimitsConfig struct {
+	MaxMemory    ByteSize      `mapstructure:"max_memory"`     // Address space (ulimit -v)
+	MaxCPUTime   time.Duration `mapstructure:"max_cpu_time"`   // CPU time (ulimit -t), whole seconds
+	MaxOpenFiles int           `mapstructure:"max_open_files"` // Open files (ulimit -n)
+}
+
+// FieldError is a setting that fails validation
+type FieldError struct {
+	Key     string // Dotted key of the setting, e.g. "server.port"
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+func fieldErrorf(key, format string, args ...interface{}) *FieldError {
+	return &FieldError{Key: key, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValuesError is returned by Load for settings whose values don't decode
+// into their types, such as a duration without a unit, one FieldError each
+type ValuesError struct {
+	Fields []*FieldError
+}
+
+func (e *ValuesError) Error() string {
+	lines := []string{"invalid config values:"}
+	for _, f := range e.Fields {
+		lines = append(lines, "  "+f.Key+": "+f.Message)
 	}
-	return &cfg, nil
+	return strings.Join(lines, "\n")
 }
 
-// Validate validates the configuration
+// valuesError turns the errors decoding the settings under prefix, e.g.
+// "commands.example.greet", into a *ValuesError naming each setting. Other
+// errors are returned as they are.
+func valuesError(err error, prefix string) error {
+	var fields []*FieldError
+	var collect func(err error) bool
+	collect = func(err error) bool {
+		switch e := err.(type) {
+		case *mapstructure.DecodeError:
+			key := e.Name()
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			fields = append(fields, &FieldError{Key: key, Message: decodeMessage(e)})
+			return true
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				if !collect(inner) {
+					return false
+				}
+			}
+			return true
+		}
+		if inner := errors.Unwrap(err); inner != nil {
+			return collect(inner)
+		}
+		return false
+	}
+	if !collect(err) || len(fields) == 0 {
+		return err
+	}
+	return &ValuesError{Fields: fields}
+}
+
+// decodeMessage describes why a setting didn't decode, without its key,
+// and in the config file's terms rather than Go's
+func decodeMessage(err *mapstructure.DecodeError) string {
+	inner := err.Unwrap()
+	for {
+		e, ok := inner.(*mapstructure.DecodeError)
+		if !ok {
+			break
+		}
+		inner = e.Unwrap()
+	}
+	var parseErr *mapstructure.ParseError
+	if !errors.As(inner, &parseErr) {
+		return inner.Error()
+	}
+	switch parseErr.Expected.Kind() {
+	case reflect.Bool:
+		return fmt.Sprintf("invalid value %q: expected true or false", fmt.Sprint(parseErr.Value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("invalid value %q: expected a whole number", fmt.Sprint(parseErr.Value))
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("invalid value %q: expected a number", fmt.Sprint(parseErr.Value))
+	}
+	return inner.Error()
+}
+
+// sectionValidators check one section of the config each, returning every
+// problem they find
+var sectionValidators = []func(c *Config) []*FieldError{
+	validateOutput,
+	validateServer,
+	validateDatabase,
+	validateFiles,
+	validateBudget,
+	validateRuntime,
+	validateTransfer,
+	validateAPI,
+	validateGRPC,
+	validateRemote,
+	validateEvents,
+	validateCompletion,
+	validateSchedule,
+}
+
+// Validate validates the configuration, returning the first problem found
+// as a *FieldError
 func (c *Config) Validate() error {
-	// Validate output format
-	validFormats := map[string]bool{
-		"text": true, "json": true, "yaml": true, "table": true, "csv": true,
+	if errs := c.ValidateAll(); len(errs) > 0 {
+		return errs[0]
 	}
-	if !validFormats[c.Output.Format] {
-		return fmt.Errorf("invalid output format: %s (valid: text, json, yaml, table, csv)", c.Output.Format)
+	return nil
+}
+
+// ValidateAll validates every section of the configuration and returns all
+// the problems found
+func (c *Config) ValidateAll() []*FieldError {
+	var errs []*FieldError
+	for _, validate := range sectionValidators {
+		errs = append(errs, validate(c)...)
 	}
+	return errs
+}
 
-	// Validate server port
+func validateOutput(c *Config) []*FieldError {
+	var errs []*FieldError
+	o := c.Output
+
+	if !slices.Contains(OutputFormats(), o.Format) && !strings.HasPrefix(o.Format, "go-template=") {
+		errs = append(errs, fieldErrorf("output.format", "invalid output format: %s (valid: %s, go-template=...)", o.Format, strings.Join(OutputFormats(), ", ")))
+	}
+
+	if !slices.Contains([]string{"", "default", "deuteranopia", "high-contrast"}, o.Theme) {
+		errs = append(errs, fieldErrorf("output.theme", "invalid output theme: %s (valid: default, deuteranopia, high-contrast)", o.Theme))
+	}
+
+	// The csv and tsv field separator
+	if d := []rune(o.Delimiter); len(d) > 1 || (len(d) == 1 && strings.ContainsRune("\"\r\n", d[0])) {
+		errs = append(errs, fieldErrorf("output.delimiter", "invalid output delimiter: %q (must be one character other than a quote or newline)", o.Delimiter))
+	}
+
+	errs = append(errs, notNegative("output", "invalid output limits", map[string]int64{
+		"max_column_width": int64(o.MaxColumnWidth),
+		"max_rows":         int64(o.MaxRows),
+		"page_size":        int64(o.PageSize),
+		"parallel_rows":    int64(o.ParallelRows),
+		"fast_json_rows":   int64(o.FastJSONRows),
+		"flatten_depth":    int64(o.FlattenDepth),
+		"stream_buffer":    int64(o.StreamBuffer),
+		"write_buffer":     int64(o.WriteBuffer),
+		"flush_interval":   int64(o.FlushInterval),
+	})...)
+
+	if !slices.Contains([]string{"", "block", "drop", "sample"}, o.StreamOverflow) {
+		errs = append(errs, fieldErrorf("output.stream_overflow", "invalid stream overflow policy: %s (valid: block, drop, sample)", o.StreamOverflow))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(o.Columns)) {
+		col, key := o.Columns[name], "output.columns."+name
+		if !slices.Contains([]string{"", "left", "right", "center"}, col.Align) {
+			errs = append(errs, fieldErrorf(key+".align", "invalid align for column %s: %q (valid: left, right, center)", name, col.Align))
+		}
+		if !slices.Contains([]string{"", "bytes", "duration"}, col.Unit) {
+			errs = append(errs, fieldErrorf(key+".unit", "invalid unit for column %s: %q (valid: bytes, duration)", name, col.Unit))
+		}
+		if col.Digits < 0 {
+			errs = append(errs, fieldErrorf(key+".digits", "invalid digits for column %s: %d (must not be negative)", name, col.Digits))
+		}
+	}
+	return errs
+}
+
+func validateServer(c *Config) []*FieldError {
 	if c.Server.Port < 0 || c.Server.Port > 65535 {
-		return fmt.Errorf("invalid server port: %d", c.Server.Port)
+		return []*FieldError{fieldErrorf("server.port", "invalid server port: %d", c.Server.Port)}
 	}
+	return nil
+}
 
-	// Validate database port if driver is specified
+func validateDatabase(c *Config) []*FieldError {
+	// The port only matters once a driver is chosen
 	if c.Database.Driver != "" && (c.Database.Port < 0 || c.Database.Port > 65535) {
-		return fmt.Errorf("invalid database port: %d", c.Database.Port)
+		return []*FieldError{fieldErrorf("database.port", "invalid database port: %d", c.Database.Port)}
 	}
+	return nil
+}
 
-	// Validate file size limit
+func validateFiles(c *Config) []*FieldError {
 	if c.Files.MaxFileSize < 0 {
-		return fmt.Errorf("invalid max file size: %d", c.Files.MaxFileSize)
+		return []*FieldError{fieldErrorf("files.max_file_size", "invalid max file size: %s", c.Files.MaxFileSize)}
+	}
+	return nil
+}
+
+func validateBudget(c *Config) []*FieldError {
+	b := c.Budget
+	return notNegative("budget", "invalid budget", map[string]int64{
+		"max_api_calls": int64(b.MaxAPICalls),
+		"max_files":     int64(b.MaxFiles),
+		"max_rows":      int64(b.MaxRows),
+	})
+}
+
+func validateRuntime(c *Config) []*FieldError {
+	rt := c.Runtime
+	return notNegative("runtime", "invalid runtime limits", map[string]int64{
+		"memory_limit":         int64(rt.MemoryLimit),
+		"max_procs":            int64(rt.MaxProcs),
+		"child.max_memory":     int64(rt.Child.MaxMemory),
+		"child.max_cpu_time":   int64(rt.Child.MaxCPUTime),
+		"child.max_open_files": int64(rt.Child.MaxOpenFiles),
+	})
+}
+
+func validateTransfer(c *Config) []*FieldError {
+	tc := c.Transfer
+	return notNegative("transfer", "invalid transfer limits", map[string]int64{
+		"download_limit":       int64(tc.DownloadLimit),
+		"upload_limit":         int64(tc.UploadLimit),
+		"total_download_limit": int64(tc.TotalDownloadLimit),
+		"total_upload_limit":   int64(tc.TotalUploadLimit),
+	})
+}
+
+func validateEvents(c *Config) []*FieldError {
+	if c.Events.Size < 1 {
+		return []*FieldError{fieldErrorf("events.size", "invalid events size %d: must be at least 1", c.Events.Size)}
+	}
+	return nil
+}
+
+func validateCompletion(c *Config) []*FieldError {
+	cc := c.Completion
+	return notNegative("completion", "invalid completion config", map[string]int64{
+		"cache_ttl":       int64(cc.CacheTTL),
+		"cache_max_stale": int64(cc.CacheMaxStale),
+		"timeout":         int64(cc.Timeout),
+	})
+}
+
+func validateSchedule(c *Config) []*FieldError {
+	var errs []*FieldError
+	valid := strings.Join(CatchUpPolicies, ", ")
+	if !slices.Contains(CatchUpPolicies, c.Schedule.CatchUp) {
+		errs = append(errs, fieldErrorf("schedule.catch_up", "invalid catch-up policy: %s (valid: %s)", c.Schedule.CatchUp, valid))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Schedule.Jobs)) {
+		job, key := c.Schedule.Jobs[name], "schedule.jobs."+name
+		if job.Every <= 0 {
+			errs = append(errs, fieldErrorf(key+".every", "invalid job %s: every must be a positive duration, e.g. 1h", name))
+		}
+		if strings.TrimSpace(job.Command) == "" {
+			errs = append(errs, fieldErrorf(key+".command", "invalid job %s: command is required, e.g. \"cache prune\"", name))
+		}
+		if job.CatchUp != "" && !slices.Contains(CatchUpPolicies, job.CatchUp) {
+			errs = append(errs, fieldErrorf(key+".catch_up", "invalid catch-up policy: %s (valid: %s)", job.CatchUp, valid))
+		}
+	}
+	return errs
+}
+
+func validateRemote(c *Config) []*FieldError {
+	rc := c.Remote
+	var errs []*FieldError
+	if rc.URL != "" {
+		if u, err := url.Parse(rc.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fieldErrorf("remote.url", "invalid remote config URL %q: expected http(s)://host/path", rc.URL))
+		}
+	}
+	if !slices.Contains([]string{"", "yaml", "yml", "json"}, rc.Format) {
+		errs = append(errs, fieldErrorf("remote.format", "invalid remote config format: %s (valid: yaml, json)", rc.Format))
 	}
+	return append(errs, notNegative("remote", "invalid remote config", map[string]int64{
+		"timeout":       int64(rc.Timeout),
+		"poll_interval": int64(rc.PollInterval),
+	})...)
+}
 
-	// Validate API retry attempts
+func validateOAuth(o OAuthConfig) []*FieldError {
+	var errs []*FieldError
+	if o.TokenStore != "keyring" && o.TokenStore != "file" {
+		errs = append(errs, fieldErrorf("api.oauth.token_store", "invalid token store: %s (valid: keyring, file)", o.TokenStore))
+	}
+	if o.RedirectPort < 0 || o.RedirectPort > 65535 {
+		errs = append(errs, fieldErrorf("api.oauth.redirect_port", "invalid redirect port: %d", o.RedirectPort))
+	}
+	endpoints := map[string]string{"auth_url": o.AuthURL, "token_url": o.TokenURL, "device_url": o.DeviceURL}
+	for _, key := range []string{"auth_url", "token_url", "device_url"} {
+		value := endpoints[key]
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fieldErrorf("api.oauth."+key, "invalid OAuth endpoint %q: expected http(s)://host/path", value))
+		}
+	}
+	if o.ClientID == "" {
+		return errs
+	}
+	if o.TokenURL == "" {
+		errs = append(errs, fieldErrorf("api.oauth.token_url", "api.oauth.token_url is required with a client ID"))
+	}
+	if o.AuthURL == "" && o.DeviceURL == "" {
+		errs = append(errs, fieldErrorf("api.oauth.auth_url", "api.oauth.auth_url or device_url is required with a client ID"))
+	}
+	return errs
+}
+
+func validateAPI(c *Config) []*FieldError {
+	var errs []*FieldError
 	if c.API.RetryAttempts < 0 {
-		return fmt.Errorf("invalid retry attempts: %d", c.API.RetryAttempts)
+		errs = append(errs, fieldErrorf("api.retry_attempts", "invalid retry attempts: %d", c.API.RetryAttempts))
+	}
+	if c.API.RetryDelay < 0 || c.API.RetryMaxDelay < 0 {
+		errs = append(errs, fieldErrorf("api.retry_delay", "invalid retry delays: retry_delay and retry_max_delay must not be negative"))
+	}
+	for _, code := range c.API.RetryStatuses {
+		if code < 100 || code > 599 {
+			errs = append(errs, fieldErrorf("api.retry_statuses", "invalid retry status %d: expected an HTTP status from 100 to 599", code))
+		}
+	}
+	if _, err := ParseHostOverrides(c.API.HostOverrides); err != nil {
+		errs = append(errs, fieldErrorf("api.host_overrides", "%s", err))
+	}
+	if c.API.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(c.API.DNSServer); err != nil {
+			errs = append(errs, fieldErrorf("api.dns_server", "invalid DNS server %q: expected host:port, e.g. 10.0.0.2:53", c.API.DNSServer))
+		}
+	}
+	if c.API.ClientKeyFile != "" && c.API.ClientCertFile == "" {
+		errs = append(errs, fieldErrorf("api.client_cert_file", "api.client_cert_file is required with a client key"))
 	}
+	errs = append(errs, validateOAuth(c.API.OAuth)...)
+	if !strings.Contains(c.API.OperationsPath, "{id}") {
+		errs = append(errs, fieldErrorf("api.operations_path", "invalid operations path %q: must contain {id}", c.API.OperationsPath))
+	}
+	if !strings.Contains(c.API.ResourcePath, "{kind}") || !strings.HasSuffix(c.API.ResourcePath, "/{name}") {
+		errs = append(errs, fieldErrorf("api.resource_path", "invalid resource path %q: must contain {kind} and end with /{name}", c.API.ResourcePath))
+	}
+	if _, err := url.ParseQuery(c.API.DryRunQuery); err != nil {
+		errs = append(errs, fieldErrorf("api.dry_run_query", "invalid dry-run query %q: %v", c.API.DryRunQuery, err))
+	}
+	if c.API.EventsPath != "" && !strings.Contains(c.API.EventsPath, "{name}") {
+		errs = append(errs, fieldErrorf("api.events_path", "invalid events path %q: must contain {name}", c.API.EventsPath))
+	}
+	if !slices.Contains([]string{"ask", "retry", "overwrite", "abort"}, c.API.OnConflict) {
+		errs = append(errs, fieldErrorf("api.on_conflict", "invalid conflict resolution: %s (valid: ask, retry, overwrite, abort)", c.API.OnConflict))
+	}
+	errs = append(errs, notNegative("api", "invalid polling", map[string]int64{
+		"poll_interval":     int64(c.API.PollInterval),
+		"poll_max_interval": int64(c.API.PollMaxInterval),
+	})...)
+	errs = append(errs, notNegative("api", "invalid rate limit", map[string]int64{
+		"rate_limit_per_sec": int64(c.API.RateLimitPerSec),
+		"rate_limit_burst":   int64(c.API.RateLimitBurst),
+	})...)
+	errs = append(errs, notNegative("api", "invalid plan expiry", map[string]int64{
+		"plan_expiry": int64(c.API.PlanExpiry),
+	})...)
+	if c.API.MaxResponseSize < 0 {
+		errs = append(errs, fieldErrorf("api.max_response_size", "invalid max response size: %s", c.API.MaxResponseSize))
+	}
+	if c.API.CacheMaxSize < 0 {
+		errs = append(errs, fieldErrorf("api.cache_max_size", "invalid cache max size: %s", c.API.CacheMaxSize))
+	}
+	return append(errs, notNegative("api", "invalid connection pool", map[string]int64{
+		"max_idle_conns":     int64(c.API.MaxIdleConns),
+		"max_conns_per_host": int64(c.API.MaxConnsPerHost),
+		"idle_conn_timeout":  int64(c.API.IdleConnTimeout),
+	})...)
+}
 
-	return nil
+func validateGRPC(c *Config) []*FieldError {
+	var errs []*FieldError
+	g := c.GRPC
+	if g.RetryAttempts < 0 {
+		errs = append(errs, fieldErrorf("grpc.retry_attempts", "invalid retry attempts: %d", g.RetryAttempts))
+	}
+	for _, name := range g.RetryCodes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name)))); err != nil {
+			errs = append(errs, fieldErrorf("grpc.retry_codes", "invalid retry code %q: expected a gRPC status code such as UNAVAILABLE", name))
+		}
+	}
+	if g.ClientKeyFile != "" && g.ClientCertFile == "" {
+		errs = append(errs, fieldErrorf("grpc.client_cert_file", "grpc.client_cert_file is required with a client key"))
+	}
+	if g.Plaintext && (g.CABundle != "" || g.ClientCertFile != "") {
+		errs = append(errs, fieldErrorf("grpc.plaintext", "grpc.plaintext can't be used with a CA bundle or client certificate"))
+	}
+	return append(errs, notNegative("grpc", "invalid timing", map[string]int64{
+		"timeout":           int64(g.Timeout),
+		"keepalive_time":    int64(g.KeepaliveTime),
+		"keepalive_timeout": int64(g.KeepaliveTimeout),
+		"retry_delay":       int64(g.RetryDelay),
+		"retry_max_delay":   int64(g.RetryMaxDelay),
+	})...)
+}
+
+// notNegative reports each of the limits in section, keyed by their name
+// within it, that is negative, in key order
+func notNegative(section, summary string, limits map[string]int64) []*FieldError {
+	var errs []*FieldError
+	for _, name := range slices.Sorted(maps.Keys(limits)) {
+		if limits[name] < 0 {
+			errs = append(errs, fieldErrorf(section+"."+name, "%s: %s must not be negative", summary, name))
+		}
+	}
+	return errs
 }
 
 // GetAPIAuthHeader returns the appropriate authorization header
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyEnvVar holds the key that encrypts config values, base64-encoded. It
+// takes priority over the key kept in the OS keychain.
+const KeyEnvVar = "TERMPLATE_CONFIG_KEY"
+
+// keySize is the length of a config key: AES-256
+const keySize = 32
+
+// An encrypted value is stored as ENC[aes-gcm,<base64 of nonce and
+// ciphertext>]
+const (
+	encryptedPrefix = "ENC[aes-gcm,"
+	encryptedSuffix = "]"
+)
+
+// ErrNoKey is returned when a config value is encrypted but no key is set
+var ErrNoKey = errors.New("no config encryption key")
+
+var (
+	keyMu     sync.Mutex
+	cachedKey []byte // Found by EncryptionKey; the keychain is slow to ask
+)
+
+// IsEncrypted reports whether a config value is stored encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// GenerateKey returns a new random key for EncryptValue
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+	return key, nil
+}
+
+// EncodeKey returns key in the form KeyEnvVar and the keychain hold it
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// DecodeKey parses a key encoded by EncodeKey
+func DecodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid config key: expected %d bytes in base64, e.g. from 'openssl rand -base64 %d'", keySize, keySize)
+	}
+	return key, nil
+}
+
+// EncryptionKey returns the key config values are encrypted with: the one
+// in KeyEnvVar, or else the one in the OS keychain. Without either, the
+// error wraps ErrNoKey.
+func EncryptionKey() ([]byte, error) {
+	if s, ok := os.LookupEnv(KeyEnvVar); ok {
+		return DecodeKey(s)
+	}
+
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if cachedKey != nil {
+		return cachedKey, nil
+	}
+	s, err := keychainGet()
+	switch {
+	case errors.Is(err, errNotInKeychain), errors.Is(err, ErrNoKeychain):
+		return nil, fmt.Errorf("%w: set %s or run 'termplate config encrypt' to create one", ErrNoKey, KeyEnvVar)
+	case err != nil:
+		return nil, err
+	}
+	key, err := DecodeKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("keychain: %w", err)
+	}
+	cachedKey = key
+	return key, nil
+}
+
+// StoreKey saves key in the OS keychain, where EncryptionKey finds it
+func StoreKey(key []byte) error {
+	if err := keychainSet(EncodeKey(key)); err != nil {
+		return err
+	}
+	keyMu.Lock()
+	cachedKey = key
+	keyMu.Unlock()
+	return nil
+}
+
+// EncryptValue encrypts a config value with AES-256-GCM
+func EncryptValue(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generating nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// DecryptValue decrypts a value encrypted by EncryptValue
+func DecryptValue(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("not an encrypted value")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)])
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt the value: wrong key, or the value was changed")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptValue decrypts value when it's encrypted, with the key from
+// EncryptionKey, and returns it unchanged otherwise
+func decryptValue(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	key, err := EncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	return DecryptValue(key, value)
+}
@@ -8,65 +8,193 @@ import (
 	"github.com/spf13/viper"
 )
 
-// SetDefaults sets default values for all configuration options
-func SetDefaults() {
+// setDefaults sets default values for all configuration options
+func setDefaults(v *viper.Viper) {
 	// General settings
-	viper.SetDefault("verbose", false)
-	viper.SetDefault("log_level", "info")
+	v.SetDefault("verbose", false)
+	v.SetDefault("offline", false)
+	v.SetDefault("usage_stats", true)
+	v.SetDefault("auto_retry", false)
+	v.SetDefault("log_level", "info")
+	v.SetDefault("profile", "")
 
 	// Output settings
-	viper.SetDefault("output.format", "text")
-	viper.SetDefault("output.color", true)
-	viper.SetDefault("output.pretty", true)
-	viper.SetDefault("output.quiet", false)
-	viper.SetDefault("output.timestamp", false)
-	viper.SetDefault("output.table_style", "ascii")
+	v.SetDefault("output.format", "text")
+	v.SetDefault("output.color", true)
+	v.SetDefault("output.pretty", true)
+	v.SetDefault("output.quiet", false)
+	v.SetDefault("output.timestamp", false)
+	v.SetDefault("output.envelope", false)
+	v.SetDefault("output.table_style", "ascii")
+	v.SetDefault("output.theme", "default")
+	v.SetDefault("output.delimiter", "")
+	v.SetDefault("output.html_style", false)
+	v.SetDefault("output.max_column_width", 0)
+	v.SetDefault("output.wrap", false)
+	v.SetDefault("output.add_columns", []string{})
+	v.SetDefault("output.group_by", "")
+	v.SetDefault("output.aggregates", "")
+	v.SetDefault("output.max_rows", 0)
+	v.SetDefault("output.page_size", 0)
+	v.SetDefault("output.parallel_rows", 2000)
+	v.SetDefault("output.fast_json_rows", 1000)
+	v.SetDefault("output.pager", true)
+	v.SetDefault("output.flatten_depth", 3)
+	v.SetDefault("output.unordered", false)
+	v.SetDefault("output.stream_buffer", 1024)
+	v.SetDefault("output.stream_overflow", "block")
+	v.SetDefault("output.write_buffer", "64KiB")
+	v.SetDefault("output.flush_interval", time.Second)
 
 	// API settings
-	viper.SetDefault("api.base_url", "https://api.example.com")
-	viper.SetDefault("api.timeout", 30*time.Second)
-	viper.SetDefault("api.retry_attempts", 3)
-	viper.SetDefault("api.retry_delay", 1*time.Second)
-	viper.SetDefault("api.follow_redirects", true)
-	viper.SetDefault("api.verify_ssl", true)
-	viper.SetDefault("api.user_agent", "termplate/1.0")
-	viper.SetDefault("api.rate_limit_per_sec", 10)
+	v.SetDefault("api.base_url", "https://api.example.com")
+	v.SetDefault("api.token_command", "")
+	v.SetDefault("api.oauth.client_id", "")
+	v.SetDefault("api.oauth.client_secret", "")
+	v.SetDefault("api.oauth.auth_url", "")
+	v.SetDefault("api.oauth.token_url", "")
+	v.SetDefault("api.oauth.device_url", "")
+	v.SetDefault("api.oauth.scopes", []string{})
+	v.SetDefault("api.oauth.redirect_port", 0)
+	v.SetDefault("api.oauth.token_store", "keyring")
+	v.SetDefault("api.timeout", 30*time.Second)
+	v.SetDefault("api.retry_attempts", 3)
+	v.SetDefault("api.retry_delay", 1*time.Second)
+	v.SetDefault("api.retry_max_delay", 30*time.Second)
+	v.SetDefault("api.retry_statuses", []int{408, 429, 502, 503, 504})
+	v.SetDefault("api.follow_redirects", true)
+	v.SetDefault("api.verify_ssl", true)
+	v.SetDefault("api.ca_bundle", "")
+	v.SetDefault("api.client_cert_file", "")
+	v.SetDefault("api.client_key_file", "")
+	v.SetDefault("api.user_agent", "termplate/1.0")
+	v.SetDefault("api.log_requests", false)
+	v.SetDefault("api.rate_limit_per_sec", 10)
+	v.SetDefault("api.rate_limit_burst", 0)
+	v.SetDefault("api.schema_dir", "")
+	v.SetDefault("api.record_schemas", false)
+	v.SetDefault("api.max_idle_conns", 100)
+	v.SetDefault("api.max_conns_per_host", 0)
+	v.SetDefault("api.idle_conn_timeout", 90*time.Second)
+	v.SetDefault("api.disable_keep_alives", false)
+	v.SetDefault("api.host_overrides", []string{})
+	v.SetDefault("api.dns_server", "")
+	v.SetDefault("api.max_response_size", "64MiB")
+	v.SetDefault("api.cache", true)
+	v.SetDefault("api.cache_max_size", "4MiB")
+	v.SetDefault("api.outbox", false)
+	v.SetDefault("api.operations_path", "/operations/{id}")
+	v.SetDefault("api.poll_interval", 1*time.Second)
+	v.SetDefault("api.poll_max_interval", 30*time.Second)
+	v.SetDefault("api.on_conflict", "ask")
+	v.SetDefault("api.resource_path", "/{kind}/{name}")
+	v.SetDefault("api.dry_run_query", "")
+	v.SetDefault("api.plan_expiry", time.Hour)
+	v.SetDefault("api.events_path", "")
+	v.SetDefault("api.graphql_path", "graphql")
+	v.SetDefault("api.persisted_queries", false)
+
+	// gRPC settings
+	v.SetDefault("grpc.address", "")
+	v.SetDefault("grpc.plaintext", false)
+	v.SetDefault("grpc.verify_ssl", true)
+	v.SetDefault("grpc.ca_bundle", "")
+	v.SetDefault("grpc.client_cert_file", "")
+	v.SetDefault("grpc.client_key_file", "")
+	v.SetDefault("grpc.server_name", "")
+	v.SetDefault("grpc.token", "")
+	v.SetDefault("grpc.metadata", map[string]string{})
+	v.SetDefault("grpc.timeout", 30*time.Second)
+	v.SetDefault("grpc.log_requests", false)
+	v.SetDefault("grpc.keepalive_time", 0)
+	v.SetDefault("grpc.keepalive_timeout", 20*time.Second)
+	v.SetDefault("grpc.retry_attempts", 3)
+	v.SetDefault("grpc.retry_delay", 1*time.Second)
+	v.SetDefault("grpc.retry_max_delay", 30*time.Second)
+	v.SetDefault("grpc.retry_codes", []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"})
 
 	// Server settings
-	viper.SetDefault("server.host", "localhost")
-	viper.SetDefault("server.port", 8080)
-	viper.SetDefault("server.read_timeout", 30*time.Second)
-	viper.SetDefault("server.write_timeout", 30*time.Second)
-	viper.SetDefault("server.idle_timeout", 60*time.Second)
-	viper.SetDefault("server.shutdown_timeout", 10*time.Second)
-	viper.SetDefault("server.tls_enabled", false)
+	v.SetDefault("server.host", "localhost")
+	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.read_timeout", 30*time.Second)
+	v.SetDefault("server.write_timeout", 30*time.Second)
+	v.SetDefault("server.idle_timeout", 60*time.Second)
+	v.SetDefault("server.shutdown_timeout", 10*time.Second)
+	v.SetDefault("server.tls_enabled", false)
 
 	// File processing settings
-	viper.SetDefault("files.input_dir", "./input")
-	viper.SetDefault("files.output_dir", "./output")
-	viper.SetDefault("files.temp_dir", getTempDir())
-	viper.SetDefault("files.patterns", []string{"*"})
-	viper.SetDefault("files.exclude_patterns", []string{})
-	viper.SetDefault("files.max_file_size", 100*1024*1024) // 100MB
-	viper.SetDefault("files.buffer_size", 4096)            // 4KB
-	viper.SetDefault("files.create_dirs", true)
-	viper.SetDefault("files.overwrite_existing", false)
-	viper.SetDefault("files.preserve_perms", true)
-	viper.SetDefault("files.backup_original", false)
+	v.SetDefault("files.input_dir", "./input")
+	v.SetDefault("files.output_dir", "./output")
+	v.SetDefault("files.temp_dir", getTempDir())
+	v.SetDefault("files.patterns", []string{"*"})
+	v.SetDefault("files.exclude_patterns", []string{})
+	v.SetDefault("files.max_file_size", "100MiB")
+	v.SetDefault("files.buffer_size", "4KiB")
+	v.SetDefault("files.create_dirs", true)
+	v.SetDefault("files.overwrite_existing", false)
+	v.SetDefault("files.preserve_perms", true)
+	v.SetDefault("files.backup_original", false)
 
 	// Database settings
-	viper.SetDefault("database.driver", "postgres")
-	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", 5432)
-	viper.SetDefault("database.database", "mydb")
-	viper.SetDefault("database.username", "user")
-	viper.SetDefault("database.ssl_mode", "disable")
-	viper.SetDefault("database.max_open_conns", 25)
-	viper.SetDefault("database.max_idle_conns", 5)
-	viper.SetDefault("database.conn_max_lifetime", 5*time.Minute)
-	viper.SetDefault("database.conn_max_idle_time", 10*time.Minute)
-	viper.SetDefault("database.timeout", 10*time.Second)
-	viper.SetDefault("database.migrations_path", "./migrations")
+	v.SetDefault("database.driver", "postgres")
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.database", "mydb")
+	v.SetDefault("database.username", "user")
+	v.SetDefault("database.ssl_mode", "disable")
+	v.SetDefault("database.max_open_conns", 25)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.conn_max_lifetime", 5*time.Minute)
+	v.SetDefault("database.conn_max_idle_time", 10*time.Minute)
+	v.SetDefault("database.timeout", 10*time.Second)
+	v.SetDefault("database.migrations_path", "./migrations")
+
+	// Budget settings
+	v.SetDefault("budget.max_api_calls", 1000)
+	v.SetDefault("budget.max_files", 500)
+	v.SetDefault("budget.max_rows", 1000)
+	v.SetDefault("budget.confirm", false)
+
+	// Download verification
+	v.SetDefault("verify.minisign_keys", []string{})
+	v.SetDefault("verify.cosign_keys", []string{})
+	v.SetDefault("verify.gpg_keys", []string{})
+	v.SetDefault("verify.require_signature", false)
+
+	// Bandwidth limits
+	v.SetDefault("transfer.download_limit", "0")
+	v.SetDefault("transfer.upload_limit", "0")
+	v.SetDefault("transfer.total_download_limit", "0")
+	v.SetDefault("transfer.total_upload_limit", "0")
+
+	// Remote config
+	v.SetDefault("remote.url", "")
+	v.SetDefault("remote.format", "")
+	v.SetDefault("remote.timeout", 5*time.Second)
+	v.SetDefault("remote.poll_interval", time.Minute)
+	v.SetDefault("remote.fallback", true)
+
+	// Event log
+	v.SetDefault("events.persist", true)
+	v.SetDefault("events.size", 1000)
+
+	// Shell completion
+	v.SetDefault("completion.cache_ttl", time.Minute)
+	v.SetDefault("completion.cache_max_stale", 24*time.Hour)
+	v.SetDefault("completion.timeout", 2*time.Second)
+
+	// Scheduled jobs
+	v.SetDefault("schedule.catch_up", "skip")
+
+	// Runtime limits
+	v.SetDefault("runtime.memory_limit", "0")
+	v.SetDefault("runtime.max_procs", 0)
+	v.SetDefault("runtime.child.max_memory", "0")
+	v.SetDefault("runtime.child.max_cpu_time", time.Duration(0))
+	v.SetDefault("runtime.child.max_open_files", 0)
+
+	// Command sections, with their flags' defaults
+	setCommandDefaults(v)
 }
 
 // getTempDir returns the system temp directory
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Deprecation declares a config key that was renamed or is going away
+type Deprecation struct {
+	Key       string // Deprecated key, e.g. "api.rate_limit"
+	NewKey    string // Replacement key; empty when the setting was dropped
+	RemovedIn string // Version that stops reading Key, e.g. "v2.0.0"
+	Note      string // Optional extra advice
+}
+
+// Deprecations lists the deprecated keys. Add an entry when renaming or
+// dropping a key so existing config files keep working for a release:
+//
+//	{Key: "api.rate_limit", NewKey: "api.rate_limit_per_sec", RemovedIn: "v2.0.0"}
+var Deprecations = []Deprecation{}
+
+// DeprecationNotice is a deprecated key found in the config file
+type DeprecationNotice struct {
+	Deprecation
+	Ignored bool // NewKey is also set, so the old value isn't used
+}
+
+func (n DeprecationNotice) String() string {
+	msg := fmt.Sprintf("config key %q is deprecated", n.Key)
+	if n.NewKey != "" {
+		msg += fmt.Sprintf("; use %q", n.NewKey)
+	}
+	if n.RemovedIn != "" {
+		msg += fmt.Sprintf(" (removed in %s)", n.RemovedIn)
+	}
+	if n.Ignored {
+		msg += fmt.Sprintf("; ignored because %q is also set", n.NewKey)
+	}
+	if n.Note != "" {
+		msg += ". " + n.Note
+	}
+	return msg
+}
+
+// Deprecations returns the deprecated keys set in the config file
+func (l *Loader) Deprecations() []DeprecationNotice {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.deprecations()
+}
+
+func (l *Loader) deprecations() []DeprecationNotice {
+	var notices []DeprecationNotice
+	for _, d := range Deprecations {
+		if !l.v.InConfig(d.Key) {
+			continue
+		}
+		notices = append(notices, DeprecationNotice{
+			Deprecation: d,
+			Ignored:     d.NewKey != "" && l.v.InConfig(d.NewKey),
+		})
+	}
+	return notices
+}
+
+// applyDeprecations maps the values of deprecated keys onto their
+// replacements and logs a warning for each. The old value takes the place
+// of the default, so the new key, environment variables, and flags still
+// win over it.
+func (l *Loader) applyDeprecations() {
+	for _, n := range l.deprecations() {
+		slog.Warn(n.String())
+		if n.NewKey != "" && !n.Ignored {
+			l.v.SetDefault(n.NewKey, l.v.Get(n.Key))
+		}
+	}
+}
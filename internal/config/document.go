@@ -0,0 +1,320 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a config file being edited. Changes are made to its YAML
+// node tree, so comments, key order, and keys the program doesn't know
+// about survive Set and Unset.
+//
+//	doc, err := config.OpenDocument(path)
+//	if err := doc.Set("output.format", "json"); err != nil { ... }
+//	err = doc.Save()
+type Document struct {
+	path string
+	mode fs.FileMode
+	root *yaml.Node // Mapping at the top of the document
+	doc  *yaml.Node
+}
+
+// OpenDocument reads the config file at path for editing. A file that
+// doesn't exist yet is an empty document, created by Save.
+func OpenDocument(path string) (*Document, error) {
+	d := &Document{path: path, mode: 0o600}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	default:
+		if info, err := os.Stat(path); err == nil {
+			d.mode = info.Mode().Perm()
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if len(doc.Content) > 0 {
+			d.doc = &doc
+		}
+	}
+
+	if d.doc == nil {
+		d.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	d.root = d.doc.Content[0]
+	if d.root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parsing %s: the top level is not a mapping of keys", path)
+	}
+	return d, nil
+}
+
+// Path returns the file the document is saved to
+func (d *Document) Path() string {
+	return d.path
+}
+
+// Set sets a single setting such as "api.timeout". The value is checked
+// against the setting's type: a bool, number, duration ("30s"), size
+// ("100MiB"), or, for lists, comma-separated items. Values with ${VAR}
+// references are checked once expanded, when the config is loaded. Errors
+// don't repeat the key.
+func (d *Document) Set(key, value string) error {
+	key = strings.ToLower(key)
+	t, ok := keyType(key)
+	if !ok {
+		return errors.New("unknown config key")
+	}
+	node, err := valueNode(t, value)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(key, ".")
+	m := d.root
+	for _, part := range parts[:len(parts)-1] {
+		i := mappingIndex(m, part)
+		if i < 0 {
+			m.Content = append(m.Content, scalarNode(part), &yaml.Node{Kind: yaml.MappingNode})
+			i = len(m.Content) - 2
+		}
+		// A section left empty ("output:") is null rather than a mapping
+		if m.Content[i+1].Kind != yaml.MappingNode {
+			m.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		m = m.Content[i+1]
+	}
+
+	last := parts[len(parts)-1]
+	if i := mappingIndex(m, last); i >= 0 {
+		node.LineComment = m.Content[i+1].LineComment
+		m.Content[i+1] = node
+		return nil
+	}
+	m.Content = append(m.Content, scalarNode(last), node)
+	return nil
+}
+
+// Position returns the line and column where key is set in the file, or
+// zeros when the file doesn't set it
+func (d *Document) Position(key string) (line, column int) {
+	m := d.root
+	parts := strings.Split(key, ".")
+	for n, part := range parts {
+		i := mappingIndex(m, part)
+		if i < 0 {
+			return 0, 0
+		}
+		if n == len(parts)-1 {
+			return m.Content[i].Line, m.Content[i].Column
+		}
+		if m = m.Content[i+1]; m.Kind != yaml.MappingNode {
+			return 0, 0
+		}
+	}
+	return 0, 0
+}
+
+// Values returns the single values the file sets, such as "api.token",
+// by key
+func (d *Document) Values() map[string]string {
+	values := map[string]string{}
+	var walk func(prefix string, m *yaml.Node)
+	walk = func(prefix string, m *yaml.Node) {
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key, value := prefix+strings.ToLower(m.Content[i].Value), m.Content[i+1]
+			switch value.Kind {
+			case yaml.MappingNode:
+				walk(key+".", value)
+			case yaml.ScalarNode:
+				values[key] = value.Value
+			}
+		}
+	}
+	walk("", d.root)
+	return values
+}
+
+// Replace sets key, a single value the file already sets, to value as a
+// string, without checking it against the setting's type; e.g. to store
+// it encrypted. It reports whether the file sets key.
+func (d *Document) Replace(key, value string) bool {
+	m := d.root
+	parts := strings.Split(strings.ToLower(key), ".")
+	for _, part := range parts[:len(parts)-1] {
+		i := mappingIndex(m, part)
+		if i < 0 || m.Content[i+1].Kind != yaml.MappingNode {
+			return false
+		}
+		m = m.Content[i+1]
+	}
+	i := mappingIndex(m, parts[len(parts)-1])
+	if i < 0 || m.Content[i+1].Kind != yaml.ScalarNode {
+		return false
+	}
+	node := scalarNode(value)
+	node.LineComment = m.Content[i+1].LineComment
+	m.Content[i+1] = node
+	return true
+}
+
+// Unset removes key from the file, and any sections left empty, so the
+// setting falls back to its default. Any key can be removed, including
+// unknown and deprecated ones. ok is false when the file doesn't set key.
+func (d *Document) Unset(key string) (ok bool) {
+	parts := strings.Split(strings.ToLower(key), ".")
+	path := []*yaml.Node{d.root}
+	for _, part := range parts[:len(parts)-1] {
+		m := path[len(path)-1]
+		i := mappingIndex(m, part)
+		if i < 0 || m.Content[i+1].Kind != yaml.MappingNode {
+			return false
+		}
+		path = append(path, m.Content[i+1])
+	}
+
+	m := path[len(path)-1]
+	i := mappingIndex(m, parts[len(parts)-1])
+	if i < 0 {
+		return false
+	}
+	removePair(m, i)
+
+	// Remove the sections that held only key, innermost first
+	for n := len(path) - 1; n > 0 && len(path[n].Content) == 0; n-- {
+		parent := path[n-1]
+		removePair(parent, mappingIndex(parent, parts[n-1]))
+	}
+	return true
+}
+
+// removePair removes the key at index i of mapping m and its value. A
+// comment above the key, often the file's heading, moves to the next key.
+func removePair(m *yaml.Node, i int) {
+	if comment := m.Content[i].HeadComment; comment != "" && i+2 < len(m.Content) {
+		next := m.Content[i+2]
+		next.HeadComment = strings.TrimSuffix(comment+"\n"+next.HeadComment, "\n")
+	}
+	m.Content = append(m.Content[:i], m.Content[i+2:]...)
+}
+
+// Save writes the document to its file, replacing it atomically and
+// creating its directory when needed
+func (d *Document) Save() error {
+	data, err := d.encode()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(d.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(d.path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", d.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", d.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", d.path, err)
+	}
+	if err := os.Chmod(tmp.Name(), d.mode); err != nil {
+		return fmt.Errorf("writing %s: %w", d.path, err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("writing %s: %w", d.path, err)
+	}
+	return nil
+}
+
+// encode returns the document as YAML, or nothing when it's empty
+func (d *Document) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if len(d.root.Content) > 0 || d.root.HeadComment != "" {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(d.doc); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", d.path, err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", d.path, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingIndex returns the index of key in the content of mapping m,
+// matched ignoring case like viper does, or -1
+func mappingIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if strings.EqualFold(m.Content[i].Value, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// valueNode parses value as a setting of type t
+func valueNode(t reflect.Type, value string) (*yaml.Node, error) {
+	if t.Kind() == reflect.Struct || t.Kind() == reflect.Map {
+		return nil, errors.New("the key is a section; set the keys inside it")
+	}
+	// References are checked once expanded, when the config is loaded
+	if strings.Contains(value, "${") {
+		return scalarNode(value), nil
+	}
+
+	switch {
+	case t == durationType:
+		if _, err := ParseDuration(value); err != nil {
+			return nil, err
+		}
+	case t == byteSizeType:
+		if _, err := ParseByteSize(value); err != nil {
+			return nil, err
+		}
+	case t.Kind() == reflect.Bool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid value %q: expected true or false", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: value}, nil
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid value %q: expected a whole number", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: value}, nil
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid value %q: expected a number", value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: value}, nil
+	case t.Kind() == reflect.Slice:
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list.Content = append(list.Content, scalarNode(item))
+			}
+		}
+		return list, nil
+	}
+	return scalarNode(value), nil
+}
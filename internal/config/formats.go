@@ -0,0 +1,32 @@
+package config
+
+import (
+	"slices"
+	"sync"
+)
+
+var (
+	outputFormatsMu sync.RWMutex
+
+	// outputFormats are the valid output.format values besides
+	// go-template=...: the built-in formats, then those registered
+	outputFormats = []string{"text", "json", "ndjson", "yaml", "table", "csv", "tsv", "html", "xml"}
+)
+
+// AddOutputFormat makes name a valid output.format; output.RegisterFormat
+// calls it
+func AddOutputFormat(name string) {
+	outputFormatsMu.Lock()
+	defer outputFormatsMu.Unlock()
+	if !slices.Contains(outputFormats, name) {
+		outputFormats = append(outputFormats, name)
+	}
+}
+
+// OutputFormats returns the valid output.format values besides
+// go-template=...
+func OutputFormats() []string {
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+	return slices.Clone(outputFormats)
+}
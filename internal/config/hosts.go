@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// HostOverride sends the connections for a host to another address, like
+// an /etc/hosts entry
+type HostOverride struct {
+	Host string // "api.example.com", or "*.example.com" for its subdomains
+	Addr string // IP or hostname, with a port when it differs from the request's
+}
+
+// ParseHostOverrides parses api.host_overrides entries of the form
+// "host=address", e.g. "api.example.com=10.0.0.5" or
+// "*.staging.example.com=[fd00::7]:8443"
+func ParseHostOverrides(entries []string) ([]HostOverride, error) {
+	overrides := make([]HostOverride, 0, len(entries))
+	for _, entry := range entries {
+		host, addr, ok := strings.Cut(entry, "=")
+		host, addr = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(addr)
+		if !ok || host == "" || addr == "" {
+			return nil, fmt.Errorf("invalid host override %q: expected host=address", entry)
+		}
+		if strings.ContainsAny(strings.TrimPrefix(host, "*."), "*:/[]") {
+			return nil, fmt.Errorf("invalid host override %q: %q is not a hostname or *.domain", entry, host)
+		}
+		if h, port, err := net.SplitHostPort(addr); err == nil {
+			if h == "" || port == "" {
+				return nil, fmt.Errorf("invalid host override %q: %q needs a host and a port", entry, addr)
+			}
+		} else if strings.Count(addr, ":") == 1 || strings.ContainsAny(addr, "/[]") {
+			return nil, fmt.Errorf("invalid host override %q: %q is not an address", entry, addr)
+		}
+		overrides = append(overrides, HostOverride{Host: host, Addr: addr})
+	}
+	return overrides, nil
+}
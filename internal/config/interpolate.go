@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// Expand interpolates environment variables in a config value:
+//
+//	${VAR}          the value of VAR; an error when VAR is not set
+//	${VAR:-default} the value of VAR, or default when VAR is unset or empty
+//	${VAR-default}  the value of VAR, or default when VAR is unset
+//	${VAR:?message} the value of VAR; an error saying message when VAR is
+//	                unset or empty
+//	${VAR?message}  the value of VAR; an error saying message when VAR is
+//	                unset
+//	$${             a literal "${"
+//
+// Defaults and messages may themselves contain references, e.g.
+// ${A:-${B:-x}}.
+func Expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			b.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference in %q", s)
+			}
+			value, err := expandRef(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end + 1
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the "}" that closes a reference whose
+// body starts at start, skipping nested references
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func expandRef(ref string) (string, error) {
+	// The name runs up to the operator, if any
+	end := strings.IndexFunc(ref, func(r rune) bool { return !isNameRune(r) })
+	name, op := ref, ""
+	if end >= 0 {
+		name, op = ref[:end], ref[end:]
+	}
+	if !isEnvName(name) {
+		return "", fmt.Errorf("invalid variable reference ${%s}", ref)
+	}
+	value, ok := os.LookupEnv(name)
+
+	// With a colon, an empty value counts as unset
+	emptyUnset := strings.HasPrefix(op, ":")
+	unset := !ok || (emptyUnset && value == "")
+	arg := strings.TrimPrefix(op, ":")
+	switch {
+	case op == "":
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set (use ${%s:-default} to make it optional)", name, name)
+		}
+		return value, nil
+	case strings.HasPrefix(arg, "-"):
+		if unset {
+			return Expand(arg[1:])
+		}
+		return value, nil
+	case strings.HasPrefix(arg, "?"):
+		if !unset {
+			return value, nil
+		}
+		msg, err := Expand(arg[1:])
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case msg != "":
+			return "", fmt.Errorf("environment variable %s: %s", name, msg)
+		case ok:
+			return "", fmt.Errorf("environment variable %s is empty", name)
+		default:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+	}
+	return "", fmt.Errorf("invalid variable reference ${%s}", ref)
+}
+
+func isEnvName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	return strings.IndexFunc(s, func(r rune) bool { return !isNameRune(r) }) < 0
+}
+
+func isNameRune(r rune) bool {
+	return r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+}
+
+// expandEnvHook decrypts or interpolates every string value as it is
+// decoded (see resolveValue), before viper's default hooks parse durations
+// and lists from it
+func expandEnvHook() mapstructure.DecodeHookFunc {
+	return func(from, _ reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String {
+			return data, nil
+		}
+		return resolveValue(data.(string))
+	}
+}
+
+// resolveValue returns the value a setting holds: decrypted when it's
+// encrypted (see EncryptValue), and otherwise interpolated. A decrypted
+// value is used as it is, so a secret containing "${" stays intact.
+func resolveValue(value string) (string, error) {
+	if IsEncrypted(value) {
+		return decryptValue(value)
+	}
+	return Expand(value)
+}
+
+// decodeHook is viper's default decode hook with interpolation first and
+// byte size parsing added
+func decodeHook() viper.DecoderConfigOption {
+	return viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		expandEnvHook(),
+		stringToDurationHook(),
+		stringToByteSizeHook(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToIntSliceHook(),
+	))
+}
+
+// stringToIntSliceHook splits a comma-separated string, e.g. from an
+// environment variable, into a list of numbers such as api.retry_statuses;
+// mapstructure's own hook only makes lists of strings
+func stringToIntSliceHook() mapstructure.DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to.Kind() != reflect.Slice || to.Elem().Kind() != reflect.Int {
+			return data, nil
+		}
+		s := strings.TrimSpace(data.(string))
+		if s == "" {
+			return []string{}, nil
+		}
+		parts := strings.Split(s, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, nil
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// The keychain entry holding the config key
+const (
+	keychainService = "termplate"
+	keychainAccount = "config-key"
+)
+
+// ErrNoKeychain is returned where there's no OS keychain to use: macOS
+// has security(1), and Linux secret-tool(1) from libsecret
+var ErrNoKeychain = errors.New("no OS keychain available")
+
+// errNotInKeychain is returned when the keychain has no config key
+var errNotInKeychain = errors.New("no config key in the keychain")
+
+// keychainGet returns the config key stored in the OS keychain
+func keychainGet() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", keychainAccount, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", keychainAccount)
+	default:
+		return "", ErrNoKeychain
+	}
+	if cmd.Err != nil {
+		return "", ErrNoKeychain
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		// Both exit non-zero for a missing entry
+		return "", errNotInKeychain
+	case err != nil:
+		return "", fmt.Errorf("reading the keychain: %w", err)
+	}
+	key := strings.TrimSpace(stdout.String())
+	if key == "" {
+		return "", errNotInKeychain
+	}
+	return key, nil
+}
+
+// keychainSet stores the config key in the OS keychain, replacing any
+func keychainSet(key string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// -U updates an existing entry
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", keychainService, "-a", keychainAccount, "-w", key)
+	case "linux":
+		// The secret is read from stdin, keeping it out of the process list
+		cmd = exec.Command("secret-tool", "store", "--label", "termplate config key", "service", keychainService, "account", keychainAccount)
+		cmd.Stdin = strings.NewReader(key)
+	default:
+		return ErrNoKeychain
+	}
+	if cmd.Err != nil {
+		return ErrNoKeychain
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("writing the keychain: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnknownKeys returns the keys in a config file that no Config field or
+// command section (see BindCommand) reads, which are usually typos.
+// Deprecated keys are not reported.
+func UnknownKeys(file string) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+
+	deprecated := map[string]bool{}
+	for _, d := range Deprecations {
+		deprecated[strings.ToLower(d.Key)] = true
+	}
+
+	var unknown []string
+	var walk func(prefix string, m map[string]interface{}, t reflect.Type)
+	walk = func(prefix string, m map[string]interface{}, t reflect.Type) {
+		for k, v := range m {
+			key := strings.ToLower(prefix + k)
+			if deprecated[key] {
+				continue
+			}
+			// Each profile holds keys of the whole config
+			if key == profilesKey {
+				profiles, _ := v.(map[string]interface{})
+				for name, p := range profiles {
+					if sub, isMap := p.(map[string]interface{}); isMap {
+						walk(profilesKey+"."+strings.ToLower(name)+".", sub, t)
+					}
+				}
+				continue
+			}
+			field, ok := commandSectionType(key)
+			if !ok {
+				field, ok = fieldForKey(t, strings.ToLower(k))
+			}
+			if !ok {
+				unknown = append(unknown, key)
+				continue
+			}
+			if sub, isMap := v.(map[string]interface{}); isMap && field.Kind() == reflect.Struct {
+				walk(key+".", sub, field)
+			}
+		}
+	}
+	walk("", raw, reflect.TypeOf(Config{}))
+
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// keyType returns the type of the Config field or command section field a
+// dotted key such as "api.base_url", or "profiles.staging.api.base_url" in
+// a profile, decodes into
+func keyType(key string) (reflect.Type, bool) {
+	return lookupKeyType(key, true)
+}
+
+// configKeyType is keyType for the fields of Config alone
+func configKeyType(key string) (reflect.Type, bool) {
+	return lookupKeyType(key, false)
+}
+
+func lookupKeyType(key string, sections bool) (reflect.Type, bool) {
+	parts := strings.Split(strings.ToLower(key), ".")
+	if len(parts) > 2 && parts[0] == profilesKey {
+		parts = parts[2:]
+	}
+	t := reflect.TypeOf(Config{})
+	for i, part := range parts {
+		if section, ok := commandSectionType(strings.Join(parts[:i+1], ".")); ok && sections {
+			t = section
+			continue
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+		field, ok := fieldForKey(t, part)
+		if !ok {
+			return nil, false
+		}
+		t = field
+	}
+	return t, true
+}
+
+// fieldForKey returns the type of the field of struct t decoded from key
+func fieldForKey(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		if name != "-" && strings.EqualFold(name, key) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns the dotted keys of the settings that hold a single value
+// or a list, such as "api.timeout", in declaration order
+func Keys() []string {
+	var keys []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			switch {
+			case name == "-" || !f.IsExported() || f.Type.Kind() == reflect.Map:
+			case f.Type.Kind() == reflect.Struct:
+				walk(prefix+name+".", f.Type)
+			default:
+				keys = append(keys, prefix+name)
+			}
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return keys
+}
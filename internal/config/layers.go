@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// layer is a config file read, with the keys it sets
+type layer struct {
+	file string
+	keys map[string]bool
+}
+
+// sets reports whether the layer sets key, or a key inside it when key
+// is a section
+func (ly layer) sets(key string) bool {
+	if ly.keys[key] {
+		return true
+	}
+	for k := range ly.keys {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// readFiles reads the config files in order, each merged over those
+// before it, and records the keys each sets. Without files set, it reads
+// the file found by searching.
+func (l *Loader) readFiles() error {
+	l.layers = nil
+	if len(l.paths) == 0 {
+		if err := l.v.ReadInConfig(); err != nil {
+			return err
+		}
+		return l.addLayer(l.v.ConfigFileUsed())
+	}
+
+	files, err := expandPaths(l.paths)
+	if err != nil {
+		return err
+	}
+	for i, file := range files {
+		// The first file replaces what an earlier read left, e.g. when Watch
+		// reloads
+		l.v.SetConfigFile(file)
+		read := l.v.MergeInConfig
+		if i == 0 {
+			read = l.v.ReadInConfig
+		}
+		if err := read(); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := l.addLayer(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addLayer records the keys file sets
+func (l *Loader) addLayer(file string) error {
+	fv := viper.New()
+	fv.SetConfigFile(file)
+	if err := fv.ReadInConfig(); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	ly := layer{file: file, keys: map[string]bool{}}
+	for _, key := range fv.AllKeys() {
+		ly.keys[key] = true
+	}
+	l.layers = append(l.layers, ly)
+	return nil
+}
+
+// expandPaths returns the files paths stand for: a file itself, and a
+// directory the .yaml and .yml files in it, in name order
+func expandPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// Sources of a setting's value, from lowest to highest priority
+const (
+	SourceDefault = "default"
+	SourceRemote  = "remote"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceSet     = "set" // Set by the program, e.g. for --no-color
+)
+
+// Origin is where the value of a setting comes from
+type Origin struct {
+	Source  string `json:"source" yaml:"source"`                       // One of the Source constants
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"`       // The file, remote config URL, environment variable, or flag
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"` // The profile of the file setting it
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Profile != "":
+		return fmt.Sprintf("%s (profile %s)", o.Name, o.Profile)
+	case o.Name != "":
+		return o.Name
+	default:
+		return o.Source
+	}
+}
+
+// Origin returns where the value of key comes from: the flag, environment
+// variable, config file, or remote config that sets it, with the file setting a key
+// inside a section such as api.headers counting for the section
+func (l *Loader) Origin(key string) Origin {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	key = strings.ToLower(key)
+
+	if l.set[key] {
+		return Origin{Source: SourceSet}
+	}
+	if f, ok := l.flags[key]; ok && f.Changed {
+		return Origin{Source: SourceFlag, Name: "--" + f.Name}
+	}
+	if name, ok := l.EnvVar(key); ok {
+		return Origin{Source: SourceEnv, Name: name}
+	}
+
+	// The selected profile is merged over every file
+	if profile := strings.ToLower(l.v.GetString("profile")); profile != "" && l.profileErr == nil {
+		for i := len(l.layers) - 1; i >= 0; i-- {
+			if l.layers[i].sets(profilesKey + "." + profile + "." + key) {
+				return Origin{Source: SourceFile, Name: l.layers[i].file, Profile: profile}
+			}
+		}
+	}
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		if l.layers[i].sets(key) {
+			return Origin{Source: SourceFile, Name: l.layers[i].file}
+		}
+	}
+	// A deprecated key sets its replacement when no file sets that
+	for _, d := range Deprecations {
+		if d.NewKey != key {
+			continue
+		}
+		for i := len(l.layers) - 1; i >= 0; i-- {
+			if l.layers[i].sets(strings.ToLower(d.Key)) {
+				return Origin{Source: SourceFile, Name: l.layers[i].file}
+			}
+		}
+	}
+	if l.remote != nil && l.remote.sets(key) {
+		return Origin{Source: SourceRemote, Name: l.remote.file}
+	}
+	return Origin{Source: SourceDefault}
+}
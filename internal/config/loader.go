@@ -0,0 +1,333 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Loader builds a Config from, in increasing priority: defaults, config
+// files, environment variables, flags, and values set with Set. Each Loader
+// has its own viper instance, so loaders never share state: tests can build
+// one per case and run in parallel, and several configs can coexist.
+//
+//	l := config.NewLoader().WithFile(path).WithEnv("TERMPLATE")
+//	if err := l.Read(); err != nil { ... }
+//	cfg, err := l.Load()
+type Loader struct {
+	mu         sync.RWMutex // Guards v once Watch may reload it
+	v          *viper.Viper
+	read       bool
+	readErr    error
+	profileErr error // The selected profile isn't defined
+
+	paths      []string // Set by WithFiles
+	layers     []layer  // The config files read, in order
+	remote     *layer   // The remote config read, beneath the files
+	searchName string   // Set by WithSearchPaths
+	searchDirs []string
+
+	flags map[string]*pflag.Flag // Bound by BindFlags, by key
+	set   map[string]bool        // Keys given to Set
+}
+
+// NewLoader creates a loader holding only the defaults
+func NewLoader() *Loader {
+	v := viper.New()
+	setDefaults(v)
+	return &Loader{v: v}
+}
+
+// WithFile reads the config from path; an empty path is ignored
+func (l *Loader) WithFile(path string) *Loader {
+	return l.WithFiles(path)
+}
+
+// WithFiles reads the config from each path in order, each file merged
+// over those before it. A directory, such as config.d, stands for the
+// .yaml and .yml files in it, in name order. Empty paths are ignored.
+func (l *Loader) WithFiles(paths ...string) *Loader {
+	for _, path := range paths {
+		if path != "" {
+			l.paths = append(l.paths, path)
+		}
+	}
+	return l
+}
+
+// WithSearchPaths looks for a YAML file called name in each dir, in order,
+// when WithFiles set no file
+func (l *Loader) WithSearchPaths(name string, dirs ...string) *Loader {
+	for _, dir := range dirs {
+		l.v.AddConfigPath(dir)
+	}
+	l.searchName, l.searchDirs = name, dirs
+	l.v.SetConfigType("yaml")
+	l.v.SetConfigName(name)
+	return l
+}
+
+// WithEnv reads environment variables named prefix_KEY, with dots in
+// nested keys replaced by underscores (TERMPLATE_OUTPUT_FORMAT)
+func (l *Loader) WithEnv(prefix string) *Loader {
+	l.v.SetEnvPrefix(prefix)
+	l.v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	l.v.AutomaticEnv()
+	return l
+}
+
+// BindFlags reads each flag into the config key of the same name, or the
+// key keys maps it to. Only flags the user set override other sources.
+func (l *Loader) BindFlags(flags *pflag.FlagSet, keys map[string]string) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		key := f.Name
+		if k, ok := keys[f.Name]; ok {
+			key = k
+		}
+		if l.flags == nil {
+			l.flags = map[string]*pflag.Flag{}
+		}
+		l.flags[key] = f
+		err = l.v.BindPFlag(key, f)
+	})
+	return err
+}
+
+// Set overrides key, taking priority over every other source
+func (l *Loader) Set(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.set == nil {
+		l.set = map[string]bool{}
+	}
+	l.set[strings.ToLower(key)] = true
+	l.v.Set(key, value)
+}
+
+// Read reads the config files and the remote config (see RemoteConfig),
+// maps deprecated keys onto their replacements, and merges the selected
+// profile over them. A file not found by searching is not an error; a
+// missing file set with WithFiles is. Only the first call reads; later
+// calls return its result.
+func (l *Loader) Read() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.read {
+		return l.readErr
+	}
+	l.read = true
+
+	fileErr := l.readFiles()
+	var notFound viper.ConfigFileNotFoundError
+	if fileErr != nil && !errors.As(fileErr, &notFound) {
+		l.readErr = fmt.Errorf("reading config: %w", fileErr)
+		return l.readErr
+	}
+	if len(l.layers) > 0 {
+		slog.Debug("using config files", "files", l.configFiles())
+	}
+	if err := l.readRemote(); err != nil {
+		l.readErr = fmt.Errorf("reading config: %w", err)
+		return l.readErr
+	}
+	if fileErr == nil {
+		l.applyDeprecations()
+	}
+	l.applyProfile()
+	return nil
+}
+
+// ConfigFileUsed returns the config file set or found, or "" when there
+// is none. With several files, it is the last, which takes priority.
+func (l *Loader) ConfigFileUsed() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.configFileUsed()
+}
+
+func (l *Loader) configFileUsed() string {
+	if len(l.layers) > 0 {
+		return l.layers[len(l.layers)-1].file
+	}
+	if len(l.paths) > 0 {
+		return l.paths[len(l.paths)-1]
+	}
+	return l.v.ConfigFileUsed()
+}
+
+// ConfigFiles returns the config files in use, in the order they are
+// merged. When reading failed, they are the files set with WithFiles, so
+// the broken one can be checked.
+func (l *Loader) ConfigFiles() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.readErr != nil && len(l.paths) > 0 {
+		if files, err := expandPaths(l.paths); err == nil {
+			return files
+		}
+		return slices.Clone(l.paths)
+	}
+	return l.configFiles()
+}
+
+func (l *Loader) configFiles() []string {
+	files := make([]string, len(l.layers))
+	for i, layer := range l.layers {
+		files[i] = layer.file
+	}
+	return files
+}
+
+// WritableFile returns the config file that edits such as config set go
+// to: the file set or found, the last of several so the edit takes
+// effect, or else name.yaml in the first directory searched. It is ""
+// when there is neither.
+func (l *Loader) WritableFile() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if file := l.configFileUsed(); file != "" {
+		return file
+	}
+	if len(l.searchDirs) == 0 {
+		return ""
+	}
+	return filepath.Join(l.searchDirs[0], l.searchName+".yaml")
+}
+
+// EnvVar returns the environment variable that overrides key, such as
+// TERMPLATE_OUTPUT_FORMAT, when it is set
+func (l *Loader) EnvVar(key string) (name string, ok bool) {
+	prefix := l.v.GetEnvPrefix()
+	if prefix == "" {
+		return "", false
+	}
+	name = strings.ToUpper(prefix + "_" + strings.ReplaceAll(key, ".", "_"))
+	_, ok = os.LookupEnv(name)
+	return name, ok
+}
+
+// GetString returns the raw value of key, for settings needed before the
+// whole config can be decoded
+func (l *Loader) GetString(key string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.v.GetString(key)
+}
+
+// GetBool returns the raw value of key, for settings needed before the
+// whole config can be decoded
+func (l *Loader) GetBool(key string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.v.GetBool(key)
+}
+
+// GetStringSlice returns the raw value of key, for settings needed before
+// the whole config can be decoded
+func (l *Loader) GetStringSlice(key string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.v.GetStringSlice(key)
+}
+
+// GetInt returns the raw value of key, for settings needed before the
+// whole config can be decoded
+func (l *Loader) GetInt(key string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.v.GetInt(key)
+}
+
+// Value returns a single setting as a string, decrypted or with
+// environment variables interpolated (see Expand). key must name a setting
+// that holds one value, not a section or a list.
+func (l *Loader) Value(key string) (string, error) {
+	t, ok := keyType(key)
+	if !ok {
+		return "", fmt.Errorf("unknown config key %q", key)
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		return "", fmt.Errorf("config key %q does not hold a single value", key)
+	}
+	return resolveValue(l.GetString(key))
+}
+
+// Load decodes the configuration, interpolating environment variables in
+// string values (see Expand). Each call returns a new Config, so a caller
+// adjusting its copy doesn't affect anyone else.
+func (l *Loader) Load() (*Config, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.profileErr != nil {
+		return nil, l.profileErr
+	}
+	var cfg Config
+	if err := l.v.Unmarshal(&cfg, decodeHook()); err != nil {
+		if err = valuesError(err, ""); errors.As(err, new(*ValuesError)) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// LoadKeys decodes only the settings at keys and those under them, such
+// as "runtime" or "api.retry_delay", leaving the rest of the Config zero.
+// It's for the few settings every command needs before it runs, so that
+// credentials elsewhere aren't decrypted, and the keychain holding their
+// key isn't asked, until a command that uses them loads the config.
+func (l *Loader) LoadKeys(keys ...string) (*Config, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.profileErr != nil {
+		return nil, l.profileErr
+	}
+	sub := viper.New()
+	for _, key := range l.v.AllKeys() {
+		for _, k := range keys {
+			if key == k || strings.HasPrefix(key, k+".") {
+				sub.Set(key, l.v.Get(key))
+				break
+			}
+		}
+	}
+	var cfg Config
+	if err := sub.Unmarshal(&cfg, decodeHook()); err != nil {
+		if err = valuesError(err, ""); errors.As(err, new(*ValuesError)) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
+	}
+	return &cfg, nil
+}
+
+type loaderKey struct{}
+
+// WithContext returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// FromContext returns the loader carried by ctx, or one holding only the
+// defaults
+func FromContext(ctx context.Context) *Loader {
+	if l, ok := ctx.Value(loaderKey{}).(*Loader); ok {
+		return l
+	}
+	return NewLoader()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// profilesKey is the config file section holding named profiles, each a
+// partial config merged over the rest of the file when selected:
+//
+//	profile: dev            # Selected unless --profile or TERMPLATE_PROFILE says otherwise
+//	profiles:
+//	  staging:
+//	    api:
+//	      base_url: https://staging.api.example.com
+const profilesKey = "profiles"
+
+// Profiles returns the names of the profiles defined in the config file,
+// sorted
+func (l *Loader) Profiles() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.profiles()
+}
+
+func (l *Loader) profiles() []string {
+	names := make([]string, 0)
+	for name := range l.v.GetStringMap(profilesKey) {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyProfile merges the selected profile over the config file, so it
+// takes the file's place: environment variables and flags still win over
+// it. A profile that isn't defined makes Load fail rather than quietly
+// running against the base settings.
+func (l *Loader) applyProfile() {
+	name := strings.ToLower(l.v.GetString("profile"))
+	if name == "" {
+		return
+	}
+	profile, ok := l.v.GetStringMap(profilesKey)[name].(map[string]interface{})
+	if !ok {
+		defined := "none are defined"
+		if names := l.profiles(); len(names) > 0 {
+			defined = "defined: " + strings.Join(names, ", ")
+		}
+		l.profileErr = fmt.Errorf("unknown profile %q (%s)", name, defined)
+		return
+	}
+	if err := l.v.MergeConfigMap(profile); err != nil {
+		l.profileErr = fmt.Errorf("applying profile %q: %w", name, err)
+		return
+	}
+	slog.Debug("using config profile", "profile", name)
+}
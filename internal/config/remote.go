@@ -0,0 +1,256 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// maxRemoteSize bounds a remote config
+const maxRemoteSize = 10 << 20
+
+// remoteCache is the copy of a remote config kept for conditional requests
+// and for when the remote can't be reached
+type remoteCache struct {
+	URL         string    `json:"url"`
+	ETag        string    `json:"etag,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Fetched     time.Time `json:"fetched"`
+	Body        []byte    `json:"body"`
+}
+
+// readRemote reads the config at remote.url, when set, beneath the config
+// files, environment, and flags: its settings take the place of the
+// defaults. When fetching it fails and remote.fallback is on, the copy
+// fetched last is used, or else only the local files.
+func (l *Loader) readRemote() error {
+	if l.remote != nil {
+		// Settings the remote config no longer has go back to the defaults
+		setDefaults(l.v)
+		l.remote = nil
+	}
+	// Read key by key, as a section wouldn't have the defaults
+	rc := RemoteConfig{
+		URL:      l.v.GetString("remote.url"),
+		Format:   l.v.GetString("remote.format"),
+		Headers:  l.v.GetStringMapString("remote.headers"),
+		Timeout:  l.v.GetDuration("remote.timeout"),
+		Fallback: l.v.GetBool("remote.fallback"),
+	}
+	if rc.URL == "" {
+		return nil
+	}
+	var err error
+	if rc.URL, err = Expand(rc.URL); err != nil {
+		return fmt.Errorf("remote.url: %w", err)
+	}
+	for name, value := range rc.Headers {
+		if rc.Headers[name], err = resolveValue(value); err != nil {
+			return fmt.Errorf("remote.headers.%s: %w", name, err)
+		}
+	}
+
+	data, contentType, err := fetchRemote(rc, l.v.GetBool("offline"))
+	var rv *viper.Viper
+	if err == nil {
+		rv = viper.New()
+		rv.SetConfigType(remoteFormat(rc, contentType))
+		if err = rv.ReadConfig(bytes.NewReader(data)); err != nil {
+			err = fmt.Errorf("parsing: %w", err)
+		}
+	}
+	if err != nil {
+		if !rc.Fallback {
+			return fmt.Errorf("remote config %s: %w", RedactedURL(rc.URL), err)
+		}
+		slog.Warn("remote config not read; using the local config alone", "url", RedactedURL(rc.URL), "error", err)
+		return nil
+	}
+
+	ly := layer{file: RedactedURL(rc.URL), keys: map[string]bool{}}
+	for _, key := range rv.AllKeys() {
+		// Where the remote config is comes from the local one
+		if key == "remote" || strings.HasPrefix(key, "remote.") {
+			continue
+		}
+		l.v.SetDefault(key, rv.Get(key))
+		ly.keys[key] = true
+	}
+	l.remote = &ly
+	slog.Debug("using remote config", "url", ly.file)
+	return nil
+}
+
+// fetchRemote gets the remote config, sending the ETag of the copy cached
+// last so an unchanged config isn't sent again, and returns it with its
+// content type. A copy cached is used when the remote can't be reached
+// and rc.Fallback is on.
+func fetchRemote(rc RemoteConfig, offline bool) ([]byte, string, error) {
+	cachePath, err := remoteCachePath(rc.URL)
+	if err != nil {
+		return nil, "", err
+	}
+	cached := readRemoteCache(cachePath, rc.URL)
+
+	if offline {
+		// The copy cached is expected to be used offline
+		if cached != nil {
+			return cached.Body, cached.ContentType, nil
+		}
+		return nil, "", fmt.Errorf("fetching: %w", model.ErrOffline)
+	}
+	data, contentType, err := getRemote(rc, cached)
+	switch {
+	case err == nil:
+		return data, contentType, nil
+	case cached != nil && rc.Fallback:
+		slog.Warn("remote config not fetched; using the copy from "+cached.Fetched.Local().Format(time.DateTime),
+			"url", RedactedURL(rc.URL), "error", err)
+		return cached.Body, cached.ContentType, nil
+	default:
+		return nil, "", err
+	}
+}
+
+// getRemote requests the remote config, conditionally on cached when
+// there is a copy, and caches what it gets
+func getRemote(rc RemoteConfig, cached *remoteCache) ([]byte, string, error) {
+	ctx := context.Background()
+	if rc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rc.URL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("building request: %w", err)
+	}
+	for name, value := range rc.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Accept", "application/yaml, application/json;q=0.9, */*;q=0.5")
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.Body, cached.ContentType, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading: %w", err)
+	}
+	if len(data) > maxRemoteSize {
+		return nil, "", fmt.Errorf("larger than %s", ByteSize(maxRemoteSize))
+	}
+
+	c := &remoteCache{
+		URL:         rc.URL,
+		ETag:        resp.Header.Get("ETag"),
+		ContentType: resp.Header.Get("Content-Type"),
+		Fetched:     time.Now(),
+		Body:        data,
+	}
+	if err := writeRemoteCache(c); err != nil {
+		slog.Debug("remote config not cached", "error", err)
+	}
+	return c.Body, c.ContentType, nil
+}
+
+// remoteFormat returns the format of the remote config: remote.format,
+// else from the URL's extension or the content type, else yaml
+func remoteFormat(rc RemoteConfig, contentType string) string {
+	if rc.Format != "" {
+		return rc.Format
+	}
+	if u, err := url.Parse(rc.URL); err == nil {
+		switch path.Ext(u.Path) {
+		case ".json":
+			return "json"
+		case ".yaml", ".yml":
+			return "yaml"
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && strings.HasSuffix(mediaType, "json") {
+		return "json"
+	}
+	return "yaml"
+}
+
+// remoteCachePath returns where the copy of the config at rawURL is cached
+func remoteCachePath(rawURL string) (string, error) {
+	dir, err := paths.CacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "remote-config", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// readRemoteCache returns the cached copy of the config at rawURL, or nil
+func readRemoteCache(path, rawURL string) *remoteCache {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var c remoteCache
+	if err := json.Unmarshal(data, &c); err != nil || c.URL != rawURL {
+		return nil
+	}
+	return &c
+}
+
+// writeRemoteCache replaces the cached copy, through a temporary file so
+// readers never see a partial write
+func writeRemoteCache(c *remoteCache) error {
+	path, err := remoteCachePath(c.URL)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RedactedURL hides the password in rawURL, for logs and origins
+func RedactedURL(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return u.Redacted()
+	}
+	return rawURL
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SecretKeys are the settings holding credentials, hidden by Redact and in
+// logs: those whose field is tagged `mapstructure:"name,secret"`
+var SecretKeys = secretKeys()
+
+// redacted is shown in place of a secret
+const redacted = "********"
+
+// secretKeys returns the dotted keys of the fields tagged secret, in
+// declaration order
+func secretKeys() []string {
+	var keys []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			switch {
+			case name == "-" || !f.IsExported():
+			case f.Type.Kind() == reflect.Struct:
+				walk(prefix+name+".", f.Type)
+			case slices.Contains(strings.Split(opts, ","), "secret"):
+				keys = append(keys, prefix+name)
+			}
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return keys
+}
+
+// IsSecret reports whether key is one of SecretKeys
+func IsSecret(key string) bool {
+	return slices.Contains(SecretKeys, strings.ToLower(key))
+}
+
+// Settings returns cfg as nested maps keyed like the config file, with
+// durations and sizes in their config file form ("30s", "100MiB")
+func Settings(cfg *Config) map[string]interface{} {
+	return settingValue(reflect.ValueOf(*cfg)).(map[string]interface{})
+}
+
+func settingValue(v reflect.Value) interface{} {
+	switch v.Type() {
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case byteSizeType:
+		return ByteSize(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		m := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			if name != "-" && f.IsExported() {
+				m[name] = settingValue(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Map:
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = settingValue(iter.Value())
+		}
+		return m
+	case reflect.Slice:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = settingValue(v.Index(i))
+		}
+		return list
+	default:
+		return v.Interface()
+	}
+}
+
+// Redact replaces the non-empty SecretKeys in settings from Settings with
+// asterisks, for showing the config
+func Redact(settings map[string]interface{}) {
+	for _, key := range SecretKeys {
+		section, name := "", key
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			section, name = key[:i], key[i+1:]
+		}
+		m := settings
+		if section != "" {
+			v, _ := Setting(settings, section)
+			if m, _ = v.(map[string]interface{}); m == nil {
+				continue
+			}
+		}
+		if s, ok := m[name].(string); ok && s != "" {
+			m[name] = redacted
+		}
+	}
+}
+
+// LogValue logs the settings with the secrets hidden
+func (c Config) LogValue() slog.Value {
+	settings := Settings(&c)
+	Redact(settings)
+	return groupValue(settings)
+}
+
+// LogValue logs the API settings with the credentials hidden
+func (c APIConfig) LogValue() slog.Value {
+	return sectionLogValue("api", c)
+}
+
+// LogValue logs the database settings with the password hidden
+func (c DBConfig) LogValue() slog.Value {
+	return sectionLogValue("database", c)
+}
+
+// sectionLogValue logs the settings of section, redacted as Redact does
+func sectionLogValue(section string, v interface{}) slog.Value {
+	settings := map[string]interface{}{section: settingValue(reflect.ValueOf(v))}
+	Redact(settings)
+	return groupValue(settings[section].(map[string]interface{}))
+}
+
+// groupValue turns settings into a log group, in key order
+func groupValue(settings map[string]interface{}) slog.Value {
+	keys := slices.Sorted(maps.Keys(settings))
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		if m, ok := settings[key].(map[string]interface{}); ok {
+			attrs[i] = slog.Attr{Key: key, Value: groupValue(m)}
+		} else {
+			attrs[i] = slog.Any(key, settings[key])
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Setting returns the value of key in settings from Settings: a single
+// value, or a section or list. ok is false when there's no such key.
+func Setting(settings map[string]interface{}, key string) (value interface{}, ok bool) {
+	value = settings
+	for _, part := range strings.Split(strings.ToLower(key), ".") {
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		if value, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
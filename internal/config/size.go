@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
+)
+
+// ByteSize is a size in bytes that config files and flags can give as
+// "4096", "100MB", or "1.5GiB". KB, MB, GB, and TB are powers of 1000;
+// KiB, MiB, GiB, and TiB powers of 1024.
+type ByteSize int64
+
+// Byte size units
+const (
+	Byte ByteSize = 1
+	KB            = 1000 * Byte
+	MB            = 1000 * KB
+	GB            = 1000 * MB
+	TB            = 1000 * GB
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+)
+
+var sizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+}
+
+// ParseByteSize parses a size such as "512", "100MB", or "1.5 GiB".
+// Units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("invalid size %q: must not be negative", s)
+	}
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	mult, ok := sizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q; use %s", s, strings.TrimSpace(s[i:]), sizeSyntax)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || number == "" {
+		return 0, fmt.Errorf("invalid size %q: use %s", s, sizeSyntax)
+	}
+	bytes := n * float64(mult)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+	return ByteSize(bytes), nil
+}
+
+// String formats the size with the largest binary unit that divides it
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// Set implements pflag.Value, so ByteSize can be used with Flags().Var
+func (b *ByteSize) Set(s string) error {
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// Type implements pflag.Value
+func (b *ByteSize) Type() string {
+	return "size"
+}
+
+var _ pflag.Value = (*ByteSize)(nil)
+
+var (
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// durationSyntax is the syntax durations are given in, for errors
+const durationSyntax = "a number with a unit, e.g. 500ms, 30s, 5m, or 1h30m (units: ns, us, ms, s, m, h)"
+
+// sizeSyntax is the syntax sizes are given in, for errors
+const sizeSyntax = "a number with an optional unit, e.g. 512, 100MB, or 2GiB (units: B, KB, MB, GB, TB, KiB, MiB, GiB, TiB)"
+
+// ParseDuration parses a duration such as "500ms", "30s", or "1h30m",
+// allowing spaces between its parts ("1h 30m"), with an error showing the
+// syntax. A bare number is an error, as its unit would be a guess, except
+// for 0.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	d, err := time.ParseDuration(strings.Join(strings.Fields(s), ""))
+	if err == nil {
+		return d, nil
+	}
+	if _, numErr := strconv.ParseFloat(s, 64); numErr == nil {
+		return 0, fmt.Errorf("invalid duration %q: missing a unit, e.g. %ss or %sms", s, s, s)
+	}
+	return 0, fmt.Errorf("invalid duration %q: use %s", s, durationSyntax)
+}
+
+// stringToByteSizeHook decodes size strings into ByteSize fields
+func stringToByteSizeHook() mapstructure.DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != byteSizeType {
+			return data, nil
+		}
+		return ParseByteSize(data.(string))
+	}
+}
+
+// stringToDurationHook decodes duration strings such as "30s" or "1h30m"
+// into time.Duration fields (see ParseDuration). Numbers other than 0 are
+// refused, where mapstructure would take them as nanoseconds.
+func stringToDurationHook() mapstructure.DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != durationType || from == durationType {
+			return data, nil
+		}
+		switch from.Kind() {
+		case reflect.String:
+			return ParseDuration(data.(string))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			if reflect.ValueOf(data).IsZero() {
+				return time.Duration(0), nil
+			}
+			return ParseDuration(fmt.Sprint(data))
+		}
+		return data, nil
+	}
+}
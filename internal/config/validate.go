@@ -0,0 +1,316 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is something wrong with a config file
+type Problem struct {
+	Key     string // Dotted key, e.g. "server.port"; empty for the whole file
+	Line    int    // Where the key is set in the file; 0 when it isn't
+	Column  int
+	Warning bool // The file still loads, e.g. an unknown or deprecated key
+	Message string
+}
+
+// CheckFile validates the config file at path on its own, over the
+// defaults, and returns every problem found rather than only the first:
+// syntax errors, unknown and deprecated keys, values that don't parse or
+// reference unset variables, and settings that fail validation. The error
+// is for a file that can't be read.
+func CheckFile(path string) ([]Problem, error) {
+	// OpenDocument treats a missing file as empty, and its other errors
+	// are then about the contents
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	doc, err := OpenDocument(path)
+	if err != nil {
+		return []Problem{syntaxProblem(err)}, nil
+	}
+
+	c := &fileChecker{}
+	c.walk("", doc.root, reflect.TypeOf(Config{}))
+
+	// The rest of the settings are validated once decoded, leaving out the
+	// values the walk found don't decode
+	for key := range c.failed {
+		doc.Unset(key)
+	}
+	data, err := doc.encode()
+	if err != nil {
+		return nil, err
+	}
+	base, err := loadData(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	cfg, err := base.Load()
+	if err != nil {
+		// Decoding failed in a way the walk didn't anticipate
+		c.problems = append(c.problems, Problem{Message: err.Error()})
+		return c.sorted(), nil
+	}
+	for _, fe := range cfg.ValidateAll() {
+		line, column := doc.Position(fe.Key)
+		c.problems = append(c.problems, Problem{Key: fe.Key, Line: line, Column: column, Message: fe.Message})
+	}
+
+	if name := strings.ToLower(cfg.Profile); name != "" && !slices.Contains(base.profiles(), name) {
+		line, column := doc.Position("profile")
+		c.problems = append(c.problems, Problem{Key: "profile", Line: line, Column: column,
+			Message: fmt.Sprintf("profile %q is not defined in profiles", name)})
+	}
+
+	// Each profile is validated merged over the base settings; problems the
+	// base has too were reported above
+	for _, name := range base.profiles() {
+		l, err := loadData(data, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		cfg, err := l.Load()
+		if err != nil {
+			c.problems = append(c.problems, Problem{Key: profilesKey + "." + name, Message: err.Error()})
+			continue
+		}
+		for _, fe := range cfg.ValidateAll() {
+			key := profilesKey + "." + name + "." + fe.Key
+			if line, column := doc.Position(key); line > 0 {
+				c.problems = append(c.problems, Problem{Key: key, Line: line, Column: column, Message: fe.Message})
+			}
+		}
+	}
+	return c.sorted(), nil
+}
+
+// loadData returns a loader holding the config file data and, when profile
+// is set, that profile merged over it
+func loadData(data []byte, profile string) (*Loader, error) {
+	l := NewLoader()
+	l.v.SetConfigType("yaml")
+	if err := l.v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	l.read = true
+	l.applyDeprecations()
+	if profile != "" {
+		l.v.Set("profile", profile)
+		l.applyProfile()
+	}
+	return l, nil
+}
+
+// sorted returns the problems in file order, those without a position last
+func (c *fileChecker) sorted() []Problem {
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		a, b := c.problems[i], c.problems[j]
+		if (a.Line == 0) != (b.Line == 0) {
+			return b.Line == 0
+		}
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return c.problems
+}
+
+// yamlLine matches the position in a YAML syntax error
+var yamlLine = regexp.MustCompile(`line (\d+): `)
+
+// syntaxProblem returns the problem for a file OpenDocument can't parse
+func syntaxProblem(err error) Problem {
+	msg := err.Error()
+	p := Problem{Message: msg}
+	if m := yamlLine.FindStringSubmatchIndex(msg); m != nil {
+		p.Line, _ = strconv.Atoi(msg[m[2]:m[3]])
+		p.Message = msg[m[1]:]
+	}
+	return p
+}
+
+type fileChecker struct {
+	problems []Problem
+	failed   map[string]bool // Keys with an error, not validated again
+}
+
+func (c *fileChecker) add(key string, node *yaml.Node, warning bool, format string, args ...interface{}) {
+	c.problems = append(c.problems, Problem{
+		Key: key, Line: node.Line, Column: node.Column, Warning: warning,
+		Message: fmt.Sprintf(format, args...),
+	})
+	if !warning {
+		if c.failed == nil {
+			c.failed = map[string]bool{}
+		}
+		c.failed[key] = true
+	}
+}
+
+// walk checks the keys of mapping m, which decodes into t: a struct, or a
+// map whose keys are names such as output.columns
+func (c *fileChecker) walk(prefix string, m *yaml.Node, t reflect.Type) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		keyNode, value := m.Content[i], m.Content[i+1]
+		key := prefix + strings.ToLower(keyNode.Value)
+
+		if key == profilesKey {
+			c.walkProfiles(keyNode, value)
+			continue
+		}
+		if d, ok := deprecation(strings.TrimPrefix(key, profilePrefix(key))); ok {
+			c.add(key, keyNode, true, "%s", DeprecationNotice{Deprecation: d})
+			continue
+		}
+
+		var field reflect.Type
+		if t.Kind() == reflect.Map {
+			field = t.Elem()
+		} else if f, ok := fieldForKey(t, keyNode.Value); ok {
+			field = f
+		} else {
+			if suggestion := closestKey(prefix, keyNode.Value, t); suggestion != "" {
+				c.add(key, keyNode, true, "unknown key; did you mean %s?", suggestion)
+			} else {
+				c.add(key, keyNode, true, "unknown key")
+			}
+			continue
+		}
+		c.check(key, keyNode, value, field)
+	}
+}
+
+// walkProfiles checks each profile in the profiles section as a config
+// of its own
+func (c *fileChecker) walkProfiles(keyNode, value *yaml.Node) {
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+		return
+	}
+	if value.Kind != yaml.MappingNode {
+		c.add(profilesKey, keyNode, false, "expected a section of profiles, got %s", describe(value))
+		return
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		name, profile := value.Content[i], value.Content[i+1]
+		key := profilesKey + "." + strings.ToLower(name.Value)
+		switch {
+		case profile.Kind == yaml.ScalarNode && profile.Tag == "!!null":
+		case profile.Kind != yaml.MappingNode:
+			c.add(key, name, false, "expected a section of settings, got %s", describe(profile))
+		default:
+			c.walk(key+".", profile, reflect.TypeOf(Config{}))
+		}
+	}
+}
+
+// profilePrefix returns the "profiles.name." that key starts with, if any
+func profilePrefix(key string) string {
+	if rest, ok := strings.CutPrefix(key, profilesKey+"."); ok {
+		if name, _, ok := strings.Cut(rest, "."); ok {
+			return profilesKey + "." + name + "."
+		}
+	}
+	return ""
+}
+
+// check checks the value of key against the type it decodes into
+func (c *fileChecker) check(key string, keyNode, value *yaml.Node, t reflect.Type) {
+	// An empty value ("key:") decodes to the zero value
+	if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+		return
+	}
+
+	isSection := t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+	switch {
+	case isSection && value.Kind != yaml.MappingNode:
+		c.add(key, keyNode, false, "expected a section of keys, got %s", describe(value))
+	case isSection:
+		c.walk(key+".", value, t)
+	case t.Kind() == reflect.Slice:
+		// A list, or a comma-separated string
+		if value.Kind != yaml.SequenceNode && value.Kind != yaml.ScalarNode {
+			c.add(key, keyNode, false, "expected a list, got %s", describe(value))
+		}
+	case value.Kind != yaml.ScalarNode:
+		c.add(key, keyNode, false, "expected a single value, got %s", describe(value))
+	default:
+		expanded, err := resolveValue(value.Value)
+		if err == nil {
+			_, err = valueNode(t, expanded)
+		}
+		if err != nil {
+			c.add(key, value, false, "%s", err)
+		}
+	}
+}
+
+// describe names the kind of a YAML value for messages
+func describe(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a section"
+	case yaml.SequenceNode:
+		return "a list"
+	default:
+		return fmt.Sprintf("%q", n.Value)
+	}
+}
+
+// deprecation returns the declared deprecation of key
+func deprecation(key string) (Deprecation, bool) {
+	for _, d := range Deprecations {
+		if strings.EqualFold(d.Key, key) {
+			return d, true
+		}
+	}
+	return Deprecation{}, false
+}
+
+// closestKey suggests the key of struct t, under prefix, that name is
+// most likely a typo of, or "" when none is close
+func closestKey(prefix, name string, t reflect.Type) string {
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	name = strings.ToLower(name)
+	best, bestDist := "", 3 // Up to two edits
+	for i := 0; i < t.NumField(); i++ {
+		field, _, _ := strings.Cut(t.Field(i).Tag.Get("mapstructure"), ",")
+		if field == "" || field == "-" {
+			continue
+		}
+		if d := editDistance(name, field); d < bestDist {
+			best, bestDist = prefix+field, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+)
+
+// settleDelay is how long Watch waits for writes to a changed file to stop
+// before reloading it
+const settleDelay = 100 * time.Millisecond
+
+// Change is a reload of the config file seen by Watch
+type Change struct {
+	Config  *Config  // The new configuration; nil when Err is set
+	Changed []string // Keys whose values changed, e.g. "api.timeout", sorted
+	Err     error    // The file no longer loads or validates; the previous configuration stays in effect
+}
+
+// Watch reloads the config files whenever one of them changes on disk, and
+// the remote config every remote.poll_interval, and sends a Change for
+// each reload that changes a setting or fails to load or validate. Environment variables, flags, and the selected profile apply
+// as they did before. The channel is closed once ctx is done.
+//
+//	changes, err := loader.Watch(ctx)
+//	for c := range changes {
+//		if c.Err != nil { slog.Warn("config not reloaded", "error", c.Err); continue }
+//		apply(c.Config)
+//	}
+func (l *Loader) Watch(ctx context.Context) (<-chan Change, error) {
+	current, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+	files := l.ConfigFiles()
+	poll := current.Remote.URL != "" && current.Remote.PollInterval > 0
+	if len(files) == 0 && !poll {
+		return nil, errors.New("no config file to watch")
+	}
+
+	var (
+		mu      sync.Mutex // Held while reloading and sending, so the channel isn't closed under a send
+		changes = make(chan Change)
+
+		timerMu sync.Mutex
+		timer   *time.Timer
+	)
+	reload := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		c := l.reload(current)
+		if c.Err == nil && len(c.Changed) == 0 {
+			return
+		}
+		if c.Config != nil {
+			current = c.Config
+			events.Record(ctx, events.Info, "config", "config reloaded", "changed", strings.Join(c.Changed, ","))
+		} else {
+			events.Record(ctx, events.Error, "config", "config not reloaded", "error", c.Err)
+		}
+		select {
+		case changes <- c:
+		case <-ctx.Done():
+		}
+	}
+	// Saving a file often takes several writes (truncate, then write), so
+	// it's reloaded once they settle rather than half written
+	changed := func(fsnotify.Event) {
+		timerMu.Lock()
+		defer timerMu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(settleDelay, reload)
+	}
+
+	// The watching vipers only report changes, handling editors that
+	// replace the file and symlinked files such as Kubernetes ConfigMaps;
+	// the loader's own viper is reloaded under its lock
+	for _, file := range files {
+		w := viper.New()
+		w.SetConfigFile(file)
+		w.OnConfigChange(changed)
+		w.WatchConfig()
+	}
+
+	// A remote config that hasn't changed costs a 304 with its ETag
+	if poll {
+		interval := current.Remote.PollInterval
+		go func() {
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					reload()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		close(changes)
+	}()
+	return changes, nil
+}
+
+// reload rereads the config files and returns how their settings changed
+// from previous
+func (l *Loader) reload(previous *Config) Change {
+	l.mu.Lock()
+	err := l.readFiles()
+	if err == nil {
+		err = l.readRemote()
+	}
+	if err != nil {
+		l.mu.Unlock()
+		return Change{Err: fmt.Errorf("reading config: %w", err)}
+	}
+	l.applyDeprecations()
+	l.profileErr = nil
+	l.applyProfile()
+	l.mu.Unlock()
+
+	cfg, err := l.Load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		slog.Debug("config file changed but was not reloaded", "error", err)
+		return Change{Err: err}
+	}
+	return Change{Config: cfg, Changed: changedKeys("", Settings(previous), Settings(cfg))}
+}
+
+// changedKeys returns the keys, under prefix, whose values differ between
+// the settings a and b
+func changedKeys(prefix string, a, b map[string]interface{}) []string {
+	var keys []string
+	for name, av := range a {
+		bv := b[name]
+		am, aSection := av.(map[string]interface{})
+		bm, bSection := bv.(map[string]interface{})
+		switch {
+		case aSection && bSection:
+			keys = append(keys, changedKeys(prefix+name+".", am, bm)...)
+		case !reflect.DeepEqual(av, bv):
+			keys = append(keys, prefix+name)
+		}
+	}
+	// Map sections such as api.headers gain keys too
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			keys = append(keys, prefix+name)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/schedule"
+)
+
+// readyPoll is how often WaitReady checks a starting daemon
+const readyPoll = 50 * time.Millisecond
+
+// Run runs args on the daemon with this process's working directory and
+// environment, writing the command's output to stdout and stderr. It
+// returns ErrNotRunning or ErrVersionMismatch before any output when the
+// command must run in-process instead, and a *RemoteError when the command
+// fails.
+func Run(ctx context.Context, socket, version string, args []string, stdout, stderr io.Writer) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+
+	conn, err := dial(ctx, socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Closing the connection tells the daemon to cancel the command
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	err = json.NewEncoder(conn).Encode(request{
+		Op:      opRun,
+		Version: version,
+		Args:    args,
+		Dir:     dir,
+		Env:     os.Environ(),
+	})
+	if err != nil {
+		return fmt.Errorf("sending to daemon: %w", err)
+	}
+
+	dec := json.NewDecoder(conn)
+	for {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return fmt.Errorf("reading from daemon: %w", err)
+		}
+		if len(resp.Stdout) > 0 {
+			if _, err := stdout.Write(resp.Stdout); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+		if len(resp.Stderr) > 0 {
+			_, _ = stderr.Write(resp.Stderr)
+		}
+		if !resp.Done {
+			continue
+		}
+
+		switch {
+		case resp.VersionMismatch:
+			return ErrVersionMismatch
+		case resp.Error != "":
+			return &RemoteError{Message: resp.Error, Code: resp.ExitCode}
+		default:
+			return nil
+		}
+	}
+}
+
+// GetStatus asks the daemon for its status
+func GetStatus(ctx context.Context, socket string) (*Status, error) {
+	resp, err := call(ctx, socket, request{Op: opStatus})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// Jobs asks the daemon for the state of the jobs it runs on a schedule
+func Jobs(ctx context.Context, socket string) ([]schedule.State, error) {
+	resp, err := call(ctx, socket, request{Op: opJobs})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Jobs, nil
+}
+
+// Stop asks the daemon to exit once running commands finish
+func Stop(ctx context.Context, socket string) error {
+	_, err := call(ctx, socket, request{Op: opStop})
+	return err
+}
+
+// WaitReady waits up to timeout for a daemon to answer on the socket
+func WaitReady(ctx context.Context, socket string, timeout time.Duration) (*Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		status, err := GetStatus(ctx, socket)
+		if err == nil {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for daemon: %w", err)
+		case <-time.After(readyPoll):
+		}
+	}
+}
+
+// Spawn starts exe with args in the background, detached from the
+// terminal, appending its output to logFile, and returns its PID
+func Spawn(exe string, args []string, logFile string) (int, error) {
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("opening daemon log: %w", err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	cmd.SysProcAttr = detached()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("starting daemon: %w", err)
+	}
+	pid := cmd.Process.Pid
+	if err := cmd.Process.Release(); err != nil {
+		return 0, fmt.Errorf("releasing daemon process: %w", err)
+	}
+	return pid, nil
+}
+
+// call sends a request that has a single response
+func call(ctx context.Context, socket string, req request) (*response, error) {
+	conn, err := dial(ctx, socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("sending to daemon: %w", err)
+	}
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("reading from daemon: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+func dial(ctx context.Context, socket string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,110 @@
+// Package daemon keeps a termplate process running behind a unix socket so
+// later invocations skip startup: the client sends its command line,
+// working directory, and environment, and streams back the output and exit
+// code.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/paths"
+	"github.com/blacksilver/termplate-go/internal/schedule"
+)
+
+// LocalAnnotation marks a cobra command, and the commands under it, as one
+// that must run in the invoking process, e.g. because it prompts or
+// manages the daemon itself
+const LocalAnnotation = "termplate.local"
+
+// EnvDisable turns off dispatching to a running daemon when set to any
+// non-empty value
+const EnvDisable = "TERMPLATE_NO_DAEMON"
+
+var (
+	// ErrNotRunning is returned by the client when no daemon answers
+	ErrNotRunning = errors.New("daemon is not running")
+
+	// ErrRunning is returned by Serve when another daemon owns the socket
+	ErrRunning = errors.New("daemon is already running")
+
+	// ErrVersionMismatch is returned by the client when the daemon runs a
+	// different build, so commands must run in-process
+	ErrVersionMismatch = errors.New("daemon runs a different version")
+)
+
+// Status describes a running daemon
+type Status struct {
+	PID      int       `json:"pid" table:"PID"`
+	Version  string    `json:"version" table:"Version"`
+	Socket   string    `json:"socket" table:"Socket"`
+	Started  time.Time `json:"started" table:"Started"`
+	Requests int       `json:"requests" table:"Requests"`
+}
+
+// RemoteError is a command failure reported by the daemon
+type RemoteError struct {
+	Message string
+	Code    int
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+func (e *RemoteError) ExitCode() int {
+	return e.Code
+}
+
+// SocketPath returns the daemon socket ($XDG_STATE_HOME/termplate/daemon.sock)
+func SocketPath() (string, error) {
+	dir, err := paths.Ensure(paths.StateDir())
+	if err != nil {
+		return "", fmt.Errorf("finding daemon socket: %w", err)
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// LogPath returns the log file of a background daemon
+// ($XDG_STATE_HOME/termplate/daemon.log)
+func LogPath() (string, error) {
+	dir, err := paths.Ensure(paths.StateDir())
+	if err != nil {
+		return "", fmt.Errorf("finding daemon log: %w", err)
+	}
+	return filepath.Join(dir, "daemon.log"), nil
+}
+
+// Ops of a request
+const (
+	opRun    = "run"
+	opStatus = "status"
+	opStop   = "stop"
+	opJobs   = "jobs"
+)
+
+// request is the one message a client sends per connection
+type request struct {
+	Op      string   `json:"op"`
+	Version string   `json:"version"`
+	Args    []string `json:"args,omitempty"`
+	Dir     string   `json:"dir,omitempty"`
+	Env     []string `json:"env,omitempty"`
+}
+
+// response is one message from the daemon: output chunks while a command
+// runs, then a final message with Done set
+type response struct {
+	Stdout   []byte  `json:"stdout,omitempty"`
+	Stderr   []byte  `json:"stderr,omitempty"`
+	Done     bool    `json:"done,omitempty"`
+	ExitCode int     `json:"exit_code,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Status   *Status `json:"status,omitempty"`
+
+	Jobs []schedule.State `json:"jobs,omitempty"`
+
+	VersionMismatch bool `json:"version_mismatch,omitempty"`
+}
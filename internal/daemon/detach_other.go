@@ -0,0 +1,10 @@
+//go:build !unix
+
+package daemon
+
+import "syscall"
+
+// detached returns nil; the daemon stays attached to its parent's console
+func detached() *syscall.SysProcAttr {
+	return nil
+}
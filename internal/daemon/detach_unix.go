@@ -0,0 +1,11 @@
+//go:build unix
+
+package daemon
+
+import "syscall"
+
+// detached starts the daemon in its own session, so it outlives the
+// terminal that started it
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
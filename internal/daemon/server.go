@@ -0,0 +1,327 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/schedule"
+)
+
+// RunFunc runs a command line in this process
+type RunFunc func(ctx context.Context, args []string) error
+
+// Server runs commands for clients connecting to the socket, and the jobs
+// of its scheduler. Commands share process state (flags, stdio, working
+// directory, environment), so they run one at a time.
+type Server struct {
+	socket    string
+	version   string
+	run       RunFunc
+	scheduler *schedule.Scheduler
+
+	mu       sync.Mutex // Held while a command runs
+	started  time.Time
+	requests atomic.Int64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewServer creates a server running commands with run. Clients of a
+// different version are turned away.
+func NewServer(socket, version string, run RunFunc) *Server {
+	return &Server{
+		socket:  socket,
+		version: version,
+		run:     run,
+		stop:    make(chan struct{}),
+	}
+}
+
+// WithScheduler runs sch's jobs while the server serves. Its RunFunc
+// should be RunExclusive, so jobs don't run alongside client commands.
+func (s *Server) WithScheduler(sch *schedule.Scheduler) *Server {
+	s.scheduler = sch
+	return s
+}
+
+// RunExclusive runs a command line in this process between client
+// commands, with the daemon's own working directory, environment, and
+// output (its log), for commands the daemon starts itself
+func (s *Server) RunExclusive(ctx context.Context, args []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Commands reinitialize the default logger
+	defer slog.SetDefault(slog.Default())
+	return s.run(ctx, args)
+}
+
+// Serve accepts clients until ctx is done or a client stops the daemon,
+// then waits for running commands to finish. Scheduled jobs still running
+// are canceled.
+func (s *Server) Serve(ctx context.Context) error {
+	if err := s.claimSocket(); err != nil {
+		return err
+	}
+	ln, err := net.Listen("unix", s.socket)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.socket, err)
+	}
+	s.started = time.Now()
+	slog.Info("daemon listening", "socket", s.socket, "pid", os.Getpid())
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.stop:
+		}
+		ln.Close()
+	}()
+
+	if s.scheduler != nil {
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if err := s.scheduler.Run(ctx); err != nil {
+				slog.Error("scheduler stopped", "error", err)
+			}
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			slog.Info("daemon stopped")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("accepting connection: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+// Stop makes Serve return once running commands finish
+func (s *Server) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// claimSocket removes a socket left behind by a daemon that died, and
+// fails when a live daemon answers on it
+func (s *Server) claimSocket() error {
+	if _, err := os.Stat(s.socket); err != nil {
+		return nil
+	}
+	if conn, err := net.DialTimeout("unix", s.socket, time.Second); err == nil {
+		conn.Close()
+		return ErrRunning
+	}
+	if err := os.Remove(s.socket); err != nil {
+		return fmt.Errorf("removing stale socket: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		slog.Debug("reading daemon request", "error", err)
+		return
+	}
+	out := &sender{enc: json.NewEncoder(conn)}
+
+	switch req.Op {
+	case opStatus:
+		out.send(response{Done: true, Status: &Status{
+			PID:      os.Getpid(),
+			Version:  s.version,
+			Socket:   s.socket,
+			Started:  s.started,
+			Requests: int(s.requests.Load()),
+		}})
+	case opStop:
+		out.send(response{Done: true})
+		s.Stop()
+	case opJobs:
+		var jobs []schedule.State
+		if s.scheduler != nil {
+			var err error
+			if jobs, err = s.scheduler.States(); err != nil {
+				out.send(response{Done: true, Error: err.Error(), ExitCode: model.ExitError})
+				return
+			}
+		}
+		out.send(response{Done: true, Jobs: jobs})
+	case opRun:
+		if req.Version != s.version {
+			out.send(response{Done: true, VersionMismatch: true})
+			return
+		}
+		s.runCommand(ctx, conn, out, req)
+	default:
+		out.send(response{Done: true, Error: fmt.Sprintf("unknown daemon request %q", req.Op), ExitCode: model.ExitError})
+	}
+}
+
+// runCommand runs the client's command line with its working directory,
+// environment, and output
+func (s *Server) runCommand(ctx context.Context, conn net.Conn, out *sender, req request) {
+	// The client closes the connection when it's interrupted
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		cancel(model.ErrInterrupted)
+	}()
+
+	s.requests.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slog.Debug("running command", "args", strings.Join(req.Args, " "))
+
+	restore, err := s.enter(req, out)
+	if err != nil {
+		out.send(response{Done: true, Error: err.Error(), ExitCode: model.ExitError})
+		return
+	}
+	err = s.run(ctx, req.Args)
+	restore()
+
+	resp := response{Done: true}
+	if err != nil {
+		resp.Error = err.Error()
+		resp.ExitCode = model.ExitCode(err)
+	}
+	out.send(resp)
+}
+
+// enter switches the process to the client's working directory and
+// environment and redirects stdio to it, returning a function that
+// switches back. Stdin is empty, so commands see a non-interactive session.
+func (s *Server) enter(req request, out *sender) (func(), error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %w", err)
+	}
+	if req.Dir != "" {
+		if err := os.Chdir(req.Dir); err != nil {
+			return nil, fmt.Errorf("changing to %s: %w", req.Dir, err)
+		}
+	}
+
+	var pipes [6]*os.File
+	for i := 0; i < len(pipes); i += 2 {
+		if pipes[i], pipes[i+1], err = os.Pipe(); err != nil {
+			for _, f := range pipes[:i] {
+				f.Close()
+			}
+			_ = os.Chdir(dir)
+			return nil, fmt.Errorf("creating pipe: %w", err)
+		}
+	}
+	stdoutR, stdoutW := pipes[0], pipes[1]
+	stderrR, stderrW := pipes[2], pipes[3]
+	stdinR, stdinW := pipes[4], pipes[5]
+	stdinW.Close()
+
+	env := os.Environ()
+	setEnv(req.Env)
+
+	stdout, stderr, stdin, logger := os.Stdout, os.Stderr, os.Stdin, slog.Default()
+	os.Stdout, os.Stderr, os.Stdin = stdoutW, stderrW, stdinR
+
+	var copying sync.WaitGroup
+	copying.Add(2)
+	go func() {
+		defer copying.Done()
+		out.copy(stdoutR, false)
+	}()
+	go func() {
+		defer copying.Done()
+		out.copy(stderrR, true)
+	}()
+
+	return func() {
+		// Commands reinitialize the default logger on the redirected stderr
+		os.Stdout, os.Stderr, os.Stdin = stdout, stderr, stdin
+		slog.SetDefault(logger)
+
+		stdoutW.Close()
+		stderrW.Close()
+		copying.Wait()
+		stdoutR.Close()
+		stderrR.Close()
+		stdinR.Close()
+
+		setEnv(env)
+		if err := os.Chdir(dir); err != nil {
+			slog.Error("restoring working directory", "error", err)
+		}
+	}, nil
+}
+
+// setEnv replaces the environment with env
+func setEnv(env []string) {
+	os.Clearenv()
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+// sender writes responses to a connection from several goroutines
+type sender struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *sender) send(resp response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A failed write means the client went away; the command is cancelled
+	_ = s.enc.Encode(resp)
+}
+
+// copy sends what r yields as output chunks until it is closed
+func (s *sender) copy(r io.Reader, stderr bool) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			if stderr {
+				s.send(response{Stderr: chunk})
+			} else {
+				s.send(response{Stdout: chunk})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
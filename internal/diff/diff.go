@@ -0,0 +1,212 @@
+// Package diff computes line-based differences and renders unified diffs.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a line edit
+type Op int
+
+const (
+	Equal Op = iota
+	Insert
+	Delete
+)
+
+// Edit is a single line of a diff
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// maxCells bounds the LCS table; larger inputs fall back to a full replace
+const maxCells = 4_000_000
+
+// Lines returns the edits that turn a into b
+func Lines(a, b []string) []Edit {
+	// Trim the common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(a)+len(b))
+	for _, l := range a[:prefix] {
+		edits = append(edits, Edit{Equal, l})
+	}
+	edits = append(edits, lcs(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Equal, l})
+	}
+	return edits
+}
+
+func lcs(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	if n*m > maxCells {
+		edits := make([]Edit, 0, n+m)
+		for _, l := range a {
+			edits = append(edits, Edit{Delete, l})
+		}
+		for _, l := range b {
+			edits = append(edits, Edit{Insert, l})
+		}
+		return edits
+	}
+
+	// table[i][j] is the LCS length of a[i:] and b[j:]
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]Edit, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Equal, a[i]})
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			edits = append(edits, Edit{Delete, a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		edits = append(edits, Edit{Delete, a[i]})
+	}
+	for ; j < m; j++ {
+		edits = append(edits, Edit{Insert, b[j]})
+	}
+	return edits
+}
+
+// SplitLines splits s into lines without their terminators
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Unified renders a unified diff between a and b with the given number of
+// context lines. It returns an empty string when a and b are equal.
+func Unified(oldName, newName, a, b string, context int) string {
+	edits := Lines(SplitLines(a), SplitLines(b))
+
+	var out strings.Builder
+	for _, h := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", span(h.oldStart, h.oldLen), span(h.newStart, h.newLen))
+		for _, e := range h.edits {
+			switch e.Op {
+			case Insert:
+				out.WriteString("+" + e.Line + "\n")
+			case Delete:
+				out.WriteString("-" + e.Line + "\n")
+			default:
+				out.WriteString(" " + e.Line + "\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+type hunk struct {
+	oldStart, oldLen int
+	newStart, newLen int
+	edits            []Edit
+}
+
+// hunks groups edits into hunks separated by more than 2*context equal lines
+func hunks(edits []Edit, context int) []hunk {
+	var result []hunk
+	var cur *hunk
+	oldLine, newLine := 1, 1
+	lastChange := -1
+
+	closeHunk := func() {
+		end := min(len(edits), lastChange+1+context)
+		cur.addEqual(edits[lastChange+1 : end])
+		result = append(result, *cur)
+		cur = nil
+	}
+
+	for i, e := range edits {
+		if e.Op != Equal {
+			if cur != nil && i-lastChange-1 > 2*context {
+				closeHunk()
+			}
+			if cur == nil {
+				start := max(0, i-context)
+				cur = &hunk{
+					oldStart: oldLine - (i - start),
+					newStart: newLine - (i - start),
+				}
+				cur.addEqual(edits[start:i])
+			} else {
+				cur.addEqual(edits[lastChange+1 : i])
+			}
+
+			cur.edits = append(cur.edits, e)
+			if e.Op == Delete {
+				cur.oldLen++
+			} else {
+				cur.newLen++
+			}
+			lastChange = i
+		}
+
+		switch e.Op {
+		case Equal:
+			oldLine++
+			newLine++
+		case Delete:
+			oldLine++
+		case Insert:
+			newLine++
+		}
+	}
+
+	if cur != nil {
+		closeHunk()
+	}
+	return result
+}
+
+func (h *hunk) addEqual(edits []Edit) {
+	h.edits = append(h.edits, edits...)
+	h.oldLen += len(edits)
+	h.newLen += len(edits)
+}
+
+func span(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, length)
+}
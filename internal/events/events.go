@@ -0,0 +1,307 @@
+// Package events records significant things that happen while termplate
+// runs, such as retries, config reloads, and the results of bulk
+// operations and outbox flushes, for operators to review with 'termplate
+// events'. Unlike logs, events are few and kept across runs: in a ring
+// buffer in memory and, unless Configure turns persistence off, in a file
+// in the state directory shared by every invocation.
+//
+//	events.Record(ctx, events.Warning, "breaker", "circuit opened", "host", host)
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// DefaultSize is the number of events kept until Configure sets another
+const DefaultSize = 1000
+
+// lockName serializes writes to the events file
+const lockName = "events"
+
+// lockWait is how long Record waits for another invocation's write.
+// Recording is best effort, so a busy file is skipped rather than waited on.
+const lockWait = time.Second
+
+// followInterval is how often Follow checks the events file
+const followInterval = 500 * time.Millisecond
+
+// Severity is how much an event matters to an operator
+type Severity string
+
+const (
+	Info    Severity = "info"
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// severities are in increasing order
+var severities = []Severity{Info, Warning, Error}
+
+// ParseSeverity parses a severity name, accepting warn for warning
+func ParseSeverity(s string) (Severity, error) {
+	s = strings.ToLower(s)
+	if s == "warn" {
+		return Warning, nil
+	}
+	if i := slices.Index(severities, Severity(s)); i >= 0 {
+		return severities[i], nil
+	}
+	return "", fmt.Errorf("unknown severity %q: expected info, warning, or error", s)
+}
+
+// AtLeast reports whether s is as severe as min or more
+func (s Severity) AtLeast(min Severity) bool {
+	return slices.Index(severities, s) >= slices.Index(severities, min)
+}
+
+// Event is something that happened
+type Event struct {
+	ID       int64             `json:"id"` // Increases with each event recorded
+	Time     time.Time         `json:"time"`
+	Severity Severity          `json:"severity"`
+	Source   string            `json:"source"` // What recorded it, e.g. retry or config
+	Message  string            `json:"message"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+}
+
+// Options configures where events are kept
+type Options struct {
+	Size    int  // Events kept, in memory and in the file; DefaultSize when zero
+	Persist bool // Keep events in the state directory, not just in memory
+}
+
+var (
+	mu     sync.Mutex
+	opts   = Options{Size: DefaultSize}
+	ring   []Event // The events recorded by this process, oldest first
+	lastID int64
+)
+
+// Configure sets how many events are kept and whether they're persisted.
+// Events already in memory are kept, up to the new size.
+func Configure(o Options) {
+	if o.Size <= 0 {
+		o.Size = DefaultSize
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	opts = o
+	if len(ring) > o.Size {
+		ring = slices.Clone(ring[len(ring)-o.Size:])
+	}
+}
+
+// Record records an event. attrs are key-value pairs, as for slog. Events
+// are recorded even once ctx is done; failing to persist one is logged,
+// not returned, so recording never gets in the way of the work.
+func Record(ctx context.Context, severity Severity, source, message string, attrs ...any) {
+	e := Event{
+		Time:     time.Now().UTC().Truncate(time.Millisecond),
+		Severity: severity,
+		Source:   source,
+		Message:  message,
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if e.Attrs == nil {
+			e.Attrs = map[string]string{}
+		}
+		e.Attrs[fmt.Sprint(attrs[i])] = fmt.Sprint(attrs[i+1])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if opts.Persist {
+		if err := write(context.WithoutCancel(ctx), &e, opts.Size); err != nil {
+			slog.DebugContext(ctx, "event not persisted", "source", source, "error", err)
+		}
+	}
+	if e.ID == 0 {
+		e.ID = lastID + 1
+	}
+	lastID = e.ID
+	ring = append(ring, e)
+	if len(ring) > opts.Size {
+		ring = slices.Delete(ring, 0, len(ring)-opts.Size)
+	}
+}
+
+// List returns the events kept, oldest first: those in the events file
+// when they're persisted, and else those this process recorded
+func List() ([]Event, error) {
+	mu.Lock()
+	p := opts.Persist
+	list := slices.Clone(ring)
+	mu.Unlock()
+	if !p {
+		return list, nil
+	}
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	return read(path)
+}
+
+// Follow sends the events recorded after the one with ID after, by this or
+// any other invocation, until ctx is done. Events must be persisted.
+func Follow(ctx context.Context, after int64) (<-chan Event, error) {
+	mu.Lock()
+	p := opts.Persist
+	mu.Unlock()
+	if !p {
+		return nil, errors.New("following needs events kept in the state directory (events.persist: true)")
+	}
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		t := time.NewTicker(followInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+			list, err := read(path)
+			if err != nil {
+				slog.DebugContext(ctx, "reading events", "error", err)
+				continue
+			}
+			// IDs start over when the file is removed
+			if n := len(list); n == 0 || list[n-1].ID < after {
+				after = 0
+			}
+			for _, e := range list {
+				if e.ID <= after {
+					continue
+				}
+				select {
+				case out <- e:
+					after = e.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Path returns the file events are persisted in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "events.jsonl"), nil
+}
+
+// write appends e to the events file, numbering it after the last one, and
+// drops the oldest events beyond size
+func write(ctx context.Context, e *Event, size int) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking events file: %w", err)
+	}
+	defer l.Release()
+
+	list, err := read(path)
+	if err != nil {
+		return err
+	}
+	e.ID = 1
+	if n := len(list); n > 0 {
+		e.ID = list[n-1].ID + 1
+	}
+	list = append(list, *e)
+	if len(list) <= size {
+		return appendLine(path, *e)
+	}
+	return replace(path, list[len(list)-size:])
+}
+
+// read loads the events file; a missing file holds no events. A line that
+// doesn't parse, such as one cut short by a crash, is skipped.
+func read(path string) ([]Event, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var list []Event
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e Event
+		if err := json.Unmarshal(s.Bytes(), &e); err == nil {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+// appendLine adds e to the end of the events file
+func appendLine(path string, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// replace rewrites the events file with list, through a temporary file so
+// readers never see a partial write
+func replace(path string, list []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range list {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encoding event: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package flags declares constraints between a command's flags — required,
+// required together, mutually exclusive, or one of them required — and
+// checks them before the command runs, so a violation is reported as a
+// *model.UsageError naming the flags concerned rather than as an error
+// from inside the command.
+//
+// The constraints are also declared with cobra, so shell completion knows
+// them. They may involve persistent flags declared on the same command.
+package flags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Flag annotations recording the groups a flag belongs to, each as the
+// space-separated names of its flags
+const (
+	togetherAnnotation    = "termplate.flags_together"
+	exclusiveAnnotation   = "termplate.flags_exclusive"
+	oneRequiredAnnotation = "termplate.flags_one_required"
+)
+
+// Required marks flags that must be set
+func Required(cmd *cobra.Command, names ...string) {
+	for _, name := range names {
+		// What cmd.MarkFlagRequired does, for persistent flags too
+		annotate(lookup(cmd, name), cobra.BashCompOneRequiredFlag, "true")
+	}
+}
+
+// RequiredTogether marks flags that must be set together: setting one of
+// them requires the others
+func RequiredTogether(cmd *cobra.Command, names ...string) {
+	group(cmd, togetherAnnotation, names)
+	cmd.MarkFlagsRequiredTogether(names...)
+}
+
+// MutuallyExclusive marks flags of which at most one may be set
+func MutuallyExclusive(cmd *cobra.Command, names ...string) {
+	group(cmd, exclusiveAnnotation, names)
+	cmd.MarkFlagsMutuallyExclusive(names...)
+}
+
+// OneRequired marks flags of which at least one must be set
+func OneRequired(cmd *cobra.Command, names ...string) {
+	group(cmd, oneRequiredAnnotation, names)
+	cmd.MarkFlagsOneRequired(names...)
+}
+
+// group records names as a group of kind on each of its flags
+func group(cmd *cobra.Command, kind string, names []string) {
+	if len(names) < 2 {
+		panic(fmt.Sprintf("flags: a group needs at least two flags, got %v", names))
+	}
+	for _, name := range names {
+		f := lookup(cmd, name)
+		annotate(f, kind, append(f.Annotations[kind], strings.Join(names, " "))...)
+	}
+}
+
+// annotate sets an annotation of f
+func annotate(f *pflag.Flag, key string, values ...string) {
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[key] = values
+}
+
+// lookup returns cmd's local or persistent flag name, panicking when there
+// is none, as declaring a constraint on it is a bug
+func lookup(cmd *cobra.Command, name string) *pflag.Flag {
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f
+	}
+	if f := cmd.PersistentFlags().Lookup(name); f != nil {
+		return f
+	}
+	panic(fmt.Sprintf("flags: %s has no flag --%s", cmd.CommandPath(), name))
+}
+
+// Validate checks the flag constraints of cmd, whose flags have been
+// parsed, returning a *model.UsageError for the first one violated.
+// Groups declared directly with cobra are checked by cobra, with its
+// messages.
+func Validate(cmd *cobra.Command) error {
+	fs := cmd.Flags()
+
+	var missing []*pflag.Flag
+	fs.VisitAll(func(f *pflag.Flag) {
+		if req := f.Annotations[cobra.BashCompOneRequiredFlag]; len(req) > 0 && req[0] == "true" && !f.Changed {
+			missing = append(missing, f)
+		}
+	})
+	if len(missing) > 0 {
+		noun := "flag"
+		if len(missing) > 1 {
+			noun = "flags"
+		}
+		return &model.UsageError{
+			Command: cmd.CommandPath(),
+			Flag:    missing[0].Name,
+			Message: fmt.Sprintf("required %s %s not set", noun, join(missing, "and")),
+			Help:    "Required flags:\n" + Usages(missing),
+		}
+	}
+
+	seen := map[string]bool{}
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		for _, kind := range []string{togetherAnnotation, exclusiveAnnotation, oneRequiredAnnotation} {
+			for _, g := range f.Annotations[kind] {
+				if err != nil || seen[kind+g] {
+					continue
+				}
+				seen[kind+g] = true
+				err = checkGroup(cmd, kind, strings.Fields(g))
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return err
+	}
+	return cmd.ValidateFlagGroups()
+}
+
+// checkGroup checks one group of flags
+func checkGroup(cmd *cobra.Command, kind string, names []string) error {
+	var members, set, unset []*pflag.Flag
+	for _, name := range names {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			return nil
+		}
+		members = append(members, f)
+		if f.Changed {
+			set = append(set, f)
+		} else {
+			unset = append(unset, f)
+		}
+	}
+
+	ue := &model.UsageError{Command: cmd.CommandPath(), Help: "Flags:\n" + Usages(members)}
+	switch {
+	case kind == togetherAnnotation && len(set) > 0 && len(unset) > 0:
+		ue.Flag = unset[0].Name
+		ue.Message = fmt.Sprintf("%s must be used together (missing %s)", join(members, "and"), join(unset, "and"))
+	case kind == exclusiveAnnotation && len(set) > 1:
+		ue.Flag, ue.Token = set[1].Name, "--"+set[1].Name
+		ue.Message = fmt.Sprintf("%s can't be used together", join(set, "and"))
+	case kind == oneRequiredAnnotation && len(set) == 0:
+		ue.Flag = members[0].Name
+		ue.Message = fmt.Sprintf("one of %s is required", join(members, "or"))
+	default:
+		return nil
+	}
+	return ue
+}
+
+// join lists flags as "--a and --b" or "--a, --b, or --c"
+func join(fs []*pflag.Flag, conj string) string {
+	names := make([]string, len(fs))
+	for i, f := range fs {
+		names[i] = "--" + f.Name
+	}
+	switch len(names) {
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " " + conj + " " + names[1]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + ", " + conj + " " + names[len(names)-1]
+	}
+}
+
+// Usages returns the help lines of fs, as in --help
+func Usages(fs []*pflag.Flag) string {
+	set := pflag.NewFlagSet("", pflag.ContinueOnError)
+	for _, f := range fs {
+		set.AddFlag(f)
+	}
+	return set.FlagUsages()
+}
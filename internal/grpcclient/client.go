@@ -0,0 +1,88 @@
+// Package grpcclient dials the gRPC backend configured in the grpc
+// section, as apiclient does the HTTP API: TLS with a CA bundle and client
+// certificate, a bearer token and metadata sent with each call,
+// keepalive pings, a default deadline, retries of unary calls, and the
+// logging and timing of each call. Generated clients take the connection:
+//
+//	conn, err := grpcclient.Dial(cfg.GRPC)
+//	if err != nil { ... }
+//	defer conn.Close()
+//	users := userpb.NewUserServiceClient(conn)
+package grpcclient
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// Dial returns a connection to cfg.Address, set up with DialOptions and
+// then opts. It connects on the first call, not before, so a backend
+// that's down fails that call rather than Dial.
+func Dial(cfg config.GRPCConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("grpc.address is not set")
+	}
+	base, err := DialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(cfg.Address, append(base, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s: %w", cfg.Address, err)
+	}
+	return conn, nil
+}
+
+// DialOptions returns the options Dial uses, for callers that create the
+// connection themselves. Interceptors run in order: the offline check,
+// errors as *StatusError, logging, the default deadline, retries, then
+// the timing of each try.
+func DialOptions(cfg config.GRPCConfig) ([]grpc.DialOption, error) {
+	creds, err := transportCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := retryCodes(cfg.RetryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	unary := []grpc.UnaryClientInterceptor{offlineUnary, errorsUnary}
+	stream := []grpc.StreamClientInterceptor{offlineStream, errorsStream}
+	if cfg.LogRequests {
+		unary = append(unary, loggingUnary)
+		stream = append(stream, loggingStream)
+	}
+	unary = append(unary, timeoutUnary(cfg.Timeout), retryUnary(retryPolicy{
+		attempts: cfg.RetryAttempts,
+		delay:    cfg.RetryDelay,
+		maxDelay: cfg.RetryMaxDelay,
+		codes:    codes,
+	}), metricsUnary)
+	stream = append(stream, metricsStream)
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(unary...),
+		grpc.WithChainStreamInterceptor(stream...),
+	}
+	if cfg.Token != "" || len(cfg.Metadata) > 0 {
+		opts = append(opts, grpc.WithPerRPCCredentials(&callCredentials{
+			token:     cfg.Token,
+			metadata:  cfg.Metadata,
+			plaintext: cfg.Plaintext,
+		}))
+	}
+	if cfg.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    cfg.KeepaliveTime,
+			Timeout: cfg.KeepaliveTimeout,
+		}))
+	}
+	return opts, nil
+}
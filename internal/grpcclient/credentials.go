@@ -0,0 +1,83 @@
+package grpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// transportCredentials returns how connections are secured: not at all
+// with grpc.plaintext, otherwise with TLS trusting the CAs of
+// grpc.ca_bundle besides the system's, sending the certificate of
+// grpc.client_cert_file to servers that ask for one, and, when
+// grpc.verify_ssl is off, not verifying the server
+func transportCredentials(cfg config.GRPCConfig) (credentials.TransportCredentials, error) {
+	if cfg.Plaintext {
+		return insecure.NewCredentials(), nil
+	}
+	c := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.ServerName}
+	if !cfg.VerifySSL {
+		c.InsecureSkipVerify = true //nolint:gosec // Opted out with grpc.verify_ssl
+	}
+
+	if cfg.CABundle != "" {
+		data, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading grpc.ca_bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("reading grpc.ca_bundle: no PEM certificates in %s", cfg.CABundle)
+		}
+		c.RootCAs = pool
+	}
+
+	if cfg.ClientCertFile != "" {
+		keyFile := cfg.ClientKeyFile
+		if keyFile == "" {
+			keyFile = cfg.ClientCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading grpc.client_cert_file: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(c), nil
+}
+
+// callCredentials sends grpc.token as a bearer token and grpc.metadata
+// with each call
+type callCredentials struct {
+	token     string
+	metadata  map[string]string
+	plaintext bool
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (c *callCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	md := make(map[string]string, len(c.metadata)+1)
+	for k, v := range c.metadata {
+		md[k] = v
+	}
+	if c.token != "" {
+		md["authorization"] = "Bearer " + c.token
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. The
+// token is only sent without TLS when grpc.plaintext asks for that.
+func (c *callCredentials) RequireTransportSecurity() bool {
+	return !c.plaintext
+}
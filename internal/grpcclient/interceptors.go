@@ -0,0 +1,206 @@
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/retry"
+)
+
+// offlineUnary fails calls fast in offline mode (see offline.Check)
+func offlineUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if err := offline.Check("grpc " + method); err != nil {
+		return err
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func offlineStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if err := offline.Check("grpc " + method); err != nil {
+		return nil, err
+	}
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// StatusError is the error of a failed call: its gRPC status, which
+// status.FromError and status.Code still find, matched by errors.Is
+// against the model error its code means, as apiclient's errors are
+type StatusError struct {
+	err  error
+	kind error // e.g. model.ErrNotFound; nil for codes without one
+}
+
+func (e *StatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *StatusError) Unwrap() []error {
+	if e.kind == nil {
+		return []error{e.err}
+	}
+	return []error{e.err, e.kind}
+}
+
+// modelKinds are the model errors status codes mean
+var modelKinds = map[codes.Code]error{
+	codes.NotFound:         model.ErrNotFound,
+	codes.AlreadyExists:    model.ErrAlreadyExists,
+	codes.InvalidArgument:  model.ErrInvalidInput,
+	codes.Unauthenticated:  model.ErrUnauthorized,
+	codes.PermissionDenied: model.ErrUnauthorized,
+	codes.Aborted:          model.ErrConflict,
+}
+
+// statusError returns err as a *StatusError when it's a status other than
+// a canceled context's
+func statusError(err error) error {
+	s, ok := status.FromError(err)
+	if err == nil || !ok || s.Code() == codes.Canceled {
+		return err
+	}
+	return &StatusError{err: err, kind: modelKinds[s.Code()]}
+}
+
+func errorsUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return statusError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+func errorsStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &errorsClientStream{ClientStream: s}, nil
+}
+
+// errorsClientStream maps the errors of a stream's messages as
+// errorsStream does the error of opening it
+type errorsClientStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorsClientStream) RecvMsg(m interface{}) error {
+	return statusError(s.ClientStream.RecvMsg(m))
+}
+
+// loggingUnary logs each call's method, status code, and duration with
+// the context's logger (grpc.log_requests)
+func loggingUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	logCall(ctx, "grpc call", method, start, err)
+	return err
+}
+
+// loggingStream logs the opening of each stream as loggingUnary does a
+// call
+func loggingStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	logCall(ctx, "grpc stream", method, start, err)
+	return s, err
+}
+
+func logCall(ctx context.Context, msg, method string, start time.Time, err error) {
+	attrs := []any{"method", method, "code", status.Code(err).String(), "duration", time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		logger.FromContext(ctx).InfoContext(ctx, msg+" failed", append(attrs, "error", status.Convert(err).Message())...)
+	} else {
+		logger.FromContext(ctx).InfoContext(ctx, msg, attrs...)
+	}
+}
+
+// timeoutUnary gives unary calls without a deadline one d away, when d is
+// set (grpc.timeout). Streams can run for as long as they're read.
+func timeoutUnary(d time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); d > 0 && !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// noRetryKey marks the context of calls that mustn't be retried
+type noRetryKey struct{}
+
+// WithoutRetries returns ctx whose unary calls aren't retried, for those
+// that aren't safe to repeat, e.g. one that charges a card without an
+// idempotency key
+func WithoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// retryPolicy is when retryUnary retries a call, from grpc.retry_*
+type retryPolicy struct {
+	attempts int           // Retries after the first try
+	delay    time.Duration // Wait before the first retry, doubled for each one after
+	maxDelay time.Duration // Longest wait; zero is unlimited
+	codes    []codes.Code
+}
+
+// retryUnary retries unary calls that fail with one of the policy's codes,
+// waiting with exponential backoff and jitter (see retry.Backoff). Each
+// retry is logged with the context's logger and recorded as an event.
+func retryUnary(p retryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if off, _ := ctx.Value(noRetryKey{}).(bool); p.attempts <= 0 || off {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		for n := 1; ; n++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || n > p.attempts || !slices.Contains(p.codes, status.Code(err)) {
+				return err
+			}
+
+			delay := retry.Backoff(p.delay, p.maxDelay, n)
+			reason := status.Code(err).String() + ": " + status.Convert(err).Message()
+			logger.FromContext(ctx).WarnContext(ctx, "retrying call",
+				"method", method, "attempt", n+1, "wait", delay, "reason", reason)
+			events.Record(ctx, events.Warning, "retry", fmt.Sprintf("retrying %s: %s", method, reason), "attempt", n+1)
+
+			if err := sleep(ctx, delay); err != nil {
+				return status.FromContextError(err).Err()
+			}
+		}
+	}
+}
+
+// retryCodes parses status code names, e.g. UNAVAILABLE, ignoring case
+func retryCodes(names []string) ([]codes.Code, error) {
+	out := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name)))); err != nil {
+			return nil, fmt.Errorf("invalid grpc.retry_codes %q: expected a gRPC status code such as UNAVAILABLE", name)
+		}
+		out = append(out, code)
+	}
+	return out, nil
+}
+
+// sleep waits for d, or returns ctx's error once it's done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,170 @@
+package grpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Call is one call's method, status code, and duration. A stream's
+// duration runs until its last message is received; streams abandoned
+// before then aren't recorded.
+type Call struct {
+	Method   string
+	Code     codes.Code
+	Stream   bool
+	Duration time.Duration
+}
+
+// Metrics aggregates the calls made on connections from Dial. It's safe
+// for concurrent use.
+type Metrics struct {
+	// OnCall, when set, is called with each call, e.g. to emit a tracing
+	// span
+	OnCall func(Call)
+
+	mu     sync.Mutex
+	calls  int
+	failed map[codes.Code]int
+	total  time.Duration
+	max    time.Duration
+}
+
+// Record adds a call
+func (m *Metrics) Record(c Call) {
+	m.mu.Lock()
+	m.calls++
+	if c.Code != codes.OK {
+		if m.failed == nil {
+			m.failed = map[codes.Code]int{}
+		}
+		m.failed[c.Code]++
+	}
+	m.total += c.Duration
+	m.max = max(m.max, c.Duration)
+	onCall := m.OnCall
+	m.mu.Unlock()
+
+	if onCall != nil {
+		onCall(c)
+	}
+}
+
+// Summary is a snapshot of Metrics
+type Summary struct {
+	Calls  int
+	Failed map[codes.Code]int // Calls that failed, by status code
+	Total  time.Duration      // Of all the calls together
+	Max    time.Duration
+}
+
+// Summary returns the metrics so far
+func (m *Metrics) Summary() Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Summary{Calls: m.calls, Failed: maps.Clone(m.failed), Total: m.total, Max: m.max}
+}
+
+// Avg returns the average duration of a call
+func (s Summary) Avg() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+// String formats the summary for --stats:
+//
+//	gRPC: 12 calls, 1 failed (Unavailable 1)
+//	  total     avg 12ms        max 30ms
+func (s Summary) String() string {
+	var b strings.Builder
+	failed := 0
+	var byCode []string
+	for _, code := range slices.Sorted(maps.Keys(s.Failed)) {
+		failed += s.Failed[code]
+		byCode = append(byCode, fmt.Sprintf("%s %d", code, s.Failed[code]))
+	}
+	fmt.Fprintf(&b, "gRPC: %d calls, %d failed", s.Calls, failed)
+	if failed > 0 {
+		fmt.Fprintf(&b, " (%s)", strings.Join(byCode, ", "))
+	}
+	fmt.Fprintf(&b, "\n  %-8s  avg %-10s  max %-10s\n", "total", s.Avg().Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	return b.String()
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns a copy of ctx whose calls are recorded in m
+func WithMetrics(ctx context.Context, m *Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// MetricsFromContext returns the metrics carried by ctx, or nil
+func MetricsFromContext(ctx context.Context) *Metrics {
+	m, _ := ctx.Value(metricsKey{}).(*Metrics)
+	return m
+}
+
+// metricsUnary times each try of a call, records it in the Metrics
+// carried by the context, if any, and logs it at debug level
+func metricsUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	record(ctx, Call{Method: method, Code: status.Code(err), Duration: time.Since(start)})
+	return err
+}
+
+// metricsStream times streams as metricsUnary does calls, until the last
+// message is received
+func metricsStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		record(ctx, Call{Method: method, Code: status.Code(err), Stream: true, Duration: time.Since(start)})
+		return nil, err
+	}
+	return &timedStream{ClientStream: s, ctx: ctx, method: method, start: start}, nil
+}
+
+// timedStream records its call once a message can't be received: at the
+// end of the stream or when it fails
+type timedStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	method string
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *timedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			code := codes.OK
+			if !errors.Is(err, io.EOF) {
+				code = status.Code(err)
+			}
+			record(s.ctx, Call{Method: s.method, Code: code, Stream: true, Duration: time.Since(s.start)})
+		})
+	}
+	return err
+}
+
+func record(ctx context.Context, c Call) {
+	slog.Debug("grpc call", "method", c.Method, "code", c.Code.String(), "stream", c.Stream, "duration", c.Duration)
+	if m := MetricsFromContext(ctx); m != nil {
+		m.Record(c)
+	}
+}
@@ -0,0 +1,12 @@
+package handler
+
+import (
+	"github.com/blacksilver/termplate-go/internal/apiclient"
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// newAPIClient returns a client for the API configured in cfg, sending
+// its credentials, user agent, and headers with each request
+func newAPIClient(cfg config.APIConfig) (*apiclient.Client, error) {
+	return apiclient.New(cfg)
+}
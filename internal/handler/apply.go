@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/service/apply"
+	"github.com/blacksilver/termplate-go/internal/update"
+)
+
+// ApplyInput is the resources to apply
+type ApplyInput struct {
+	Files []string // Files and directories of resources; "-" is stdin
+	Prune bool     // Delete live resources of the same kinds that no file declares
+
+	// Selector limits the resources applied, and pruned, to those with
+	// matching labels, e.g. "env=prod,team!=core"
+	Selector string
+
+	// ServerDiff shows the changes as the API's dry run of them returns
+	// the resources; api.dry_run_query must be set
+	ServerDiff bool
+}
+
+// ApplyOutput is a plan and, unless it was a dry run, how applying it went
+type ApplyOutput struct {
+	Plan     *apply.Plan  `json:"plan" yaml:"plan"`
+	PlanFile string       `json:"plan_file,omitempty" yaml:"plan_file,omitempty"` // Where the plan was saved, to apply later
+	Report   *bulk.Report `json:"report,omitempty" yaml:"report,omitempty"`
+}
+
+// ApplyHandler brings API resources to the state declared in files
+type ApplyHandler struct{}
+
+// NewApplyHandler creates a new apply handler
+func NewApplyHandler() *ApplyHandler {
+	return &ApplyHandler{}
+}
+
+// Plan reads the resources in the input's files and compares them with
+// the API configured in cfg
+func (h *ApplyHandler) Plan(ctx context.Context, cfg config.APIConfig, in ApplyInput) (*apply.Plan, error) {
+	if len(in.Files) == 0 {
+		return nil, model.NewValidationError("filename", "at least one file is required")
+	}
+	if in.ServerDiff && cfg.DryRunQuery == "" {
+		return nil, model.NewValidationError("server-diff", "api.dry_run_query must be set to the API's dry-run query, e.g. dryRun=All")
+	}
+	selector, err := model.ParseSelector(in.Selector)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := apply.Load(in.Files)
+	if err != nil {
+		return nil, fmt.Errorf("reading resources: %w", err)
+	}
+	if err := offline.Check("planning changes"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return svc.Plan(ctx, resources, apply.PlanOptions{Prune: in.Prune, Selector: selector, ServerDiff: in.ServerDiff})
+}
+
+// SavePlan writes plan to path, to be applied as it is until
+// api.plan_expiry passes
+func (h *ApplyHandler) SavePlan(cfg config.APIConfig, plan *apply.Plan, path string) error {
+	if err := apply.WritePlan(path, plan, cfg.BaseURL, cfg.PlanExpiry); err != nil {
+		return fmt.Errorf("saving plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan saved with SavePlan, checking that the resources
+// it changes are still as they were when it was made
+func (h *ApplyHandler) LoadPlan(ctx context.Context, cfg config.APIConfig, path string) (*apply.Plan, error) {
+	plan, err := apply.ReadPlan(path, cfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := offline.Check("checking the plan"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.Verify(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Apply makes the changes in plan, resolving updates that collide with
+// another change as api.on_conflict says, and records the specs applied
+func (h *ApplyHandler) Apply(ctx context.Context, cfg config.APIConfig, plan *apply.Plan, opts apply.ApplyOptions) (*bulk.Report, error) {
+	if err := offline.Check("applying changes"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	report, err := svc.Apply(ctx, plan, opts)
+	if err != nil {
+		return nil, fmt.Errorf("applying changes: %w", err)
+	}
+	return report, nil
+}
+
+func newApplyService(cfg config.APIConfig) (*apply.Service, error) {
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := update.NewResolver(cfg.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+	lastApplied, err := apply.OpenLastApplied(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("loading last-applied state: %w", err)
+	}
+	return apply.NewService(&apply.HTTPBackend{
+		Client:      client.HTTP,
+		BaseURL:     cfg.BaseURL,
+		Path:        cfg.ResourcePath,
+		Resolver:    resolver,
+		DryRunQuery: cfg.DryRunQuery,
+		EventsPath:  cfg.EventsPath,
+	}, lastApplied), nil
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/apiclient"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/oauth"
+)
+
+// AuthStatus describes the login stored for the configured OAuth client
+type AuthStatus struct {
+	ClientID    string     `json:"client_id" yaml:"client_id" table:"Client ID"`
+	TokenURL    string     `json:"token_url" yaml:"token_url" table:"Token URL"`
+	Store       string     `json:"store" yaml:"store"`
+	Expires     *time.Time `json:"expires,omitempty" yaml:"expires,omitempty"` // When the access token expires
+	Refreshable bool       `json:"refreshable" yaml:"refreshable"`             // A refresh token is stored
+	Scope       string     `json:"scope,omitempty" yaml:"scope,omitempty"`
+}
+
+// AuthHandler logs in to and out of the API with OAuth 2.0
+type AuthHandler struct{}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler() *AuthHandler {
+	return &AuthHandler{}
+}
+
+// Login signs in as the OAuth client configured in cfg, through the
+// browser or, with device or no api.oauth.auth_url, with the device flow,
+// writing instructions for the user to w, and stores the tokens
+func (h *AuthHandler) Login(ctx context.Context, cfg config.APIConfig, device bool, w io.Writer) (*AuthStatus, error) {
+	o := cfg.OAuth
+	if err := oauthConfigured(o); err != nil {
+		return nil, err
+	}
+	if device && o.DeviceURL == "" {
+		return nil, model.NewValidationError("api.oauth.device_url", "device login needs api.oauth.device_url")
+	}
+	transport, err := apiclient.NewTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating transport: %w", err)
+	}
+	client := &http.Client{Transport: transport, Timeout: cfg.Timeout}
+
+	var t *oauth.Token
+	if device || o.AuthURL == "" {
+		t, err = oauth.LoginDevice(ctx, client, o, w)
+	} else {
+		t, err = oauth.LoginBrowser(ctx, client, o, w)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := oauth.Save(ctx, o, t); err != nil {
+		return nil, fmt.Errorf("storing token: %w", err)
+	}
+	return authStatus(o, t), nil
+}
+
+// Logout removes the tokens stored for the configured OAuth client
+func (h *AuthHandler) Logout(ctx context.Context, cfg config.APIConfig) error {
+	if err := oauthConfigured(cfg.OAuth); err != nil {
+		return err
+	}
+	if err := oauth.Delete(ctx, cfg.OAuth); err != nil {
+		return fmt.Errorf("removing token: %w", err)
+	}
+	return nil
+}
+
+// Status returns the login stored for the configured OAuth client, or
+// oauth.ErrNotLoggedIn
+func (h *AuthHandler) Status(ctx context.Context, cfg config.APIConfig) (*AuthStatus, error) {
+	if err := oauthConfigured(cfg.OAuth); err != nil {
+		return nil, err
+	}
+	t, err := oauth.Load(ctx, cfg.OAuth)
+	if err != nil {
+		return nil, err
+	}
+	return authStatus(cfg.OAuth, t), nil
+}
+
+// oauthConfigured checks an OAuth client is configured to log in as
+func oauthConfigured(o config.OAuthConfig) error {
+	if o.ClientID == "" {
+		return model.NewValidationError("api.oauth.client_id", "no OAuth client configured; set api.oauth.client_id")
+	}
+	return nil
+}
+
+func authStatus(o config.OAuthConfig, t *oauth.Token) *AuthStatus {
+	s := &AuthStatus{
+		ClientID:    o.ClientID,
+		TokenURL:    o.TokenURL,
+		Store:       o.TokenStore,
+		Refreshable: t.RefreshToken != "",
+		Scope:       t.Scope,
+	}
+	if !t.Expiry.IsZero() {
+		expires := t.Expiry.Round(time.Second)
+		s.Expires = &expires
+	}
+	return s
+}
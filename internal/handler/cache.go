@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blacksilver/termplate-go/internal/apiclient"
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// CacheClearOutput is what clearing the caches removed
+type CacheClearOutput struct {
+	Removed     int             `json:"removed" yaml:"removed"`         // API responses
+	Completions int             `json:"completions" yaml:"completions"` // Lists of shell completions
+	Size        config.ByteSize `json:"size" yaml:"size"`
+}
+
+// CacheHandler manages the API response cache
+type CacheHandler struct{}
+
+// NewCacheHandler creates a new cache handler
+func NewCacheHandler() *CacheHandler {
+	return &CacheHandler{}
+}
+
+// Clear removes every cached API response and shell completion list
+func (h *CacheHandler) Clear(_ context.Context) (*CacheClearOutput, error) {
+	dir, err := apiclient.CacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("finding response cache: %w", err)
+	}
+	n, size, err := apiclient.ClearCache(dir)
+	if err != nil {
+		return nil, fmt.Errorf("clearing response cache: %w", err)
+	}
+
+	dir, err = apiclient.CompletionCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("finding completion cache: %w", err)
+	}
+	completions, completionSize, err := apiclient.ClearCache(dir)
+	if err != nil {
+		return nil, fmt.Errorf("clearing completion cache: %w", err)
+	}
+	return &CacheClearOutput{Removed: n, Completions: completions, Size: config.ByteSize(size + completionSize)}, nil
+}
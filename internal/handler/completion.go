@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/service/completion"
+)
+
+type CompletionInstallInput struct {
+	Shell  string
+	Name   string // The program completed, e.g. termplate
+	Script []byte // Its completion script for Shell
+}
+
+// CompletionHandler installs shell completion scripts
+type CompletionHandler struct {
+	service *completion.Service
+}
+
+// NewCompletionHandler creates a new completion handler
+func NewCompletionHandler() *CompletionHandler {
+	return &CompletionHandler{
+		service: completion.NewService(),
+	}
+}
+
+// Install writes the completion script where the shell loads it from and
+// checks that a new shell does
+func (h *CompletionHandler) Install(ctx context.Context, in CompletionInstallInput) (*completion.Result, error) {
+	if err := validateShell(in.Shell); err != nil {
+		return nil, err
+	}
+	res, err := h.service.Install(ctx, in.Shell, in.Name, in.Script)
+	if err != nil {
+		return nil, fmt.Errorf("installing %s completion: %w", in.Shell, err)
+	}
+	return res, nil
+}
+
+// Uninstall removes the completion script Install wrote for name, returning
+// nil when there was none
+func (h *CompletionHandler) Uninstall(ctx context.Context, shell, name string) (*completion.Result, error) {
+	if err := validateShell(shell); err != nil {
+		return nil, err
+	}
+	res, err := h.service.Uninstall(ctx, shell, name)
+	if err != nil {
+		return nil, fmt.Errorf("uninstalling %s completion: %w", shell, err)
+	}
+	return res, nil
+}
+
+func validateShell(shell string) error {
+	if !slices.Contains(completion.Shells, shell) {
+		return model.NewValidationError("shell",
+			fmt.Sprintf("unsupported shell %q (supported: %s)", shell, strings.Join(completion.Shells, ", ")))
+	}
+	return nil
+}
@@ -0,0 +1,600 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Doctor check severities
+const (
+	SeverityOK    = "ok"
+	SeverityInfo  = "info"
+	SeverityWarn  = "warn"
+	SeverityError = "error"
+)
+
+// DoctorCheck is a single finding of the config doctor
+type DoctorCheck struct {
+	Severity string `json:"severity" yaml:"severity" table:"Severity"`
+	Check    string `json:"check" yaml:"check" table:"Check"`
+	Message  string `json:"message" yaml:"message" table:"Message"`
+}
+
+// ID identifies the check in quiet output
+func (c DoctorCheck) ID() string {
+	return c.Check
+}
+
+// DoctorReport lists the findings for the active configuration
+type DoctorReport struct {
+	ConfigFile  string        `json:"config_file" yaml:"config_file"`                       // The file edits go to
+	ConfigFiles []string      `json:"config_files,omitempty" yaml:"config_files,omitempty"` // Every file in use, in merge order
+	Checks      []DoctorCheck `json:"checks" yaml:"checks"`
+}
+
+// Errors returns the number of error findings
+func (r *DoctorReport) Errors() int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *DoctorReport) add(severity, check, message string) {
+	r.Checks = append(r.Checks, DoctorCheck{Severity: severity, Check: check, Message: message})
+}
+
+// ConfigHandler inspects the application configuration
+type ConfigHandler struct{}
+
+// NewConfigHandler creates a new config handler
+func NewConfigHandler() *ConfigHandler {
+	return &ConfigHandler{}
+}
+
+// Doctor checks the config files for read errors, deprecated and unknown
+// keys, unresolvable values, and invalid settings
+func (h *ConfigHandler) Doctor(_ context.Context, loader *config.Loader) (*DoctorReport, error) {
+	report := &DoctorReport{ConfigFile: loader.ConfigFileUsed()}
+
+	// Read returns the error the startup read only logged
+	err := loader.Read()
+	report.ConfigFiles = loader.ConfigFiles()
+	switch {
+	case err != nil:
+		report.add(SeverityError, "file", err.Error())
+		return report, nil
+	case len(report.ConfigFiles) == 0:
+		report.add(SeverityInfo, "file", "no config file found; using defaults and environment")
+	default:
+		report.add(SeverityOK, "file", "using "+strings.Join(report.ConfigFiles, ", "))
+	}
+
+	for _, n := range loader.Deprecations() {
+		report.add(SeverityWarn, "deprecated", n.String())
+	}
+	for _, file := range report.ConfigFiles {
+		unknown, err := config.UnknownKeys(file)
+		if err != nil {
+			report.add(SeverityError, "keys", err.Error())
+		}
+		for _, key := range unknown {
+			msg := fmt.Sprintf("unknown key %q", key)
+			if len(report.ConfigFiles) > 1 {
+				msg += " in " + file
+			}
+			report.add(SeverityWarn, "keys", msg)
+		}
+	}
+
+	cfg, err := loader.Load()
+	if err != nil {
+		var valuesErr *config.ValuesError
+		if !errors.As(err, &valuesErr) {
+			report.add(SeverityError, "values", err.Error())
+			return report, nil
+		}
+		for _, f := range valuesErr.Fields {
+			report.add(SeverityError, "values", f.Key+": "+f.Message)
+		}
+		return report, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		report.add(SeverityError, "validate", err.Error())
+		return report, nil
+	}
+	report.add(SeverityOK, "validate", "configuration is valid")
+
+	return report, nil
+}
+
+// ConfigEdit is a change config set or unset made to the config file
+type ConfigEdit struct {
+	File    string `json:"file" yaml:"file"`
+	Key     string `json:"key" yaml:"key"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+	Changed bool   `json:"changed" yaml:"changed"`                     // False when unset found nothing to remove
+	EnvVar  string `json:"env_var,omitempty" yaml:"env_var,omitempty"` // Set environment variable taking priority over the file
+}
+
+// View returns the effective configuration, merged from the defaults,
+// config file, environment, and flags, with credentials redacted unless
+// showSecrets is set
+func (h *ConfigHandler) View(_ context.Context, loader *config.Loader, showSecrets bool) (map[string]interface{}, error) {
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	settings := config.Settings(cfg)
+	if !showSecrets {
+		config.Redact(settings)
+	}
+	return settings, nil
+}
+
+// ConfigSetting is a setting in effect and where its value comes from
+type ConfigSetting struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"` // default, file, env, flag, or set
+	Origin string `json:"origin" yaml:"origin"` // The file, environment variable, or flag
+}
+
+// ID identifies the setting by its key in quiet output
+func (s ConfigSetting) ID() string {
+	return s.Key
+}
+
+// Origins returns each setting in effect, by key, with where its value
+// comes from, credentials redacted unless showSecrets is set
+func (h *ConfigHandler) Origins(_ context.Context, loader *config.Loader, showSecrets bool) ([]ConfigSetting, error) {
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	settings := config.Settings(cfg)
+	if !showSecrets {
+		config.Redact(settings)
+	}
+
+	var list []ConfigSetting
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for _, name := range slices.Sorted(maps.Keys(m)) {
+			key := prefix + name
+			if section, ok := m[name].(map[string]interface{}); ok {
+				walk(key+".", section)
+				continue
+			}
+			origin := loader.Origin(key)
+			list = append(list, ConfigSetting{Key: key, Value: fmt.Sprint(m[name]), Source: origin.Source, Origin: origin.String()})
+		}
+	}
+	walk("", settings)
+	return list, nil
+}
+
+// ConfigOverride is a setting changed from its default, and by what
+type ConfigOverride struct {
+	Key     string `json:"key" yaml:"key"`
+	Value   string `json:"value" yaml:"value"`
+	Default string `json:"default" yaml:"default"`
+	Source  string `json:"source" yaml:"source"` // file, env, flag, remote, or set
+	Origin  string `json:"origin" yaml:"origin"` // The file, environment variable, or flag
+}
+
+// ID identifies the override by its key in quiet output
+func (o ConfigOverride) ID() string {
+	return o.Key
+}
+
+// Diff returns the settings overridden from their defaults, by key, within
+// section when it's set, with credentials redacted unless showSecrets is
+// set. A setting something sets to its default value is listed too, as it
+// no longer follows the default.
+func (h *ConfigHandler) Diff(_ context.Context, loader *config.Loader, section string, showSecrets bool) ([]ConfigOverride, error) {
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	defaultCfg, err := config.NewLoader().Load()
+	if err != nil {
+		return nil, err
+	}
+	settings, defaults := config.Settings(cfg), config.Settings(defaultCfg)
+	if !showSecrets {
+		config.Redact(settings)
+		config.Redact(defaults)
+	}
+	if section != "" {
+		if _, ok := config.Setting(settings, section); !ok {
+			return nil, model.NewValidationError(section, "unknown config key")
+		}
+	}
+	section = strings.ToLower(section)
+
+	var list []ConfigOverride
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for _, name := range slices.Sorted(maps.Keys(m)) {
+			key := prefix + name
+			if sub, ok := m[name].(map[string]interface{}); ok {
+				walk(key+".", sub)
+				continue
+			}
+			if section != "" && key != section && !strings.HasPrefix(key, section+".") {
+				continue
+			}
+			value, def := fmt.Sprint(m[name]), ""
+			if d, ok := config.Setting(defaults, key); ok {
+				def = fmt.Sprint(d)
+			}
+			origin := loader.Origin(key)
+			if origin.Source == config.SourceDefault && value == def {
+				continue
+			}
+			list = append(list, ConfigOverride{
+				Key:     key,
+				Value:   value,
+				Default: def,
+				Source:  origin.Source,
+				Origin:  origin.String(),
+			})
+		}
+	}
+	walk("", settings)
+	return list, nil
+}
+
+// Get returns the effective value of key: a string for a single setting,
+// otherwise the section or list
+func (h *ConfigHandler) Get(_ context.Context, loader *config.Loader, key string) (interface{}, error) {
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	// Credentials are hidden in a section, but shown when asked for by name
+	settings := config.Settings(cfg)
+	if !config.IsSecret(key) {
+		config.Redact(settings)
+	}
+	value, ok := config.Setting(settings, key)
+	if !ok {
+		// A setting of a command section (see config.BindCommand) isn't
+		// part of Config
+		if value, err := loader.Value(key); err == nil {
+			return value, nil
+		}
+		return nil, model.NewValidationError(key, "unknown config key")
+	}
+	if _, section := value.(map[string]interface{}); section {
+		return value, nil
+	}
+	if _, list := value.([]interface{}); list {
+		return value, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Set writes key to the config file, refusing values that don't parse or
+// that fail validation
+func (h *ConfigHandler) Set(_ context.Context, loader *config.Loader, key, value string) (*ConfigEdit, error) {
+	doc, err := openConfigDocument(loader)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Set(key, value); err != nil {
+		return nil, model.NewValidationError(key, err.Error())
+	}
+
+	// The value is validated on its own, so problems elsewhere in the
+	// config don't block fixing them. References are checked once
+	// expanded, when the config is loaded.
+	if !strings.Contains(value, "${") {
+		check := config.NewLoader()
+		check.Set(key, value)
+		cfg, err := check.Load()
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			return nil, model.NewValidationError(key, err.Error())
+		}
+	}
+
+	if err := doc.Save(); err != nil {
+		return nil, err
+	}
+	edit := &ConfigEdit{File: doc.Path(), Key: strings.ToLower(key), Value: value, Changed: true}
+	if name, ok := loader.EnvVar(key); ok {
+		edit.EnvVar = name
+	}
+	return edit, nil
+}
+
+// Unset removes key from the config file, so it falls back to its default
+func (h *ConfigHandler) Unset(_ context.Context, loader *config.Loader, key string) (*ConfigEdit, error) {
+	doc, err := openConfigDocument(loader)
+	if err != nil {
+		return nil, err
+	}
+	edit := &ConfigEdit{File: doc.Path(), Key: strings.ToLower(key)}
+	if !doc.Unset(key) {
+		return edit, nil
+	}
+	if err := doc.Save(); err != nil {
+		return nil, err
+	}
+	edit.Changed = true
+	if name, ok := loader.EnvVar(key); ok {
+		edit.EnvVar = name
+	}
+	return edit, nil
+}
+
+// ConfigCrypt is what config encrypt or decrypt changed in the config file
+type ConfigCrypt struct {
+	File       string   `json:"file" yaml:"file"`
+	Keys       []string `json:"keys" yaml:"keys"`                                   // The settings encrypted or decrypted
+	Skipped    []string `json:"skipped,omitempty" yaml:"skipped,omitempty"`         // Settings referencing environment variables, left as they are
+	KeyCreated bool     `json:"key_created,omitempty" yaml:"key_created,omitempty"` // A new key was stored in the OS keychain
+}
+
+// Encrypt encrypts the values of keys in the config file, or of each
+// credential it holds in plain text when keys is empty (see
+// config.SecretKeys). Without a key in config.KeyEnvVar or the OS
+// keychain, it creates one in the keychain.
+func (h *ConfigHandler) Encrypt(_ context.Context, loader *config.Loader, keys []string) (*ConfigCrypt, error) {
+	doc, err := openConfigDocument(loader)
+	if err != nil {
+		return nil, err
+	}
+	result := &ConfigCrypt{File: doc.Path()}
+	values := doc.Values()
+	var targets []string
+	for _, key := range cryptKeys(keys, values) {
+		value := values[key]
+		switch {
+		case config.IsEncrypted(value):
+		case strings.Contains(value, "${"):
+			// The secret is in the environment, not the file
+			result.Skipped = append(result.Skipped, key)
+		default:
+			targets = append(targets, key)
+		}
+	}
+	if len(targets) == 0 {
+		return result, nil
+	}
+
+	secret, err := config.EncryptionKey()
+	if errors.Is(err, config.ErrNoKey) {
+		if secret, err = config.GenerateKey(); err != nil {
+			return nil, err
+		}
+		if err = config.StoreKey(secret); errors.Is(err, config.ErrNoKeychain) {
+			return nil, fmt.Errorf("no OS keychain to keep a key in; set %s to one, e.g. from 'openssl rand -base64 32'", config.KeyEnvVar)
+		}
+		result.KeyCreated = err == nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range targets {
+		encrypted, err := config.EncryptValue(secret, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("encrypting %s: %w", key, err)
+		}
+		doc.Replace(key, encrypted)
+		result.Keys = append(result.Keys, key)
+	}
+	if err := doc.Save(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Decrypt stores the values of keys in the config file in plain text
+// again, or of every encrypted value when keys is empty
+func (h *ConfigHandler) Decrypt(_ context.Context, loader *config.Loader, keys []string) (*ConfigCrypt, error) {
+	doc, err := openConfigDocument(loader)
+	if err != nil {
+		return nil, err
+	}
+	result := &ConfigCrypt{File: doc.Path()}
+	values := doc.Values()
+	if len(keys) == 0 {
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			if config.IsEncrypted(values[key]) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	var targets []string
+	for _, key := range cryptKeys(keys, values) {
+		if config.IsEncrypted(values[key]) {
+			targets = append(targets, key)
+		}
+	}
+	if len(targets) == 0 {
+		return result, nil
+	}
+
+	secret, err := config.EncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range targets {
+		plain, err := config.DecryptValue(secret, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", key, err)
+		}
+		doc.Replace(key, plain)
+		result.Keys = append(result.Keys, key)
+	}
+	if err := doc.Save(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cryptKeys returns the keys of values to encrypt or decrypt: keys, which
+// must be set in the file, or else the credentials it sets
+func cryptKeys(keys []string, values map[string]string) []string {
+	if len(keys) == 0 {
+		for _, key := range config.SecretKeys {
+			if _, ok := values[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+	var found []string
+	for _, key := range keys {
+		key = strings.ToLower(key)
+		if _, ok := values[key]; ok {
+			found = append(found, key)
+		}
+	}
+	return found
+}
+
+// openConfigDocument opens the config file in use for editing
+func openConfigDocument(loader *config.Loader) (*config.Document, error) {
+	file := loader.WritableFile()
+	if file == "" {
+		return nil, model.NewValidationError("config", "no config file to write; pass one with --config")
+	}
+	return config.OpenDocument(file)
+}
+
+// ConfigProblem is a problem config validate found in a config file
+type ConfigProblem struct {
+	File     string `json:"file" yaml:"file" table:"-"`
+	Line     int    `json:"line,omitempty" yaml:"line,omitempty" table:"Line"`
+	Column   int    `json:"column,omitempty" yaml:"column,omitempty" table:"Column"`
+	Severity string `json:"severity" yaml:"severity" table:"Severity"`
+	Key      string `json:"key,omitempty" yaml:"key,omitempty" table:"Key"`
+	Message  string `json:"message" yaml:"message" table:"Message"`
+}
+
+// ID identifies the problem by its key in quiet output
+func (p ConfigProblem) ID() string {
+	return p.Key
+}
+
+// String formats the problem like a compiler diagnostic:
+// file:line:column: severity: key: message
+func (p ConfigProblem) String() string {
+	pos := p.File
+	if p.Line > 0 {
+		pos += fmt.Sprintf(":%d", p.Line)
+	}
+	if p.Column > 0 {
+		pos += fmt.Sprintf(":%d", p.Column)
+	}
+	if p.Key != "" {
+		return fmt.Sprintf("%s: %s: %s: %s", pos, p.Severity, p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, p.Severity, p.Message)
+}
+
+// ValidationReport lists every problem found in config files
+type ValidationReport struct {
+	File     string          `json:"file" yaml:"file"` // The files checked, comma-separated
+	Errors   int             `json:"errors" yaml:"errors"`
+	Warnings int             `json:"warnings" yaml:"warnings"`
+	Problems []ConfigProblem `json:"problems" yaml:"problems"`
+}
+
+// Validate checks the config file at file, or each of those in use when
+// file is empty, and reports all their problems at once
+func (h *ConfigHandler) Validate(_ context.Context, loader *config.Loader, file string) (*ValidationReport, error) {
+	files := []string{file}
+	if file == "" {
+		files = loader.ConfigFiles()
+	}
+	if len(files) == 0 {
+		return nil, model.NewValidationError("file", "no config file found; pass one as an argument or with --config")
+	}
+
+	report := &ValidationReport{File: strings.Join(files, ", "), Problems: []ConfigProblem{}}
+	for _, file := range files {
+		if err := report.check(file); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// check adds the problems of file to the report
+func (r *ValidationReport) check(file string) error {
+	problems, err := config.CheckFile(file)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		severity := SeverityError
+		if p.Warning {
+			severity = SeverityWarn
+			r.Warnings++
+		} else {
+			r.Errors++
+		}
+		r.Problems = append(r.Problems, ConfigProblem{
+			File: file, Line: p.Line, Column: p.Column, Severity: severity, Key: p.Key, Message: p.Message,
+		})
+	}
+	return nil
+}
+
+// ConfigChange is a reload of the config file seen by config watch
+type ConfigChange struct {
+	Time    time.Time `json:"time" yaml:"time"`
+	Changed []string  `json:"changed,omitempty" yaml:"changed,omitempty"`
+	Error   string    `json:"error,omitempty" yaml:"error,omitempty"` // Set when the new file didn't load; the old settings stay
+}
+
+// String describes the change on one line, after its time
+func (c ConfigChange) String() string {
+	if c.Error != "" {
+		return fmt.Sprintf("%s not reloaded: %s", c.Time.Format("15:04:05"), c.Error)
+	}
+	return fmt.Sprintf("%s changed: %s", c.Time.Format("15:04:05"), strings.Join(c.Changed, ", "))
+}
+
+// Watch reports each change to the config file until ctx is done
+func (h *ConfigHandler) Watch(ctx context.Context, loader *config.Loader) (<-chan ConfigChange, error) {
+	changes, err := loader.Watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan ConfigChange)
+	go func() {
+		defer close(out)
+		for c := range changes {
+			change := ConfigChange{Time: time.Now(), Changed: c.Changed}
+			if c.Err != nil {
+				change.Error = c.Err.Error()
+			}
+			select {
+			case out <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
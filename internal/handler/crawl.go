@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/pool"
+	"github.com/blacksilver/termplate-go/internal/service/example"
+)
+
+type CrawlInput struct {
+	Root    string
+	Workers int
+	Rate    float64       // Files started per second; 0 is unlimited
+	Delay   time.Duration // Simulated work per file
+
+	// Unordered gives entries as files finish rather than in the order
+	// they're listed, which is the same from run to run
+	Unordered bool
+
+	// Progress, if set, is told the number of files once they are listed
+	// and then counts each file done, from several goroutines at once
+	Progress CrawlProgress
+}
+
+// CrawlProgress follows a crawl; *progress.Bar implements it
+type CrawlProgress interface {
+	SetTotal(n int)
+	Add(n int)
+}
+
+// CrawlEntry is the result for one file
+type CrawlEntry struct {
+	Path   string          `json:"path" yaml:"path"`
+	Size   config.ByteSize `json:"size" yaml:"size"`
+	SHA256 string          `json:"sha256,omitempty" yaml:"sha256,omitempty" table:"SHA-256"`
+	Status bulk.Status     `json:"status" yaml:"status"`
+	Error  string          `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ID identifies the entry in quiet output
+func (e CrawlEntry) ID() string {
+	return e.Path
+}
+
+type CrawlOutput struct {
+	Files []CrawlEntry
+}
+
+// Err returns why the crawl stopped early, if it was canceled, or else a
+// partial failure error when any file failed
+func (o *CrawlOutput) Err(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	failed := 0
+	for _, f := range o.Files {
+		if f.Status == bulk.StatusFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return model.NewPartialFailureError(failed, len(o.Files))
+	}
+	return nil
+}
+
+// CrawlHandler hashes the files of a directory tree concurrently
+type CrawlHandler struct {
+	service *example.Service
+}
+
+// NewCrawlHandler creates a new crawl handler
+func NewCrawlHandler() *CrawlHandler {
+	return &CrawlHandler{
+		service: example.NewService(),
+	}
+}
+
+// Crawl hashes every file under in.Root on a worker pool. When ctx is
+// canceled it stops starting files and returns what it has, with the rest
+// marked skipped; see CrawlOutput.Err.
+func (h *CrawlHandler) Crawl(ctx context.Context, in CrawlInput) (*CrawlOutput, error) {
+	out := &CrawlOutput{}
+	err := h.Stream(ctx, in, func(entry CrawlEntry) bool {
+		out.Files = append(out.Files, entry)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Stream is Crawl, giving each file's entry to emit as soon as it's ready
+// rather than returning them all at the end: in the order the files are
+// listed or, with in.Unordered, as they finish. It stops when emit
+// returns false.
+func (h *CrawlHandler) Stream(ctx context.Context, in CrawlInput, emit func(CrawlEntry) bool) error {
+	if in.Root == "" {
+		return model.NewValidationError("dir", "a directory is required")
+	}
+	paths, err := h.service.ListFiles(ctx, in.Root)
+	if err != nil {
+		return err
+	}
+
+	type hashed struct {
+		size int64
+		sum  string
+	}
+	if in.Progress != nil {
+		in.Progress.SetTotal(len(paths))
+	}
+	opts := pool.Options{Workers: in.Workers, Rate: in.Rate, Unordered: in.Unordered}
+	hash := func(ctx context.Context, path string) (hashed, error) {
+		size, sum, err := h.service.HashFile(ctx, path, in.Delay)
+		if in.Progress != nil {
+			in.Progress.Add(1)
+		}
+		return hashed{size, sum}, err
+	}
+	pool.Stream(ctx, opts, paths, hash, func(i int, r pool.Result[hashed]) bool {
+		entry := CrawlEntry{Path: paths[i], Status: bulk.StatusSucceeded}
+		switch {
+		case !r.Done || (r.Err != nil && ctx.Err() != nil):
+			// Not started, or interrupted mid-file, before ctx was canceled
+			entry.Status, entry.Error = bulk.StatusSkipped, context.Cause(ctx).Error()
+		case r.Err != nil:
+			entry.Status, entry.Error = bulk.StatusFailed, r.Err.Error()
+		default:
+			entry.Size, entry.SHA256 = config.ByteSize(r.Value.size), r.Value.sum
+		}
+		return emit(entry)
+	})
+	return nil
+}
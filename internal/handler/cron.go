@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/schedule"
+)
+
+// CronJob is a scheduled job and its history
+type CronJob struct {
+	Name       string        `json:"name" yaml:"name" table:"Name"`
+	Command    string        `json:"command" yaml:"command" table:"Command"`
+	Every      time.Duration `json:"every_ns" yaml:"every" table:"Every"`
+	LastRun    time.Time     `json:"last_run" yaml:"last_run" table:"Last Run"`
+	LastStatus string        `json:"last_status,omitempty" yaml:"last_status,omitempty" table:"Status"`
+	NextRun    time.Time     `json:"next_run" yaml:"next_run" table:"Next Run"`
+	Runs       int           `json:"runs" yaml:"runs" table:"Runs"`
+	Failures   int           `json:"failures" yaml:"failures" table:"Failures"`
+	Missed     int           `json:"missed" yaml:"missed" table:"Missed"`
+	LastError  string        `json:"last_error,omitempty" yaml:"last_error,omitempty" table:"Last Error"`
+}
+
+// CronHandler reports on the jobs the daemon runs on a schedule
+type CronHandler struct{}
+
+// NewCronHandler creates a new cron handler
+func NewCronHandler() *CronHandler {
+	return &CronHandler{}
+}
+
+// List returns the scheduled jobs by name: those the running daemon has,
+// or those of cfg, with the history kept in the state directory, when no
+// daemon is running
+func (h *CronHandler) List(ctx context.Context, cfg config.ScheduleConfig) ([]CronJob, error) {
+	states, err := h.states(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("loading job states: %w", err)
+	}
+	jobs := make([]CronJob, len(states))
+	for i, st := range states {
+		jobs[i] = CronJob(st)
+	}
+	return jobs, nil
+}
+
+func (h *CronHandler) states(ctx context.Context, cfg config.ScheduleConfig) ([]schedule.State, error) {
+	socket, err := daemon.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	states, err := daemon.Jobs(ctx, socket)
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return schedule.List(cfg)
+	}
+	return states, err
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/daemon"
+	"github.com/blacksilver/termplate-go/internal/schedule"
+	"github.com/blacksilver/termplate-go/pkg/version"
+)
+
+// daemonStartTimeout is how long Start waits for a background daemon to
+// answer
+const daemonStartTimeout = 5 * time.Second
+
+type DaemonStartInput struct {
+	Foreground bool
+	Run        daemon.RunFunc // Runs client command lines in this process
+	Args       []string       // Arguments that start the daemon in the foreground
+	Schedule   config.ScheduleConfig
+}
+
+// DaemonHandler starts, stops, and inspects the background daemon
+type DaemonHandler struct{}
+
+// NewDaemonHandler creates a new daemon handler
+func NewDaemonHandler() *DaemonHandler {
+	return &DaemonHandler{}
+}
+
+// Start runs the daemon. In the foreground it serves until ctx is done or
+// it is stopped, and returns nil status; otherwise it starts a background
+// process and returns its status once it answers.
+func (h *DaemonHandler) Start(ctx context.Context, in DaemonStartInput) (*daemon.Status, error) {
+	socket, err := daemon.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	if status, err := daemon.GetStatus(ctx, socket); err == nil {
+		return nil, fmt.Errorf("%w (pid %d)", daemon.ErrRunning, status.PID)
+	}
+
+	if in.Foreground {
+		srv := daemon.NewServer(socket, version.Version, in.Run)
+		srv.WithScheduler(schedule.New(in.Schedule, srv.RunExclusive))
+		if err := srv.Serve(ctx); err != nil {
+			return nil, fmt.Errorf("serving: %w", err)
+		}
+		return nil, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("finding executable: %w", err)
+	}
+	logFile, err := daemon.LogPath()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := daemon.Spawn(exe, in.Args, logFile); err != nil {
+		return nil, err
+	}
+	status, err := daemon.WaitReady(ctx, socket, daemonStartTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%w; see %s", err, logFile)
+	}
+	return status, nil
+}
+
+// Stop asks the daemon to exit. It returns daemon.ErrNotRunning when there
+// is none.
+func (h *DaemonHandler) Stop(ctx context.Context) error {
+	socket, err := daemon.SocketPath()
+	if err != nil {
+		return err
+	}
+	if err := daemon.Stop(ctx, socket); err != nil {
+		return fmt.Errorf("stopping daemon: %w", err)
+	}
+	return nil
+}
+
+// Status returns the status of the running daemon, or daemon.ErrNotRunning
+func (h *DaemonHandler) Status(ctx context.Context) (*daemon.Status, error) {
+	socket, err := daemon.SocketPath()
+	if err != nil {
+		return nil, err
+	}
+	status, err := daemon.GetStatus(ctx, socket)
+	if err != nil {
+		return nil, fmt.Errorf("getting daemon status: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/service/shellenv"
+)
+
+// DefaultExportKeys are the settings env export prints when no keys are
+// given: the API endpoint and credentials other tools need
+var DefaultExportKeys = []string{"api.base_url", "api.token", "api.key", "api.secret"}
+
+type EnvExportInput struct {
+	Shell  string
+	Keys   []string // Config keys; DefaultExportKeys when empty
+	Prefix string   // Variable name prefix, e.g. TERMPLATE
+	Loader *config.Loader
+}
+
+// EnvHandler exports configuration to the shell environment
+type EnvHandler struct {
+	service *shellenv.Service
+}
+
+// NewEnvHandler creates a new env handler
+func NewEnvHandler() *EnvHandler {
+	return &EnvHandler{
+		service: shellenv.NewService(),
+	}
+}
+
+// Export returns statements that set the selected config values as
+// environment variables. Default keys that are empty are skipped; keys
+// asked for explicitly are always set.
+func (h *EnvHandler) Export(_ context.Context, in EnvExportInput) (string, error) {
+	if !slices.Contains(shellenv.Shells, in.Shell) {
+		return "", model.NewValidationError("shell",
+			fmt.Sprintf("unsupported shell %q (supported: %s)", in.Shell, strings.Join(shellenv.Shells, ", ")))
+	}
+
+	keys, explicit := in.Keys, len(in.Keys) > 0
+	if !explicit {
+		keys = DefaultExportKeys
+	}
+
+	vars := make([]shellenv.Var, 0, len(keys))
+	for _, key := range keys {
+		value, err := in.Loader.Value(key)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", key, err)
+		}
+		if value == "" && !explicit {
+			continue
+		}
+		vars = append(vars, shellenv.Var{Name: shellenv.VarName(in.Prefix, key), Value: value})
+	}
+
+	out, err := h.service.Export(in.Shell, vars)
+	if err != nil {
+		return "", fmt.Errorf("rendering exports: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+)
+
+// Event is an event from the event log
+type Event struct {
+	ID       int64             `json:"id" yaml:"id" table:"ID"`
+	Time     time.Time         `json:"time" yaml:"time" table:"Time"`
+	Severity string            `json:"severity" yaml:"severity" table:"Severity"`
+	Source   string            `json:"source" yaml:"source" table:"Source"`
+	Message  string            `json:"message" yaml:"message" table:"Message"`
+	Attrs    map[string]string `json:"attrs,omitempty" yaml:"attrs,omitempty" table:"-"`
+}
+
+// String formats the event like a log line: time, severity, source,
+// message, and attributes as key=value
+func (e Event) String() string {
+	s := fmt.Sprintf("%s %-7s %-7s %s", e.Time.Local().Format(time.DateTime), strings.ToUpper(e.Severity), e.Source, e.Message)
+	for _, k := range slices.Sorted(maps.Keys(e.Attrs)) {
+		s += " " + k + "=" + strconv.Quote(e.Attrs[k])
+	}
+	return s
+}
+
+// EventFilter picks the events to show
+type EventFilter struct {
+	Severity events.Severity // The least severe shown; all when empty
+	Source   string          // Only events from this source; all when empty
+	Since    time.Time       // Only events at or after this time; all when zero
+}
+
+func (f EventFilter) match(e events.Event) bool {
+	return (f.Severity == "" || e.Severity.AtLeast(f.Severity)) &&
+		(f.Source == "" || strings.EqualFold(e.Source, f.Source)) &&
+		!e.Time.Before(f.Since)
+}
+
+// EventsHandler lists and follows the event log
+type EventsHandler struct{}
+
+// NewEventsHandler creates a new events handler
+func NewEventsHandler() *EventsHandler {
+	return &EventsHandler{}
+}
+
+// List returns the kept events matching f, oldest first, and the ID of the
+// last event kept, to follow from
+func (h *EventsHandler) List(_ context.Context, f EventFilter) ([]Event, int64, error) {
+	list, err := events.List()
+	if err != nil {
+		return nil, 0, fmt.Errorf("loading events: %w", err)
+	}
+	var (
+		matched []Event
+		last    int64
+	)
+	for _, e := range list {
+		last = e.ID
+		if f.match(e) {
+			matched = append(matched, event(e))
+		}
+	}
+	return matched, last, nil
+}
+
+// Follow sends the events matching f recorded after the one with ID after,
+// until ctx is done
+func (h *EventsHandler) Follow(ctx context.Context, f EventFilter, after int64) (<-chan Event, error) {
+	in, err := events.Follow(ctx, after)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for e := range in {
+			if !f.match(e) {
+				continue
+			}
+			select {
+			case out <- event(e):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func event(e events.Event) Event {
+	return Event{
+		ID:       e.ID,
+		Time:     e.Time,
+		Severity: string(e.Severity),
+		Source:   e.Source,
+		Message:  e.Message,
+		Attrs:    e.Attrs,
+	}
+}
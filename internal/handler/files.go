@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/service/files"
+	"github.com/blacksilver/termplate-go/internal/service/scaffold"
+)
+
+type LintTemplatesInput struct {
+	Dir        string
+	ValuesFile string
+	Patterns   []string
+	LeftDelim  string
+	RightDelim string
+}
+
+// FilesHandler handles file operations
+type FilesHandler struct {
+	service *files.Service
+}
+
+// NewFilesHandler creates a new files handler
+func NewFilesHandler() *FilesHandler {
+	return &FilesHandler{
+		service: files.NewService(),
+	}
+}
+
+// LintTemplates lints every template in a directory
+func (h *FilesHandler) LintTemplates(ctx context.Context, in LintTemplatesInput) (*files.LintResult, error) {
+	info, err := os.Stat(in.Dir)
+	if err != nil || !info.IsDir() {
+		return nil, model.NewValidationError("dir", fmt.Sprintf("%s is not a directory", in.Dir))
+	}
+	if (in.LeftDelim == "") != (in.RightDelim == "") {
+		return nil, model.NewValidationError("delims", "left and right delimiters must be set together")
+	}
+
+	var values map[string]interface{}
+	if in.ValuesFile != "" {
+		values, err = readValues(in.ValuesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := h.service.LintTemplates(ctx, files.LintOptions{
+		Dir:        in.Dir,
+		Patterns:   in.Patterns,
+		Values:     values,
+		Funcs:      scaffold.FuncNames(),
+		LeftDelim:  in.LeftDelim,
+		RightDelim: in.RightDelim,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("linting templates: %w", err)
+	}
+
+	return result, nil
+}
+
+// readValues reads a YAML or JSON values file
+func readValues(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading values file: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, model.NewValidationError("values", fmt.Sprintf("parsing %s: %v", path, err))
+	}
+	return values, nil
+}
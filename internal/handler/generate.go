@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/service/mockgen"
+)
+
+type MocksInput struct {
+	Dirs       []string
+	Interfaces []string
+	Package    string
+	DryRun     bool
+}
+
+// GenerateHandler handles code generation
+type GenerateHandler struct {
+	mocks *mockgen.Service
+}
+
+// NewGenerateHandler creates a new generate handler
+func NewGenerateHandler() *GenerateHandler {
+	return &GenerateHandler{
+		mocks: mockgen.NewService(),
+	}
+}
+
+var packageNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Mocks generates mocks for the interfaces in the given package directories
+func (h *GenerateHandler) Mocks(ctx context.Context, in MocksInput) (*mockgen.Result, error) {
+	if in.Package != "" && !packageNameRe.MatchString(in.Package) {
+		return nil, model.NewValidationError("package", fmt.Sprintf("invalid package name %q", in.Package))
+	}
+
+	result, err := h.mocks.Generate(ctx, mockgen.Options{
+		Dirs:       in.Dirs,
+		Interfaces: in.Interfaces,
+		Package:    in.Package,
+		DryRun:     in.DryRun,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generating mocks: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+type GreetBatchInput struct {
+	Names     []string
+	Uppercase bool
+	Options   bulk.Options
+}
+
+type GreetBatchOutput struct {
+	Messages []string
+	Report   *bulk.Report
+}
+
+// GreetBatch greets several users, continuing past invalid names
+func (h *GreetHandler) GreetBatch(ctx context.Context, in GreetBatchInput) (*GreetBatchOutput, error) {
+	if len(in.Names) == 0 {
+		return nil, model.NewValidationError("names", "at least one name is required")
+	}
+
+	out := &GreetBatchOutput{}
+	staged := &greetings{out: out}
+	items := make([]bulk.Item, 0, len(in.Names))
+	for i, name := range in.Names {
+		name := name
+		items = append(items, bulk.Item{
+			ID: fmt.Sprintf("%d:%s", i+1, name),
+			Apply: func(ctx context.Context) error {
+				if strings.TrimSpace(name) == "" {
+					return model.NewValidationError("name", "name is required")
+				}
+				message, err := h.service.GenerateGreeting(ctx, name, in.Uppercase)
+				if err != nil {
+					return fmt.Errorf("generating greeting: %w", err)
+				}
+				staged.add(message)
+				return nil
+			},
+		})
+	}
+
+	report, err := bulk.NewRunner(in.Options).WithTransactor(staged).Run(ctx, items)
+	if err != nil {
+		return nil, fmt.Errorf("running batch: %w", err)
+	}
+	out.Report = report
+
+	return out, nil
+}
+
+// greetings is the batch's transactional backend: in a transaction,
+// messages are staged, kept on commit and dropped on rollback
+type greetings struct {
+	out    *GreetBatchOutput
+	open   bool
+	staged []string
+}
+
+func (g *greetings) Begin(context.Context) (bulk.Tx, error) {
+	g.open, g.staged = true, nil
+	return g, nil
+}
+
+func (g *greetings) Commit() error {
+	g.out.Messages = append(g.out.Messages, g.staged...)
+	g.open, g.staged = false, nil
+	return nil
+}
+
+func (g *greetings) Rollback() error {
+	g.open, g.staged = false, nil
+	return nil
+}
+
+func (g *greetings) add(message string) {
+	if g.open {
+		g.staged = append(g.staged, message)
+	} else {
+		g.out.Messages = append(g.out.Messages, message)
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/poll"
+)
+
+// OpsWaitInput is an operation to wait for
+type OpsWaitInput struct {
+	ID string
+
+	// OnStatus, when set, is called with the status the API reports at
+	// each check
+	OnStatus func(status string)
+}
+
+// OpsHandler waits for long-running operations the API tracks by ID
+type OpsHandler struct{}
+
+// NewOpsHandler creates a new ops handler
+func NewOpsHandler() *OpsHandler {
+	return &OpsHandler{}
+}
+
+// Wait polls the operation at api.operations_path until it finishes and
+// returns the resource it produced. A failed operation is a
+// *poll.FailedError.
+func (h *OpsHandler) Wait(ctx context.Context, cfg config.APIConfig, input OpsWaitInput) (interface{}, error) {
+	if input.ID == "" {
+		return nil, model.NewValidationError("id", "an operation ID is required")
+	}
+	if err := offline.Check("waiting for operation " + input.ID); err != nil {
+		return nil, err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	opURL := client.URL(strings.ReplaceAll(cfg.OperationsPath, "{id}", url.PathEscape(input.ID)))
+	state, err := poll.Wait(ctx, poll.HTTPCheck(client.HTTP, opURL), poll.Options{
+		Interval:    cfg.PollInterval,
+		MaxInterval: cfg.PollMaxInterval,
+		OnCheck: func(s poll.State) {
+			if input.OnStatus != nil && s.Status != "" {
+				input.OnStatus(s.Status)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("waiting for operation %s: %w", input.ID, err)
+	}
+
+	var resource interface{}
+	if err := json.Unmarshal(state.Result, &resource); err != nil {
+		return nil, fmt.Errorf("parsing result of operation %s: %w", input.ID, err)
+	}
+	return resource, nil
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/outbox"
+)
+
+// OutboxEntry is a request waiting in the outbox
+type OutboxEntry struct {
+	ID        int       `json:"id" yaml:"id" table:"ID"`
+	Method    string    `json:"method" yaml:"method"`
+	URL       string    `json:"url" yaml:"url" table:"URL"`
+	Queued    time.Time `json:"queued" yaml:"queued"`
+	Attempts  int       `json:"attempts" yaml:"attempts"`
+	LastError string    `json:"last_error,omitempty" yaml:"last_error,omitempty" table:"Last Error"`
+}
+
+// OutboxFlushOutput is what a flush sent
+type OutboxFlushOutput struct {
+	Sent    int          `json:"sent" yaml:"sent"`
+	Pending int          `json:"pending" yaml:"pending"`
+	Failed  *OutboxEntry `json:"failed,omitempty" yaml:"failed,omitempty"` // The request that stopped the flush
+}
+
+// OutboxHandler lists, sends, and drops the requests queued in the outbox
+type OutboxHandler struct{}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler() *OutboxHandler {
+	return &OutboxHandler{}
+}
+
+// List returns the queued requests, oldest first
+func (h *OutboxHandler) List(_ context.Context) ([]OutboxEntry, error) {
+	entries, err := outbox.List()
+	if err != nil {
+		return nil, fmt.Errorf("loading outbox: %w", err)
+	}
+	list := make([]OutboxEntry, len(entries))
+	for i, e := range entries {
+		list[i] = outboxEntry(e)
+	}
+	return list, nil
+}
+
+// Flush sends the queued requests to the API configured in cfg, in order,
+// stopping at the first that fails
+func (h *OutboxHandler) Flush(ctx context.Context, cfg config.APIConfig) (*OutboxFlushOutput, error) {
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Credentials aren't stored with queued requests; the client adds them
+	res, err := outbox.Flush(ctx, client.HTTP.Do)
+	if err != nil {
+		return nil, fmt.Errorf("flushing outbox: %w", err)
+	}
+
+	out := &OutboxFlushOutput{Sent: res.Sent, Pending: res.Pending}
+	if res.Failed != nil {
+		failed := outboxEntry(*res.Failed)
+		out.Failed = &failed
+	}
+	return out, nil
+}
+
+// Clear drops the queued requests with the given IDs, or all of them when
+// none are given, and returns how many were dropped
+func (h *OutboxHandler) Clear(ctx context.Context, ids []int) (int, error) {
+	n, err := outbox.Remove(ctx, ids...)
+	if err != nil {
+		return 0, fmt.Errorf("clearing outbox: %w", err)
+	}
+	return n, nil
+}
+
+func outboxEntry(e outbox.Entry) OutboxEntry {
+	return OutboxEntry{
+		ID:        e.ID,
+		Method:    e.Method,
+		URL:       e.URL,
+		Queued:    e.Created,
+		Attempts:  e.Attempts,
+		LastError: e.LastError,
+	}
+}
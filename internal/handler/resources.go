@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/apiclient"
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/service/apply"
+)
+
+// ResourceEntry is a live API resource
+type ResourceEntry struct {
+	Kind   string       `json:"kind" yaml:"kind"`
+	Name   string       `json:"name" yaml:"name" table:"Name"`
+	Labels model.Labels `json:"labels,omitempty" yaml:"labels,omitempty" table:"Labels"`
+}
+
+// ID identifies the resource by its kind/name in quiet output
+func (e ResourceEntry) ID() string {
+	return e.Kind + "/" + e.Name
+}
+
+// recentEvents is how many of a resource's latest events Describe returns
+const recentEvents = 10
+
+// ResourceDetail is a live resource in full, as describe shows it
+type ResourceDetail struct {
+	Kind        string
+	Name        string
+	Labels      model.Labels
+	Annotations map[string]string
+	Spec        map[string]interface{} // The fields apply manages
+	Status      interface{}            // What the API reports of the resource's state
+	Events      []ResourceEvent        // The most recent, oldest first
+}
+
+// ResourceEvent is something that happened to a resource
+type ResourceEvent struct {
+	Time    string `json:"time" yaml:"time"`
+	Type    string `json:"type" yaml:"type"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// newResourceDetail splits body into the resource's metadata, spec, and
+// status. A body with a spec field, as in Kubernetes, has its spec there;
+// otherwise the spec is the fields apply sets, all but name, labels,
+// annotations, metadata, and status.
+func newResourceDetail(kind, name string, body map[string]interface{}, events []map[string]interface{}) *ResourceDetail {
+	d := &ResourceDetail{Kind: kind, Name: name, Labels: apply.LabelsOf(body), Status: body["status"]}
+	annotations, _ := body["annotations"].(map[string]interface{})
+	if meta, ok := body["metadata"].(map[string]interface{}); ok && annotations == nil {
+		annotations, _ = meta["annotations"].(map[string]interface{})
+	}
+	if len(annotations) > 0 {
+		d.Annotations = make(map[string]string, len(annotations))
+		for k, v := range annotations {
+			d.Annotations[k] = fmt.Sprint(v)
+		}
+	}
+
+	if spec, ok := body["spec"].(map[string]interface{}); ok {
+		d.Spec = spec
+	} else {
+		d.Spec = map[string]interface{}{}
+		for k, v := range body {
+			switch k {
+			case "name", "labels", "annotations", "metadata", "status":
+			default:
+				d.Spec[k] = v
+			}
+		}
+	}
+
+	for _, e := range events {
+		d.Events = append(d.Events, ResourceEvent{
+			Time:    eventField(e, "time", "timestamp", "lastTimestamp", "created_at", "createdAt"),
+			Type:    eventField(e, "type", "level"),
+			Reason:  eventField(e, "reason"),
+			Message: eventField(e, "message", "note"),
+		})
+	}
+	// RFC 3339 times sort as strings
+	slices.SortStableFunc(d.Events, func(a, b ResourceEvent) int {
+		return strings.Compare(a.Time, b.Time)
+	})
+	if len(d.Events) > recentEvents {
+		d.Events = d.Events[len(d.Events)-recentEvents:]
+	}
+	return d
+}
+
+// eventField returns the first of fields an event sets
+func eventField(event map[string]interface{}, fields ...string) string {
+	for _, f := range fields {
+		if v, ok := event[f]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// ResourcesHandler reads and deletes the API resources apply manages
+type ResourcesHandler struct{}
+
+// NewResourcesHandler creates a new resources handler
+func NewResourcesHandler() *ResourcesHandler {
+	return &ResourcesHandler{}
+}
+
+// Get returns the live resources of kind whose labels match selector,
+// e.g. "env=prod,team!=core", or the named ones when names are given; an
+// empty selector matches every one
+func (h *ResourcesHandler) Get(ctx context.Context, cfg config.APIConfig, kind string, names []string, selector string) ([]ResourceEntry, error) {
+	if kind == "" {
+		return nil, model.NewValidationError("kind", "kind is required")
+	}
+	sel, err := model.ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if err := offline.Check("reading resources"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var live []apply.LiveResource
+	if len(names) == 0 {
+		if live, err = svc.Select(ctx, kind, sel); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range names {
+		body, err := svc.Get(ctx, kind, name)
+		if err != nil {
+			return nil, err
+		}
+		if labels := apply.LabelsOf(body); sel.Matches(labels) {
+			live = append(live, apply.LiveResource{Kind: kind, Name: name, Labels: labels, Body: body})
+		}
+	}
+
+	entries := make([]ResourceEntry, len(live))
+	for i, r := range live {
+		entries[i] = ResourceEntry{Kind: r.Kind, Name: r.Name, Labels: r.Labels}
+	}
+	return entries, nil
+}
+
+// Names returns the names of the live resources of kind, sorted, for
+// shell completion. They're cached as completion configures, so a tab
+// pressed again soon doesn't wait on the API.
+func (h *ResourcesHandler) Names(ctx context.Context, cfg *config.Config, kind string) ([]string, error) {
+	if kind == "" {
+		return nil, model.NewValidationError("kind", "kind is required")
+	}
+	api := cfg.API
+	// The names depend on the API, and on who asks
+	key := strings.Join([]string{"resources", api.BaseURL, api.ResourcePath, kind,
+		api.Token, api.Key, api.TokenCommand, api.OAuth.TokenURL, api.OAuth.ClientID}, "\x00")
+	return apiclient.Completions(ctx, cfg.Completion, key, func(ctx context.Context) ([]string, error) {
+		if err := offline.Check("reading resources"); err != nil {
+			return nil, err
+		}
+		svc, err := newApplyService(api)
+		if err != nil {
+			return nil, err
+		}
+		live, err := svc.Select(ctx, kind, model.Selector{})
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(live))
+		for i, r := range live {
+			names[i] = r.Name
+		}
+		slices.Sort(names)
+		return names, nil
+	})
+}
+
+// Describe returns the live resource kind/name in full, with its most
+// recent events
+func (h *ResourcesHandler) Describe(ctx context.Context, cfg config.APIConfig, kind, name string) (*ResourceDetail, error) {
+	switch {
+	case kind == "":
+		return nil, model.NewValidationError("kind", "kind is required")
+	case name == "":
+		return nil, model.NewValidationError("name", "name is required")
+	}
+	if err := offline.Check("reading resources"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	body, err := svc.Get(ctx, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	events, err := svc.Events(ctx, kind, name)
+	if err != nil {
+		return nil, err
+	}
+	return newResourceDetail(kind, name, body, events), nil
+}
+
+// Delete deletes the named resources of kind and reports how each went
+func (h *ResourcesHandler) Delete(ctx context.Context, cfg config.APIConfig, kind string, names []string, opts bulk.Options) (*bulk.Report, error) {
+	if err := offline.Check("deleting resources"); err != nil {
+		return nil, err
+	}
+	svc, err := newApplyService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	report, err := svc.Delete(ctx, kind, names, opts)
+	if err != nil {
+		return nil, fmt.Errorf("deleting %s: %w", kind, err)
+	}
+	return report, nil
+}
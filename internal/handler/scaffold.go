@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/prompt"
+	"github.com/blacksilver/termplate-go/internal/service/scaffold"
+)
+
+// GenerateOptions are shared by the project and command generators
+type GenerateOptions struct {
+	From        string
+	Vars        []string
+	Interactive bool
+	Force       bool
+	SkipHooks   bool
+	Yes         bool      // Run shell hooks without confirmation
+	HookOutput  io.Writer // Receives streamed hook output
+	Subsystems  []string  // Nil asks interactively or uses the template's defaults
+}
+
+type NewProjectInput struct {
+	Dir string
+	GenerateOptions
+
+	// Build scaffolding. Unset values fall back to the template's prompts
+	// and defaults.
+	BuildTool  string // make, task, or none
+	Docker     bool
+	Goreleaser bool
+}
+
+// buildTools maps --build-tool values to the cli template's variables
+var buildTools = map[string][]string{
+	"make": {"WithMakefile=true", "WithTaskfile=false"},
+	"task": {"WithMakefile=false", "WithTaskfile=true"},
+	"none": {"WithMakefile=false", "WithTaskfile=false"},
+}
+
+type NewCommandInput struct {
+	Name       string
+	ProjectDir string
+	GenerateOptions
+}
+
+// UpgradeInput describes an upgrade of a generated project
+type UpgradeInput struct {
+	Dir            string
+	From           string // Overrides the source recorded at generation time
+	Vars           []string
+	Interactive    bool
+	DryRun         bool
+	WriteConflicts bool
+}
+
+// RenameModuleInput describes a module path and binary rename
+type RenameModuleInput struct {
+	Dir      string
+	NewPath  string
+	OldPaths []string
+	Binary   string
+	DryRun   bool
+}
+
+type GenerateOutput struct {
+	Dir        string
+	Subsystems []string
+	Notes      []string
+	Files      []string
+	Hooks      []scaffold.HookResult
+}
+
+// HooksErr returns a partial failure error when any hook failed
+func (o *GenerateOutput) HooksErr() error {
+	failed := 0
+	for _, h := range o.Hooks {
+		if h.Status == scaffold.HookFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return model.NewPartialFailureError(failed, len(o.Hooks))
+	}
+	return nil
+}
+
+// ScaffoldHandler handles project and command generation
+type ScaffoldHandler struct {
+	service *scaffold.Service
+}
+
+// NewScaffoldHandler creates a new scaffold handler
+func NewScaffoldHandler() *ScaffoldHandler {
+	return &ScaffoldHandler{
+		service: scaffold.NewService(),
+	}
+}
+
+var (
+	commandNameRe = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	modulePathRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._~-]*(/[A-Za-z0-9._~-]+)*$`)
+	binaryNameRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// NewProject generates a new project into in.Dir
+func (h *ScaffoldHandler) NewProject(ctx context.Context, in NewProjectInput) (*GenerateOutput, error) {
+	if in.Dir == "" {
+		return nil, model.NewValidationError("dir", "directory is required")
+	}
+	if entries, err := os.ReadDir(in.Dir); err == nil && len(entries) > 0 && !in.Force {
+		return nil, model.NewValidationError("dir", fmt.Sprintf("%s is not empty (use --force to overwrite)", in.Dir))
+	}
+
+	abs, err := filepath.Abs(in.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", in.Dir, err)
+	}
+
+	if in.From == "" {
+		in.From = "cli"
+	}
+
+	// Flags come first so --var can still override them
+	var build []string
+	if in.BuildTool != "" {
+		vars, ok := buildTools[in.BuildTool]
+		if !ok {
+			return nil, model.NewValidationError("build-tool", "must be one of: make, task, none")
+		}
+		build = append(build, vars...)
+	}
+	if in.Docker {
+		build = append(build, "WithDocker=true")
+	}
+	if in.Goreleaser {
+		build = append(build, "WithGoreleaser=true")
+	}
+	in.Vars = append(build, in.Vars...)
+
+	return h.generate(ctx, abs, in.GenerateOptions, true, map[string]interface{}{
+		"ProjectName": filepath.Base(abs),
+	})
+}
+
+// NewCommand generates a new command into an existing project
+func (h *ScaffoldHandler) NewCommand(ctx context.Context, in NewCommandInput) (*GenerateOutput, error) {
+	if !commandNameRe.MatchString(in.Name) {
+		return nil, model.NewValidationError("name", "must be lowercase letters, digits, and hyphens")
+	}
+
+	module, err := scaffold.ModulePath(in.ProjectDir)
+	if err != nil {
+		return nil, model.NewValidationError("dir", fmt.Sprintf("%s is not a Go module: %v", in.ProjectDir, err))
+	}
+
+	if in.From == "" {
+		in.From = "command"
+	}
+	return h.generate(ctx, in.ProjectDir, in.GenerateOptions, false, map[string]interface{}{
+		"Module":         module,
+		"CommandName":    in.Name,
+		"CommandPackage": strings.ReplaceAll(in.Name, "-", ""),
+	})
+}
+
+func (h *ScaffoldHandler) generate(
+	ctx context.Context,
+	dest string,
+	opts GenerateOptions,
+	record bool,
+	builtins map[string]interface{},
+) (*GenerateOutput, error) {
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("opening template registry: %w", err)
+	}
+	src, err := registry.Resolve(ctx, opts.From)
+	if err != nil {
+		return nil, fmt.Errorf("opening template %s: %w", opts.From, err)
+	}
+
+	overrides, err := scaffold.ParseVars(opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req := scaffold.GenerateRequest{
+		Source:    src,
+		Dest:      dest,
+		Builtins:  builtins,
+		Overrides: overrides,
+		Force:     opts.Force,
+		SkipHooks: opts.SkipHooks,
+		Trust:     opts.Yes,
+		Output:    opts.HookOutput,
+		From:      registry.Canonical(opts.From),
+		Record:    record,
+
+		Subsystems: opts.Subsystems,
+	}
+	if opts.Interactive {
+		req.Prompter = prompt.NewStdio()
+	}
+
+	result, err := h.service.Generate(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("generating from %s: %w", opts.From, err)
+	}
+
+	return &GenerateOutput{
+		Dir:        dest,
+		Subsystems: result.Subsystems,
+		Notes:      result.Notes,
+		Files:      result.Files,
+		Hooks:      result.Hooks,
+	}, nil
+}
+
+// Upgrade merges the current version of a project's template into the project
+func (h *ScaffoldHandler) Upgrade(ctx context.Context, in UpgradeInput) (*scaffold.UpgradeResult, error) {
+	if in.Dir == "" {
+		in.Dir = "."
+	}
+	rec, err := scaffold.LoadRecord(in.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s was not generated by termplate or predates upgrades: %w", in.Dir, err)
+	}
+
+	from := in.From
+	if from == "" {
+		from = rec.Source
+	}
+	if from == "" {
+		return nil, model.NewValidationError("from", "no source recorded; pass --from")
+	}
+
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("opening template registry: %w", err)
+	}
+	src, err := registry.ResolveLatest(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("opening template %s: %w", from, err)
+	}
+
+	overrides, err := scaffold.ParseVars(in.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	req := scaffold.UpgradeRequest{
+		Dir:            in.Dir,
+		Source:         src,
+		From:           registry.Canonical(from),
+		Overrides:      overrides,
+		DryRun:         in.DryRun,
+		WriteConflicts: in.WriteConflicts,
+	}
+	if in.Interactive {
+		req.Prompter = prompt.NewStdio()
+	}
+
+	result, err := h.service.Upgrade(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("upgrading from %s: %w", from, err)
+	}
+	return result, nil
+}
+
+// RenameModule changes a project's module path and, optionally, its binary name
+func (h *ScaffoldHandler) RenameModule(ctx context.Context, in RenameModuleInput) (*scaffold.RenameResult, error) {
+	if !modulePathRe.MatchString(in.NewPath) {
+		return nil, model.NewValidationError("path", fmt.Sprintf("invalid module path %q", in.NewPath))
+	}
+	if in.Binary != "" && !binaryNameRe.MatchString(in.Binary) {
+		return nil, model.NewValidationError("binary-name", fmt.Sprintf("invalid binary name %q", in.Binary))
+	}
+	if in.Dir == "" {
+		in.Dir = "."
+	}
+
+	result, err := h.service.RenameModule(ctx, scaffold.RenameRequest{
+		Dir:      in.Dir,
+		NewPath:  in.NewPath,
+		OldPaths: in.OldPaths,
+		Binary:   in.Binary,
+		DryRun:   in.DryRun,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("renaming module: %w", err)
+	}
+	return result, nil
+}
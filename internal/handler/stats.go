@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/usage"
+)
+
+// CommandStat is the recorded usage of one command
+type CommandStat struct {
+	Command  string        `json:"command" yaml:"command" table:"Command"`
+	Runs     int           `json:"runs" yaml:"runs" table:"Runs"`
+	Failures int           `json:"failures" yaml:"failures" table:"Failures"`
+	Average  time.Duration `json:"avg_ns" yaml:"avg" table:"Avg"`
+	Max      time.Duration `json:"max_ns" yaml:"max" table:"Max"`
+	LastUsed time.Time     `json:"last_used" yaml:"last_used" table:"Last Used"`
+}
+
+// ID identifies the command in quiet output
+func (s CommandStat) ID() string {
+	return s.Command
+}
+
+// CommandStatsOutput ranks commands by use and by average duration
+type CommandStatsOutput struct {
+	MostUsed []CommandStat `json:"most_used" yaml:"most_used"`
+	Slowest  []CommandStat `json:"slowest" yaml:"slowest"`
+}
+
+// StatsHandler reports local command usage
+type StatsHandler struct{}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler() *StatsHandler {
+	return &StatsHandler{}
+}
+
+// Commands returns up to limit commands in each ranking; 0 is no limit
+func (h *StatsHandler) Commands(_ context.Context, limit int) (*CommandStatsOutput, error) {
+	recorded, err := usage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading usage: %w", err)
+	}
+
+	stats := make([]CommandStat, len(recorded))
+	for i, s := range recorded {
+		stats[i] = CommandStat{
+			Command:  s.Command,
+			Runs:     s.Count,
+			Failures: s.Failures,
+			Average:  s.Average().Round(100 * time.Microsecond),
+			Max:      s.Max.Round(100 * time.Microsecond),
+			LastUsed: s.LastUsed,
+		}
+	}
+
+	return &CommandStatsOutput{
+		MostUsed: ranked(stats, limit, func(a, b CommandStat) bool { return a.Runs > b.Runs }),
+		Slowest:  ranked(stats, limit, func(a, b CommandStat) bool { return a.Average > b.Average }),
+	}, nil
+}
+
+// ResetStats deletes the recorded usage
+func (h *StatsHandler) ResetStats(_ context.Context) error {
+	if err := usage.Reset(); err != nil {
+		return fmt.Errorf("resetting usage: %w", err)
+	}
+	return nil
+}
+
+// ranked returns a copy of stats sorted by less, ties by command, cut to
+// limit
+func ranked(stats []CommandStat, limit int, less func(a, b CommandStat) bool) []CommandStat {
+	list := append([]CommandStat{}, stats...)
+	sort.SliceStable(list, func(i, j int) bool {
+		if less(list[i], list[j]) {
+			return true
+		}
+		if less(list[j], list[i]) {
+			return false
+		}
+		return list[i].Command < list[j].Command
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blacksilver/termplate-go/internal/service/scaffold"
+)
+
+type AddTemplateInput struct {
+	Name        string
+	Source      string
+	Description string
+}
+
+// TemplateHandler manages the scaffold template registry
+type TemplateHandler struct{}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{}
+}
+
+// List returns the built-in and registered templates
+func (h *TemplateHandler) List(_ context.Context) ([]scaffold.ListEntry, error) {
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("opening template registry: %w", err)
+	}
+	return registry.List(), nil
+}
+
+// Add registers a template source under a name
+func (h *TemplateHandler) Add(ctx context.Context, in AddTemplateInput) error {
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return fmt.Errorf("opening template registry: %w", err)
+	}
+	if err := registry.Add(ctx, scaffold.Entry{
+		Name:        in.Name,
+		Source:      in.Source,
+		Description: in.Description,
+	}); err != nil {
+		return fmt.Errorf("adding template: %w", err)
+	}
+	if err := registry.Save(); err != nil {
+		return fmt.Errorf("saving template registry: %w", err)
+	}
+	return nil
+}
+
+// Remove unregisters a template
+func (h *TemplateHandler) Remove(_ context.Context, name string) error {
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return fmt.Errorf("opening template registry: %w", err)
+	}
+	if err := registry.Remove(name); err != nil {
+		return fmt.Errorf("removing template: %w", err)
+	}
+	if err := registry.Save(); err != nil {
+		return fmt.Errorf("saving template registry: %w", err)
+	}
+	return nil
+}
+
+// Update re-fetches git templates and returns the names updated
+func (h *TemplateHandler) Update(ctx context.Context, names []string) ([]string, error) {
+	registry, err := scaffold.OpenRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("opening template registry: %w", err)
+	}
+	updated, err := registry.Update(ctx, names)
+	if err != nil {
+		return updated, fmt.Errorf("updating templates: %w", err)
+	}
+	return updated, nil
+}
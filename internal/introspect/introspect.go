@@ -0,0 +1,162 @@
+// Package introspect describes a cobra command tree as data, so GUIs, docs
+// sites, and assistants can discover and drive the CLI without parsing
+// help text.
+package introspect
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// SchemaVersion is bumped when fields are renamed or removed, so consumers
+// can detect descriptions they don't understand. New fields don't bump it.
+const SchemaVersion = 1
+
+// ExitCodesAnnotation is the cobra command annotation listing the exit
+// codes a command returns besides ok and error, comma separated ("3,5").
+// The locked code is added for commands holding a lock.
+const ExitCodesAnnotation = "termplate.exit_codes"
+
+// ExitCodes formats codes as an ExitCodesAnnotation value
+func ExitCodes(codes ...int) string {
+	s := make([]string, len(codes))
+	for i, code := range codes {
+		s[i] = strconv.Itoa(code)
+	}
+	return strings.Join(s, ",")
+}
+
+// CLI describes a program and its commands
+type CLI struct {
+	SchemaVersion int                  `json:"schema_version" yaml:"schema_version"`
+	Name          string               `json:"name" yaml:"name"`
+	Version       string               `json:"version" yaml:"version"`
+	ExitCodes     []model.ExitCodeInfo `json:"exit_codes" yaml:"exit_codes"`
+	Command       Command              `json:"command" yaml:"command"`
+}
+
+// Command describes a command and its subcommands
+type Command struct {
+	Name        string            `json:"name" yaml:"name"`
+	Path        string            `json:"path" yaml:"path"` // Full command line, e.g. "termplate template add"
+	Usage       string            `json:"usage" yaml:"usage"`
+	Aliases     []string          `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Short       string            `json:"short" yaml:"short"`
+	Long        string            `json:"long,omitempty" yaml:"long,omitempty"`
+	Example     string            `json:"example,omitempty" yaml:"example,omitempty"`
+	Runnable    bool              `json:"runnable" yaml:"runnable"` // False for groups that only hold subcommands
+	Hidden      bool              `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Deprecated  string            `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	ExitCodes   []int             `json:"exit_codes" yaml:"exit_codes"`
+	Flags       []Flag            `json:"flags" yaml:"flags"`
+	Commands    []Command         `json:"commands,omitempty" yaml:"commands,omitempty"`
+}
+
+// Flag describes a flag. Persistent flags apply to the command's
+// subcommands too and are listed only where they are defined.
+type Flag struct {
+	Name       string `json:"name" yaml:"name"`
+	Shorthand  string `json:"shorthand,omitempty" yaml:"shorthand,omitempty"`
+	Type       string `json:"type" yaml:"type"` // pflag type: string, bool, int, duration, stringArray, ...
+	Default    string `json:"default" yaml:"default"`
+	Usage      string `json:"usage" yaml:"usage"`
+	Persistent bool   `json:"persistent" yaml:"persistent"`
+	Required   bool   `json:"required,omitempty" yaml:"required,omitempty"`
+	Hidden     bool   `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Deprecated string `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+}
+
+// Describe returns the description of root and every command under it.
+// Help commands and flags are left out.
+func Describe(root *cobra.Command, version string) *CLI {
+	return &CLI{
+		SchemaVersion: SchemaVersion,
+		Name:          root.Name(),
+		Version:       version,
+		ExitCodes:     model.ExitCodes,
+		Command:       describeCommand(root),
+	}
+}
+
+func describeCommand(c *cobra.Command) Command {
+	d := Command{
+		Name:        c.Name(),
+		Path:        c.CommandPath(),
+		Usage:       c.UseLine(),
+		Aliases:     c.Aliases,
+		Short:       c.Short,
+		Long:        c.Long,
+		Example:     c.Example,
+		Runnable:    c.Runnable(),
+		Hidden:      c.Hidden,
+		Deprecated:  c.Deprecated,
+		Annotations: c.Annotations,
+		ExitCodes:   exitCodes(c),
+		Flags:       []Flag{},
+	}
+
+	persistent := c.PersistentFlags()
+	c.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Name == "help" {
+			return
+		}
+		d.Flags = append(d.Flags, describeFlag(f, persistent.Lookup(f.Name) != nil))
+	})
+
+	for _, sub := range c.Commands() {
+		if sub.Name() == "help" {
+			continue
+		}
+		d.Commands = append(d.Commands, describeCommand(sub))
+	}
+	return d
+}
+
+func describeFlag(f *pflag.Flag, persistent bool) Flag {
+	_, required := f.Annotations[cobra.BashCompOneRequiredFlag]
+	return Flag{
+		Name:       f.Name,
+		Shorthand:  f.Shorthand,
+		Type:       f.Value.Type(),
+		Default:    f.DefValue,
+		Usage:      f.Usage,
+		Persistent: persistent,
+		Required:   required,
+		Hidden:     f.Hidden,
+		Deprecated: f.Deprecated,
+	}
+}
+
+// exitCodes returns the exit codes c may return, in order. Groups without
+// a Run only print help, so they return ok, error, or usage.
+func exitCodes(c *cobra.Command) []int {
+	codes := map[int]bool{model.ExitOK: true, model.ExitError: true, model.ExitUsage: true}
+	if c.Runnable() {
+		// Any command can be interrupted or outlive --timeout
+		codes[model.ExitTimeout] = true
+		codes[model.ExitInterrupted] = true
+		if c.Annotations[lock.Annotation] != "" {
+			codes[model.ExitLocked] = true
+		}
+		for _, s := range strings.Split(c.Annotations[ExitCodesAnnotation], ",") {
+			if code, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+				codes[code] = true
+			}
+		}
+	}
+
+	list := make([]int, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	sort.Ints(list)
+	return list
+}
@@ -0,0 +1,111 @@
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+const (
+	// lockName serializes changes to the credentials file
+	lockName = "credentials"
+	// lockWait is how long to wait for another invocation's change
+	lockWait = 10 * time.Second
+)
+
+// filePath returns the file secrets are kept in with the File store
+func filePath() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "credentials.json"), nil
+}
+
+func fileGet(_ context.Context, key string) (string, error) {
+	path, err := filePath()
+	if err != nil {
+		return "", err
+	}
+	secrets, err := readFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func fileSet(ctx context.Context, key, secret string) error {
+	return updateFile(ctx, func(secrets map[string]string) {
+		secrets[key] = secret
+	})
+}
+
+func fileDelete(ctx context.Context, key string) error {
+	return updateFile(ctx, func(secrets map[string]string) {
+		delete(secrets, key)
+	})
+}
+
+// updateFile applies fn to the secrets in the credentials file under its
+// lock, replacing the file through a temporary one so readers never see
+// a partial write
+func updateFile(ctx context.Context, fn func(map[string]string)) error {
+	path, err := filePath()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking credentials: %w", err)
+	}
+	defer l.Release()
+
+	secrets, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	fn(secrets)
+
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding credentials: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+// readFile loads the credentials file; a missing file holds no secrets
+func readFile(path string) (map[string]string, error) {
+	secrets := map[string]string{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return secrets, nil
+}
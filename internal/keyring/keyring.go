@@ -0,0 +1,133 @@
+// Package keyring keeps secrets, such as OAuth refresh tokens, in the
+// operating system's credential store: the login keychain on macOS,
+// through security, and the Secret Service (GNOME Keyring, KWallet) on
+// Linux, through secret-tool. Where there's none, they can be kept in a
+// file in the state directory only the user can read instead.
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Service is what secrets are stored under in the credential store
+const Service = "termplate"
+
+// Store is where secrets are kept
+type Store string
+
+const (
+	Keyring Store = "keyring" // The operating system's credential store
+	File    Store = "file"    // credentials.json in the state directory
+)
+
+// ErrNotFound is returned by Get for a key with no secret stored
+var ErrNotFound = errors.New("secret not found")
+
+// Get returns the secret stored under key
+func Get(ctx context.Context, store Store, key string) (string, error) {
+	if store == File {
+		return fileGet(ctx, key)
+	}
+	var out string
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		out, err = run(ctx, "", "security", "find-generic-password", "-s", Service, "-a", key, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		out, err = run(ctx, "", "secret-tool", "lookup", "service", Service, "account", key)
+	default:
+		return "", unsupported()
+	}
+	if notFound(err) || (err == nil && out == "") {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out))
+	if err != nil {
+		return "", fmt.Errorf("reading %s from the keyring: %w", key, err)
+	}
+	return string(secret), nil
+}
+
+// Set stores secret under key, replacing any stored before
+func Set(ctx context.Context, store Store, key, secret string) error {
+	if store == File {
+		return fileSet(ctx, key, secret)
+	}
+	// Encoded, so the secret needs no quoting and can't be mangled by a
+	// tool's handling of newlines
+	encoded := base64.StdEncoding.EncodeToString([]byte(secret))
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		// Through stdin, so the secret isn't in the process list
+		_, err = run(ctx, fmt.Sprintf("add-generic-password -U -s %q -a %q -w %q\n", Service, key, encoded), "security", "-i")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err = run(ctx, encoded, "secret-tool", "store", "--label", Service+": "+key, "service", Service, "account", key)
+	default:
+		return unsupported()
+	}
+	if err != nil {
+		return fmt.Errorf("storing %s in the keyring: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored under key, if any
+func Delete(ctx context.Context, store Store, key string) error {
+	if store == File {
+		return fileDelete(ctx, key)
+	}
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		_, err = run(ctx, "", "security", "delete-generic-password", "-s", Service, "-a", key)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err = run(ctx, "", "secret-tool", "clear", "service", Service, "account", key)
+	default:
+		return unsupported()
+	}
+	if err != nil && !notFound(err) {
+		return fmt.Errorf("removing %s from the keyring: %w", key, err)
+	}
+	return nil
+}
+
+// run runs a credential store tool with stdin and returns what it printed
+func run(ctx context.Context, stdin, name string, args ...string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("the keyring needs %s installed, or api.oauth.token_store: file", name)
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// notFound reports whether a tool failed for want of the secret:
+// security exits with 44, and secret-tool with 1 and says nothing
+func notFound(err error) bool {
+	var exit *exec.ExitError
+	return errors.As(err, &exit) && (exit.ExitCode() == 44 || err == error(exit))
+}
+
+func unsupported() error {
+	return fmt.Errorf("no keyring support on %s; set api.oauth.token_store: file", runtime.GOOS)
+}
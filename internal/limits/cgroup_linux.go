@@ -0,0 +1,92 @@
+//go:build linux
+
+package limits
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystems are mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cgroupCPUQuota returns the CPUs this process's cgroup may use (quota
+// divided by period), from cgroup v2's cpu.max or v1's cfs files. ok is
+// false when there is no quota or it can't be read.
+func cgroupCPUQuota() (quota float64, ok bool) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	// Lines are hierarchy-id:controllers:path; v2 has id 0 and no
+	// controllers. Inside a cgroup namespace the process's own group is
+	// mounted at the root, so the root is tried after the full path.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		controllers, path := parts[1], parts[2]
+		switch {
+		case parts[0] == "0" && controllers == "":
+			for _, dir := range []string{filepath.Join(cgroupRoot, path), cgroupRoot} {
+				if quota, ok := readCPUMax(filepath.Join(dir, "cpu.max")); ok {
+					return quota, true
+				}
+			}
+		case slices.Contains(strings.Split(controllers, ","), "cpu"):
+			for _, dir := range []string{filepath.Join(cgroupRoot, "cpu", path), filepath.Join(cgroupRoot, "cpu")} {
+				if quota, ok := readCFSQuota(dir); ok {
+					return quota, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// readCPUMax parses a v2 cpu.max file: "max 100000" or "150000 100000"
+func readCPUMax(file string) (float64, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, false
+	}
+	return ratio(fields[0], fields[1])
+}
+
+// readCFSQuota reads v1's cpu.cfs_quota_us (-1 for none) and
+// cpu.cfs_period_us in dir
+func readCFSQuota(dir string) (float64, bool) {
+	quota, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false
+	}
+	period, err := os.ReadFile(filepath.Join(dir, "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false
+	}
+	return ratio(strings.TrimSpace(string(quota)), strings.TrimSpace(string(period)))
+}
+
+func ratio(quota, period string) (float64, bool) {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return q / p, true
+}
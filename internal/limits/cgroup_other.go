@@ -0,0 +1,8 @@
+//go:build !linux
+
+package limits
+
+// cgroupCPUQuota reports no quota; cgroups exist only on Linux
+func cgroupCPUQuota() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package limits
+
+import (
+	"context"
+	"log/slog"
+	"os/exec"
+)
+
+// limitedCommand runs name without limits; rlimits exist only on Unix
+func limitedCommand(ctx context.Context, _ Child, name string, args []string) *exec.Cmd {
+	slog.Debug("child process limits are not supported on this platform")
+	return exec.CommandContext(ctx, name, args...)
+}
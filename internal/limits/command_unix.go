@@ -0,0 +1,32 @@
+//go:build unix
+
+package limits
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// limitedCommand runs name through sh, which sets the limits with ulimit
+// and then execs it, so they are in place before the command's first
+// instruction. Go can't set rlimits on a child directly.
+func limitedCommand(ctx context.Context, c Child, name string, args []string) *exec.Cmd {
+	var script []string
+	if c.MaxMemory > 0 {
+		script = append(script, fmt.Sprintf("ulimit -v %d", max(1, c.MaxMemory/1024))) // KiB
+	}
+	if c.MaxCPUTime > 0 {
+		seconds := (c.MaxCPUTime + time.Second - 1) / time.Second
+		script = append(script, fmt.Sprintf("ulimit -t %d", seconds))
+	}
+	if c.MaxOpenFiles > 0 {
+		script = append(script, fmt.Sprintf("ulimit -n %d", c.MaxOpenFiles))
+	}
+	script = append(script, `exec "$@"`)
+
+	shArgs := append([]string{"-c", strings.Join(script, " && "), "sh", name}, args...)
+	return exec.CommandContext(ctx, "sh", shArgs...)
+}
@@ -0,0 +1,89 @@
+// Package limits applies the runtime section of the config: a memory limit
+// and GOMAXPROCS for this process, and resource limits for the processes it
+// starts. Both matter when the CLI runs in a constrained container.
+package limits
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// Child limits the resources of a child process; zero fields are unlimited
+type Child struct {
+	MaxMemory    int64         // Address space in bytes
+	MaxCPUTime   time.Duration // CPU time, rounded up to whole seconds
+	MaxOpenFiles int
+}
+
+// IsZero reports whether c sets no limits
+func (c Child) IsZero() bool {
+	return c == Child{}
+}
+
+var child atomic.Pointer[Child]
+
+// Apply sets this process's memory limit and GOMAXPROCS from cfg and
+// records the limits for child processes. GOMEMLIMIT and GOMAXPROCS in the
+// environment take precedence, as they do for the runtime.
+func Apply(cfg config.RuntimeConfig) {
+	if cfg.MemoryLimit > 0 && os.Getenv("GOMEMLIMIT") == "" {
+		debug.SetMemoryLimit(int64(cfg.MemoryLimit))
+		slog.Debug("set memory limit", "bytes", int64(cfg.MemoryLimit))
+	}
+
+	if os.Getenv("GOMAXPROCS") == "" {
+		procs := cfg.MaxProcs
+		if procs == 0 {
+			procs = cpuQuotaProcs()
+		}
+		if procs > 0 && procs != runtime.GOMAXPROCS(0) {
+			runtime.GOMAXPROCS(procs)
+			slog.Debug("set GOMAXPROCS", "procs", procs)
+		}
+	}
+
+	SetChild(Child{
+		MaxMemory:    int64(cfg.Child.MaxMemory),
+		MaxCPUTime:   cfg.Child.MaxCPUTime,
+		MaxOpenFiles: cfg.Child.MaxOpenFiles,
+	})
+}
+
+// SetChild sets the limits Command applies to child processes
+func SetChild(c Child) {
+	child.Store(&c)
+}
+
+// Command is exec.CommandContext for child processes that run user-supplied
+// code, such as hooks: the process starts with the limits set by SetChild.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	c := child.Load()
+	if c == nil || c.IsZero() {
+		return exec.CommandContext(ctx, name, args...)
+	}
+	return limitedCommand(ctx, *c, name, args)
+}
+
+// cpuQuotaProcs returns the CPUs the container's cgroup quota allows,
+// rounded down and at least 1, like automaxprocs; 0 when there is no quota
+// or it allows all CPUs
+func cpuQuotaProcs() int {
+	quota, ok := cgroupCPUQuota()
+	if !ok {
+		return 0
+	}
+	procs := max(1, int(math.Floor(quota)))
+	if procs >= runtime.NumCPU() {
+		return 0
+	}
+	return procs
+}
@@ -0,0 +1,240 @@
+// Package lock provides named single-instance locks so commands that
+// modify shared state can't run concurrently.
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// Annotation is the cobra command annotation naming the lock a command
+// holds while it runs. Commands sharing a name exclude each other.
+const Annotation = "termplate.lock"
+
+// pollInterval is how often a waiting Acquire retries
+const pollInterval = 100 * time.Millisecond
+
+// ErrLocked is returned when a lock is held by another process
+var ErrLocked = errors.New("locked")
+
+// Owner describes the process holding a lock
+type Owner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+func (o Owner) String() string {
+	return fmt.Sprintf("pid %d on %s (%s) since %s", o.PID, o.Host, o.Command, o.Started.Format(time.RFC3339))
+}
+
+// HeldError reports that a lock is held by another process
+type HeldError struct {
+	Name  string
+	Owner *Owner // nil when the holder couldn't be identified
+}
+
+func (e *HeldError) Error() string {
+	if e.Owner == nil {
+		return fmt.Sprintf("%s is already running", e.Name)
+	}
+	return fmt.Sprintf("%s is already running: held by %s", e.Name, e.Owner)
+}
+
+func (e *HeldError) Unwrap() error {
+	return ErrLocked
+}
+
+func (e *HeldError) ExitCode() int {
+	return model.ExitLocked
+}
+
+// Lock is a held lock; Release it when done
+type Lock struct {
+	name string
+	path string
+	file *os.File
+	// exclusive is set for lock files created with O_EXCL where flock
+	// isn't available; those are removed on release
+	exclusive bool
+}
+
+// Path returns the lock file for name in the state directory
+func Path(name string) (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "locks", name+".lock"), nil
+}
+
+// Acquire takes the named lock, waiting up to wait for another holder to
+// release it. A zero wait fails immediately with a *HeldError.
+func Acquire(ctx context.Context, name string, wait time.Duration) (*Lock, error) {
+	path, err := Path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+
+	deadline := time.Now().Add(wait)
+	logged := false
+	for {
+		l, err := tryAcquire(name, path)
+		if err == nil {
+			return l, nil
+		}
+		var held *HeldError
+		if !errors.As(err, &held) || !time.Now().Before(deadline) {
+			return nil, err
+		}
+		if !logged {
+			attrs := []any{"lock", name, "timeout", wait}
+			if held.Owner != nil {
+				attrs = append(attrs, "holder", held.Owner.String())
+			}
+			slog.InfoContext(ctx, "waiting for lock", attrs...)
+			logged = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-time.After(min(pollInterval, time.Until(deadline))):
+		}
+	}
+}
+
+func tryAcquire(name, path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock %s: %w", path, err)
+	}
+
+	locked, err := flock(f)
+	if errors.Is(err, errUnsupported) {
+		f.Close()
+		return tryExclusive(name, path)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", path, err)
+	}
+	if !locked {
+		owner := readOwner(f)
+		f.Close()
+		return nil, &HeldError{Name: name, Owner: owner}
+	}
+
+	l := &Lock{name: name, path: path, file: f}
+	if err := l.writeOwner(); err != nil {
+		l.Release()
+		return nil, err
+	}
+	return l, nil
+}
+
+// tryExclusive is the fallback for filesystems without flock: the lock is
+// the existence of the file. A file left by a process that is no longer
+// running on this host is stale and taken over.
+func tryExclusive(name, path string) (*Lock, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path+".pid", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			l := &Lock{name: name, path: path + ".pid", file: f, exclusive: true}
+			if err := l.writeOwner(); err != nil {
+				l.Release()
+				return nil, err
+			}
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("creating lock %s: %w", path, err)
+		}
+
+		existing, err := os.Open(path + ".pid")
+		if err != nil {
+			continue // Released in the meantime
+		}
+		owner := readOwner(existing)
+		existing.Close()
+		if owner == nil || !stale(owner) {
+			return nil, &HeldError{Name: name, Owner: owner}
+		}
+		slog.Warn("removing stale lock", "lock", name, "holder", owner)
+		if err := os.Remove(path + ".pid"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("removing stale lock %s: %w", path, err)
+		}
+	}
+	return nil, &HeldError{Name: name}
+}
+
+// stale reports whether owner is a process on this host that has exited
+func stale(owner *Owner) bool {
+	host, err := os.Hostname()
+	if err != nil || host != owner.Host {
+		return false
+	}
+	return !processAlive(owner.PID)
+}
+
+func (l *Lock) writeOwner() error {
+	host, _ := os.Hostname()
+	owner := Owner{
+		PID:     os.Getpid(),
+		Host:    host,
+		Command: strings.Join(append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...), " "),
+		Started: time.Now().UTC().Truncate(time.Second),
+	}
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return fmt.Errorf("encoding lock owner: %w", err)
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("writing lock %s: %w", l.path, err)
+	}
+	if _, err := l.file.WriteAt(append(data, '\n'), 0); err != nil {
+		return fmt.Errorf("writing lock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+func readOwner(f *os.File) *Owner {
+	var owner Owner
+	if err := json.NewDecoder(f).Decode(&owner); err != nil || owner.PID == 0 {
+		return nil
+	}
+	return &owner
+}
+
+// Release gives up the lock. The flock lock file is left in place, since
+// removing it would race with processes that already opened it.
+func (l *Lock) Release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	if !l.exclusive {
+		// An empty file tells readers nobody holds the lock
+		l.file.Truncate(0)
+	}
+	l.file.Close()
+	if l.exclusive {
+		os.Remove(l.path)
+	}
+	l.file = nil
+	slog.Debug("released lock", "lock", l.name)
+}
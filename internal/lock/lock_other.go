@@ -0,0 +1,21 @@
+//go:build !unix
+
+package lock
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("flock not supported")
+
+// flock is unavailable here; callers fall back to exclusive lock files
+func flock(*os.File) (bool, error) {
+	return false, errUnsupported
+}
+
+// processAlive can't check other processes portably, so it assumes they
+// are running; stale locks then have to be removed by hand
+func processAlive(int) bool {
+	return true
+}
@@ -5,12 +5,32 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/config"
 )
 
+// secretAttrs are attribute names whose values are hidden in logs, besides
+// config.SecretKeys
+var secretAttrs = []string{"password", "secret", "token", "api_key", "authorization"}
+
+// initMu serializes Init, which goroutines may call at once, such as
+// commands run side by side in one process
+var initMu sync.Mutex
+
+// Init sets the default logger: text on stderr, or JSON on stdout in
+// production. It's safe to call from several goroutines; the last call
+// wins.
 func Init(level slog.Level, production bool) {
+	initMu.Lock()
+	defer initMu.Unlock()
+
 	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: !production && level == slog.LevelDebug,
+		Level:       level,
+		AddSource:   !production && level == slog.LevelDebug,
+		ReplaceAttr: redactAttr,
 	}
 
 	var handler slog.Handler
@@ -24,7 +44,7 @@ func Init(level slog.Level, production bool) {
 }
 
 func InitWithWriter(w io.Writer, level slog.Level) *slog.Logger {
-	opts := &slog.HandlerOptions{Level: level}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
 	return slog.New(slog.NewTextHandler(w, opts))
 }
 
@@ -44,3 +64,15 @@ type loggerKey struct{}
 func With(args ...any) *slog.Logger {
 	return slog.Default().With(args...)
 }
+
+// redactAttr hides the value of an attribute named like a credential,
+// unless it's empty
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup || a.Value.String() == "" {
+		return a
+	}
+	if config.IsSecret(a.Key) || slices.Contains(secretAttrs, strings.ToLower(a.Key)) {
+		return slog.String(a.Key, "********")
+	}
+	return a
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Logs are plain text whatever the color settings, so they read the same
+// in a terminal, a file, or a log collector
+func TestLogsHaveNoColor(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "defaults", env: map[string]string{"TERM": "xterm"}},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0", "TERM": "xterm"}},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}},
+		{name: "CLICOLOR_FORCE and TERM=dumb", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"NO_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "TERM"} {
+				t.Setenv(k, tt.env[k])
+			}
+			var buf bytes.Buffer
+			l := InitWithWriter(&buf, slog.LevelDebug)
+			l.Error("request failed", "status", "failed", "token", "abc123")
+			l.Debug("retrying", "attempt", 2)
+
+			out := buf.String()
+			if strings.Contains(out, "\x1b") {
+				t.Errorf("log has escape sequences:\n%q", out)
+			}
+			for _, want := range []string{"level=ERROR", `msg="request failed"`, "status=failed", "token=********", "level=DEBUG"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("log is missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CancelError is the cause a command's context is canceled with. It tells
+// the user why the work stopped instead of a bare "context canceled", and
+// picks the exit code.
+type CancelError struct {
+	Reason string
+	Code   int
+}
+
+func (e *CancelError) Error() string {
+	return e.Reason
+}
+
+func (e *CancelError) ExitCode() int {
+	return e.Code
+}
+
+// Reasons for canceling a command's context; pass them to
+// context.WithCancelCause and context.WithTimeoutCause
+var (
+	ErrInterrupted      = &CancelError{"canceled by user (interrupt)", ExitInterrupted}
+	ErrTimeout          = &CancelError{"deadline exceeded (--timeout)", ExitTimeout}
+	ErrDependencyFailed = &CancelError{"canceled because a dependency failed", ExitDependencyFailed}
+)
+
+// CancelCause explains err, returned by work that ctx stopped, with the
+// CancelError ctx was canceled with. Other errors, and context errors
+// without a CancelError cause, are returned unchanged.
+func CancelCause(ctx context.Context, err error) error {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var ce *CancelError
+	if errors.As(err, &ce) || !errors.As(context.Cause(ctx), &ce) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ce, err)
+}
@@ -10,6 +10,10 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrUnauthorized  = errors.New("unauthorized")
+	ErrOffline       = errors.New("network access disabled in offline mode (--offline)")
+	ErrOverBudget    = errors.New("over budget")
+	ErrVerification  = errors.New("verification failed")
+	ErrConflict      = errors.New("conflict")
 )
 
 type ValidationError struct {
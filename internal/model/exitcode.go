@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// Process exit codes used by the CLI
+const (
+	ExitOK             = 0
+	ExitError          = 1
+	ExitUsage          = 2 // The command line is invalid
+	ExitPartialFailure = 3
+	ExitLocked         = 4 // Another instance holds the command's lock
+	ExitOverBudget     = 5 // The operation exceeds a budget and wasn't confirmed
+
+	ExitDependencyFailed = 6   // Work was canceled because something it depends on failed
+	ExitConflict         = 7   // A resource changed since it was read, so a change to it wasn't applied
+	ExitTimeout          = 124 // --timeout expired, as with timeout(1)
+	ExitInterrupted      = 130 // Interrupted by SIGINT or SIGTERM (128 + SIGINT)
+)
+
+// ExitCodeInfo describes an exit code for docs and tools driving the CLI
+type ExitCodeInfo struct {
+	Code        int    `json:"code" yaml:"code"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// ExitCodes describes every exit code the CLI uses
+var ExitCodes = []ExitCodeInfo{
+	{ExitOK, "ok", "The command succeeded"},
+	{ExitError, "error", "The command failed"},
+	{ExitUsage, "usage", "The command line is invalid: an unknown command or flag, a bad flag value, or wrong arguments"},
+	{ExitPartialFailure, "partial_failure", "Some items of a bulk operation failed"},
+	{ExitLocked, "locked", "Another instance holds the command's lock"},
+	{ExitOverBudget, "over_budget", "The operation exceeds a budget and wasn't confirmed"},
+	{ExitDependencyFailed, "dependency_failed", "Work was canceled because something it depends on failed"},
+	{ExitConflict, "conflict", "A resource changed since it was read, so a change to it wasn't applied"},
+	{ExitTimeout, "timeout", "The command ran longer than --timeout"},
+	{ExitInterrupted, "interrupted", "The command was interrupted (Ctrl-C)"},
+}
+
+var ErrPartialFailure = errors.New("partial failure")
+
+// ExitCoder is implemented by errors that map to a specific process exit code
+type ExitCoder interface {
+	ExitCode() int
+}
+
+// ExitCode returns the process exit code for err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	// *exec.ExitError has an ExitCode method too, but a child process's
+	// status isn't the CLI's
+	var ec ExitCoder
+	if errors.As(err, &ec) {
+		if _, child := ec.(*exec.ExitError); !child {
+			return ec.ExitCode()
+		}
+	}
+	return ExitError
+}
+
+// PartialFailureError reports that some items of a bulk operation failed
+type PartialFailureError struct {
+	Failed int
+	Total  int
+}
+
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d items failed", e.Failed, e.Total)
+}
+
+func (e *PartialFailureError) Unwrap() error {
+	return ErrPartialFailure
+}
+
+func (e *PartialFailureError) ExitCode() int {
+	return ExitPartialFailure
+}
+
+func NewPartialFailureError(failed, total int) *PartialFailureError {
+	return &PartialFailureError{Failed: failed, Total: total}
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HTTPError is an API response with an unsuccessful status. Retry
+// classifiers match on it (see retry.ForStatus).
+type HTTPError struct {
+	Method     string
+	URL        string // The request URL
+	StatusCode int
+	Status     string // e.g. "409 Conflict"
+	Header     http.Header
+	Body       []byte // The start of the response body
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %s", e.Method, e.URL, e.Status)
+}
@@ -0,0 +1,259 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Label limits, as in Kubernetes: a name of up to 63 characters with an
+// optional DNS subdomain prefix ("example.com/team") of up to 253
+const (
+	maxLabelName   = 63
+	maxLabelPrefix = 253
+)
+
+var (
+	labelNameRE   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefixRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// Labels are the key=value pairs identifying a resource, which selectors
+// match
+type Labels map[string]string
+
+// String returns the labels as key=value pairs sorted by key, e.g.
+// "env=prod,team=core"
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for _, k := range slices.Sorted(maps.Keys(l)) {
+		pairs = append(pairs, k+"="+l[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ValidateLabels checks that each label's key and value are well formed,
+// returning a *ValidationError on field for the first that isn't
+func ValidateLabels(field string, labels Labels) error {
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if err := validateLabelKey(k); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+		if err := validateLabelValue(labels[k]); err != nil {
+			return NewValidationError(field, fmt.Sprintf("label %s: %s", k, err))
+		}
+	}
+	return nil
+}
+
+// ValidateAnnotations checks that each annotation's key is well formed.
+// Annotation values may hold anything.
+func ValidateAnnotations(field string, annotations map[string]string) error {
+	for _, k := range slices.Sorted(maps.Keys(annotations)) {
+		if err := validateLabelKey(k); err != nil {
+			return NewValidationError(field, err.Error())
+		}
+	}
+	return nil
+}
+
+// validateLabelKey checks a key: [prefix/]name
+func validateLabelKey(key string) error {
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		name, prefix = prefix, ""
+	}
+	switch {
+	case hasPrefix && (prefix == "" || len(prefix) > maxLabelPrefix || !labelPrefixRE.MatchString(prefix)):
+		return fmt.Errorf("invalid key %q: the prefix must be a DNS subdomain of at most %d characters", key, maxLabelPrefix)
+	case name == "" || len(name) > maxLabelName || !labelNameRE.MatchString(name):
+		return fmt.Errorf("invalid key %q: the name must be at most %d letters, digits, '-', '_', or '.', starting and ending with a letter or digit", key, maxLabelName)
+	}
+	return nil
+}
+
+// validateLabelValue checks a value: empty, or like a key's name
+func validateLabelValue(value string) error {
+	if value != "" && (len(value) > maxLabelName || !labelNameRE.MatchString(value)) {
+		return fmt.Errorf("invalid value %q: must be at most %d letters, digits, '-', '_', or '.', starting and ending with a letter or digit", value, maxLabelName)
+	}
+	return nil
+}
+
+// Selector operators
+const (
+	SelectEquals    = "="
+	SelectNotEquals = "!="
+	SelectIn        = "in"
+	SelectNotIn     = "notin"
+	SelectExists    = "exists"
+	SelectNotExists = "!"
+)
+
+// Requirement is one condition of a selector on a label
+type Requirement struct {
+	Key      string
+	Operator string   // One of the Select constants
+	Values   []string // One for = and !=, one or more for in and notin
+}
+
+// Matches reports whether labels meet the requirement. As in Kubernetes,
+// != and notin match labels without the key.
+func (r Requirement) Matches(labels Labels) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectEquals, SelectIn:
+		return ok && slices.Contains(r.Values, value)
+	case SelectNotEquals, SelectNotIn:
+		return !ok || !slices.Contains(r.Values, value)
+	case SelectExists:
+		return ok
+	case SelectNotExists:
+		return !ok
+	default:
+		return false
+	}
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case SelectIn, SelectNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	case SelectExists:
+		return r.Key
+	case SelectNotExists:
+		return "!" + r.Key
+	default:
+		return r.Key + r.Operator + r.Values[0]
+	}
+}
+
+// Selector selects resources by their labels: those meeting every
+// requirement. The empty selector selects everything.
+type Selector []Requirement
+
+// ParseSelector parses a selector in the Kubernetes syntax, requirements
+// separated by commas:
+//
+//	env=prod         env==prod        team!=core
+//	tier in (web,api)                 tier notin (batch)
+//	canary           !deprecated      (the label is set, or isn't)
+//
+// A malformed selector is a *ValidationError on "selector".
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+	parts, err := splitRequirements(s)
+	if err != nil {
+		return nil, NewValidationError("selector", err.Error())
+	}
+	for _, part := range parts {
+		r, err := parseRequirement(strings.TrimSpace(part))
+		if err != nil {
+			return nil, NewValidationError("selector", err.Error())
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels meet every requirement
+func (s Selector) Matches(labels Labels) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the selector selects everything
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// splitRequirements splits s at the commas outside parentheses
+func splitRequirements(s string) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("nested parentheses in %q", s)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unmatched ')' in %q", s)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unmatched '(' in %q", s)
+	}
+	return append(parts, s[start:]), nil
+}
+
+// parseRequirement parses one requirement of a selector
+func parseRequirement(s string) (Requirement, error) {
+	if s == "" {
+		return Requirement{}, fmt.Errorf("empty requirement")
+	}
+	var r Requirement
+	if fields := strings.Fields(s); len(fields) > 1 && (fields[1] == SelectIn || fields[1] == SelectNotIn) {
+		r.Key, r.Operator = fields[0], fields[1]
+		list := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s[len(fields[0]):]), fields[1]))
+		if !strings.HasPrefix(list, "(") || !strings.HasSuffix(list, ")") {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %s needs a list in parentheses, e.g. %s %s (a,b)", s, r.Operator, r.Key, r.Operator)
+		}
+		for _, v := range strings.Split(list[1:len(list)-1], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(v))
+		}
+		if len(r.Values) == 1 && r.Values[0] == "" {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: empty list", s)
+		}
+	} else {
+		switch {
+		case strings.HasPrefix(s, "!") && !strings.Contains(s, "="):
+			r.Key, r.Operator = strings.TrimSpace(s[1:]), SelectNotExists
+		case strings.Contains(s, "!="):
+			key, value, _ := strings.Cut(s, "!=")
+			r.Key, r.Operator, r.Values = strings.TrimSpace(key), SelectNotEquals, []string{strings.TrimSpace(value)}
+		case strings.Contains(s, "="):
+			key, value, _ := strings.Cut(s, "=")
+			value = strings.TrimPrefix(value, "=")
+			r.Key, r.Operator, r.Values = strings.TrimSpace(key), SelectEquals, []string{strings.TrimSpace(value)}
+		default:
+			r.Key, r.Operator = s, SelectExists
+		}
+	}
+
+	if err := validateLabelKey(r.Key); err != nil {
+		return Requirement{}, fmt.Errorf("invalid requirement %q: %w", s, err)
+	}
+	for _, v := range r.Values {
+		if err := validateLabelValue(v); err != nil {
+			return Requirement{}, fmt.Errorf("invalid requirement %q: %w", s, err)
+		}
+	}
+	return r, nil
+}
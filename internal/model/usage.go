@@ -0,0 +1,29 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UsageError reports a command line that can't run: an unknown command or
+// flag, a bad flag value, or the wrong arguments
+type UsageError struct {
+	Command     string   // Command path, e.g. "termplate template add"
+	Args        []string // The command line, without the program name
+	Token       string   // The offending argument as typed, e.g. "--fromat"
+	Flag        string   // The flag concerned, without dashes
+	Message     string
+	Suggestions []string // Near misses for an unknown command or flag, e.g. "--format"
+	Help        string   // Help for just the flags concerned, or the usage line
+}
+
+func (e *UsageError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", e.Message, strings.Join(e.Suggestions, " or "))
+}
+
+func (e *UsageError) ExitCode() int {
+	return ExitUsage
+}
@@ -0,0 +1,210 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// deviceGrantType is the grant_type polling for a device's tokens
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// LoginBrowser signs in with the authorization code flow and PKCE: it
+// opens the authorization page in the browser, or asks the user on w to,
+// and waits on 127.0.0.1 for the browser to come back with the code,
+// which it exchanges for tokens
+func LoginBrowser(ctx context.Context, client *http.Client, cfg config.OAuthConfig, w io.Writer) (*Token, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.RedirectPort)))
+	if err != nil {
+		return nil, fmt.Errorf("listening for the browser: %w", err)
+	}
+	defer ln.Close()
+	redirect := fmt.Sprintf("http://%s/callback", ln.Addr())
+
+	verifier, state := randomString(), randomString()
+	sum := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {redirect},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	if len(cfg.Scopes) > 0 {
+		query.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	sep := "?"
+	if strings.Contains(cfg.AuthURL, "?") {
+		sep = "&"
+	}
+	authURL := cfg.AuthURL + sep + query.Encode()
+
+	codes := make(chan string, 1)
+	errs := make(chan error, 1)
+	srv := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/callback" {
+				http.NotFound(rw, r)
+				return
+			}
+			q := r.URL.Query()
+			var err error
+			switch {
+			case q.Get("state") != state:
+				err = errors.New("the browser came back from another login")
+			case q.Get("error") != "":
+				err = &Error{Code: q.Get("error"), Description: q.Get("error_description")}
+			case q.Get("code") == "":
+				err = errors.New("the browser came back without a code")
+			}
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(rw, "<p>Login failed: %s</p>", html.EscapeString(err.Error()))
+				select {
+				case errs <- err:
+				default:
+				}
+				return
+			}
+			fmt.Fprint(rw, "<p>Logged in to termplate. You can close this tab.</p>")
+			select {
+			case codes <- q.Get("code"):
+			default:
+			}
+		}),
+	}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	if err := openBrowser(ctx, authURL); err != nil {
+		fmt.Fprintf(w, "Open this page in your browser to log in:\n\n  %s\n\n", authURL)
+	} else {
+		fmt.Fprintf(w, "Opened the browser to log in. If it didn't open, visit:\n\n  %s\n\n", authURL)
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-errs:
+		return nil, fmt.Errorf("logging in: %w", err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for the browser: %w", ctx.Err())
+	}
+	t, err := tokenRequest(ctx, client, cfg, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirect},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+	return t, nil
+}
+
+// LoginDevice signs in with the device authorization flow: it shows the
+// user on w where to enter a code, on this machine or another, and polls
+// for tokens until they've done so
+func LoginDevice(ctx context.Context, client *http.Client, cfg config.OAuthConfig, w io.Writer) (*Token, error) {
+	form := url.Values{}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	var auth struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURL         string `json:"verification_url"` // As some servers name it
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := post(ctx, client, cfg, cfg.DeviceURL, form, &auth); err != nil {
+		return nil, fmt.Errorf("starting device login: %w", err)
+	}
+	if auth.VerificationURI == "" {
+		auth.VerificationURI = auth.VerificationURL
+	}
+	if auth.DeviceCode == "" || auth.VerificationURI == "" {
+		return nil, errors.New("starting device login: the response has no device_code or verification_uri")
+	}
+
+	fmt.Fprintf(w, "To log in, visit:\n\n  %s\n\nand enter the code: %s\n\n", auth.VerificationURI, auth.UserCode)
+	if auth.VerificationURIComplete != "" {
+		fmt.Fprintf(w, "Or open, with the code filled in:\n\n  %s\n\n", auth.VerificationURIComplete)
+	}
+
+	interval := 5 * time.Second // RFC 8628's default
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for the code to be entered: %w", ctx.Err())
+		}
+		t, err := tokenRequest(ctx, client, cfg, url.Values{
+			"grant_type":  {deviceGrantType},
+			"device_code": {auth.DeviceCode},
+		})
+		var oerr *Error
+		switch {
+		case err == nil:
+			return t, nil
+		case errors.As(err, &oerr) && oerr.Code == "authorization_pending":
+		case errors.As(err, &oerr) && oerr.Code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, fmt.Errorf("waiting for the code to be entered: %w", err)
+		}
+	}
+}
+
+// randomString returns 32 random bytes, base64url encoded, for a PKCE
+// verifier or state
+func randomString() string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// openBrowser opens u in the user's browser, without waiting for it
+func openBrowser(ctx context.Context, u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.CommandContext(ctx, "open", u)
+	case "windows":
+		cmd = exec.CommandContext(ctx, "rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.CommandContext(ctx, "xdg-open", u)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
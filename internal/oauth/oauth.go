@@ -0,0 +1,213 @@
+// Package oauth signs in to the API with OAuth 2.0: the authorization code
+// flow with PKCE through the browser, and the device authorization flow
+// for machines without one. The tokens are kept in the keyring (see
+// package keyring) and refreshed as they expire.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/keyring"
+	"github.com/blacksilver/termplate-go/internal/lock"
+)
+
+const (
+	// ExpiryLeeway is how long before it expires an access token is
+	// refreshed, so it doesn't expire on the way to the API
+	ExpiryLeeway = 30 * time.Second
+	// refreshLockName serializes refreshes across invocations, as a
+	// refresh token may only be used once
+	refreshLockName = "oauth-refresh"
+	lockWait        = 30 * time.Second
+)
+
+// ErrNotLoggedIn is returned when there are no tokens stored
+var ErrNotLoggedIn = errors.New("not logged in; run: termplate auth login")
+
+// Token is what the authorization server granted
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"` // Zero when the server didn't say
+	Scope        string    `json:"scope,omitempty"`
+}
+
+// Valid reports whether the access token can still be sent
+func (t *Token) Valid() bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > ExpiryLeeway)
+}
+
+// Error is an error response from the authorization server
+type Error struct {
+	Code        string // e.g. invalid_grant
+	Description string
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Code, e.Description)
+	}
+	return e.Code
+}
+
+// Load returns the tokens stored for cfg by a login
+func Load(ctx context.Context, cfg config.OAuthConfig) (*Token, error) {
+	data, err := keyring.Get(ctx, keyring.Store(cfg.TokenStore), storeKey(cfg))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, ErrNotLoggedIn
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		return nil, fmt.Errorf("parsing stored token: %w", err)
+	}
+	return &t, nil
+}
+
+// Save stores t for cfg
+func Save(ctx context.Context, cfg config.OAuthConfig, t *Token) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("encoding token: %w", err)
+	}
+	return keyring.Set(ctx, keyring.Store(cfg.TokenStore), storeKey(cfg), string(data))
+}
+
+// Delete removes the tokens stored for cfg
+func Delete(ctx context.Context, cfg config.OAuthConfig) error {
+	return keyring.Delete(ctx, keyring.Store(cfg.TokenStore), storeKey(cfg))
+}
+
+// Current returns stored tokens with an access token that can be sent,
+// refreshing them with client when they've expired or the API refused
+// stale, the access token sent last. Another invocation may have
+// refreshed them already, in which case theirs are used.
+func Current(ctx context.Context, client *http.Client, cfg config.OAuthConfig, stale string) (*Token, error) {
+	l, err := lock.Acquire(ctx, refreshLockName, lockWait)
+	if err != nil {
+		return nil, fmt.Errorf("locking token refresh: %w", err)
+	}
+	defer l.Release()
+
+	t, err := Load(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if t.Valid() && t.AccessToken != stale {
+		return t, nil
+	}
+	if t.RefreshToken == "" {
+		return nil, errors.New("login expired; run: termplate auth login")
+	}
+
+	fresh, err := Refresh(ctx, client, cfg, t.RefreshToken)
+	if err != nil {
+		var oerr *Error
+		if errors.As(err, &oerr) && oerr.Code == "invalid_grant" {
+			return nil, fmt.Errorf("login expired (%w); run: termplate auth login", err)
+		}
+		return nil, fmt.Errorf("refreshing token: %w", err)
+	}
+	if fresh.RefreshToken == "" {
+		// Not rotated, so the old one still works
+		fresh.RefreshToken = t.RefreshToken
+	}
+	if err := Save(ctx, cfg, fresh); err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// Refresh exchanges refreshToken for new tokens
+func Refresh(ctx context.Context, client *http.Client, cfg config.OAuthConfig, refreshToken string) (*Token, error) {
+	return tokenRequest(ctx, client, cfg, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// tokenRequest sends form, with the client's credentials, to the token
+// endpoint and returns the tokens it grants
+func tokenRequest(ctx context.Context, client *http.Client, cfg config.OAuthConfig, form url.Values) (*Token, error) {
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		Scope        string `json:"scope"`
+	}
+	if err := post(ctx, client, cfg, cfg.TokenURL, form, &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+	t := &Token{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+		Scope:        resp.Scope,
+	}
+	if resp.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// post sends form, with the client's credentials, to endpoint and
+// decodes the JSON response into out; an error response is an *Error
+func post(ctx context.Context, client *http.Client, cfg config.OAuthConfig, endpoint string, form url.Values, out interface{}) error {
+	form.Set("client_id", cfg.ClientID)
+	if cfg.ClientSecret != "" {
+		form.Set("client_secret", cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("reading response from %s: %w", endpoint, err)
+	}
+
+	var oerr struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if json.Unmarshal(data, &oerr) == nil && oerr.Error != "" {
+		return &Error{Code: oerr.Error, Description: oerr.Description}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", endpoint, resp.Status)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("parsing response from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+// storeKey is what cfg's tokens are stored under, so each API and client
+// has its own
+func storeKey(cfg config.OAuthConfig) string {
+	return "oauth:" + cfg.ClientID + "@" + cfg.TokenURL
+}
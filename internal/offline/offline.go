@@ -0,0 +1,32 @@
+// Package offline holds the process-wide offline mode set by --offline.
+// Code that needs the network calls Check first so it fails fast, and
+// code with a cache falls back to it instead.
+package offline
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+var enabled atomic.Bool
+
+// Set turns offline mode on or off
+func Set(on bool) {
+	enabled.Store(on)
+}
+
+// Enabled reports whether network access is disabled
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Check returns an error wrapping model.ErrOffline when offline mode is on;
+// what describes the operation that needs the network
+func Check(what string) error {
+	if enabled.Load() {
+		return fmt.Errorf("%s: %w", what, model.ErrOffline)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+)
+
+// maxErrorBody is how much of an error response is kept in its HTTPError
+const maxErrorBody = 1024
+
+// Sender sends a request, e.g. http.Client.Do after adding credentials
+type Sender func(*http.Request) (*http.Response, error)
+
+// Result is what a flush did
+type Result struct {
+	Sent    int
+	Failed  *Entry // The request that stopped the flush, with its LastError; nil when all were sent
+	Pending int    // Requests still queued, including Failed
+}
+
+// Flush sends the queued requests in order with send, dropping each once
+// the API accepts it with a 2xx status. It stops at the first request that
+// fails, so none is applied ahead of an earlier one; that request stays
+// queued with its error, to be sent again or removed.
+func Flush(ctx context.Context, send Sender) (*Result, error) {
+	if err := offline.Check("sending queued requests"); err != nil {
+		return nil, err
+	}
+	l, err := lock.Acquire(ctx, flushLockName, lockWait)
+	if err != nil {
+		return nil, fmt.Errorf("locking outbox: %w", err)
+	}
+	defer l.Release()
+
+	entries, err := List()
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, e := range entries {
+		sendErr := sendEntry(ctx, send, e)
+		// The outcome is recorded even when ctx ended mid-request
+		if sendErr == nil {
+			if _, err := Remove(context.WithoutCancel(ctx), e.ID); err != nil {
+				return nil, err
+			}
+			res.Sent++
+			continue
+		}
+		err := update(context.WithoutCancel(ctx), func(f *file) {
+			for i := range f.Entries {
+				if f.Entries[i].ID == e.ID {
+					f.Entries[i].Attempts++
+					f.Entries[i].LastError = sendErr.Error()
+					e = f.Entries[i]
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		res.Failed = &e
+		break
+	}
+	res.Pending = len(entries) - res.Sent
+	switch {
+	case res.Failed != nil:
+		events.Record(ctx, events.Error, "outbox", fmt.Sprintf("queued request %d failed: %s", res.Failed.ID, res.Failed.LastError),
+			"sent", res.Sent, "pending", res.Pending)
+	case res.Sent > 0:
+		events.Record(ctx, events.Info, "outbox", fmt.Sprintf("sent %d queued requests", res.Sent))
+	}
+	return res, nil
+}
+
+// sendEntry sends e, returning a *model.HTTPError for a status other than
+// 2xx
+func sendEntry(ctx context.Context, send Sender, e Entry) error {
+	req, err := e.Request(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &model.HTTPError{
+		Method:     e.Method,
+		URL:        e.URL,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
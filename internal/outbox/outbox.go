@@ -0,0 +1,217 @@
+// Package outbox queues API requests that change things (POST, PUT, PATCH,
+// DELETE) made while offline, in a file in the state directory, and sends
+// them later in the order they were made. Each carries an idempotency key
+// so a request the API already applied isn't applied twice when resent.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// IdempotencyKeyHeader carries a queued request's key, so the API can
+// recognize a request it has already applied
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	// lockName serializes changes to the outbox file
+	lockName = "outbox"
+	// flushLockName is held for a whole flush, so two flushes can't send
+	// the same request
+	flushLockName = "outbox-flush"
+	// lockWait is how long to wait for another invocation's change
+	lockWait = 10 * time.Second
+)
+
+// secretHeaders aren't stored with a queued request; credentials are
+// added again when it's sent
+var secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key"}
+
+// Entry is a queued request
+type Entry struct {
+	ID             int         `json:"id"`
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	Header         http.Header `json:"header,omitempty"`
+	Body           []byte      `json:"body,omitempty"`
+	IdempotencyKey string      `json:"idempotency_key"`
+	Created        time.Time   `json:"created"`
+	Attempts       int         `json:"attempts"`             // Times a flush tried to send it
+	LastError      string      `json:"last_error,omitempty"` // Why the last attempt failed
+}
+
+// file is the stored form of the outbox
+type file struct {
+	NextID  int     `json:"next_id"`
+	Entries []Entry `json:"entries"`
+}
+
+// Path returns the file the outbox is stored in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "outbox.json"), nil
+}
+
+// Add queues req, reading its body, and returns the queued entry
+func Add(ctx context.Context, req *http.Request) (Entry, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return Entry{}, fmt.Errorf("reading request body: %w", err)
+		}
+	}
+
+	header := req.Header.Clone()
+	for _, name := range secretHeaders {
+		header.Del(name)
+	}
+	key := header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		key = newKey()
+	}
+	header.Del(IdempotencyKeyHeader)
+
+	var e Entry
+	err := update(ctx, func(f *file) {
+		f.NextID++
+		e = Entry{
+			ID:             f.NextID,
+			Method:         req.Method,
+			URL:            req.URL.String(),
+			Header:         header,
+			Body:           body,
+			IdempotencyKey: key,
+			Created:        time.Now().UTC().Truncate(time.Second),
+		}
+		f.Entries = append(f.Entries, e)
+	})
+	if err == nil {
+		events.Record(ctx, events.Info, "outbox", fmt.Sprintf("queued %s %s to send later", e.Method, req.URL.Path), "id", e.ID)
+	}
+	return e, err
+}
+
+// List returns the queued requests, oldest first
+func List() ([]Entry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Entries, nil
+}
+
+// Remove drops the queued requests with the given IDs, or every request
+// when none are given, and returns how many were dropped
+func Remove(ctx context.Context, ids ...int) (int, error) {
+	var removed int
+	err := update(ctx, func(f *file) {
+		n := len(f.Entries)
+		if len(ids) == 0 {
+			f.Entries = nil
+		} else {
+			f.Entries = slices.DeleteFunc(f.Entries, func(e Entry) bool { return slices.Contains(ids, e.ID) })
+		}
+		removed = n - len(f.Entries)
+	})
+	return removed, err
+}
+
+// Request returns the request that sends e, with its idempotency key
+func (e Entry) Request(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, e.Method, e.URL, bytes.NewReader(e.Body))
+	if err != nil {
+		return nil, fmt.Errorf("building request %d: %w", e.ID, err)
+	}
+	for name, values := range e.Header {
+		req.Header[name] = slices.Clone(values)
+	}
+	req.Header.Set(IdempotencyKeyHeader, e.IdempotencyKey)
+	return req, nil
+}
+
+// update applies fn to the outbox file under its lock
+func update(ctx context.Context, fn func(*file)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking outbox: %w", err)
+	}
+	defer l.Release()
+
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	fn(f)
+	return write(path, f)
+}
+
+// newKey returns a random idempotency key in UUID (version 4) form
+func newKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// read loads the outbox file; a missing file is an empty outbox
+func read(path string) (*file, error) {
+	f := &file{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// write replaces the outbox file, through a temporary file so readers
+// never see a partial write
+func write(path string, f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding outbox: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
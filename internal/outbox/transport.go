@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/blacksilver/termplate-go/internal/offline"
+)
+
+// QueuedHeader is set on the response to a queued request to its ID in
+// the outbox
+const QueuedHeader = "Termplate-Outbox-Id"
+
+// Transport queues the requests that change things while offline mode is
+// on or the API can't be reached, answering them with 202 Accepted and
+// QueuedHeader. Once the API can be reached, queued requests are sent
+// ahead of the next one, so the API sees them in the order they were made.
+type Transport struct {
+	Base http.RoundTripper // http.DefaultTransport when nil
+
+	// Prepare adds what a queued request needs when it's sent but isn't
+	// stored with it, such as credentials
+	Prepare func(*http.Request)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !Mutating(req.Method) {
+		return base.RoundTrip(req)
+	}
+	if offline.Enabled() {
+		return queue(req, "offline mode is on")
+	}
+
+	pending, err := List()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) > 0 {
+		res, err := Flush(req.Context(), t.sender(base))
+		if err != nil {
+			return nil, err
+		}
+		if res.Failed != nil {
+			return queue(req, "earlier requests are still queued")
+		}
+	}
+
+	// The body is kept to queue the request if the API can't be reached
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+		_ = req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil && unreachable(err) {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return queue(req, err.Error())
+	}
+	return resp, err
+}
+
+func (t *Transport) sender(base http.RoundTripper) Sender {
+	return func(req *http.Request) (*http.Response, error) {
+		if t.Prepare != nil {
+			t.Prepare(req)
+		}
+		return base.RoundTrip(req)
+	}
+}
+
+// Mutating reports whether requests with method change things, and so are
+// queued
+func Mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Queued returns the outbox ID of a request Transport queued rather than
+// sent
+func Queued(resp *http.Response) (int, bool) {
+	id, err := strconv.Atoi(resp.Header.Get(QueuedHeader))
+	return id, err == nil
+}
+
+// queue adds req to the outbox and returns the response standing in for
+// the API's
+func queue(req *http.Request, reason string) (*http.Response, error) {
+	e, err := Add(req.Context(), req)
+	if err != nil {
+		return nil, fmt.Errorf("queuing %s %s: %w", req.Method, req.URL.Redacted(), err)
+	}
+	slog.Warn("request queued in the outbox; send it with: termplate outbox flush",
+		"id", e.ID, "method", e.Method, "url", req.URL.Redacted(), "reason", reason)
+	return &http.Response{
+		Status:        "202 Accepted",
+		StatusCode:    http.StatusAccepted,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{QueuedHeader: {strconv.Itoa(e.ID)}},
+		Body:          http.NoBody,
+		ContentLength: 0,
+		Request:       req,
+	}, nil
+}
+
+// unreachable reports whether err is a failure to connect, so the request
+// wasn't sent
+func unreachable(err error) bool {
+	var op *net.OpError
+	if errors.As(err, &op) && op.Op == "dial" {
+		return true
+	}
+	var dns *net.DNSError
+	return errors.As(err, &dns)
+}
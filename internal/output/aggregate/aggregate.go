@@ -0,0 +1,373 @@
+// Package aggregate summarizes tables of text cells by group: rows that
+// share the values of the group-by columns become one row, with
+// aggregates such as count and sum(size) computed over them. It's what
+// --group-by and --agg run on list output, and works on any [][]string
+// table whose first row is the headers.
+//
+//	spec, err := aggregate.Parse("region", "count,total=sum(size)")
+//	summary, err := spec.Table(table)
+package aggregate
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Funcs are the aggregate functions
+var Funcs = []string{"count", "distinct", "sum", "avg", "min", "max"}
+
+// Agg is an aggregate computed for each group
+type Agg struct {
+	Name   string // Header of its column: as written, e.g. sum(size), or the name given with name=sum(size)
+	Func   string // One of Funcs
+	Column string // The column it's over; empty for count, which then counts rows
+}
+
+// Spec is how rows are grouped and summarized
+type Spec struct {
+	GroupBy []string // Rows with the same values in these columns are a group; all rows are one without
+	Aggs    []Agg    // Computed for each group, after the group-by columns
+
+	// Match reports whether a header names column; headers equal to it
+	// ignoring case do when it's nil
+	Match func(header, column string) bool
+}
+
+// Group is the rows that share a key
+type Group struct {
+	Key    []string // Values of the group-by columns
+	Rows   []int    // Indexes of its rows, in order
+	Values []string // The aggregates, in Spec.Aggs order
+}
+
+// Parse parses comma-separated group-by columns and aggregates, e.g.
+// "region,kind" and "count,sum(size),largest=max(size)". Aggregates are
+// count when only columns are given.
+func Parse(groupBy, aggs string) (*Spec, error) {
+	s := &Spec{}
+	for _, column := range strings.Split(groupBy, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			s.GroupBy = append(s.GroupBy, column)
+		}
+	}
+	for _, def := range splitList(aggs) {
+		a, err := parseAgg(def)
+		if err != nil {
+			return nil, err
+		}
+		s.Aggs = append(s.Aggs, a)
+	}
+	if len(s.Aggs) == 0 {
+		if len(s.GroupBy) == 0 {
+			return nil, model.NewValidationError("group-by", "expected columns to group by or aggregates")
+		}
+		s.Aggs = []Agg{{Name: "count", Func: "count"}}
+	}
+	return s, nil
+}
+
+// splitList splits s at commas outside parentheses, leaving out empty
+// items. What follows an unclosed parenthesis is the last item.
+func splitList(s string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth = max(depth-1, 0)
+		case r == ',' && depth == 0:
+			if item := strings.TrimSpace(s[start:i]); item != "" {
+				items = append(items, item)
+			}
+			start = i + 1
+		}
+	}
+	if item := strings.TrimSpace(s[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
+
+// parseAgg parses one aggregate: func, func(column), or name=func(column)
+func parseAgg(def string) (Agg, error) {
+	a := Agg{Name: def}
+	expr := def
+	if name, rest, ok := strings.Cut(def, "="); ok {
+		a.Name, expr = strings.TrimSpace(name), strings.TrimSpace(rest)
+		if a.Name == "" {
+			return Agg{}, model.NewValidationError("agg", fmt.Sprintf("%q: empty name before =", def))
+		}
+	}
+	fn, arg, call := strings.Cut(expr, "(")
+	a.Func = strings.ToLower(strings.TrimSpace(fn))
+	if call {
+		if !strings.HasSuffix(arg, ")") {
+			return Agg{}, model.NewValidationError("agg", fmt.Sprintf("%q: expected func(column)", def))
+		}
+		a.Column = strings.TrimSpace(strings.TrimSuffix(arg, ")"))
+	}
+	if !slices.Contains(Funcs, a.Func) {
+		return Agg{}, model.NewValidationError("agg", fmt.Sprintf("%q: unknown function %q (valid: %s)", def, a.Func, strings.Join(Funcs, ", ")))
+	}
+	if a.Column == "" && a.Func != "count" {
+		return Agg{}, model.NewValidationError("agg", fmt.Sprintf("%q: %s needs a column, e.g. %s(size)", def, a.Func, a.Func))
+	}
+	return a, nil
+}
+
+// Headers returns the headers of the summarized table: the group-by
+// columns, named as in headers, then the aggregates
+func (s *Spec) Headers(headers []string) ([]string, error) {
+	keys, err := s.columns(headers, s.GroupBy, "group-by")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(keys)+len(s.Aggs))
+	for _, i := range keys {
+		out = append(out, headers[i])
+	}
+	for _, a := range s.Aggs {
+		out = append(out, a.Name)
+	}
+	return out, nil
+}
+
+// Groups returns the groups of rows under headers, in the order of their
+// first rows
+func (s *Spec) Groups(headers []string, rows [][]string) ([]Group, error) {
+	keys, err := s.columns(headers, s.GroupBy, "group-by")
+	if err != nil {
+		return nil, err
+	}
+	aggColumns := make([]string, len(s.Aggs))
+	for i, a := range s.Aggs {
+		aggColumns[i] = a.Column
+	}
+	cols, err := s.columns(headers, aggColumns, "agg")
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	index := map[string]int{}
+	for r, row := range rows {
+		key := make([]string, len(keys))
+		for i, c := range keys {
+			key[i] = cell(row, c)
+		}
+		id := strings.Join(key, "\x00")
+		g, ok := index[id]
+		if !ok {
+			g = len(groups)
+			index[id] = g
+			groups = append(groups, Group{Key: key})
+		}
+		groups[g].Rows = append(groups[g].Rows, r)
+	}
+	// Without group-by columns, all rows are one group, even none
+	if len(keys) == 0 && len(groups) == 0 {
+		groups = []Group{{}}
+	}
+
+	for g := range groups {
+		for i, a := range s.Aggs {
+			cells := make([]string, len(groups[g].Rows))
+			for j, r := range groups[g].Rows {
+				if cols[i] >= 0 {
+					cells[j] = cell(rows[r], cols[i])
+				}
+			}
+			v, err := compute(a, cells)
+			if err != nil {
+				return nil, err
+			}
+			groups[g].Values = append(groups[g].Values, v)
+		}
+	}
+	return groups, nil
+}
+
+// Table returns the summarized table of table, whose first row is the
+// headers: a row per group, with its key and aggregates
+func (s *Spec) Table(table [][]string) ([][]string, error) {
+	if len(table) == 0 {
+		return table, nil
+	}
+	headers, err := s.Headers(table[0])
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.Groups(table[0], table[1:])
+	if err != nil {
+		return nil, err
+	}
+	out := [][]string{headers}
+	for _, g := range groups {
+		out = append(out, append(slices.Clone(g.Key), g.Values...))
+	}
+	return out, nil
+}
+
+// columns returns the index of each column in headers, or -1 for an empty
+// one; field names the flag in the error for a column that isn't there
+func (s *Spec) columns(headers, columns []string, field string) ([]int, error) {
+	match := s.Match
+	if match == nil {
+		match = strings.EqualFold
+	}
+	out := make([]int, len(columns))
+	for i, column := range columns {
+		out[i] = -1
+		if column == "" {
+			continue
+		}
+		out[i] = slices.IndexFunc(headers, func(h string) bool { return match(h, column) })
+		if out[i] < 0 {
+			return nil, model.NewValidationError(field,
+				fmt.Sprintf("unknown column %q (columns: %s)", column, strings.Join(headers, ", ")))
+		}
+	}
+	return out, nil
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// compute returns the aggregate a of a group's cells of its column.
+// Empty cells are left out of everything but count without a column.
+func compute(a Agg, cells []string) (string, error) {
+	if a.Func == "count" && a.Column == "" {
+		return strconv.Itoa(len(cells)), nil
+	}
+	cells = slices.DeleteFunc(slices.Clone(cells), func(c string) bool { return strings.TrimSpace(c) == "" })
+
+	switch a.Func {
+	case "count":
+		return strconv.Itoa(len(cells)), nil
+	case "distinct":
+		slices.Sort(cells)
+		return strconv.Itoa(len(slices.Compact(cells))), nil
+	case "min", "max":
+		if len(cells) == 0 {
+			return "", nil
+		}
+		// The cell itself, so a size or duration keeps its unit
+		cmpCells := compareCells(cells)
+		if a.Func == "min" {
+			return slices.MinFunc(cells, cmpCells), nil
+		}
+		return slices.MaxFunc(cells, cmpCells), nil
+	}
+
+	// sum and avg
+	var total float64
+	kind, mixed := unitNone, false
+	for i, c := range cells {
+		n, k, ok := parse(c)
+		if !ok {
+			return "", model.NewValidationError("agg", fmt.Sprintf("%s: %q isn't a number", a.Name, c))
+		}
+		if i == 0 {
+			kind = k
+		}
+		mixed = mixed || k != kind
+		total += n
+	}
+	if mixed {
+		kind = unitNone // Sizes and durations together sum as plain numbers
+	}
+	if a.Func == "avg" {
+		if len(cells) == 0 {
+			return "", nil
+		}
+		total /= float64(len(cells))
+	}
+	return format(total, kind), nil
+}
+
+// compareCells returns how cells compare: by value when they're all
+// numbers, sizes, or durations, and as text otherwise
+func compareCells(cells []string) func(a, b string) int {
+	for _, c := range cells {
+		if _, _, ok := parse(c); !ok {
+			return strings.Compare
+		}
+	}
+	return func(a, b string) int {
+		x, _, _ := parse(a)
+		y, _, _ := parse(b)
+		return cmp.Compare(x, y)
+	}
+}
+
+// unit is what a cell's number counts
+type unit int
+
+const (
+	unitNone    unit = iota
+	unitBytes        // A size such as 10MiB, parsed to bytes
+	unitSeconds      // A duration such as 1m30s, parsed to seconds
+)
+
+// parse returns a cell's number: as it's written, a size in bytes, or a
+// duration in seconds
+func parse(s string) (float64, unit, bool) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(n, 0) && !math.IsNaN(n) {
+		return n, unitNone, true
+	}
+	if strings.IndexFunc(s, unicode.IsLetter) <= 0 {
+		return 0, unitNone, false
+	}
+	if b, err := config.ParseByteSize(s); err == nil {
+		return float64(b), unitBytes, true
+	}
+	if d, err := config.ParseDuration(s); err == nil {
+		return d.Seconds(), unitSeconds, true
+	}
+	return 0, unitNone, false
+}
+
+// format writes a sum or average in the unit of the cells it came from
+func format(n float64, u unit) string {
+	switch u {
+	case unitBytes:
+		return formatBytes(n)
+	case unitSeconds:
+		d := time.Duration(n * float64(time.Second))
+		if d.Abs() >= time.Millisecond {
+			d = d.Round(time.Millisecond)
+		}
+		return d.String()
+	}
+	return strconv.FormatFloat(math.Round(n*100)/100, 'f', -1, 64)
+}
+
+// formatBytes writes a number of bytes with the largest binary unit it
+// reaches and one decimal, e.g. 1.5GiB
+func formatBytes(n float64) string {
+	for _, u := range []struct {
+		size float64
+		name string
+	}{{1 << 40, "TiB"}, {1 << 30, "GiB"}, {1 << 20, "MiB"}, {1 << 10, "KiB"}} {
+		if math.Abs(n) >= u.size {
+			return strings.TrimSuffix(strconv.FormatFloat(n/u.size, 'f', 1, 64), ".0") + u.name
+		}
+	}
+	return strconv.FormatFloat(math.Round(n), 'f', 0, 64) + "B"
+}
@@ -0,0 +1,186 @@
+package aggregate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+var files = [][]string{
+	{"Name", "Region", "Kind", "Size", "Took"},
+	{"a", "eu", "log", "1KiB", "1s"},
+	{"b", "us", "log", "512B", "500ms"},
+	{"c", "eu", "img", "2KiB", "2s"},
+	{"d", "eu", "log", "1KiB", ""},
+	{"e", "us", "img", "", "1m"},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		aggs    string
+		want    *Spec
+	}{
+		{name: "count by default", groupBy: "region", want: &Spec{GroupBy: []string{"region"}, Aggs: []Agg{{Name: "count", Func: "count"}}}},
+		{name: "several columns", groupBy: " region, ,kind ", want: &Spec{GroupBy: []string{"region", "kind"}, Aggs: []Agg{{Name: "count", Func: "count"}}}},
+		{name: "no group-by", aggs: "sum(size)", want: &Spec{Aggs: []Agg{{Name: "sum(size)", Func: "sum", Column: "size"}}}},
+		{
+			name: "named", groupBy: "region", aggs: "count, largest = MAX( size ),distinct(kind)",
+			want: &Spec{GroupBy: []string{"region"}, Aggs: []Agg{
+				{Name: "count", Func: "count"},
+				{Name: "largest", Func: "max", Column: "size"},
+				{Name: "distinct(kind)", Func: "distinct", Column: "kind"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.groupBy, tt.aggs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		aggs    string
+		field   string
+		want    string // In the message
+	}{
+		{name: "nothing", groupBy: " , ", field: "group-by", want: "expected columns"},
+		{name: "unknown function", aggs: "median(size)", field: "agg", want: `unknown function "median"`},
+		{name: "unclosed", groupBy: "region", aggs: "count,sum(size", field: "agg", want: "expected func(column)"},
+		{name: "unclosed with others", aggs: "sum(size,count", field: "agg", want: "expected func(column)"},
+		{name: "empty name", aggs: "=sum(size)", field: "agg", want: "empty name"},
+		{name: "no column", aggs: "sum", field: "agg", want: "sum needs a column"},
+		{name: "empty column", aggs: "avg()", field: "agg", want: "avg needs a column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.groupBy, tt.aggs)
+			var verr *model.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Parse() error = %v, want a validation error", err)
+			}
+			if verr.Field != tt.field || !strings.Contains(verr.Message, tt.want) {
+				t.Errorf("Parse() error = %v, want one on %s with %q", err, tt.field, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		aggs    string
+		table   [][]string
+		want    [][]string
+	}{
+		{
+			name: "count", groupBy: "region", table: files,
+			want: [][]string{{"Region", "count"}, {"eu", "3"}, {"us", "2"}},
+		},
+		{
+			name: "count of a column", aggs: "count(size),count(took)", table: files,
+			want: [][]string{{"count(size)", "count(took)"}, {"4", "4"}},
+		},
+		{
+			name: "distinct", aggs: "distinct(kind),distinct(size)", table: files,
+			want: [][]string{{"distinct(kind)", "distinct(size)"}, {"2", "3"}},
+		},
+		{
+			name: "sum of sizes and durations", groupBy: "region", aggs: "sum(size),sum(took)", table: files,
+			want: [][]string{{"Region", "sum(size)", "sum(took)"}, {"eu", "4KiB", "3s"}, {"us", "512B", "1m0.5s"}},
+		},
+		{
+			name: "avg", groupBy: "kind", aggs: "avg(size),avg(took)", table: files,
+			want: [][]string{{"Kind", "avg(size)", "avg(took)"}, {"log", "853B", "750ms"}, {"img", "2KiB", "31s"}},
+		},
+		{
+			name: "min and max by value", aggs: "min(size),max(size),min(took),max(took),max(name)", table: files,
+			want: [][]string{{"min(size)", "max(size)", "min(took)", "max(took)", "max(name)"}, {"512B", "2KiB", "500ms", "1m", "e"}},
+		},
+		{
+			name: "plain numbers", aggs: "sum(n),avg(n),min(n),max(n)",
+			table: [][]string{{"N"}, {"10"}, {"2.5"}, {"-1"}},
+			want:  [][]string{{"sum(n)", "avg(n)", "min(n)", "max(n)"}, {"11.5", "3.83", "-1", "10"}},
+		},
+		{
+			name: "several columns", groupBy: "region,kind", aggs: "n=count,total=sum(size)", table: files,
+			want: [][]string{
+				{"Region", "Kind", "n", "total"},
+				{"eu", "log", "2", "2KiB"},
+				{"us", "log", "1", "512B"},
+				{"eu", "img", "1", "2KiB"},
+				{"us", "img", "1", "0"}, // No sizes, so no unit
+			},
+		},
+		{
+			name: "no rows grouped", groupBy: "region", aggs: "count,sum(size)", table: files[:1],
+			want: [][]string{{"Region", "count", "sum(size)"}},
+		},
+		{
+			name: "no rows", aggs: "count,sum(size),avg(size),min(size)", table: files[:1],
+			want: [][]string{{"count", "sum(size)", "avg(size)", "min(size)"}, {"0", "0", "", ""}},
+		},
+		{name: "no table", groupBy: "region", table: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Parse(tt.groupBy, tt.aggs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := spec.Table(tt.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Table() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy string
+		aggs    string
+		field   string
+		want    string // In the message
+	}{
+		{name: "sum of text", aggs: "sum(name)", field: "agg", want: `sum(name): "a" isn't a number`},
+		{name: "avg of text", groupBy: "region", aggs: "avg(kind)", field: "agg", want: `avg(kind): "log" isn't a number`},
+		{name: "unknown group-by column", groupBy: "zone", field: "group-by", want: `unknown column "zone"`},
+		{name: "unknown agg column", aggs: "max(weight)", field: "agg", want: `unknown column "weight"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Parse(tt.groupBy, tt.aggs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = spec.Table(files)
+			var verr *model.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Table() error = %v, want a validation error", err)
+			}
+			if verr.Field != tt.field || !strings.Contains(verr.Message, tt.want) {
+				t.Errorf("Table() error = %v, want one on %s with %q", err, tt.field, tt.want)
+			}
+		})
+	}
+}
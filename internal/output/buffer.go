@@ -0,0 +1,147 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Buffer is a writer that holds output for an underlying writer, so a row
+// written in many small pieces costs one write rather than one each. It
+// writes out what it holds once it has size bytes, once interval has
+// passed since it started holding them, so a slow stream still shows up
+// promptly, and on Flush. Buffers holding output are flushed by FlushAll
+// when the program exits. It's safe for concurrent use.
+type Buffer struct {
+	mu       sync.Mutex
+	w        *bufio.Writer // nil writes straight to out
+	out      io.Writer
+	interval time.Duration
+	held     bool        // Output is held, and the buffer is in pending
+	timer    *time.Timer // Set while held output waits for the interval
+	err      error       // From a flush by the timer, returned by the next call
+}
+
+// pending are the buffers holding output, for FlushAll
+var (
+	pendingMu sync.Mutex
+	pending   = map[*Buffer]struct{}{}
+)
+
+// NewBuffer returns a buffer of size bytes for out, flushed interval after
+// output is first held. A size of 0 writes straight through; an interval
+// of 0 only flushes a full buffer and on Flush.
+func NewBuffer(out io.Writer, size int, interval time.Duration) *Buffer {
+	b := &Buffer{out: out, interval: interval}
+	if size > 0 {
+		b.w = bufio.NewWriterSize(out, size)
+	}
+	return b
+}
+
+// Write implements io.Writer
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return 0, err
+	}
+	if b.w == nil {
+		return b.out.Write(p)
+	}
+
+	n, err := b.w.Write(p)
+	if b.w.Buffered() == 0 {
+		b.release()
+		return n, err
+	}
+	if !b.held {
+		b.held = true
+		pendingMu.Lock()
+		pending[b] = struct{}{}
+		pendingMu.Unlock()
+		if b.interval > 0 {
+			b.timer = time.AfterFunc(b.interval, b.flushOnTimer)
+		}
+	}
+	return n, err
+}
+
+// Flush writes out the output held
+func (b *Buffer) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+func (b *Buffer) flush() error {
+	b.release()
+	if b.w == nil {
+		return nil
+	}
+	if err := b.w.Flush(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+func (b *Buffer) flushOnTimer() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer == nil {
+		// Flushed since the timer fired
+		return
+	}
+	b.timer = nil
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// release stops waiting for the interval, as nothing's held any more
+func (b *Buffer) release() {
+	if !b.held {
+		return
+	}
+	b.held = false
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	pendingMu.Lock()
+	delete(pending, b)
+	pendingMu.Unlock()
+}
+
+// takeErr returns and clears the error of the last flush by the timer
+func (b *Buffer) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// FlushAll flushes every buffer holding output, such as one left by a
+// stream that ended early on an error or interrupt. It's called as the
+// program exits, before stdout is closed or committed to --output-file,
+// and returns the first error.
+func FlushAll() error {
+	pendingMu.Lock()
+	buffers := make([]*Buffer, 0, len(pending))
+	for b := range pending {
+		buffers = append(buffers, b)
+	}
+	pendingMu.Unlock()
+
+	var first error
+	for _, b := range buffers {
+		if err := b.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,618 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Column is a compiled --add-column definition, name=expr: a column
+// computed from the others of each row before it's filtered, sorted, and
+// printed.
+//
+//	mib=size/1048576                  arithmetic: + - * / % and parentheses
+//	label=name + ' (' + region + ')'  + joins text unless both sides are numbers
+//	gib=round(size/1073741824, 2)     functions (see calcFuncs)
+//	size=bytes(size)                  replaces the column of the same name
+//
+// Columns are named as in --filter. A cell is a number when it's written
+// as one, or as a size (10MiB, in bytes) or a duration (1m30s, in
+// seconds). An empty cell makes arithmetic empty, as does dividing by zero.
+type Column struct {
+	Name string
+	src  string
+	expr calcExpr
+}
+
+// calcValue is the value of an expression: a number, or text
+type calcValue struct {
+	text  string
+	num   float64
+	isNum bool
+}
+
+// calcExpr evaluates an expression for a row; get returns a column's value
+type calcExpr func(get func(column string) (string, error)) (calcValue, error)
+
+// CompileColumn parses a derived column definition, name=expr
+func CompileColumn(def string) (*Column, error) {
+	name, src, ok := strings.Cut(def, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return nil, model.NewValidationError("add-column", fmt.Sprintf("%q: expected name=expression", def))
+	}
+	p := &calcParser{src: src}
+	expr, err := p.parseSum()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.src) {
+			err = fmt.Errorf("unexpected %q at offset %d", p.src[p.pos:], p.pos)
+		}
+	}
+	if err != nil {
+		return nil, model.NewValidationError("add-column", fmt.Sprintf("%s: %v", name, err))
+	}
+	return &Column{Name: name, src: def, expr: expr}, nil
+}
+
+// String returns the column definition
+func (c *Column) String() string {
+	return c.src
+}
+
+// eval computes the column for a row; get returns a column's value
+func (c *Column) eval(get func(column string) (string, error)) (calcValue, error) {
+	v, err := c.expr(get)
+	var verr *model.ValidationError
+	if err != nil && !errors.As(err, &verr) {
+		err = model.NewValidationError("add-column", fmt.Sprintf("%s: %v", c.Name, err))
+	}
+	return v, err
+}
+
+// derivedColumns compiles output.add_columns
+func (f *Formatter) derivedColumns() ([]*Column, error) {
+	cols := make([]*Column, 0, len(f.config.AddColumns))
+	for _, def := range f.config.AddColumns {
+		c, err := CompileColumn(def)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+// deriveHeaders returns headers with the columns of cols that aren't in
+// them added at the end
+func deriveHeaders(headers []string, cols []*Column) []string {
+	headers = slices.Clone(headers)
+	for _, c := range cols {
+		if headerIndex(headers, c.Name) < 0 {
+			headers = append(headers, c.Name)
+		}
+	}
+	return headers
+}
+
+// deriveRow returns row, under headers, with the columns of cols computed
+// in order, so each can use those before it. The result is under
+// deriveHeaders(headers, cols).
+func deriveRow(headers, row []string, cols []*Column) ([]string, error) {
+	headers, row = slices.Clone(headers), slices.Clone(row)
+	for _, c := range cols {
+		v, err := c.eval(func(column string) (string, error) {
+			i := headerIndex(headers, column)
+			if i < 0 {
+				return "", model.NewValidationError("add-column",
+					fmt.Sprintf("%s: unknown column %q (columns: %s)", c.Name, column, strings.Join(headers, ", ")))
+			}
+			return rowCell(row, i), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		i := headerIndex(headers, c.Name)
+		if i < 0 {
+			headers = append(headers, c.Name)
+			i = len(headers) - 1
+		}
+		for len(row) <= i {
+			row = append(row, "")
+		}
+		row[i] = v.String()
+	}
+	return row, nil
+}
+
+// deriveTable returns table with the columns of cols computed for each
+// row after the header
+func deriveTable(table [][]string, cols []*Column) ([][]string, error) {
+	if len(cols) == 0 || len(table) == 0 {
+		return table, nil
+	}
+	out := make([][]string, len(table))
+	out[0] = deriveHeaders(table[0], cols)
+	for i, row := range table[1:] {
+		derived, err := deriveRow(table[0], row, cols)
+		if err != nil {
+			return nil, err
+		}
+		out[i+1] = derived
+	}
+	return out, nil
+}
+
+// deriveValue returns v, a generic value (see toValue), with the columns
+// of cols set on each object of a list; numbers are JSON numbers. Items
+// without a column have an empty value for it, but a column that no item
+// has is an error, as in filterValue. Anything but a list is returned as
+// is.
+func deriveValue(v interface{}, cols []*Column) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(cols) == 0 {
+		return v, nil
+	}
+	found := map[string]bool{}
+	for _, item := range list {
+		obj, ok := item.(*object)
+		if !ok {
+			continue
+		}
+		for _, c := range cols {
+			value, err := c.eval(func(column string) (string, error) {
+				s, ok := objectField(obj, column)
+				found[column] = found[column] || ok
+				return s, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			key := c.Name
+			if i := headerIndex(obj.keys, c.Name); i >= 0 {
+				key = obj.keys[i]
+			}
+			if value.isNum {
+				obj.set(key, value.num)
+			} else {
+				obj.set(key, value.text)
+			}
+		}
+	}
+	for column, ok := range found {
+		if !ok {
+			return nil, model.NewValidationError("add-column", fmt.Sprintf("no item has a field matching %q", column))
+		}
+	}
+	return list, nil
+}
+
+// headerIndex returns the index of the header naming column, or -1
+func headerIndex(headers []string, column string) int {
+	return slices.IndexFunc(headers, func(h string) bool {
+		return columnKey(h) == columnKey(column)
+	})
+}
+
+// Values
+
+func numberValue(n float64) calcValue {
+	return calcValue{num: n, isNum: true}
+}
+
+func textValue(s string) calcValue {
+	return calcValue{text: s}
+}
+
+func (v calcValue) String() string {
+	if v.isNum {
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	}
+	return v.text
+}
+
+// empty reports whether v is empty text, which arithmetic passes on
+func (v calcValue) empty() bool {
+	return !v.isNum && v.text == ""
+}
+
+// number returns v as a number: a size in bytes, a duration in seconds,
+// or the number it's written as. ok is false when it isn't one.
+func (v calcValue) number() (n float64, ok bool) {
+	if v.isNum {
+		return v.num, true
+	}
+	if n, ok := parseNumber(v.text); ok {
+		return n, true
+	}
+	if strings.IndexFunc(v.text, unicode.IsLetter) > 0 {
+		if b, err := config.ParseByteSize(v.text); err == nil {
+			return float64(b), true
+		}
+		if d, ok := parseFilterDuration(v.text); ok {
+			return d.Seconds(), true
+		}
+	}
+	return 0, false
+}
+
+// mustNumber returns v as a number, or an error naming what it is
+func (v calcValue) mustNumber() (float64, error) {
+	n, ok := v.number()
+	if !ok {
+		return 0, fmt.Errorf("%q is not a number", v.text)
+	}
+	return n, nil
+}
+
+// Parser
+
+type calcParser struct {
+	src string
+	pos int
+}
+
+func (p *calcParser) parseSum() (calcExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept("+"):
+			op = '+'
+		case p.accept("-"):
+			op = '-'
+		default:
+			return left, nil
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = calcBinary(op, left, right)
+	}
+}
+
+func (p *calcParser) parseProduct() (calcExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept("*"):
+			op = '*'
+		case p.accept("/"):
+			op = '/'
+		case p.accept("%"):
+			op = '%'
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = calcBinary(op, left, right)
+	}
+}
+
+func (p *calcParser) parseUnary() (calcExpr, error) {
+	if p.accept("-") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return calcBinary('-', calcConstant(numberValue(0)), inner), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *calcParser) parsePrimary() (calcExpr, error) {
+	p.skipSpace()
+	if p.accept("(") {
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+		}
+		return inner, nil
+	}
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("expected a value at offset %d", p.pos)
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '\'' || c == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], c)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		s := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return calcConstant(textValue(s)), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", p.src[start:p.pos], start)
+		}
+		return calcConstant(numberValue(n)), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.pos += size
+	}
+	name := p.src[start:p.pos]
+	if name == "" {
+		return nil, fmt.Errorf("expected a value at offset %d", start)
+	}
+	if !p.accept("(") {
+		return func(get func(string) (string, error)) (calcValue, error) {
+			cell, err := get(name)
+			return textValue(cell), err
+		}, nil
+	}
+	return p.parseCall(name, start)
+}
+
+// parseCall reads the arguments of a call to the function name, after
+// its opening parenthesis
+func (p *calcParser) parseCall(name string, at int) (calcExpr, error) {
+	fn, ok := calcFuncs[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at offset %d (functions: %s)", name, at, strings.Join(calcFuncNames(), ", "))
+	}
+	var args []calcExpr
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if !p.accept(",") {
+				return nil, fmt.Errorf("expected , or ) at offset %d", p.pos)
+			}
+		}
+	}
+	if len(args) < fn.min || (fn.max >= 0 && len(args) > fn.max) {
+		return nil, fmt.Errorf("%s takes %s at offset %d", name, fn.arity(), at)
+	}
+	return func(get func(string) (string, error)) (calcValue, error) {
+		values := make([]calcValue, len(args))
+		for i, arg := range args {
+			v, err := arg(get)
+			if err != nil {
+				return calcValue{}, err
+			}
+			values[i] = v
+		}
+		v, err := fn.call(values)
+		if err != nil {
+			return calcValue{}, fmt.Errorf("%s: %w", name, err)
+		}
+		return v, nil
+	}, nil
+}
+
+func (p *calcParser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// calcConstant returns an expression that is v
+func calcConstant(v calcValue) calcExpr {
+	return func(func(string) (string, error)) (calcValue, error) {
+		return v, nil
+	}
+}
+
+// calcBinary returns the expression left op right. + joins text unless both
+// sides are numbers; the others need numbers.
+func calcBinary(op byte, left, right calcExpr) calcExpr {
+	return func(get func(string) (string, error)) (calcValue, error) {
+		l, err := left(get)
+		if err != nil {
+			return calcValue{}, err
+		}
+		r, err := right(get)
+		if err != nil {
+			return calcValue{}, err
+		}
+		if l.empty() || r.empty() {
+			return textValue(""), nil
+		}
+
+		a, aok := l.number()
+		b, bok := r.number()
+		if op == '+' && (!aok || !bok) {
+			return textValue(l.String() + r.String()), nil
+		}
+		if !aok {
+			return calcValue{}, fmt.Errorf("%q is not a number", l.text)
+		}
+		if !bok {
+			return calcValue{}, fmt.Errorf("%q is not a number", r.text)
+		}
+		switch op {
+		case '+':
+			return numberValue(a + b), nil
+		case '-':
+			return numberValue(a - b), nil
+		case '*':
+			return numberValue(a * b), nil
+		}
+		if b == 0 {
+			return textValue(""), nil
+		}
+		if op == '/' {
+			return numberValue(a / b), nil
+		}
+		return numberValue(math.Mod(a, b)), nil
+	}
+}
+
+// Functions
+
+// calcFunc is a function expressions can call, with min to max arguments
+// (max -1 for any number)
+type calcFunc struct {
+	min, max int
+	call     func(args []calcValue) (calcValue, error)
+}
+
+func (f calcFunc) arity() string {
+	switch {
+	case f.max < 0:
+		return fmt.Sprintf("at least %d arguments", f.min)
+	case f.min == f.max && f.min == 1:
+		return "1 argument"
+	case f.min == f.max:
+		return fmt.Sprintf("%d arguments", f.min)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.min, f.max)
+}
+
+// calcFuncs are the functions of --add-column expressions
+var calcFuncs = map[string]calcFunc{
+	"upper": {1, 1, textFunc(strings.ToUpper)},
+	"lower": {1, 1, textFunc(strings.ToLower)},
+	"trim":  {1, 1, textFunc(strings.TrimSpace)},
+	"len": {1, 1, func(args []calcValue) (calcValue, error) {
+		return numberValue(float64(utf8.RuneCountInString(args[0].String()))), nil
+	}},
+	"concat": {1, -1, func(args []calcValue) (calcValue, error) {
+		var b strings.Builder
+		for _, a := range args {
+			b.WriteString(a.String())
+		}
+		return textValue(b.String()), nil
+	}},
+	"replace": {3, 3, func(args []calcValue) (calcValue, error) {
+		return textValue(strings.ReplaceAll(args[0].String(), args[1].String(), args[2].String())), nil
+	}},
+	// substr(s, start, n) takes n runes of s from start, counted from 0
+	"substr": {2, 3, func(args []calcValue) (calcValue, error) {
+		runes := []rune(args[0].String())
+		start, err := args[1].mustNumber()
+		if err != nil {
+			return calcValue{}, err
+		}
+		from := min(max(int(start), 0), len(runes))
+		to := len(runes)
+		if len(args) == 3 {
+			n, err := args[2].mustNumber()
+			if err != nil {
+				return calcValue{}, err
+			}
+			to = min(from+max(int(n), 0), len(runes))
+		}
+		return textValue(string(runes[from:to])), nil
+	}},
+	// round(x, digits) rounds to digits decimals, 0 by default
+	"round": {1, 2, numberFunc(func(x []float64) float64 {
+		if len(x) == 1 {
+			return math.Round(x[0])
+		}
+		scale := math.Pow(10, math.Round(x[1]))
+		return math.Round(x[0]*scale) / scale
+	})},
+	"floor": {1, 1, numberFunc(func(x []float64) float64 { return math.Floor(x[0]) })},
+	"ceil":  {1, 1, numberFunc(func(x []float64) float64 { return math.Ceil(x[0]) })},
+	"abs":   {1, 1, numberFunc(func(x []float64) float64 { return math.Abs(x[0]) })},
+	"min":   {1, -1, numberFunc(func(x []float64) float64 { return slices.Min(x) })},
+	"max":   {1, -1, numberFunc(func(x []float64) float64 { return slices.Max(x) })},
+	// bytes formats a number of bytes, e.g. 1.5MiB
+	"bytes": {1, 1, func(args []calcValue) (calcValue, error) {
+		if args[0].empty() {
+			return args[0], nil
+		}
+		n, err := args[0].mustNumber()
+		if err != nil {
+			return calcValue{}, err
+		}
+		return textValue(humanBytes(n, 0)), nil
+	}},
+	// duration formats a number of seconds, e.g. 3m12s
+	"duration": {1, 1, func(args []calcValue) (calcValue, error) {
+		if args[0].empty() {
+			return args[0], nil
+		}
+		n, err := args[0].mustNumber()
+		if err != nil {
+			return calcValue{}, err
+		}
+		return textValue(humanDuration(time.Duration(n * float64(time.Second)))), nil
+	}},
+}
+
+// calcFuncNames returns the names of calcFuncs, sorted
+func calcFuncNames() []string {
+	names := make([]string, 0, len(calcFuncs))
+	for name := range calcFuncs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// textFunc is a function of one argument's text
+func textFunc(fn func(string) string) func([]calcValue) (calcValue, error) {
+	return func(args []calcValue) (calcValue, error) {
+		return textValue(fn(args[0].String())), nil
+	}
+}
+
+// numberFunc is a function of numbers, empty when an argument is
+func numberFunc(fn func([]float64) float64) func([]calcValue) (calcValue, error) {
+	return func(args []calcValue) (calcValue, error) {
+		x := make([]float64, len(args))
+		for i, a := range args {
+			if a.empty() {
+				return a, nil
+			}
+			n, err := a.mustNumber()
+			if err != nil {
+				return calcValue{}, err
+			}
+			x[i] = n
+		}
+		return numberValue(fn(x)), nil
+	}
+}
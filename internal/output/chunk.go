@@ -0,0 +1,102 @@
+package output
+
+import (
+	"os"
+	"sync"
+)
+
+// Chunk is the page of list output one invocation prints when output is
+// paged across invocations (output.page_size)
+type Chunk struct {
+	Offset int  // Rows left out before the page: those on earlier pages
+	Size   int  // Rows on a page
+	Shown  int  // Rows printed, of the longest list when there are several
+	More   bool // Rows were left out after the page
+}
+
+var (
+	chunkMu sync.Mutex
+	chunk   *Chunk
+)
+
+// StartChunk makes list output to stdout print only the size rows after
+// the first offset, once filtered and sorted, until EndChunk
+func StartChunk(offset, size int) {
+	chunkMu.Lock()
+	defer chunkMu.Unlock()
+	chunk = &Chunk{Offset: offset, Size: size}
+}
+
+// EndChunk stops paging output and returns the page printed since
+// StartChunk; ok is false when it wasn't called
+func EndChunk() (c Chunk, ok bool) {
+	chunkMu.Lock()
+	defer chunkMu.Unlock()
+	if chunk == nil {
+		return Chunk{}, false
+	}
+	c, chunk = *chunk, nil
+	return c, true
+}
+
+// chunkRange returns the rows of the page f prints, [from, to) of the
+// rows it's given; ok is false when its output isn't paged
+func (f *Formatter) chunkRange() (from, to int, ok bool) {
+	if f.out != os.Stdout {
+		return 0, 0, false
+	}
+	chunkMu.Lock()
+	defer chunkMu.Unlock()
+	if chunk == nil {
+		return 0, 0, false
+	}
+	return chunk.Offset, chunk.Offset + chunk.Size, true
+}
+
+// chunked records that a list printed shown rows of the page, and whether
+// it left rows out after it. Each list printed is paged on its own.
+func chunked(shown int, more bool) {
+	chunkMu.Lock()
+	defer chunkMu.Unlock()
+	if chunk == nil {
+		return
+	}
+	chunk.Shown = max(chunk.Shown, shown)
+	chunk.More = chunk.More || more
+}
+
+// chunkSlice returns the page of items f prints
+func chunkSlice[T any](f *Formatter, items []T) []T {
+	from, to, ok := f.chunkRange()
+	if !ok {
+		return items
+	}
+	from, to = min(from, len(items)), min(to, len(items))
+	chunked(to-from, len(items) > to)
+	return items[from:to]
+}
+
+// chunkTable returns the page of table's rows f prints, with the headers
+func (f *Formatter) chunkTable(table [][]string) [][]string {
+	if _, _, ok := f.chunkRange(); !ok || len(table) == 0 {
+		return table
+	}
+	return append([][]string{table[0]}, chunkSlice(f, table[1:])...)
+}
+
+// chunkValue returns the page of data f prints when it's a list, and data
+// as it is otherwise
+func (f *Formatter) chunkValue(data interface{}) (interface{}, error) {
+	if table, ok := data.([][]string); ok {
+		return f.chunkTable(table), nil
+	}
+	v, err := resultValue(data)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return data, nil
+	}
+	return queryResult{chunkSlice(f, list)}, nil
+}
@@ -0,0 +1,169 @@
+package output
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Style is an ANSI SGR sequence, e.g. "1;31" for bold red
+type Style string
+
+const (
+	StyleNone   Style = ""
+	StyleBold   Style = "1"
+	StyleDim    Style = "2"
+	StyleRed    Style = "31"
+	StyleGreen  Style = "32"
+	StyleYellow Style = "33"
+	StyleBlue   Style = "34"
+	StyleCyan   Style = "36"
+)
+
+// StyleFunc picks the style of a cell from its value
+type StyleFunc func(value string) Style
+
+// Paint wraps s in the style's escape sequences, as restyled by the
+// current theme (see SetTheme)
+func (s Style) Paint(text string) string {
+	s = s.themed()
+	if s == StyleNone || text == "" {
+		return text
+	}
+	return "\x1b[" + string(s) + "m" + text + "\x1b[0m"
+}
+
+// severities maps status-like values to styles
+var severities = map[string]Style{
+	"error":       StyleRed,
+	"failed":      StyleRed,
+	"fatal":       StyleRed,
+	"conflict":    StyleRed,
+	"warn":        StyleYellow,
+	"warning":     StyleYellow,
+	"skipped":     StyleYellow,
+	"rolled_back": StyleYellow,
+	"info":        StyleBlue,
+	"ok":          StyleGreen,
+	"pass":        StyleGreen,
+	"passed":      StyleGreen,
+	"success":     StyleGreen,
+	"succeeded":   StyleGreen,
+	"debug":       StyleDim,
+}
+
+// SeverityStyle colors status and log-level values: failures red, warnings
+// yellow, successes green
+func SeverityStyle(value string) Style {
+	return severities[strings.ToLower(strings.TrimSpace(value))]
+}
+
+// defaultColumnStyles applies SeverityStyle to the usual status columns
+var defaultColumnStyles = map[string]StyleFunc{
+	"status":   SeverityStyle,
+	"severity": SeverityStyle,
+	"level":    SeverityStyle,
+	"result":   SeverityStyle,
+}
+
+// ColorEnabled reports whether output to w should be colored. Everything
+// that colors output decides with it, following the NO_COLOR and CLICOLOR
+// conventions in this order:
+//
+//   - off when NO_COLOR is set to anything but "", or enabled is false
+//     (output.color or --no-color)
+//   - on when CLICOLOR_FORCE is set to anything but "" or "0", even when w
+//     isn't a terminal
+//   - off when CLICOLOR is "0" or TERM is "dumb"
+//   - otherwise on when w is a terminal
+func ColorEnabled(enabled bool, w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || !enabled {
+		return false
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// CursorControl reports whether w is a terminal that can redraw lines in
+// place, as progress displays do: not when TERM is "dumb"
+func CursorControl(w io.Writer) bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	// Unlike isTerminal, not fooled by /dev/null
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// WithColumnStyle styles the cells of the named column (matched
+// case-insensitively against the header) in table output
+func (f *Formatter) WithColumnStyle(header string, style StyleFunc) *Formatter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.columnStyles == nil {
+		f.columnStyles = map[string]StyleFunc{}
+	}
+	f.columnStyles[strings.ToLower(header)] = style
+	return f
+}
+
+// WithColor forces color on or off, overriding detection
+func (f *Formatter) WithColor(on bool) *Formatter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.color = on
+	return f
+}
+
+// cellStyles returns the style function of each column in header, or nil
+// when color is off
+func (f *Formatter) cellStyles(header []string) []StyleFunc {
+	if !f.color {
+		return nil
+	}
+	return f.columnStyleFuncs(header)
+}
+
+// columnStyleFuncs returns the style function of each column in header
+func (f *Formatter) columnStyleFuncs(header []string) []StyleFunc {
+	styles := make([]StyleFunc, len(header))
+	for i, h := range header {
+		key := strings.ToLower(h)
+		if style, ok := f.columnStyles[key]; ok {
+			styles[i] = style
+		} else {
+			styles[i] = defaultColumnStyles[key]
+		}
+	}
+	return styles
+}
+
+// paintCell pads a cell to width, aligned per align, and styles it. Only
+// the text is styled; widths are measured without the escape sequences.
+func (f *Formatter) paintCell(cell string, width int, style Style, align string) string {
+	if !f.color || style == StyleNone {
+		return alignCell(cell, width, align)
+	}
+	gap := max(0, width-displayWidth(cell))
+	left := alignPadding(gap, align)
+	return strings.Repeat(" ", left) + style.Paint(cell) + strings.Repeat(" ", gap-left)
+}
@@ -0,0 +1,146 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/termtest"
+)
+
+// colorEnv are the variables ColorEnabled and CursorControl read
+var colorEnv = []string{"NO_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "TERM"}
+
+// setColorEnv sets the variables of colorEnv to env's values, and the
+// others to empty
+func setColorEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, k := range colorEnv {
+		t.Setenv(k, env[k])
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		disabled bool // output.color false, as with --no-color
+		terminal bool
+		want     bool
+	}{
+		{name: "terminal", env: map[string]string{"TERM": "xterm"}, terminal: true, want: true},
+		{name: "not a terminal", env: map[string]string{"TERM": "xterm"}},
+		{name: "disabled", env: map[string]string{"TERM": "xterm"}, disabled: true, terminal: true},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "TERM": "xterm"}, terminal: true},
+		{name: "NO_COLOR empty", env: map[string]string{"TERM": "xterm"}, terminal: true, want: true},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}, terminal: true},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0", "TERM": "xterm"}, terminal: true},
+		{name: "CLICOLOR=1", env: map[string]string{"CLICOLOR": "1", "TERM": "xterm"}, terminal: true, want: true},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1"}, want: true},
+		{name: "CLICOLOR_FORCE=0", env: map[string]string{"CLICOLOR_FORCE": "0", "TERM": "xterm"}},
+		{name: "CLICOLOR_FORCE over TERM=dumb", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"}, want: true},
+		{name: "CLICOLOR_FORCE over CLICOLOR=0", env: map[string]string{"CLICOLOR_FORCE": "1", "CLICOLOR": "0"}, want: true},
+		{name: "NO_COLOR over CLICOLOR_FORCE", env: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, terminal: true},
+		{name: "disabled over CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1"}, disabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setColorEnv(t, tt.env)
+			var w io.Writer = &bytes.Buffer{}
+			if tt.terminal {
+				w = termtest.Open(t)
+			}
+			if got := ColorEnabled(!tt.disabled, w); got != tt.want {
+				t.Errorf("ColorEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorControl(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		terminal bool
+		want     bool
+	}{
+		{name: "terminal", env: map[string]string{"TERM": "xterm"}, terminal: true, want: true},
+		{name: "not a terminal", env: map[string]string{"TERM": "xterm"}},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}, terminal: true},
+		// Color settings don't stop a terminal from redrawing lines
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "TERM": "xterm"}, terminal: true, want: true},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0", "TERM": "xterm"}, terminal: true, want: true},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR_FORCE and TERM=dumb", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"}, terminal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setColorEnv(t, tt.env)
+			var w io.Writer = &bytes.Buffer{}
+			if tt.terminal {
+				w = termtest.Open(t)
+			}
+			if got := CursorControl(w); got != tt.want {
+				t.Errorf("CursorControl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableColor(t *testing.T) {
+	table := [][]string{{"Name", "Status"}, {"a", "failed"}, {"b", "succeeded"}}
+	tests := []struct {
+		name  string
+		env   map[string]string
+		theme string
+		want  []string // Escape sequences the output has; none when empty
+	}{
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1"}, want: []string{"\x1b[31mfailed\x1b[0m", "\x1b[32msucceeded\x1b[0m"}},
+		{name: "deuteranopia", env: map[string]string{"CLICOLOR_FORCE": "1"}, theme: "deuteranopia", want: []string{"\x1b[38;5;166mfailed", "\x1b[38;5;32msucceeded"}},
+		{name: "high-contrast", env: map[string]string{"CLICOLOR_FORCE": "1"}, theme: "high-contrast", want: []string{"\x1b[1;91mfailed", "\x1b[1;92msucceeded"}},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, theme: "high-contrast"},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0"}},
+		{name: "not a terminal", env: map[string]string{"TERM": "xterm"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setColorEnv(t, tt.env)
+			if err := SetTheme(tt.theme); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = SetTheme("") })
+
+			var buf bytes.Buffer
+			f := NewFormatterWithWriter(config.OutputConfig{Format: "table", ColorOutput: true}, &buf)
+			if err := f.Print(table); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			if len(tt.want) == 0 && strings.Contains(out, "\x1b[") {
+				t.Errorf("output has escape sequences:\n%q", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output is missing %q:\n%q", want, out)
+				}
+			}
+			// Colors don't change the layout
+			if plain := ansiRe.ReplaceAllString(out, ""); !strings.Contains(plain, "| a    | failed    |") {
+				t.Errorf("misaligned table:\n%s", plain)
+			}
+		})
+	}
+}
+
+func TestSetThemeUnknown(t *testing.T) {
+	t.Cleanup(func() { _ = SetTheme("") })
+	if err := SetTheme("sepia"); err == nil {
+		t.Fatal("SetTheme(sepia) succeeded, want an error")
+	}
+	if got := CurrentTheme().Name; got != "default" {
+		t.Errorf("theme = %q after a failed SetTheme, want default", got)
+	}
+}
@@ -0,0 +1,209 @@
+package output
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// columnFormats returns the configured format of each column in headers
+// (see config.OutputConfig.Columns), or nil when none is configured
+func (f *Formatter) columnFormats(headers []string) []config.ColumnFormat {
+	if len(f.config.Columns) == 0 {
+		return nil
+	}
+	formats := make([]config.ColumnFormat, len(headers))
+	for name, cf := range f.config.Columns {
+		for i, h := range headers {
+			if columnKey(h) == columnKey(name) {
+				formats[i] = cf
+			}
+		}
+	}
+	return formats
+}
+
+// columnAligns returns the alignment of each column with formats
+func columnAligns(formats []config.ColumnFormat) []string {
+	if formats == nil {
+		return nil
+	}
+	aligns := make([]string, len(formats))
+	for i, cf := range formats {
+		aligns[i] = cf.Align
+	}
+	return aligns
+}
+
+// align returns the alignment of column i of the table being printed
+func (f *Formatter) align(i int) string {
+	if i < len(f.aligns) {
+		return f.aligns[i]
+	}
+	return ""
+}
+
+// formatTable returns table with the cells after the header formatted
+func formatTable(table [][]string, formats []config.ColumnFormat) [][]string {
+	if formats == nil || len(table) == 0 {
+		return table
+	}
+	out := [][]string{table[0]}
+	for _, row := range table[1:] {
+		out = append(out, formatRow(row, formats))
+	}
+	return out
+}
+
+// formatRow returns row with each cell formatted for its column
+func formatRow(row []string, formats []config.ColumnFormat) []string {
+	if formats == nil {
+		return row
+	}
+	out := make([]string, len(row))
+	for i, cell := range row {
+		if i < len(formats) {
+			cell = formatCell(cell, formats[i])
+		}
+		out[i] = cell
+	}
+	return out
+}
+
+// formatCell formats a number, size, or duration cell; anything else is
+// returned as is
+func formatCell(cell string, cf config.ColumnFormat) string {
+	switch cf.Unit {
+	case "bytes":
+		if n, ok := parseNumber(cell); ok {
+			return humanBytes(n, cf.Digits)
+		}
+		if b, err := config.ParseByteSize(cell); err == nil {
+			return humanBytes(float64(b), cf.Digits)
+		}
+		return cell
+	case "duration":
+		if d, ok := parseFilterDuration(cell); ok {
+			return humanDuration(d)
+		}
+		if n, ok := parseNumber(cell); ok {
+			return humanDuration(time.Duration(n * float64(time.Second)))
+		}
+		return cell
+	}
+
+	n, ok := parseNumber(cell)
+	if !ok || (cf.Digits == 0 && !cf.Thousands) {
+		return cell
+	}
+	s := strings.TrimSpace(cell)
+	if cf.Digits > 0 {
+		s = significant(n, cf.Digits)
+	}
+	if cf.Thousands {
+		s = groupThousands(s)
+	}
+	return s
+}
+
+// significant formats n rounded to digits significant digits, without an
+// exponent: 1234.5 is 1230 and 0.012345 is 0.0123 to 3 digits
+func significant(n float64, digits int) string {
+	if n == 0 {
+		return "0"
+	}
+	shift := digits - 1 - int(math.Floor(math.Log10(math.Abs(n))))
+	n = math.Round(n*math.Pow10(shift)) / math.Pow10(shift)
+	return strconv.FormatFloat(n, 'f', max(0, shift), 64)
+}
+
+// groupThousands puts commas between groups of three digits in the integer
+// part of a decimal number; numbers with an exponent are left as they are
+func groupThousands(s string) string {
+	if strings.ContainsAny(s, "eExX") {
+		return s
+	}
+	sign := ""
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		sign, s = s[:1], s[1:]
+	}
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		return sign + b.String() + "." + frac
+	}
+	return sign + b.String()
+}
+
+// byteUnits are the binary units of humanBytes, largest first
+var byteUnits = []struct {
+	size float64
+	name string
+}{{1 << 50, "PiB"}, {1 << 40, "TiB"}, {1 << 30, "GiB"}, {1 << 20, "MiB"}, {1 << 10, "KiB"}}
+
+// humanBytes formats a number of bytes with the largest binary unit it
+// reaches, e.g. 1.2GiB: with one decimal, or digits significant digits
+func humanBytes(n float64, digits int) string {
+	for _, u := range byteUnits {
+		if math.Abs(n) >= u.size {
+			v := n / u.size
+			if digits > 0 {
+				return significant(v, digits) + u.name
+			}
+			return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0") + u.name
+		}
+	}
+	return strconv.FormatFloat(math.Round(n), 'f', 0, 64) + "B"
+}
+
+// humanDuration rounds d for reading: to the second from a minute up, as
+// in 3m12s, and to two decimals of seconds or milliseconds below that
+func humanDuration(d time.Duration) string {
+	switch abs := d.Abs(); {
+	case abs >= time.Minute:
+		// Drop zero seconds and minutes: 3m0s is 3m and 2h0m0s is 2h
+		s := d.Round(time.Second).String()
+		if strings.HasSuffix(s, "m0s") {
+			s = strings.TrimSuffix(s, "0s")
+		}
+		if strings.HasSuffix(s, "h0m") {
+			s = strings.TrimSuffix(s, "0m")
+		}
+		return s
+	case abs >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case abs >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.String()
+	}
+}
+
+// alignPadding returns how much of gap, the spaces a cell needs to fill
+// its column, goes before it
+func alignPadding(gap int, align string) int {
+	switch align {
+	case "right":
+		return gap
+	case "center":
+		return gap / 2
+	default:
+		return 0
+	}
+}
+
+// alignCell pads s with spaces to width display columns, aligned in them
+func alignCell(s string, width int, align string) string {
+	gap := max(0, width-displayWidth(s))
+	left := alignPadding(gap, align)
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", gap-left)
+}
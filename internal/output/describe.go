@@ -0,0 +1,198 @@
+package output
+
+import "strings"
+
+// none stands for an empty value in a Description
+const none = "<none>"
+
+// Description is the detailed view of one record that describe commands
+// print, where get commands print a compact table row for each: titled
+// sections, such as Metadata, Spec, Status, and Events, in order. Text
+// output lays the sections out for reading, like kubectl describe: one
+// field per line, nested fields indented, and tables aligned. json and
+// yaml output is an object with a key for each section, its title in
+// lowercase.
+type Description struct {
+	Name     string // Identifies the record in quiet output
+	Sections []Section
+}
+
+// Section is a titled part of a Description. Its value is any data:
+// objects list their fields, lists their items, and lists of flat objects,
+// like events, and [][]string tables are laid out as tables. A nil or
+// empty value is shown as <none>.
+type Section struct {
+	Title string
+	Value interface{}
+}
+
+// ID returns the record's name for quiet output
+func (d *Description) ID() string {
+	return d.Name
+}
+
+// String lays out the sections as text
+func (d *Description) String() string {
+	var b strings.Builder
+	for _, s := range d.Sections {
+		var v interface{} = s.Value
+		if _, ok := v.([][]string); !ok {
+			var err error
+			if v, err = toValue(v); err != nil {
+				v = err.Error()
+			}
+		}
+		if blank(v) {
+			b.WriteString(s.Title + ":  " + none + "\n")
+			continue
+		}
+		switch v.(type) {
+		case *object, []interface{}, [][]string:
+			b.WriteString(s.Title + ":\n")
+			writeDescribed(&b, v, "  ")
+		default:
+			b.WriteString(s.Title + ":  " + valueString(v) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// object returns the sections as an object keyed by their titles
+func (d *Description) object() (*object, error) {
+	obj := newObject()
+	for _, s := range d.Sections {
+		v, err := toValue(s.Value)
+		if err != nil {
+			return nil, err
+		}
+		obj.set(strings.ToLower(s.Title), v)
+	}
+	return obj, nil
+}
+
+// MarshalJSON writes the sections in order
+func (d *Description) MarshalJSON() ([]byte, error) {
+	obj, err := d.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.MarshalJSON()
+}
+
+// MarshalYAML writes the sections in order
+func (d *Description) MarshalYAML() (interface{}, error) {
+	obj, err := d.object()
+	if err != nil {
+		return nil, err
+	}
+	return obj.MarshalYAML()
+}
+
+// writeDescribed writes a generic value or table as indented lines
+func writeDescribed(b *strings.Builder, v interface{}, indent string) {
+	switch t := v.(type) {
+	case *object:
+		width := 0
+		for _, k := range t.keys {
+			if !nested(t.values[k]) {
+				width = max(width, displayWidth(k)+1)
+			}
+		}
+		for _, k := range t.keys {
+			value := t.values[k]
+			switch {
+			case nested(value):
+				b.WriteString(indent + k + ":\n")
+				writeDescribed(b, value, indent+"  ")
+			case blank(value):
+				b.WriteString(indent + padRight(k+":", width) + "  " + none + "\n")
+			default:
+				b.WriteString(indent + padRight(k+":", width) + "  " + valueString(value) + "\n")
+			}
+		}
+	case []interface{}:
+		if records(t) {
+			table := valueToTable(t, 0)
+			for i, h := range table[0] {
+				table[0][i] = strings.ToUpper(h[:1]) + h[1:]
+			}
+			writeDescribed(b, table, indent)
+			return
+		}
+		for _, item := range t {
+			if !nested(item) {
+				b.WriteString(indent + "- " + valueString(item) + "\n")
+				continue
+			}
+			// The item's first line follows the dash
+			var sub strings.Builder
+			writeDescribed(&sub, item, indent+"  ")
+			b.WriteString(indent + "- " + strings.TrimPrefix(sub.String(), indent+"  "))
+		}
+	case [][]string:
+		var widths []int
+		for _, row := range t {
+			for i, cell := range row {
+				if i == len(widths) {
+					widths = append(widths, 0)
+				}
+				widths[i] = max(widths[i], displayWidth(cell))
+			}
+		}
+		for _, row := range t {
+			cells := make([]string, len(row))
+			for i, cell := range row {
+				cells[i] = padRight(cell, widths[i])
+			}
+			b.WriteString(indent + strings.TrimRight(strings.Join(cells, "  "), " ") + "\n")
+		}
+	default:
+		b.WriteString(indent + valueString(v) + "\n")
+	}
+}
+
+// nested reports whether v is an object or list with something in it,
+// shown on lines of its own
+func nested(v interface{}) bool {
+	switch t := v.(type) {
+	case *object:
+		return len(t.keys) > 0
+	case []interface{}:
+		return len(t) > 0
+	}
+	return false
+}
+
+// records reports whether list holds only objects without nested fields,
+// laid out as a table
+func records(list []interface{}) bool {
+	for _, item := range list {
+		obj, ok := item.(*object)
+		if !ok || len(obj.keys) == 0 {
+			return false
+		}
+		for _, k := range obj.keys {
+			if nested(obj.values[k]) || k == "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// blank reports whether v has nothing to show
+func blank(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case *object:
+		return len(t.keys) == 0
+	case []interface{}:
+		return len(t) == 0
+	case [][]string:
+		return len(t) <= 1
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// EnvelopeOptions describes the command whose output is wrapped
+type EnvelopeOptions struct {
+	Command   string    // Command path, e.g. "termplate template list"
+	Start     time.Time // When the command started
+	Timestamp bool      // Include Start (output.timestamp)
+	Format    string    // json or yaml; other formats aren't wrapped
+	Pretty    bool
+}
+
+// envelope is the document written by WriteEnvelope
+type envelope struct {
+	Command    string      `json:"command" yaml:"command"`
+	Timestamp  string      `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	DurationMS int64       `json:"duration_ms" yaml:"duration_ms"`
+	ExitStatus int         `json:"exit_status" yaml:"exit_status"`
+	Error      string      `json:"error,omitempty" yaml:"error,omitempty"`
+	Data       interface{} `json:"data" yaml:"data"`
+}
+
+var (
+	envelopeMu   sync.Mutex
+	envelopeOpts *EnvelopeOptions
+	envelopeData []interface{}
+)
+
+// StartEnvelope makes Print collect json and yaml output to stdout until
+// WriteEnvelope wraps it in an envelope with metadata about the command
+// (output.envelope)
+func StartEnvelope(opts EnvelopeOptions) {
+	if opts.Format != "json" && opts.Format != "yaml" {
+		return
+	}
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	envelopeOpts, envelopeData = &opts, nil
+}
+
+// WriteEnvelope writes the output collected since StartEnvelope to w,
+// wrapped with the command's exit status and error, and stops collecting.
+// It writes nothing when the envelope wasn't started, or when nothing was
+// printed and the command succeeded.
+func WriteEnvelope(w io.Writer, exitStatus int, cmdErr error) error {
+	envelopeMu.Lock()
+	opts, data := envelopeOpts, envelopeData
+	envelopeOpts, envelopeData = nil, nil
+	envelopeMu.Unlock()
+	if opts == nil || (len(data) == 0 && cmdErr == nil) {
+		return nil
+	}
+
+	doc := envelope{
+		Command:    opts.Command,
+		DurationMS: time.Since(opts.Start).Milliseconds(),
+		ExitStatus: exitStatus,
+	}
+	if opts.Timestamp {
+		doc.Timestamp = opts.Start.Format(time.RFC3339)
+	}
+	if cmdErr != nil {
+		doc.Error = cmdErr.Error()
+	}
+	switch len(data) {
+	case 0:
+	case 1:
+		doc.Data = data[0]
+	default:
+		doc.Data = data
+	}
+
+	f := NewFormatterWithWriter(config.OutputConfig{Format: opts.Format, Pretty: opts.Pretty}, w)
+	var err error
+	if opts.Format == "yaml" {
+		err = f.printYAML(doc)
+	} else {
+		err = f.printJSON(doc)
+	}
+	if ferr := f.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// enveloped reports whether f's output goes in the envelope: json and
+// yaml output to stdout while one is started
+func (f *Formatter) enveloped() bool {
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	return envelopeOpts != nil && f.config.Format == envelopeOpts.Format && f.out == os.Stdout
+}
+
+// collect keeps data for the envelope instead of printing it
+func collect(data interface{}) {
+	envelopeMu.Lock()
+	defer envelopeMu.Unlock()
+	envelopeData = append(envelopeData, data)
+}
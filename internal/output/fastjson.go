@@ -0,0 +1,184 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxPooledBuffer is the largest buffer put back in jsonBuffers, so one
+// huge row doesn't pin its memory for the rest of the process
+const maxPooledBuffer = 1 << 20
+
+// jsonBuffers holds the buffers rows are encoded into on the fast path
+var jsonBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// jsonEncoder encodes rows for json and ndjson output of
+// output.fast_json_rows rows or more. Rather than allocating a new []byte
+// for each row as json.Marshal does, it encodes into one pooled buffer,
+// reused from row to row; []string rows are written out by hand, with
+// their keys encoded once. The bytes are the same as json.Marshal's.
+type jsonEncoder struct {
+	buf    *bytes.Buffer
+	enc    *json.Encoder
+	keys   [][]byte // The headers, encoded
+	indent bool     // Lay rows out as elements of an indented array
+}
+
+// useFastJSON reports whether json output of n rows goes through a
+// jsonEncoder
+func (f *Formatter) useFastJSON(n int) bool {
+	limit := f.config.FastJSONRows
+	return limit > 0 && n >= limit
+}
+
+// newJSONEncoder returns an encoder for rows, keyed by headers when
+// they're []string. With indent, rows are laid out as printJSON lays out
+// the elements of an array when Pretty is set.
+func newJSONEncoder(headers []string, indent bool) *jsonEncoder {
+	e := &jsonEncoder{buf: jsonBuffers.Get().(*bytes.Buffer), indent: indent}
+	e.buf.Reset()
+	e.enc = json.NewEncoder(e.buf)
+	if indent {
+		e.enc.SetIndent("  ", "  ")
+	}
+	e.keys = make([][]byte, len(headers))
+	for i, h := range headers {
+		e.keys[i] = appendJSONString(nil, h)
+	}
+	return e
+}
+
+// release hands the encoder's buffer back to the pool
+func (e *jsonEncoder) release() {
+	if e.buf.Cap() <= maxPooledBuffer {
+		jsonBuffers.Put(e.buf)
+	}
+	e.buf = nil
+}
+
+// encode appends v to the buffer as json.Marshal would encode it
+func (e *jsonEncoder) encode(v interface{}) error {
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	e.buf.Truncate(e.buf.Len() - 1) // Encode ends with a newline
+	return nil
+}
+
+// encodeCells appends a []string row to the buffer as an object with a
+// key per header
+func (e *jsonEncoder) encodeCells(cells []string) {
+	b := e.buf.AvailableBuffer()
+	b = append(b, '{')
+	n := min(len(cells), len(e.keys))
+	for i := range n {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		if e.indent {
+			b = append(b, "\n    "...)
+		}
+		b = append(b, e.keys[i]...)
+		b = append(b, ':')
+		if e.indent {
+			b = append(b, ' ')
+		}
+		b = appendJSONString(b, cells[i])
+	}
+	if e.indent && n > 0 {
+		b = append(b, "\n  "...)
+	}
+	b = append(b, '}')
+	e.buf.Write(b)
+}
+
+// jsonElements returns the elements of data when it's a list json.Marshal
+// writes as an array, and false otherwise
+func jsonElements(data interface{}) (reflect.Value, bool) {
+	if r, ok := data.(queryResult); ok {
+		data = r.value
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return v, false
+	}
+	// nil is null, []byte base64, and a marshaler says for itself
+	if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+		return v, false
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v, false
+	}
+	return v, true
+}
+
+// jsonElement returns the i'th element of v for encoding: a pointer to it
+// where it's addressable, as json.Marshal encodes it, so methods with
+// pointer receivers apply and a struct isn't copied
+func jsonElement(v reflect.Value, i int) interface{} {
+	elem := v.Index(i)
+	if elem.CanAddr() {
+		return elem.Addr().Interface()
+	}
+	return elem.Interface()
+}
+
+var jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s to dst as a JSON string, escaped as
+// json.Marshal escapes it, HTML characters and U+2028 and U+2029
+// included. Strings that aren't valid UTF-8 are left to json.Marshal.
+func appendJSONString(dst []byte, s string) []byte {
+	mark := len(dst)
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			quoted, _ := json.Marshal(s) // Can't fail for a string
+			return append(dst[:mark], quoted...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
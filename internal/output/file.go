@@ -0,0 +1,89 @@
+package output
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File is output being written to a file. Unless appending, it goes to a
+// temporary file next to the target, which Commit renames into place, so
+// readers never see a partial file and a failed command leaves the old one.
+type File struct {
+	*os.File
+	path string
+	temp bool
+	done bool
+}
+
+// CreateFile starts writing output to path. With appendTo the output is
+// added to the end of path directly.
+func CreateFile(path string, appendTo bool) (*File, error) {
+	if appendTo {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening output file: %w", err)
+		}
+		return &File{File: f, path: path}, nil
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating output file: %w", err)
+	}
+	return &File{File: f, path: path, temp: true}, nil
+}
+
+// Path returns the file the output ends up in
+func (f *File) Path() string {
+	return f.path
+}
+
+// Commit finishes the output, replacing path with it. A replaced file
+// keeps its permissions; a new one gets 0644.
+func (f *File) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+	if err := f.Close(); err != nil {
+		f.remove()
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	if !f.temp {
+		return nil
+	}
+
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		f.remove()
+		return fmt.Errorf("setting output file mode: %w", err)
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		f.remove()
+		return fmt.Errorf("replacing output file: %w", err)
+	}
+	return nil
+}
+
+// Abort discards the output, leaving path as it was. Output already
+// appended stays.
+func (f *File) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.Close()
+	f.remove()
+}
+
+// remove deletes the temporary file; appended output is kept
+func (f *File) remove() {
+	if f.temp {
+		_ = os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,381 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Filter is a compiled --filter expression that keeps the rows it matches.
+//
+//	status==running              compare a column with a value
+//	age>3d  size<=10MiB  n!=0    numbers, durations, and sizes by value
+//	name=~'^api-'  name!~test    regular expression match
+//	a && b  a || b  !a  (a)      combine conditions
+//
+// Columns are named by header or JSON key, ignoring case and treating
+// spaces, underscores, and hyphens alike, so last_used matches "Last
+// Used". Values may be quoted with ' or " and otherwise end at a space or
+// parenthesis. == and != ignore case.
+type Filter struct {
+	src  string
+	root cond
+}
+
+// cond reports whether a row matches; get returns a column's value
+type cond func(get func(column string) (string, error)) (bool, error)
+
+// CompileFilter parses a filter expression
+func CompileFilter(src string) (*Filter, error) {
+	p := &filterParser{src: src}
+	root, err := p.parseOr()
+	if err == nil {
+		p.skipSpace()
+		if p.pos < len(p.src) {
+			err = fmt.Errorf("unexpected %q at offset %d", p.src[p.pos:], p.pos)
+		}
+	}
+	if err != nil {
+		return nil, model.NewValidationError("filter", err.Error())
+	}
+	return &Filter{src: src, root: root}, nil
+}
+
+// String returns the filter source
+func (f *Filter) String() string {
+	return f.src
+}
+
+// filterTable returns table without the rows, after the header, that the
+// filter doesn't match. A column not in the header is an error.
+func (f *Filter) filterTable(table [][]string) ([][]string, error) {
+	if len(table) == 0 {
+		return table, nil
+	}
+	out := [][]string{table[0]}
+	for _, row := range table[1:] {
+		ok, err := f.matchRow(table[0], row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, row)
+		}
+	}
+	return out, nil
+}
+
+// matchRow reports whether the filter matches a row with the given headers
+func (f *Filter) matchRow(headers, row []string) (bool, error) {
+	return f.root(func(column string) (string, error) {
+		i := slices.IndexFunc(headers, func(h string) bool {
+			return columnKey(h) == columnKey(column)
+		})
+		if i < 0 {
+			return "", model.NewValidationError("filter",
+				fmt.Sprintf("unknown column %q (columns: %s)", column, strings.Join(headers, ", ")))
+		}
+		return rowCell(row, i), nil
+	})
+}
+
+// filterValue returns v, a generic value (see toValue), with the list
+// items the filter doesn't match removed. Items look columns up by key,
+// and one without the key has an empty value, but a column that no item
+// has is an error. Anything but a list is returned as is.
+func (f *Filter) filterValue(v interface{}) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v, nil
+	}
+	found := map[string]bool{}
+	out := []interface{}{}
+	for _, item := range list {
+		ok, err := f.root(func(column string) (string, error) {
+			value, ok := objectField(item, column)
+			found[column] = found[column] || ok
+			return value, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, item)
+		}
+	}
+	for column, ok := range found {
+		if !ok {
+			return nil, model.NewValidationError("filter", fmt.Sprintf("no item has a field matching %q", column))
+		}
+	}
+	return out, nil
+}
+
+// objectField returns the field of item, an object, matching column
+func objectField(item interface{}, column string) (string, bool) {
+	obj, ok := item.(*object)
+	if !ok {
+		return "", false
+	}
+	for _, k := range obj.keys {
+		if columnKey(k) == columnKey(column) {
+			return valueString(obj.values[k]), true
+		}
+	}
+	return "", false
+}
+
+// columnKey normalizes a column name for matching
+func columnKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// Parser
+
+type filterParser struct {
+	src string
+	pos int
+}
+
+func (p *filterParser) parseOr() (cond, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(get func(string) (string, error)) (bool, error) {
+			if ok, err := l(get); err != nil || ok {
+				return ok, err
+			}
+			return right(get)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (cond, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(get func(string) (string, error)) (bool, error) {
+			if ok, err := l(get); err != nil || !ok {
+				return ok, err
+			}
+			return right(get)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (cond, error) {
+	if p.accept("!") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(get func(string) (string, error)) (bool, error) {
+			ok, err := inner(get)
+			return !ok, err
+		}, nil
+	}
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+		}
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+// filterOps are the comparison operators, longest first so "<=" isn't
+// read as "<"
+var filterOps = []string{"==", "!=", "<=", ">=", "=~", "!~", "<", ">"}
+
+func (p *filterParser) parseComparison() (cond, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isColumnChar(r) {
+			break
+		}
+		p.pos += size
+	}
+	column := p.src[start:p.pos]
+	if column == "" {
+		return nil, fmt.Errorf("expected a column name at offset %d", start)
+	}
+
+	p.skipSpace()
+	op := ""
+	for _, o := range filterOps {
+		if strings.HasPrefix(p.src[p.pos:], o) {
+			op = o
+			p.pos += len(o)
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("expected a comparison (==, !=, <, <=, >, >=, =~, !~) after %s at offset %d", column, p.pos)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "=~" || op == "!~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		return func(get func(string) (string, error)) (bool, error) {
+			cell, err := get(column)
+			if err != nil {
+				return false, err
+			}
+			return re.MatchString(cell) == (op == "=~"), nil
+		}, nil
+	}
+
+	return func(get func(string) (string, error)) (bool, error) {
+		cell, err := get(column)
+		if err != nil {
+			return false, err
+		}
+		c := compareFilterValues(cell, value)
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}, nil
+}
+
+// parseValue reads a quoted string or a bare word
+func (p *filterParser) parseValue() (string, error) {
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == '\'' || p.src[p.pos] == '"') {
+		quote := p.src[p.pos]
+		end := strings.IndexByte(p.src[p.pos+1:], quote)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		value := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t()", rune(p.src[p.pos])) &&
+		!strings.HasPrefix(p.src[p.pos:], "&&") && !strings.HasPrefix(p.src[p.pos:], "||") {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("expected a value at offset %d", start)
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *filterParser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func isColumnChar(r rune) bool {
+	return r == '_' || r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Comparison
+
+// compareFilterValues compares a cell with a filter value: by value when
+// both are numbers, durations, or sizes, and otherwise as text ignoring
+// case (see compareCells)
+func compareFilterValues(cell, value string) int {
+	if a, ok := parseNumber(cell); ok {
+		if b, ok := parseNumber(value); ok {
+			return cmp.Compare(a, b)
+		}
+	}
+	if a, ok := parseFilterDuration(cell); ok {
+		if b, ok := parseFilterDuration(value); ok {
+			return cmp.Compare(a, b)
+		}
+	}
+	if a, err := config.ParseByteSize(cell); err == nil {
+		if b, err := config.ParseByteSize(value); err == nil {
+			return cmp.Compare(a, b)
+		}
+	}
+	if strings.EqualFold(cell, value) {
+		return 0
+	}
+	return compareCells(cell, value)
+}
+
+// parseFilterDuration parses a Go duration, or a number of days or weeks
+// such as 3d or 1.5w
+func parseFilterDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(s) < 2 || unit[s[len(s)-1]] == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n * float64(unit[s[len(s)-1]])), true
+}
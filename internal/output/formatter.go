@@ -1,58 +1,248 @@
 package output
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/blacksilver/termplate-go/internal/config"
 )
 
-// Formatter handles formatting output in different formats
+// Formatter handles formatting output in different formats. It's safe for
+// concurrent use: each Print, PrintRows, and call of a StreamPrinter holds
+// the formatter until it's done, so goroutines sharing one, such as
+// workers of a pool, never interleave their output. Goroutines writing to
+// the same writer should share a formatter.
 type Formatter struct {
+	mu     sync.Mutex // Held while output is written
 	config config.OutputConfig
-	writer io.Writer
+	out    io.Writer // Where output ends up, e.g. os.Stdout
+	buf    *Buffer   // Holds output for out
+	writer io.Writer // Where output is written: buf, or a page being laid out
+
+	color        bool // Emit ANSI colors in table output
+	columnStyles map[string]StyleFunc
+	aligns       []string // Alignment of each column of the table being printed
 }
 
 // NewFormatter creates a new output formatter
 func NewFormatter(cfg config.OutputConfig) *Formatter {
-	return &Formatter{
-		config: cfg,
-		writer: os.Stdout,
-	}
+	return NewFormatterWithWriter(cfg, os.Stdout)
 }
 
-// NewFormatterWithWriter creates a formatter with a custom writer
+// NewFormatterWithWriter creates a formatter with a custom writer. Output
+// to it is buffered by output.write_buffer and output.flush_interval (see
+// Buffer).
 func NewFormatterWithWriter(cfg config.OutputConfig, w io.Writer) *Formatter {
+	buf := NewBuffer(w, int(cfg.WriteBuffer), cfg.FlushInterval)
 	return &Formatter{
 		config: cfg,
-		writer: w,
+		out:    w,
+		buf:    buf,
+		writer: buf,
+		color:  ColorEnabled(cfg.ColorOutput, w),
 	}
 }
 
-// Print formats and prints data based on the configured output format
+// Flush writes out the output the formatter holds. Print flushes its own
+// output, and StreamPrinter.End a stream's.
+func (f *Formatter) Flush() error {
+	return f.buf.Flush()
+}
+
+// Print formats and prints data based on the configured output format,
+// after applying the configured query (see Query). Output taller than the
+// terminal is paged when Pager is set. json and yaml output is held for
+// the envelope once StartEnvelope is called. Quiet prints only the
+// identifiers of the records, whatever the format (see IDer). Rows are
+// printed as they're produced (see PrintRows).
 func (f *Formatter) Print(data interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.printData(data)
+}
+
+// printData is Print, with f.mu held
+func (f *Formatter) printData(data interface{}) error {
+	if rows, ok := data.(Rows); ok {
+		return f.streamRows(nil, rows)
+	}
+	out := f.pagerTerminal()
+	if out == nil || f.enveloped() {
+		err := f.print(data)
+		if ferr := f.Flush(); err == nil {
+			err = ferr
+		}
+		return err
+	}
+
+	var buf bytes.Buffer
+	f.writer = &buf
+	err := f.print(data)
+	f.writer = f.buf
+	if err != nil {
+		return err
+	}
+	return page(out, buf.Bytes())
+}
+
+func (f *Formatter) print(data interface{}) error {
+	// A list is added to, filtered, and summarized before the query, so
+	// those see the command's rows and the query projects what's left;
+	// anything else is queried first, so the query can pick the list
+	rowsFirst := false
+	if f.config.Query != "" && f.listStages() {
+		v, err := resultValue(data)
+		if err != nil {
+			return err
+		}
+		data = queryResult{v}
+		_, rowsFirst = v.([]interface{})
+	}
+	var err error
+	if f.config.Query != "" && !rowsFirst {
+		if data, err = f.query(data); err != nil {
+			return err
+		}
+	}
+	if f.listStages() {
+		if data, err = f.listValue(data); err != nil {
+			return err
+		}
+	}
+	if rowsFirst {
+		if data, err = f.query(data); err != nil {
+			return err
+		}
+	}
+
+	// Table formats and quiet output page rows once they're filtered and
+	// sorted, the rest page list items here
+	if _, _, ok := f.chunkRange(); ok && !f.tableFormat() && !f.config.Quiet {
+		var err error
+		if data, err = f.chunkValue(data); err != nil {
+			return err
+		}
+	}
+
+	if f.config.Quiet {
+		return f.printIDs(data)
+	}
+	if f.enveloped() {
+		collect(data)
+		return nil
+	}
+	if IsTemplateFormat(f.config.Format) {
+		return f.printTemplate(data)
+	}
+	if fn, ok := customFormat(f.config.Format); ok {
+		return f.printCustom(fn, data)
+	}
+
 	switch f.config.Format {
 	case "json":
 		return f.printJSON(data)
+	case "ndjson":
+		return f.printNDJSON(data)
 	case "yaml":
 		return f.printYAML(data)
 	case "table":
 		return f.printTable(data)
-	case "csv":
+	case "csv", "tsv":
 		return f.printCSV(data)
+	case "html":
+		return f.printHTML(data)
+	case "xml":
+		return f.printXML(data)
 	default:
 		return f.printText(data)
 	}
 }
 
+// query applies output.query to data
+func (f *Formatter) query(data interface{}) (interface{}, error) {
+	q, err := CompileQuery(f.config.Query)
+	if err != nil {
+		return nil, err
+	}
+	v, err := q.Apply(data)
+	if err != nil {
+		return nil, err
+	}
+	return queryResult{v}, nil
+}
+
+// listStages reports whether listValue changes data. Table formats and
+// quiet output add columns to rows, filter, and summarize them in
+// toTable instead.
+func (f *Formatter) listStages() bool {
+	if f.tableFormat() || f.config.Quiet {
+		return false
+	}
+	return len(f.config.AddColumns) > 0 || f.config.Filter != "" || f.config.GroupBy != "" || f.config.Aggregates != ""
+}
+
+// listValue returns data with the items of a list given the columns of
+// output.add_columns, kept when they match output.filter, and summarized
+// by output.group_by and output.aggregates
+func (f *Formatter) listValue(data interface{}) (interface{}, error) {
+	if len(f.config.AddColumns) > 0 {
+		cols, err := f.derivedColumns()
+		if err != nil {
+			return nil, err
+		}
+		v, err := resultValue(data)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = deriveValue(v, cols); err != nil {
+			return nil, err
+		}
+		data = queryResult{v}
+	}
+	if f.config.Filter != "" {
+		filter, err := CompileFilter(f.config.Filter)
+		if err != nil {
+			return nil, err
+		}
+		v, err := resultValue(data)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = filter.filterValue(v); err != nil {
+			return nil, err
+		}
+		data = queryResult{v}
+	}
+	spec, err := f.grouping()
+	if err != nil || spec == nil {
+		return data, err
+	}
+	v, err := resultValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if v, err = groupValue(v, spec); err != nil {
+		return nil, err
+	}
+	return queryResult{v}, nil
+}
+
 // printJSON outputs data as JSON
 func (f *Formatter) printJSON(data interface{}) error {
+	if list, ok := jsonElements(data); ok && f.useFastJSON(list.Len()) {
+		return f.printJSONElements(list)
+	}
+
 	var output []byte
 	var err error
 
@@ -72,6 +262,85 @@ func (f *Formatter) printJSON(data interface{}) error {
 	return nil
 }
 
+// printJSONElements writes list as printJSON would, but an element at a
+// time through a jsonEncoder, rather than marshaling all of it into one
+// []byte first
+func (f *Formatter) printJSONElements(list reflect.Value) error {
+	e := newJSONEncoder(nil, f.config.Pretty)
+	defer e.release()
+	for i := range list.Len() {
+		e.buf.Reset()
+		if i == 0 {
+			e.buf.WriteByte('[')
+		} else {
+			e.buf.WriteByte(',')
+		}
+		if f.config.Pretty {
+			e.buf.WriteString("\n  ")
+		}
+		if err := e.encode(jsonElement(list, i)); err != nil {
+			return fmt.Errorf("marshaling JSON: %w", err)
+		}
+		if _, err := f.writer.Write(e.buf.Bytes()); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+
+	end := "]\n"
+	switch {
+	case list.Len() == 0:
+		end = "[]\n"
+	case f.config.Pretty:
+		end = "\n]\n"
+	}
+	if _, err := io.WriteString(f.writer, end); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// printNDJSON outputs data as newline-delimited JSON: one compact value
+// per list element or table row, so output can be piped into jq and other
+// stream processors. Anything else is a single line.
+func (f *Formatter) printNDJSON(data interface{}) error {
+	var items []interface{}
+	switch d := data.(type) {
+	case queryResult:
+		items = templateItems(d.value)
+	case [][]string:
+		items = tableToValue(d)
+	default:
+		items = templateItems(data)
+	}
+
+	if f.useFastJSON(len(items)) {
+		e := newJSONEncoder(nil, false)
+		defer e.release()
+		for _, item := range items {
+			e.buf.Reset()
+			if err := e.encode(item); err != nil {
+				return fmt.Errorf("marshaling JSON: %w", err)
+			}
+			e.buf.WriteByte('\n')
+			if _, err := f.writer.Write(e.buf.Bytes()); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+		return nil
+	}
+
+	for _, item := range items {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("marshaling JSON: %w", err)
+		}
+		if _, err := fmt.Fprintf(f.writer, "%s\n", line); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
 // printYAML outputs data as YAML
 func (f *Formatter) printYAML(data interface{}) error {
 	encoder := yaml.NewEncoder(f.writer)
@@ -93,6 +362,18 @@ func (f *Formatter) printTable(data interface{}) error {
 	if err != nil {
 		return err
 	}
+	if len(table) > 0 {
+		formats := f.columnFormats(table[0])
+		table = formatTable(table, formats)
+		f.aligns = columnAligns(formats)
+	}
+
+	// Hold back rows past the configured limit
+	hidden := 0
+	if limit := f.config.MaxRows; limit > 0 && len(table) > limit+1 {
+		hidden = len(table) - limit - 1
+		table = table[:limit+1]
+	}
 
 	// Print table based on style
 	switch f.config.TableStyle {
@@ -104,17 +385,29 @@ func (f *Formatter) printTable(data interface{}) error {
 		f.printASCIITable(table)
 	}
 
+	if hidden > 0 {
+		f.printHiddenRows(hidden)
+	}
 	return nil
 }
 
-// printCSV outputs data as CSV
+// printHiddenRows notes the rows left out by MaxRows
+func (f *Formatter) printHiddenRows(n int) {
+	noun := "rows"
+	if n == 1 {
+		noun = "row"
+	}
+	fmt.Fprintf(f.writer, "… %d more %s\n", n, noun)
+}
+
+// printCSV outputs data as CSV, or TSV for the tsv format
 func (f *Formatter) printCSV(data interface{}) error {
 	table, err := f.toTable(data)
 	if err != nil {
 		return err
 	}
 
-	writer := csv.NewWriter(f.writer)
+	writer := f.csvWriter()
 	defer writer.Flush()
 
 	for _, row := range table {
@@ -129,17 +422,101 @@ func (f *Formatter) printCSV(data interface{}) error {
 	return nil
 }
 
+// csvWriter returns a CSV writer separating fields with the configured
+// delimiter, or with a comma (csv) or tab (tsv) by default
+func (f *Formatter) csvWriter() *csv.Writer {
+	w := csv.NewWriter(f.writer)
+	switch {
+	case f.config.Delimiter != "":
+		w.Comma, _ = utf8.DecodeRuneInString(f.config.Delimiter)
+	case f.config.Format == "tsv":
+		w.Comma = '\t'
+	}
+	return w
+}
+
 // printText outputs data as plain text
 func (f *Formatter) printText(data interface{}) error {
+	if r, ok := data.(queryResult); ok {
+		data = valueLines(r.value)
+	}
 	if _, err := fmt.Fprintln(f.writer, data); err != nil {
 		return fmt.Errorf("writing output: %w", err)
 	}
 	return nil
 }
 
-// toTable converts various data types to table format
+// tableFormat reports whether the format prints data through toTable
+func (f *Formatter) tableFormat() bool {
+	switch f.config.Format {
+	case "table", "csv", "tsv", "html":
+		return true
+	}
+	return false
+}
+
+// toTable converts various data types to table format, keeping the rows
+// that match Filter, sorted by SortBy, and on the page printed (see
+// StartChunk). Structs and slices of structs are converted by reflection;
+// see TagName. Other data goes through its JSON encoding. Nested objects
+// are flattened into columns named like spec.replicas, FlattenDepth levels
+// deep.
 func (f *Formatter) toTable(data interface{}) ([][]string, error) {
+	table, err := f.convertTable(data)
+	if err != nil {
+		return nil, err
+	}
+	if table, err = f.filterTable(table); err != nil {
+		return nil, err
+	}
+	return f.chunkTable(table), nil
+}
+
+// filterTable adds the columns of AddColumns to table and keeps the rows
+// that match Filter, summarized by GroupBy and Aggregates, sorted by
+// SortBy
+func (f *Formatter) filterTable(table [][]string) ([][]string, error) {
+	if len(f.config.AddColumns) > 0 {
+		cols, err := f.derivedColumns()
+		if err != nil {
+			return nil, err
+		}
+		if table, err = deriveTable(table, cols); err != nil {
+			return nil, err
+		}
+	}
+	if f.config.Filter != "" {
+		filter, err := CompileFilter(f.config.Filter)
+		if err != nil {
+			return nil, err
+		}
+		if table, err = filter.filterTable(table); err != nil {
+			return nil, err
+		}
+	}
+	spec, err := f.grouping()
+	if err != nil {
+		return nil, err
+	}
+	if spec != nil {
+		if table, err = spec.Table(table); err != nil {
+			return nil, err
+		}
+	}
+	if f.config.SortBy == "" {
+		return table, nil
+	}
+	keys, err := ParseSortBy(f.config.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	return sortTable(table, keys)
+}
+
+func (f *Formatter) convertTable(data interface{}) ([][]string, error) {
 	switch v := data.(type) {
+	case queryResult:
+		return valueToTable(v.value, f.config.FlattenDepth), nil
 	case [][]string:
 		return v, nil
 	case []map[string]string:
@@ -147,7 +524,15 @@ func (f *Formatter) toTable(data interface{}) ([][]string, error) {
 	case map[string]string:
 		return f.mapToTable(v), nil
 	default:
-		return nil, fmt.Errorf("unsupported data type for table output")
+		if table, ok := reflectToTable(data, f.config.FlattenDepth); ok {
+			return table, nil
+		}
+		// Such as maps and lists decoded from an API response
+		v, err := toValue(data)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported data type %T for table output: %w", data, err)
+		}
+		return valueToTable(v, f.config.FlattenDepth), nil
 	}
 }
 
@@ -193,17 +578,18 @@ func (f *Formatter) printASCIITable(table [][]string) {
 
 	// Calculate column widths
 	widths := f.calculateColumnWidths(table)
+	styles := f.cellStyles(table[0])
 
 	// Print header
-	f.printASCIIRow(table[0], widths, true)
+	f.printASCIIRow(f.writer, table[0], widths, nil, true)
 
 	// Print separator
 	f.printASCIISeparator(widths)
 
 	// Print rows
-	for _, row := range table[1:] {
-		f.printASCIIRow(row, widths, false)
-	}
+	f.printRows(table[1:], func(w io.Writer, row []string) {
+		f.printASCIIRow(w, row, widths, styles, false)
+	})
 }
 
 // printUnicodeTable prints a table using Unicode box drawing characters
@@ -213,20 +599,21 @@ func (f *Formatter) printUnicodeTable(table [][]string) {
 	}
 
 	widths := f.calculateColumnWidths(table)
+	styles := f.cellStyles(table[0])
 
 	// Print top border
 	f.printUnicodeBorder(widths, "┌", "┬", "┐")
 
 	// Print header
-	f.printUnicodeRow(table[0], widths)
+	f.printUnicodeRow(f.writer, table[0], widths, nil, true)
 
 	// Print header separator
 	f.printUnicodeBorder(widths, "├", "┼", "┤")
 
 	// Print rows
-	for _, row := range table[1:] {
-		f.printUnicodeRow(row, widths)
-	}
+	f.printRows(table[1:], func(w io.Writer, row []string) {
+		f.printUnicodeRow(w, row, widths, styles, false)
+	})
 
 	// Print bottom border
 	f.printUnicodeBorder(widths, "└", "┴", "┘")
@@ -241,33 +628,46 @@ func (f *Formatter) printMarkdownTable(table [][]string) {
 	widths := f.calculateColumnWidths(table)
 
 	// Print header
-	f.printMarkdownRow(table[0], widths)
+	f.printMarkdownRow(f.writer, table[0], widths)
 
 	// Print separator
-	fmt.Fprint(f.writer, "|")
-	for _, w := range widths {
-		fmt.Fprint(f.writer, strings.Repeat("-", w+2), "|")
-	}
-	fmt.Fprintln(f.writer)
+	f.printMarkdownSeparator(widths)
 
 	// Print rows
-	for _, row := range table[1:] {
-		f.printMarkdownRow(row, widths)
+	f.printRows(table[1:], func(w io.Writer, row []string) {
+		f.printMarkdownRow(w, row, widths)
+	})
+}
+
+// printMarkdownSeparator prints the line under the header, marking right
+// and centered columns with colons
+func (f *Formatter) printMarkdownSeparator(widths []int) {
+	fmt.Fprint(f.writer, "|")
+	for i, w := range widths {
+		switch f.align(i) {
+		case "right":
+			fmt.Fprint(f.writer, strings.Repeat("-", w+1), ":|")
+		case "center":
+			fmt.Fprint(f.writer, ":", strings.Repeat("-", w), ":|")
+		default:
+			fmt.Fprint(f.writer, strings.Repeat("-", w+2), "|")
+		}
 	}
+	fmt.Fprintln(f.writer)
 }
 
-// calculateColumnWidths calculates the width of each column
+// calculateColumnWidths calculates the display width of each column
 func (f *Formatter) calculateColumnWidths(table [][]string) []int {
 	if len(table) == 0 {
 		return nil
 	}
 
-	widths := make([]int, len(table[0]))
-	for _, row := range table {
-		for i, cell := range row {
-			if len(cell) > widths[i] {
-				widths[i] = len(cell)
-			}
+	widths := f.scanWidths(table)
+
+	// Wider cells are truncated or wrapped to fit (see cellLines)
+	if limit := f.config.MaxColumnWidth; limit > 0 {
+		for i := range widths {
+			widths[i] = min(widths[i], limit)
 		}
 	}
 
@@ -275,12 +675,56 @@ func (f *Formatter) calculateColumnWidths(table [][]string) []int {
 }
 
 // Helper functions for ASCII table
-func (f *Formatter) printASCIIRow(row []string, widths []int, _ bool) {
-	fmt.Fprint(f.writer, "| ")
+func (f *Formatter) printASCIIRow(w io.Writer, row []string, widths []int, styles []StyleFunc, header bool) {
+	f.printBoxedRow(w, row, widths, styles, header, "| ", " | ")
+}
+
+// printBoxedRow prints a row of an ASCII or Unicode table, over several
+// lines when cells wrap. Cells are styled by their whole value, so a
+// truncated status keeps its color.
+func (f *Formatter) printBoxedRow(w io.Writer, row []string, widths []int, styles []StyleFunc, header bool, left, sep string) {
+	lines := make([][]string, len(row))
+	height := 1
 	for i, cell := range row {
-		fmt.Fprintf(f.writer, "%-*s | ", widths[i], cell)
+		lines[i] = f.cellLines(cell, widths[i])
+		height = max(height, len(lines[i]))
 	}
-	fmt.Fprintln(f.writer)
+
+	for n := 0; n < height; n++ {
+		fmt.Fprint(w, left)
+		for i, cell := range row {
+			line := ""
+			if n < len(lines[i]) {
+				line = lines[i][n]
+			}
+			fmt.Fprint(w, f.paintCell(line, widths[i], cellStyle(cell, i, styles, header), f.align(i)), sep)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// cellLines fits a cell to its column width: wrapped onto several lines
+// when Wrap is set, otherwise truncated
+func (f *Formatter) cellLines(cell string, width int) []string {
+	if f.config.MaxColumnWidth <= 0 {
+		return []string{cell}
+	}
+	if f.config.Wrap {
+		return wrapCell(cell, width)
+	}
+	return []string{truncateCell(cell, width)}
+}
+
+// cellStyle returns the style of column i: bold for headers, otherwise the
+// column's StyleFunc if it has one
+func cellStyle(cell string, i int, styles []StyleFunc, header bool) Style {
+	if header {
+		return StyleBold
+	}
+	if i < len(styles) && styles[i] != nil {
+		return styles[i](cell)
+	}
+	return StyleNone
 }
 
 func (f *Formatter) printASCIISeparator(widths []int) {
@@ -292,12 +736,8 @@ func (f *Formatter) printASCIISeparator(widths []int) {
 }
 
 // Helper functions for Unicode table
-func (f *Formatter) printUnicodeRow(row []string, widths []int) {
-	fmt.Fprint(f.writer, "│ ")
-	for i, cell := range row {
-		fmt.Fprintf(f.writer, "%-*s │ ", widths[i], cell)
-	}
-	fmt.Fprintln(f.writer)
+func (f *Formatter) printUnicodeRow(w io.Writer, row []string, widths []int, styles []StyleFunc, header bool) {
+	f.printBoxedRow(w, row, widths, styles, header, "│ ", " │ ")
 }
 
 func (f *Formatter) printUnicodeBorder(widths []int, left, mid, right string) {
@@ -311,11 +751,15 @@ func (f *Formatter) printUnicodeBorder(widths []int, left, mid, right string) {
 	fmt.Fprintln(f.writer, right)
 }
 
-// Helper functions for Markdown table
-func (f *Formatter) printMarkdownRow(row []string, widths []int) {
-	fmt.Fprint(f.writer, "| ")
+// Helper functions for Markdown table. A Markdown row can't span lines,
+// so cells are truncated even when Wrap is set.
+func (f *Formatter) printMarkdownRow(w io.Writer, row []string, widths []int) {
+	fmt.Fprint(w, "| ")
 	for i, cell := range row {
-		fmt.Fprintf(f.writer, "%-*s | ", widths[i], cell)
+		if f.config.MaxColumnWidth > 0 {
+			cell = truncateCell(cell, widths[i])
+		}
+		fmt.Fprint(w, alignCell(cell, widths[i], f.align(i)), " | ")
 	}
-	fmt.Fprintln(f.writer)
+	fmt.Fprintln(w)
 }
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"strconv"
+
+	"github.com/blacksilver/termplate-go/internal/output/aggregate"
+)
+
+// CompileGroupBy parses --group-by columns and --agg aggregates into the
+// summary of rows they ask for (see aggregate.Parse), with columns named
+// as in --filter; nil when neither is set
+func CompileGroupBy(groupBy, aggs string) (*aggregate.Spec, error) {
+	if groupBy == "" && aggs == "" {
+		return nil, nil
+	}
+	spec, err := aggregate.Parse(groupBy, aggs)
+	if err != nil {
+		return nil, err
+	}
+	spec.Match = func(header, column string) bool {
+		return columnKey(header) == columnKey(column)
+	}
+	return spec, nil
+}
+
+// grouping compiles output.group_by and output.aggregates; nil when rows
+// aren't summarized. Quiet output prints the identifiers of rows, so it
+// isn't.
+func (f *Formatter) grouping() (*aggregate.Spec, error) {
+	if f.config.Quiet {
+		return nil, nil
+	}
+	return CompileGroupBy(f.config.GroupBy, f.config.Aggregates)
+}
+
+// groupValue returns v, a generic value (see toValue), with the objects
+// of a list summarized by spec: an object per group, with its group-by
+// fields as the group's first item has them and its aggregates as numbers
+// when they are. Anything but a list is returned as is.
+func groupValue(v interface{}, spec *aggregate.Spec) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return v, nil
+	}
+
+	// The fields of every item are columns, in the order they come
+	var headers []string
+	seen := map[string]bool{}
+	for _, item := range list {
+		if obj, ok := item.(*object); ok {
+			for _, k := range obj.keys {
+				if !seen[k] {
+					seen[k] = true
+					headers = append(headers, k)
+				}
+			}
+		}
+	}
+	rows := make([][]string, len(list))
+	for i, item := range list {
+		rows[i] = make([]string, len(headers))
+		for j, h := range headers {
+			if obj, ok := item.(*object); ok {
+				rows[i][j] = valueString(obj.values[h])
+			}
+		}
+	}
+
+	out, err := spec.Headers(headers)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := spec.Groups(headers, rows)
+	if err != nil {
+		return nil, err
+	}
+	keys := len(out) - len(spec.Aggs)
+	result := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		obj := newObject()
+		for _, h := range out[:keys] {
+			var first interface{}
+			if item, ok := list[g.Rows[0]].(*object); ok {
+				first = item.values[h]
+			}
+			obj.set(h, first)
+		}
+		for i, value := range g.Values {
+			obj.set(out[keys+i], aggregateValue(value))
+		}
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+// aggregateValue returns an aggregate as a JSON number when it's written
+// as one
+func aggregateValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return n
+	}
+	return s
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileGroupBy(t *testing.T) {
+	table := [][]string{
+		{"File Name", "Region", "Size Bytes"},
+		{"a", "eu", "1KiB"},
+		{"b", "us", "512B"},
+		{"c", "eu", "2KiB"},
+	}
+	tests := []struct {
+		name    string
+		groupBy string
+		aggs    string
+		want    [][]string // nil when no spec is compiled
+		wantErr bool
+	}{
+		{name: "neither set"},
+		{name: "group-by only", groupBy: "region", want: [][]string{{"Region", "count"}, {"eu", "2"}, {"us", "1"}}},
+		{name: "agg only", aggs: "sum(size_bytes)", want: [][]string{{"sum(size_bytes)"}, {"3.5KiB"}}},
+		// Columns are named as in --filter: case, spaces, - and _ don't matter
+		{
+			name: "column names", groupBy: "REGION", aggs: "files=distinct(file-name),largest=max(sizebytes)",
+			want: [][]string{{"Region", "files", "largest"}, {"eu", "2", "2KiB"}, {"us", "1", "512B"}},
+		},
+		{name: "malformed agg", groupBy: "region", aggs: "max(size", wantErr: true},
+		{name: "unknown function", aggs: "mean(size_bytes)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := CompileGroupBy(tt.groupBy, tt.aggs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("CompileGroupBy() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if spec != nil {
+					t.Errorf("CompileGroupBy() = %+v, want nil", spec)
+				}
+				return
+			}
+			got, err := spec.Table(table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Table() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// Inline CSS added to html output when HTMLStyle is set. Inline styles
+// survive dashboards and mail clients that strip <style> blocks.
+const (
+	htmlTableCSS = "border-collapse: collapse; font-family: sans-serif; font-size: 14px;"
+	htmlCellCSS  = "border: 1px solid #d0d7de; padding: 4px 8px; text-align: left;"
+	htmlHeadCSS  = htmlCellCSS + " background: #f6f8fa; font-weight: bold;"
+)
+
+// htmlColors maps the cell styles of table output to CSS colors, in the
+// default theme
+var htmlColors = map[Style]string{
+	StyleRed:    "#cf222e",
+	StyleGreen:  "#1a7f37",
+	StyleYellow: "#9a6700",
+	StyleBlue:   "#0969da",
+	StyleCyan:   "#1b7c83",
+	StyleDim:    "#6e7781",
+}
+
+// printHTML outputs data as an HTML <table> element, to embed in a page
+func (f *Formatter) printHTML(data interface{}) error {
+	table, err := f.toTable(data)
+	if err != nil {
+		return err
+	}
+	if len(table) == 0 {
+		return nil
+	}
+	formats := f.columnFormats(table[0])
+	table = formatTable(table, formats)
+	f.aligns = columnAligns(formats)
+
+	f.openHTMLTable(table[0])
+	styles := f.htmlStyles(table[0])
+	for _, row := range table[1:] {
+		f.printHTMLRow(row, styles)
+	}
+	return f.closeHTMLTable()
+}
+
+// openHTMLTable writes the start of the table and its header row
+func (f *Formatter) openHTMLTable(headers []string) {
+	fmt.Fprintf(f.writer, "<table%s>\n  <thead>\n    <tr>", f.htmlStyle(htmlTableCSS))
+	for _, h := range headers {
+		fmt.Fprintf(f.writer, "<th%s>%s</th>", f.htmlStyle(htmlHeadCSS), html.EscapeString(h))
+	}
+	fmt.Fprint(f.writer, "</tr>\n  </thead>\n  <tbody>\n")
+}
+
+func (f *Formatter) printHTMLRow(row []string, styles []StyleFunc) {
+	fmt.Fprint(f.writer, "    <tr>")
+	for i, cell := range row {
+		css := htmlCellCSS
+		if color := CurrentTheme().CSS[cellStyle(cell, i, styles, false)]; color != "" {
+			css += " color: " + color + ";"
+		}
+		attr := f.htmlStyle(css)
+		if align := f.align(i); align == "right" || align == "center" {
+			// Alignment is configured per column, so it applies without
+			// html_style too
+			if attr = f.htmlStyle(css + " text-align: " + align + ";"); attr == "" {
+				attr = ` style="text-align: ` + align + `;"`
+			}
+		}
+		text := strings.ReplaceAll(html.EscapeString(cell), "\n", "<br>")
+		fmt.Fprintf(f.writer, "<td%s>%s</td>", attr, text)
+	}
+	fmt.Fprint(f.writer, "</tr>\n")
+}
+
+func (f *Formatter) closeHTMLTable() error {
+	if _, err := io.WriteString(f.writer, "  </tbody>\n</table>\n"); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// htmlStyles returns the style function of each column in headers, which
+// color status cells when HTMLStyle is set
+func (f *Formatter) htmlStyles(headers []string) []StyleFunc {
+	if !f.config.HTMLStyle {
+		return nil
+	}
+	return f.columnStyleFuncs(headers)
+}
+
+// htmlStyle returns a style attribute with css, or nothing when HTMLStyle
+// is off
+func (f *Formatter) htmlStyle(css string) string {
+	if !f.config.HTMLStyle {
+		return ""
+	}
+	return ` style="` + css + `"`
+}
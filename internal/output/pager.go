@@ -0,0 +1,66 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// defaultPager runs when $PAGER is unset. -F quits at once when the output
+// fits, -R passes colors through, and -X leaves the output on screen.
+const defaultPager = "less -FRX"
+
+// pagerTerminal returns the terminal output is paged on, or nil when
+// paging is off or the output isn't a terminal a pager can run on
+func (f *Formatter) pagerTerminal() *os.File {
+	if !f.config.Pager || !CursorControl(f.out) {
+		return nil
+	}
+	return f.out.(*os.File)
+}
+
+// page writes content to out, through $PAGER when it is taller than the
+// terminal. When the pager can't run the content is written directly.
+func page(out *os.File, content []byte) error {
+	_, height, err := term.GetSize(int(out.Fd()))
+	if err != nil || bytes.Count(content, []byte("\n")) < height {
+		return write(out, content)
+	}
+
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = defaultPager
+	}
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		return write(out, content)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			slog.Debug("running pager", "pager", pager, "error", err)
+			return write(out, content)
+		}
+		// The pager ran and the user quit it; its exit status doesn't matter
+	}
+	return nil
+}
+
+func write(w io.Writer, content []byte) error {
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// parallelChunk is how many rows a worker lays out at a time
+const parallelChunk = 512
+
+// parallelWorkers returns how many goroutines lay out a table of n rows:
+// one when it's under output.parallel_rows, otherwise GOMAXPROCS
+func (f *Formatter) parallelWorkers(n int) int {
+	if limit := f.config.ParallelRows; limit <= 0 || n < limit {
+		return 1
+	}
+	return min(runtime.GOMAXPROCS(0), (n+parallelChunk-1)/parallelChunk)
+}
+
+// scanWidths returns the widest cell of each column of table, scanning
+// chunks of rows on all CPUs at once for a large table
+func (f *Formatter) scanWidths(table [][]string) []int {
+	workers := f.parallelWorkers(len(table))
+	if workers == 1 {
+		return rowWidths(nil, table)
+	}
+
+	parts := make([][]int, workers)
+	size := (len(table) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := range workers {
+		start, end := w*size, min((w+1)*size, len(table))
+		if start >= end {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parts[w] = rowWidths(nil, table[start:end])
+		}()
+	}
+	wg.Wait()
+
+	widths := make([]int, len(table[0]))
+	for _, part := range parts {
+		for i, width := range part {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], width)
+		}
+	}
+	return widths
+}
+
+// rowWidths widens widths to fit the cells of rows
+func rowWidths(widths []int, rows [][]string) []int {
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	return widths
+}
+
+// printRows writes rows with print. A table of output.parallel_rows or
+// more is laid out a chunk of rows at a time by workers on all CPUs, and
+// the chunks written in order as they're done; only a few are held at
+// once, so memory doesn't grow with the table.
+func (f *Formatter) printRows(rows [][]string, print func(w io.Writer, row []string)) {
+	workers := f.parallelWorkers(len(rows))
+	if workers == 1 {
+		for _, row := range rows {
+			print(f.writer, row)
+		}
+		return
+	}
+
+	type chunk struct {
+		rows [][]string
+		buf  bytes.Buffer
+		done chan struct{}
+	}
+	jobs := make(chan *chunk)
+	order := make(chan *chunk, 2*workers) // Chunks handed out, in order
+	go func() {
+		defer close(jobs)
+		defer close(order)
+		for start := 0; start < len(rows); start += parallelChunk {
+			c := &chunk{rows: rows[start:min(start+parallelChunk, len(rows))], done: make(chan struct{})}
+			order <- c
+			jobs <- c
+		}
+	}()
+	for range workers {
+		go func() {
+			for c := range jobs {
+				for _, row := range c.rows {
+					print(&c.buf, row)
+				}
+				close(c.done)
+			}
+		}()
+	}
+
+	for c := range order {
+		<-c.done
+		_, _ = f.writer.Write(c.buf.Bytes())
+	}
+}
@@ -0,0 +1,75 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// bigTable returns a table of n rows with cells of varying widths,
+// including wide characters, so chunks laid out apart size differently
+func bigTable(n int) [][]string {
+	table := [][]string{{"ID", "Name", "Status"}}
+	for i := range n {
+		name := fmt.Sprintf("item-%d", i*i%9973)
+		if i%7 == 0 {
+			name = "項目-" + name
+		}
+		table = append(table, []string{fmt.Sprint(i), name, []string{"succeeded", "failed", ""}[i%3]})
+	}
+	return table
+}
+
+// printTable prints table with the given style, laid out in parallel from
+// parallelRows rows; 0 never does
+func printTable(tb testing.TB, table [][]string, style string, parallelRows int) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	f := NewFormatterWithWriter(config.OutputConfig{Format: "table", TableStyle: style, ParallelRows: parallelRows}, &buf)
+	if err := f.Print(table); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPrintRowsParallel(t *testing.T) {
+	// Several workers even on a single CPU, so the parallel path runs
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	table := bigTable(5000)
+	for _, style := range []string{"ascii", "unicode", "markdown"} {
+		t.Run(style, func(t *testing.T) {
+			sequential := printTable(t, table, style, 0)
+			parallel := printTable(t, table, style, 2000)
+			if !bytes.Equal(parallel, sequential) {
+				t.Errorf("parallel output (%d bytes) differs from sequential output (%d bytes)", len(parallel), len(sequential))
+			}
+		})
+	}
+}
+
+// BenchmarkPrintTableParallel compares laying out tables sequentially with
+// the default output.parallel_rows of 2000: just below it, where the
+// parallel setting falls back to sequential layout, just above it, and
+// well above it
+func BenchmarkPrintTableParallel(b *testing.B) {
+	for _, rows := range []int{1990, 2010, 50000} {
+		table := bigTable(rows)
+		for _, bm := range []struct {
+			name         string
+			parallelRows int
+		}{
+			{name: "sequential", parallelRows: 0},
+			{name: "parallel", parallelRows: 2000},
+		} {
+			b.Run(fmt.Sprintf("%d/%s", rows, bm.name), func(b *testing.B) {
+				for b.Loop() {
+					printTable(b, table, "unicode", bm.parallelRows)
+				}
+			})
+		}
+	}
+}
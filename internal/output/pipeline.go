@@ -0,0 +1,167 @@
+package output
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Overflow policies of a Pipeline: what Send does with a row when the
+// queue is full because output is slower than rows are produced
+const (
+	OverflowBlock  = "block"  // Wait for room, slowing the producer to the output's pace
+	OverflowDrop   = "drop"   // Drop the row
+	OverflowSample = "sample" // Keep one in sampleEvery of the rows that find the queue full
+)
+
+// sampleEvery is how often the sample policy keeps a row while the queue
+// is full
+const sampleEvery = 10
+
+// PipelineStats counts the rows through a Pipeline
+type PipelineStats struct {
+	Sent    int64 // Rows given to Send
+	Written int64 // Rows the printer wrote, including those its filter skipped
+	Dropped int64 // Rows dropped because the queue was full
+	Peak    int   // Most rows queued at once
+}
+
+// Pipeline writes rows to a StreamPrinter in the background, from a
+// bounded queue, so a producer isn't held up by every slow write, e.g. to
+// a pipe over SSH, and memory stays bounded when output falls behind. The
+// queue holds output.stream_buffer rows; output.stream_overflow says what
+// happens to rows when it's full (see the Overflow policies).
+//
+//	p := s.Pipe()
+//	for rec := range records {
+//		if err := p.Send(ctx, rec); err != nil { ... }
+//	}
+//	if err := p.Close(); err != nil { ... }
+//	return s.End()
+type Pipeline struct {
+	s        *StreamPrinter
+	rows     chan interface{}
+	overflow string
+	done     chan struct{} // Closed once the writer stops
+
+	mu        sync.Mutex
+	stats     PipelineStats
+	congested int   // Rows that found the queue full, for sampling
+	err       error // Stopped the writer
+}
+
+// Pipe starts writing rows sent to the returned pipeline to s, which must
+// have begun. Close it before End.
+func (s *StreamPrinter) Pipe() *Pipeline {
+	overflow := s.f.config.StreamOverflow
+	if overflow == "" {
+		overflow = OverflowBlock
+	}
+	p := &Pipeline{
+		s:        s,
+		rows:     make(chan interface{}, max(s.f.config.StreamBuffer, 0)),
+		overflow: overflow,
+		done:     make(chan struct{}),
+	}
+	go p.write()
+	return p
+}
+
+// Send queues row to be written, following the overflow policy when the
+// queue is full; blocking ends when ctx is done. It returns the error
+// that stopped the writer, if one did.
+func (p *Pipeline) Send(ctx context.Context, row interface{}) error {
+	p.mu.Lock()
+	if p.err != nil {
+		defer p.mu.Unlock()
+		return p.err
+	}
+	p.stats.Sent++
+	p.mu.Unlock()
+
+	select {
+	case p.rows <- row:
+		p.queued()
+		return nil
+	default:
+	}
+
+	// The queue is full
+	if p.overflow != OverflowBlock {
+		p.mu.Lock()
+		p.congested++
+		drop := p.overflow == OverflowDrop || p.congested%sampleEvery != 1
+		if drop {
+			p.stats.Dropped++
+		}
+		p.mu.Unlock()
+		if drop {
+			return nil
+		}
+	}
+	select {
+	case p.rows <- row:
+		p.queued()
+		return nil
+	case <-p.done:
+		return p.Err()
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Close waits for the queued rows to be written and returns the error that
+// stopped the writer, if one did. Dropped rows are logged as a warning.
+func (p *Pipeline) Close() error {
+	close(p.rows)
+	<-p.done
+	if st := p.Stats(); st.Dropped > 0 {
+		slog.Warn("output fell behind; rows dropped", "dropped", st.Dropped, "sent", st.Sent, "policy", p.overflow)
+	}
+	return p.Err()
+}
+
+// Err returns the error that stopped the writer, if one did
+func (p *Pipeline) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Stats returns the pipeline's counters so far
+func (p *Pipeline) Stats() PipelineStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// queued records the depth of the queue after a row was added
+func (p *Pipeline) queued() {
+	n := len(p.rows)
+	p.mu.Lock()
+	p.stats.Peak = max(p.stats.Peak, n)
+	p.mu.Unlock()
+}
+
+// write writes queued rows until the queue is closed. After a write fails,
+// the rest are discarded, so senders aren't left blocked.
+func (p *Pipeline) write() {
+	defer close(p.done)
+	for row := range p.rows {
+		if p.Err() != nil {
+			continue
+		}
+		err := p.s.WriteRow(row)
+		if err == nil && len(p.rows) == 0 {
+			// Caught up: write out what's held rather than wait for more
+			err = p.s.f.Flush()
+		}
+		p.mu.Lock()
+		if err != nil {
+			p.err = err
+		} else {
+			p.stats.Written++
+		}
+		p.mu.Unlock()
+	}
+}
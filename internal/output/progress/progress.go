@@ -0,0 +1,253 @@
+// Package progress shows the progress of long-running work on stderr: a
+// Bar when the amount of work is known and a Spinner when it isn't. On a
+// terminal they redraw in place; otherwise, such as when stderr is piped
+// to a log or TERM is dumb, they degrade to a log line every few seconds.
+// Quiet shows nothing.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+const (
+	// redrawInterval is how often a terminal display is redrawn
+	redrawInterval = 100 * time.Millisecond
+
+	// logInterval is how often progress is logged when not on a terminal
+	logInterval = 2 * time.Second
+)
+
+// Options configures a Bar or Spinner
+type Options struct {
+	Quiet  bool      // Show nothing (output.quiet)
+	Output io.Writer // Where the display goes; os.Stderr when nil
+	Unit   string    // What is counted, e.g. "files"; "items" when empty
+}
+
+// display runs the redraw loop shared by Bar and Spinner
+type display struct {
+	out   io.Writer
+	tty   bool
+	start time.Time
+	draw  func(final bool)
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newDisplay(opts Options, draw func(final bool)) *display {
+	d := &display{out: opts.Output, start: time.Now(), draw: draw}
+	if opts.Quiet {
+		return d
+	}
+	if d.out == nil {
+		d.out = os.Stderr
+	}
+	d.tty = output.CursorControl(d.out)
+
+	d.stop, d.done = make(chan struct{}), make(chan struct{})
+	go d.run()
+	return d
+}
+
+func (d *display) run() {
+	defer close(d.done)
+	interval := logInterval
+	if d.tty {
+		interval = redrawInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			d.draw(false)
+		case <-d.stop:
+			d.draw(true)
+			return
+		}
+	}
+}
+
+// finish stops the loop after a final draw; later calls do nothing
+func (d *display) finish() {
+	if d.stop == nil {
+		return
+	}
+	d.once.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+}
+
+// line replaces the current terminal line with s
+func (d *display) line(s string) {
+	fmt.Fprintf(d.out, "\r%s\x1b[K", s)
+}
+
+// width returns the terminal's width, or 80 when it's unknown
+func (d *display) width() int {
+	if f, ok := d.out.(*os.File); ok {
+		if w, _, err := term.GetSize(int(f.Fd())); err == nil && w > 0 {
+			return w
+		}
+	}
+	return 80
+}
+
+// Bar shows progress through a known number of items with the throughput
+// and an estimate of the time left. Add may be called from any goroutine.
+type Bar struct {
+	label string
+	unit  string
+	total atomic.Int64
+	count atomic.Int64
+	d     *display
+}
+
+// NewBar starts a bar counting up to total; a total of 0 means it isn't
+// known yet (see SetTotal)
+func NewBar(label string, total int, opts Options) *Bar {
+	b := &Bar{label: label, unit: opts.Unit}
+	if b.unit == "" {
+		b.unit = "items"
+	}
+	b.total.Store(int64(total))
+	b.d = newDisplay(opts, b.draw)
+	return b
+}
+
+// SetTotal sets the number of items once it is known
+func (b *Bar) SetTotal(n int) {
+	b.total.Store(int64(n))
+}
+
+// Add counts n more items done
+func (b *Bar) Add(n int) {
+	b.count.Add(int64(n))
+}
+
+// Finish draws the bar a last time and ends its line. Call it once the
+// work is over, whether or not it completed.
+func (b *Bar) Finish() {
+	b.d.finish()
+}
+
+func (b *Bar) draw(final bool) {
+	count, total := b.count.Load(), b.total.Load()
+	if final && count == 0 && total == 0 {
+		// Nothing to report, such as when listing the work failed
+		if b.d.tty {
+			b.d.line("")
+		}
+		return
+	}
+	elapsed := time.Since(b.d.start)
+	rate := float64(count) / elapsed.Seconds()
+
+	var eta time.Duration
+	if count > 0 && total > count {
+		eta = time.Duration(float64(total-count) / rate * float64(time.Second))
+	}
+
+	if !b.d.tty {
+		attrs := []any{"done", count, "total", total, "rate", fmt.Sprintf("%.1f %s/s", rate, b.unit)}
+		if final {
+			attrs = append(attrs, "elapsed", elapsed.Round(time.Millisecond))
+		} else if eta > 0 {
+			attrs = append(attrs, "eta", eta.Round(time.Second))
+		}
+		slog.Info(b.label, attrs...)
+		return
+	}
+
+	stats := fmt.Sprintf(" %d/%d %s  %.1f/s", count, total, b.unit, rate)
+	if total == 0 {
+		stats = fmt.Sprintf(" %d %s  %.1f/s", count, b.unit, rate)
+	}
+	switch {
+	case final:
+		stats += "  " + elapsed.Round(100*time.Millisecond).String()
+	case eta > 0:
+		stats += "  ETA " + eta.Round(time.Second).String()
+	}
+
+	bar := ""
+	if total > 0 {
+		size := min(40, b.d.width()-len(b.label)-len(stats)-4)
+		if size >= 10 {
+			filled := int(min(count, total) * int64(size) / total)
+			bar = " [" + strings.Repeat("=", filled) + strings.Repeat(" ", size-filled) + "]"
+		}
+	}
+	b.d.line(b.label + bar + stats)
+	if final {
+		fmt.Fprintln(b.d.out)
+	}
+}
+
+// spinnerFrames animate a Spinner
+var spinnerFrames = []string{"-", "\\", "|", "/"}
+
+// Spinner shows that work of unknown length is still going, with the time
+// it has taken
+type Spinner struct {
+	label  string
+	status atomic.Pointer[string] // Set with SetStatus
+	frame  int
+	shown  bool
+	d      *display
+}
+
+// NewSpinner starts a spinner
+func NewSpinner(label string, opts Options) *Spinner {
+	s := &Spinner{label: label}
+	s.d = newDisplay(opts, s.draw)
+	return s
+}
+
+// SetStatus shows status after the label, e.g. the state of an operation
+// being waited on. It may be called from any goroutine.
+func (s *Spinner) SetStatus(status string) {
+	s.status.Store(&status)
+}
+
+// Stop removes the spinner. Off a terminal nothing more is logged.
+func (s *Spinner) Stop() {
+	s.d.finish()
+}
+
+func (s *Spinner) draw(final bool) {
+	elapsed := time.Since(s.d.start)
+	label := s.label
+	if status := s.status.Load(); status != nil && *status != "" {
+		label += ": " + *status
+	}
+	if !s.d.tty {
+		if !final {
+			slog.Info(label, "elapsed", elapsed.Round(time.Second))
+		}
+		return
+	}
+	if final {
+		if s.shown {
+			s.d.line("")
+		}
+		return
+	}
+	s.shown = true
+	s.frame = (s.frame + 1) % len(spinnerFrames)
+	s.d.line(fmt.Sprintf("%s %s (%s)", spinnerFrames[s.frame], label, elapsed.Round(time.Second)))
+}
@@ -0,0 +1,63 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/blacksilver/termplate-go/internal/termtest"
+)
+
+func TestBarDisplay(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		terminal bool
+		quiet    bool
+		redraw   bool // Drawn in place on the terminal rather than logged
+	}{
+		{name: "terminal", env: map[string]string{"TERM": "xterm"}, terminal: true, redraw: true},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}, terminal: true},
+		{name: "not a terminal", env: map[string]string{"TERM": "xterm"}},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "TERM": "xterm"}, terminal: true, redraw: true},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0", "TERM": "xterm"}, terminal: true, redraw: true},
+		// Forcing color doesn't make a pipe a terminal, or a dumb one smart
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR_FORCE and TERM=dumb", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "dumb"}, terminal: true},
+		{name: "quiet", env: map[string]string{"TERM": "xterm"}, terminal: true, quiet: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"NO_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "TERM"} {
+				t.Setenv(k, tt.env[k])
+			}
+			var logs bytes.Buffer
+			defer slog.SetDefault(slog.Default())
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+			var out bytes.Buffer
+			var w io.Writer = &out
+			if tt.terminal {
+				w = termtest.Open(t)
+			}
+			bar := NewBar("Hashing", 10, Options{Quiet: tt.quiet, Output: w, Unit: "files"})
+			bar.Add(10)
+			bar.Finish()
+
+			logged := strings.Contains(logs.String(), "msg=Hashing")
+			if want := !tt.redraw && !tt.quiet; logged != want {
+				t.Errorf("logged = %v, want %v:\n%s", logged, want, logs.String())
+			}
+			if logged && !strings.Contains(logs.String(), "done=10 total=10") {
+				t.Errorf("log is missing the count:\n%s", logs.String())
+			}
+			// Only a terminal gets the bar; a pipe gets nothing, not
+			// even cursor movement
+			if out.Len() > 0 {
+				t.Errorf("wrote %q to a non-terminal", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,796 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Query is a compiled --query expression: a subset of jq that covers
+// selecting, filtering, and projecting structured output.
+//
+//	.                      the whole value
+//	.name  .a.b  ."key"    object fields (null when missing)
+//	.[0]  .[-1]  .[1:3]    list elements and slices
+//	.items[]               every element, one result each
+//	a | b                  feed each result of a into b
+//	select(.n > 1)         keep values where the condition holds
+//	{name, id: .meta.id}   build an object
+//	[ .items[].name ]      collect results into a list
+//	== != < <= > >= and or not, length, keys, map(f)
+//
+// A query that iterates prints its results as a list; one that doesn't
+// prints its single result as is.
+type Query struct {
+	src   string
+	root  filter
+	multi bool // Yields a stream of results rather than one value
+}
+
+// filter maps an input value to zero or more outputs
+type filter func(in interface{}) ([]interface{}, error)
+
+// CompileQuery parses a query expression
+func CompileQuery(src string) (*Query, error) {
+	p := &queryParser{src: src}
+	if err := p.lex(); err != nil {
+		return nil, model.NewValidationError("query", err.Error())
+	}
+	root, err := p.parsePipe()
+	if err == nil && p.pos < len(p.toks) {
+		err = fmt.Errorf("unexpected %q at offset %d", p.toks[p.pos].text, p.toks[p.pos].at)
+	}
+	if err != nil {
+		return nil, model.NewValidationError("query", err.Error())
+	}
+	return &Query{src: src, root: root, multi: p.multi}, nil
+}
+
+// String returns the query source
+func (q *Query) String() string {
+	return q.src
+}
+
+// Apply runs the query on data, which is first converted to its JSON form
+func (q *Query) Apply(data interface{}) (interface{}, error) {
+	v, err := toValue(data)
+	if err != nil {
+		return nil, err
+	}
+	out, err := q.root(v)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", q.src, err)
+	}
+	if !q.multi && len(out) == 1 {
+		return out[0], nil
+	}
+	if out == nil {
+		out = []interface{}{}
+	}
+	return out, nil
+}
+
+// queryResult wraps the result of a query so the formatter prints it as
+// generic values
+type queryResult struct {
+	value interface{}
+}
+
+// resultValue returns data in generic form: a query result's value, or
+// data converted by toValue
+func resultValue(data interface{}) (interface{}, error) {
+	if r, ok := data.(queryResult); ok {
+		return r.value, nil
+	}
+	return toValue(data)
+}
+
+func (r queryResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.value)
+}
+
+func (r queryResult) MarshalYAML() (interface{}, error) {
+	return r.value, nil
+}
+
+// Lexer
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokPunct
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type token struct {
+	kind tokKind
+	text string
+	at   int
+}
+
+type queryParser struct {
+	src     string
+	toks    []token
+	pos     int
+	collect int  // Depth of [...] collectors, which turn streams into lists
+	multi   bool // An iteration outside any collector was parsed
+}
+
+func (p *queryParser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case strings.ContainsRune(".[]{}():,|", rune(c)):
+			p.toks = append(p.toks, token{tokPunct, string(c), i})
+			i++
+		case strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			p.toks = append(p.toks, token{tokPunct, s[i : i+2], i})
+			i += 2
+		case c == '<' || c == '>':
+			p.toks = append(p.toks, token{tokPunct, string(c), i})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return fmt.Errorf("invalid string at offset %d", i)
+			}
+			p.toks = append(p.toks, token{tokString, text, i})
+			i = j + 1
+		case c == '-' || c >= '0' && c <= '9':
+			j := i + 1
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' && j+1 < len(s) && s[j+1] >= '0' && s[j+1] <= '9') {
+				j++
+			}
+			p.toks = append(p.toks, token{tokNumber, s[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '-' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, token{tokIdent, s[i:j], i})
+			i = j
+		default:
+			return fmt.Errorf("unexpected %q at offset %d", c, i)
+		}
+	}
+	return nil
+}
+
+func (p *queryParser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{kind: tokEOF, at: len(p.src)}
+}
+
+func (p *queryParser) accept(text string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == text || t.kind == tokIdent && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		if t.kind == tokEOF {
+			return fmt.Errorf("expected %q at end of query", text)
+		}
+		return fmt.Errorf("expected %q at offset %d, found %q", text, t.at, t.text)
+	}
+	return nil
+}
+
+// Parser
+
+func (p *queryParser) parsePipe() (filter, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = pipe(left, right)
+	}
+	return left, nil
+}
+
+func pipe(left, right filter) filter {
+	return func(in interface{}) ([]interface{}, error) {
+		vs, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []interface{}
+		for _, v := range vs {
+			rs, err := right(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rs...)
+		}
+		return out, nil
+	}
+}
+
+func (p *queryParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, func(a, b interface{}) (interface{}, error) {
+			return truthy(a) || truthy(b), nil
+		})
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (filter, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, func(a, b interface{}) (interface{}, error) {
+			return truthy(a) && truthy(b), nil
+		})
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseCompare() (filter, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokPunct {
+		return left, nil
+	}
+	switch op := t.text; op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.pos++
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return binary(left, right, func(a, b interface{}) (interface{}, error) {
+			return compare(op, a, b), nil
+		}), nil
+	}
+	return left, nil
+}
+
+// binary evaluates both sides against the same input and combines every
+// pair of results
+func binary(left, right filter, op func(a, b interface{}) (interface{}, error)) filter {
+	return func(in interface{}) ([]interface{}, error) {
+		ls, err := left(in)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := right(in)
+		if err != nil {
+			return nil, err
+		}
+		var out []interface{}
+		for _, l := range ls {
+			for _, r := range rs {
+				v, err := op(l, r)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, v)
+			}
+		}
+		return out, nil
+	}
+}
+
+func (p *queryParser) parsePostfix() (filter, error) {
+	f, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case t.kind == tokPunct && t.text == "." && p.pos+1 < len(p.toks) &&
+			(p.toks[p.pos+1].kind == tokIdent || p.toks[p.pos+1].kind == tokString):
+			p.pos++
+			f = pipe(f, field(p.toks[p.pos].text))
+			p.pos++
+		case t.kind == tokPunct && t.text == "[":
+			suffix, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			f = pipe(f, suffix)
+		default:
+			return f, nil
+		}
+	}
+}
+
+// parseBracket parses [], [n], ["key"], and [a:b] after a value
+func (p *queryParser) parseBracket() (filter, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.accept("]") {
+		if p.collect == 0 {
+			p.multi = true
+		}
+		return iterate, nil
+	}
+
+	var from, to *int
+	t := p.peek()
+	if t.kind == tokString {
+		p.pos++
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return field(t.text), nil
+	}
+	if t.kind == tokNumber {
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q at offset %d", t.text, t.at)
+		}
+		from = &n
+		p.pos++
+	}
+	if !p.accept(":") {
+		if from == nil {
+			return nil, fmt.Errorf("expected an index, slice, or key at offset %d", p.peek().at)
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return index(*from), nil
+	}
+	if t := p.peek(); t.kind == tokNumber {
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q at offset %d", t.text, t.at)
+		}
+		to = &n
+		p.pos++
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return slice(from, to), nil
+}
+
+func (p *queryParser) parsePrimary() (filter, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokPunct && t.text == ".":
+		p.pos++
+		if n := p.peek(); n.kind == tokIdent || n.kind == tokString {
+			p.pos++
+			return field(n.text), nil
+		}
+		return identity, nil
+	case t.kind == tokPunct && t.text == "(":
+		p.pos++
+		f, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case t.kind == tokPunct && t.text == "[":
+		p.pos++
+		p.collect++
+		f, err := p.parsePipe()
+		p.collect--
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(in interface{}) ([]interface{}, error) {
+			vs, err := f(in)
+			if vs == nil {
+				vs = []interface{}{}
+			}
+			return []interface{}{vs}, err
+		}, nil
+	case t.kind == tokPunct && t.text == "{":
+		return p.parseObject()
+	case t.kind == tokString:
+		p.pos++
+		return constant(t.text), nil
+	case t.kind == tokNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return constant(i), nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at offset %d", t.text, t.at)
+		}
+		return constant(f), nil
+	case t.kind == tokIdent:
+		p.pos++
+		return p.parseIdent(t)
+	case t.kind == tokEOF:
+		return nil, fmt.Errorf("unexpected end of query")
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", t.text, t.at)
+	}
+}
+
+func (p *queryParser) parseIdent(t token) (filter, error) {
+	switch t.text {
+	case "true":
+		return constant(true), nil
+	case "false":
+		return constant(false), nil
+	case "null":
+		return constant(nil), nil
+	case "not":
+		return func(in interface{}) ([]interface{}, error) {
+			return []interface{}{!truthy(in)}, nil
+		}, nil
+	case "length":
+		return length, nil
+	case "keys":
+		return keys, nil
+	case "select", "map":
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		// map collects its results, so its iteration doesn't make the
+		// whole query a stream
+		if t.text == "map" {
+			p.collect++
+		}
+		arg, err := p.parsePipe()
+		if t.text == "map" {
+			p.collect--
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if t.text == "map" {
+			return func(in interface{}) ([]interface{}, error) {
+				vs, err := pipe(iterate, arg)(in)
+				if vs == nil {
+					vs = []interface{}{}
+				}
+				return []interface{}{vs}, err
+			}, nil
+		}
+		return func(in interface{}) ([]interface{}, error) {
+			conds, err := arg(in)
+			if err != nil {
+				return nil, err
+			}
+			var out []interface{}
+			for _, c := range conds {
+				if truthy(c) {
+					out = append(out, in)
+				}
+			}
+			return out, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %q at offset %d", t.text, t.at)
+	}
+}
+
+// parseObject parses {a, b: .x, "c d": .y}
+func (p *queryParser) parseObject() (filter, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	type entry struct {
+		key   string
+		value filter
+	}
+	var entries []entry
+	for !p.accept("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.peek()
+		if t.kind != tokIdent && t.kind != tokString {
+			return nil, fmt.Errorf("expected a key at offset %d", t.at)
+		}
+		p.pos++
+		value := field(t.text)
+		if p.accept(":") {
+			var err error
+			if value, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry{t.text, value})
+	}
+
+	return func(in interface{}) ([]interface{}, error) {
+		// Each entry may yield several values; build every combination
+		objs := []*object{newObject()}
+		for _, e := range entries {
+			vs, err := e.value(in)
+			if err != nil {
+				return nil, err
+			}
+			var next []*object
+			for _, obj := range objs {
+				for _, v := range vs {
+					o := newObject()
+					for _, k := range obj.keys {
+						o.set(k, obj.values[k])
+					}
+					o.set(e.key, v)
+					next = append(next, o)
+				}
+			}
+			objs = next
+		}
+		out := make([]interface{}, len(objs))
+		for i, o := range objs {
+			out[i] = o
+		}
+		return out, nil
+	}, nil
+}
+
+// Filters
+
+func identity(in interface{}) ([]interface{}, error) {
+	return []interface{}{in}, nil
+}
+
+func constant(v interface{}) filter {
+	return func(interface{}) ([]interface{}, error) {
+		return []interface{}{v}, nil
+	}
+}
+
+func field(name string) filter {
+	return func(in interface{}) ([]interface{}, error) {
+		switch t := in.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case *object:
+			v, _ := t.get(name)
+			return []interface{}{v}, nil
+		default:
+			return nil, fmt.Errorf("cannot get field %q of %s", name, typeName(in))
+		}
+	}
+}
+
+func index(i int) filter {
+	return func(in interface{}) ([]interface{}, error) {
+		switch t := in.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case []interface{}:
+			j := i
+			if j < 0 {
+				j += len(t)
+			}
+			if j < 0 || j >= len(t) {
+				return []interface{}{nil}, nil
+			}
+			return []interface{}{t[j]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with a number", typeName(in))
+		}
+	}
+}
+
+func slice(from, to *int) filter {
+	return func(in interface{}) ([]interface{}, error) {
+		list, ok := in.([]interface{})
+		if !ok {
+			if in == nil {
+				return []interface{}{nil}, nil
+			}
+			return nil, fmt.Errorf("cannot slice %s", typeName(in))
+		}
+		start, end := 0, len(list)
+		if from != nil {
+			start = clampIndex(*from, len(list))
+		}
+		if to != nil {
+			end = clampIndex(*to, len(list))
+		}
+		if start > end {
+			start = end
+		}
+		return []interface{}{list[start:end]}, nil
+	}
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+func iterate(in interface{}) ([]interface{}, error) {
+	switch t := in.(type) {
+	case []interface{}:
+		return t, nil
+	case *object:
+		out := make([]interface{}, len(t.keys))
+		for i, k := range t.keys {
+			out[i] = t.values[k]
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", typeName(in))
+	}
+}
+
+func length(in interface{}) ([]interface{}, error) {
+	switch t := in.(type) {
+	case nil:
+		return []interface{}{int64(0)}, nil
+	case string:
+		return []interface{}{int64(len([]rune(t)))}, nil
+	case []interface{}:
+		return []interface{}{int64(len(t))}, nil
+	case *object:
+		return []interface{}{int64(len(t.keys))}, nil
+	default:
+		return nil, fmt.Errorf("%s has no length", typeName(in))
+	}
+}
+
+func keys(in interface{}) ([]interface{}, error) {
+	obj, ok := in.(*object)
+	if !ok {
+		return nil, fmt.Errorf("%s has no keys", typeName(in))
+	}
+	sorted := append([]string(nil), obj.keys...)
+	sort.Strings(sorted)
+	out := make([]interface{}, len(sorted))
+	for i, k := range sorted {
+		out[i] = k
+	}
+	return []interface{}{out}, nil
+}
+
+// Helpers
+
+func truthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case int64, float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case *object:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func compare(op string, a, b interface{}) bool {
+	c, ok := order(a, b)
+	switch op {
+	case "==":
+		return ok && c == 0
+	case "!=":
+		return !ok || c != 0
+	case "<":
+		return ok && c < 0
+	case "<=":
+		return ok && c <= 0
+	case ">":
+		return ok && c > 0
+	default:
+		return ok && c >= 0
+	}
+}
+
+// order compares two values of the same kind; ok is false when they can't
+// be compared, e.g. a string and a number
+func order(a, b interface{}) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case fa < fb:
+			return -1, true
+		case fa > fb:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch ta := a.(type) {
+	case nil:
+		return 0, b == nil
+	case string:
+		tb, ok := b.(string)
+		return strings.Compare(ta, tb), ok
+	case bool:
+		tb, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if ta == tb {
+			return 0, true
+		}
+		if !ta {
+			return -1, true
+		}
+		return 1, true
+	default:
+		// Lists and objects are only equal to themselves
+		return 0, valueString(a) == valueString(b)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	}
+	return 0, false
+}
@@ -0,0 +1,139 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// commands is introspect-like output: commands with lists of flags of
+// different lengths
+const commands = `{"commands": [
+	{"name": "apply", "hidden": false, "flags": [{"name": "a"}, {"name": "b"}, {"name": "c"}]},
+	{"name": "events", "hidden": true, "flags": [{"name": "d"}]},
+	{"name": "new", "hidden": false, "flags": []},
+	{"name": "rename", "hidden": false, "flags": [{"name": "e"}, {"name": "f"}]}
+]}`
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // JSON of the result
+	}{
+		{name: "identity", query: ".commands[0].name", want: `"apply"`},
+		{name: "missing field", query: ".commands[0].usage", want: `null`},
+		{name: "quoted field", query: `."commands"[1]."name"`, want: `"events"`},
+		{name: "bracket field", query: `.commands[1]["name"]`, want: `"events"`},
+		{name: "index", query: ".commands[1].flags[0].name", want: `"d"`},
+		{name: "negative index", query: ".commands[-1].name", want: `"rename"`},
+		{name: "index out of range", query: ".commands[9]", want: `null`},
+		{name: "negative index out of range", query: ".commands[-9]", want: `null`},
+		// Each element is indexed from its own end
+		{name: "negative index in iteration", query: ".commands[] | .flags[-1].name", want: `["c","d",null,"f"]`},
+		{name: "index in iteration", query: ".commands[].flags[1].name", want: `["b",null,null,"f"]`},
+		{name: "slice", query: "[.commands[1:3][].name]", want: `["events","new"]`},
+		{name: "open start", query: "[.commands[:1][].name]", want: `["apply"]`},
+		{name: "open end", query: "[.commands[-1:][].name]", want: `["rename"]`},
+		{name: "slice in iteration", query: ".commands[] | .flags[-2:] | length", want: `[2,1,0,2]`},
+		{name: "iterate", query: ".commands[].name", want: `["apply","events","new","rename"]`},
+		{name: "collect", query: "[.commands[].name] | length", want: `4`},
+		{name: "select", query: ".commands[] | select(.hidden) | .name", want: `["events"]`},
+		{name: "compare", query: ".commands[] | select((.flags | length) >= 2 and .name != \"apply\") | .name", want: `["rename"]`},
+		{name: "not", query: ".commands[] | select(.hidden | not) | .name", want: `["apply","new","rename"]`},
+		{name: "map", query: ".commands | map(.name)", want: `["apply","events","new","rename"]`},
+		{name: "keys", query: ".commands[0] | keys", want: `["flags","hidden","name"]`},
+		{
+			name: "object", query: ".commands[] | {name, last: .flags[-1].name, n: (.flags | length)}",
+			want: `[{"name":"apply","last":"c","n":3},{"name":"events","last":"d","n":1},{"name":"new","last":null,"n":0},{"name":"rename","last":"f","n":2}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := CompileQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := q.Apply(json.RawMessage(commands))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		compile bool // Fails to compile, rather than to run
+	}{
+		{name: "unclosed bracket", query: ".commands[", compile: true},
+		{name: "unknown function", query: ".commands | sort", compile: true},
+		{name: "trailing input", query: ".commands )", compile: true},
+		{name: "empty", query: "", compile: true},
+		{name: "field of a list", query: ".commands.name"},
+		{name: "index of an object", query: ".commands[0][0]"},
+		{name: "iterate a string", query: ".commands[0].name[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := CompileQuery(tt.query)
+			if tt.compile {
+				if err == nil {
+					t.Fatalf("CompileQuery(%q) succeeded, want an error", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.Apply(json.RawMessage(commands)); err == nil {
+				t.Errorf("%s succeeded, want an error", tt.query)
+			}
+		})
+	}
+}
+
+func TestQueryWithFilter(t *testing.T) {
+	type template struct {
+		Name string `json:"name"`
+		Kind string `json:"kind"`
+	}
+	templates := []template{{"cli", "embedded"}, {"api", "git"}, {"web", "git"}}
+	tests := []struct {
+		name   string
+		data   interface{}
+		config config.OutputConfig
+		want   string
+	}{
+		// The filter and group-by see the rows, and the query projects them
+		{name: "list", data: templates, config: config.OutputConfig{Query: ".[].name", Filter: "kind==git"}, want: `["api","web"]`},
+		{name: "added column", data: templates, config: config.OutputConfig{Query: ".[].label", AddColumns: []string{"label=upper(name)"}, Filter: "name!=cli"}, want: `["API","WEB"]`},
+		{name: "grouped", data: templates, config: config.OutputConfig{Query: "map(.count)", GroupBy: "kind"}, want: `[1,2]`},
+		// The query picks the list the filter runs on
+		{name: "object", data: map[string]interface{}{"results": templates}, config: config.OutputConfig{Query: ".results", Filter: "name=~b"}, want: `[{"name":"web","kind":"git"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.config.Format = "json"
+			if err := NewFormatterWithWriter(tt.config, &buf).Print(tt.data); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(buf.String()); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
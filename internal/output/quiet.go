@@ -0,0 +1,195 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// IDer is implemented by records with a primary identifier. Quiet output
+// (output.quiet) prints only the identifiers, one per line, like
+// docker ps -q, so they can be piped into xargs.
+type IDer interface {
+	ID() string
+}
+
+// idKeys name the field used as the identifier of records that aren't
+// IDers, matched case-insensitively in this order
+var idKeys = []string{"id", "name"}
+
+// printIDs prints the identifier of each record in data
+func (f *Formatter) printIDs(data interface{}) error {
+	ids, err := identifiers(data)
+	if err != nil {
+		return err
+	}
+	if f.config.Filter != "" || f.config.SortBy != "" {
+		if ids, err = f.filterIDs(data, ids); err != nil {
+			return err
+		}
+	}
+	for _, id := range chunkSlice(f, ids) {
+		if _, err := fmt.Fprintln(f.writer, id); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	return nil
+}
+
+// idHeader names the column holding the identifiers while filterIDs
+// filters and sorts the table of the records
+const idHeader = "\x00id"
+
+// filterIDs keeps the ids of the records matching Filter, sorted by
+// SortBy, filtering and sorting the records as table output would
+func (f *Formatter) filterIDs(data interface{}, ids []string) ([]string, error) {
+	table, err := f.convertTable(data)
+	if err != nil {
+		return nil, err
+	}
+	// A single record is a table of its fields rather than a row
+	if len(table) != len(ids)+1 {
+		return ids, nil
+	}
+	// Copied, as the table may be the caller's data
+	rows := make([][]string, len(table))
+	rows[0] = append(slices.Clone(table[0]), idHeader)
+	for i, id := range ids {
+		rows[i+1] = append(slices.Clone(table[i+1]), id)
+	}
+	if table, err = f.filterTable(rows); err != nil {
+		return nil, err
+	}
+
+	col := slices.Index(table[0], idHeader)
+	ids = ids[:0]
+	for _, row := range table[1:] {
+		ids = append(ids, row[col])
+	}
+	return ids, nil
+}
+
+// identifiers returns the identifier of each item of a list, or of data
+// itself when it's a single record
+func identifiers(data interface{}) ([]string, error) {
+	switch d := data.(type) {
+	case queryResult:
+		return valueIDs(d.value)
+	case [][]string:
+		return tableIDs(d), nil
+	case IDer:
+		return []string{d.ID()}, nil
+	}
+
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		id, err := identifier(data)
+		if err != nil {
+			return nil, err
+		}
+		return []string{id}, nil
+	}
+
+	ids := make([]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		// Elements are addressable, so pointer receivers count too
+		if item.CanAddr() {
+			if ider, ok := item.Addr().Interface().(IDer); ok {
+				ids = append(ids, ider.ID())
+				continue
+			}
+		}
+		id, err := identifier(item.Interface())
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// identifier returns the identifier of one record: its ID method, else
+// its id or name field, else the record itself when it's a scalar
+func identifier(item interface{}) (string, error) {
+	if ider, ok := item.(IDer); ok {
+		return ider.ID(), nil
+	}
+	v, err := toValue(item)
+	if err != nil {
+		return "", fmt.Errorf("unsupported data type %T for quiet output: %w", item, err)
+	}
+	id, ok := valueID(v)
+	if !ok {
+		return "", fmt.Errorf("%T has no ID method or id or name field for quiet output", item)
+	}
+	return id, nil
+}
+
+// valueIDs is identifiers for a generic value
+func valueIDs(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	ids := make([]string, len(list))
+	for i, item := range list {
+		if ids[i], ok = valueID(item); !ok {
+			return nil, errors.New("query result items have no id or name field for quiet output")
+		}
+	}
+	return ids, nil
+}
+
+// valueID is identifier for a generic value; ok is false for objects
+// without an id or name and for lists
+func valueID(v interface{}) (id string, ok bool) {
+	switch t := v.(type) {
+	case *object:
+		for _, want := range idKeys {
+			for _, k := range t.keys {
+				if strings.EqualFold(k, want) {
+					return valueString(t.values[k]), true
+				}
+			}
+		}
+		return "", false
+	case []interface{}:
+		return "", false
+	default:
+		return valueString(t), true
+	}
+}
+
+// tableIDs returns the id or name column of a table's rows, or else the
+// first column
+func tableIDs(table [][]string) []string {
+	if len(table) == 0 {
+		return nil
+	}
+	col := idColumn(table[0])
+	ids := make([]string, 0, len(table)-1)
+	for _, row := range table[1:] {
+		if col < len(row) {
+			ids = append(ids, row[col])
+		}
+	}
+	return ids
+}
+
+// idColumn returns the index of the id or name header, or 0
+func idColumn(headers []string) int {
+	for _, want := range idKeys {
+		for i, h := range headers {
+			if strings.EqualFold(h, want) {
+				return i
+			}
+		}
+	}
+	return 0
+}
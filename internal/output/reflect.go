@@ -0,0 +1,217 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TagName is the struct tag that names a table column. `table:"-"` leaves
+// the field out; untagged exported fields use the field name.
+const TagName = "table"
+
+// column is a struct field shown in table output
+type column struct {
+	header string
+	index  []int
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// reflectToTable converts a struct, or a slice or array of structs, to
+// table format, flattening struct fields that are structs themselves depth
+// levels deep. ok is false for any other type.
+func reflectToTable(data interface{}, depth int) (table [][]string, ok bool) {
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		// A single record reads better vertically, like a map
+		table = [][]string{{"Field", "Value"}}
+		for _, c := range columns(v.Type(), depth) {
+			table = append(table, []string{c.header, cell(fieldByIndex(v, c.index))})
+		}
+		return table, true
+	case reflect.Slice, reflect.Array:
+		elem := v.Type().Elem()
+		for elem.Kind() == reflect.Pointer {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		cols := columns(elem, depth)
+		headers := make([]string, len(cols))
+		for i, c := range cols {
+			headers[i] = c.header
+		}
+		table = [][]string{headers}
+		for i := 0; i < v.Len(); i++ {
+			table = append(table, structRow(reflect.Indirect(v.Index(i)), cols))
+		}
+		return table, true
+	default:
+		return nil, false
+	}
+}
+
+// structRow formats the columns of struct v; an invalid v (a nil element)
+// is an empty row
+func structRow(v reflect.Value, cols []column) []string {
+	row := make([]string, len(cols))
+	if !v.IsValid() {
+		return row
+	}
+	for i, c := range cols {
+		row[i] = cell(fieldByIndex(v, c.index))
+	}
+	return row
+}
+
+// columns lists the exported fields of t in declaration order, flattening
+// embedded structs, and other struct fields depth levels deep with their
+// columns named like Spec.Replicas
+func columns(t reflect.Type, depth int) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get(TagName)
+		if tag == "-" {
+			continue
+		}
+
+		// Fields reached through an unexported embedded struct can't be
+		// read by reflection, so those are skipped too
+		if !field.IsExported() {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && tag == "" && ft.Kind() == reflect.Struct {
+			for _, c := range columns(ft, depth) {
+				c.index = append([]int{i}, c.index...)
+				cols = append(cols, c)
+			}
+			continue
+		}
+
+		header := tag
+		if header == "" {
+			header = field.Name
+		}
+		if depth > 0 && flattens(ft) {
+			for _, c := range columns(ft, depth-1) {
+				c.header = header + "." + c.header
+				c.index = append([]int{i}, c.index...)
+				cols = append(cols, c)
+			}
+			continue
+		}
+		cols = append(cols, column{header: header, index: []int{i}})
+	}
+	return cols
+}
+
+// flattens reports whether fields of type t are shown as a column per
+// field of their own, rather than in one cell: structs with exported
+// fields that don't format themselves
+func flattens(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(stringerType) || pt.Implements(errorType) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldByIndex is like Value.FieldByIndex but returns an invalid Value
+// instead of panicking on a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 {
+			if v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					return reflect.Value{}
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// cell formats a field value for a table cell
+func cell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	case v.Type() == durationType:
+		return v.Interface().(time.Duration).String()
+	case v.CanInterface() && v.Type().Implements(errorType):
+		return v.Interface().(error).Error()
+	case v.CanInterface() && v.Type().Implements(stringerType):
+		return v.Interface().(fmt.Stringer).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return cell(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("%x", v.Interface())
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = cell(v.Index(i))
+		}
+		return strings.Join(parts, ", ")
+	case reflect.Map, reflect.Struct:
+		// Beyond the flatten depth, like nested values of other data
+		if v.CanInterface() {
+			if raw, err := json.Marshal(v.Interface()); err == nil {
+				return string(raw)
+			}
+		}
+		return fmt.Sprint(v.Interface())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,88 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// FormatFunc writes data to w in a custom output format. data is what the
+// command printed, or after --query or --filter the result in generic form:
+// map[string]interface{}, []interface{}, string, int64, float64, bool, or
+// nil.
+type FormatFunc func(w io.Writer, data interface{}) error
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]FormatFunc{}
+)
+
+// RegisterFormat adds an output format selected with -o name, such as TOML
+// or protobuf text. Call it from an init function. Like sql.Register, it
+// panics when name is empty, built in, or already registered.
+//
+// Print calls fn with the whole output; a StreamPrinter collects its rows
+// and calls fn with them once End is called.
+func RegisterFormat(name string, fn FormatFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	switch {
+	case name == "" || fn == nil:
+		panic("output: RegisterFormat needs a name and a FormatFunc")
+	case formats[name] != nil:
+		panic(fmt.Sprintf("output: format %s registered twice", name))
+	case slices.Contains(config.OutputFormats(), name) || IsTemplateFormat(name):
+		panic(fmt.Sprintf("output: %s is a built-in format", name))
+	}
+	formats[name] = fn
+	config.AddOutputFormat(name)
+}
+
+// Formats returns the names of the built-in formats, then the registered
+// ones in the order they were registered
+func Formats() []string {
+	return config.OutputFormats()
+}
+
+// customFormat returns the FormatFunc registered for name
+func customFormat(name string) (FormatFunc, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	fn, ok := formats[name]
+	return fn, ok
+}
+
+// printCustom prints data with a registered format
+func (f *Formatter) printCustom(fn FormatFunc, data interface{}) error {
+	if r, ok := data.(queryResult); ok {
+		data = plainValue(r.value)
+	}
+	if err := fn(f.writer, data); err != nil {
+		return fmt.Errorf("formatting %s output: %w", f.config.Format, err)
+	}
+	return nil
+}
+
+// plainValue converts a generic value to plain Go maps and slices, for
+// code outside the package
+func plainValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *object:
+		m := make(map[string]interface{}, len(t.keys))
+		for _, k := range t.keys {
+			m[k] = plainValue(t.values[k])
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, item := range t {
+			list[i] = plainValue(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
@@ -0,0 +1,105 @@
+package output
+
+import "reflect"
+
+// Rows produces rows one at a time, e.g. page by page from an API or from
+// a database cursor, calling yield with each until it returns false. It
+// returns the error that stopped it, if any.
+type Rows func(yield func(row interface{}) bool) error
+
+// ChanRows returns Rows yielding what's received from ch until it's
+// closed
+func ChanRows(ch <-chan interface{}) Rows {
+	return func(yield func(row interface{}) bool) error {
+		for row := range ch {
+			if !yield(row) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// PrintRows prints rows as Print prints a slice of them, but writes each
+// as it's produced, so exporting millions of rows takes constant memory.
+// headers names the columns of []string rows; it may be nil when rows are
+// structs (see StreamPrinter). json is written as one array, element by
+// element, and the table format sizes its columns from the first rows.
+// yaml, queries, sort_by, and the envelope need every row, so they're
+// collected and passed to Print. Output isn't paged.
+func (f *Formatter) PrintRows(headers []string, rows Rows) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.streamRows(headers, rows)
+}
+
+// streamRows is PrintRows, with f.mu held
+func (f *Formatter) streamRows(headers []string, rows Rows) error {
+	if f.config.Query != "" || f.config.SortBy != "" || f.config.Format == "yaml" || f.enveloped() {
+		return f.printCollected(headers, rows)
+	}
+
+	s := f.Stream()
+	s.array = true
+	if err := s.begin(headers); err != nil {
+		return err
+	}
+	var writeErr error
+	err := rows(func(row interface{}) bool {
+		writeErr = s.writeRow(row)
+		// Rows past the page printed aren't produced
+		return writeErr == nil && !s.pageFull
+	})
+	if writeErr != nil {
+		_ = f.Flush()
+		return writeErr
+	}
+
+	// Rows produced before a failure are still written out in full
+	if endErr := s.end(); endErr != nil {
+		return endErr
+	}
+	return err
+}
+
+// printCollected gathers rows into a slice, or a table for []string rows,
+// and prints it with Print
+func (f *Formatter) printCollected(headers []string, rows Rows) error {
+	var items []interface{}
+	table := [][]string{headers}
+	err := rows(func(row interface{}) bool {
+		if cells, ok := row.([]string); ok {
+			table = append(table, cells)
+		} else {
+			items = append(items, row)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if len(table) > 1 {
+		return f.printData(table)
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	return f.printData(typedSlice(items))
+}
+
+// typedSlice returns items as a slice of their type when they all have the
+// same one, so struct rows keep their table tags and field formats
+func typedSlice(items []interface{}) interface{} {
+	if len(items) == 0 || items[0] == nil {
+		return items
+	}
+	t := reflect.TypeOf(items[0])
+	s := reflect.MakeSlice(reflect.SliceOf(t), 0, len(items))
+	for _, item := range items {
+		if reflect.TypeOf(item) != t {
+			return items
+		}
+		s = reflect.Append(s, reflect.ValueOf(item))
+	}
+	return s.Interface()
+}
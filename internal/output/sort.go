@@ -0,0 +1,150 @@
+package output
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// SortKey is one column of a --sort-by spec
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSortBy parses a --sort-by spec: comma-separated column names, each
+// optionally followed by :asc or :desc, e.g. "size:desc,name". Later
+// columns break ties in earlier ones.
+func ParseSortBy(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		column, dir, _ := strings.Cut(strings.TrimSpace(part), ":")
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return nil, model.NewValidationError("sort-by", fmt.Sprintf("empty column name in %q", spec))
+		}
+		key := SortKey{Column: column}
+		switch strings.ToLower(strings.TrimSpace(dir)) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, model.NewValidationError("sort-by", fmt.Sprintf("invalid direction %q for %s (use asc or desc)", dir, column))
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortTable returns table with its rows, after the header, sorted by
+// keys. Columns match header names case-insensitively. The sort is stable,
+// so rows that compare equal keep their order.
+func sortTable(table [][]string, keys []SortKey) ([][]string, error) {
+	if len(table) < 2 || len(keys) == 0 {
+		return table, nil
+	}
+
+	cols := make([]int, len(keys))
+	for i, key := range keys {
+		cols[i] = slices.IndexFunc(table[0], func(h string) bool {
+			return strings.EqualFold(h, key.Column)
+		})
+		if cols[i] < 0 {
+			return nil, model.NewValidationError("sort-by",
+				fmt.Sprintf("unknown column %q (columns: %s)", key.Column, strings.Join(table[0], ", ")))
+		}
+	}
+
+	rows := slices.Clone(table[1:])
+	slices.SortStableFunc(rows, func(a, b []string) int {
+		for i, key := range keys {
+			c := compareCells(rowCell(a, cols[i]), rowCell(b, cols[i]))
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return append([][]string{table[0]}, rows...), nil
+}
+
+func rowCell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// compareCells orders numbers by value and before other text, which is
+// compared case-insensitively with runs of digits compared by value, so
+// "file2" comes before "file10"
+func compareCells(a, b string) int {
+	x, aNum := parseNumber(a)
+	y, bNum := parseNumber(b)
+	switch {
+	case aNum && bNum:
+		return cmp.Compare(x, y)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	if c := naturalCompare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// parseNumber parses a cell holding only a finite number
+func parseNumber(s string) (float64, bool) {
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+// naturalCompare compares a and b rune by rune, except that runs of digits
+// are compared by their value
+func naturalCompare(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if c := cmp.Compare(len(na), len(nb)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		if c := cmp.Compare(ra[i], rb[j]); c != 0 {
+			return c
+		}
+		i++
+		j++
+	}
+	return cmp.Compare(len(ra)-i, len(rb)-j)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,671 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/output/aggregate"
+)
+
+// streamSampleRows is how many rows a streamed table buffers to size its
+// columns; later rows that are wider than the sample overflow their cells
+const streamSampleRows = 100
+
+var errStreamNotStarted = errors.New("stream: Begin was not called")
+
+// StreamPrinter writes rows as they are produced, for datasets too large
+// to hold in memory. json and ndjson write one object per line (JSON
+// Lines), yaml one document per row, csv, tsv, and table one line per row,
+// html one <tr> and xml one <item> per row, go-template one execution per
+// row, and text a row's String() when it has one. Registered formats (see
+// RegisterFormat) get all the rows at End. Quiet writes only the
+// identifier of each row (see IDer). Use Print for small payloads, and
+// Pipe to keep producing rows while output is slow.
+//
+//	s := f.Stream()
+//	if err := s.Begin(nil); err != nil { ... }
+//	for rec := range records {
+//		if err := s.WriteRow(rec); err != nil { ... }
+//	}
+//	return s.End()
+type StreamPrinter struct {
+	f       *Formatter
+	started bool
+	headers []string
+	cols    []column // Set when rows are structs
+	rows    int
+	array   bool // Set by PrintRows: json is one array, as Print writes it, rather than JSON Lines
+
+	csv    *csv.Writer
+	yaml   *yaml.Encoder
+	xml    *xml.Encoder
+	tmpl   *template.Template
+	filter *Filter
+	added  []*Column // Columns computed for each row (output.add_columns)
+	base   []string  // The headers without added, once rows have them
+	group  *aggregate.Spec
+	held   [][]string // Rows held for the summary End writes when grouped
+	sample [][]string // Table rows buffered until the widths are known
+	widths []int
+	styles []StyleFunc
+	hidden int          // Table rows past MaxRows
+	json   *jsonEncoder // Set once output.fast_json_rows rows are written
+
+	// Only rows [pageFrom, pageTo) of those matching the filter are
+	// printed when paged (see StartChunk)
+	paged            bool
+	pageFrom, pageTo int
+	matched          int  // Rows that matched the filter, printed or not
+	pageFull         bool // Rows past pageTo were left out
+
+	formats []config.ColumnFormat // Column formats of table and html rows
+
+	custom     FormatFunc    // Set for a registered format
+	customRows []interface{} // Rows collected for custom
+}
+
+// Stream returns a printer that writes rows incrementally in the
+// formatter's format
+func (f *Formatter) Stream() *StreamPrinter {
+	return &StreamPrinter{f: f}
+}
+
+// Begin starts the output. headers names the columns of []string rows; it
+// may be nil when rows are structs, whose columns follow the rules of
+// table output (see TagName).
+func (s *StreamPrinter) Begin(headers []string) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	return s.begin(headers)
+}
+
+func (s *StreamPrinter) begin(headers []string) error {
+	s.headers = headers
+	added, err := s.f.derivedColumns()
+	if err != nil {
+		return err
+	}
+	s.added = added
+	if len(added) > 0 && headers != nil {
+		s.base, s.headers = headers, deriveHeaders(headers, added)
+	}
+	if s.f.config.Filter != "" {
+		filter, err := CompileFilter(s.f.config.Filter)
+		if err != nil {
+			return err
+		}
+		s.filter = filter
+	}
+	if IsTemplateFormat(s.f.config.Format) {
+		t, err := ParseTemplate(s.f.config.Format)
+		if err != nil {
+			return err
+		}
+		s.tmpl = t
+	}
+	if s.group, err = s.f.grouping(); err != nil {
+		return err
+	}
+	s.custom, _ = customFormat(s.f.config.Format)
+	s.pageFrom, s.pageTo, s.paged = s.f.chunkRange()
+	s.started = true
+	if s.f.config.Quiet {
+		return nil
+	}
+
+	switch s.f.config.Format {
+	case "csv", "tsv":
+		s.csv = s.f.csvWriter()
+	case "yaml":
+		s.yaml = yaml.NewEncoder(s.f.writer)
+		if s.f.config.Pretty {
+			s.yaml.SetIndent(2)
+		}
+	case "xml":
+		if _, err := fmt.Fprint(s.f.writer, xml.Header); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		s.xml = xml.NewEncoder(s.f.writer)
+		if s.f.config.Pretty {
+			s.xml.Indent("", "  ")
+		}
+		if err := s.xml.EncodeToken(xml.StartElement{Name: xml.Name{Local: xmlRoot}}); err != nil {
+			return fmt.Errorf("encoding XML: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteRow writes one row: a []string matching the headers, or a struct
+// or pointer to a struct. Rows that don't match the configured filter are
+// skipped.
+func (s *StreamPrinter) WriteRow(row interface{}) error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	return s.writeRow(row)
+}
+
+func (s *StreamPrinter) writeRow(row interface{}) error {
+	if !s.started {
+		return errStreamNotStarted
+	}
+	var derived []string
+	if len(s.added) > 0 {
+		var err error
+		if derived, err = s.derive(row); err != nil {
+			return err
+		}
+		// Quiet output keeps the row, to name it as it would without
+		if !s.f.config.Quiet {
+			row = derived
+		}
+	}
+	if s.filter != nil {
+		cells, err := derived, error(nil)
+		if cells == nil {
+			cells, err = s.cells(row)
+		}
+		if err != nil {
+			return err
+		}
+		if ok, err := s.filter.matchRow(s.headers, cells); err != nil || !ok {
+			return err
+		}
+	}
+	if s.group != nil {
+		cells, err := s.cells(row)
+		if err != nil {
+			return err
+		}
+		s.held = append(s.held, cells)
+		return nil
+	}
+	return s.emit(row)
+}
+
+// emit writes a row that's been filtered, if it's on the page printed
+func (s *StreamPrinter) emit(row interface{}) error {
+	if s.paged {
+		s.matched++
+		if s.matched <= s.pageFrom {
+			return nil
+		}
+		if s.matched > s.pageTo {
+			s.pageFull = true
+			return nil
+		}
+	}
+	s.rows++
+
+	if s.f.config.Quiet {
+		return s.writeID(row)
+	}
+	if s.custom != nil {
+		if cells, ok := row.([]string); ok {
+			row = s.object(cells)
+		}
+		s.customRows = append(s.customRows, row)
+		return nil
+	}
+	if s.tmpl != nil {
+		if cells, ok := row.([]string); ok {
+			row = s.object(cells)
+		}
+		return s.f.executeTemplate(s.tmpl, row)
+	}
+
+	switch s.f.config.Format {
+	case "json", "ndjson":
+		return s.writeJSON(row)
+	case "yaml":
+		if cells, ok := row.([]string); ok {
+			row = s.object(cells)
+		}
+		if err := s.yaml.Encode(row); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+		return nil
+	case "xml":
+		return s.writeXML(row)
+	case "text":
+		if str, ok := row.(fmt.Stringer); ok {
+			_, err := fmt.Fprintln(s.f.writer, str.String())
+			return err
+		}
+	}
+
+	cells, err := s.cells(row)
+	if err != nil {
+		return err
+	}
+	if s.rows == 1 && (s.f.config.Format == "table" || s.f.config.Format == "html") {
+		s.formats = s.f.columnFormats(s.headers)
+		s.f.aligns = columnAligns(s.formats)
+	}
+	cells = formatRow(cells, s.formats)
+
+	switch s.f.config.Format {
+	case "csv", "tsv":
+		if s.rows == 1 && len(s.headers) > 0 {
+			if err := s.csv.Write(s.headers); err != nil {
+				return fmt.Errorf("writing CSV: %w", err)
+			}
+		}
+		if err := s.csv.Write(cells); err != nil {
+			return fmt.Errorf("writing CSV: %w", err)
+		}
+		// Hand each row on to the formatter's Buffer, which decides when
+		// it's written out
+		s.csv.Flush()
+		return s.csv.Error()
+	case "table":
+		return s.writeTableRow(cells)
+	case "html":
+		if s.rows == 1 {
+			s.f.openHTMLTable(s.headers)
+			s.styles = s.f.htmlStyles(s.headers)
+		}
+		s.f.printHTMLRow(cells, s.styles)
+		return nil
+	default:
+		_, err := fmt.Fprintln(s.f.writer, strings.Join(cells, "\t"))
+		return err
+	}
+}
+
+// End finishes the output, flushing any buffered rows
+func (s *StreamPrinter) End() error {
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	return s.end()
+}
+
+func (s *StreamPrinter) end() error {
+	var err error
+	if s.group != nil {
+		err = s.writeGroups()
+	}
+	if s.paged {
+		chunked(s.rows, s.pageFull)
+	}
+	if ferr := s.finish(); err == nil {
+		err = ferr
+	}
+	if s.json != nil {
+		s.json.release()
+		s.json = nil
+	}
+	if ferr := s.f.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+func (s *StreamPrinter) finish() error {
+	if !s.started {
+		return errStreamNotStarted
+	}
+	if s.f.config.Quiet {
+		return nil
+	}
+	if s.custom != nil {
+		return s.f.printCustom(s.custom, s.customRows)
+	}
+	switch s.f.config.Format {
+	case "json":
+		if s.array {
+			return s.closeArray()
+		}
+	case "csv", "tsv":
+		if s.rows == 0 && len(s.headers) > 0 {
+			if err := s.csv.Write(s.headers); err != nil {
+				return fmt.Errorf("writing CSV: %w", err)
+			}
+		}
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return fmt.Errorf("CSV writer error: %w", err)
+		}
+	case "yaml":
+		if err := s.yaml.Close(); err != nil {
+			return fmt.Errorf("encoding YAML: %w", err)
+		}
+	case "xml":
+		if err := s.xml.EncodeToken(xml.EndElement{Name: xml.Name{Local: xmlRoot}}); err != nil {
+			return fmt.Errorf("encoding XML: %w", err)
+		}
+		if err := s.xml.Close(); err != nil {
+			return fmt.Errorf("encoding XML: %w", err)
+		}
+		_, err := fmt.Fprintln(s.f.writer)
+		return err
+	case "html":
+		if s.rows == 0 {
+			if len(s.headers) == 0 {
+				return nil
+			}
+			s.f.openHTMLTable(s.headers)
+		}
+		return s.f.closeHTMLTable()
+	case "table":
+		// Without headers or rows there are no columns to print
+		if s.widths == nil && (len(s.headers) > 0 || len(s.sample) > 0) {
+			s.flushSample()
+		}
+		if s.f.config.TableStyle == "unicode" && s.widths != nil {
+			s.f.printUnicodeBorder(s.widths, "└", "┴", "┘")
+		}
+		if s.hidden > 0 {
+			s.f.printHiddenRows(s.hidden)
+		}
+	}
+	return nil
+}
+
+// writeGroups writes the summary of the rows held for it (see
+// output.group_by), in place of the rows
+func (s *StreamPrinter) writeGroups() error {
+	if len(s.headers) == 0 {
+		return nil // No rows gave the headers
+	}
+	table, err := s.group.Table(append([][]string{s.headers}, s.held...))
+	if err != nil {
+		return err
+	}
+	s.headers, s.held = table[0], nil
+	for _, row := range table[1:] {
+		if err := s.emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cells converts a row to strings, taking the headers from the first
+// struct row when Begin had none
+func (s *StreamPrinter) cells(row interface{}) ([]string, error) {
+	if cells, ok := row.([]string); ok {
+		return cells, nil
+	}
+
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported row type %T for streamed output", row)
+	}
+	if s.cols == nil {
+		s.cols = columns(v.Type(), s.f.config.FlattenDepth)
+		if s.headers == nil {
+			for _, c := range s.cols {
+				s.headers = append(s.headers, c.header)
+			}
+		}
+	}
+	return structRow(v, s.cols), nil
+}
+
+// derive returns the cells of a row with the added columns, extending the
+// headers with them on the first row
+func (s *StreamPrinter) derive(row interface{}) ([]string, error) {
+	cells, err := s.cells(row)
+	if err != nil {
+		return nil, err
+	}
+	if s.base == nil {
+		s.base = s.headers
+		s.headers = deriveHeaders(s.headers, s.added)
+	}
+	return deriveRow(s.base, cells, s.added)
+}
+
+// writeID writes the identifier of a row for quiet output
+func (s *StreamPrinter) writeID(row interface{}) error {
+	id := ""
+	if cells, ok := row.([]string); ok {
+		if col := idColumn(s.headers); col < len(cells) {
+			id = cells[col]
+		}
+	} else {
+		var err error
+		if id, err = identifier(row); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(s.f.writer, id); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// object pairs []string cells with the headers
+func (s *StreamPrinter) object(cells []string) map[string]string {
+	obj := make(map[string]string, len(cells))
+	for i, c := range cells {
+		if i < len(s.headers) {
+			obj[s.headers[i]] = c
+		}
+	}
+	return obj
+}
+
+// writeJSON writes a row as one line; []string rows become objects with
+// their keys in header order
+func (s *StreamPrinter) writeJSON(row interface{}) error {
+	array := s.array && s.f.config.Format == "json"
+	if s.json == nil && s.f.useFastJSON(s.rows) {
+		s.json = newJSONEncoder(s.headers, array && s.f.config.Pretty)
+	}
+	if s.json != nil {
+		return s.writeFastJSON(row, array)
+	}
+
+	var line []byte
+	if cells, ok := row.([]string); ok {
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, c := range cells {
+			if i >= len(s.headers) {
+				break
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(s.headers[i])
+			value, _ := json.Marshal(c)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		line = buf.Bytes()
+	} else {
+		var err error
+		if line, err = json.Marshal(row); err != nil {
+			return fmt.Errorf("marshaling JSON: %w", err)
+		}
+	}
+
+	if array {
+		return s.writeElement(line)
+	}
+	if _, err := fmt.Fprintf(s.f.writer, "%s\n", line); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// writeFastJSON writes a row as writeJSON does, but through s.json, so
+// writing it allocates next to nothing
+func (s *StreamPrinter) writeFastJSON(row interface{}, array bool) error {
+	buf := s.json.buf
+	buf.Reset()
+	if array {
+		buf.WriteString(s.elementStart())
+	}
+	if cells, ok := row.([]string); ok {
+		s.json.encodeCells(cells)
+	} else if err := s.json.encode(row); err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	if !array {
+		buf.WriteByte('\n')
+	}
+	if _, err := s.f.writer.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// elementStart returns what comes before the next element of the array
+// writeElement writes: a bracket or comma, then a new line when Pretty is
+// set
+func (s *StreamPrinter) elementStart() string {
+	switch first := s.rows == 1; {
+	case first && s.f.config.Pretty:
+		return "[\n  "
+	case first:
+		return "["
+	case s.f.config.Pretty:
+		return ",\n  "
+	default:
+		return ","
+	}
+}
+
+// writeElement writes a row's JSON as the next element of an array laid
+// out as printJSON would, indented when Pretty is set
+func (s *StreamPrinter) writeElement(line []byte) error {
+	var buf bytes.Buffer
+	buf.WriteString(s.elementStart())
+	if s.f.config.Pretty {
+		if err := json.Indent(&buf, line, "  ", "  "); err != nil {
+			return fmt.Errorf("marshaling JSON: %w", err)
+		}
+	} else {
+		buf.Write(line)
+	}
+	if _, err := s.f.writer.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// closeArray ends the array writeElement began
+func (s *StreamPrinter) closeArray() error {
+	end := "]\n"
+	switch {
+	case s.rows == 0:
+		end = "[]\n"
+	case s.f.config.Pretty:
+		end = "\n]\n"
+	}
+	if _, err := fmt.Fprint(s.f.writer, end); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// writeXML writes a row as an <item> element; []string rows have an
+// element per header
+func (s *StreamPrinter) writeXML(row interface{}) error {
+	var v interface{}
+	if cells, ok := row.([]string); ok {
+		obj := newObject()
+		for i, c := range cells {
+			if i < len(s.headers) {
+				obj.set(s.headers[i], c)
+			}
+		}
+		v = obj
+	} else {
+		var err error
+		if v, err = toValue(row); err != nil {
+			return err
+		}
+	}
+
+	if err := encodeXML(s.xml, xmlItem, v); err != nil {
+		return fmt.Errorf("encoding XML: %w", err)
+	}
+	// Hand each row on to the formatter's Buffer, which decides when it's
+	// written out
+	if err := s.xml.Flush(); err != nil {
+		return fmt.Errorf("encoding XML: %w", err)
+	}
+	return nil
+}
+
+func (s *StreamPrinter) writeTableRow(cells []string) error {
+	if limit := s.f.config.MaxRows; limit > 0 && s.rows > limit {
+		s.hidden++
+		return nil
+	}
+	if s.widths == nil {
+		s.sample = append(s.sample, cells)
+		if len(s.sample) >= streamSampleRows {
+			s.flushSample()
+		}
+		return nil
+	}
+	s.printTableRow(cells, false)
+	return nil
+}
+
+// flushSample sizes the columns from the buffered rows and prints the
+// header and those rows
+func (s *StreamPrinter) flushSample() {
+	table := append([][]string{s.headers}, s.sample...)
+	s.widths = s.f.calculateColumnWidths(table)
+	s.styles = s.f.cellStyles(s.headers)
+
+	switch s.f.config.TableStyle {
+	case "unicode":
+		s.f.printUnicodeBorder(s.widths, "┌", "┬", "┐")
+		s.printTableRow(s.headers, true)
+		s.f.printUnicodeBorder(s.widths, "├", "┼", "┤")
+	case "markdown":
+		s.printTableRow(s.headers, true)
+		s.f.printMarkdownSeparator(s.widths)
+	default:
+		s.printTableRow(s.headers, true)
+		s.f.printASCIISeparator(s.widths)
+	}
+	for _, row := range s.sample {
+		s.printTableRow(row, false)
+	}
+	s.sample = nil
+}
+
+func (s *StreamPrinter) printTableRow(row []string, header bool) {
+	// Rows wider than the sample keep their extra cells rather than
+	// indexing past the widths
+	widths := s.widths
+	for len(widths) < len(row) {
+		widths = append(widths, 0)
+	}
+	styles := s.styles
+	if header {
+		styles = nil
+	}
+
+	switch s.f.config.TableStyle {
+	case "unicode":
+		s.f.printUnicodeRow(s.f.writer, row, widths, styles, header)
+	case "markdown":
+		s.f.printMarkdownRow(s.f.writer, row, widths)
+	default:
+		s.f.printASCIIRow(s.f.writer, row, widths, styles, header)
+	}
+}
@@ -0,0 +1,235 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// TemplateFormatPrefix starts the go-template output format, whose
+// template follows the "=": --output go-template='{{.Name}}\t{{.Status}}'
+const TemplateFormatPrefix = "go-template="
+
+// IsTemplateFormat reports whether format is a go-template format
+func IsTemplateFormat(format string) bool {
+	return strings.HasPrefix(format, TemplateFormatPrefix)
+}
+
+// ParseTemplate parses the template of a go-template format. Literal \t and
+// \n outside actions become a tab and a newline, since shells don't expand
+// them inside single quotes.
+func ParseTemplate(format string) (*template.Template, error) {
+	text := unescapeText(strings.TrimPrefix(format, TemplateFormatPrefix))
+	t, err := template.New("output").Funcs(TemplateFuncs()).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, model.NewValidationError("output", fmt.Sprintf("parsing go-template: %v", err))
+	}
+	return t, nil
+}
+
+// unescapeText expands \t and \n in the text between actions, leaving
+// string literals inside {{ }} to the template parser
+func unescapeText(s string) string {
+	r := strings.NewReplacer(`\t`, "\t", `\n`, "\n")
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "{{")
+		if start < 0 {
+			b.WriteString(r.Replace(s))
+			return b.String()
+		}
+		b.WriteString(r.Replace(s[:start]))
+		end := strings.Index(s[start:], "}}")
+		if end < 0 {
+			b.WriteString(s[start:])
+			return b.String()
+		}
+		b.WriteString(s[start : start+end+2])
+		s = s[start+end+2:]
+	}
+}
+
+// TemplateFuncs returns the functions available to go-template output,
+// named and ordered like their sprig counterparts
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+		"trunc":      trunc,
+		"indent":     func(n int, s string) string { return indent(strings.Repeat(" ", n), s) },
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+		"default":    defaultValue,
+		"empty":      empty,
+		"toJson":     toJSON,
+		"toYaml":     toYAML,
+		"now":        time.Now,
+		"date":       func(layout string, t time.Time) string { return t.Format(layout) },
+		"ago":        func(t time.Time) string { return time.Since(t).Round(time.Second).String() },
+	}
+}
+
+// printTemplate executes the go-template format against data: once per
+// item for lists and table rows, otherwise once. Each execution ends with a
+// newline unless the template ends with one.
+func (f *Formatter) printTemplate(data interface{}) error {
+	t, err := ParseTemplate(f.config.Format)
+	if err != nil {
+		return err
+	}
+
+	var items []interface{}
+	switch d := data.(type) {
+	case queryResult:
+		items = templateItems(templateValue(d.value))
+	case [][]string:
+		for _, row := range tableToValue(d) {
+			items = append(items, templateValue(row))
+		}
+	default:
+		items = templateItems(data)
+	}
+
+	for _, item := range items {
+		if err := f.executeTemplate(t, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Formatter) executeTemplate(t *template.Template, data interface{}) error {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return fmt.Errorf("executing go-template: %w", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	if _, err := f.writer.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// templateItems splits a slice into its elements; anything else is a
+// single item
+func templateItems(data interface{}) []interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{data}
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items
+}
+
+// templateValue converts query values to maps, which templates can index
+// with .field
+func templateValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *object:
+		m := make(map[string]interface{}, len(t.keys))
+		for _, k := range t.keys {
+			m[k] = templateValue(t.values[k])
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, item := range t {
+			list[i] = templateValue(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+func title(s string) string {
+	r := []rune(s)
+	if len(r) == 0 {
+		return s
+	}
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
+
+func trunc(n int, s string) string {
+	r := []rune(s)
+	if n < 0 || n >= len(r) {
+		return s
+	}
+	return string(r[:n])
+}
+
+func indent(prefix, s string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// join joins the items of any slice with sep
+func join(sep string, list interface{}) string {
+	items := templateItems(list)
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep)
+}
+
+// defaultValue returns v, or def when v is empty
+func defaultValue(def, v interface{}) interface{} {
+	if empty(v) {
+		return def
+	}
+	return v
+}
+
+// empty reports whether v is nil or its type's zero value; empty slices
+// and maps are empty too
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func toJSON(v interface{}) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func toYAML(v interface{}) (string, error) {
+	raw, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(raw), "\n"), nil
+}
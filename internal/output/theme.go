@@ -0,0 +1,115 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Theme restyles colored output for accessibility: each of the base color
+// styles (StyleRed for failures, StyleGreen for successes, and so on) is
+// painted as its replacement, and html output uses the theme's CSS color
+type Theme struct {
+	Name   string
+	Styles map[Style]Style  // Replacement terminal styles; StyleNone drops the color
+	CSS    map[Style]string // Colors of html cells
+}
+
+// Themes lists the output.theme values
+var Themes = []*Theme{
+	{Name: "default", CSS: htmlColors},
+	{
+		// Okabe-Ito colors, distinguishable with red-green color blindness:
+		// failures vermillion, successes blue
+		Name: "deuteranopia",
+		Styles: map[Style]Style{
+			StyleRed:    "38;5;166",
+			StyleGreen:  "38;5;32",
+			StyleYellow: "38;5;178",
+			StyleBlue:   "38;5;74",
+			StyleCyan:   "38;5;36",
+		},
+		CSS: map[Style]string{
+			StyleRed:    "#d55e00",
+			StyleGreen:  "#0072b2",
+			StyleYellow: "#e69f00",
+			StyleBlue:   "#56b4e9",
+			StyleCyan:   "#009e73",
+			StyleDim:    "#6e7781",
+		},
+	},
+	{
+		// Bold, bright colors and no dimmed text
+		Name: "high-contrast",
+		Styles: map[Style]Style{
+			StyleRed:    "1;91",
+			StyleGreen:  "1;92",
+			StyleYellow: "1;93",
+			StyleBlue:   "1;94",
+			StyleCyan:   "1;96",
+			StyleDim:    StyleNone,
+		},
+		CSS: map[Style]string{
+			StyleRed:    "#a40e26",
+			StyleGreen:  "#055d20",
+			StyleYellow: "#6c4400",
+			StyleBlue:   "#0349b4",
+			StyleCyan:   "#00585e",
+			StyleDim:    "#24292f",
+		},
+	},
+}
+
+var theme atomic.Pointer[Theme]
+
+func init() {
+	theme.Store(Themes[0])
+}
+
+// SetTheme selects the theme named by output.theme for all output; an
+// empty name is the default theme
+func SetTheme(name string) error {
+	if name == "" {
+		name = Themes[0].Name
+	}
+	i := slices.IndexFunc(Themes, func(t *Theme) bool { return t.Name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown output theme %q (valid: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	theme.Store(Themes[i])
+	return nil
+}
+
+// CurrentTheme returns the theme set by SetTheme
+func CurrentTheme() *Theme {
+	return theme.Load()
+}
+
+// ThemeNames returns the names of Themes
+func ThemeNames() []string {
+	names := make([]string, len(Themes))
+	for i, t := range Themes {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// themed returns s with its base color styles replaced per the theme
+func (s Style) themed() Style {
+	t := CurrentTheme()
+	// Extended colors (38;5;n) are left alone, as their parts aren't styles
+	if len(t.Styles) == 0 || strings.Contains(string(s), "8;") {
+		return s
+	}
+	var parts []string
+	for _, part := range strings.Split(string(s), ";") {
+		if repl, ok := t.Styles[Style(part)]; ok {
+			part = string(repl)
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return Style(strings.Join(parts, ";"))
+}
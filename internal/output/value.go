@@ -0,0 +1,274 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// object is a JSON object that keeps its keys in order, so query results
+// print their fields in the order the data declared them
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+func (o *object) get(key string) (interface{}, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// MarshalJSON writes the fields in order
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML writes the fields in order
+func (o *object) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range o.keys {
+		var value yaml.Node
+		if err := value.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: k}, &value)
+	}
+	return node, nil
+}
+
+// toValue converts data to the generic form queries work on: *object,
+// []interface{}, string, int64, float64, bool, or nil. Values go through
+// their JSON encoding, so json tags decide the field names. A [][]string
+// table becomes a list of objects keyed by its header row.
+func toValue(data interface{}) (interface{}, error) {
+	if table, ok := data.([][]string); ok {
+		return tableToValue(table), nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding data for query: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func tableToValue(table [][]string) []interface{} {
+	list := []interface{}{}
+	if len(table) == 0 {
+		return list
+	}
+	for _, row := range table[1:] {
+		obj := newObject()
+		for i, h := range table[0] {
+			if i < len(row) {
+				obj.set(h, row[i])
+			}
+		}
+		list = append(list, obj)
+	}
+	return list
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("decoding data for query: %w", err)
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := newObject()
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, fmt.Errorf("decoding data for query: %w", err)
+				}
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.set(keyTok.(string), v)
+			}
+			_, err = dec.Token() // Closing brace
+			return obj, err
+		case '[':
+			list := []interface{}{}
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			_, err = dec.Token() // Closing bracket
+			return list, err
+		}
+		return nil, fmt.Errorf("decoding data for query: unexpected %v", t)
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("decoding data for query: %w", err)
+		}
+		return f, nil
+	default:
+		return t, nil // string, bool, or nil
+	}
+}
+
+// valueString formats a generic value for a table cell or a line of text:
+// scalars as they are, objects and lists as compact JSON
+func valueString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		raw, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(raw)
+	}
+}
+
+// valueToTable converts a generic value to table format: a list of objects
+// has a column per key, a single object Key/Value rows, and anything else a
+// single "value" column. Objects within objects are flattened depth levels
+// deep; see flatten.
+func valueToTable(v interface{}, depth int) [][]string {
+	switch t := v.(type) {
+	case *object:
+		t = flatten(t, depth)
+		table := [][]string{{"Key", "Value"}}
+		for _, k := range t.keys {
+			table = append(table, []string{k, valueString(t.values[k])})
+		}
+		return table
+	case []interface{}:
+		var headers []string
+		seen := map[string]bool{}
+		allObjects := true
+		objects := make([]*object, len(t))
+		for i, item := range t {
+			obj, ok := item.(*object)
+			if !ok {
+				allObjects = false
+				break
+			}
+			objects[i] = flatten(obj, depth)
+			for _, k := range objects[i].keys {
+				if !seen[k] {
+					seen[k] = true
+					headers = append(headers, k)
+				}
+			}
+		}
+
+		if !allObjects || len(t) == 0 {
+			table := [][]string{{"value"}}
+			for _, item := range t {
+				table = append(table, []string{valueString(item)})
+			}
+			return table
+		}
+
+		table := [][]string{headers}
+		for _, obj := range objects {
+			row := make([]string, len(headers))
+			for i, h := range headers {
+				row[i] = valueString(obj.values[h])
+			}
+			table = append(table, row)
+		}
+		return table
+	default:
+		return [][]string{{"value"}, {valueString(v)}}
+	}
+}
+
+// flatten replaces the fields of obj that are non-empty objects with their
+// own fields, named like spec.replicas, down to depth levels of nesting.
+// Deeper objects, and lists, are left for valueString.
+func flatten(obj *object, depth int) *object {
+	if depth <= 0 {
+		return obj
+	}
+	flat := newObject()
+	for _, k := range obj.keys {
+		nested, ok := obj.values[k].(*object)
+		if !ok || len(nested.keys) == 0 {
+			flat.set(k, obj.values[k])
+			continue
+		}
+		inner := flatten(nested, depth-1)
+		for _, nk := range inner.keys {
+			flat.set(k+"."+nk, inner.values[nk])
+		}
+	}
+	return flat
+}
+
+// valueLines formats a generic value as text, one list item per line
+func valueLines(v interface{}) string {
+	list, ok := v.([]interface{})
+	if !ok {
+		return valueString(v)
+	}
+	lines := make([]string, len(list))
+	for i, item := range list {
+		lines[i] = valueString(item)
+	}
+	return strings.Join(lines, "\n")
+}
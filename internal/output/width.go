@@ -0,0 +1,70 @@
+package output
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ansiRe matches ANSI escape sequences: CSI (colors, cursor movement) and
+// OSC (titles, hyperlinks)
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// displayWidth returns the number of terminal columns s occupies: wide
+// CJK characters and emoji take two, combining marks none, and escape
+// sequences are ignored
+func displayWidth(s string) int {
+	if strings.IndexByte(s, '\x1b') >= 0 {
+		s = ansiRe.ReplaceAllString(s, "")
+	}
+	return runewidth.StringWidth(s)
+}
+
+// padRight pads s with spaces to width display columns
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-displayWidth(s)))
+}
+
+// truncateCell shortens s to width display columns, ending it with an
+// ellipsis; a width of 0 leaves it whole
+func truncateCell(s string, width int) string {
+	if width <= 0 || displayWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// wrapCell splits s into lines of at most width display columns, breaking
+// at spaces where it can and inside words where it must
+func wrapCell(s string, width int) []string {
+	if width <= 0 || displayWidth(s) <= width {
+		return []string{s}
+	}
+
+	var lines []string
+	line, lineWidth := "", 0
+	for _, word := range strings.Fields(s) {
+		w := runewidth.StringWidth(word)
+		if lineWidth > 0 && lineWidth+1+w <= width {
+			line, lineWidth = line+" "+word, lineWidth+1+w
+			continue
+		}
+		if lineWidth > 0 {
+			lines = append(lines, line)
+			line, lineWidth = "", 0
+		}
+		// Split words longer than a line
+		for w > width {
+			head := runewidth.Truncate(word, width, "")
+			lines = append(lines, head)
+			word = word[len(head):]
+			w = runewidth.StringWidth(word)
+		}
+		line, lineWidth = word, w
+	}
+	if lineWidth > 0 || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
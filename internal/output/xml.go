@@ -0,0 +1,89 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// xmlRoot and xmlItem name the document element and the elements of lists
+const (
+	xmlRoot = "result"
+	xmlItem = "item"
+)
+
+// printXML outputs data as an XML document. Objects become elements named
+// after their keys (the JSON names), lists repeat an <item> element, and
+// scalars are text.
+func (f *Formatter) printXML(data interface{}) error {
+	v, err := resultValue(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(f.writer, xml.Header); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	enc := xml.NewEncoder(f.writer)
+	if f.config.Pretty {
+		enc.Indent("", "  ")
+	}
+	if err := encodeXML(enc, xmlRoot, v); err != nil {
+		return fmt.Errorf("encoding XML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encoding XML: %w", err)
+	}
+	_, err = fmt.Fprintln(f.writer)
+	return err
+}
+
+// encodeXML writes v, a generic value (see toValue), as an element
+func encodeXML(enc *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case *object:
+		for _, k := range t.keys {
+			if err := encodeXML(enc, k, t.values[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range t {
+			if err := encodeXML(enc, xmlItem, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+		// An empty element
+	default:
+		if err := enc.EncodeToken(xml.CharData(valueString(t))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName turns a key into a valid element name: characters that can't
+// appear in names become underscores, and names that can't start with
+// their first character get a leading underscore
+func xmlName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, key)
+	first, _ := utf8.DecodeRuneInString(name)
+	if name == "" || !(unicode.IsLetter(first) || first == '_') || strings.HasPrefix(strings.ToLower(name), "xml") {
+		name = "_" + name
+	}
+	return name
+}
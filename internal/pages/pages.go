@@ -0,0 +1,171 @@
+// Package pages keeps the continuation tokens of list output printed a
+// page at a time (output.page_size), in a file in the state directory. A
+// token names the command line that printed a page and where the next
+// one starts, so running the command again with --next prints it.
+package pages
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+const (
+	// lockName serializes changes to the tokens file
+	lockName = "pages"
+	// lockWait is how long to wait for another invocation's change
+	lockWait = 5 * time.Second
+	// tokenTTL is how long a token can be used after it's issued
+	tokenTTL = 24 * time.Hour
+	// maxTokens is how many tokens are kept; the oldest go first
+	maxTokens = 100
+)
+
+// ErrUnknownToken is returned by Load for a token that was never issued
+// or has expired
+var ErrUnknownToken = errors.New("unknown or expired page token")
+
+// Token is where the next page of a command's output starts
+type Token struct {
+	ID      string    `json:"id"`
+	Args    []string  `json:"args"`   // Command line, without the program name, --next, and --page-size
+	Offset  int       `json:"offset"` // Rows on the pages before
+	Size    int       `json:"size"`   // Rows per page
+	Created time.Time `json:"created"`
+}
+
+// file is the stored form of the tokens
+type file struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Path returns the file tokens are stored in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pages.json"), nil
+}
+
+// Save issues a token for the page of args' output starting at offset,
+// returning the one already issued for it if there is one
+func Save(ctx context.Context, args []string, offset, size int) (Token, error) {
+	var t Token
+	err := update(ctx, func(f *file) {
+		for _, existing := range f.Tokens {
+			if existing.Offset == offset && existing.Size == size && slices.Equal(existing.Args, args) {
+				t = existing
+				return
+			}
+		}
+		t = Token{
+			ID:      newID(),
+			Args:    args,
+			Offset:  offset,
+			Size:    size,
+			Created: time.Now().UTC().Truncate(time.Second),
+		}
+		f.Tokens = append(f.Tokens, t)
+		if len(f.Tokens) > maxTokens {
+			f.Tokens = f.Tokens[len(f.Tokens)-maxTokens:]
+		}
+	})
+	return t, err
+}
+
+// Load returns the token with id
+func Load(id string) (Token, error) {
+	path, err := Path()
+	if err != nil {
+		return Token{}, err
+	}
+	f, err := read(path)
+	if err != nil {
+		return Token{}, err
+	}
+	for _, t := range f.Tokens {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+	return Token{}, ErrUnknownToken
+}
+
+// update applies fn to the tokens file under its lock, dropping expired
+// tokens
+func update(ctx context.Context, fn func(*file)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking page tokens: %w", err)
+	}
+	defer l.Release()
+
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	fn(f)
+	return write(path, f)
+}
+
+// newID returns a random token ID, short enough to type
+func newID() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// read loads the tokens file, leaving out expired tokens; a missing file
+// holds none
+func read(path string) (*file, error) {
+	f := &file{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	f.Tokens = slices.DeleteFunc(f.Tokens, func(t Token) bool {
+		return time.Since(t.Created) > tokenTTL
+	})
+	return f, nil
+}
+
+// write replaces the tokens file, through a temporary file so readers
+// never see a partial write
+func write(path string, f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding page tokens: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
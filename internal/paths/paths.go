@@ -0,0 +1,60 @@
+// Package paths resolves per-user directories following the XDG base
+// directory spec, falling back to the platform defaults.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppName is the directory name used under each base directory
+const AppName = "termplate"
+
+// ConfigDir returns the directory for user configuration ($XDG_CONFIG_HOME/termplate)
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, AppName), nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding config directory: %w", err)
+	}
+	return filepath.Join(base, AppName), nil
+}
+
+// CacheDir returns the directory for disposable cached data ($XDG_CACHE_HOME/termplate)
+func CacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, AppName), nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding cache directory: %w", err)
+	}
+	return filepath.Join(base, AppName), nil
+}
+
+// StateDir returns the directory for persistent state such as locks and
+// history ($XDG_STATE_HOME/termplate, default ~/.local/state/termplate)
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, AppName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", AppName), nil
+}
+
+// Ensure creates dir (and parents) if needed and returns it
+func Ensure(dir string, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating %s: %w", dir, err)
+	}
+	return dir, nil
+}
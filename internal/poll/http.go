@@ -0,0 +1,166 @@
+package poll
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// maxErrorBody is how much of an error response is kept in its HTTPError
+const maxErrorBody = 1024
+
+// Statuses taken as finished, successfully or not, compared
+// case-insensitively
+var (
+	succeededStatuses = []string{"succeeded", "success", "successful", "completed", "complete", "done", "finished", "ready"}
+	failedStatuses    = []string{"failed", "failure", "error", "errored", "canceled", "cancelled", "aborted"}
+)
+
+// HTTPCheck returns a CheckFunc that gets url with client and reads the
+// operation's state from the JSON response (see ParseState). A status
+// other than 2xx is a *model.HTTPError, which Wait tries again when
+// transient.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) (State, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return State{}, fmt.Errorf("building request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return State{}, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+			return State{}, &model.HTTPError{
+				Method:     req.Method,
+				URL:        url,
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Header:     resp.Header,
+				Body:       body,
+			}
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return State{}, fmt.Errorf("reading operation: %w", err)
+		}
+		state, err := ParseState(body)
+		if err != nil {
+			return State{}, err
+		}
+		state.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+		return state, nil
+	}
+}
+
+// ParseState reads an operation's state from the JSON object an API
+// returns for it. The common shapes are understood:
+//
+//	{"status": "running"}                          // or "state"
+//	{"status": "succeeded", "result": {...}}       // or "response", "resource"
+//	{"status": "failed", "error": "quota exceeded"} // or {"error": {"message": ...}}
+//	{"done": true, "response": {...}}
+//
+// An object without a status or done field is taken as the finished
+// resource itself, as returned by APIs that redirect to it.
+func ParseState(body []byte) (State, error) {
+	var op map[string]json.RawMessage
+	if err := json.Unmarshal(body, &op); err != nil {
+		return State{}, fmt.Errorf("parsing operation: %w", err)
+	}
+
+	var state State
+	status, hasStatus := stringField(op, "status", "state")
+	state.Status = status
+	var done bool
+	_, hasDone := op["done"]
+	if hasDone {
+		if err := json.Unmarshal(op["done"], &done); err != nil {
+			return State{}, fmt.Errorf("parsing operation: done: %w", err)
+		}
+	}
+	failure := errorField(op)
+
+	switch lower := strings.ToLower(status); {
+	case !hasStatus && !hasDone:
+		return State{Done: true, Result: body}, nil
+	case slices.Contains(failedStatuses, lower):
+		state.Done = true
+		if failure == nil {
+			failure = errors.New(status)
+		}
+		state.Err = failure
+	case slices.Contains(succeededStatuses, lower), done:
+		state.Done = true
+		state.Err = failure
+	}
+
+	if state.Done && state.Err == nil {
+		state.Result = body
+		for _, name := range []string{"result", "response", "resource"} {
+			if r, ok := op[name]; ok && string(r) != "null" {
+				state.Result = r
+				break
+			}
+		}
+	}
+	return state, nil
+}
+
+// stringField returns the first of the named fields that is a string
+func stringField(op map[string]json.RawMessage, names ...string) (string, bool) {
+	for _, name := range names {
+		var s string
+		if raw, ok := op[name]; ok && json.Unmarshal(raw, &s) == nil {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// errorField returns the operation's error: a string, or an object with
+// a message
+func errorField(op map[string]json.RawMessage) error {
+	raw, ok := op["error"]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	var msg string
+	if json.Unmarshal(raw, &msg) == nil {
+		return errors.New(msg)
+	}
+	var obj struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &obj) == nil && obj.Message != "" {
+		return errors.New(obj.Message)
+	}
+	return errors.New(string(raw))
+}
+
+// retryAfter parses a Retry-After header: seconds, or an HTTP date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+// Package poll waits for long-running operations that an API tracks by ID,
+// checking their status with a growing interval until they finish.
+package poll
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/retry"
+)
+
+// Defaults for Options left zero
+const (
+	defaultInterval    = time.Second
+	defaultMaxInterval = 30 * time.Second
+	defaultMultiplier  = 1.5
+)
+
+// ErrTimeout is returned by Wait when Options.Timeout passes first
+var ErrTimeout = errors.New("operation still running")
+
+// State is an operation's status as of one check
+type State struct {
+	Status     string          // As the API reports it, e.g. "running"
+	Done       bool            // The operation finished, successfully or not
+	Err        error           // Why a finished operation failed; nil when it succeeded
+	Result     json.RawMessage // The resource the operation produced, once done
+	RetryAfter time.Duration   // When the API asked to check again, if it did
+}
+
+// CheckFunc checks an operation's status once
+type CheckFunc func(ctx context.Context) (State, error)
+
+// Options configures Wait
+type Options struct {
+	Interval    time.Duration // Wait before the second check; 1s when zero
+	MaxInterval time.Duration // Longest wait between checks; 30s when zero
+	Multiplier  float64       // Growth of the wait after each check; 1.5 when zero
+	Timeout     time.Duration // Give up after this long; zero waits until ctx is done
+
+	// OnCheck, when set, is called with each state, e.g. to show the
+	// status on a spinner
+	OnCheck func(State)
+}
+
+// FailedError is returned by Wait for an operation that finished
+// unsuccessfully
+type FailedError struct {
+	Status string
+	Err    error
+}
+
+func (e *FailedError) Error() string {
+	if e.Status == "" {
+		return fmt.Sprintf("operation failed: %s", e.Err)
+	}
+	return fmt.Sprintf("operation %s: %s", e.Status, e.Err)
+}
+
+func (e *FailedError) Unwrap() error {
+	return e.Err
+}
+
+// Wait checks an operation until it's done and returns its final state,
+// waiting longer between checks each time, or as long as the API asks
+// with Retry-After. A check failing with a transient error (see
+// retry.IsRetryable) is tried again at the next interval. It returns a
+// *FailedError for an operation that failed, and an error wrapping
+// ErrTimeout once Options.Timeout passes.
+func Wait(ctx context.Context, check CheckFunc, opts Options) (State, error) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxInterval
+	}
+	maxInterval = max(maxInterval, interval)
+	multiplier := opts.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultMultiplier
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, opts.Timeout,
+			fmt.Errorf("%w after %s", ErrTimeout, opts.Timeout))
+		defer cancel()
+	}
+
+	var last State
+	for checks := 1; ; checks++ {
+		state, err := check(ctx)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return last, context.Cause(ctx)
+		case err != nil && !retry.IsRetryable(err):
+			return last, err
+		case err != nil:
+			slog.Debug("checking operation failed; trying again", "check", checks, "error", err)
+		default:
+			last = state
+			if opts.OnCheck != nil {
+				opts.OnCheck(state)
+			}
+			if state.Done {
+				if state.Err != nil {
+					return state, &FailedError{Status: state.Status, Err: state.Err}
+				}
+				return state, nil
+			}
+		}
+
+		wait := interval
+		if state.RetryAfter > 0 {
+			wait = state.RetryAfter
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return last, context.Cause(ctx)
+		}
+		interval = min(time.Duration(float64(interval)*multiplier), maxInterval)
+	}
+}
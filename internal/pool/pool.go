@@ -0,0 +1,140 @@
+// Package pool runs independent tasks on a fixed number of goroutines,
+// optionally rate limited, and stops handing out tasks when the context is
+// canceled so the caller can report the results it has.
+package pool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Options configures Map and Stream
+type Options struct {
+	Workers   int     // Tasks run at once; 0 means GOMAXPROCS
+	Rate      float64 // Tasks started per second at most; 0 is unlimited
+	Unordered bool    // Stream emits results as tasks finish rather than in item order
+}
+
+// Result is the outcome of one task
+type Result[R any] struct {
+	Value R
+	Err   error
+	Done  bool // False when the task never started because ctx was done
+}
+
+// Map calls fn for every item on opts.Workers goroutines and returns the
+// results in item order. Once ctx is done no more tasks start; running
+// tasks get ctx and should return promptly. Check ctx afterwards to tell a
+// complete run from a canceled one.
+func Map[T, R any](ctx context.Context, opts Options, items []T, fn func(context.Context, T) (R, error)) []Result[R] {
+	results := make([]Result[R], len(items))
+	Stream(ctx, opts, items, fn, func(i int, r Result[R]) bool {
+		results[i] = r
+		return true
+	})
+	return results
+}
+
+// Stream calls fn for every item as Map does, and hands each result to
+// emit, on the calling goroutine, as soon as it may: in item order, once
+// the results before it have been emitted, so what's printed from them is
+// the same from run to run, or, with opts.Unordered, as each task
+// finishes. Items whose tasks never started are emitted last, in order,
+// with Done unset. Once emit returns false, no more tasks start, running
+// ones see ctx done, and nothing more is emitted.
+func Stream[T, R any](ctx context.Context, opts Options, items []T, fn func(context.Context, T) (R, error), emit func(i int, r Result[R]) bool) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(items))
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	type finished struct {
+		i int
+		r Result[R]
+	}
+	done := make(chan finished, workers)
+	jobs := make(chan int)
+	go dispatch(ctx, opts.Rate, len(items), jobs)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				v, err := fn(ctx, items[i])
+				done <- finished{i, Result[R]{Value: v, Err: err, Done: true}}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	emitting := true
+	send := func(i int, r Result[R]) {
+		if emitting && !emit(i, r) {
+			emitting = false
+			stop()
+		}
+	}
+	// Results finished ahead of one still running wait here for it
+	held := map[int]Result[R]{}
+	next, received := 0, 0
+	for f := range done {
+		received++
+		if opts.Unordered {
+			send(f.i, f.r)
+			continue
+		}
+		held[f.i] = f.r
+		for r, ok := held[next]; ok; r, ok = held[next] {
+			delete(held, next)
+			send(next, r)
+			next++
+		}
+	}
+	// Tasks are handed out in item order, so those that never started
+	// are the last
+	for i := received; i < len(items); i++ {
+		send(i, Result[R]{})
+	}
+}
+
+// dispatch sends the indexes of n tasks to jobs, no faster than rate per
+// second, until ctx is done
+func dispatch(ctx context.Context, rate float64, n int, jobs chan<- int) {
+	defer close(jobs)
+
+	var tick <-chan time.Time
+	if rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / rate))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for i := range n {
+		if ctx.Err() != nil {
+			return
+		}
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
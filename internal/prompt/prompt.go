@@ -0,0 +1,156 @@
+// Package prompt provides simple interactive prompts on a terminal.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var ErrNoInput = errors.New("no input available")
+
+// Prompter asks questions on out and reads answers from in
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// New creates a prompter reading from in and writing prompts to out
+func New(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// NewStdio creates a prompter on stdin, writing prompts to stderr so
+// they don't mix with command output
+func NewStdio() *Prompter {
+	return New(os.Stdin, os.Stderr)
+}
+
+// IsInteractive reports whether stdin is a terminal. /dev/null is a
+// character device too, so checking the file mode isn't enough.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// Ask prompts for a free-form answer, returning def when the answer is empty
+func (p *Prompter) Ask(label, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", label)
+	}
+
+	answer, err := p.readLine()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Confirm prompts for a yes/no answer
+func (p *Prompter) Confirm(label string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Fprintf(p.out, "%s [%s]: ", label, hint)
+
+	answer, err := p.readLine()
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Select prompts for one of choices, accepting either the value or its number
+func (p *Prompter) Select(label string, choices []string, def string) (string, error) {
+	fmt.Fprintf(p.out, "%s\n", label)
+	for i, c := range choices {
+		marker := " "
+		if c == def {
+			marker = "*"
+		}
+		fmt.Fprintf(p.out, " %s %d) %s\n", marker, i+1, c)
+	}
+
+	for {
+		answer, err := p.Ask("Choose", def)
+		if err != nil {
+			return "", err
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1], nil
+		}
+		for _, c := range choices {
+			if c == answer {
+				return c, nil
+			}
+		}
+		fmt.Fprintf(p.out, "invalid choice %q\n", answer)
+	}
+}
+
+// Choose prompts for one of a few short answers on one line, e.g.
+// "Retry? [y/N/always]". An answer matches a choice or a prefix of it,
+// ignoring case; the first matching choice wins.
+func (p *Prompter) Choose(label string, choices []string, def string) (string, error) {
+	hints := make([]string, len(choices))
+	for i, c := range choices {
+		hints[i] = c
+		if c == def {
+			hints[i] = strings.ToUpper(c[:1]) + c[1:]
+		}
+	}
+
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", label, strings.Join(hints, "/"))
+		answer, err := p.readLine()
+		if err != nil {
+			return "", err
+		}
+		if answer == "" {
+			return def, nil
+		}
+		for _, c := range choices {
+			if strings.HasPrefix(strings.ToLower(c), strings.ToLower(answer)) {
+				return c, nil
+			}
+		}
+		fmt.Fprintf(p.out, "invalid choice %q\n", answer)
+	}
+}
+
+// Warn prints a message without expecting an answer
+func (p *Prompter) Warn(msg string) {
+	fmt.Fprintln(p.out, msg)
+}
+
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", ErrNoInput
+		}
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
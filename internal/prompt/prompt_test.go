@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Prompts are plain text whatever the color settings, so they work on
+// dumb terminals and screen readers alike
+func TestPromptRendering(t *testing.T) {
+	envs := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "defaults", env: map[string]string{"TERM": "xterm"}},
+		{name: "NO_COLOR", env: map[string]string{"NO_COLOR": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR_FORCE", env: map[string]string{"CLICOLOR_FORCE": "1", "TERM": "xterm"}},
+		{name: "CLICOLOR=0", env: map[string]string{"CLICOLOR": "0", "TERM": "xterm"}},
+		{name: "TERM=dumb", env: map[string]string{"TERM": "dumb"}},
+	}
+	prompts := []struct {
+		name   string
+		input  string
+		prompt func(p *Prompter) (interface{}, error)
+		want   interface{}
+		output string
+	}{
+		{
+			name:   "ask",
+			input:  "\n",
+			prompt: func(p *Prompter) (interface{}, error) { return p.Ask("Name", "app") },
+			want:   "app",
+			output: "Name [app]: ",
+		},
+		{
+			name:   "confirm",
+			input:  "y\n",
+			prompt: func(p *Prompter) (interface{}, error) { return p.Confirm("Continue?", false) },
+			want:   true,
+			output: "Continue? [y/N]: ",
+		},
+		{
+			name:   "select",
+			input:  "4\n2\n",
+			prompt: func(p *Prompter) (interface{}, error) { return p.Select("Kind", []string{"cli", "api"}, "cli") },
+			want:   "api",
+			output: "Kind\n * 1) cli\n   2) api\nChoose [cli]: invalid choice \"4\"\nChoose [cli]: ",
+		},
+		{
+			name:   "choose",
+			input:  "al\n",
+			prompt: func(p *Prompter) (interface{}, error) { return p.Choose("Retry?", []string{"y", "n", "always"}, "n") },
+			want:   "always",
+			output: "Retry? [y/N/always]: ",
+		},
+	}
+	for _, e := range envs {
+		for _, tt := range prompts {
+			t.Run(e.name+"/"+tt.name, func(t *testing.T) {
+				for _, k := range []string{"NO_COLOR", "CLICOLOR_FORCE", "CLICOLOR", "TERM"} {
+					t.Setenv(k, e.env[k])
+				}
+				var out bytes.Buffer
+				got, err := tt.prompt(New(strings.NewReader(tt.input), &out))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("answer = %v, want %v", got, tt.want)
+				}
+				if out.String() != tt.output {
+					t.Errorf("prompt = %q, want %q", out.String(), tt.output)
+				}
+			})
+		}
+	}
+}
+
+func TestNoInput(t *testing.T) {
+	_, err := New(strings.NewReader(""), &bytes.Buffer{}).Ask("Name", "")
+	if !errors.Is(err, ErrNoInput) {
+		t.Errorf("err = %v, want ErrNoInput", err)
+	}
+}
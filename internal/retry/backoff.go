@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Backoff returns how long to wait before retry n (1 for the first): base
+// doubled for each earlier retry, capped at maxDelay when it's set, less a
+// random jitter of up to half, so clients that failed together don't retry
+// together
+func Backoff(base, maxDelay time.Duration, n int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < n && (maxDelay <= 0 || d < maxDelay); i++ {
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// RetryAfter returns the wait a response's Retry-After header asks for,
+// given in seconds or as an HTTP date; ok is false without one
+func RetryAfter(h http.Header, now time.Time) (d time.Duration, ok bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// wait returns how long to wait before retry n after err: what a
+// Retry-After header on a *model.HTTPError asks for, up to maxDelay, or
+// else Backoff
+func wait(err error, base, maxDelay time.Duration, n int) time.Duration {
+	if he := httpError(err); he != nil {
+		if d, ok := RetryAfter(he.Header, time.Now()); ok {
+			if maxDelay > 0 {
+				d = min(d, maxDelay)
+			}
+			return d
+		}
+	}
+	return Backoff(base, maxDelay, n)
+}
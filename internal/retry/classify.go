@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Verdict is a Classifier's decision on whether an error is transient
+type Verdict int
+
+const (
+	// Pass leaves the decision to the next classifier, and in the end to
+	// IsRetryable
+	Pass Verdict = iota
+	Retry
+	NoRetry
+)
+
+// Classifier decides whether err is worth retrying, letting a project
+// teach retries the semantics of its backend, such as a 409 whose body
+// says a lock is held
+type Classifier func(err error) Verdict
+
+var (
+	classifiersMu sync.RWMutex
+	classifiers   []Classifier
+)
+
+// RegisterClassifier adds a classifier consulted by every Retrier, after
+// those in its Options. Call it from an init function.
+func RegisterClassifier(c Classifier) {
+	classifiersMu.Lock()
+	defer classifiersMu.Unlock()
+	classifiers = append(classifiers, c)
+}
+
+// retryable classifies err with r's classifiers, the registered ones, and
+// then Options.Statuses or IsRetryable. Cancellations are never retried, whatever a classifier
+// says.
+func (r *Retrier) retryable(err error) bool {
+	if err == nil || canceled(err) {
+		return false
+	}
+	classifiersMu.RLock()
+	all := append(slices.Clone(r.opts.Classifiers), classifiers...)
+	classifiersMu.RUnlock()
+	for _, c := range all {
+		switch c(err) {
+		case Retry:
+			return true
+		case NoRetry:
+			return false
+		}
+	}
+	if he := httpError(err); he != nil && r.opts.Statuses != nil {
+		return slices.Contains(r.opts.Statuses, he.StatusCode)
+	}
+	return IsRetryable(err)
+}
+
+// ForStatus returns a classifier giving verdict to a *model.HTTPError with
+// one of codes
+func ForStatus(verdict Verdict, codes ...int) Classifier {
+	return func(err error) Verdict {
+		var he *model.HTTPError
+		if errors.As(err, &he) && slices.Contains(codes, he.StatusCode) {
+			return verdict
+		}
+		return Pass
+	}
+}
+
+// ForEndpoint scopes c to a *model.HTTPError from requests with method
+// (any when empty) to a URL path starting with pathPrefix, e.g. so 429s
+// from a search endpoint with a tight quota aren't retried
+func ForEndpoint(method, pathPrefix string, c Classifier) Classifier {
+	return func(err error) Verdict {
+		var he *model.HTTPError
+		if !errors.As(err, &he) || (method != "" && !strings.EqualFold(method, he.Method)) {
+			return Pass
+		}
+		path := he.URL
+		if u, perr := url.Parse(he.URL); perr == nil {
+			path = u.Path
+		}
+		if !strings.HasPrefix(path, pathPrefix) {
+			return Pass
+		}
+		return c(err)
+	}
+}
@@ -0,0 +1,210 @@
+// Package retry retries operations that fail with transient errors, such
+// as a dropped connection. After the configured automatic attempts, an
+// interactive session asks whether to keep going, so a long bulk operation
+// on a flaky network doesn't have to start over.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/prompt"
+)
+
+// Answers to the retry prompt
+const (
+	answerYes    = "y"
+	answerNo     = "n"
+	answerAlways = "always"
+)
+
+// Options configures a Retrier
+type Options struct {
+	Attempts int           // Automatic retries before asking
+	Delay    time.Duration // Wait before the first automatic retry, doubled for each one after (see Backoff)
+	MaxDelay time.Duration // Longest wait, including one a Retry-After header asks for; zero is unlimited
+
+	// Statuses are the HTTP statuses of a *model.HTTPError retried, after
+	// the Classifiers; DefaultStatuses when nil
+	Statuses []int
+
+	// Auto retries without asking, as if every prompt were answered
+	// "always" (--auto-retry)
+	Auto bool
+
+	// Prompter asks whether to retry; nil means the session isn't
+	// interactive and the error is returned once the attempts are used up
+	Prompter *prompt.Prompter
+
+	// Classifiers decide which errors are retried, before those added
+	// with RegisterClassifier and the defaults of IsRetryable
+	Classifiers []Classifier
+}
+
+// Retrier runs operations with retries. Answering "always" applies to
+// every later failure in the run, so one Retrier is shared per command.
+type Retrier struct {
+	opts Options
+
+	mu     sync.Mutex // Serializes prompts
+	always bool
+}
+
+// New creates a retrier
+func New(opts Options) *Retrier {
+	return &Retrier{opts: opts, always: opts.Auto}
+}
+
+// Do runs fn, retrying it while it fails with a retryable error (see
+// Classifier and IsRetryable): first up to Attempts times automatically, then for as long
+// as the user asks to. what names the operation in logs and the prompt.
+// Retrying stops when ctx is done.
+func (r *Retrier) Do(ctx context.Context, what string, fn func(context.Context) error) error {
+	for {
+		err := r.attempt(ctx, what, fn)
+		if !r.retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if !r.confirm(ctx, what, err) {
+			return err
+		}
+	}
+}
+
+// attempt runs fn once plus up to Attempts automatic retries
+func (r *Retrier) attempt(ctx context.Context, what string, fn func(context.Context) error) error {
+	for i := 0; ; i++ {
+		err := fn(ctx)
+		if !r.retryable(err) || i >= r.opts.Attempts {
+			return err
+		}
+		delay := wait(err, r.opts.Delay, r.opts.MaxDelay, i+1)
+		slog.WarnContext(ctx, "retrying", "operation", what, "attempt", i+2, "wait", delay, "error", err)
+		events.Record(ctx, events.Warning, "retry", fmt.Sprintf("retrying %s: %v", what, err), "attempt", i+2)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// confirm asks whether to retry after the automatic attempts failed
+func (r *Retrier) confirm(ctx context.Context, what string, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.always {
+		slog.WarnContext(ctx, "retrying", "operation", what, "error", err)
+		events.Record(ctx, events.Warning, "retry", fmt.Sprintf("retrying %s: %v", what, err))
+		return true
+	}
+	if r.opts.Prompter == nil {
+		return false
+	}
+
+	r.opts.Prompter.Warn(fmt.Sprintf("%s failed: %v", what, err))
+	answer, perr := r.opts.Prompter.Choose("Retry?", []string{answerYes, answerNo, answerAlways}, answerNo)
+	if perr != nil {
+		return false
+	}
+	if answer == answerAlways {
+		r.always = true
+	}
+	return answer != answerNo
+}
+
+// retryableError marks an error as transient
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Retryable marks err as transient, so Do retries it. It returns nil for a
+// nil err.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// IsRetryable reports whether err is transient by default: marked with
+// Retryable, a network timeout, a refused or reset connection, a
+// connection closed mid-response, or a *model.HTTPError with one of
+// DefaultStatuses. Cancellations never are. Classifiers aren't
+// consulted; a Retrier consults them first.
+func IsRetryable(err error) bool {
+	if err == nil || canceled(err) {
+		return false
+	}
+	var re *retryableError
+	if errors.As(err, &re) {
+		return true
+	}
+	if he := httpError(err); he != nil {
+		return slices.Contains(DefaultStatuses, he.StatusCode)
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// DefaultStatuses are the HTTP statuses retried unless configured
+// otherwise: timeouts, rate limits, and unavailable upstreams
+var DefaultStatuses = []int{408, 429, 502, 503, 504}
+
+// httpError returns the *model.HTTPError in err's chain, or nil
+func httpError(err error) *model.HTTPError {
+	var he *model.HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	return nil
+}
+
+// canceled reports whether err comes from a cancellation
+func canceled(err error) bool {
+	var ce *model.CancelError
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ce)
+}
+
+type retrierKey struct{}
+
+// WithContext returns a copy of ctx carrying r
+func WithContext(ctx context.Context, r *Retrier) context.Context {
+	return context.WithValue(ctx, retrierKey{}, r)
+}
+
+// FromContext returns the retrier carried by ctx, or one that never
+// retries
+func FromContext(ctx context.Context) *Retrier {
+	if r, ok := ctx.Value(retrierKey{}).(*Retrier); ok {
+		return r
+	}
+	return New(Options{})
+}
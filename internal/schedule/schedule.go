@@ -0,0 +1,319 @@
+// Package schedule runs the jobs of the schedule section in the daemon:
+// termplate commands run every so often. Each job's state (last run, next
+// run, failures) is kept in a file in the state directory, so it survives
+// restarts, and runs missed while the daemon was down are caught up per
+// the job's catch-up policy.
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/events"
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+const (
+	// lockName serializes changes to the state file
+	lockName = "schedule"
+	// lockWait is how long to wait for another invocation's change
+	lockWait = 10 * time.Second
+	// maxCatchUp caps the missed runs the all policy makes at once
+	maxCatchUp = 10
+)
+
+// Job statuses
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// State is what's known of a job across restarts
+type State struct {
+	Name       string        `json:"name"`
+	Command    string        `json:"command"`
+	Every      time.Duration `json:"every_ns"`
+	LastRun    time.Time     `json:"last_run"`
+	LastStatus string        `json:"last_status,omitempty"` // StatusSucceeded or StatusFailed; empty before the first run
+	NextRun    time.Time     `json:"next_run"`              // Zero until the daemon has started with the job
+	Runs       int           `json:"runs"`
+	Failures   int           `json:"failures"` // Failed runs in a row, up to the last
+	Missed     int           `json:"missed"`   // Runs not made while the daemon was down or busy
+	LastError  string        `json:"last_error,omitempty"`
+}
+
+// file is the stored form of the job states
+type file struct {
+	Jobs map[string]*State `json:"jobs"`
+}
+
+// Path returns the file job states are stored in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "schedule.json"), nil
+}
+
+// List returns the state of the jobs of cfg, by name
+func List(cfg config.ScheduleConfig) ([]State, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]State, 0, len(cfg.Jobs))
+	for _, name := range slices.Sorted(maps.Keys(cfg.Jobs)) {
+		states = append(states, *f.state(name, cfg.Jobs[name]))
+	}
+	return states, nil
+}
+
+// RunFunc runs a command line in this process
+type RunFunc func(ctx context.Context, args []string) error
+
+// Scheduler runs the jobs of a ScheduleConfig
+type Scheduler struct {
+	cfg config.ScheduleConfig
+	run RunFunc
+	now func() time.Time
+}
+
+// New creates a scheduler running the jobs of cfg with run
+func New(cfg config.ScheduleConfig, run RunFunc) *Scheduler {
+	return &Scheduler{cfg: cfg, run: run, now: time.Now}
+}
+
+// States returns the state of the scheduler's jobs, by name
+func (s *Scheduler) States() ([]State, error) {
+	return List(s.cfg)
+}
+
+// Run runs the jobs as they come due until ctx is done. A job's first run
+// is one interval after the daemon first starts with it. Runs missed while
+// the daemon was down are made at once per the job's catch-up policy (see
+// catchUp); those missed while it was busy are made once.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.cfg.Jobs) == 0 {
+		return nil
+	}
+	names := slices.Sorted(maps.Keys(s.cfg.Jobs))
+	for _, name := range names {
+		if s.cfg.Jobs[name].Every <= 0 {
+			return fmt.Errorf("job %s: every must be a positive duration", name)
+		}
+	}
+
+	due := map[string]int{}
+	next, err := s.update(ctx, func(f *file, now time.Time) {
+		// Jobs no longer configured are forgotten
+		maps.DeleteFunc(f.Jobs, func(name string, _ *State) bool { return !slices.Contains(names, name) })
+		for _, name := range names {
+			job := s.cfg.Jobs[name]
+			policy := job.CatchUp
+			if policy == "" {
+				policy = s.cfg.CatchUp
+			}
+			due[name] = catchUp(f.state(name, job), policy, now)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("scheduler started", "jobs", len(names), "next_run", next)
+
+	for {
+		for _, name := range names {
+			for range due[name] {
+				if next, err = s.runJob(ctx, name); err != nil || ctx.Err() != nil {
+					return err
+				}
+			}
+		}
+
+		t := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+
+		clear(due)
+		next, err = s.update(ctx, func(f *file, now time.Time) {
+			for _, name := range names {
+				st := f.state(name, s.cfg.Jobs[name])
+				if slots := advance(st, now); slots > 0 {
+					due[name] = 1
+					st.Missed += slots - 1
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runJob runs a job and records its result, returning when the next job
+// is due. Only a failure to record the result is returned; a run cut
+// short by ctx isn't recorded.
+func (s *Scheduler) runJob(ctx context.Context, name string) (time.Time, error) {
+	job := s.cfg.Jobs[name]
+	start := s.now()
+	slog.Info("running scheduled job", "job", name, "command", job.Command)
+	err := s.run(ctx, strings.Fields(job.Command))
+	if ctx.Err() != nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		slog.Warn("scheduled job failed", "job", name, "error", err)
+		events.Record(ctx, events.Warning, "schedule", fmt.Sprintf("job %s failed: %v", name, err), "job", name)
+	}
+	return s.update(ctx, func(f *file, _ time.Time) {
+		st := f.state(name, job)
+		st.LastRun = start.UTC().Truncate(time.Second)
+		st.Runs++
+		if err != nil {
+			st.LastStatus, st.LastError = StatusFailed, err.Error()
+			st.Failures++
+		} else {
+			st.LastStatus, st.LastError = StatusSucceeded, ""
+			st.Failures = 0
+		}
+	})
+}
+
+// update applies fn to the state file under its lock, with the current
+// time, and returns when the next job is due
+func (s *Scheduler) update(ctx context.Context, fn func(f *file, now time.Time)) (time.Time, error) {
+	var next time.Time
+	err := update(ctx, func(f *file) {
+		fn(f, s.now())
+		for name, job := range s.cfg.Jobs {
+			if at := f.state(name, job).NextRun; next.IsZero() || at.Before(next) {
+				next = at
+			}
+		}
+	})
+	return next, err
+}
+
+// catchUp returns the runs of a job to make when the daemon starts, and
+// moves its next run after now. A job seen for the first time is first due
+// one interval from now. Of the runs due while the daemon was down, skip
+// makes none, once makes one, and all makes each, up to maxCatchUp; the
+// others count as missed.
+func catchUp(st *State, policy string, now time.Time) int {
+	if st.NextRun.IsZero() {
+		st.NextRun = now.Add(st.Every).UTC().Truncate(time.Second)
+		return 0
+	}
+	slots := advance(st, now)
+	runs := 0
+	switch policy {
+	case "once":
+		runs = min(slots, 1)
+	case "all":
+		runs = min(slots, maxCatchUp)
+	}
+	st.Missed += slots - runs
+	return runs
+}
+
+// advance moves a job's next run to the first one after now, returning
+// how many were due by now
+func advance(st *State, now time.Time) int {
+	if st.NextRun.After(now) {
+		return 0
+	}
+	slots := int(now.Sub(st.NextRun)/st.Every) + 1
+	st.NextRun = st.NextRun.Add(time.Duration(slots) * st.Every)
+	return slots
+}
+
+// state returns the state of a job, added when there's none, with its
+// command and interval as configured
+func (f *file) state(name string, job config.JobConfig) *State {
+	if f.Jobs == nil {
+		f.Jobs = map[string]*State{}
+	}
+	st, ok := f.Jobs[name]
+	if !ok {
+		st = &State{Name: name}
+		f.Jobs[name] = st
+	}
+	st.Command, st.Every = job.Command, job.Every
+	return st
+}
+
+// update applies fn to the state file under its lock
+func update(ctx context.Context, fn func(*file)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking job states: %w", err)
+	}
+	defer l.Release()
+
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	fn(f)
+	return write(path, f)
+}
+
+// read loads the state file; a missing file has no jobs
+func read(path string) (*file, error) {
+	f := &file{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// write replaces the state file, through a temporary file so readers
+// never see a partial write
+func write(path string, f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding job states: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatchUp(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		next     time.Time // Zero for a job seen for the first time
+		policy   string
+		runs     int
+		missed   int
+		wantNext time.Time
+	}{
+		{name: "new job", policy: "all", wantNext: now.Add(time.Hour)},
+		{name: "not due", next: now.Add(10 * time.Minute), policy: "all", wantNext: now.Add(10 * time.Minute)},
+		{name: "due now", next: now, policy: "once", runs: 1, wantNext: now.Add(time.Hour)},
+		{name: "skip", next: now.Add(-150 * time.Minute), policy: "skip", missed: 3, wantNext: now.Add(30 * time.Minute)},
+		{name: "once", next: now.Add(-150 * time.Minute), policy: "once", runs: 1, missed: 2, wantNext: now.Add(30 * time.Minute)},
+		{name: "all", next: now.Add(-150 * time.Minute), policy: "all", runs: 3, wantNext: now.Add(30 * time.Minute)},
+		{name: "all capped", next: now.Add(-24 * time.Hour), policy: "all", runs: maxCatchUp, missed: 25 - maxCatchUp, wantNext: now.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &State{Every: time.Hour, NextRun: tt.next, Missed: 1}
+			if runs := catchUp(st, tt.policy, now); runs != tt.runs {
+				t.Errorf("runs = %d, want %d", runs, tt.runs)
+			}
+			if missed := st.Missed - 1; missed != tt.missed {
+				t.Errorf("missed = %d, want %d", missed, tt.missed)
+			}
+			if !st.NextRun.Equal(tt.wantNext) {
+				t.Errorf("next run = %s, want %s", st.NextRun, tt.wantNext)
+			}
+		})
+	}
+}
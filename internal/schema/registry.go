@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Registry holds the schemas stored in a directory, one JSON Schema per
+// file, each naming its endpoint with x-endpoint:
+//
+//	{"x-endpoint": "GET /v1/users/*", "type": "object", "properties": {...}}
+type Registry struct {
+	dir string
+
+	mu      sync.RWMutex
+	schemas []*Schema
+}
+
+// LoadDir reads the *.json schemas in dir. A directory that doesn't exist
+// holds no schemas; Save creates it.
+func LoadDir(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing schemas: %w", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading schema: %w", err)
+		}
+		var s Schema
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("parsing schema %s: %w", file, err)
+		}
+		if _, _, err := splitEndpoint(s.Endpoint); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file, err)
+		}
+		r.schemas = append(r.schemas, &s)
+	}
+	return r, nil
+}
+
+// Lookup returns the schema of the first endpoint matching a request, in
+// file name order, or nil
+func (r *Registry) Lookup(method, urlPath string) *Schema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range r.schemas {
+		m, pattern, _ := splitEndpoint(s.Endpoint)
+		if !strings.EqualFold(m, method) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// Save writes s to the directory, named after its endpoint, and adds it
+// to the registry
+func (r *Registry) Save(s *Schema) error {
+	method, pattern, err := splitEndpoint(s.Endpoint)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding schema: %w", err)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("creating schema directory: %w", err)
+	}
+	// GET /v1/users/* is GET_v1_users_-.json; * isn't allowed in Windows file names
+	name := strings.ToUpper(method) + fileNameReplacer.Replace(pattern) + ".json"
+	if err := os.WriteFile(filepath.Join(r.dir, name), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing schema: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas = append(r.schemas, s)
+	return nil
+}
+
+var fileNameReplacer = strings.NewReplacer("/", "_", "*", "-", "?", "-", "[", "(", "]", ")")
+
+// splitEndpoint splits "GET /v1/users/*" into its method and path pattern
+func splitEndpoint(endpoint string) (method, pattern string, err error) {
+	method, pattern, ok := strings.Cut(strings.TrimSpace(endpoint), " ")
+	pattern = strings.TrimSpace(pattern)
+	if !ok || method == "" || !strings.HasPrefix(pattern, "/") {
+		return "", "", fmt.Errorf("x-endpoint %q must be a method and a path, e.g. \"GET /v1/users/*\"", endpoint)
+	}
+	if _, err := path.Match(pattern, ""); errors.Is(err, path.ErrBadPattern) {
+		return "", "", fmt.Errorf("x-endpoint %q: bad path pattern", endpoint)
+	}
+	return method, pattern, nil
+}
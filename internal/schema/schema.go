@@ -0,0 +1,262 @@
+// Package schema detects drift between API responses and the JSON Schemas
+// stored for them: fields the schema doesn't know, values whose type
+// changed, and required fields gone missing. It understands the parts of
+// JSON Schema that describe a response's shape (type, properties,
+// required, items, additionalProperties) and ignores the rest.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is a JSON Schema for a response body
+type Schema struct {
+	// Endpoint is the request the schema describes, a method and a path
+	// pattern such as "GET /v1/users/*" (see path.Match); only the schema
+	// at the top of a file has one
+	Endpoint string `json:"x-endpoint,omitempty"`
+
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"`
+}
+
+// Types is the type keyword: one JSON type or a list of them
+type Types []string
+
+// UnmarshalJSON accepts "string" as well as ["string", "null"]
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON writes a single type as a string
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Drift kinds
+const (
+	UnknownField = "unknown-field" // A field the schema doesn't declare
+	TypeChange   = "type-change"   // A value of another type than declared
+	MissingField = "missing-field" // A required field that's absent
+)
+
+// Drift is a difference between a response and its schema
+type Drift struct {
+	Path     string // Where in the response, e.g. ".items[0].status"
+	Kind     string // UnknownField, TypeChange, or MissingField
+	Expected string // Declared types, for TypeChange
+	Actual   string // Type found, for UnknownField and TypeChange
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case UnknownField:
+		return fmt.Sprintf("%s: unknown field (%s)", d.Path, d.Actual)
+	case MissingField:
+		return fmt.Sprintf("%s: required field missing", d.Path)
+	default:
+		return fmt.Sprintf("%s: expected %s, got %s", d.Path, d.Expected, d.Actual)
+	}
+}
+
+// Check compares data, decoded with json.Decoder.UseNumber, to s. Only the
+// first element of a list that drifts in a given way is reported, so a
+// long list with a new field reports it once.
+func Check(s *Schema, data interface{}) []Drift {
+	c := &checker{seen: map[string]bool{}}
+	c.check(s, data, "", "")
+	return c.drifts
+}
+
+type checker struct {
+	drifts []Drift
+	seen   map[string]bool // Drifts reported, keyed by kind and path with list indexes removed
+}
+
+func (c *checker) add(d Drift, pattern string) {
+	key := d.Kind + " " + pattern
+	if !c.seen[key] {
+		c.seen[key] = true
+		c.drifts = append(c.drifts, d)
+	}
+}
+
+// check compares v to s at path; pattern is path without list indexes
+func (c *checker) check(s *Schema, v interface{}, path, pattern string) {
+	if s == nil {
+		return
+	}
+	actual := typeOf(v)
+	if len(s.Type) > 0 && !s.Type.allows(actual) {
+		c.add(Drift{Path: display(path), Kind: TypeChange, Expected: strings.Join(s.Type, " or "), Actual: actual}, pattern)
+		return
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := t[name]; !ok {
+				c.add(Drift{Path: path + "." + name, Kind: MissingField}, pattern+"."+name)
+			}
+		}
+		allowed, extra := s.additional()
+		for _, name := range sortedKeys(t) {
+			sub, declared := s.Properties[name]
+			switch {
+			case declared:
+				c.check(sub, t[name], path+"."+name, pattern+"."+name)
+			case extra != nil:
+				c.check(extra, t[name], path+"."+name, pattern+".*")
+			case !allowed && s.Properties != nil:
+				c.add(Drift{Path: path + "." + name, Kind: UnknownField, Actual: typeOf(t[name])}, pattern+"."+name)
+			}
+		}
+	case []interface{}:
+		for i, item := range t {
+			c.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i), pattern+"[]")
+		}
+	}
+}
+
+// additional returns what additionalProperties allows: any field, or
+// fields matching extra. Undeclared fields drift when neither is set.
+func (s *Schema) additional() (allowed bool, extra *Schema) {
+	raw := strings.TrimSpace(string(s.AdditionalProperties))
+	switch raw {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	}
+	if err := json.Unmarshal(s.AdditionalProperties, &extra); err != nil {
+		return true, nil
+	}
+	return true, extra
+}
+
+// allows reports whether a value of JSON type actual matches t
+func (t Types) allows(actual string) bool {
+	return slices.Contains(t, actual) || (actual == "integer" && slices.Contains(t, "number"))
+}
+
+// typeOf returns the JSON type of a decoded value
+func typeOf(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if t == float64(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// Generate infers a schema from a sample response, for endpoint. Every
+// field of the sample is required, and the items of a list are described
+// by merging its elements.
+func Generate(endpoint string, data interface{}) *Schema {
+	s := generate(data)
+	s.Endpoint = endpoint
+	return s
+}
+
+func generate(v interface{}) *Schema {
+	s := &Schema{Type: Types{typeOf(v)}}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		s.Properties = make(map[string]*Schema, len(t))
+		for _, name := range sortedKeys(t) {
+			s.Properties[name] = generate(t[name])
+			s.Required = append(s.Required, name)
+		}
+	case []interface{}:
+		for _, item := range t {
+			s.Items = merge(s.Items, generate(item))
+		}
+	}
+	return s
+}
+
+// merge combines the schemas of two list elements: fields of either are
+// declared, fields of both required, and differing types all allowed
+func merge(a, b *Schema) *Schema {
+	if a == nil {
+		return b
+	}
+	for _, t := range b.Type {
+		if !a.Type.allows(t) {
+			a.Type = append(a.Type, t)
+		}
+	}
+	if slices.Contains(a.Type, "integer") && slices.Contains(a.Type, "number") {
+		a.Type = slices.DeleteFunc(a.Type, func(t string) bool { return t == "integer" })
+	}
+	if b.Properties != nil {
+		if a.Properties == nil {
+			a.Properties = map[string]*Schema{}
+		}
+		for name, sub := range b.Properties {
+			a.Properties[name] = merge(a.Properties[name], sub)
+		}
+		a.Required = slices.DeleteFunc(a.Required, func(name string) bool {
+			return !slices.Contains(b.Required, name)
+		})
+	}
+	if b.Items != nil {
+		a.Items = merge(a.Items, b.Items)
+	}
+	return a
+}
+
+// display returns path for messages, "." for the whole response
+func display(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// maxCheckedBody is the largest response body Transport checks; larger
+// ones pass through unread
+const maxCheckedBody = 10 << 20
+
+// Transport checks JSON responses against the schemas in a Registry,
+// reporting drift without failing the request. The response body is
+// buffered and handed on unchanged.
+//
+//	client := &http.Client{Transport: &schema.Transport{Schemas: registry}}
+type Transport struct {
+	Base    http.RoundTripper // http.DefaultTransport when nil
+	Schemas *Registry
+
+	// Record saves a schema generated from the first response of each
+	// endpoint without one, to review and commit
+	Record bool
+
+	// OnDrift is called with a response's drift; by default each is
+	// logged as a warning
+	OnDrift func(req *http.Request, drifts []Drift)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil || !checkable(resp) {
+		return resp, err
+	}
+
+	s := t.Schemas.Lookup(req.Method, req.URL.Path)
+	if s == nil && !t.Record {
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCheckedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if len(body) > maxCheckedBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		// Left for the caller's decoding to report
+		slog.Debug("response not checked against its schema", "url", req.URL.Redacted(), "error", err)
+		return resp, nil
+	}
+
+	if s == nil {
+		s = Generate(req.Method+" "+req.URL.Path, data)
+		if err := t.Schemas.Save(s); err != nil {
+			slog.Warn("recording response schema failed", "endpoint", s.Endpoint, "error", err)
+		} else {
+			slog.Info("recorded response schema", "endpoint", s.Endpoint)
+		}
+		return resp, nil
+	}
+	if drifts := Check(s, data); len(drifts) > 0 {
+		t.report(req, drifts)
+	}
+	return resp, nil
+}
+
+func (t *Transport) report(req *http.Request, drifts []Drift) {
+	if t.OnDrift != nil {
+		t.OnDrift(req, drifts)
+		return
+	}
+	for _, d := range drifts {
+		slog.Warn("API response drifted from its schema",
+			"endpoint", req.Method+" "+req.URL.Path, "path", d.Path, "drift", d.Kind, "detail", d.String())
+	}
+}
+
+// checkable reports whether resp is a successful JSON response small
+// enough to check
+func checkable(resp *http.Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.ContentLength > maxCheckedBody {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Wrap returns base checking responses against the schemas in
+// cfg.SchemaDir, or base itself when no directory is set
+func Wrap(cfg config.APIConfig, base http.RoundTripper) (http.RoundTripper, error) {
+	if cfg.SchemaDir == "" {
+		return base, nil
+	}
+	registry, err := LoadDir(cfg.SchemaDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Transport{Base: base, Schemas: registry, Record: cfg.RecordSchemas}, nil
+}
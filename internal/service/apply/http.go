@@ -0,0 +1,226 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/update"
+)
+
+// maxErrorBody is how much of an error response is kept in its HTTPError
+const maxErrorBody = 1024
+
+// HTTPBackend keeps resources in a REST API. A resource is at BaseURL +
+// Path, with {kind} and {name} replaced, and the collection of a kind at
+// the part of Path before /{name}: resources are listed and created there.
+// Updates send If-Match (see update.Modify).
+type HTTPBackend struct {
+	Client   *http.Client
+	BaseURL  string
+	Path     string          // e.g. /v1/{kind}/{name}
+	Resolver update.Resolver // Resolves updates colliding with another change; nil aborts them
+
+	// DryRunQuery is added to the query of a create or update to have the
+	// API validate it and return the result without making it, e.g.
+	// dryRun=All; empty when the API has no dry run
+	DryRunQuery string
+
+	// EventsPath is where a resource's events are listed under BaseURL,
+	// with {kind} and {name} replaced; empty when the API has none
+	EventsPath string
+}
+
+// Get fetches a resource
+func (b *HTTPBackend) Get(ctx context.Context, kind, name string) (map[string]interface{}, error) {
+	var body map[string]interface{}
+	if err := b.do(ctx, http.MethodGet, b.url(kind, name), nil, &body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Names lists the names of the resources of kind (see List)
+func (b *HTTPBackend) Names(ctx context.Context, kind string) ([]string, error) {
+	list, err := b.List(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		if name := NameOf(item); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// List lists the resources of kind: a JSON array of objects, or an object
+// with them in items or data
+func (b *HTTPBackend) List(ctx context.Context, kind string) ([]map[string]interface{}, error) {
+	var raw json.RawMessage
+	if err := b.do(ctx, http.MethodGet, b.collectionURL(kind), nil, &raw); err != nil {
+		return nil, err
+	}
+	list, err := decodeList(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing list of %s: %w", kind, err)
+	}
+	return list, nil
+}
+
+// Events lists the events of a resource at EventsPath, as List reads a
+// list, or returns ErrNoEvents when EventsPath is empty
+func (b *HTTPBackend) Events(ctx context.Context, kind, name string) ([]map[string]interface{}, error) {
+	if b.EventsPath == "" {
+		return nil, ErrNoEvents
+	}
+	path := strings.NewReplacer("{kind}", url.PathEscape(kind), "{name}", url.PathEscape(name)).Replace(b.EventsPath)
+	var raw json.RawMessage
+	if err := b.do(ctx, http.MethodGet, strings.TrimRight(b.BaseURL, "/")+"/"+strings.TrimLeft(path, "/"), nil, &raw); err != nil {
+		return nil, err
+	}
+	list, err := decodeList(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing events of %s/%s: %w", kind, name, err)
+	}
+	return list, nil
+}
+
+// decodeList decodes a JSON array of objects, or an object with them in
+// items or data
+func decodeList(raw json.RawMessage) ([]map[string]interface{}, error) {
+	var list []map[string]interface{}
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var page struct {
+		Items []map[string]interface{} `json:"items"`
+		Data  []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &page); err != nil {
+		return nil, err
+	}
+	return append(page.Items, page.Data...), nil
+}
+
+// Create posts a resource to its collection, with its name in the name
+// field unless spec sets it
+func (b *HTTPBackend) Create(ctx context.Context, kind, name string, spec map[string]interface{}) error {
+	body := merge(map[string]interface{}{"name": name}, spec)
+	return b.do(ctx, http.MethodPost, b.collectionURL(kind), body, nil)
+}
+
+// Update changes a resource with update.Modify
+func (b *HTTPBackend) Update(ctx context.Context, kind, name string, mutate update.Mutator) error {
+	_, err := update.Modify(ctx, b.Client, b.url(kind, name), mutate, update.Options{Resolver: b.Resolver})
+	return err
+}
+
+// DryRunCreate posts a resource to its collection as a dry run, and
+// returns the resource the API responds with
+func (b *HTTPBackend) DryRunCreate(ctx context.Context, kind, name string, spec map[string]interface{}) (map[string]interface{}, error) {
+	body := merge(map[string]interface{}{"name": name}, spec)
+	return b.dryRun(ctx, http.MethodPost, b.collectionURL(kind), body)
+}
+
+// DryRunUpdate puts a resource as a dry run, and returns the resource the
+// API responds with
+func (b *HTTPBackend) DryRunUpdate(ctx context.Context, kind, name string, body map[string]interface{}) (map[string]interface{}, error) {
+	return b.dryRun(ctx, http.MethodPut, b.url(kind, name), body)
+}
+
+func (b *HTTPBackend) dryRun(ctx context.Context, method, u string, body map[string]interface{}) (map[string]interface{}, error) {
+	// Without the API's dry run, the request would make the change
+	if b.DryRunQuery == "" {
+		return nil, ErrNoDryRun
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	var out map[string]interface{}
+	if err := b.do(ctx, method, u+sep+b.DryRunQuery, body, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Delete deletes a resource; one already gone is fine
+func (b *HTTPBackend) Delete(ctx context.Context, kind, name string) error {
+	err := b.do(ctx, http.MethodDelete, b.url(kind, name), nil, nil)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// url returns the URL of a resource
+func (b *HTTPBackend) url(kind, name string) string {
+	path := strings.NewReplacer("{kind}", url.PathEscape(kind), "{name}", url.PathEscape(name)).Replace(b.Path)
+	return strings.TrimRight(b.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// collectionURL returns the URL of the resources of kind
+func (b *HTTPBackend) collectionURL(kind string) string {
+	path := strings.TrimSuffix(b.Path, "/{name}")
+	path = strings.ReplaceAll(path, "{kind}", url.PathEscape(kind))
+	return strings.TrimRight(b.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// do sends a request with body as JSON and decodes the response into out,
+// when set. A 404 is an error wrapping model.ErrNotFound.
+func (b *HTTPBackend) do(ctx context.Context, method, url string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		httpErr := &model.HTTPError{
+			Method:     method,
+			URL:        req.URL.Redacted(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     resp.Header,
+			Body:       data,
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: %w", model.ErrNotFound, httpErr)
+		}
+		return httpErr
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("parsing %s: %w", req.URL.Redacted(), err)
+	}
+	return nil
+}
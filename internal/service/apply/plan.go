@@ -0,0 +1,204 @@
+package apply
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/diff"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Actions a plan takes on a resource
+const (
+	ActionCreate    = "create"    // Not live yet
+	ActionUpdate    = "update"    // Live with other values for fields of its spec
+	ActionDelete    = "delete"    // Live but in no file, with --prune
+	ActionUnchanged = "unchanged" // Live as declared
+)
+
+// Change is what a plan does to one resource
+type Change struct {
+	Kind   string                 `json:"kind" yaml:"kind"`
+	Name   string                 `json:"name" yaml:"name"`
+	Action string                 `json:"action" yaml:"action"`
+	Spec   map[string]interface{} `json:"spec,omitempty" yaml:"spec,omitempty"` // The declared fields
+	Diff   string                 `json:"diff,omitempty" yaml:"diff,omitempty"` // From the live state to the desired one
+
+	// Fields someone else changed since they were last applied, which the
+	// change would overwrite or remove, e.g. ["limits.cpu"]
+	Conflicts []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+
+	// LiveChecksum is the SHA-256 of the live state the change was planned
+	// against, empty when there was none, for Verify
+	LiveChecksum string `json:"live_checksum,omitempty" yaml:"live_checksum,omitempty"`
+}
+
+// ID returns the resource's kind/name
+func (c Change) ID() string {
+	return c.Kind + "/" + c.Name
+}
+
+// Plan is the changes that bring the live resources to the declared state
+type Plan struct {
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+// Count returns the number of changes with action
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Empty reports whether every resource is already as declared
+func (p *Plan) Empty() bool {
+	return p.Count(ActionUnchanged) == len(p.Changes)
+}
+
+// Summary counts the changes, e.g. "1 to create, 2 to update, 0 to
+// delete, 4 unchanged"
+func (p *Plan) Summary() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to delete, %d unchanged",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionDelete), p.Count(ActionUnchanged))
+}
+
+// merge returns live with the fields of spec set over it. Objects are
+// merged field by field; other values, lists included, are replaced.
+func merge(live, spec map[string]interface{}) map[string]interface{} {
+	out := maps.Clone(live)
+	if out == nil {
+		out = map[string]interface{}{}
+	}
+	for k, v := range spec {
+		sub, isMap := v.(map[string]interface{})
+		cur, curIsMap := out[k].(map[string]interface{})
+		if isMap && curIsMap {
+			out[k] = merge(cur, sub)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// threeWay returns live with the fields of spec set over it, as merge
+// does, and the fields of last, the spec last applied, that spec no longer
+// declares removed. Fields no apply set are left as they are.
+func threeWay(live, last, spec map[string]interface{}) map[string]interface{} {
+	out := merge(live, spec)
+	removeDropped(out, last, spec)
+	return out
+}
+
+// removeDropped removes from out the fields of last that spec dropped,
+// inside objects too. A dropped object loses only the fields last set, and
+// goes once it's empty. An object of out that spec declares is a copy made
+// by merge, or spec's own without those fields.
+func removeDropped(out, last, spec map[string]interface{}) {
+	for k, lv := range last {
+		sv, declared := spec[k]
+		lastSub, ok1 := lv.(map[string]interface{})
+		outSub, ok2 := out[k].(map[string]interface{})
+		specSub, ok3 := sv.(map[string]interface{})
+		switch {
+		case !declared && ok1 && ok2:
+			sub := maps.Clone(outSub)
+			removeDropped(sub, lastSub, nil)
+			if len(sub) == 0 {
+				delete(out, k)
+			} else {
+				out[k] = sub
+			}
+		case !declared:
+			delete(out, k)
+		case ok1 && ok2 && ok3:
+			removeDropped(outSub, lastSub, specSub)
+		}
+	}
+}
+
+// applyInto changes body in place as threeWay does
+func applyInto(body, last, spec map[string]interface{}) {
+	out := threeWay(body, last, spec)
+	clear(body)
+	maps.Copy(body, out)
+}
+
+// conflicts returns the fields of last, the spec last applied, whose live
+// value someone else has since changed and which applying spec would
+// overwrite or remove, as dotted paths under prefix
+func conflicts(live, last, spec map[string]interface{}, prefix string) []string {
+	var fields []string
+	for _, k := range slices.Sorted(maps.Keys(last)) {
+		lv := last[k]
+		cur, isLive := live[k]
+		sv, declared := spec[k]
+
+		lastSub, ok1 := lv.(map[string]interface{})
+		curSub, ok2 := cur.(map[string]interface{})
+		specSub, ok3 := sv.(map[string]interface{})
+		if ok1 && ok2 && (ok3 || !declared) {
+			fields = append(fields, conflicts(curSub, lastSub, specSub, prefix+k+".")...)
+			continue
+		}
+
+		changedElsewhere := !isLive || !reflect.DeepEqual(cur, lv)
+		if !changedElsewhere || (declared && reflect.DeepEqual(cur, sv)) || (!declared && !isLive) {
+			continue
+		}
+		fields = append(fields, prefix+k)
+	}
+	return fields
+}
+
+// render shows a resource's state for a diff, with its keys sorted
+func render(body map[string]interface{}) string {
+	if body == nil {
+		return ""
+	}
+	data, err := yaml.Marshal(body)
+	if err != nil {
+		return fmt.Sprint(body)
+	}
+	return string(data)
+}
+
+// diffOf shows the change from live to desired
+func diffOf(id string, live, desired map[string]interface{}) string {
+	return unified(id, "desired", live, desired)
+}
+
+// serverDiffOf shows the change from live to the resource a server dry run
+// returned
+func serverDiffOf(id string, live, result map[string]interface{}) string {
+	return unified(id, "server dry run", live, result)
+}
+
+func unified(id, to string, live, desired map[string]interface{}) string {
+	return strings.TrimSuffix(diff.Unified(id+" (live)", id+" ("+to+")", render(live), render(desired), 3), "\n")
+}
+
+// FieldConflictError is returned for an update that would overwrite or
+// remove fields someone else changed since they were last applied
+type FieldConflictError struct {
+	ID     string
+	Fields []string
+}
+
+func (e *FieldConflictError) Error() string {
+	return fmt.Sprintf("%s: fields changed elsewhere since the last apply: %s", e.ID, strings.Join(e.Fields, ", "))
+}
+
+// Unwrap makes the error match model.ErrConflict
+func (e *FieldConflictError) Unwrap() error {
+	return model.ErrConflict
+}
@@ -0,0 +1,156 @@
+package apply
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// planFileVersion is the format of the plan files WritePlan writes
+const planFileVersion = 1
+
+// ErrPlanExpired is returned by ReadPlan for a plan past its expiry
+var ErrPlanExpired = errors.New("plan expired")
+
+// SavedPlan is a plan written to a file, to be applied later exactly as
+// it was reviewed
+type SavedPlan struct {
+	Version  int       `json:"version"`
+	Target   string    `json:"target"` // The API the plan was made against, e.g. its base URL
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires,omitempty"` // Zero when it never expires
+	Plan     *Plan     `json:"plan"`
+	Checksum string    `json:"checksum"` // SHA-256 of the rest, so an edited file is refused
+}
+
+// StalePlanError is returned by Verify for a plan whose resources changed
+// since it was made
+type StalePlanError struct {
+	IDs []string
+}
+
+func (e *StalePlanError) Error() string {
+	return fmt.Sprintf("changed since the plan was made: %s; plan again", strings.Join(e.IDs, ", "))
+}
+
+// Unwrap makes the error match model.ErrConflict
+func (e *StalePlanError) Unwrap() error {
+	return model.ErrConflict
+}
+
+func (e *StalePlanError) ExitCode() int {
+	return model.ExitConflict
+}
+
+// WritePlan saves plan to path for target, to be applied until ttl
+// passes; a ttl of zero never expires
+func WritePlan(path string, plan *Plan, target string, ttl time.Duration) error {
+	saved := SavedPlan{
+		Version: planFileVersion,
+		Target:  target,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Plan:    plan,
+	}
+	if ttl > 0 {
+		saved.Expires = saved.Created.Add(ttl)
+	}
+	sum, err := saved.checksum()
+	if err != nil {
+		return err
+	}
+	saved.Checksum = sum
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
+
+// ReadPlan loads the plan saved at path, refusing one that was edited,
+// made against an API other than target, or past its expiry
+func ReadPlan(path, target string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+	var saved SavedPlan
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if saved.Version != planFileVersion {
+		return nil, fmt.Errorf("%s: unsupported plan version %d", path, saved.Version)
+	}
+	sum, err := saved.checksum()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case saved.Checksum != sum:
+		return nil, fmt.Errorf("%s: checksum mismatch; the plan was changed after it was made", path)
+	case saved.Target != target:
+		return nil, fmt.Errorf("%s: made against %s, not %s", path, saved.Target, target)
+	case !saved.Expires.IsZero() && time.Now().After(saved.Expires):
+		return nil, fmt.Errorf("%s: %w at %s", path, ErrPlanExpired, saved.Expires.Local().Format(time.DateTime))
+	case saved.Plan == nil:
+		return nil, fmt.Errorf("%s: no plan", path)
+	}
+	return saved.Plan, nil
+}
+
+// checksum returns the SHA-256 of the plan without its checksum
+func (p SavedPlan) checksum() (string, error) {
+	p.Checksum = ""
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", fmt.Errorf("encoding plan: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks that the live state of each resource in plan is still
+// what the plan was made against, returning a *StalePlanError naming
+// those that changed
+func (s *Service) Verify(ctx context.Context, plan *Plan) error {
+	var stale []string
+	for _, c := range plan.Changes {
+		live, err := s.backend.Get(ctx, c.Kind, c.Name)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return fmt.Errorf("reading %s: %w", c.ID(), err)
+		}
+		if liveChecksum(live) != c.LiveChecksum {
+			stale = append(stale, c.ID())
+		}
+	}
+	if len(stale) > 0 {
+		return &StalePlanError{IDs: stale}
+	}
+	return nil
+}
+
+// liveChecksum returns the SHA-256 of a resource's live state, or "" when
+// it has none
+func liveChecksum(live map[string]interface{}) string {
+	if live == nil {
+		return ""
+	}
+	// Maps are encoded with their keys sorted, so equal states match
+	data, err := json.Marshal(live)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,191 @@
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Resource is the desired state of one resource, as written in a file:
+//
+//	kind: users
+//	metadata:
+//	  name: ana
+//	  labels:
+//	    team: core
+//	spec:
+//	  email: ana@example.com
+//
+// Its labels and annotations are set in the labels and annotations fields
+// of the resource, beside those of its spec.
+type Resource struct {
+	Kind     string                 `json:"kind" yaml:"kind"`
+	Metadata Metadata               `json:"metadata" yaml:"metadata"`
+	Spec     map[string]interface{} `json:"spec" yaml:"spec"`
+
+	Source string `json:"-" yaml:"-"` // The file it was read from
+}
+
+// Metadata identifies a resource and labels it
+type Metadata struct {
+	Name        string            `json:"name" yaml:"name"`
+	Labels      model.Labels      `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// ID returns the resource's kind/name
+func (r Resource) ID() string {
+	return r.Kind + "/" + r.Metadata.Name
+}
+
+// Load reads resources from files: YAML files, which may hold several
+// documents separated by ---, and JSON files. A directory stands for the
+// .yaml, .yml, and .json files in it, in name order, and "-" for stdin.
+func Load(paths []string) ([]Resource, error) {
+	var resources []Resource
+	seen := map[string]string{}
+	for _, path := range paths {
+		files, err := expand(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			list, err := loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range list {
+				if prev, ok := seen[r.ID()]; ok {
+					return nil, model.NewValidationError(r.ID(), fmt.Sprintf("defined in both %s and %s", prev, file))
+				}
+				seen[r.ID()] = file
+				resources = append(resources, r)
+			}
+		}
+	}
+	return resources, nil
+}
+
+// expand returns the files path stands for
+func expand(path string) ([]string, error) {
+	if path == "-" {
+		return []string{path}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// loadFile reads the resources in file
+func loadFile(file string) ([]Resource, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML too
+	var resources []Resource
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 1; ; i++ {
+		var r Resource
+		if err := dec.Decode(&r); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", file, i, err)
+		}
+		if r.Kind == "" && r.Metadata.Name == "" && r.Spec == nil {
+			continue // An empty document
+		}
+		if err := r.normalize(); err != nil {
+			return nil, fmt.Errorf("%s: document %d: %w", file, i, err)
+		}
+		r.Source = file
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// normalize checks r and gives its spec the types JSON decodes to, so it
+// compares equal to the same state read from an API
+func (r *Resource) normalize() error {
+	switch {
+	case r.Kind == "":
+		return model.NewValidationError("kind", "kind is required")
+	case r.Metadata.Name == "":
+		return model.NewValidationError("metadata.name", "name is required")
+	case strings.Contains(r.Kind, "/") || strings.Contains(r.Metadata.Name, "/"):
+		return model.NewValidationError(r.ID(), "kind and name must not contain /")
+	}
+	if err := model.ValidateLabels("metadata.labels", r.Metadata.Labels); err != nil {
+		return err
+	}
+	if err := model.ValidateAnnotations("metadata.annotations", r.Metadata.Annotations); err != nil {
+		return err
+	}
+	if r.Spec == nil {
+		r.Spec = map[string]interface{}{}
+	}
+	for field, values := range map[string]map[string]string{"labels": r.Metadata.Labels, "annotations": r.Metadata.Annotations} {
+		if len(values) == 0 {
+			continue
+		}
+		if _, ok := r.Spec[field]; ok {
+			return model.NewValidationError("spec."+field, fmt.Sprintf("set %s in metadata.%s, not the spec", field, field))
+		}
+		r.Spec[field] = values
+	}
+	data, err := json.Marshal(r.Spec)
+	if err != nil {
+		return fmt.Errorf("%s: spec: %w", r.ID(), err)
+	}
+	return json.Unmarshal(data, &r.Spec)
+}
+
+// LabelsOf returns the labels of a live resource: its labels field, or
+// that of its metadata
+func LabelsOf(body map[string]interface{}) model.Labels {
+	raw, ok := body["labels"].(map[string]interface{})
+	if !ok {
+		if meta, isMap := body["metadata"].(map[string]interface{}); isMap {
+			raw, _ = meta["labels"].(map[string]interface{})
+		}
+	}
+	labels := make(model.Labels, len(raw))
+	for k, v := range raw {
+		labels[k] = fmt.Sprint(v)
+	}
+	return labels
+}
@@ -0,0 +1,140 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Lister is a Backend that can list the live resources of a kind whole,
+// saving Select reading each by name
+type Lister interface {
+	List(ctx context.Context, kind string) ([]map[string]interface{}, error)
+}
+
+// EventLister is a Backend that can list what happened to a resource, e.g.
+// its creation, restarts, and failures, each an object with fields such as
+// time, type, reason, and message
+type EventLister interface {
+	Events(ctx context.Context, kind, name string) ([]map[string]interface{}, error)
+}
+
+// ErrNoEvents is returned by an EventLister for an API that keeps no events
+var ErrNoEvents = errors.New("the backend has no events")
+
+// LiveResource is a live resource found by Select
+type LiveResource struct {
+	Kind   string                 `json:"kind" yaml:"kind"`
+	Name   string                 `json:"name" yaml:"name"`
+	Labels model.Labels           `json:"labels" yaml:"labels"`
+	Body   map[string]interface{} `json:"-" yaml:"-"`
+}
+
+// ID returns the resource's kind/name
+func (r LiveResource) ID() string {
+	return r.Kind + "/" + r.Name
+}
+
+// NameOf returns the name of a live resource: its name field, or its id
+func NameOf(body map[string]interface{}) string {
+	for _, field := range []string{"name", "id"} {
+		if v, ok := body[field]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// Select returns the live resources of kind whose labels sel matches, in
+// the order the backend lists them
+func (s *Service) Select(ctx context.Context, kind string, sel model.Selector) ([]LiveResource, error) {
+	var bodies []map[string]interface{}
+	if lister, ok := s.backend.(Lister); ok {
+		list, err := lister.List(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		bodies = list
+	} else {
+		names, err := s.backend.Names(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		for _, name := range names {
+			body, err := s.backend.Get(ctx, kind, name)
+			switch {
+			case errors.Is(err, model.ErrNotFound):
+				continue // Deleted since it was listed
+			case err != nil:
+				return nil, fmt.Errorf("reading %s/%s: %w", kind, name, err)
+			}
+			if NameOf(body) == "" {
+				body["name"] = name
+			}
+			bodies = append(bodies, body)
+		}
+	}
+
+	var selected []LiveResource
+	for _, body := range bodies {
+		r := LiveResource{Kind: kind, Name: NameOf(body), Labels: LabelsOf(body), Body: body}
+		if r.Name != "" && sel.Matches(r.Labels) {
+			selected = append(selected, r)
+		}
+	}
+	return selected, nil
+}
+
+// Get returns the live resource kind/name; one that doesn't exist is an
+// error wrapping model.ErrNotFound
+func (s *Service) Get(ctx context.Context, kind, name string) (map[string]interface{}, error) {
+	body, err := s.backend.Get(ctx, kind, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s/%s: %w", kind, name, err)
+	}
+	return body, nil
+}
+
+// Events returns the events of kind/name as the backend lists them, or
+// none when it keeps no events
+func (s *Service) Events(ctx context.Context, kind, name string) ([]map[string]interface{}, error) {
+	lister, ok := s.backend.(EventLister)
+	if !ok {
+		return nil, nil
+	}
+	events, err := lister.Events(ctx, kind, name)
+	switch {
+	case errors.Is(err, ErrNoEvents):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("listing events of %s/%s: %w", kind, name, err)
+	}
+	return events, nil
+}
+
+// Delete deletes the named resources of kind, one bulk item each, and
+// forgets the specs they were last applied with
+func (s *Service) Delete(ctx context.Context, kind string, names []string, opts bulk.Options) (*bulk.Report, error) {
+	items := make([]bulk.Item, len(names))
+	for i, name := range names {
+		id := kind + "/" + name
+		items[i] = bulk.Item{
+			ID: id,
+			Apply: func(ctx context.Context) error {
+				if err := s.backend.Delete(ctx, kind, name); err != nil {
+					return err
+				}
+				s.lastApplied.Delete(id)
+				return nil
+			},
+		}
+	}
+	report, err := bulk.NewRunner(opts).Run(ctx, items)
+	if saveErr := s.lastApplied.Save(context.WithoutCancel(ctx)); saveErr != nil {
+		return report, errors.Join(err, fmt.Errorf("saving last-applied state: %w", saveErr))
+	}
+	return report, err
+}
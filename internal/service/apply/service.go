@@ -0,0 +1,233 @@
+// Package apply brings live resources to the state declared in files, in
+// the manner of kubectl apply: it plans the resources to create, update,
+// and, when pruning, delete, shows the plan as diffs, and applies it.
+// Resources are read and changed through a Backend, such as HTTPBackend
+// for a REST API. With the specs last applied (see LastApplied), updates
+// are three-way merges that remove the fields dropped from a file and
+// report those changed elsewhere.
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/blacksilver/termplate-go/internal/bulk"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/update"
+)
+
+// Backend reads and changes the live resources, e.g. through an API or a
+// database
+type Backend interface {
+	// Get returns the live state of a resource, or an error wrapping
+	// model.ErrNotFound when there's none
+	Get(ctx context.Context, kind, name string) (map[string]interface{}, error)
+
+	// Names returns the names of the live resources of kind, for pruning
+	Names(ctx context.Context, kind string) ([]string, error)
+
+	Create(ctx context.Context, kind, name string, spec map[string]interface{}) error
+
+	// Update changes a resource with mutate, without overwriting a change
+	// made since it was read
+	Update(ctx context.Context, kind, name string, mutate update.Mutator) error
+
+	Delete(ctx context.Context, kind, name string) error
+}
+
+// DryRunner is a Backend that can show what a change would make of a
+// resource without making it, for server-side diffs
+type DryRunner interface {
+	// DryRunCreate returns the resource creating it with spec would make
+	DryRunCreate(ctx context.Context, kind, name string, spec map[string]interface{}) (map[string]interface{}, error)
+
+	// DryRunUpdate returns the resource replacing it with body would make
+	DryRunUpdate(ctx context.Context, kind, name string, body map[string]interface{}) (map[string]interface{}, error)
+}
+
+// ErrNoDryRun is returned by Plan for a server diff the backend can't make
+var ErrNoDryRun = errors.New("the backend has no dry run")
+
+// PlanOptions configures Plan
+type PlanOptions struct {
+	// Prune deletes the live resources of the kinds in the files that no
+	// file declares
+	Prune bool
+
+	// Selector limits the plan to the declared resources whose labels it
+	// matches, and pruning to the live resources it matches
+	Selector model.Selector
+
+	// ServerDiff shows each creation and update as the backend's dry run
+	// of it returns the resource, with defaults and computed fields the
+	// server fills in; the backend must be a DryRunner
+	ServerDiff bool
+}
+
+// ApplyOptions configures Apply
+type ApplyOptions struct {
+	bulk.Options
+
+	// ForceConflicts makes updates overwrite fields changed elsewhere since
+	// they were last applied, which otherwise fail with a
+	// *FieldConflictError
+	ForceConflicts bool
+}
+
+// Service plans and applies declared resources
+type Service struct {
+	backend     Backend
+	lastApplied *LastApplied
+}
+
+// NewService creates a service keeping resources in backend. lastApplied,
+// when not nil, has the specs last applied, and is updated by Apply; with
+// nil, updates only set the declared fields.
+func NewService(backend Backend, lastApplied *LastApplied) *Service {
+	return &Service{backend: backend, lastApplied: lastApplied}
+}
+
+// Plan compares resources with their live state and returns the changes
+// that make them match, in file order, with deletions last
+func (s *Service) Plan(ctx context.Context, resources []Resource, opts PlanOptions) (*Plan, error) {
+	dryRunner, ok := s.backend.(DryRunner)
+	if opts.ServerDiff && !ok {
+		return nil, ErrNoDryRun
+	}
+
+	plan := &Plan{}
+	var kinds []string
+	declared := map[string]bool{}
+	for _, r := range resources {
+		if !slices.Contains(kinds, r.Kind) {
+			kinds = append(kinds, r.Kind)
+		}
+		declared[r.ID()] = true
+		if !opts.Selector.Matches(r.Metadata.Labels) {
+			continue
+		}
+
+		c := Change{Kind: r.Kind, Name: r.Metadata.Name, Spec: r.Spec}
+		last := s.lastApplied.Get(r.ID())
+		live, err := s.backend.Get(ctx, r.Kind, r.Metadata.Name)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("reading %s: %w", r.ID(), err)
+		}
+		c.LiveChecksum = liveChecksum(live)
+		desired := threeWay(live, last, r.Spec)
+		switch {
+		case err != nil:
+			c.Action = ActionCreate
+			c.Diff = diffOf(r.ID(), nil, r.Spec)
+			if opts.ServerDiff {
+				result, err := dryRunner.DryRunCreate(ctx, r.Kind, r.Metadata.Name, r.Spec)
+				if err != nil {
+					return nil, fmt.Errorf("dry run of %s: %w", r.ID(), err)
+				}
+				c.Diff = serverDiffOf(r.ID(), nil, result)
+			}
+		case !reflect.DeepEqual(desired, live):
+			c.Action = ActionUpdate
+			c.Diff = diffOf(r.ID(), live, desired)
+			c.Conflicts = conflicts(live, last, r.Spec, "")
+			if opts.ServerDiff {
+				result, err := dryRunner.DryRunUpdate(ctx, r.Kind, r.Metadata.Name, desired)
+				if err != nil {
+					return nil, fmt.Errorf("dry run of %s: %w", r.ID(), err)
+				}
+				c.Diff = serverDiffOf(r.ID(), live, result)
+			}
+		default:
+			c.Action = ActionUnchanged
+		}
+		plan.Changes = append(plan.Changes, c)
+	}
+
+	if !opts.Prune {
+		return plan, nil
+	}
+	for _, kind := range kinds {
+		names, err := s.backend.Names(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", kind, err)
+		}
+		for _, name := range names {
+			c := Change{Kind: kind, Name: name, Action: ActionDelete}
+			if declared[c.ID()] {
+				continue
+			}
+			live, err := s.backend.Get(ctx, kind, name)
+			if err != nil && !errors.Is(err, model.ErrNotFound) {
+				return nil, fmt.Errorf("reading %s: %w", c.ID(), err)
+			}
+			if !opts.Selector.Matches(LabelsOf(live)) {
+				continue
+			}
+			c.Diff = diffOf(c.ID(), live, nil)
+			c.LiveChecksum = liveChecksum(live)
+			plan.Changes = append(plan.Changes, c)
+		}
+	}
+	return plan, nil
+}
+
+// Apply makes the changes in plan, one bulk item each, and reports how
+// each went. An update finding the resource changed since the plan was
+// made is merged over the latest version, as the Backend resolves it.
+// The spec of each resource created, updated, or found unchanged is
+// recorded as last applied, and saved with the report.
+func (s *Service) Apply(ctx context.Context, plan *Plan, opts ApplyOptions) (*bulk.Report, error) {
+	var items []bulk.Item
+	for _, c := range plan.Changes {
+		if c.Action == ActionUnchanged {
+			s.lastApplied.Set(c.ID(), c.Spec)
+			continue
+		}
+		items = append(items, bulk.Item{
+			ID: c.ID(),
+			Apply: func(ctx context.Context) error {
+				if err := s.change(ctx, c, opts.ForceConflicts); err != nil {
+					return err
+				}
+				if c.Action == ActionDelete {
+					s.lastApplied.Delete(c.ID())
+				} else {
+					s.lastApplied.Set(c.ID(), c.Spec)
+				}
+				return nil
+			},
+		})
+	}
+	report, err := bulk.NewRunner(opts.Options).Run(ctx, items)
+	// What was applied is recorded even when ctx ended mid-run
+	if saveErr := s.lastApplied.Save(context.WithoutCancel(ctx)); saveErr != nil {
+		return report, errors.Join(err, fmt.Errorf("saving last-applied state: %w", saveErr))
+	}
+	return report, err
+}
+
+// change makes one change
+func (s *Service) change(ctx context.Context, c Change, force bool) error {
+	switch c.Action {
+	case ActionCreate:
+		return s.backend.Create(ctx, c.Kind, c.Name, c.Spec)
+	case ActionUpdate:
+		last := s.lastApplied.Get(c.ID())
+		return s.backend.Update(ctx, c.Kind, c.Name, func(body map[string]interface{}) error {
+			// Checked against the version being changed, which may be newer
+			// than the plan's
+			if fields := conflicts(body, last, c.Spec, ""); len(fields) > 0 && !force {
+				return &FieldConflictError{ID: c.ID(), Fields: fields}
+			}
+			applyInto(body, last, c.Spec)
+			return nil
+		})
+	case ActionDelete:
+		return s.backend.Delete(ctx, c.Kind, c.Name)
+	default:
+		return fmt.Errorf("unknown action %q", c.Action)
+	}
+}
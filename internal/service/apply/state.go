@@ -0,0 +1,141 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+const (
+	// stateLockName serializes saves to the last-applied file
+	stateLockName = "apply-state"
+	// stateLockWait is how long to wait for another invocation's save
+	stateLockWait = 10 * time.Second
+)
+
+// LastApplied keeps the spec each resource was last applied with. Knowing
+// the fields an apply set lets the next one remove those since dropped
+// from its file and notice those someone else changed since: a three-way
+// merge of the last-applied, live, and declared states. The specs are
+// stored in the state directory, kept apart for each API.
+type LastApplied struct {
+	path  string
+	scope string
+	specs map[string]map[string]interface{} // By kind/name
+}
+
+// lastAppliedFile is the stored form: the specs of each API's resources
+type lastAppliedFile map[string]map[string]map[string]interface{}
+
+// LastAppliedPath returns the file the last-applied specs are stored in
+func LastAppliedPath() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "last-applied.json"), nil
+}
+
+// OpenLastApplied loads the specs last applied to the API scope names,
+// e.g. its base URL
+func OpenLastApplied(scope string) (*LastApplied, error) {
+	path, err := LastAppliedPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := readLastApplied(path)
+	if err != nil {
+		return nil, err
+	}
+	specs := f[scope]
+	if specs == nil {
+		specs = map[string]map[string]interface{}{}
+	}
+	return &LastApplied{path: path, scope: scope, specs: specs}, nil
+}
+
+// Get returns the spec id was last applied with, or nil when it wasn't.
+// A nil LastApplied has none.
+func (s *LastApplied) Get(id string) map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	return s.specs[id]
+}
+
+// Set records the spec id was applied with
+func (s *LastApplied) Set(id string, spec map[string]interface{}) {
+	if s != nil {
+		s.specs[id] = spec
+	}
+}
+
+// Delete forgets id, e.g. once it's deleted
+func (s *LastApplied) Delete(id string) {
+	if s != nil {
+		delete(s.specs, id)
+	}
+}
+
+// Save stores the specs, leaving those of other APIs as they are stored
+// now
+func (s *LastApplied) Save(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	l, err := lock.Acquire(ctx, stateLockName, stateLockWait)
+	if err != nil {
+		return fmt.Errorf("locking last-applied state: %w", err)
+	}
+	defer l.Release()
+
+	f, err := readLastApplied(s.path)
+	if err != nil {
+		return err
+	}
+	if len(s.specs) == 0 {
+		delete(f, s.scope)
+	} else {
+		f[s.scope] = s.specs
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding last-applied state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(s.path), err)
+	}
+	// Through a temporary file, so readers never see a partial write
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("replacing %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// readLastApplied loads the last-applied file; a missing file has no specs
+func readLastApplied(path string) (lastAppliedFile, error) {
+	f := lastAppliedFile{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return f, nil
+}
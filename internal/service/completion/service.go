@@ -0,0 +1,407 @@
+// Package completion installs shell completion scripts where each shell
+// loads them from, checks that they load, and removes them again.
+package completion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Shells lists the supported shells
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// shellTimeout bounds each shell started to find paths or verify a script
+const shellTimeout = 10 * time.Second
+
+// Result describes a completion script installed or removed
+type Result struct {
+	Shell    string `json:"shell"`
+	Path     string `json:"path,omitempty"`    // The completion script
+	Profile  string `json:"profile,omitempty"` // The profile changed to load it, for PowerShell
+	Verified bool   `json:"verified"`          // A new shell loaded the script
+	Note     string `json:"note,omitempty"`    // Anything left for the user to do
+}
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// Install writes script, the completion script for the program name, to
+// where shell loads completions from, then starts the shell to check the
+// script loads. A shell that isn't installed leaves the script unverified.
+func (s *Service) Install(ctx context.Context, shell, name string, script []byte) (*Result, error) {
+	res := &Result{Shell: shell}
+	var err error
+	switch shell {
+	case "bash":
+		res.Path, err = bashPath(name)
+	case "zsh":
+		var dir string
+		var onPath bool
+		dir, onPath, err = zshDir(ctx)
+		res.Path = filepath.Join(dir, "_"+name)
+		if err == nil && !onPath {
+			res.Note = fmt.Sprintf("add 'fpath=(%s $fpath)' to ~/.zshrc before compinit", dir)
+		}
+	case "fish":
+		res.Path, err = fishPath(name)
+	case "powershell":
+		res.Profile, err = powershellProfile(ctx)
+		res.Path = filepath.Join(filepath.Dir(res.Profile), name+"-completion.ps1")
+	default:
+		return nil, fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(res.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("creating completion directory: %w", err)
+	}
+	if err := os.WriteFile(res.Path, script, 0o644); err != nil {
+		return nil, fmt.Errorf("writing completion script: %w", err)
+	}
+	if shell == "powershell" {
+		if err := addProfileLine(res.Profile, profileLine(name, res.Path)); err != nil {
+			return nil, err
+		}
+	}
+
+	if shell == "bash" && !bashCompletionInstalled() {
+		res.Note = fmt.Sprintf("bash-completion isn't installed; install it, or add 'source %s' to ~/.bashrc", res.Path)
+	}
+
+	verified, err := verify(ctx, shell, name, res.Path)
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		res.addNote("not verified, as " + shellCommand(shell) + " wasn't found")
+	case err != nil:
+		return res, fmt.Errorf("verifying completion script: %w", err)
+	case !verified:
+		res.addNote("a new " + shell + " didn't load the script")
+	}
+	res.Verified = verified
+	return res, nil
+}
+
+// addNote adds note to r's notes
+func (r *Result) addNote(note string) {
+	if r.Note != "" {
+		note = r.Note + "; " + note
+	}
+	r.Note = note
+}
+
+// Uninstall removes the completion scripts Install may have written for
+// name, and the PowerShell profile line loading one. It returns nil when
+// there were none.
+func (s *Service) Uninstall(ctx context.Context, shell, name string) (*Result, error) {
+	var candidates []string
+	profile := ""
+	switch shell {
+	case "bash":
+		path, err := bashPath(name)
+		if err != nil {
+			return nil, err
+		}
+		candidates = []string{path}
+	case "zsh":
+		dirs, err := zshDirs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			candidates = append(candidates, filepath.Join(dir, "_"+name))
+		}
+	case "fish":
+		path, err := fishPath(name)
+		if err != nil {
+			return nil, err
+		}
+		candidates = []string{path}
+	case "powershell":
+		var err error
+		if profile, err = powershellProfile(ctx); err != nil {
+			return nil, err
+		}
+		candidates = []string{filepath.Join(filepath.Dir(profile), name+"-completion.ps1")}
+	default:
+		return nil, fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+
+	var res *Result
+	for _, path := range candidates {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("removing completion script: %w", err)
+		}
+		res = &Result{Shell: shell, Path: path}
+	}
+	if profile != "" {
+		removed, err := removeProfileLine(profile, profileMarker(name))
+		if err != nil {
+			return nil, err
+		}
+		if removed {
+			if res == nil {
+				res = &Result{Shell: shell}
+			}
+			res.Profile = profile
+		}
+	}
+	return res, nil
+}
+
+// bashPath returns the script's path in the user completions directory
+// bash-completion loads scripts from on demand
+func bashPath(name string) (string, error) {
+	dir, err := dataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bash-completion", "completions", name), nil
+}
+
+// bashCompletionScripts are where bash-completion is commonly installed
+var bashCompletionScripts = []string{
+	"/usr/share/bash-completion/bash_completion",
+	"/etc/bash_completion",
+	"/usr/local/share/bash-completion/bash_completion",
+	"/opt/homebrew/share/bash-completion/bash_completion",
+	"/usr/local/etc/profile.d/bash_completion.sh",
+}
+
+// bashCompletionInstalled reports whether bash-completion, which loads
+// the user completions directory, is installed
+func bashCompletionInstalled() bool {
+	for _, path := range bashCompletionScripts {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// fishPath returns the script's path in fish's user completions directory
+func fishPath(name string) (string, error) {
+	dir, err := configHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fish", "completions", name+".fish"), nil
+}
+
+// zshDir returns a directory for the script: the first writable fpath
+// directory of an interactive zsh under the home directory, or else
+// ~/.zfunc, which isn't on fpath (onPath is false) until the user adds it
+func zshDir(ctx context.Context) (dir string, onPath bool, err error) {
+	dirs, err := zshDirs(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	for _, dir := range dirs[:len(dirs)-1] {
+		if writable(dir) {
+			return dir, true, nil
+		}
+	}
+	fallback := dirs[len(dirs)-1]
+	return fallback, slices.Contains(dirs[:len(dirs)-1], fallback), nil
+}
+
+// zshDirs returns the fpath directories of an interactive zsh under the
+// home directory, followed by the ~/.zfunc fallback
+func zshDirs(ctx context.Context) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("finding home directory: %w", err)
+	}
+	zdotdir := os.Getenv("ZDOTDIR")
+	if zdotdir == "" {
+		zdotdir = home
+	}
+
+	var dirs []string
+	// Interactive, so .zshrc sets fpath as it does for the user
+	if out, err := runShell(ctx, "zsh", "", "-i", "-c", "print -rl -- $fpath"); err == nil {
+		for _, dir := range strings.Split(strings.TrimSpace(out), "\n") {
+			if strings.HasPrefix(dir, home+string(filepath.Separator)) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	return append(dirs, filepath.Join(zdotdir, ".zfunc")), nil
+}
+
+// writable reports whether dir is a directory the user can create files in
+func writable(dir string) bool {
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return false
+	}
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+	return true
+}
+
+// powershellProfile returns the current user's PowerShell profile, as
+// $PROFILE reports it, or else its default location
+func powershellProfile(ctx context.Context) (string, error) {
+	if out, err := runShell(ctx, "powershell", "", "-NoProfile", "-NonInteractive", "-Command", "$PROFILE"); err == nil {
+		if profile := strings.TrimSpace(out); profile != "" {
+			return profile, nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"), nil
+	}
+	dir, err := configHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "powershell", "Microsoft.PowerShell_profile.ps1"), nil
+}
+
+// profileMarker ends the profile line loading name's completions, so
+// Uninstall can find it
+func profileMarker(name string) string {
+	return "# " + name + " completion"
+}
+
+// profileLine dot-sources the script at path
+func profileLine(name, path string) string {
+	return ". '" + strings.ReplaceAll(path, "'", "''") + "' " + profileMarker(name)
+}
+
+// addProfileLine appends line to the profile unless it's already there
+func addProfileLine(profile, line string) error {
+	data, err := os.ReadFile(profile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading profile: %w", err)
+	}
+	if slices.Contains(strings.Split(string(data), "\n"), line) {
+		return nil
+	}
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, line+"\n"...)
+	if err := os.MkdirAll(filepath.Dir(profile), 0o755); err != nil {
+		return fmt.Errorf("creating profile directory: %w", err)
+	}
+	if err := os.WriteFile(profile, data, 0o644); err != nil {
+		return fmt.Errorf("writing profile: %w", err)
+	}
+	return nil
+}
+
+// removeProfileLine removes the profile lines ending with marker,
+// reporting whether there were any
+func removeProfileLine(profile, marker string) (bool, error) {
+	data, err := os.ReadFile(profile)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading profile: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	kept := slices.DeleteFunc(slices.Clone(lines), func(line string) bool {
+		return strings.HasSuffix(strings.TrimRight(line, "\r"), marker)
+	})
+	if len(kept) == len(lines) {
+		return false, nil
+	}
+	if err := os.WriteFile(profile, []byte(strings.Join(kept, "\n")), 0o644); err != nil {
+		return false, fmt.Errorf("writing profile: %w", err)
+	}
+	return true, nil
+}
+
+// verify starts a shell without the user's startup files that loads the
+// script at path and reports whether completion for name is registered
+func verify(ctx context.Context, shell, name, path string) (bool, error) {
+	var args []string
+	switch shell {
+	case "bash":
+		args = []string{"--norc", "--noprofile", "-c", `source "$COMPLETION_SCRIPT" && complete -p ` + name}
+	case "zsh":
+		args = []string{"-f", "-c", `fpath=("${COMPLETION_SCRIPT:h}" $fpath); autoload -U compinit && compinit -D -u && (( ${+_comps[` + name + `]} ))`}
+	case "fish":
+		args = []string{"--no-config", "-c", `source $COMPLETION_SCRIPT; and complete -c ` + name + ` | string length -q`}
+	case "powershell":
+		args = []string{"-NoProfile", "-NonInteractive", "-Command", `. $env:COMPLETION_SCRIPT`}
+	}
+	_, err := runShell(ctx, shell, path, args...)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// runShell runs shell with args and COMPLETION_SCRIPT set to script,
+// returning its output. PowerShell is pwsh, or Windows PowerShell.
+func runShell(ctx context.Context, shell, script string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, shellTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, shellCommand(shell), args...)
+	cmd.Env = append(os.Environ(), "COMPLETION_SCRIPT="+script)
+	out, err := cmd.Output()
+	if err != nil && shell == "powershell" && errors.Is(err, exec.ErrNotFound) && runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "powershell.exe", args...)
+		cmd.Env = append(os.Environ(), "COMPLETION_SCRIPT="+script)
+		out, err = cmd.Output()
+	}
+	return string(out), err
+}
+
+// shellCommand returns the executable of shell
+func shellCommand(shell string) string {
+	if shell == "powershell" {
+		return "pwsh"
+	}
+	return shell
+}
+
+// dataHome returns $XDG_DATA_HOME, default ~/.local/share
+func dataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// configHome returns $XDG_CONFIG_HOME, default ~/.config
+func configHome() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("finding home directory: %w", err)
+	}
+	return filepath.Join(home, ".config"), nil
+}
@@ -0,0 +1,337 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// LintOptions controls template linting
+type LintOptions struct {
+	Dir        string
+	Patterns   []string               // File name patterns to lint
+	Values     map[string]interface{} // Values to check variables against (nil skips the check)
+	Funcs      []string               // Extra function names available to templates
+	LeftDelim  string
+	RightDelim string
+}
+
+// Issue is a single problem found in a template
+type Issue struct {
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line" yaml:"line"`
+	Severity string `json:"severity" yaml:"severity"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// LintResult holds the outcome of linting a directory
+type LintResult struct {
+	Files  int     `json:"files" yaml:"files"`
+	Issues []Issue `json:"issues" yaml:"issues"`
+}
+
+// Errors returns the number of error-severity issues
+func (r *LintResult) Errors() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// builtinFuncs are the functions predefined by text/template
+var builtinFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or",
+	"print", "printf", "println", "urlquery",
+	"eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// LintTemplates parses every matching template under opts.Dir and reports problems
+func (s *Service) LintTemplates(ctx context.Context, opts LintOptions) (*LintResult, error) {
+	if opts.LeftDelim == "" {
+		opts.LeftDelim = "{{"
+	}
+	if opts.RightDelim == "" {
+		opts.RightDelim = "}}"
+	}
+
+	result := &LintResult{Issues: []Issue{}}
+	err := filepath.WalkDir(opts.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if d.IsDir() || !matchAny(opts.Patterns, d.Name()) {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		rel, err := filepath.Rel(opts.Dir, path)
+		if err != nil {
+			rel = path
+		}
+
+		slog.DebugContext(ctx, "linting template", "file", rel)
+		result.Files++
+		result.Issues = append(result.Issues, lintTemplate(rel, string(content), opts)...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking %s: %w", opts.Dir, err)
+	}
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		if result.Issues[i].File != result.Issues[j].File {
+			return result.Issues[i].File < result.Issues[j].File
+		}
+		return result.Issues[i].Line < result.Issues[j].Line
+	})
+
+	return result, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var parseErrRe = regexp.MustCompile(`^template: [^:]*:(\d+):(?:\d+:)? (.*)$`)
+
+// lintTemplate parses a single template and walks its trees
+func lintTemplate(name, content string, opts LintOptions) []Issue {
+	l := &linter{file: name, opts: opts, funcs: map[string]bool{}}
+	for _, f := range builtinFuncs {
+		l.funcs[f] = true
+	}
+	for _, f := range opts.Funcs {
+		l.funcs[f] = true
+	}
+
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	treeSet := map[string]*parse.Tree{}
+	if _, err := tree.Parse(content, opts.LeftDelim, opts.RightDelim, treeSet); err != nil {
+		l.addParseError(err)
+		return l.issues
+	}
+
+	root := value{v: opts.Values, known: opts.Values != nil}
+	names := make([]string, 0, len(treeSet))
+	for n := range treeSet {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		t := treeSet[n]
+		l.tree = t
+		l.root = root
+		l.walk(t.Root, root)
+	}
+
+	return l.issues
+}
+
+// value is a value resolved from the values file; known is false when
+// the linter cannot tell what dot refers to
+type value struct {
+	v     interface{}
+	known bool
+}
+
+type linter struct {
+	file   string
+	opts   LintOptions
+	funcs  map[string]bool
+	tree   *parse.Tree
+	root   value
+	issues []Issue
+}
+
+func (l *linter) add(node parse.Node, offset int, severity, msg string) {
+	line := 0
+	if node != nil {
+		loc, _ := l.tree.ErrorContext(node)
+		if parts := strings.Split(loc, ":"); len(parts) >= 2 {
+			line, _ = strconv.Atoi(parts[len(parts)-2])
+		}
+	}
+	l.issues = append(l.issues, Issue{
+		File:     l.file,
+		Line:     line + offset,
+		Severity: severity,
+		Message:  msg,
+	})
+}
+
+func (l *linter) addParseError(err error) {
+	issue := Issue{File: l.file, Severity: SeverityError, Message: err.Error()}
+	if m := parseErrRe.FindStringSubmatch(err.Error()); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+		issue.Message = m[2]
+	}
+	if strings.Contains(issue.Message, "unexpected EOF") {
+		issue.Message += " (missing {{end}}?)"
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *linter) walk(node parse.Node, dot value) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			l.walk(child, dot)
+		}
+	case *parse.TextNode:
+		l.checkText(n)
+	case *parse.ActionNode:
+		l.checkPipe(n.Pipe, dot)
+	case *parse.IfNode:
+		l.checkPipe(n.Pipe, dot)
+		l.walk(n.List, dot)
+		l.walk(n.ElseList, dot)
+	case *parse.RangeNode:
+		l.checkPipe(n.Pipe, dot)
+		l.walk(n.List, l.rangeElem(l.resolvePipe(n.Pipe, dot)))
+		l.walk(n.ElseList, dot)
+	case *parse.WithNode:
+		l.checkPipe(n.Pipe, dot)
+		l.walk(n.List, l.resolvePipe(n.Pipe, dot))
+		l.walk(n.ElseList, dot)
+	case *parse.TemplateNode:
+		l.checkPipe(n.Pipe, dot)
+	}
+}
+
+func (l *linter) checkPipe(pipe *parse.PipeNode, dot value) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			l.checkArg(arg, dot)
+		}
+	}
+}
+
+func (l *linter) checkArg(arg parse.Node, dot value) {
+	switch a := arg.(type) {
+	case *parse.IdentifierNode:
+		if !l.funcs[a.Ident] {
+			l.add(a, 0, SeverityError, fmt.Sprintf("function %q not defined", a.Ident))
+		}
+	case *parse.FieldNode:
+		l.lookup(a, dot, a.Ident, "")
+	case *parse.VariableNode:
+		if len(a.Ident) > 1 && a.Ident[0] == "$" {
+			l.lookup(a, l.root, a.Ident[1:], "$")
+		}
+	case *parse.ChainNode:
+		l.checkArg(a.Node, dot)
+	case *parse.PipeNode:
+		l.checkPipe(a, dot)
+	}
+}
+
+// lookup resolves a field path against v and reports the first missing key
+func (l *linter) lookup(node parse.Node, v value, idents []string, prefix string) value {
+	if !v.known {
+		return value{}
+	}
+	cur := v.v
+	for i, ident := range idents {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return value{}
+		}
+		next, ok := m[ident]
+		if !ok {
+			path := prefix + "." + strings.Join(idents[:i+1], ".")
+			l.add(node, 0, SeverityError, fmt.Sprintf("undefined variable %s", path))
+			return value{}
+		}
+		cur = next
+	}
+	return value{v: cur, known: true}
+}
+
+// resolvePipe returns the value of a pipeline when it is a single field reference
+func (l *linter) resolvePipe(pipe *parse.PipeNode, dot value) value {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return value{}
+	}
+	switch a := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return l.lookup(nil, dot, a.Ident, "")
+	case *parse.VariableNode:
+		if a.Ident[0] == "$" {
+			return l.lookup(nil, l.root, a.Ident[1:], "$")
+		}
+	}
+	return value{}
+}
+
+func (l *linter) rangeElem(v value) value {
+	if !v.known {
+		return value{}
+	}
+	if s, ok := v.v.([]interface{}); ok && len(s) > 0 {
+		return value{v: s[0], known: true}
+	}
+	return value{}
+}
+
+// suspiciousDelims are delimiters from other template languages
+var suspiciousDelims = []string{"{%", "%}", "<%", "%>"}
+
+func (l *linter) checkText(n *parse.TextNode) {
+	text := string(n.Text)
+
+	candidates := append([]string{l.opts.RightDelim}, suspiciousDelims...)
+	if l.opts.LeftDelim != "{{" {
+		candidates = append(candidates, "{{", "}}")
+	}
+
+	for _, delim := range candidates {
+		idx := strings.Index(text, delim)
+		if idx < 0 {
+			continue
+		}
+		offset := strings.Count(text[:idx], "\n")
+		msg := fmt.Sprintf("unexpected delimiter %q in text (wrong or unbalanced delimiters?)", delim)
+		l.add(n, offset, SeverityWarning, msg)
+	}
+}
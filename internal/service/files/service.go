@@ -0,0 +1,9 @@
+package files
+
+type Service struct {
+	// Add dependencies here (repositories, clients, etc.)
+}
+
+func NewService() *Service {
+	return &Service{}
+}
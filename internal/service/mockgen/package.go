@@ -0,0 +1,380 @@
+package mockgen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pkgInfo is a parsed package directory
+type pkgInfo struct {
+	name       string
+	importPath string
+	fset       *token.FileSet
+	types      map[string]typeDecl
+}
+
+// typeDecl is a type declaration and the imports of its file
+type typeDecl struct {
+	spec    *ast.TypeSpec
+	imports map[string]string // Name to import path
+}
+
+// loadPackage parses the non-test Go files in dir. It returns nil when the
+// directory has none.
+func loadPackage(dir string) (*pkgInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+
+	pkg := &pkgInfo{fset: token.NewFileSet(), types: map[string]typeDecl{}}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(pkg.fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, name), err)
+		}
+		if pkg.name == "" {
+			pkg.name = f.Name.Name
+		}
+		if f.Name.Name != pkg.name {
+			continue // e.g. a package main helper behind a build tag
+		}
+
+		imports := fileImports(f)
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				pkg.types[ts.Name.Name] = typeDecl{spec: ts, imports: imports}
+			}
+		}
+	}
+	if pkg.name == "" {
+		return nil, nil
+	}
+
+	pkg.importPath, err = importPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func fileImports(f *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		} else if guessed, ok := packageName(p); ok {
+			name = guessed
+		}
+		imports[name] = p
+	}
+	return imports
+}
+
+// packageName guesses the package name of an import path
+func packageName(importPath string) (string, bool) {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	return name, token.IsIdentifier(name)
+}
+
+// importPath derives the import path of dir from the nearest go.mod
+func importPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", dir, err)
+	}
+
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module, err := modulePath(data)
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", filepath.Join(root, "go.mod"), err)
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", fmt.Errorf("resolving %s: %w", dir, err)
+			}
+			if rel == "." {
+				return module, nil
+			}
+			return module + "/" + filepath.ToSlash(rel), nil
+		}
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("%s is not inside a Go module", dir)
+		}
+	}
+}
+
+func modulePath(gomod []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module directive")
+}
+
+// interfaces returns the exported interfaces to mock, sorted by name
+func (p *pkgInfo) interfaces(only []string) []string {
+	var names []string
+	for name, decl := range p.types {
+		if _, ok := decl.spec.Type.(*ast.InterfaceType); !ok || !ast.IsExported(name) {
+			continue
+		}
+		if len(only) > 0 && !slices.Contains(only, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// method is an interface method with types rewritten for the mock package
+type method struct {
+	Name     string
+	Params   []param
+	Results  []string
+	Variadic bool
+}
+
+type param struct {
+	Name  string
+	Field string // Field name in the call record
+	Type  string // Type in the call record
+	Sig   string // Type in the signature; differs from Type for variadic parameters
+}
+
+// methods collects the methods of an interface, including the methods of
+// embedded interfaces declared in the same package
+func (p *pkgInfo) methods(name string, imports map[string]string, seen map[string]bool) ([]method, error) {
+	if seen[name] {
+		return nil, nil
+	}
+	seen[name] = true
+
+	decl := p.types[name]
+	if decl.spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic interfaces are not supported")
+	}
+	iface := decl.spec.Type.(*ast.InterfaceType)
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.FuncType:
+			m, err := p.method(field.Names[0].Name, t, decl.imports, imports)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, m)
+		case *ast.Ident:
+			embedded, ok := p.types[t.Name]
+			if _, isIface := embedded.spec.Type.(*ast.InterfaceType); !ok || !isIface {
+				return nil, fmt.Errorf("embeds %s, which is not an interface in this package", t.Name)
+			}
+			inner, err := p.methods(t.Name, imports, seen)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, inner...)
+		default:
+			return nil, fmt.Errorf("embeds %s; embedding interfaces from other packages is not supported", exprString(field.Type))
+		}
+	}
+	return methods, nil
+}
+
+func (p *pkgInfo) method(name string, fn *ast.FuncType, fileImports, used map[string]string) (method, error) {
+	m := method{Name: name}
+	q := qualifier{pkg: p, fileImports: fileImports, used: used}
+
+	n := 0
+	for _, field := range fn.Params.List {
+		typ, err := q.qualify(field.Type)
+		if err != nil {
+			return m, fmt.Errorf("%s: %w", name, err)
+		}
+		sig := exprString(typ)
+		if ell, ok := typ.(*ast.Ellipsis); ok {
+			m.Variadic = true
+			typ = &ast.ArrayType{Elt: ell.Elt}
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{{Name: "_"}}
+		}
+		for _, id := range names {
+			pname := id.Name
+			if pname == "_" || pname == "m" {
+				pname = fmt.Sprintf("arg%d", n)
+			}
+			m.Params = append(m.Params, param{
+				Name:  pname,
+				Field: strings.ToUpper(pname[:1]) + pname[1:],
+				Type:  exprString(typ),
+				Sig:   sig,
+			})
+			n++
+		}
+	}
+
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			typ, err := q.qualify(field.Type)
+			if err != nil {
+				return m, fmt.Errorf("%s: %w", name, err)
+			}
+			count := max(1, len(field.Names))
+			for range count {
+				m.Results = append(m.Results, exprString(typ))
+			}
+		}
+	}
+	return m, nil
+}
+
+// qualifier rewrites type expressions from the interface's package so
+// they can be used from the mock package
+type qualifier struct {
+	pkg         *pkgInfo
+	fileImports map[string]string
+	used        map[string]string // Imports needed by the mock, name to path
+}
+
+func (q qualifier) qualify(expr ast.Expr) (ast.Expr, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(t.Name) != nil {
+			return t, nil
+		}
+		if !ast.IsExported(t.Name) {
+			return nil, fmt.Errorf("uses unexported type %s", t.Name)
+		}
+		q.used[q.pkg.name] = q.pkg.importPath
+		return &ast.SelectorExpr{X: ast.NewIdent(q.pkg.name), Sel: ast.NewIdent(t.Name)}, nil
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			p, ok := q.fileImports[x.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown package %s", x.Name)
+			}
+			q.used[x.Name] = p
+		}
+		return t, nil
+	case *ast.StarExpr:
+		x, err := q.qualify(t.X)
+		return &ast.StarExpr{X: x}, err
+	case *ast.ParenExpr:
+		return q.qualify(t.X)
+	case *ast.ArrayType:
+		elt, err := q.qualify(t.Elt)
+		return &ast.ArrayType{Len: t.Len, Elt: elt}, err
+	case *ast.Ellipsis:
+		elt, err := q.qualify(t.Elt)
+		return &ast.Ellipsis{Elt: elt}, err
+	case *ast.MapType:
+		key, err := q.qualify(t.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := q.qualify(t.Value)
+		return &ast.MapType{Key: key, Value: value}, err
+	case *ast.ChanType:
+		value, err := q.qualify(t.Value)
+		return &ast.ChanType{Dir: t.Dir, Value: value}, err
+	case *ast.FuncType:
+		params, err := q.fields(t.Params)
+		if err != nil {
+			return nil, err
+		}
+		results, err := q.fields(t.Results)
+		return &ast.FuncType{Params: params, Results: results}, err
+	case *ast.StructType:
+		fields, err := q.fields(t.Fields)
+		return &ast.StructType{Fields: fields}, err
+	case *ast.InterfaceType:
+		if len(t.Methods.List) > 0 {
+			return nil, fmt.Errorf("inline interface types are not supported")
+		}
+		return t, nil
+	case *ast.IndexExpr:
+		x, err := q.qualify(t.X)
+		if err != nil {
+			return nil, err
+		}
+		index, err := q.qualify(t.Index)
+		return &ast.IndexExpr{X: x, Index: index}, err
+	case *ast.IndexListExpr:
+		x, err := q.qualify(t.X)
+		if err != nil {
+			return nil, err
+		}
+		indices := make([]ast.Expr, len(t.Indices))
+		for i, index := range t.Indices {
+			if indices[i], err = q.qualify(index); err != nil {
+				return nil, err
+			}
+		}
+		return &ast.IndexListExpr{X: x, Indices: indices}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", expr)
+	}
+}
+
+func (q qualifier) fields(list *ast.FieldList) (*ast.FieldList, error) {
+	if list == nil {
+		return nil, nil
+	}
+	out := &ast.FieldList{}
+	for _, f := range list.List {
+		typ, err := q.qualify(f.Type)
+		if err != nil {
+			return nil, err
+		}
+		out.List = append(out.List, &ast.Field{Names: f.Names, Type: typ})
+	}
+	return out, nil
+}
+
+// exprString prints a type expression on one line, ignoring the positions
+// that rewritten expressions no longer have
+func exprString(expr ast.Expr) string {
+	return types.ExprString(expr)
+}
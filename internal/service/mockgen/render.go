@@ -0,0 +1,152 @@
+package mockgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+var mockTemplate = template.Must(template.New("mock").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`// Code generated by termplate generate mocks; DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+{{- range .StdImports }}
+	{{ . }}
+{{- end }}
+{{ range .Imports }}
+	{{ . }}
+{{- end }}
+)
+
+// {{ .Mock }} is a mock of {{ .Qualified }}. Set the Func fields to control
+// its behavior; calls are recorded for assertions.
+type {{ .Mock }} struct {
+{{- range .Methods }}
+	{{ .Name }}Func func({{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.Sig }}{{ end }}) ({{ join .Results ", " }})
+{{- end }}
+
+	mu    sync.Mutex
+	calls struct {
+{{- range .Methods }}
+		{{ .Name }} []{{ $.Mock }}{{ .Name }}Call
+{{- end }}
+	}
+}
+
+var _ {{ .Qualified }} = (*{{ .Mock }})(nil)
+{{ range .Methods }}
+// {{ $.Mock }}{{ .Name }}Call records a call to {{ .Name }}
+type {{ $.Mock }}{{ .Name }}Call struct {
+{{- range .Params }}
+	{{ .Field }} {{ .Type }}
+{{- end }}
+}
+
+// {{ .Name }} records the call and delegates to {{ .Name }}Func
+func (m *{{ $.Mock }}) {{ .Name }}({{ range $i, $p := .Params }}{{ if $i }}, {{ end }}{{ $p.Name }} {{ $p.Sig }}{{ end }}) ({{ join .Results ", " }}) {
+	m.mu.Lock()
+	m.calls.{{ .Name }} = append(m.calls.{{ .Name }}, {{ $.Mock }}{{ .Name }}Call{
+{{- range .Params }}{{ .Field }}: {{ .Name }}, {{ end -}}
+	})
+	m.mu.Unlock()
+
+	if m.{{ .Name }}Func == nil {
+		panic("{{ $.Mock }}.{{ .Name }}Func is nil but {{ .Name }} was called")
+	}
+	{{ if .Results }}return {{ end }}m.{{ .Name }}Func({{ .Args }})
+}
+
+// {{ .Name }}Calls returns the recorded calls to {{ .Name }}
+func (m *{{ $.Mock }}) {{ .Name }}Calls() []{{ $.Mock }}{{ .Name }}Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]{{ $.Mock }}{{ .Name }}Call(nil), m.calls.{{ .Name }}...)
+}
+{{ end }}`))
+
+type mockData struct {
+	Package    string
+	StdImports []string
+	Imports    []string
+	Mock       string
+	Qualified  string
+	Methods    []methodData
+}
+
+type methodData struct {
+	method
+	Args string
+}
+
+// render generates the mock source for an interface
+func (p *pkgInfo) render(iface, mockPkg string) ([]byte, error) {
+	used := map[string]string{}
+	methods, err := p.methods(iface, used, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	used[p.name] = p.importPath
+	if p.name == mockPkg {
+		return nil, fmt.Errorf("package %s has the same name as the mock package", p.name)
+	}
+
+	data := mockData{
+		Package:   mockPkg,
+		Mock:      iface + "Mock",
+		Qualified: p.name + "." + iface,
+	}
+
+	used["sync"] = "sync"
+	for name, importPath := range used {
+		spec := fmt.Sprintf("%q", importPath)
+		if guessed, ok := packageName(importPath); !ok || guessed != name {
+			spec = name + " " + spec
+		}
+		// Standard library paths have no dot in their first element
+		if first, _, _ := strings.Cut(importPath, "/"); strings.Contains(first, ".") {
+			data.Imports = append(data.Imports, spec)
+		} else {
+			data.StdImports = append(data.StdImports, spec)
+		}
+	}
+	for _, specs := range [][]string{data.StdImports, data.Imports} {
+		sort.Slice(specs, func(i, j int) bool {
+			return importSortKey(specs[i]) < importSortKey(specs[j])
+		})
+	}
+
+	for _, m := range methods {
+		args := make([]string, len(m.Params))
+		for i, p := range m.Params {
+			args[i] = p.Name
+		}
+		if m.Variadic {
+			args[len(args)-1] += "..."
+		}
+		data.Methods = append(data.Methods, methodData{method: m, Args: strings.Join(args, ", ")})
+	}
+
+	var buf bytes.Buffer
+	if err := mockTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("rendering mock: %w", err)
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting mock: %w", err)
+	}
+	return out, nil
+}
+
+// importSortKey orders imports by path, ignoring any alias
+func importSortKey(spec string) string {
+	if i := strings.Index(spec, `"`); i >= 0 {
+		return spec[i:]
+	}
+	return spec
+}
@@ -0,0 +1,158 @@
+// Package mockgen generates call-recording mocks for Go interfaces.
+package mockgen
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultPackage is the package name and directory of generated mocks
+const DefaultPackage = "mocks"
+
+type Service struct {
+	// Add dependencies here (repositories, clients, etc.)
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// Options selects the packages and interfaces to mock
+type Options struct {
+	Dirs       []string // Package directories; "dir/..." includes subdirectories
+	Interfaces []string // Interface names to mock; empty mocks every exported interface
+	Package    string   // Output package name and subdirectory (default "mocks")
+	DryRun     bool
+}
+
+// Mock is a generated mock file
+type Mock struct {
+	Interface string `json:"interface" yaml:"interface"`
+	File      string `json:"file" yaml:"file"`
+}
+
+// Skipped is an interface that couldn't be mocked
+type Skipped struct {
+	Interface string `json:"interface" yaml:"interface"`
+	Reason    string `json:"reason" yaml:"reason"`
+}
+
+// Result lists the generated and skipped mocks
+type Result struct {
+	Mocks   []Mock    `json:"mocks" yaml:"mocks"`
+	Skipped []Skipped `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// Generate writes a mock for each selected interface into a subdirectory
+// of the interface's package
+func (s *Service) Generate(ctx context.Context, opts Options) (*Result, error) {
+	if opts.Package == "" {
+		opts.Package = DefaultPackage
+	}
+
+	dirs, err := expandDirs(opts.Dirs, opts.Package)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, dir := range dirs {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+
+		pkg, err := loadPackage(dir)
+		if err != nil {
+			return nil, err
+		}
+		if pkg == nil {
+			continue
+		}
+
+		for _, iface := range pkg.interfaces(opts.Interfaces) {
+			qualified := pkg.name + "." + iface
+			src, err := pkg.render(iface, opts.Package)
+			if err != nil {
+				result.Skipped = append(result.Skipped, Skipped{Interface: qualified, Reason: err.Error()})
+				continue
+			}
+
+			file := filepath.Join(dir, opts.Package, fileName(iface))
+			result.Mocks = append(result.Mocks, Mock{Interface: qualified, File: file})
+			if opts.DryRun {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+				return nil, fmt.Errorf("creating %s: %w", filepath.Dir(file), err)
+			}
+			if err := os.WriteFile(file, src, 0o644); err != nil {
+				return nil, fmt.Errorf("writing %s: %w", file, err)
+			}
+			slog.DebugContext(ctx, "generated mock", "interface", qualified, "file", file)
+		}
+	}
+
+	return result, nil
+}
+
+// expandDirs resolves "dir/..." patterns into package directories, skipping
+// generated mock packages, testdata, vendor, and hidden directories
+func expandDirs(patterns []string, mockDir string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	var dirs []string
+	for _, pattern := range patterns {
+		root, recursive := strings.CutSuffix(filepath.ToSlash(pattern), "/...")
+		if root == "..." {
+			root, recursive = ".", true
+		}
+		root = filepath.FromSlash(root)
+		if !recursive {
+			dirs = append(dirs, root)
+			continue
+		}
+
+		err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if p != root && (name == mockDir || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, p)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walking %s: %w", root, err)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// fileName converts an interface name to a snake_case file name
+func fileName(iface string) string {
+	var b strings.Builder
+	runes := []rune(iface)
+	for i, r := range runes {
+		upper := r >= 'A' && r <= 'Z'
+		// Break before an upper-case letter that starts a new word:
+		// HTTPClient -> http_client
+		if upper && i > 0 && (runes[i-1] < 'A' || runes[i-1] > 'Z' || i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z') {
+			b.WriteByte('_')
+		}
+		b.WriteString(strings.ToLower(string(r)))
+	}
+	return b.String() + ".go"
+}
@@ -0,0 +1,37 @@
+package scaffold
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+)
+
+// Built-in scaffold sources, one directory per template
+//
+//go:embed all:templates
+var embedded embed.FS
+
+// Embedded returns the built-in scaffold source with the given name
+func Embedded(name string) (fs.FS, bool) {
+	if _, err := fs.Stat(embedded, "templates/"+name); err != nil {
+		return nil, false
+	}
+	sub, err := fs.Sub(embedded, "templates/"+name)
+	if err != nil {
+		return nil, false
+	}
+	return sub, true
+}
+
+// EmbeddedNames returns the names of the built-in scaffold sources
+func EmbeddedNames() []string {
+	entries, _ := fs.ReadDir(embedded, "templates")
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
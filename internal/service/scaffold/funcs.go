@@ -0,0 +1,112 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// FuncMap returns the functions available to scaffold templates
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"title":     title,
+		"trim":      strings.TrimSpace,
+		"snake":     func(s string) string { return strings.Join(lowerWords(s), "_") },
+		"kebab":     func(s string) string { return strings.Join(lowerWords(s), "-") },
+		"camel":     camel,
+		"pascal":    func(s string) string { return title(camel(s)) },
+		"replace":   func(old, repl, s string) string { return strings.ReplaceAll(s, old, repl) },
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"default": func(def, v interface{}) interface{} {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+	}
+}
+
+// FuncNames returns the sorted names of the scaffold template functions
+func FuncNames() []string {
+	names := make([]string, 0, len(FuncMap()))
+	for name := range FuncMap() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderString executes s as a template against vars
+func renderString(s string, vars map[string]interface{}) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	t, err := template.New("").Funcs(FuncMap()).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("executing template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func camel(s string) string {
+	words := lowerWords(s)
+	for i := 1; i < len(words); i++ {
+		words[i] = title(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// lowerWords splits s on separators and case changes, e.g. "myHTTPServer-v2"
+// becomes [my http server v2]
+func lowerWords(s string) []string {
+	var words []string
+	var cur []rune
+	runes := []rune(s)
+
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, strings.ToLower(string(cur)))
+			cur = nil
+		}
+	}
+
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(cur) > 0:
+			prevLower := unicode.IsLower(cur[len(cur)-1]) || unicode.IsDigit(cur[len(cur)-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(cur[len(cur)-1]) && nextLower) {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+
+	return words
+}
@@ -0,0 +1,170 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// TemplateSuffix marks files whose content is rendered; the suffix is
+// stripped from the generated file name. Other files are copied verbatim.
+const TemplateSuffix = ".tmpl"
+
+// renderedFile is a generated file held in memory
+type renderedFile struct {
+	Path    string // Slash-separated path relative to the destination
+	Content []byte
+	Mode    fs.FileMode
+}
+
+// renderAll renders every file of src with vars, rendering templated paths
+// and .tmpl files. A path that renders to an empty segment is skipped,
+// which lets sources include files conditionally, and so are the excluded
+// source paths of unselected subsystems.
+func renderAll(ctx context.Context, src fs.FS, vars map[string]interface{}, exclude []string) ([]renderedFile, error) {
+	var files []renderedFile
+
+	err := fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if p == "." || p == ManifestFile {
+			return nil
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return fs.SkipDir
+		}
+		if isExcluded(p, exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		target, ok, err := renderPath(p, vars)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		out, err := renderFile(src, p, vars)
+		if err != nil {
+			return err
+		}
+		target = strings.TrimSuffix(target, TemplateSuffix)
+		if strings.HasSuffix(p, TemplateSuffix) && strings.HasSuffix(target, ".go") {
+			out = tidyGo(out)
+		}
+		files = append(files, renderedFile{
+			Path:    target,
+			Content: out,
+			Mode:    fileMode(d),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rendering templates: %w", err)
+	}
+
+	return files, nil
+}
+
+// writeAll writes rendered files into dest and returns their paths
+func writeAll(dest string, files []renderedFile, force bool) ([]string, error) {
+	created := make([]string, 0, len(files))
+	for _, f := range files {
+		if err := writeFile(filepath.Join(dest, filepath.FromSlash(f.Path)), f.Content, f.Mode, force); err != nil {
+			return created, err
+		}
+		created = append(created, f.Path)
+	}
+	return created, nil
+}
+
+// renderPath renders each templated segment of p; ok is false when a
+// segment renders empty
+func renderPath(p string, vars map[string]interface{}) (string, bool, error) {
+	if !strings.Contains(p, "{{") {
+		return p, true, nil
+	}
+
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		out, err := renderString(seg, vars)
+		if err != nil {
+			return "", false, fmt.Errorf("rendering path %s: %w", p, err)
+		}
+		// "{{if .X}}name{{end}}.tmpl" leaves only the suffix when skipped
+		if strings.TrimSpace(strings.TrimSuffix(out, TemplateSuffix)) == "" {
+			return "", false, nil
+		}
+		segments[i] = out
+	}
+	return path.Join(segments...), true, nil
+}
+
+func renderFile(src fs.FS, p string, vars map[string]interface{}) ([]byte, error) {
+	content, err := fs.ReadFile(src, p)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", p, err)
+	}
+	if !strings.HasSuffix(p, TemplateSuffix) {
+		return content, nil
+	}
+
+	t, err := template.New(p).Funcs(FuncMap()).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", p, err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("executing %s: %w", p, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeFile(target string, content []byte, mode fs.FileMode, force bool) error {
+	if _, err := os.Stat(target); err == nil && !force {
+		return model.NewOperationError("create", "file", target, model.ErrAlreadyExists)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("checking %s: %w", target, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", target, err)
+	}
+	if err := os.WriteFile(target, content, mode); err != nil {
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	return nil
+}
+
+func fileMode(d fs.DirEntry) fs.FileMode {
+	info, err := d.Info()
+	if err != nil || info.Mode().Perm() == 0 {
+		return 0o644
+	}
+	// Keep the executable bit but always make generated files owner-writable
+	return info.Mode().Perm() | 0o200
+}
@@ -0,0 +1,156 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/limits"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Hook is a post-generation step declared in the manifest
+type Hook struct {
+	Name  string `yaml:"name"`
+	Step  string `yaml:"step"`  // Built-in step: go-mod-tidy, gofmt, git-init
+	Shell string `yaml:"shell"` // Custom shell command; always needs confirmation
+	If    string `yaml:"if"`    // Template condition, e.g. "{{ .WithGit }}"
+}
+
+// Hook statuses
+const (
+	HookOK      = "ok"
+	HookFailed  = "failed"
+	HookSkipped = "skipped"
+)
+
+// HookResult is the outcome of a single hook
+type HookResult struct {
+	Name     string        `json:"name" yaml:"name"`
+	Status   string        `json:"status" yaml:"status"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Reason   string        `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// builtinSteps maps built-in step names to the commands they run
+var builtinSteps = map[string][]string{
+	"go-mod-tidy": {"go", "mod", "tidy"},
+	"gofmt":       {"gofmt", "-w", "."},
+	"git-init":    {"git", "init", "--quiet"},
+}
+
+func (h Hook) validate() error {
+	field := "hooks." + h.Name
+	if (h.Step == "") == (h.Shell == "") {
+		return model.NewValidationError(field, "set exactly one of step or shell")
+	}
+	if h.Step != "" {
+		if _, ok := builtinSteps[h.Step]; !ok {
+			return model.NewValidationError(field, fmt.Sprintf("unknown step %q (valid: go-mod-tidy, gofmt, git-init)", h.Step))
+		}
+	}
+	return nil
+}
+
+func (h Hook) label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if h.Step != "" {
+		return h.Step
+	}
+	return h.Shell
+}
+
+// hookOptions controls how hooks run
+type hookOptions struct {
+	Dir      string
+	Vars     map[string]interface{}
+	Output   io.Writer
+	Prompter Prompter
+	Trust    bool // Run shell hooks without asking
+}
+
+// runHooks runs each hook in order, streaming its output. A failed hook
+// doesn't stop the remaining hooks; every outcome is reported.
+func runHooks(ctx context.Context, hooks []Hook, opts hookOptions) []HookResult {
+	results := make([]HookResult, 0, len(hooks))
+	for _, h := range hooks {
+		results = append(results, runHook(ctx, h, opts))
+	}
+	return results
+}
+
+func runHook(ctx context.Context, h Hook, opts hookOptions) HookResult {
+	res := HookResult{Name: h.label()}
+
+	if h.If != "" {
+		cond, err := renderString(h.If, opts.Vars)
+		if err != nil {
+			res.Status, res.Reason = HookFailed, err.Error()
+			return res
+		}
+		if ok, _ := strconv.ParseBool(strings.TrimSpace(cond)); !ok {
+			res.Status, res.Reason = HookSkipped, "condition not met"
+			return res
+		}
+	}
+
+	args := builtinSteps[h.Step]
+	if h.Shell != "" {
+		script, err := renderString(h.Shell, opts.Vars)
+		if err != nil {
+			res.Status, res.Reason = HookFailed, err.Error()
+			return res
+		}
+		if ok, reason := confirmShell(script, opts); !ok {
+			res.Status, res.Reason = HookSkipped, reason
+			return res
+		}
+		args = shellArgs(script)
+	}
+
+	fmt.Fprintf(opts.Output, "==> %s\n", res.Name)
+	start := time.Now()
+	cmd := limits.Command(ctx, args[0], args[1:]...)
+	cmd.Dir = opts.Dir
+	cmd.Stdout = opts.Output
+	cmd.Stderr = opts.Output
+	err := cmd.Run()
+	res.Duration = time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		res.Status, res.Reason = HookFailed, err.Error()
+		return res
+	}
+	res.Status = HookOK
+	return res
+}
+
+func confirmShell(script string, opts hookOptions) (bool, string) {
+	if opts.Trust {
+		return true, ""
+	}
+	if opts.Prompter == nil {
+		return false, "shell hooks need confirmation (use --yes)"
+	}
+	ok, err := opts.Prompter.Confirm(fmt.Sprintf("Run shell hook %q?", script), false)
+	if err != nil {
+		return false, err.Error()
+	}
+	if !ok {
+		return false, "declined"
+	}
+	return true, ""
+}
+
+func shellArgs(script string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", script}
+	}
+	return []string{"sh", "-c", script}
+}
@@ -0,0 +1,111 @@
+package scaffold
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var majorVersionRe = regexp.MustCompile(`^v[0-9]+$`)
+
+// tidyGo removes the imports a rendered Go file doesn't use, so templates
+// can import packages unconditionally and only gate the code that uses
+// them, and gofmts the result, which realigns fields that template
+// conditions left ragged. Files that don't parse are returned unchanged.
+func tidyGo(src []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	// Any selector on a matching identifier counts as a use; a local
+	// variable shadowing the package name only keeps an import around
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	removed := false
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		specs := gen.Specs[:0]
+		for _, spec := range gen.Specs {
+			name, ok := importName(spec.(*ast.ImportSpec))
+			if ok && !used[name] {
+				removed = true
+				continue
+			}
+			specs = append(specs, spec)
+		}
+		gen.Specs = specs
+	}
+	if !removed {
+		if out, err := format.Source(src); err == nil {
+			return out
+		}
+		return src
+	}
+
+	decls := f.Decls[:0]
+	for _, decl := range f.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && len(gen.Specs) == 0 {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	f.Decls = decls
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return src
+	}
+	// Removed specs leave their lines behind; formatting again collapses them
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes()
+	}
+	return out
+}
+
+// importName returns the name an import is referred to by. ok is false for
+// blank and dot imports and for paths whose package name can't be guessed.
+func importName(spec *ast.ImportSpec) (string, bool) {
+	if spec.Name != nil {
+		if spec.Name.Name == "_" || spec.Name.Name == "." {
+			return "", false
+		}
+		return spec.Name.Name, true
+	}
+
+	p, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return "", false
+	}
+	name := path.Base(p)
+	if majorVersionRe.MatchString(name) && strings.Contains(p, "/") {
+		name = path.Base(path.Dir(p))
+	}
+	// gopkg.in/yaml.v3 is package yaml; github.com/x/go-foo is usually foo
+	name, _, _ = strings.Cut(name, ".")
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(name, "-go")
+	if !identRe.MatchString(name) {
+		return "", false
+	}
+	return name, true
+}
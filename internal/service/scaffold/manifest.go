@@ -0,0 +1,92 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// ManifestFile is the name of the manifest at the root of a scaffold source
+const ManifestFile = "template.yaml"
+
+// Manifest describes a scaffold source and the variables it needs
+type Manifest struct {
+	Name        string      `yaml:"name"`
+	Description string      `yaml:"description"`
+	Version     string      `yaml:"version"`
+	Variables   []Variable  `yaml:"variables"`
+	Hooks       []Hook      `yaml:"hooks"`      // Post-generation steps, run in order
+	Subsystems  []Subsystem `yaml:"subsystems"` // Optional parts users can leave out
+}
+
+// Variable declares a value consumed by the templates of a scaffold source
+type Variable struct {
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type"`     // string (default), bool, int
+	Default  string   `yaml:"default"`  // May reference earlier variables, e.g. "{{ .Name | kebab }}"
+	Prompt   string   `yaml:"prompt"`   // Question shown when prompting interactively
+	Validate string   `yaml:"validate"` // Regular expression the value must match
+	Choices  []string `yaml:"choices"`  // Allowed values
+	Required bool     `yaml:"required"`
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LoadManifest reads the manifest from the root of src.
+// Sources without a manifest get an empty one.
+func LoadManifest(src fs.FS) (*Manifest, error) {
+	data, err := fs.ReadFile(src, ManifestFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ManifestFile, err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", ManifestFile, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that the manifest is well formed
+func (m *Manifest) Validate() error {
+	seen := map[string]bool{}
+	for _, v := range m.Variables {
+		field := "variables." + v.Name
+		if !identRe.MatchString(v.Name) {
+			return model.NewValidationError("variables", fmt.Sprintf("invalid variable name %q", v.Name))
+		}
+		if seen[v.Name] {
+			return model.NewValidationError(field, "declared more than once")
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case "", "string", "bool", "int":
+		default:
+			return model.NewValidationError(field, fmt.Sprintf("unknown type %q (valid: string, bool, int)", v.Type))
+		}
+		if v.Validate != "" {
+			if _, err := regexp.Compile(v.Validate); err != nil {
+				return model.NewValidationError(field, fmt.Sprintf("invalid validation regex: %v", err))
+			}
+		}
+	}
+
+	for _, h := range m.Hooks {
+		if err := h.validate(); err != nil {
+			return err
+		}
+	}
+	return m.validateSubsystems()
+}
@@ -0,0 +1,131 @@
+package scaffold
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/pkg/version"
+)
+
+// RecordDir holds the generation record and the pristine generated files
+// ("base" copies) that upgrades use for three-way merges. Go tooling
+// ignores directories starting with a dot, so the copies never build.
+const RecordDir = ".termplate"
+
+const (
+	recordFile = "manifest.yaml"
+	baseDir    = "base"
+)
+
+// Record describes how a project was generated
+type Record struct {
+	Template   string                 `yaml:"template"`
+	Source     string                 `yaml:"source"`
+	Version    string                 `yaml:"version"`
+	Generator  string                 `yaml:"generator"`
+	Generated  time.Time              `yaml:"generated"`
+	Builtins   map[string]interface{} `yaml:"builtins"`
+	Vars       map[string]interface{} `yaml:"vars"`
+	Subsystems []string               `yaml:"subsystems"`
+	Files      map[string]string      `yaml:"files"` // Path to SHA-256 of the generated content
+}
+
+func newRecord(from string, m *Manifest, vars, builtins map[string]interface{}, subsystems []string) *Record {
+	rec := &Record{
+		Template:   m.Name,
+		Source:     from,
+		Version:    m.Version,
+		Generator:  version.Version,
+		Generated:  time.Now().UTC().Truncate(time.Second),
+		Builtins:   builtins,
+		Vars:       map[string]interface{}{},
+		Subsystems: subsystems,
+		Files:      map[string]string{},
+	}
+	if rec.Subsystems == nil {
+		rec.Subsystems = []string{}
+	}
+	for _, v := range m.Variables {
+		rec.Vars[v.Name] = vars[v.Name]
+	}
+	return rec
+}
+
+// LoadRecord reads the generation record of the project in dir
+func LoadRecord(dir string) (*Record, error) {
+	data, err := os.ReadFile(filepath.Join(dir, RecordDir, recordFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, model.NewOperationError("load", "generation record", dir, model.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading generation record: %w", err)
+	}
+
+	var rec Record
+	if err := yaml.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parsing generation record: %w", err)
+	}
+	if rec.Files == nil {
+		rec.Files = map[string]string{}
+	}
+	return &rec, nil
+}
+
+// save stores base copies of files and writes the record
+func (r *Record) save(dir string, files []renderedFile) error {
+	for _, f := range files {
+		if err := r.setBase(dir, f); err != nil {
+			return err
+		}
+	}
+	return r.write(dir)
+}
+
+func (r *Record) setBase(dir string, f renderedFile) error {
+	target := filepath.Join(dir, RecordDir, baseDir, filepath.FromSlash(f.Path))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("creating base directory: %w", err)
+	}
+	if err := os.WriteFile(target, f.Content, 0o644); err != nil {
+		return fmt.Errorf("writing base copy of %s: %w", f.Path, err)
+	}
+	r.Files[f.Path] = hash(f.Content)
+	return nil
+}
+
+func (r *Record) write(dir string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encoding generation record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, RecordDir, recordFile), data, 0o644); err != nil {
+		return fmt.Errorf("writing generation record: %w", err)
+	}
+	return nil
+}
+
+// base returns the pristine generated content of path, if recorded
+func (r *Record) base(dir, path string) ([]byte, bool) {
+	if _, ok := r.Files[path]; !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, RecordDir, baseDir, filepath.FromSlash(path)))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
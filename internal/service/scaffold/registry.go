@@ -0,0 +1,233 @@
+package scaffold
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// RegistryFile is the name of the registry file in the config directory
+const RegistryFile = "templates.yaml"
+
+var templateNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Entry is a named scaffold source registered by the user
+type Entry struct {
+	Name        string `yaml:"name" json:"name"`
+	Source      string `yaml:"source" json:"source"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// ListEntry describes a template available to the generators
+type ListEntry struct {
+	Name        string `json:"name" yaml:"name" table:"Name"`
+	Kind        string `json:"kind" yaml:"kind" table:"Kind"`
+	Source      string `json:"source" yaml:"source" table:"Source"`
+	Description string `json:"description" yaml:"description" table:"Description"`
+}
+
+// Registry maps template names to sources and caches git checkouts
+type Registry struct {
+	path     string
+	cacheDir string
+	entries  []Entry
+}
+
+// OpenRegistry loads the user's registry from the config directory
+func OpenRegistry() (*Registry, error) {
+	configDir, err := paths.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := paths.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadRegistry(filepath.Join(configDir, RegistryFile), filepath.Join(cacheDir, "templates"))
+}
+
+// LoadRegistry loads a registry file; a missing file is an empty registry
+func LoadRegistry(path, cacheDir string) (*Registry, error) {
+	r := &Registry{path: path, cacheDir: cacheDir}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading registry: %w", err)
+	}
+
+	var file struct {
+		Templates []Entry `yaml:"templates"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing registry %s: %w", path, err)
+	}
+	r.entries = file.Templates
+	return r, nil
+}
+
+// Save writes the registry file
+func (r *Registry) Save() error {
+	data, err := yaml.Marshal(map[string][]Entry{"templates": r.entries})
+	if err != nil {
+		return fmt.Errorf("encoding registry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return fmt.Errorf("creating registry directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o600); err != nil {
+		return fmt.Errorf("writing registry: %w", err)
+	}
+	return nil
+}
+
+// Add registers a named source, fetching it to make sure it is usable
+func (r *Registry) Add(ctx context.Context, e Entry) error {
+	if !templateNameRe.MatchString(e.Name) {
+		return model.NewValidationError("name", fmt.Sprintf("invalid template name %q", e.Name))
+	}
+	if _, ok := Embedded(e.Name); ok {
+		return model.NewValidationError("name", fmt.Sprintf("%q is a built-in template", e.Name))
+	}
+	if r.find(e.Name) >= 0 {
+		return model.NewOperationError("add", "template", e.Name, model.ErrAlreadyExists)
+	}
+
+	src := ParseSource(e.Source)
+	if src.Kind == KindLocal {
+		abs, err := filepath.Abs(src.Location)
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", src.Location, err)
+		}
+		e.Source = abs
+		src.Location = abs
+	}
+	fsys, err := src.open(ctx, r.cacheDir, true)
+	if err != nil {
+		return err
+	}
+	if _, err := LoadManifest(fsys); err != nil {
+		return fmt.Errorf("checking template: %w", err)
+	}
+
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+// Remove unregisters a named source
+func (r *Registry) Remove(name string) error {
+	i := r.find(name)
+	if i < 0 {
+		return model.NewOperationError("remove", "template", name, model.ErrNotFound)
+	}
+	r.entries = slices.Delete(r.entries, i, i+1)
+	return nil
+}
+
+// Update re-fetches the named git sources, or all of them when names is empty.
+// It returns the names that were updated.
+func (r *Registry) Update(ctx context.Context, names []string) ([]string, error) {
+	for _, name := range names {
+		if r.find(name) < 0 {
+			return nil, model.NewOperationError("update", "template", name, model.ErrNotFound)
+		}
+	}
+
+	if err := offline.Check("fetching git templates"); err != nil {
+		return nil, err
+	}
+
+	var updated []string
+	for _, e := range r.entries {
+		if len(names) > 0 && !slices.Contains(names, e.Name) {
+			continue
+		}
+		src := ParseSource(e.Source)
+		if src.Kind != KindGit {
+			continue
+		}
+		if _, err := src.open(ctx, r.cacheDir, true); err != nil {
+			return updated, fmt.Errorf("updating %s: %w", e.Name, err)
+		}
+		updated = append(updated, e.Name)
+	}
+	return updated, nil
+}
+
+// List returns the built-in and registered templates
+func (r *Registry) List() []ListEntry {
+	var list []ListEntry
+	for _, name := range EmbeddedNames() {
+		src, _ := Embedded(name)
+		m, _ := LoadManifest(src)
+		var desc string
+		if m != nil {
+			desc = m.Description
+		}
+		list = append(list, ListEntry{Name: name, Kind: KindEmbedded, Source: "(built-in)", Description: desc})
+	}
+
+	registered := make([]ListEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		registered = append(registered, ListEntry{
+			Name:        e.Name,
+			Kind:        ParseSource(e.Source).Kind,
+			Source:      e.Source,
+			Description: e.Description,
+		})
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name < registered[j].Name })
+
+	return append(list, registered...)
+}
+
+// Resolve opens the source for a --from value: a registered name, a
+// built-in template name, a git URL, or a local directory
+func (r *Registry) Resolve(ctx context.Context, from string) (fs.FS, error) {
+	if i := r.find(from); i >= 0 {
+		from = r.entries[i].Source
+	}
+	return ParseSource(from).open(ctx, r.cacheDir, false)
+}
+
+// ResolveLatest is like Resolve but re-fetches git sources first
+func (r *Registry) ResolveLatest(ctx context.Context, from string) (fs.FS, error) {
+	if i := r.find(from); i >= 0 {
+		from = r.entries[i].Source
+	}
+	return ParseSource(from).open(ctx, r.cacheDir, true)
+}
+
+// Canonical returns a --from value that resolves to the same source from
+// any working directory
+func (r *Registry) Canonical(from string) string {
+	if r.find(from) >= 0 {
+		return from
+	}
+	src := ParseSource(from)
+	if src.Kind != KindLocal {
+		return from
+	}
+	if abs, err := filepath.Abs(src.Location); err == nil {
+		return abs
+	}
+	return from
+}
+
+func (r *Registry) find(name string) int {
+	return slices.IndexFunc(r.entries, func(e Entry) bool { return e.Name == name })
+}
@@ -0,0 +1,447 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RenameRequest describes a module path and binary rename
+type RenameRequest struct {
+	Dir       string
+	NewPath   string
+	OldPaths  []string // Extra import paths to rewrite, besides go.mod's and the detected ones
+	Binary    string   // New binary name; empty keeps the current one
+	OldBinary string   // Current binary name; detected when empty
+	DryRun    bool
+}
+
+// RenamedFile is a file the rename changes
+type RenamedFile struct {
+	Path    string `json:"path" yaml:"path"`
+	Changes int    `json:"changes" yaml:"changes"`
+	NewPath string `json:"new_path,omitempty" yaml:"new_path,omitempty"` // Set when the file itself is renamed
+}
+
+// RenameResult describes a rename
+type RenameResult struct {
+	OldPath   string        `json:"old_path" yaml:"old_path"`
+	NewPath   string        `json:"new_path" yaml:"new_path"`
+	Aliases   []string      `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	OldBinary string        `json:"old_binary,omitempty" yaml:"old_binary,omitempty"`
+	NewBinary string        `json:"new_binary,omitempty" yaml:"new_binary,omitempty"`
+	Files     []RenamedFile `json:"files" yaml:"files"`
+}
+
+// textFiles are rewritten along with Go sources
+var textFiles = []string{"*.md", "*.yml", "*.yaml", "*.tmpl", "*.sh", "*.txt", "Makefile", "Taskfile*", "Dockerfile*", "go.mod"}
+
+var (
+	rootUseRe     = regexp.MustCompile(`rootCmd\s*=\s*&cobra\.Command\{\s*Use:\s*"([^"\s]+)`)
+	binaryVarRe   = regexp.MustCompile(`(?m)^BINARY_NAME\s*:?=\s*(\S+)`)
+	envPrefixRe   = regexp.MustCompile(`SetEnvPrefix\("[^"]*"\)`)
+	configNameRe  = regexp.MustCompile(`SetConfigName\("[^"]*"\)`)
+	nonIdentRunes = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// RenameModule rewrites the module path in go.mod and every import, and
+// optionally renames the binary: cobra Use strings, help text, the
+// environment variable prefix, and config file names. Import paths that
+// point into the project under a different prefix (left over from forks
+// and copies) are detected and rewritten too.
+func (s *Service) RenameModule(ctx context.Context, req RenameRequest) (*RenameResult, error) {
+	oldPath, err := ModulePath(req.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	goFiles, otherFiles, err := projectFiles(req.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases, err := detectAliases(req.Dir, oldPath, goFiles)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range req.OldPaths {
+		if p != oldPath && p != req.NewPath && !slices.Contains(aliases, p) {
+			aliases = append(aliases, p)
+		}
+	}
+
+	result := &RenameResult{OldPath: oldPath, NewPath: req.NewPath, Aliases: aliases}
+	r := &renamer{paths: pathRewriter(append([]string{oldPath}, aliases...), req.NewPath)}
+
+	if req.Binary != "" {
+		r.oldBin = req.OldBinary
+		if r.oldBin == "" {
+			r.oldBin = detectBinary(req.Dir, oldPath, goFiles)
+		}
+		r.newBin = req.Binary
+		result.OldBinary, result.NewBinary = r.oldBin, r.newBin
+	}
+
+	slog.InfoContext(ctx, "renaming module",
+		"from", oldPath,
+		"to", req.NewPath,
+		"aliases", aliases,
+		"binary", req.Binary,
+	)
+
+	all := append(goFiles, otherFiles...)
+	sort.Strings(all)
+	for _, rel := range all {
+		full := filepath.Join(req.Dir, rel)
+		data, err := os.ReadFile(full)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", rel, err)
+		}
+
+		var out []byte
+		var n int
+		if strings.HasSuffix(rel, ".go") {
+			out, n, err = r.goFile(data)
+			if err != nil {
+				return nil, fmt.Errorf("rewriting %s: %w", rel, err)
+			}
+		} else {
+			out, n = r.text(data, rel)
+		}
+
+		change := RenamedFile{Path: filepath.ToSlash(rel), Changes: n}
+		if newName := r.fileName(filepath.Base(rel)); newName != filepath.Base(rel) {
+			change.NewPath = filepath.ToSlash(filepath.Join(filepath.Dir(rel), newName))
+		}
+		if n == 0 && change.NewPath == "" {
+			continue
+		}
+		result.Files = append(result.Files, change)
+
+		if req.DryRun {
+			continue
+		}
+		info, err := os.Stat(full)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", rel, err)
+		}
+		if err := os.WriteFile(full, out, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("writing %s: %w", rel, err)
+		}
+		if change.NewPath != "" {
+			if err := os.Rename(full, filepath.Join(req.Dir, filepath.FromSlash(change.NewPath))); err != nil {
+				return nil, fmt.Errorf("renaming %s: %w", rel, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// projectFiles lists the Go sources and rewritable text files under dir,
+// skipping hidden directories, vendor, and testdata
+func projectFiles(dir string) (goFiles, other []string, err error) {
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != dir && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(name, ".go") {
+			goFiles = append(goFiles, rel)
+			return nil
+		}
+		for _, pattern := range textFiles {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				other = append(other, rel)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("walking %s: %w", dir, err)
+	}
+	return goFiles, other, nil
+}
+
+// detectAliases finds import path prefixes other than the module path that
+// resolve to packages inside the project, such as
+// github.com/old/name/internal/handler when ./internal/handler exists
+func detectAliases(dir, module string, goFiles []string) ([]string, error) {
+	seen := map[string]bool{}
+	var aliases []string
+
+	fset := token.NewFileSet()
+	for _, rel := range goFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, rel), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", rel, err)
+		}
+		for _, imp := range f.Imports {
+			p, err := strconv.Unquote(imp.Path.Value)
+			if err != nil || p == module || strings.HasPrefix(p, module+"/") {
+				continue
+			}
+			if prefix, ok := projectPrefix(dir, p); ok && !seen[prefix] {
+				seen[prefix] = true
+				aliases = append(aliases, prefix)
+			}
+		}
+	}
+	sort.Strings(aliases)
+	return aliases, nil
+}
+
+// packageRoots are the top-level directories an aliased import must point
+// into; matching any directory would mistake third-party packages for ours
+var packageRoots = []string{"cmd", "internal", "pkg"}
+
+// projectPrefix splits an import path at the first suffix that names a
+// package directory of the project
+func projectPrefix(dir, importPath string) (string, bool) {
+	parts := strings.Split(importPath, "/")
+	if !strings.Contains(parts[0], ".") {
+		return "", false // Standard library
+	}
+	// A module path has at least a host and one element
+	for i := 2; i < len(parts); i++ {
+		if !slices.Contains(packageRoots, parts[i]) {
+			continue
+		}
+		rel := filepath.Join(parts[i:]...)
+		if hasGoFiles(filepath.Join(dir, rel)) {
+			return strings.Join(parts[:i], "/"), true
+		}
+	}
+	return "", false
+}
+
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// detectBinary finds the current binary name from the root command, the
+// Makefile, or the module path
+func detectBinary(dir, module string, goFiles []string) string {
+	for _, rel := range goFiles {
+		data, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			continue
+		}
+		if m := rootUseRe.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Makefile")); err == nil {
+		if m := binaryVarRe.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+	}
+	return path.Base(module)
+}
+
+// pathRewriter matches any of the old paths as a whole path prefix. Longer
+// paths come first so github.com/x/app-go wins over github.com/x/app.
+func pathRewriter(old []string, newPath string) func(string) (string, int) {
+	sorted := append([]string(nil), old...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	quoted := make([]string, len(sorted))
+	for i, p := range sorted {
+		quoted[i] = regexp.QuoteMeta(p)
+	}
+	re := regexp.MustCompile(`(` + strings.Join(quoted, "|") + `)([^A-Za-z0-9._~-]|$)`)
+
+	return func(s string) (string, int) {
+		n := 0
+		out := re.ReplaceAllStringFunc(s, func(m string) string {
+			n++
+			sub := re.FindStringSubmatch(m)
+			return newPath + sub[2]
+		})
+		return out, n
+	}
+}
+
+type renamer struct {
+	paths  func(string) (string, int)
+	oldBin string
+	newBin string
+}
+
+// goFile rewrites import paths everywhere and binary names inside string
+// literals only, so identifiers and comments keep their meaning
+func (r *renamer) goFile(src []byte) ([]byte, int, error) {
+	out, n := r.paths(string(src))
+	if r.newBin == "" {
+		return []byte(out), n, nil
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(out))
+	var errs scanner.ErrorList
+	var sc scanner.Scanner
+	sc.Init(file, []byte(out), func(pos token.Position, msg string) { errs.Add(pos, msg) }, 0)
+
+	var b strings.Builder
+	last := 0
+	for {
+		pos, tok, lit := sc.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.STRING {
+			continue
+		}
+		off := file.Offset(pos)
+		replaced, m := r.binaryText(lit)
+		if m == 0 {
+			continue
+		}
+		b.WriteString(out[last:off])
+		b.WriteString(replaced)
+		last = off + len(lit)
+		n += m
+	}
+	if errs.Len() > 0 {
+		return nil, 0, errs.Err()
+	}
+	b.WriteString(out[last:])
+
+	result := b.String()
+	result, m := r.configCalls(result)
+	return []byte(result), n + m, nil
+}
+
+// text rewrites import paths and binary names in a non-Go file
+func (r *renamer) text(src []byte, rel string) ([]byte, int) {
+	out, n := r.paths(string(src))
+	if r.newBin == "" {
+		return []byte(out), n
+	}
+	if filepath.Base(rel) == "go.mod" {
+		return []byte(out), n
+	}
+
+	out, m := r.binaryText(out)
+	n += m
+	out = binaryVarRe.ReplaceAllStringFunc(out, func(s string) string {
+		if strings.HasSuffix(s, r.newBin) {
+			return s
+		}
+		n++
+		sub := binaryVarRe.FindStringSubmatchIndex(s)
+		return s[:sub[2]] + r.newBin
+	})
+	return []byte(out), n
+}
+
+// binaryText replaces the old binary name as a whole word (not inside a
+// path or a longer name) and its upper-case environment prefix
+func (r *renamer) binaryText(s string) (string, int) {
+	out, n := replaceWord(s, r.oldBin, r.newBin, "_/-")
+	out, m := replaceWord(out, envPrefix(r.oldBin), envPrefix(r.newBin), "")
+	return out, n + m
+}
+
+// configCalls points viper's env prefix and config name at the new binary,
+// whatever they were before, so all three stay consistent
+func (r *renamer) configCalls(s string) (string, int) {
+	n := 0
+	env := fmt.Sprintf("SetEnvPrefix(%q)", envPrefix(r.newBin))
+	s = envPrefixRe.ReplaceAllStringFunc(s, func(m string) string {
+		if m != env {
+			n++
+		}
+		return env
+	})
+	name := fmt.Sprintf("SetConfigName(%q)", "."+r.newBin)
+	s = configNameRe.ReplaceAllStringFunc(s, func(m string) string {
+		if m != name {
+			n++
+		}
+		return name
+	})
+	return s, n
+}
+
+// fileName renames config files named after the old binary
+func (r *renamer) fileName(name string) string {
+	if r.newBin == "" {
+		return name
+	}
+	for _, ext := range []string{".yaml", ".yml", ".json", ".toml"} {
+		if name == "."+r.oldBin+ext {
+			return "." + r.newBin + ext
+		}
+	}
+	return name
+}
+
+// replaceWord replaces old with repl where it is not part of a longer word.
+// Characters in joiners also count as part of a word before the match.
+func replaceWord(s, old, repl, joiners string) (string, int) {
+	if old == "" || old == repl {
+		return s, 0
+	}
+	isWord := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+
+	var b strings.Builder
+	n, last := 0, 0
+	for i := 0; ; {
+		j := strings.Index(s[i:], old)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(old)
+		before := start == 0 || !isWord(s[start-1]) && !strings.ContainsRune(joiners, rune(s[start-1]))
+		after := end == len(s) || !isWord(s[end]) && s[end] != '-'
+		if before && after {
+			b.WriteString(s[last:start])
+			b.WriteString(repl)
+			last = end
+			n++
+		}
+		i = end
+	}
+	b.WriteString(s[last:])
+	return b.String(), n
+}
+
+// envPrefix turns a binary name into an environment variable prefix
+func envPrefix(name string) string {
+	return strings.Trim(nonIdentRunes.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
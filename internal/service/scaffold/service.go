@@ -0,0 +1,109 @@
+package scaffold
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+)
+
+type Service struct {
+	// Add dependencies here (repositories, clients, etc.)
+}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// GenerateRequest describes a single generation run
+type GenerateRequest struct {
+	Source    fs.FS                  // Scaffold source (template files + optional template.yaml)
+	Dest      string                 // Directory the files are written to
+	Builtins  map[string]interface{} // Values provided by the generator itself
+	Overrides map[string]string      // Values given with --var
+	Prompter  Prompter               // Nil disables interactive prompts
+	Force     bool                   // Overwrite existing files
+	SkipHooks bool                   // Don't run post-generation hooks
+	Trust     bool                   // Run shell hooks without confirmation
+	Output    io.Writer              // Receives hook output
+	From      string                 // The --from value, kept in the generation record
+	Record    bool                   // Write a generation record for later upgrades
+
+	// Subsystems to include; nil asks the prompter or uses the defaults
+	Subsystems []string
+}
+
+// GenerateResult describes what a generation run produced
+type GenerateResult struct {
+	Manifest   *Manifest
+	Vars       map[string]interface{}
+	Subsystems []string
+	Notes      []string // Subsystems added to satisfy requirements
+	Files      []string
+	Hooks      []HookResult
+}
+
+// Generate resolves the source's variables and renders it into req.Dest
+func (s *Service) Generate(ctx context.Context, req GenerateRequest) (*GenerateResult, error) {
+	manifest, err := LoadManifest(req.Source)
+	if err != nil {
+		return nil, fmt.Errorf("loading manifest: %w", err)
+	}
+
+	subsystems, notes, err := manifest.ResolveSubsystems(req.Subsystems, req.Prompter)
+	if err != nil {
+		return nil, fmt.Errorf("resolving subsystems: %w", err)
+	}
+
+	vars, err := manifest.Resolve(manifest.subsystemVars(req.Builtins, subsystems), req.Overrides, req.Prompter)
+	if err != nil {
+		return nil, fmt.Errorf("resolving variables: %w", err)
+	}
+
+	slog.InfoContext(ctx, "generating files",
+		"template", manifest.Name,
+		"dest", req.Dest,
+		"subsystems", subsystems,
+	)
+
+	rendered, err := renderAll(ctx, req.Source, vars, manifest.excludedPaths(subsystems))
+	if err != nil {
+		return nil, err
+	}
+	files, err := writeAll(req.Dest, rendered, req.Force)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Record {
+		rec := newRecord(req.From, manifest, vars, req.Builtins, subsystems)
+		if err := rec.save(req.Dest, rendered); err != nil {
+			return nil, fmt.Errorf("recording generation: %w", err)
+		}
+	}
+
+	result := &GenerateResult{
+		Manifest:   manifest,
+		Vars:       vars,
+		Subsystems: subsystems,
+		Notes:      notes,
+		Files:      files,
+	}
+
+	if !req.SkipHooks && len(manifest.Hooks) > 0 {
+		out := req.Output
+		if out == nil {
+			out = io.Discard
+		}
+		result.Hooks = runHooks(ctx, manifest.Hooks, hookOptions{
+			Dir:      req.Dest,
+			Vars:     vars,
+			Output:   out,
+			Prompter: req.Prompter,
+			Trust:    req.Trust,
+		})
+	}
+
+	return result, nil
+}
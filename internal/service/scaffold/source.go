@@ -0,0 +1,210 @@
+package scaffold
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/offline"
+	"github.com/blacksilver/termplate-go/internal/retry"
+)
+
+// Source kinds
+const (
+	KindEmbedded = "embedded"
+	KindLocal    = "local"
+	KindGit      = "git"
+)
+
+// Source identifies where a scaffold comes from
+type Source struct {
+	Kind     string
+	Location string // Embedded name, local directory, or git URL
+	Ref      string // Git branch, tag, or commit
+	Subdir   string // Directory inside a git repository
+}
+
+// ParseSource parses a --from value. Git sources accept a subdirectory and
+// a pinned ref: https://github.com/org/repo.git//templates/cli#v1.2.0
+func ParseSource(s string) Source {
+	if isGitURL(s) {
+		src := Source{Kind: KindGit, Location: s}
+		if loc, ref, ok := strings.Cut(src.Location, "#"); ok {
+			src.Location, src.Ref = loc, ref
+		}
+		// Skip the scheme's "//" when looking for the subdirectory separator
+		offset := 0
+		if i := strings.Index(src.Location, "://"); i >= 0 {
+			offset = i + 3
+		}
+		if i := strings.Index(src.Location[offset:], "//"); i >= 0 {
+			src.Subdir = src.Location[offset+i+2:]
+			src.Location = src.Location[:offset+i]
+		}
+		return src
+	}
+
+	if !strings.ContainsAny(s, `/\`) {
+		if _, ok := Embedded(s); ok {
+			return Source{Kind: KindEmbedded, Location: s}
+		}
+	}
+	return Source{Kind: KindLocal, Location: s}
+}
+
+func isGitURL(s string) bool {
+	for _, prefix := range []string{"https://", "http://", "ssh://", "git://", "file://", "git@"} {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	base, _, _ := strings.Cut(s, "#")
+	return strings.HasSuffix(base, ".git") || strings.Contains(base, ".git//")
+}
+
+func (s Source) String() string {
+	out := s.Location
+	if s.Subdir != "" {
+		out += "//" + s.Subdir
+	}
+	if s.Ref != "" {
+		out += "#" + s.Ref
+	}
+	return out
+}
+
+// cacheKey identifies a git checkout in the cache directory
+func (s Source) cacheKey() string {
+	sum := sha256.Sum256([]byte(s.Location + "#" + s.Ref))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// open returns the files of the source, fetching git sources into cacheDir
+// when they are not cached yet (or always, when refresh is set)
+func (s Source) open(ctx context.Context, cacheDir string, refresh bool) (fs.FS, error) {
+	switch s.Kind {
+	case KindEmbedded:
+		src, ok := Embedded(s.Location)
+		if !ok {
+			return nil, model.NewOperationError("open", "template", s.Location, model.ErrNotFound)
+		}
+		return src, nil
+	case KindGit:
+		dir := filepath.Join(cacheDir, s.cacheKey())
+		err := retry.FromContext(ctx).Do(ctx, "fetching "+s.String(), func(ctx context.Context) error {
+			return s.fetch(ctx, dir, refresh)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return openDir(filepath.Join(dir, filepath.FromSlash(s.Subdir)))
+	default:
+		return openDir(s.Location)
+	}
+}
+
+func openDir(dir string) (fs.FS, error) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil, model.NewValidationError("from", fmt.Sprintf("%s is not a directory", dir))
+	}
+	return os.DirFS(dir), nil
+}
+
+// fetch checks out the source's ref into dir. Fetching by ref works for
+// branches, tags, and commit hashes alike.
+// In offline mode a cached checkout is used as is, even when refresh is set.
+func (s Source) fetch(ctx context.Context, dir string, refresh bool) error {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("checking cache: %w", err)
+	}
+	cached := err == nil
+	if cached && !refresh {
+		return nil
+	}
+	if err := offline.Check("fetching " + s.String()); err != nil {
+		if cached {
+			slog.WarnContext(ctx, "offline: using cached template", "url", s.Location, "ref", s.Ref)
+			return nil
+		}
+		return err
+	}
+
+	slog.InfoContext(ctx, "fetching template", "url", s.Location, "ref", s.Ref)
+
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("clearing %s: %w", tmp, err)
+	}
+	if err := os.MkdirAll(tmp, 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", tmp, err)
+	}
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	steps := [][]string{
+		{"git", "init", "--quiet"},
+		{"git", "remote", "add", "origin", s.Location},
+		{"git", "fetch", "--quiet", "--depth", "1", "origin", ref},
+		{"git", "checkout", "--quiet", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Dir = tmp
+		if out, err := cmd.CombinedOutput(); err != nil {
+			_ = os.RemoveAll(tmp)
+			if ctx.Err() != nil {
+				return fmt.Errorf("%s: %w", strings.Join(args, " "), context.Cause(ctx))
+			}
+			err = fmt.Errorf("%s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+			if isNetworkFailure(string(out)) {
+				err = retry.Retryable(err)
+			}
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("replacing cached template: %w", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("replacing cached template: %w", err)
+	}
+	return nil
+}
+
+// gitNetworkErrors are fragments of git's messages for failures that may
+// succeed when tried again
+var gitNetworkErrors = []string{
+	"Could not resolve host",
+	"Connection timed out",
+	"Connection refused",
+	"Connection reset",
+	"Operation timed out",
+	"early EOF",
+	"remote end hung up unexpectedly",
+	"Temporary failure",
+}
+
+// isNetworkFailure reports whether git output describes a transient
+// network failure
+func isNetworkFailure(out string) bool {
+	for _, msg := range gitNetworkErrors {
+		if strings.Contains(out, msg) {
+			return true
+		}
+	}
+	return false
+}
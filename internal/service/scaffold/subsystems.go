@@ -0,0 +1,178 @@
+package scaffold
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// SubsystemsVar is the template variable holding the selected subsystems,
+// e.g. {{ if .Subsystems.server }}
+const SubsystemsVar = "Subsystems"
+
+// Subsystem is an optional part of a scaffold source. Its files are only
+// generated when it is selected, and templates check .Subsystems.<name>
+// to include the code and config sections that use it.
+type Subsystem struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Default     bool     `yaml:"default"`
+	Paths       []string `yaml:"paths"`    // Source files and directories that belong to the subsystem
+	Requires    []string `yaml:"requires"` // Each entry needs one of its "|"-separated alternatives, e.g. "server|pushgateway"
+}
+
+func (m *Manifest) validateSubsystems() error {
+	names := make(map[string]bool, len(m.Subsystems))
+	for _, s := range m.Subsystems {
+		if !identRe.MatchString(s.Name) {
+			return model.NewValidationError("subsystems", fmt.Sprintf("invalid subsystem name %q", s.Name))
+		}
+		if names[s.Name] {
+			return model.NewValidationError("subsystems."+s.Name, "declared more than once")
+		}
+		names[s.Name] = true
+	}
+
+	for _, s := range m.Subsystems {
+		for _, req := range s.Requires {
+			for _, alt := range strings.Split(req, "|") {
+				if alt == s.Name {
+					return model.NewValidationError("subsystems."+s.Name, "requires itself")
+				}
+				if !names[alt] {
+					return model.NewValidationError("subsystems."+s.Name, fmt.Sprintf("requires unknown subsystem %q", alt))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// SubsystemNames returns the names of the manifest's subsystems
+func (m *Manifest) SubsystemNames() []string {
+	names := make([]string, len(m.Subsystems))
+	for i, s := range m.Subsystems {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// ResolveSubsystems picks the subsystems to generate. A nil requested list
+// asks the prompter, or falls back to the defaults. Requirements are then
+// added until every selected subsystem is satisfied; when a requirement has
+// alternatives the prompter chooses, otherwise the first one wins. The
+// returned notes explain every subsystem added this way.
+func (m *Manifest) ResolveSubsystems(requested []string, p Prompter) (selected, notes []string, err error) {
+	if len(m.Subsystems) == 0 {
+		if len(requested) > 0 {
+			return nil, nil, model.NewValidationError("with", "template has no optional subsystems")
+		}
+		return nil, nil, nil
+	}
+
+	chosen := map[string]bool{}
+	switch {
+	case requested != nil:
+		for _, name := range requested {
+			if !slices.Contains(m.SubsystemNames(), name) {
+				return nil, nil, model.NewValidationError("with",
+					fmt.Sprintf("unknown subsystem %q (available: %s)", name, strings.Join(m.SubsystemNames(), ", ")))
+			}
+			chosen[name] = true
+		}
+	case p != nil:
+		for _, s := range m.Subsystems {
+			label := "Include " + s.Name
+			if s.Description != "" {
+				label += " (" + s.Description + ")"
+			}
+			ok, err := p.Confirm(label, s.Default)
+			if err != nil {
+				return nil, nil, fmt.Errorf("prompting for %s: %w", s.Name, err)
+			}
+			chosen[s.Name] = ok
+		}
+	default:
+		for _, s := range m.Subsystems {
+			chosen[s.Name] = s.Default
+		}
+	}
+
+	// Adding a subsystem can bring new requirements, so repeat until stable
+	for changed := true; changed; {
+		changed = false
+		for _, s := range m.Subsystems {
+			if !chosen[s.Name] {
+				continue
+			}
+			for _, req := range s.Requires {
+				alts := strings.Split(req, "|")
+				if slices.ContainsFunc(alts, func(a string) bool { return chosen[a] }) {
+					continue
+				}
+
+				pick := alts[0]
+				if len(alts) > 1 && p != nil {
+					pick, err = p.Select(fmt.Sprintf("%s needs one of", s.Name), alts, alts[0])
+					if err != nil {
+						return nil, nil, fmt.Errorf("prompting for %s requirement: %w", s.Name, err)
+					}
+				}
+				chosen[pick] = true
+				changed = true
+				notes = append(notes, fmt.Sprintf("added %s (required by %s)", pick, s.Name))
+			}
+		}
+	}
+
+	for _, s := range m.Subsystems {
+		if chosen[s.Name] {
+			selected = append(selected, s.Name)
+		}
+	}
+	return selected, notes, nil
+}
+
+// subsystemVars returns builtins plus the .Subsystems map, which holds
+// every declared subsystem so templates can test any of them
+func (m *Manifest) subsystemVars(builtins map[string]interface{}, selected []string) map[string]interface{} {
+	if len(m.Subsystems) == 0 {
+		return builtins
+	}
+	flags := make(map[string]bool, len(m.Subsystems))
+	for _, s := range m.Subsystems {
+		flags[s.Name] = slices.Contains(selected, s.Name)
+	}
+
+	out := make(map[string]interface{}, len(builtins)+1)
+	for k, v := range builtins {
+		out[k] = v
+	}
+	out[SubsystemsVar] = flags
+	return out
+}
+
+// excludedPaths returns the source paths of the subsystems left out
+func (m *Manifest) excludedPaths(selected []string) []string {
+	var paths []string
+	for _, s := range m.Subsystems {
+		if !slices.Contains(selected, s.Name) {
+			paths = append(paths, s.Paths...)
+		}
+	}
+	return paths
+}
+
+// isExcluded reports whether source path p is, or is inside, an excluded path
+func isExcluded(p string, excluded []string) bool {
+	for _, e := range excluded {
+		e = path.Clean(e)
+		if p == e || strings.HasPrefix(p, e+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package scaffold
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/blacksilver/termplate-go/internal/diff"
+)
+
+// Upgrade actions
+const (
+	ActionUnchanged       = "unchanged"        // Already matches the template
+	ActionUpdated         = "updated"          // Not modified locally; replaced with the new version
+	ActionKept            = "kept"             // Modified locally; template didn't change it
+	ActionMerged          = "merged"           // Local and template changes merged cleanly
+	ActionConflict        = "conflict"         // Needs a manual merge
+	ActionAdded           = "added"            // New in the template
+	ActionDeletedLocally  = "deleted-locally"  // Removed locally; left alone
+	ActionRemovedUpstream = "removed-upstream" // No longer generated by the template
+)
+
+// UpgradeRequest describes an upgrade of a generated project
+type UpgradeRequest struct {
+	Dir            string
+	Source         fs.FS
+	From           string            // Source recorded for future upgrades
+	Overrides      map[string]string // Values given with --var
+	Prompter       Prompter          // Asks for variables the new version adds
+	DryRun         bool
+	WriteConflicts bool // Write files with conflict markers instead of only suggesting a patch
+}
+
+// FileChange is the planned or applied change to one file
+type FileChange struct {
+	Path   string `json:"path" yaml:"path"`
+	Action string `json:"action" yaml:"action"`
+	Patch  string `json:"patch,omitempty" yaml:"patch,omitempty"`
+}
+
+// UpgradeResult describes an upgrade
+type UpgradeResult struct {
+	FromVersion string       `json:"from_version" yaml:"from_version"`
+	ToVersion   string       `json:"to_version" yaml:"to_version"`
+	Changes     []FileChange `json:"changes" yaml:"changes"`
+}
+
+// Conflicts returns the number of files that need a manual merge
+func (r *UpgradeResult) Conflicts() int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == ActionConflict {
+			n++
+		}
+	}
+	return n
+}
+
+// Upgrade re-renders the project's template and merges the result into the
+// project, using the base copies from the generation record as the common
+// ancestor
+func (s *Service) Upgrade(ctx context.Context, req UpgradeRequest) (*UpgradeResult, error) {
+	rec, err := LoadRecord(req.Dir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := LoadManifest(req.Source)
+	if err != nil {
+		return nil, fmt.Errorf("loading manifest: %w", err)
+	}
+
+	overrides := map[string]string{}
+	for _, v := range manifest.Variables {
+		if old, ok := rec.Vars[v.Name]; ok {
+			overrides[v.Name] = fmt.Sprint(old)
+		}
+	}
+	for k, v := range req.Overrides {
+		overrides[k] = v
+	}
+	// Keep the recorded subsystems that still exist; records from before
+	// the template had subsystems fall back to prompts and defaults
+	var requested []string
+	if rec.Subsystems != nil {
+		requested = []string{}
+		for _, name := range rec.Subsystems {
+			if slices.Contains(manifest.SubsystemNames(), name) {
+				requested = append(requested, name)
+			}
+		}
+	}
+	subsystems, _, err := manifest.ResolveSubsystems(requested, req.Prompter)
+	if err != nil {
+		return nil, fmt.Errorf("resolving subsystems: %w", err)
+	}
+
+	vars, err := manifest.Resolve(manifest.subsystemVars(rec.Builtins, subsystems), overrides, req.Prompter)
+	if err != nil {
+		return nil, fmt.Errorf("resolving variables: %w", err)
+	}
+
+	rendered, err := renderAll(ctx, req.Source, vars, manifest.excludedPaths(subsystems))
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "upgrading project",
+		"dir", req.Dir,
+		"from", rec.Version,
+		"to", manifest.Version,
+	)
+
+	result := &UpgradeResult{FromVersion: rec.Version, ToVersion: manifest.Version}
+	next := newRecord(req.From, manifest, vars, rec.Builtins, subsystems)
+	seen := map[string]bool{}
+
+	for _, f := range rendered {
+		seen[f.Path] = true
+		change, content, err := planFile(ctx, req, rec, f)
+		if err != nil {
+			return nil, err
+		}
+		result.Changes = append(result.Changes, change)
+
+		if req.DryRun {
+			continue
+		}
+		if content != nil {
+			target := filepath.Join(req.Dir, filepath.FromSlash(f.Path))
+			if err := writeFile(target, content, f.Mode, true); err != nil {
+				return nil, err
+			}
+		}
+		// Files left with an unresolved conflict keep their old base so the
+		// next upgrade proposes the change again
+		if change.Action == ActionConflict && !req.WriteConflicts {
+			if old, ok := rec.base(req.Dir, f.Path); ok {
+				f.Content = old
+			} else {
+				continue
+			}
+		}
+		if err := next.setBase(req.Dir, f); err != nil {
+			return nil, err
+		}
+	}
+
+	removed := make([]string, 0)
+	for p := range rec.Files {
+		if !seen[p] {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(removed)
+	for _, p := range removed {
+		result.Changes = append(result.Changes, FileChange{Path: p, Action: ActionRemovedUpstream})
+	}
+
+	if !req.DryRun {
+		if err := next.write(req.Dir); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// planFile decides what to do with one rendered file. It returns the content
+// to write, or nil to leave the file alone.
+func planFile(ctx context.Context, req UpgradeRequest, rec *Record, f renderedFile) (FileChange, []byte, error) {
+	change := FileChange{Path: f.Path}
+
+	current, err := os.ReadFile(filepath.Join(req.Dir, filepath.FromSlash(f.Path)))
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return change, nil, fmt.Errorf("reading %s: %w", f.Path, err)
+	}
+	base, hasBase := rec.base(req.Dir, f.Path)
+
+	switch {
+	case exists && bytes.Equal(current, f.Content):
+		change.Action = ActionUnchanged
+		return change, nil, nil
+	case !hasBase && !exists:
+		change.Action = ActionAdded
+		return change, f.Content, nil
+	case !hasBase:
+		change.Action = ActionConflict
+		change.Patch = diff.Unified("a/"+f.Path, "b/"+f.Path, string(current), string(f.Content), 3)
+		return change, nil, nil
+	case !exists:
+		change.Action = ActionDeletedLocally
+		return change, nil, nil
+	case bytes.Equal(base, f.Content):
+		change.Action = ActionKept
+		return change, nil, nil
+	case bytes.Equal(base, current):
+		change.Action = ActionUpdated
+		change.Patch = diff.Unified("a/"+f.Path, "b/"+f.Path, string(current), string(f.Content), 3)
+		return change, f.Content, nil
+	}
+
+	merged, clean, err := merge3(ctx, current, base, f.Content)
+	if err != nil {
+		slog.WarnContext(ctx, "three-way merge unavailable", "file", f.Path, "error", err)
+	}
+	if clean {
+		change.Action = ActionMerged
+		change.Patch = diff.Unified("a/"+f.Path, "b/"+f.Path, string(current), string(merged), 3)
+		return change, merged, nil
+	}
+
+	change.Action = ActionConflict
+	change.Patch = diff.Unified("a/"+f.Path, "b/"+f.Path, string(base), string(f.Content), 3)
+	if req.WriteConflicts && merged != nil {
+		return change, merged, nil
+	}
+	return change, nil, nil
+}
+
+// merge3 merges the template's changes (base to theirs) into ours using
+// git merge-file. clean is false when the result contains conflict markers.
+func merge3(ctx context.Context, ours, base, theirs []byte) (merged []byte, clean bool, err error) {
+	tmp, err := os.MkdirTemp("", "termplate-merge-")
+	if err != nil {
+		return nil, false, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	names := []string{"current", "base", "template"}
+	for i, content := range [][]byte{ours, base, theirs} {
+		if err := os.WriteFile(filepath.Join(tmp, names[i]), content, 0o600); err != nil {
+			return nil, false, fmt.Errorf("writing merge input: %w", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "merge-file", "-p",
+		"-L", "current", "-L", "base", "-L", "template",
+		"current", "base", "template")
+	cmd.Dir = tmp
+	out, err := cmd.Output()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return out, true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() > 0:
+		// The exit code is the number of conflicts
+		return out, false, nil
+	default:
+		return nil, false, fmt.Errorf("running git merge-file: %w", err)
+	}
+}
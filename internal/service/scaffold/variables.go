@@ -0,0 +1,134 @@
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Prompter asks the user for variable values and hook confirmations
+type Prompter interface {
+	Ask(label, def string) (string, error)
+	Select(label string, choices []string, def string) (string, error)
+	Confirm(label string, def bool) (bool, error)
+	Warn(msg string)
+}
+
+// ParseVars parses key=value pairs given on the command line
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, model.NewValidationError("var", fmt.Sprintf("expected key=value, got %q", pair))
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// Resolve computes the value of every manifest variable. Values come from
+// overrides first, then from the prompter (when set), then from defaults.
+// Builtin values are available to defaults and templates but can't be overridden.
+func (m *Manifest) Resolve(builtins map[string]interface{}, overrides map[string]string, p Prompter) (map[string]interface{}, error) {
+	for key := range overrides {
+		if !slices.ContainsFunc(m.Variables, func(v Variable) bool { return v.Name == key }) {
+			return nil, model.NewValidationError("var", fmt.Sprintf("unknown variable %q", key))
+		}
+	}
+
+	vars := make(map[string]interface{}, len(builtins)+len(m.Variables))
+	for k, v := range builtins {
+		vars[k] = v
+	}
+
+	for _, v := range m.Variables {
+		def, err := renderString(v.Default, vars)
+		if err != nil {
+			return nil, fmt.Errorf("rendering default for %s: %w", v.Name, err)
+		}
+
+		raw, fromUser := overrides[v.Name]
+		if !fromUser {
+			raw = def
+		}
+
+		for {
+			if !fromUser && p != nil {
+				raw, err = ask(p, v, def)
+				if err != nil {
+					return nil, fmt.Errorf("prompting for %s: %w", v.Name, err)
+				}
+			}
+
+			value, verr := v.convert(raw)
+			if verr == nil {
+				vars[v.Name] = value
+				break
+			}
+			if fromUser || p == nil {
+				return nil, verr
+			}
+			p.Warn(verr.Error())
+		}
+	}
+
+	return vars, nil
+}
+
+func ask(p Prompter, v Variable, def string) (string, error) {
+	label := v.Prompt
+	if label == "" {
+		label = v.Name
+	}
+	if len(v.Choices) > 0 {
+		return p.Select(label, v.Choices, def)
+	}
+	return p.Ask(label, def)
+}
+
+// convert validates raw and converts it to the variable's type
+func (v Variable) convert(raw string) (interface{}, error) {
+	if raw == "" {
+		if v.Required {
+			return nil, model.NewValidationError(v.Name, "value is required")
+		}
+		if v.Type == "" || v.Type == "string" {
+			return "", nil
+		}
+	}
+
+	if len(v.Choices) > 0 && !slices.Contains(v.Choices, raw) {
+		return nil, model.NewValidationError(v.Name, fmt.Sprintf("must be one of: %s", strings.Join(v.Choices, ", ")))
+	}
+	if v.Validate != "" && !regexp.MustCompile(v.Validate).MatchString(raw) {
+		return nil, model.NewValidationError(v.Name, fmt.Sprintf("must match %s", v.Validate))
+	}
+
+	switch v.Type {
+	case "bool":
+		if raw == "" {
+			return false, nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, model.NewValidationError(v.Name, fmt.Sprintf("invalid bool %q", raw))
+		}
+		return b, nil
+	case "int":
+		if raw == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, model.NewValidationError(v.Name, fmt.Sprintf("invalid int %q", raw))
+		}
+		return n, nil
+	default:
+		return raw, nil
+	}
+}
@@ -0,0 +1,78 @@
+// Package shellenv renders environment variable assignments for shells to
+// eval.
+package shellenv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Shells lists the supported shells
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Var is an environment variable to set
+type Var struct {
+	Name  string
+	Value string
+}
+
+// VarName returns the environment variable for a config key, following the
+// loader's naming: prefix_KEY with dots as underscores (TERMPLATE_API_TOKEN)
+func VarName(prefix, key string) string {
+	name := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if prefix == "" {
+		return name
+	}
+	return strings.ToUpper(prefix) + "_" + name
+}
+
+type Service struct{}
+
+func NewService() *Service {
+	return &Service{}
+}
+
+// Export returns statements setting vars in shell, one per line, with
+// values quoted so any content survives eval
+func (s *Service) Export(shell string, vars []Var) (string, error) {
+	var line func(v Var) string
+	switch shell {
+	case "bash", "zsh":
+		line = func(v Var) string {
+			return fmt.Sprintf("export %s=%s", v.Name, posixQuote(v.Value))
+		}
+	case "fish":
+		line = func(v Var) string {
+			return fmt.Sprintf("set -gx %s %s", v.Name, fishQuote(v.Value))
+		}
+	case "powershell":
+		line = func(v Var) string {
+			return fmt.Sprintf("$env:%s = %s", v.Name, powershellQuote(v.Value))
+		}
+	default:
+		return "", fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(Shells, ", "))
+	}
+
+	var b strings.Builder
+	for _, v := range vars {
+		b.WriteString(line(v))
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
+// posixQuote single-quotes s, closing and reopening the quotes around an
+// escaped quote
+func posixQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes s; fish unescapes only \\ and \' inside
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// powershellQuote single-quotes s, doubling any single quote inside
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package termtest
+
+import (
+	"errors"
+	"os"
+)
+
+func open() (*os.File, error) {
+	return nil, errors.New("only supported on linux")
+}
@@ -0,0 +1,22 @@
+// Package termtest gives tests a terminal to write to, for code that
+// behaves differently on one, such as colored output and progress
+// displays.
+package termtest
+
+import (
+	"os"
+	"testing"
+)
+
+// Open returns the terminal end of a new pseudo-terminal, closed when the
+// test ends. It skips the test where none can be opened. Output written
+// to it is discarded.
+func Open(t testing.TB) *os.File {
+	t.Helper()
+	tty, err := open()
+	if err != nil {
+		t.Skipf("no pseudo-terminal: %v", err)
+	}
+	t.Cleanup(func() { tty.Close() })
+	return tty
+}
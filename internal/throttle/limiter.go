@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxBurst caps the bytes a Limiter hands out at once, so a fast link is
+// smoothed into steady chunks rather than bursts a second long
+const maxBurst = 64 << 10
+
+// Limiter hands out bytes at a steady rate (a token bucket). It's safe for
+// concurrent use: transfers sharing a Limiter share its rate. A nil
+// Limiter is unlimited.
+type Limiter struct {
+	rate  float64 // Bytes per second
+	burst int
+
+	mu     sync.Mutex
+	tokens float64 // Negative while transfers wait for their bytes
+	last   time.Time
+}
+
+// NewLimiter returns a Limiter for bytesPerSec, or nil (unlimited) when it
+// isn't positive
+func NewLimiter(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := int(min(bytesPerSec, maxBurst))
+	return &Limiter{rate: float64(bytesPerSec), burst: burst, tokens: float64(burst), last: time.Now()}
+}
+
+// Burst returns the most bytes worth transferring at once
+func (l *Limiter) Burst() int {
+	if l == nil {
+		return 0
+	}
+	return l.burst
+}
+
+// WaitN takes n bytes from the limiter, waiting until the rate allows
+// them or ctx is done
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
@@ -0,0 +1,107 @@
+// Package throttle limits the bandwidth of file transfers, per transfer
+// and across all the transfers of the process, so bulk syncs leave room on
+// shared links. Apply sets the limits from the transfer section of the
+// config; transfer code wraps what it reads with Download or Upload.
+//
+//	_, err := io.Copy(f, throttle.Download(ctx, resp.Body))
+package throttle
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+)
+
+// direction holds the limits of downloads or of uploads
+type direction struct {
+	each  int64    // Bytes per second for each transfer
+	total *Limiter // Shared by all transfers
+}
+
+type limits struct {
+	download, upload direction
+}
+
+var current atomic.Pointer[limits]
+
+// Apply sets the bandwidth limits from cfg; zero is unlimited. Transfers
+// already running keep the limits they started with.
+func Apply(cfg config.TransferConfig) {
+	current.Store(&limits{
+		download: direction{each: int64(cfg.DownloadLimit), total: NewLimiter(int64(cfg.TotalDownloadLimit))},
+		upload:   direction{each: int64(cfg.UploadLimit), total: NewLimiter(int64(cfg.TotalUploadLimit))},
+	})
+	if cfg != (config.TransferConfig{}) {
+		slog.Debug("set bandwidth limits",
+			"download", cfg.DownloadLimit, "upload", cfg.UploadLimit,
+			"total_download", cfg.TotalDownloadLimit, "total_upload", cfg.TotalUploadLimit)
+	}
+}
+
+// Download returns r, holding data being downloaded, limited to the
+// configured download bandwidth. Reads fail with ctx's cause once it's
+// done while waiting.
+func Download(ctx context.Context, r io.Reader) io.Reader {
+	if l := current.Load(); l != nil {
+		return l.download.reader(ctx, r)
+	}
+	return r
+}
+
+// Upload returns r, holding data being uploaded, limited to the configured
+// upload bandwidth
+func Upload(ctx context.Context, r io.Reader) io.Reader {
+	if l := current.Load(); l != nil {
+		return l.upload.reader(ctx, r)
+	}
+	return r
+}
+
+func (d direction) reader(ctx context.Context, r io.Reader) io.Reader {
+	return NewReader(ctx, r, NewLimiter(d.each), d.total)
+}
+
+// NewReader returns r limited by each of limiters; nil ones are ignored,
+// and r itself is returned when none are left
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	var ls []*Limiter
+	chunk := 0
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		ls = append(ls, l)
+		if chunk == 0 || l.Burst() < chunk {
+			chunk = l.Burst()
+		}
+	}
+	if len(ls) == 0 {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiters: ls, chunk: chunk}
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+	chunk    int // Most bytes read at once
+}
+
+// Read reads at most a chunk, then waits until every limiter allows the
+// bytes read
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		if werr := l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,65 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/prompt"
+)
+
+// Resolution is what to do about a conflict
+type Resolution string
+
+const (
+	Retry     Resolution = "retry"     // Apply the change again to the latest version
+	Overwrite Resolution = "overwrite" // Replace the latest version with this change
+	Abort     Resolution = "abort"     // Leave the resource as it is
+)
+
+// Resolutions are the valid resolutions
+var Resolutions = []Resolution{Retry, Overwrite, Abort}
+
+// Resolver decides what to do about a conflict
+type Resolver func(ctx context.Context, conflict *ConflictError) (Resolution, error)
+
+// Always returns a Resolver that resolves every conflict with r
+func Always(r Resolution) Resolver {
+	return func(context.Context, *ConflictError) (Resolution, error) {
+		return r, nil
+	}
+}
+
+// Ask returns a Resolver that shows the conflict's diff and asks what to
+// do. An unanswered prompt aborts.
+func Ask(p *prompt.Prompter) Resolver {
+	return func(_ context.Context, conflict *ConflictError) (Resolution, error) {
+		p.Warn(conflict.Error() + ":\n" + conflict.Diff())
+		choices := make([]string, len(Resolutions))
+		for i, r := range Resolutions {
+			choices[i] = string(r)
+		}
+		answer, err := p.Choose("Apply your change again, overwrite theirs, or abort?", choices, string(Abort))
+		if err != nil {
+			return Abort, nil
+		}
+		return Resolution(answer), nil
+	}
+}
+
+// NewResolver returns the Resolver for an api.on_conflict setting: ask
+// prompts when the session is interactive and aborts otherwise
+func NewResolver(onConflict string) (Resolver, error) {
+	if onConflict == "ask" {
+		if !prompt.IsInteractive() {
+			return nil, nil
+		}
+		return Ask(prompt.NewStdio()), nil
+	}
+	r := Resolution(strings.ToLower(onConflict))
+	if !slices.Contains(Resolutions, r) {
+		return nil, fmt.Errorf("invalid conflict resolution %q (valid: ask, retry, overwrite, abort)", onConflict)
+	}
+	return Always(r), nil
+}
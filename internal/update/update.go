@@ -0,0 +1,256 @@
+// Package update changes API resources with optimistic concurrency. Modify
+// fetches a resource, applies a change to it, and sends it back with
+// If-Match set to the ETag it was fetched with, or with its version field
+// as fetched when the API doesn't send ETags, so the API refuses the update
+// when someone else changed the resource in between. Such a mid-air
+// collision is a *ConflictError showing both changes, and a Resolver
+// decides whether to apply the change again to the latest version,
+// overwrite that version, or abort.
+package update
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/blacksilver/termplate-go/internal/diff"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// maxErrorBody is how much of an error response is kept in its HTTPError
+const maxErrorBody = 1024
+
+// DefaultVersionField is the body field compared when the API sends no
+// ETag
+const DefaultVersionField = "version"
+
+// Resource is a resource as fetched, with what identifies its version
+type Resource struct {
+	URL     string
+	ETag    string                 // The ETag header; empty when the API doesn't send one
+	Version interface{}            // The version field, when there's no ETag
+	Body    map[string]interface{} // The JSON object
+}
+
+// Mutator changes body, a copy of the fetched resource, in place. It may
+// be called again with the latest version after a conflict.
+type Mutator func(body map[string]interface{}) error
+
+// Options configures Modify
+type Options struct {
+	Method       string // PUT when empty; PATCH sends the whole changed body too
+	VersionField string // DefaultVersionField when empty
+
+	// Resolver decides what to do about a conflict; nil aborts
+	Resolver Resolver
+}
+
+// Get fetches the resource at url, which must be a JSON object
+func Get(ctx context.Context, client *http.Client, url string, versionField string) (*Resource, error) {
+	if versionField == "" {
+		versionField = DefaultVersionField
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, httpError(req, resp)
+	}
+
+	res := &Resource{URL: url, ETag: resp.Header.Get("ETag")}
+	if err := json.NewDecoder(resp.Body).Decode(&res.Body); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", url, err)
+	}
+	if res.Body == nil {
+		return nil, fmt.Errorf("parsing %s: not a JSON object", url)
+	}
+	if res.ETag == "" {
+		res.Version = res.Body[versionField]
+	}
+	return res, nil
+}
+
+// Modify fetches the resource at url, changes it with mutate, and sends it
+// back unless someone else changed it since it was fetched. On a conflict
+// it asks opts.Resolver: Retry fetches the latest version and changes that
+// instead, Overwrite sends the change over the latest version, and Abort
+// returns the *ConflictError. It returns the resource as updated.
+func Modify(ctx context.Context, client *http.Client, url string, mutate Mutator, opts Options) (*Resource, error) {
+	if opts.Method == "" {
+		opts.Method = http.MethodPut
+	}
+	if opts.VersionField == "" {
+		opts.VersionField = DefaultVersionField
+	}
+
+	base, err := Get(ctx, client, url, opts.VersionField)
+	if err != nil {
+		return nil, err
+	}
+	mine, err := apply(base, mutate)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		updated, err := put(ctx, client, opts, base, mine)
+		if !errors.Is(err, model.ErrConflict) {
+			return updated, err
+		}
+
+		theirs, err := Get(ctx, client, url, opts.VersionField)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s after a conflict: %w", url, err)
+		}
+		conflict := &ConflictError{URL: url, Base: base.Body, Mine: mine, Theirs: theirs.Body}
+		resolution := Abort
+		if opts.Resolver != nil {
+			if resolution, err = opts.Resolver(ctx, conflict); err != nil {
+				return nil, err
+			}
+		}
+		slog.DebugContext(ctx, "update conflict", "url", url, "resolution", resolution)
+
+		switch resolution {
+		case Retry:
+			base = theirs
+			if mine, err = apply(base, mutate); err != nil {
+				return nil, err
+			}
+		case Overwrite:
+			// Sent with the latest version, so a later change still conflicts
+			base = &Resource{URL: url, ETag: theirs.ETag, Version: theirs.Version, Body: mine}
+		default:
+			return nil, conflict
+		}
+	}
+}
+
+// apply returns a copy of res's body changed by mutate
+func apply(res *Resource, mutate Mutator) (map[string]interface{}, error) {
+	body, err := clone(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := mutate(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// put sends body as the new version of base, returning an error wrapping
+// model.ErrConflict when the API refuses it because base isn't the latest
+// version
+func put(ctx context.Context, client *http.Client, opts Options, base *Resource, body map[string]interface{}) (*Resource, error) {
+	if base.ETag == "" && base.Version != nil {
+		// The version field says which version the change is based on
+		body[opts.VersionField] = base.Version
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", base.URL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, opts.Method, base.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if base.ETag != "" {
+		req.Header.Set("If-Match", base.ETag)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed || resp.StatusCode == http.StatusConflict:
+		return nil, fmt.Errorf("%w: %w", model.ErrConflict, httpError(req, resp))
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, httpError(req, resp)
+	}
+
+	updated := &Resource{URL: base.URL, ETag: resp.Header.Get("ETag"), Body: body}
+	// The API may answer with the resource as stored, or nothing
+	var stored map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&stored); err == nil && stored != nil {
+		updated.Body = stored
+	}
+	if updated.ETag == "" {
+		updated.Version = updated.Body[opts.VersionField]
+	}
+	return updated, nil
+}
+
+// clone deep-copies a JSON object
+func clone(body map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var c map[string]interface{}
+	return c, json.Unmarshal(data, &c)
+}
+
+func httpError(req *http.Request, resp *http.Response) *model.HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &model.HTTPError{
+		Method:     req.Method,
+		URL:        req.URL.Redacted(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+	}
+}
+
+// ConflictError reports that a resource changed after it was fetched, so
+// a change to it wasn't applied
+type ConflictError struct {
+	URL    string
+	Base   map[string]interface{} // The version the change was made to
+	Mine   map[string]interface{} // The version with the change
+	Theirs map[string]interface{} // The latest version
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s was changed by someone else since it was fetched", e.URL)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return model.ErrConflict
+}
+
+func (e *ConflictError) ExitCode() int {
+	return model.ExitConflict
+}
+
+// Diff shows what each side changed since the version both started from:
+// first the other change, then this one
+func (e *ConflictError) Diff() string {
+	base, theirs, mine := indent(e.Base), indent(e.Theirs), indent(e.Mine)
+	return diff.Unified("fetched", "theirs", base, theirs, 3) +
+		diff.Unified("fetched", "yours", base, mine, 3)
+}
+
+// indent renders a body for diffing, with its keys sorted
+func indent(body map[string]interface{}) string {
+	data, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		return fmt.Sprint(body)
+	}
+	return string(data) + "\n"
+}
@@ -0,0 +1,159 @@
+// Package usage keeps per-command invocation counts and durations in the
+// state directory. Nothing leaves the machine; the data shows adopters which
+// commands are used most and which are slow.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blacksilver/termplate-go/internal/lock"
+	"github.com/blacksilver/termplate-go/internal/paths"
+)
+
+// SkipAnnotation marks a cobra command, and the commands under it, as one
+// whose runs aren't recorded, e.g. because it runs until stopped
+const SkipAnnotation = "termplate.no_usage"
+
+// lockName serializes updates from concurrent invocations
+const lockName = "usage"
+
+// lockWait is how long Record waits for another invocation's update.
+// Recording is best effort, so a busy file is skipped rather than waited on.
+const lockWait = time.Second
+
+// Stat is the usage of one command
+type Stat struct {
+	Command  string        `json:"command"`
+	Count    int           `json:"count"`
+	Failures int           `json:"failures"`
+	Total    time.Duration `json:"total_ns"`
+	Max      time.Duration `json:"max_ns"`
+	LastUsed time.Time     `json:"last_used"`
+}
+
+// Average returns the mean duration of the command's runs
+func (s Stat) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// file is the stored form: stats keyed by command path
+type file struct {
+	Commands map[string]*Stat `json:"commands"`
+}
+
+// Path returns the file usage is stored in
+func Path() (string, error) {
+	dir, err := paths.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.json"), nil
+}
+
+// Record adds a run of command that took d
+func Record(ctx context.Context, command string, d time.Duration, failed bool) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	l, err := lock.Acquire(ctx, lockName, lockWait)
+	if err != nil {
+		return fmt.Errorf("locking usage file: %w", err)
+	}
+	defer l.Release()
+
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	s, ok := f.Commands[command]
+	if !ok {
+		s = &Stat{Command: command}
+		f.Commands[command] = s
+	}
+	s.Count++
+	if failed {
+		s.Failures++
+	}
+	s.Total += d
+	s.Max = max(s.Max, d)
+	s.LastUsed = time.Now().UTC().Truncate(time.Second)
+	return write(path, f)
+}
+
+// Load returns the recorded stats in no particular order
+func Load() ([]Stat, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]Stat, 0, len(f.Commands))
+	for _, s := range f.Commands {
+		stats = append(stats, *s)
+	}
+	return stats, nil
+}
+
+// Reset deletes the recorded stats
+func Reset() error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", path, err)
+	}
+	return nil
+}
+
+// read loads the usage file; a missing file holds no stats
+func read(path string) (*file, error) {
+	f := &file{Commands: map[string]*Stat{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if f.Commands == nil {
+		f.Commands = map[string]*Stat{}
+	}
+	return f, nil
+}
+
+// write replaces the usage file, through a temporary file so readers never
+// see a partial write
+func write(path string, f *file) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding usage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", path, err)
+	}
+	return nil
+}
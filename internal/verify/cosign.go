@@ -0,0 +1,55 @@
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseCosignKey parses a PEM public key, as written by cosign
+// generate-key-pair
+func parseCosignKey(text string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(text))
+	if block == nil {
+		return nil, errors.New("not a PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T (use ECDSA or Ed25519)", key)
+	}
+}
+
+// verifyCosign checks a signature made by cosign sign-blob, base64-encoded
+func verifyCosign(keys []crypto.PublicKey, data, sigData []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		return errors.New("malformed cosign signature: not base64")
+	}
+	digest := sha256.Sum256(data)
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, data, sig) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("cosign signature doesn't match the file with any of the %d keys in verify.cosign_keys", len(keys))
+}
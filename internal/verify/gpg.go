@@ -0,0 +1,74 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// verifyGPG checks a detached GPG signature with gpg, in a keyring of its
+// own holding only keys, so the user's keyring doesn't count
+func verifyGPG(ctx context.Context, keys []string, data, sigData []byte) error {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		return errors.New("verifying GPG signatures needs gpg installed")
+	}
+	home, err := os.MkdirTemp("", "termplate-gpg-")
+	if err != nil {
+		return fmt.Errorf("creating keyring: %w", err)
+	}
+	defer os.RemoveAll(home)
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.CommandContext(ctx, gpg, append([]string{"--batch", "--no-tty", "--homedir", home}, args...)...)
+		var out bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &out, &out
+		err := cmd.Run()
+		return out.String(), err
+	}
+
+	for i, key := range keys {
+		file := filepath.Join(home, fmt.Sprintf("key%d.asc", i))
+		if err := os.WriteFile(file, []byte(key), 0o600); err != nil {
+			return fmt.Errorf("writing keyring: %w", err)
+		}
+		if out, err := run("--import", file); err != nil {
+			return fmt.Errorf("importing GPG key %d of verify.gpg_keys: %s", i+1, strings.TrimSpace(out))
+		}
+	}
+
+	dataFile, sigFile := filepath.Join(home, "data"), filepath.Join(home, "data.sig")
+	if err := os.WriteFile(dataFile, data, 0o600); err != nil {
+		return fmt.Errorf("writing keyring: %w", err)
+	}
+	if err := os.WriteFile(sigFile, sigData, 0o600); err != nil {
+		return fmt.Errorf("writing keyring: %w", err)
+	}
+	out, err := run("--status-fd", "1", "--verify", sigFile, dataFile)
+	if err == nil && strings.Contains(out, "[GNUPG:] VALIDSIG") {
+		return nil
+	}
+	if strings.Contains(out, "[GNUPG:] NO_PUBKEY") {
+		return errors.New("GPG signature was made with a key that isn't among verify.gpg_keys")
+	}
+	if strings.Contains(out, "[GNUPG:] BADSIG") {
+		return errors.New("GPG signature doesn't match the file")
+	}
+	return fmt.Errorf("checking GPG signature: %s", gpgMessage(out))
+}
+
+// gpgMessage returns gpg's human-readable lines, leaving out status lines
+func gpgMessage(out string) string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "[GNUPG:]") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// minisignKey is a minisign public key: "Ed", an 8-byte key ID, and an
+// Ed25519 key, base64-encoded on the second line of a .pub file
+type minisignKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+func parseMinisignKey(text string) (minisignKey, error) {
+	var k minisignKey
+	raw, err := base64.StdEncoding.DecodeString(lastLine(text))
+	if err != nil || len(raw) != 42 || string(raw[:2]) != "Ed" {
+		return k, errors.New("not a minisign public key")
+	}
+	copy(k.id[:], raw[2:10])
+	k.key = ed25519.PublicKey(raw[10:])
+	return k, nil
+}
+
+// minisignSignature is a .minisig file: an untrusted comment, the
+// signature of the file, a trusted comment, and a signature of both
+type minisignSignature struct {
+	prehashed bool // "ED": the file's BLAKE2b-512 digest was signed
+	keyID     [8]byte
+	sig       []byte
+	trusted   string
+	globalSig []byte
+}
+
+func parseMinisignSignature(data []byte) (*minisignSignature, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return nil, errors.New("malformed minisign signature")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(raw) != 74 {
+		return nil, errors.New("malformed minisign signature")
+	}
+	s := &minisignSignature{sig: raw[10:], trusted: strings.TrimSuffix(strings.TrimPrefix(lines[2], "trusted comment: "), "\r")}
+	switch string(raw[:2]) {
+	case "Ed":
+	case "ED":
+		s.prehashed = true
+	default:
+		return nil, fmt.Errorf("unsupported minisign algorithm %q", raw[:2])
+	}
+	copy(s.keyID[:], raw[2:10])
+	if s.globalSig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3])); err != nil || len(s.globalSig) != 64 {
+		return nil, errors.New("malformed minisign signature")
+	}
+	return s, nil
+}
+
+// verifyMinisign checks data's signature against the keys with its key ID
+func verifyMinisign(keys []minisignKey, data, sigData []byte) error {
+	s, err := parseMinisignSignature(sigData)
+	if err != nil {
+		return err
+	}
+	msg := data
+	if s.prehashed {
+		sum := blake2b512(data)
+		msg = sum[:]
+	}
+	for _, k := range keys {
+		if !bytes.Equal(k.id[:], s.keyID[:]) {
+			continue
+		}
+		if !ed25519.Verify(k.key, msg, s.sig) {
+			return errors.New("minisign signature doesn't match the file")
+		}
+		if !ed25519.Verify(k.key, append(append([]byte{}, s.sig...), s.trusted...), s.globalSig) {
+			return errors.New("minisign trusted comment was tampered with")
+		}
+		return nil
+	}
+	return fmt.Errorf("signed with minisign key %s, which isn't among verify.minisign_keys", minisignID(s.keyID))
+}
+
+// minisignID formats a key ID as minisign prints it
+func minisignID(id [8]byte) string {
+	// minisign shows the little-endian ID as a big-endian number
+	rev := make([]byte, 8)
+	for i := range id {
+		rev[i] = id[7-i]
+	}
+	return strings.ToUpper(hex.EncodeToString(rev))
+}
+
+// lastLine returns the last non-empty line of text, so a key can be given
+// inline or as the contents of its .pub file
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
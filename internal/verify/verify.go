@@ -0,0 +1,218 @@
+// Package verify checks downloaded files, such as self-update artifacts
+// and template archives, against checksums and signatures made with
+// minisign, cosign, or GPG, using the public keys in the config.
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/model"
+)
+
+// Expect is what vouches for a downloaded file
+type Expect struct {
+	// Checksum is "sha256:<hex>" or "sha512:<hex>"; a bare digest is
+	// SHA-256 or SHA-512 by its length. See ChecksumFromList.
+	Checksum string
+
+	// Signature is the detached signature: a .minisig file, the base64
+	// output of cosign sign-blob, or a GPG signature (.asc or .sig)
+	Signature []byte
+}
+
+// Verifier checks files with the keys from the verify config section
+type Verifier struct {
+	minisign         []minisignKey
+	cosign           []crypto.PublicKey
+	gpg              []string
+	requireSignature bool
+}
+
+// New loads the public keys in cfg. Each is given inline or as the path
+// of a file holding it.
+func New(cfg config.VerifyConfig) (*Verifier, error) {
+	v := &Verifier{requireSignature: cfg.RequireSignature}
+	for _, k := range cfg.MinisignKeys {
+		text, err := keyText(k)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseMinisignKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("verify.minisign_keys: %s: %w", shorten(k), err)
+		}
+		v.minisign = append(v.minisign, key)
+	}
+	for _, k := range cfg.CosignKeys {
+		text, err := keyText(k)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseCosignKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("verify.cosign_keys: %s: %w", shorten(k), err)
+		}
+		v.cosign = append(v.cosign, key)
+	}
+	for _, k := range cfg.GPGKeys {
+		text, err := keyText(k)
+		if err != nil {
+			return nil, err
+		}
+		v.gpg = append(v.gpg, text)
+	}
+	return v, nil
+}
+
+// File checks the file at path against want, returning an error wrapping
+// model.ErrVerification that says what didn't match. A file with neither a
+// checksum nor a signature passes unless verify.require_signature is set,
+// which also makes a checksum alone not enough.
+func (v *Verifier) File(ctx context.Context, path string, want Expect) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	if err := v.check(ctx, data, want); err != nil {
+		return fmt.Errorf("verifying %s: %w: %w", filepath.Base(path), model.ErrVerification, err)
+	}
+	return nil
+}
+
+func (v *Verifier) check(ctx context.Context, data []byte, want Expect) error {
+	if want.Checksum != "" {
+		if err := checkSum(data, want.Checksum); err != nil {
+			return err
+		}
+	}
+	if len(want.Signature) == 0 {
+		if v.requireSignature {
+			return fmt.Errorf("no signature to check, and verify.require_signature is set")
+		}
+		return nil
+	}
+
+	switch kind := signatureKind(want.Signature); kind {
+	case "minisign":
+		if len(v.minisign) == 0 {
+			return fmt.Errorf("signed with minisign, but verify.minisign_keys is empty")
+		}
+		return verifyMinisign(v.minisign, data, want.Signature)
+	case "gpg":
+		if len(v.gpg) == 0 {
+			return fmt.Errorf("signed with GPG, but verify.gpg_keys is empty")
+		}
+		return verifyGPG(ctx, v.gpg, data, want.Signature)
+	default:
+		if len(v.cosign) == 0 {
+			return fmt.Errorf("signed with cosign, but verify.cosign_keys is empty")
+		}
+		return verifyCosign(v.cosign, data, want.Signature)
+	}
+}
+
+// signatureKind tells the signature formats apart by their contents
+func signatureKind(sig []byte) string {
+	switch {
+	case bytes.HasPrefix(sig, []byte("untrusted comment:")):
+		return "minisign"
+	case bytes.HasPrefix(sig, []byte("-----BEGIN PGP SIGNATURE-----")), sig[0]&0x80 != 0:
+		// Binary OpenPGP packets have the high bit of their first byte set
+		return "gpg"
+	default:
+		return "cosign"
+	}
+}
+
+// checkSum compares data's digest with a checksum such as "sha256:<hex>"
+func checkSum(data []byte, want string) error {
+	algo, digest, ok := strings.Cut(want, ":")
+	if !ok {
+		algo, digest = "", want
+	}
+	expected, err := hex.DecodeString(strings.TrimSpace(digest))
+	if err != nil {
+		return fmt.Errorf("malformed checksum %q", want)
+	}
+	if algo == "" {
+		switch len(expected) {
+		case sha256.Size:
+			algo = "sha256"
+		case sha512.Size:
+			algo = "sha512"
+		}
+	}
+
+	var actual []byte
+	switch strings.ToLower(algo) {
+	case "sha256":
+		sum := sha256.Sum256(data)
+		actual = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(data)
+		actual = sum[:]
+	default:
+		return fmt.Errorf("unsupported checksum %q (use sha256:<hex> or sha512:<hex>)", want)
+	}
+	if subtle.ConstantTimeCompare(actual, expected) != 1 {
+		return fmt.Errorf("checksum mismatch: expected %s:%s, got %s:%x", strings.ToLower(algo), hex.EncodeToString(expected), strings.ToLower(algo), actual)
+	}
+	return nil
+}
+
+// ChecksumFromList returns the checksum of name from a checksum list such
+// as a release's SHA256SUMS, in the format sha256sum writes:
+// "<hex>  <name>" per line
+func ChecksumFromList(list []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		digest, file, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+		// sha256sum marks files hashed in binary mode with "*"
+		file = strings.TrimPrefix(strings.TrimSpace(file), "*")
+		if file == name || filepath.Base(file) == name {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in the checksum list", name)
+}
+
+// keyText returns a key given inline, or the contents of the file it names
+func keyText(k string) (string, error) {
+	k = strings.TrimSpace(k)
+	if strings.Contains(k, "\n") || strings.HasPrefix(k, "-----BEGIN") {
+		return k, nil
+	}
+	data, err := os.ReadFile(k)
+	if os.IsNotExist(err) {
+		// A single-line inline key, such as a minisign key
+		return k, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading key %s: %w", k, err)
+	}
+	return string(data), nil
+}
+
+// shorten abbreviates an inline key for messages
+func shorten(k string) string {
+	k = strings.TrimSpace(k)
+	if len(k) > 24 {
+		return k[:20] + "…"
+	}
+	return k
+}
@@ -8,6 +8,7 @@ import (
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		cmd.PrintError(os.Stderr, err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -0,0 +1,89 @@
+// Package termplate exposes the termplate core to other Go programs: the
+// command tree, config loading, output formatting, and logger setup. Use it
+// to embed termplate's commands or reuse its subsystems instead of
+// shelling out to the binary.
+//
+// Mount the commands under your own root, where they run as
+// "mytool termplate ...":
+//
+//	root.AddCommand(termplate.Command())
+//
+// Or run a command line in-process:
+//
+//	err := termplate.Run(ctx, "template", "list", "-o", "json")
+//	os.Exit(termplate.ExitCode(err))
+package termplate
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/blacksilver/termplate-go/cmd"
+	"github.com/blacksilver/termplate-go/internal/config"
+	"github.com/blacksilver/termplate-go/internal/logger"
+	"github.com/blacksilver/termplate-go/internal/model"
+	"github.com/blacksilver/termplate-go/internal/output"
+)
+
+// Config holds all configuration; see Loader
+type Config = config.Config
+
+// OutputConfig controls output formatting
+type OutputConfig = config.OutputConfig
+
+// Loader builds a Config from defaults, a config file, environment
+// variables, and flags. Loaders don't share state.
+type Loader = config.Loader
+
+// Formatter prints data as text, json, yaml, table, or csv
+type Formatter = output.Formatter
+
+// NewLoader creates a config loader holding only the defaults
+func NewLoader() *Loader {
+	return config.NewLoader()
+}
+
+// LoaderFromContext returns the loader a running termplate command was
+// given, for commands added with Register
+func LoaderFromContext(ctx context.Context) *Loader {
+	return config.FromContext(ctx)
+}
+
+// NewFormatter creates a formatter writing to w
+func NewFormatter(cfg OutputConfig, w io.Writer) *Formatter {
+	return output.NewFormatterWithWriter(cfg, w)
+}
+
+// InitLogger sets up the default slog logger the way the termplate binary
+// does: text on stderr, or JSON on stdout in production
+func InitLogger(level slog.Level, production bool) {
+	logger.Init(level, production)
+}
+
+// Command returns the termplate root command. There is a single command
+// tree per process, so mount it in one place only.
+func Command() *cobra.Command {
+	return cmd.Root()
+}
+
+// Register adds commands to the termplate root. They get the global flags,
+// config loading (see LoaderFromContext), and command locks like the
+// built-in commands.
+func Register(cmds ...*cobra.Command) {
+	cmd.Root().AddCommand(cmds...)
+}
+
+// Run executes a termplate command line (without the program name) in
+// this process. Commands share flag state, so calls must not overlap.
+func Run(ctx context.Context, args ...string) error {
+	return cmd.ExecuteContext(ctx, args)
+}
+
+// ExitCode returns the process exit code the termplate binary uses for an
+// error returned by Run
+func ExitCode(err error) int {
+	return model.ExitCode(err)
+}